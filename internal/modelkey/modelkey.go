@@ -0,0 +1,386 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modelkey
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	ModelTypeKey           string = "model_type"
+	BucketKey              string = "bucket"
+	DiskSizeBytesKey       string = "disk_size_bytes"
+	SchemaPathKey          string = "schema_path"
+	StorageKeyKey          string = "storage_key"
+	StorageParamsKey       string = "storage_params"
+	VersionPolicyKey       string = "version_policy"
+	PluginConfigKey        string = "plugin_config"
+	SequenceBatchingKey    string = "sequence_batching"
+	OptimizationKey        string = "optimization"
+	TarSubpathKey          string = "tar_subpath"
+	VersionIDKey           string = "version_id"
+	BackendKey             string = "backend"
+	ParametersKey          string = "parameters"
+	DownloadConcurrencyKey string = "download_concurrency"
+	InstancesPerGpuKey     string = "instances_per_gpu"
+	ServedModelNameKey     string = "served_model_name"
+	ModelFilenameKey       string = "model_filename"
+	LabelsKey              string = "labels"
+	FileMappingsKey        string = "file_mappings"
+)
+
+// ModelKey is the JSON passed in the ModelKey field of a LoadModelRequest
+//
+// Fields that are not part of the struct are kept as they are and written
+// back out when the ModelKey is marshalled.
+type ModelKey struct {
+	ModelType     *ModelType
+	Bucket        string // DEPRECATED: use StorageParams["bucket"]
+	DiskSizeBytes *int64
+	SchemaPath    *string
+	StorageKey    *string
+	StorageParams map[string]string
+	VersionPolicy *VersionPolicy
+	PluginConfig  map[string]string
+	// the sequence_batching field as it was given, which has the shape of
+	// the sequence_batching of a Triton model config
+	SequenceBatching json.RawMessage
+	// the optimization field as it was given, which has the shape of the
+	// optimization policy of a Triton model config
+	Optimization json.RawMessage
+	// the path within a tar archive at the ModelPath to extract the model from
+	TarSubpath string
+	// the version of the object at the ModelPath to pull, for storage that
+	// keeps versions of objects
+	VersionID string
+	// the runtime backend that serves the model and the parameters passed to it
+	Backend    string
+	Parameters map[string]string
+	// the number of files to download in parallel, 0 for the default
+	DownloadConcurrency int
+	// the number of instances of the model to run on each GPU, 0 for the
+	// default of the runtime
+	InstancesPerGpu int
+	// the name that clients use for the model in the runtime, if it differs
+	// from the model id
+	ServedModelName string
+	// the name of the model file, if it is not named per the convention of
+	// the runtime
+	ModelFilename string
+	// labels of the model, eg. its team, that an adapter may attach to the
+	// metrics of the model
+	Labels map[string]string
+	// single files to pull into a chosen destination within the model path,
+	// regardless of the names of their keys
+	FileMappings []FileMapping
+
+	// unknown fields, for pass-through
+	extra map[string]json.RawMessage
+}
+
+// ModelType is the model_type field of the ModelKey, which is either the name
+// of the model type or an object like {"name": "tensorflow", "version": "2"}
+//
+// A parsed ModelType is marshalled back in its original form.
+type ModelType struct {
+	Name    string
+	Version string
+
+	// the original JSON, nil if the ModelType was not parsed
+	raw json.RawMessage
+}
+
+// FileMapping is an element of the file_mappings field of the ModelKey, which
+// pulls the file at the storage key into the destination path, relative to the
+// local path of the model, eg. {"key": "exports/v2.onnx", "dest": "1/model.onnx"}
+type FileMapping struct {
+	Key  string `json:"key"`
+	Dest string `json:"dest"`
+}
+
+// VersionPolicy is the version_policy field of the ModelKey, which selects the
+// versions to serve when the model has multiple version directories
+//
+// It has the same shape as the version_policy of a Triton model config, with
+// exactly one of the fields set, eg. {"latest": {"num_versions": 2}}.
+type VersionPolicy struct {
+	All      *VersionPolicyAll      `json:"all,omitempty"`
+	Latest   *VersionPolicyLatest   `json:"latest,omitempty"`
+	Specific *VersionPolicySpecific `json:"specific,omitempty"`
+}
+
+// VersionPolicyAll serves all versions of the model
+type VersionPolicyAll struct{}
+
+// VersionPolicyLatest serves the NumVersions highest versions of the model
+type VersionPolicyLatest struct {
+	NumVersions uint32 `json:"num_versions"`
+}
+
+// VersionPolicySpecific serves the listed versions of the model
+type VersionPolicySpecific struct {
+	Versions []int64 `json:"versions"`
+}
+
+// Parse parses the ModelKey JSON
+func Parse(modelKey string) (*ModelKey, error) {
+	var mk ModelKey
+	if err := json.Unmarshal([]byte(modelKey), &mk); err != nil {
+		return nil, err
+	}
+	return &mk, nil
+}
+
+// ParseFields parses only the given fields of the ModelKey JSON, so that a
+// field with a value of the wrong type does not fail a caller that does not
+// read it. The other fields are left unset and are not kept for pass-through.
+func ParseFields(modelKey string, keys ...string) (*ModelKey, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(modelKey), &fields); err != nil {
+		return nil, err
+	}
+
+	var mk ModelKey
+	for _, key := range keys {
+		if value, ok := fields[key]; ok {
+			if err := mk.unmarshalField(key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &mk, nil
+}
+
+// GetModelTypeName returns the name of the model type, or the empty string
+// if it is not set
+func (mk *ModelKey) GetModelTypeName() string {
+	if mk.ModelType == nil {
+		return ""
+	}
+	return mk.ModelType.Name
+}
+
+func (mk *ModelKey) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*mk = ModelKey{}
+	for key, value := range fields {
+		if err := mk.unmarshalField(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unmarshalField sets the field of the ModelKey for the key, or keeps the
+// value for pass-through if the key is unknown
+func (mk *ModelKey) unmarshalField(key string, value json.RawMessage) error {
+	var target interface{}
+	switch key {
+	case ModelTypeKey:
+		target = &mk.ModelType
+	case BucketKey:
+		target = &mk.Bucket
+	case DiskSizeBytesKey:
+		size, err := parseDiskSizeBytes(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for '%s' (%s): %w", key, string(value), err)
+		}
+		mk.DiskSizeBytes = size
+		return nil
+	case SchemaPathKey:
+		target = &mk.SchemaPath
+	case StorageKeyKey:
+		target = &mk.StorageKey
+	case StorageParamsKey:
+		target = &mk.StorageParams
+	case VersionPolicyKey:
+		target = &mk.VersionPolicy
+	case PluginConfigKey:
+		target = &mk.PluginConfig
+	case SequenceBatchingKey:
+		target = &mk.SequenceBatching
+	case OptimizationKey:
+		target = &mk.Optimization
+	case TarSubpathKey:
+		target = &mk.TarSubpath
+	case VersionIDKey:
+		target = &mk.VersionID
+	case BackendKey:
+		target = &mk.Backend
+	case ParametersKey:
+		target = &mk.Parameters
+	case DownloadConcurrencyKey:
+		target = &mk.DownloadConcurrency
+	case InstancesPerGpuKey:
+		target = &mk.InstancesPerGpu
+	case ServedModelNameKey:
+		target = &mk.ServedModelName
+	case ModelFilenameKey:
+		target = &mk.ModelFilename
+	case LabelsKey:
+		target = &mk.Labels
+	case FileMappingsKey:
+		target = &mk.FileMappings
+	default:
+		if mk.extra == nil {
+			mk.extra = make(map[string]json.RawMessage)
+		}
+		mk.extra[key] = value
+		return nil
+	}
+
+	if err := json.Unmarshal(value, target); err != nil {
+		return fmt.Errorf("invalid value for '%s' (%s): %w", key, string(value), err)
+	}
+	return nil
+}
+
+// parseDiskSizeBytes parses the disk_size_bytes, which may also be written as
+// a float, eg. 1.5e+09, by clients that encode all numbers as floats. A
+// fractional size is rounded to the nearest byte.
+func parseDiskSizeBytes(value json.RawMessage) (*int64, error) {
+	var size *int64
+	if err := json.Unmarshal(value, &size); err == nil {
+		if size != nil && *size < 0 {
+			return nil, fmt.Errorf("%d is a negative number of bytes", *size)
+		}
+		return size, nil
+	}
+	var f float64
+	if err := json.Unmarshal(value, &f); err != nil {
+		return nil, err
+	}
+	f = math.Round(f)
+	if f < 0 || f >= math.MaxInt64 {
+		return nil, fmt.Errorf("%v is not a valid number of bytes", f)
+	}
+	i := int64(f)
+	return &i, nil
+}
+
+// MarshalJSON writes the known fields in a fixed order, followed by the
+// unknown fields sorted by key
+func (mk ModelKey) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	first := true
+	writeField := func(key string, value interface{}) error {
+		valueBytes, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("error marshalling '%s': %w", key, err)
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		keyBytes, _ := json.Marshal(key)
+		buf.Write(keyBytes)
+		buf.WriteByte(':')
+		buf.Write(valueBytes)
+		return nil
+	}
+
+	type field struct {
+		key   string
+		value interface{}
+		isSet bool
+	}
+	fields := []field{
+		{ModelTypeKey, mk.ModelType, mk.ModelType != nil},
+		{BucketKey, mk.Bucket, mk.Bucket != ""},
+		{DiskSizeBytesKey, mk.DiskSizeBytes, mk.DiskSizeBytes != nil},
+		{SchemaPathKey, mk.SchemaPath, mk.SchemaPath != nil},
+		{StorageKeyKey, mk.StorageKey, mk.StorageKey != nil},
+		{StorageParamsKey, mk.StorageParams, len(mk.StorageParams) > 0},
+		{VersionPolicyKey, mk.VersionPolicy, mk.VersionPolicy != nil},
+		{PluginConfigKey, mk.PluginConfig, len(mk.PluginConfig) > 0},
+		{SequenceBatchingKey, mk.SequenceBatching, len(mk.SequenceBatching) > 0},
+		{OptimizationKey, mk.Optimization, len(mk.Optimization) > 0},
+		{TarSubpathKey, mk.TarSubpath, mk.TarSubpath != ""},
+		{VersionIDKey, mk.VersionID, mk.VersionID != ""},
+		{BackendKey, mk.Backend, mk.Backend != ""},
+		{ParametersKey, mk.Parameters, len(mk.Parameters) > 0},
+		{DownloadConcurrencyKey, mk.DownloadConcurrency, mk.DownloadConcurrency != 0},
+		{InstancesPerGpuKey, mk.InstancesPerGpu, mk.InstancesPerGpu != 0},
+		{ServedModelNameKey, mk.ServedModelName, mk.ServedModelName != ""},
+		{ModelFilenameKey, mk.ModelFilename, mk.ModelFilename != ""},
+		{LabelsKey, mk.Labels, len(mk.Labels) > 0},
+		{FileMappingsKey, mk.FileMappings, len(mk.FileMappings) > 0},
+	}
+	for _, f := range fields {
+		if !f.isSet {
+			continue
+		}
+		if err := writeField(f.key, f.value); err != nil {
+			return nil, err
+		}
+	}
+
+	extraKeys := make([]string, 0, len(mk.extra))
+	for key := range mk.extra {
+		extraKeys = append(extraKeys, key)
+	}
+	sort.Strings(extraKeys)
+	for _, key := range extraKeys {
+		if err := writeField(key, mk.extra[key]); err != nil {
+			return nil, err
+		}
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (mt *ModelType) UnmarshalJSON(data []byte) error {
+	*mt = ModelType{raw: append(json.RawMessage(nil), data...)}
+
+	// the name and version are only read if they are strings, but the
+	// original value is always kept
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		mt.Name = name
+		return nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err == nil {
+		mt.Name, _ = fields["name"].(string)
+		mt.Version, _ = fields["version"].(string)
+	}
+
+	return nil
+}
+
+func (mt ModelType) MarshalJSON() ([]byte, error) {
+	if mt.raw != nil {
+		return mt.raw, nil
+	}
+
+	fields := map[string]string{"name": mt.Name}
+	if mt.Version != "" {
+		fields["version"] = mt.Version
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,225 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modelkey
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseModelTypeShapes(t *testing.T) {
+	tests := []struct {
+		name            string
+		modelKey        string
+		expectedName    string
+		expectedVersion string
+		expectedJSON    string
+	}{
+		{
+			name:         "string",
+			modelKey:     `{"model_type": "openvino"}`,
+			expectedName: "openvino",
+			expectedJSON: `{"model_type":"openvino"}`,
+		},
+		{
+			name:            "nested object",
+			modelKey:        `{"model_type": {"name": "tensorflow", "version": "1.5"}}`,
+			expectedName:    "tensorflow",
+			expectedVersion: "1.5",
+			expectedJSON:    `{"model_type":{"name":"tensorflow","version":"1.5"}}`,
+		},
+		{
+			name:         "nested object with a name that is not a string",
+			modelKey:     `{"model_type": {"name": 5}}`,
+			expectedName: "",
+			expectedJSON: `{"model_type":{"name":5}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mk, err := Parse(tt.modelKey)
+			if err != nil {
+				t.Fatalf("Unexpected error parsing ModelKey: %v", err)
+			}
+
+			if mk.GetModelTypeName() != tt.expectedName {
+				t.Errorf("Expected model type name '%s' but got '%s'", tt.expectedName, mk.GetModelTypeName())
+			}
+			if mk.ModelType.Version != tt.expectedVersion {
+				t.Errorf("Expected model type version '%s' but got '%s'", tt.expectedVersion, mk.ModelType.Version)
+			}
+
+			// the model type keeps its shape
+			out, err := json.Marshal(mk)
+			if err != nil {
+				t.Fatalf("Unexpected error marshalling ModelKey: %v", err)
+			}
+			if string(out) != tt.expectedJSON {
+				t.Errorf("Expected ModelKey JSON %s but got %s", tt.expectedJSON, string(out))
+			}
+		})
+	}
+}
+
+func TestParseMissingFields(t *testing.T) {
+	mk, err := Parse(`{}`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing ModelKey: %v", err)
+	}
+
+	if mk.ModelType != nil || mk.GetModelTypeName() != "" {
+		t.Errorf("Expected no model type but got %v", mk.ModelType)
+	}
+	if mk.DiskSizeBytes != nil {
+		t.Errorf("Expected no disk size but got %d", *mk.DiskSizeBytes)
+	}
+	if mk.SchemaPath != nil || mk.StorageKey != nil || mk.StorageParams != nil || mk.Bucket != "" {
+		t.Errorf("Expected all fields to be unset but got %+v", mk)
+	}
+
+	out, err := json.Marshal(mk)
+	if err != nil {
+		t.Fatalf("Unexpected error marshalling ModelKey: %v", err)
+	}
+	if string(out) != `{}` {
+		t.Errorf("Expected empty ModelKey JSON but got %s", string(out))
+	}
+}
+
+func TestParseAllFieldsAndPassThrough(t *testing.T) {
+	mk, err := Parse(`{
+		"storage_key": "myStorage",
+		"storage_params": {"bucket": "bucket1"},
+		"bucket": "bucket0",
+		"disk_size_bytes": 54321,
+		"schema_path": "my_schema",
+		"model_type": {"name": "onnx"},
+		"custom_field": {"nested": [1, 2]},
+		"another_field": "value"
+	}`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing ModelKey: %v", err)
+	}
+
+	if *mk.StorageKey != "myStorage" || mk.StorageParams["bucket"] != "bucket1" || mk.Bucket != "bucket0" {
+		t.Errorf("Unexpected storage fields in %+v", mk)
+	}
+	if *mk.DiskSizeBytes != 54321 {
+		t.Errorf("Expected disk size 54321 but got %d", *mk.DiskSizeBytes)
+	}
+	if *mk.SchemaPath != "my_schema" {
+		t.Errorf("Expected schema path 'my_schema' but got '%s'", *mk.SchemaPath)
+	}
+
+	// unknown fields are written back after the known fields
+	mk.StorageKey = nil
+	mk.StorageParams = nil
+	mk.Bucket = ""
+	out, err := json.Marshal(mk)
+	if err != nil {
+		t.Fatalf("Unexpected error marshalling ModelKey: %v", err)
+	}
+	expectedJSON := `{"model_type":{"name":"onnx"},"disk_size_bytes":54321,"schema_path":"my_schema","another_field":"value","custom_field":{"nested":[1,2]}}`
+	if string(out) != expectedJSON {
+		t.Errorf("Expected ModelKey JSON %s but got %s", expectedJSON, string(out))
+	}
+}
+
+func TestParseDiskSizeBytesFloat(t *testing.T) {
+	for modelKey, expected := range map[string]int64{
+		`{"disk_size_bytes": 1024.0}`:  1024,
+		`{"disk_size_bytes": 1.5e+09}`: 1500000000,
+		`{"disk_size_bytes": 1234.5}`:  1235,
+		`{"disk_size_bytes": 1234.4}`:  1234,
+	} {
+		mk, err := Parse(modelKey)
+		if err != nil {
+			t.Errorf("Unexpected error parsing ModelKey %s: %v", modelKey, err)
+			continue
+		}
+		if mk.DiskSizeBytes == nil || *mk.DiskSizeBytes != expected {
+			t.Errorf("Expected disk size %d for ModelKey %s but got %v", expected, modelKey, mk.DiskSizeBytes)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, modelKey := range []string{
+		`{}{"model_type":{"name": "tensorflow"}}`,
+		`{"model_type":{"name": "tensorflow"},"schema_path": 2}`,
+		`{"disk_size_bytes": "large"}`,
+		`{"disk_size_bytes": -1024}`,
+		`{"disk_size_bytes": -0.7}`,
+		`{"disk_size_bytes": 1e400}`,
+		`{"plugin_config": {"NIREQ": 4}}`,
+		`{"parameters": {"max_batch": 4}}`,
+		`{"download_concurrency": "8"}`,
+		`{"file_mappings": {"key": "model.onnx", "dest": "1/model.onnx"}}`,
+	} {
+		if _, err := Parse(modelKey); err == nil {
+			t.Errorf("Expected an error parsing ModelKey %s", modelKey)
+		}
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	modelKey := `{"schema_path": "my_schema", "disk_size_bytes": 1024, "labels": {"team": 1}, "download_concurrency": "4"}`
+
+	mk, err := ParseFields(modelKey, SchemaPathKey, DiskSizeBytesKey, ModelTypeKey)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing the fields of ModelKey %s: %v", modelKey, err)
+	}
+	if mk.SchemaPath == nil || *mk.SchemaPath != "my_schema" {
+		t.Errorf("Expected schema path my_schema but got %v", mk.SchemaPath)
+	}
+	if mk.DiskSizeBytes == nil || *mk.DiskSizeBytes != 1024 {
+		t.Errorf("Expected disk size 1024 but got %v", mk.DiskSizeBytes)
+	}
+	if mk.ModelType != nil {
+		t.Errorf("Expected no model type but got %v", mk.ModelType)
+	}
+
+	if _, err = ParseFields(modelKey, LabelsKey); err == nil {
+		t.Errorf("Expected an error parsing the labels of ModelKey %s", modelKey)
+	}
+	if _, err = ParseFields(`{"schema_path": `, SchemaPathKey); err == nil {
+		t.Error("Expected an error parsing invalid JSON")
+	}
+}
+
+func TestParseVersionPolicy(t *testing.T) {
+	mk, err := Parse(`{"version_policy": {"specific": {"versions": [1, 3]}}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing ModelKey: %v", err)
+	}
+
+	vp := mk.VersionPolicy
+	if vp == nil || vp.Specific == nil || vp.All != nil || vp.Latest != nil {
+		t.Fatalf("Expected a specific version policy but got %+v", vp)
+	}
+	if len(vp.Specific.Versions) != 2 || vp.Specific.Versions[0] != 1 || vp.Specific.Versions[1] != 3 {
+		t.Errorf("Expected versions [1 3] but got %v", vp.Specific.Versions)
+	}
+
+	out, err := json.Marshal(mk)
+	if err != nil {
+		t.Fatalf("Unexpected error marshalling ModelKey: %v", err)
+	}
+	expectedJSON := `{"version_policy":{"specific":{"versions":[1,3]}}}`
+	if string(out) != expectedJSON {
+		t.Errorf("Expected ModelKey JSON %s but got %s", expectedJSON, string(out))
+	}
+}
@@ -51,6 +51,7 @@ const (
 	FP16   string = "FP16"
 	FP32   string = "FP32"
 	FP64   string = "FP64"
+	BF16   string = "BF16"
 	// extensions to KFSv2 types
 	STRING string = "STRING"
 )
@@ -68,3 +69,32 @@ func NewFromFile(schemaFilename string) (*ModelSchema, error) {
 
 	return &schema, nil
 }
+
+// ValidateDatatypes checks that every tensor in the schema declares one of the
+// supported datatypes. Runtimes support different subsets of the datatypes, so
+// this reports an unsupported datatype before the schema is converted to the
+// runtime's configuration format.
+func (ms *ModelSchema) ValidateDatatypes(supportedDatatypes []string) error {
+	for _, input := range ms.Inputs {
+		if !isSupportedDatatype(input.Datatype, supportedDatatypes) {
+			return fmt.Errorf("Unsupported datatype '%s' for input tensor '%s' in model schema, supported datatypes are %v",
+				input.Datatype, input.Name, supportedDatatypes)
+		}
+	}
+	for _, output := range ms.Outputs {
+		if !isSupportedDatatype(output.Datatype, supportedDatatypes) {
+			return fmt.Errorf("Unsupported datatype '%s' for output tensor '%s' in model schema, supported datatypes are %v",
+				output.Datatype, output.Name, supportedDatatypes)
+		}
+	}
+	return nil
+}
+
+func isSupportedDatatype(datatype string, supportedDatatypes []string) bool {
+	for _, dt := range supportedDatatypes {
+		if datatype == dt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modelschema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDatatypes(t *testing.T) {
+	supported := []string{FP32, INT64}
+
+	testCases := []struct {
+		name          string
+		schema        ModelSchema
+		expectedError string
+	}{
+		{
+			name: "supported",
+			schema: ModelSchema{
+				Inputs:  []TensorMetadata{{Name: "in", Datatype: FP32, Shape: []int64{1}}},
+				Outputs: []TensorMetadata{{Name: "out", Datatype: INT64, Shape: []int64{1}}},
+			},
+		},
+		{
+			name: "unsupported-input",
+			schema: ModelSchema{
+				Inputs: []TensorMetadata{{Name: "in", Datatype: BF16, Shape: []int64{1}}},
+			},
+			expectedError: "'BF16' for input tensor 'in'",
+		},
+		{
+			name: "unsupported-output",
+			schema: ModelSchema{
+				Inputs:  []TensorMetadata{{Name: "in", Datatype: FP32, Shape: []int64{1}}},
+				Outputs: []TensorMetadata{{Name: "out", Datatype: STRING, Shape: []int64{1}}},
+			},
+			expectedError: "'STRING' for output tensor 'out'",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.schema.ValidateDatatypes(supported)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected an error containing [%s] but got none", tc.expectedError)
+			}
+			if !strings.Contains(err.Error(), tc.expectedError) {
+				t.Errorf("Expected an error containing [%s] but got: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
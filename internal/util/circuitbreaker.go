@@ -0,0 +1,132 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CircuitBreaker stops requests from reaching a runtime that keeps failing
+//
+// After Threshold consecutive failures are recorded the breaker opens and
+// Allow fails fast with Unavailable. While open, the runtime health is probed
+// every Cooldown and the breaker closes again once a probe succeeds.
+//
+// A nil CircuitBreaker, or one with a Threshold that is not positive, never
+// opens.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	probe     func(ctx context.Context) error
+	log       logr.Logger
+
+	mutex    sync.Mutex
+	failures int
+	open     bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration, probe func(ctx context.Context) error, log logr.Logger) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		probe:     probe,
+		log:       log.WithName("CircuitBreaker"),
+	}
+}
+
+// Allow returns an Unavailable error while the breaker is open
+func (b *CircuitBreaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.open {
+		return status.Errorf(codes.Unavailable, "Runtime is unavailable after %d consecutive failures, waiting for it to become healthy", b.failures)
+	}
+	return nil
+}
+
+// RecordSuccess resets the count of consecutive failures
+func (b *CircuitBreaker) RecordSuccess() {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.open {
+		b.failures = 0
+	}
+}
+
+// RecordFailure counts a failure and opens the breaker when the threshold is
+// reached
+func (b *CircuitBreaker) RecordFailure() {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	if b.open || b.failures < b.threshold {
+		return
+	}
+
+	b.open = true
+	b.log.Info("Opening circuit breaker, requests will fail until the runtime is healthy", "failures", b.failures, "probeInterval", b.cooldown)
+	go b.probeUntilHealthy()
+}
+
+// IsOpen returns true while requests are being failed fast
+func (b *CircuitBreaker) IsOpen() bool {
+	if b == nil {
+		return false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.open
+}
+
+func (b *CircuitBreaker) probeUntilHealthy() {
+	ticker := time.NewTicker(b.cooldown)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), b.cooldown)
+		err := b.probe(ctx)
+		cancel()
+		if err != nil {
+			b.log.V(1).Info("Runtime health probe failed, circuit breaker stays open", "error", err)
+			continue
+		}
+
+		b.mutex.Lock()
+		b.open = false
+		b.failures = 0
+		b.mutex.Unlock()
+		b.log.Info("Runtime health probe succeeded, closing circuit breaker")
+		return
+	}
+}
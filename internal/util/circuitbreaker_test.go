@@ -0,0 +1,85 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	probe := func(ctx context.Context) error {
+		if !healthy.Load() {
+			return errors.New("unhealthy")
+		}
+		return nil
+	}
+	b := NewCircuitBreaker(2, 10*time.Millisecond, probe, logr.Discard())
+
+	// a success in between resets the count
+	b.RecordFailure()
+	b.RecordSuccess()
+	b.RecordFailure()
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Expected the breaker to be closed, got: %v", err)
+	}
+
+	b.RecordFailure()
+	if err := b.Allow(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected the open breaker to fail with Unavailable, got: %v", err)
+	}
+
+	// the breaker stays open while the probe fails
+	time.Sleep(50 * time.Millisecond)
+	if !b.IsOpen() {
+		t.Fatal("Expected the breaker to stay open while the runtime is unhealthy")
+	}
+
+	healthy.Store(true)
+	deadline := time.Now().Add(2 * time.Second)
+	for b.IsOpen() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the breaker to close")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := b.Allow(); err != nil {
+		t.Errorf("Expected the breaker to be closed after recovery, got: %v", err)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	var nilBreaker *CircuitBreaker
+	nilBreaker.RecordFailure()
+	if err := nilBreaker.Allow(); err != nil {
+		t.Errorf("Expected a nil breaker to allow requests, got: %v", err)
+	}
+
+	b := NewCircuitBreaker(0, time.Second, nil, logr.Discard())
+	for i := 0; i < 10; i++ {
+		b.RecordFailure()
+	}
+	if err := b.Allow(); err != nil {
+		t.Errorf("Expected a breaker without a threshold to allow requests, got: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// GrpcCompressionServerOptions returns the options of an adapter gRPC server
+// that compress its responses with gzip if it is enabled, for the clients
+// that accept gzip
+//
+// The gzip compressor is registered by this package, so a request that a
+// client compressed is accepted and answered with a compressed response even
+// if the compression is disabled, which is the default behavior of gRPC.
+func GrpcCompressionServerOptions(enabled bool, log logr.Logger) []grpc.ServerOption {
+	if !enabled {
+		return nil
+	}
+	log.Info("gRPC gzip compression is enabled")
+	return []grpc.ServerOption{grpc.ChainUnaryInterceptor(compressResponse)}
+}
+
+// compressResponse compresses the response with gzip if the client accepts it
+func compressResponse(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if compressors, err := grpc.ClientSupportedCompressors(ctx); err == nil {
+		for _, compressor := range compressors {
+			if compressor == gzip.Name {
+				if err = grpc.SetSendCompressor(ctx, gzip.Name); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	}
+	return handler(ctx, req)
+}
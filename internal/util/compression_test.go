@@ -0,0 +1,122 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/stats"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+)
+
+// echoModelRuntime responds to a load with the size of the ModelKey
+type echoModelRuntime struct {
+	mmesh.UnimplementedModelRuntimeServer
+}
+
+func (echoModelRuntime) LoadModel(ctx context.Context, req *mmesh.LoadModelRequest) (*mmesh.LoadModelResponse, error) {
+	return &mmesh.LoadModelResponse{SizeInBytes: uint64(len(req.ModelKey))}, nil
+}
+
+// responseCompression records the compression of the responses that a
+// client receives
+type responseCompression struct {
+	mutex       sync.Mutex
+	compression string
+}
+
+func (r *responseCompression) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (r *responseCompression) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if header, ok := s.(*stats.InHeader); ok {
+		r.mutex.Lock()
+		r.compression = header.Compression
+		r.mutex.Unlock()
+	}
+}
+
+func (r *responseCompression) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (r *responseCompression) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestGrpcCompressionServerOptions(t *testing.T) {
+	modelKey := `{"storage_key": "` + strings.Repeat("a", 64*1024) + `"}`
+
+	testCases := []struct {
+		name                string
+		enabled             bool
+		compressRequest     bool
+		expectedCompression string
+	}{
+		{"disabled", false, false, ""},
+		// gRPC answers a compressed request with a compressed response
+		{"disabled with a compressed request", false, true, gzip.Name},
+		{"enabled", true, false, gzip.Name},
+		{"enabled with a compressed request", true, true, gzip.Name},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lis, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := grpc.NewServer(GrpcCompressionServerOptions(tc.enabled, logr.Discard())...)
+			mmesh.RegisterModelRuntimeServer(s, echoModelRuntime{})
+			go s.Serve(lis)
+			defer s.Stop()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			received := &responseCompression{}
+			conn, err := grpc.DialContext(ctx, lis.Addr().String(),
+				grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithStatsHandler(received))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			var opts []grpc.CallOption
+			if tc.compressRequest {
+				opts = append(opts, grpc.UseCompressor(gzip.Name))
+			}
+			resp, err := mmesh.NewModelRuntimeClient(conn).LoadModel(ctx, &mmesh.LoadModelRequest{ModelKey: modelKey}, opts...)
+			if err != nil {
+				t.Fatalf("Expected the load to succeed, got error: %v", err)
+			}
+			if resp.SizeInBytes != uint64(len(modelKey)) {
+				t.Errorf("Expected the server to receive the ModelKey of %d bytes, got %d", len(modelKey), resp.SizeInBytes)
+			}
+			received.mutex.Lock()
+			defer received.mutex.Unlock()
+			if received.compression != tc.expectedCompression {
+				t.Errorf("Expected the response compression to be '%s', got '%s'", tc.expectedCompression, received.compression)
+			}
+		})
+	}
+}
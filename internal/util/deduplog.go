@@ -0,0 +1,168 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// NewDedupLogger returns a logger that collapses identical messages that are
+// logged repeatedly, like the errors of retries during an outage
+//
+// The first occurrence of a message is logged right away. Occurrences within
+// window after it are only counted, and logged once at the end of the window
+// with the count in "repeated". This continues every window for as long as
+// the message keeps occurring. Messages are identical if they have the same
+// text and error, or "error" value for Info, regardless of their other
+// key/value pairs. Loggers derived with WithValues and WithName share the
+// window, but their messages are only identical if they were derived with the
+// same values and names, so that the errors of different models are counted
+// separately. If window is not positive, log is returned unchanged.
+func NewDedupLogger(log logr.Logger, window time.Duration) logr.Logger {
+	sink := log.GetSink()
+	if window <= 0 || sink == nil {
+		return log
+	}
+	// account for the frame of dedupSink when the caller is logged
+	if cd, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = cd.WithCallDepth(1)
+	}
+	return logr.New(&dedupSink{
+		sink: sink,
+		state: &dedupState{
+			window:  window,
+			entries: map[string]*dedupEntry{},
+		},
+	})
+}
+
+type dedupState struct {
+	window time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*dedupEntry
+}
+
+// dedupEntry is a message that was logged within the current window, with
+// the arguments of its latest occurrence
+type dedupEntry struct {
+	sink          logr.LogSink
+	scope         string
+	level         int
+	err           error
+	msg           string
+	keysAndValues []interface{}
+	isError       bool
+	repeated      int
+}
+
+type dedupSink struct {
+	sink  logr.LogSink
+	state *dedupState
+	// the names and values the logger was derived with
+	scope string
+}
+
+// Init is a no-op, the wrapped sink is already initialized
+func (d *dedupSink) Init(info logr.RuntimeInfo) {}
+
+func (d *dedupSink) Enabled(level int) bool {
+	return d.sink.Enabled(level)
+}
+
+func (d *dedupSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	d.log(&dedupEntry{sink: d.sink, scope: d.scope, level: level, msg: msg, keysAndValues: keysAndValues})
+}
+
+func (d *dedupSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	d.log(&dedupEntry{sink: d.sink, scope: d.scope, err: err, msg: msg, keysAndValues: keysAndValues, isError: true})
+}
+
+func (d *dedupSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	scope := d.scope + fmt.Sprintf("\x00%v", keysAndValues)
+	return &dedupSink{sink: d.sink.WithValues(keysAndValues...), state: d.state, scope: scope}
+}
+
+func (d *dedupSink) WithName(name string) logr.LogSink {
+	return &dedupSink{sink: d.sink.WithName(name), state: d.state, scope: d.scope + "\x00" + name}
+}
+
+func (d *dedupSink) log(e *dedupEntry) {
+	key := e.key()
+	s := d.state
+
+	s.mutex.Lock()
+	if prev, ok := s.entries[key]; ok {
+		e.repeated = prev.repeated + 1
+		s.entries[key] = e
+		s.mutex.Unlock()
+		return
+	}
+	s.entries[key] = e
+	time.AfterFunc(s.window, func() { s.summarize(key) })
+	s.mutex.Unlock()
+
+	e.write()
+}
+
+// summarize logs the occurrences of a message that were counted in the
+// window that just ended
+func (s *dedupState) summarize(key string) {
+	s.mutex.Lock()
+	e := s.entries[key]
+	if e.repeated == 0 {
+		// the message stopped, log its next occurrence right away
+		delete(s.entries, key)
+		s.mutex.Unlock()
+		return
+	}
+	summary := *e
+	summary.keysAndValues = append(append([]interface{}(nil), e.keysAndValues...), "repeated", e.repeated, "window", s.window)
+	e.repeated = 0
+	time.AfterFunc(s.window, func() { s.summarize(key) })
+	s.mutex.Unlock()
+
+	summary.write()
+}
+
+func (e *dedupEntry) key() string {
+	err := e.err
+	if !e.isError {
+		for i := 0; i+1 < len(e.keysAndValues); i += 2 {
+			if e.keysAndValues[i] == "error" {
+				if errValue, ok := e.keysAndValues[i+1].(error); ok {
+					err = errValue
+				}
+				break
+			}
+		}
+	}
+	if err == nil {
+		return e.scope + "\x01" + e.msg
+	}
+	return e.scope + "\x01" + e.msg + "\x00" + err.Error()
+}
+
+func (e *dedupEntry) write() {
+	if e.isError {
+		e.sink.Error(e.err, e.msg, e.keysAndValues...)
+	} else {
+		e.sink.Info(e.level, e.msg, e.keysAndValues...)
+	}
+}
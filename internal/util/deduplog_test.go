@@ -0,0 +1,147 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+)
+
+type capturedLog struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (c *capturedLog) logger() logr.Logger {
+	return funcr.New(func(prefix, args string) {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		c.lines = append(c.lines, args)
+	}, funcr.Options{})
+}
+
+func (c *capturedLog) get() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]string(nil), c.lines...)
+}
+
+func TestDedupLogger(t *testing.T) {
+	captured := &capturedLog{}
+	window := 100 * time.Millisecond
+	log := NewDedupLogger(captured.logger(), window)
+
+	err := errors.New("connection refused")
+	for i := 0; i < 10; i++ {
+		log.Error(err, "Failed to pull model from storage", "attempt", i)
+	}
+	// a different error is logged separately
+	log.Error(errors.New("access denied"), "Failed to pull model from storage")
+
+	lines := captured.get()
+	if len(lines) != 2 {
+		t.Fatalf("Expected the first occurrence of each error to be logged right away but got %v", lines)
+	}
+	if !strings.Contains(lines[0], "connection refused") || !strings.Contains(lines[0], `"attempt"=0`) {
+		t.Errorf("Expected the first occurrence of the error but got %s", lines[0])
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(captured.get()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	lines = captured.get()
+	if len(lines) != 3 {
+		t.Fatalf("Expected one summary at the end of the window but got %v", lines)
+	}
+	if !strings.Contains(lines[2], "connection refused") || !strings.Contains(lines[2], `"repeated"=9`) || !strings.Contains(lines[2], `"attempt"=9`) {
+		t.Errorf("Expected a summary of the 9 repeated errors with the latest values but got %s", lines[2])
+	}
+
+	// no summary is logged for a window without repeats, after which the
+	// error is logged right away again
+	time.Sleep(3 * window)
+	if lines = captured.get(); len(lines) != 3 {
+		t.Fatalf("Expected no more logs without repeated errors but got %v", lines)
+	}
+	log.Error(err, "Failed to pull model from storage")
+	if lines = captured.get(); len(lines) != 4 || strings.Contains(lines[3], "repeated") {
+		t.Errorf("Expected the error to be logged right away after it stopped but got %v", lines)
+	}
+}
+
+func TestDedupLoggerWithValues(t *testing.T) {
+	captured := &capturedLog{}
+	log := NewDedupLogger(captured.logger(), 100*time.Millisecond)
+
+	// the same error of different models is counted per model
+	err := errors.New("connection refused")
+	for i := 0; i < 3; i++ {
+		for _, modelID := range []string{"model-a", "model-b"} {
+			log.WithValues("model_id", modelID).Error(err, "Failed to pull model from storage")
+		}
+	}
+	lines := captured.get()
+	if len(lines) != 2 || !strings.Contains(lines[0], "model-a") || !strings.Contains(lines[1], "model-b") {
+		t.Fatalf("Expected the first occurrence of the error of each model to be logged but got %v", lines)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(captured.get()) < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	summaries := strings.Join(captured.get()[2:], "\n")
+	for _, modelID := range []string{"model-a", "model-b"} {
+		if !strings.Contains(summaries, `"model_id"="`+modelID+`"`) {
+			t.Errorf("Expected a summary of the errors of %s but got %s", modelID, summaries)
+		}
+	}
+	if strings.Count(summaries, `"repeated"=2`) != 2 {
+		t.Errorf("Expected 2 repeats in the summary of each model but got %s", summaries)
+	}
+}
+
+func TestDedupLoggerInfo(t *testing.T) {
+	captured := &capturedLog{}
+	log := NewDedupLogger(captured.logger(), time.Minute)
+
+	for i := 0; i < 3; i++ {
+		log.Info("Retrying the config reload", "error", errors.New("connection refused"), "backoff", i)
+	}
+	log.Info("Retrying the config reload", "error", errors.New("timeout"))
+	log.Info("Config reload succeeded")
+
+	if lines := captured.get(); len(lines) != 3 {
+		t.Errorf("Expected Info messages to be collapsed by their error but got %v", lines)
+	}
+}
+
+func TestDedupLoggerDisabled(t *testing.T) {
+	captured := &capturedLog{}
+	log := NewDedupLogger(captured.logger(), 0)
+
+	for i := 0; i < 3; i++ {
+		log.Error(errors.New("connection refused"), "Failed to pull model from storage")
+	}
+	if lines := captured.get(); len(lines) != 3 {
+		t.Errorf("Expected every error to be logged without a window but got %v", lines)
+	}
+}
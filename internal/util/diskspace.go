@@ -0,0 +1,53 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"math"
+	"syscall"
+)
+
+// DiskSpace is the free space of a filesystem
+type DiskSpace struct {
+	// bytes available to unprivileged users
+	FreeBytes uint64
+	// inodes that are not in use, which limit the number of files that can
+	// be created even when there are free bytes
+	FreeInodes uint64
+}
+
+// statfs is replaced in tests to fake a filesystem
+var statfs = syscall.Statfs
+
+// AvailableDiskSpace returns the free bytes and inodes of the filesystem of
+// the path
+//
+// Filesystems that allocate inodes dynamically, like btrfs, report no inodes
+// at all, in which case FreeInodes is math.MaxUint64.
+func AvailableDiskSpace(path string) (DiskSpace, error) {
+	var st syscall.Statfs_t
+	if err := statfs(path, &st); err != nil {
+		return DiskSpace{}, fmt.Errorf("Error getting the free space of the filesystem of %s: %w", path, err)
+	}
+	space := DiskSpace{
+		FreeBytes:  st.Bavail * uint64(st.Bsize),
+		FreeInodes: st.Ffree,
+	}
+	if st.Files == 0 {
+		space.FreeInodes = math.MaxUint64
+	}
+	return space, nil
+}
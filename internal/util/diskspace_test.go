@@ -0,0 +1,66 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"math"
+	"syscall"
+	"testing"
+)
+
+func TestAvailableDiskSpace(t *testing.T) {
+	originalStatfs := statfs
+	defer func() { statfs = originalStatfs }()
+
+	tests := []struct {
+		name     string
+		stat     syscall.Statfs_t
+		expected DiskSpace
+	}{
+		{
+			name:     "inodes",
+			stat:     syscall.Statfs_t{Bsize: 4096, Bavail: 1000, Files: 5000, Ffree: 12},
+			expected: DiskSpace{FreeBytes: 4096000, FreeInodes: 12},
+		},
+		{
+			name:     "dynamic inodes",
+			stat:     syscall.Statfs_t{Bsize: 4096, Bavail: 1000},
+			expected: DiskSpace{FreeBytes: 4096000, FreeInodes: math.MaxUint64},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statfs = func(path string, st *syscall.Statfs_t) error {
+				*st = tt.stat
+				return nil
+			}
+			space, err := AvailableDiskSpace("/models")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if space != tt.expected {
+				t.Errorf("Expected %+v but got %+v", tt.expected, space)
+			}
+		})
+	}
+
+	statfs = originalStatfs
+	if _, err := AvailableDiskSpace(t.TempDir()); err != nil {
+		t.Errorf("Unexpected error getting the free space of a temp dir: %v", err)
+	}
+	if _, err := AvailableDiskSpace("/does/not/exist"); err == nil {
+		t.Error("Expected an error for a path that does not exist")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileListing maps the paths of the files of a tree, relative to its root, to
+// their sizes; a root that is a single file is listed as "."
+type FileListing map[string]int64
+
+// ListFiles lists the regular files of the file or directory tree at root
+//
+// Symlinks are followed, so that a tree lists the same whether its files were
+// placed as symlinks or as copies of the files they point to. A symlink that
+// does not resolve is listed with a size of -1.
+func ListFiles(root string) (FileListing, error) {
+	listing := FileListing{}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	err := listFiles(root, ".", listing, map[string]bool{})
+	return listing, err
+}
+
+// listFiles adds the files of the tree at dir to the listing under prefix,
+// visited holds the resolved directories being listed to stop symlink cycles
+func listFiles(dir, prefix string, listing FileListing, visited map[string]bool) error {
+	if visited[dir] {
+		return fmt.Errorf("Symlink cycle at %s", dir)
+	}
+	visited[dir] = true
+	defer delete(visited, dir)
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.Join(prefix, rel)
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			resolved, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				listing[rel] = -1
+				return nil
+			}
+			if info, err = os.Stat(resolved); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return listFiles(resolved, rel, listing, visited)
+			}
+		}
+		listing[rel] = info.Size()
+		return nil
+	})
+}
+
+// DiffFileListings returns an error naming every file of the expected listing
+// that is missing from the actual one or has another size, and every file of
+// the actual listing that is not expected
+func DiffFileListings(expected, actual FileListing) error {
+	var missing, mismatched, unexpected []string
+	for path, size := range expected {
+		actualSize, ok := actual[path]
+		switch {
+		case !ok:
+			missing = append(missing, fmt.Sprintf("%s (%d bytes)", path, size))
+		case actualSize != size:
+			mismatched = append(mismatched, fmt.Sprintf("%s (expected %d bytes, found %d bytes)", path, size, actualSize))
+		}
+	}
+	for path, size := range actual {
+		if _, ok := expected[path]; !ok {
+			unexpected = append(unexpected, fmt.Sprintf("%s (%d bytes)", path, size))
+		}
+	}
+	if len(missing) == 0 && len(mismatched) == 0 && len(unexpected) == 0 {
+		return nil
+	}
+
+	var problems []string
+	for _, p := range []struct {
+		kind  string
+		files []string
+	}{{"missing", missing}, {"size mismatch", mismatched}, {"unexpected", unexpected}} {
+		if len(p.files) > 0 {
+			sort.Strings(p.files)
+			problems = append(problems, fmt.Sprintf("%s: %s", p.kind, strings.Join(p.files, ", ")))
+		}
+	}
+	return fmt.Errorf("%d of %d files differ, %s", len(missing)+len(mismatched)+len(unexpected), len(expected), strings.Join(problems, "; "))
+}
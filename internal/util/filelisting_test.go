@@ -0,0 +1,85 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestListFiles(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "model")
+	if err := os.MkdirAll(filepath.Join(source, "1", "variables"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "1", "model.pb"), []byte("graph"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "1", "variables", "data"), []byte("weights"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("1", filepath.Join(source, "latest")); err != nil {
+		t.Fatal(err)
+	}
+	expected := FileListing{
+		"1/model.pb":            5,
+		"1/variables/data":      7,
+		"latest/model.pb":       5,
+		"latest/variables/data": 7,
+	}
+
+	listing, err := ListFiles(source)
+	if err != nil {
+		t.Fatalf("ListFiles failed: %v", err)
+	}
+	if !reflect.DeepEqual(expected, listing) {
+		t.Errorf("Expected the listing %v, got %v", expected, listing)
+	}
+
+	// a copy that dereferences the symlinks lists the same
+	copied := filepath.Join(t.TempDir(), "copied")
+	if err = PlaceFile(source, source, copied, FilePlacementCopy, SymlinkPolicyDereference); err != nil {
+		t.Fatal(err)
+	}
+	if listing, err = ListFiles(copied); err != nil || !reflect.DeepEqual(expected, listing) {
+		t.Errorf("Expected the copy to list %v, got %v: %v", expected, listing, err)
+	}
+
+	if listing, err = ListFiles(filepath.Join(source, "latest", "model.pb")); err != nil || !reflect.DeepEqual(FileListing{".": 5}, listing) {
+		t.Errorf("Expected a single file to be listed as '.', got %v: %v", listing, err)
+	}
+
+	if err = os.Symlink("..", filepath.Join(source, "1", "parent")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ListFiles(source); err == nil {
+		t.Error("Expected a symlink cycle to fail the listing")
+	}
+}
+
+func TestDiffFileListings(t *testing.T) {
+	expected := FileListing{"a": 1, "b": 2, "c": 3}
+	if err := DiffFileListings(expected, FileListing{"a": 1, "b": 2, "c": 3}); err != nil {
+		t.Errorf("Expected equal listings not to differ, got: %v", err)
+	}
+
+	err := DiffFileListings(expected, FileListing{"a": 1, "c": 30, "d": 4})
+	expectedMessage := "3 of 3 files differ, missing: b (2 bytes); size mismatch: c (expected 3 bytes, found 30 bytes); unexpected: d (4 bytes)"
+	if err == nil || err.Error() != expectedMessage {
+		t.Errorf("Expected the error '%s', got: %v", expectedMessage, err)
+	}
+}
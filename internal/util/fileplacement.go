@@ -0,0 +1,278 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// FilePlacement is how the files downloaded by the puller are placed in the
+// model repository of a runtime
+type FilePlacement string
+
+const (
+	// symlink to the downloaded files
+	FilePlacementLink FilePlacement = "link"
+	// copy the downloaded files, leaving them in place
+	FilePlacementCopy FilePlacement = "copy"
+	// rename the downloaded files, saving the I/O of a copy when the
+	// puller's files are not needed anymore once loaded
+	FilePlacementMove FilePlacement = "move"
+)
+
+func ParseFilePlacement(placement string) (FilePlacement, error) {
+	switch p := FilePlacement(placement); p {
+	case FilePlacementLink, FilePlacementCopy, FilePlacementMove:
+		return p, nil
+	}
+	return "", fmt.Errorf("Unknown file placement '%s', expected one of %s, %s or %s", placement, FilePlacementLink, FilePlacementCopy, FilePlacementMove)
+}
+
+// SymlinkPolicy is how the symlinks within the files downloaded by the puller,
+// eg. from an archive or a PVC, are handled when they are placed
+type SymlinkPolicy string
+
+const (
+	// keep the symlinks as they are, wherever they point to
+	SymlinkPolicyPreserve SymlinkPolicy = "preserve"
+	// copy the files that the symlinks point to, which must be within the
+	// model files; a symlink that escapes them fails the placement
+	SymlinkPolicyDereference SymlinkPolicy = "dereference"
+	// fail the placement if there is any symlink
+	SymlinkPolicyReject SymlinkPolicy = "reject"
+)
+
+func ParseSymlinkPolicy(policy string) (SymlinkPolicy, error) {
+	switch p := SymlinkPolicy(policy); p {
+	case SymlinkPolicyPreserve, SymlinkPolicyDereference, SymlinkPolicyReject:
+		return p, nil
+	}
+	return "", fmt.Errorf("Unknown symlink policy '%s', expected one of %s, %s or %s", policy, SymlinkPolicyPreserve, SymlinkPolicyDereference, SymlinkPolicyReject)
+}
+
+// PlaceFile places the file or directory at source at the target path
+//
+// The source is root, the ModelPath of the model, or a file or directory in
+// it. The source itself may be a symlink, which is resolved. The symlinks
+// within it are handled per the symlinks policy: a copy dereferences them,
+// while a link or a move keeps them and only checks that they are allowed.
+// They may point anywhere within root, eg. from one placed file of the model
+// to another.
+//
+// A move that fails because the target is on another filesystem, eg. when the
+// model files are in a bind mount, falls back to a copy and then removes the
+// source. A move of a source with a symlink to elsewhere in root, which would
+// not resolve anymore once moved, copies instead and leaves the source in
+// place.
+//
+// A copy that fails removes what it copied to the target, so that the
+// placement can be retried.
+func PlaceFile(root, source, target string, placement FilePlacement, symlinks SymlinkPolicy) error {
+	switch placement {
+	case FilePlacementCopy:
+		return copyPathOrRemove(root, source, target, symlinks)
+	case FilePlacementMove:
+		escapes, err := checkSymlinks(root, source, symlinks)
+		if err != nil {
+			return err
+		}
+		if escapes {
+			return copyPathOrRemove(root, source, target, symlinks)
+		}
+		err = renameFile(source, target)
+		if errors.Is(err, syscall.EXDEV) {
+			if err = copyPathOrRemove(root, source, target, symlinks); err != nil {
+				return err
+			}
+			if err = os.RemoveAll(source); err != nil {
+				return fmt.Errorf("Error removing %s after copying it to another filesystem: %w", source, err)
+			}
+		}
+		return err
+	default:
+		if _, err := checkSymlinks(root, source, symlinks); err != nil {
+			return err
+		}
+		return os.Symlink(source, target)
+	}
+}
+
+// renameFile moves a file or directory, replaced in tests
+var renameFile = os.Rename
+
+// checkSymlinks fails if the tree at source has a symlink that the policy
+// does not allow within root, and returns whether a symlink points outside of
+// the source itself
+func checkSymlinks(root, source string, symlinks SymlinkPolicy) (bool, error) {
+	if symlinks == SymlinkPolicyPreserve {
+		return false, nil
+	}
+	if resolved, err := filepath.EvalSymlinks(source); err == nil {
+		source = resolved
+	}
+	escapes := false
+	err := filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+		if symlinks == SymlinkPolicyReject {
+			return fmt.Errorf("%s is a symlink, which the symlink policy %s does not allow", path, symlinks)
+		}
+		resolved, err := resolveSymlinkWithin(root, path)
+		if err != nil {
+			return err
+		}
+		escapes = escapes || !isWithin(source, resolved)
+		return nil
+	})
+	return escapes, err
+}
+
+// resolveSymlinkWithin resolves the symlink at path, which must point to a
+// file or directory within root
+func resolveSymlinkWithin(root, path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("Error resolving symlink %s: %w", path, err)
+	}
+	if !isWithin(root, resolved) {
+		return "", fmt.Errorf("Symlink %s points to %s, which is outside of %s", path, resolved, root)
+	}
+	return resolved, nil
+}
+
+// isWithin returns true if the path is the root or a path below it
+//
+// Both are compared with their symlinks resolved, so that a root that is a
+// symlink, eg. to a mounted volume, contains the paths it resolves to. A path
+// that cannot be resolved is compared as it is.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(evalSymlinksOrSelf(root), evalSymlinksOrSelf(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// evalSymlinksOrSelf returns the path with its symlinks resolved, or the path
+// itself if it cannot be resolved
+func evalSymlinksOrSelf(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// copyPathOrRemove copies like copyPath and removes the partial copy if the
+// copy fails, unless the target existed before
+func copyPathOrRemove(root, source, target string, symlinks SymlinkPolicy) error {
+	_, statErr := os.Lstat(target)
+	err := copyPath(root, source, target, symlinks)
+	if err != nil && os.IsNotExist(statErr) {
+		if rmErr := os.RemoveAll(target); rmErr != nil {
+			return fmt.Errorf("%w (and failed to remove the partial copy: %v)", err, rmErr)
+		}
+	}
+	return err
+}
+
+// copyPath copies a file or a directory tree in root, handling the symlinks
+// in it per the symlinks policy
+func copyPath(root, source, target string, symlinks SymlinkPolicy) error {
+	if resolved, err := filepath.EvalSymlinks(source); err == nil {
+		source = resolved
+	}
+	return copyTree(root, source, target, symlinks)
+}
+
+// copyTree copies the tree at dir to target, where root is the ModelPath
+// within which symlinks may be dereferenced
+func copyTree(root, dir, target string, symlinks SymlinkPolicy) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(dest, info.Mode().Perm())
+		case info.Mode()&fs.ModeSymlink != 0:
+			return copySymlink(root, path, dest, symlinks)
+		default:
+			return copyFile(path, dest, info.Mode().Perm())
+		}
+	})
+}
+
+func copySymlink(root, path, dest string, symlinks SymlinkPolicy) error {
+	switch symlinks {
+	case SymlinkPolicyPreserve:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, dest)
+	case SymlinkPolicyReject:
+		return fmt.Errorf("%s is a symlink, which the symlink policy %s does not allow", path, symlinks)
+	}
+
+	resolved, err := resolveSymlinkWithin(root, path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return copyFile(resolved, dest, info.Mode().Perm())
+	}
+	// a symlink to a directory that contains it would be copied endlessly
+	if isWithin(resolved, filepath.Dir(path)) {
+		return fmt.Errorf("Symlink %s points to %s, which contains it", path, resolved)
+	}
+	return copyTree(root, resolved, dest, symlinks)
+}
+
+func copyFile(source, target string, perm fs.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
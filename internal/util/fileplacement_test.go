@@ -0,0 +1,290 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestPlaceFile(t *testing.T) {
+	for _, placement := range []FilePlacement{FilePlacementLink, FilePlacementCopy, FilePlacementMove} {
+		t.Run(string(placement), func(t *testing.T) {
+			source := filepath.Join(t.TempDir(), "model")
+			if err := os.MkdirAll(filepath.Join(source, "1"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(source, "1", "model.onnx"), []byte("weights"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink("1", filepath.Join(source, "latest")); err != nil {
+				t.Fatal(err)
+			}
+			target := filepath.Join(t.TempDir(), "placed")
+
+			if err := PlaceFile(source, source, target, placement, SymlinkPolicyDereference); err != nil {
+				t.Fatalf("PlaceFile failed: %v", err)
+			}
+
+			if contents, err := os.ReadFile(filepath.Join(target, "latest", "model.onnx")); err != nil || string(contents) != "weights" {
+				t.Errorf("Expected the placed model file to contain 'weights', got '%s': %v", contents, err)
+			}
+			info, err := os.Lstat(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isLink := info.Mode()&os.ModeSymlink != 0; isLink != (placement == FilePlacementLink) {
+				t.Errorf("Expected the target to be a symlink only when linked, got mode %v", info.Mode())
+			}
+
+			exists, err := FileExists(filepath.Join(source, "1", "model.onnx"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := placement != FilePlacementMove; exists != expected {
+				t.Errorf("Expected the source model file to exist: %v, but it exists: %v", expected, exists)
+			}
+		})
+	}
+}
+
+func TestPlaceFileSymlinkPolicy(t *testing.T) {
+	// a model with a symlink within it and a symlink that escapes it
+	newSource := func(t *testing.T, escaping bool) string {
+		dir := t.TempDir()
+		source := filepath.Join(dir, "model")
+		if err := os.MkdirAll(filepath.Join(source, "1"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(source, "1", "model.onnx"), []byte("weights"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if !escaping {
+			if err := os.Symlink("1", filepath.Join(source, "latest")); err != nil {
+				t.Fatal(err)
+			}
+			return source
+		}
+		if err := os.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join("..", "secret"), filepath.Join(source, "labels.txt")); err != nil {
+			t.Fatal(err)
+		}
+		return source
+	}
+
+	tests := []struct {
+		name        string
+		escaping    bool
+		placement   FilePlacement
+		symlinks    SymlinkPolicy
+		expectError bool
+		// the placed file that is expected to be a symlink, if any
+		expectLink string
+	}{
+		{name: "within dereferenced", placement: FilePlacementCopy, symlinks: SymlinkPolicyDereference},
+		{name: "within preserved", placement: FilePlacementCopy, symlinks: SymlinkPolicyPreserve, expectLink: "latest"},
+		{name: "within rejected", placement: FilePlacementCopy, symlinks: SymlinkPolicyReject, expectError: true},
+		{name: "within linked", placement: FilePlacementLink, symlinks: SymlinkPolicyDereference},
+		{name: "escaping dereferenced", escaping: true, placement: FilePlacementCopy, symlinks: SymlinkPolicyDereference, expectError: true},
+		{name: "escaping linked", escaping: true, placement: FilePlacementLink, symlinks: SymlinkPolicyDereference, expectError: true},
+		{name: "escaping moved", escaping: true, placement: FilePlacementMove, symlinks: SymlinkPolicyDereference, expectError: true},
+		{name: "escaping preserved", escaping: true, placement: FilePlacementCopy, symlinks: SymlinkPolicyPreserve, expectLink: "labels.txt"},
+		{name: "escaping rejected", escaping: true, placement: FilePlacementCopy, symlinks: SymlinkPolicyReject, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := newSource(t, tt.escaping)
+			target := filepath.Join(t.TempDir(), "placed")
+
+			err := PlaceFile(source, source, target, tt.placement, tt.symlinks)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected PlaceFile to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PlaceFile failed: %v", err)
+			}
+
+			if !tt.escaping {
+				if contents, err := os.ReadFile(filepath.Join(target, "latest", "model.onnx")); err != nil || string(contents) != "weights" {
+					t.Errorf("Expected the placed model file to contain 'weights', got '%s': %v", contents, err)
+				}
+			}
+			if tt.placement != FilePlacementCopy {
+				return
+			}
+			for _, name := range []string{"latest", "labels.txt"} {
+				info, err := os.Lstat(filepath.Join(target, name))
+				if os.IsNotExist(err) {
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if isLink := info.Mode()&os.ModeSymlink != 0; isLink != (name == tt.expectLink) {
+					t.Errorf("Expected %s to be a symlink: %v, got mode %v", name, name == tt.expectLink, info.Mode())
+				}
+			}
+		})
+	}
+}
+
+func TestPlaceFileRetryAfterFailedCopy(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "model")
+	if err := os.MkdirAll(filepath.Join(source, "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "1", "model.onnx"), []byte("weights"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("1", filepath.Join(source, "latest")); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(t.TempDir(), "placed")
+
+	// the symlink fails the copy after the model file was copied
+	if err := PlaceFile(source, source, target, FilePlacementCopy, SymlinkPolicyReject); err == nil {
+		t.Fatal("Expected PlaceFile to fail")
+	}
+	if _, err := os.Lstat(target); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial copy to be removed, got: %v", err)
+	}
+
+	if err := PlaceFile(source, source, target, FilePlacementCopy, SymlinkPolicyDereference); err != nil {
+		t.Fatalf("Retried PlaceFile failed: %v", err)
+	}
+	if contents, err := os.ReadFile(filepath.Join(target, "latest", "model.onnx")); err != nil || string(contents) != "weights" {
+		t.Errorf("Expected the placed model file to contain 'weights', got '%s': %v", contents, err)
+	}
+}
+
+func TestPlaceFileMoveAcrossFilesystems(t *testing.T) {
+	originalRenameFile := renameFile
+	defer func() { renameFile = originalRenameFile }()
+	renameFile = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+
+	source := filepath.Join(t.TempDir(), "model")
+	if err := os.MkdirAll(filepath.Join(source, "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "1", "model.onnx"), []byte("weights"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(t.TempDir(), "placed")
+
+	if err := PlaceFile(source, source, target, FilePlacementMove, SymlinkPolicyDereference); err != nil {
+		t.Fatalf("PlaceFile failed: %v", err)
+	}
+	if contents, err := os.ReadFile(filepath.Join(target, "1", "model.onnx")); err != nil || string(contents) != "weights" {
+		t.Errorf("Expected the placed model file to contain 'weights', got '%s': %v", contents, err)
+	}
+	// the copy is removed from the source, like a move
+	if _, err := os.Lstat(source); !os.IsNotExist(err) {
+		t.Errorf("Expected the source to be removed, got: %v", err)
+	}
+}
+
+func TestPlaceFileSymlinkWithinModelPath(t *testing.T) {
+	// a model whose config symlinks to a file next to it, placed entry by
+	// entry like the MLServer adapter does
+	root := filepath.Join(t.TempDir(), "model")
+	if err := os.MkdirAll(filepath.Join(root, "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "labels.txt"), []byte("labels"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "labels.txt"), filepath.Join(root, "1", "labels.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, placement := range []FilePlacement{FilePlacementLink, FilePlacementCopy, FilePlacementMove} {
+		t.Run(string(placement), func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "1")
+			// the symlink escapes the placed entry, but not the model
+			if err := PlaceFile(root, filepath.Join(root, "1"), target, placement, SymlinkPolicyDereference); err != nil {
+				t.Fatalf("PlaceFile failed: %v", err)
+			}
+			if contents, err := os.ReadFile(filepath.Join(target, "labels.txt")); err != nil || string(contents) != "labels" {
+				t.Errorf("Expected the placed labels to contain 'labels', got '%s': %v", contents, err)
+			}
+			// a move copies instead, since the moved symlink would not resolve
+			if _, err := os.Lstat(filepath.Join(root, "1", "labels.txt")); err != nil {
+				t.Errorf("Expected the source to be left in place: %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveSymlinkWithinSymlinkedRoot(t *testing.T) {
+	// the root model dir is a symlink to the mounted volume it is on
+	dir := t.TempDir()
+	mount := filepath.Join(dir, "mnt")
+	if err := os.MkdirAll(filepath.Join(mount, "model", "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "models")
+	if err := os.Symlink(mount, root); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("1", filepath.Join(root, "model", "latest")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "..", "secret"), filepath.Join(root, "model", "labels.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := resolveSymlinkWithin(root, filepath.Join(root, "model", "latest")); err != nil {
+		t.Errorf("Expected a symlink within the symlinked root to resolve, got: %v", err)
+	}
+	if _, err := resolveSymlinkWithin(root, filepath.Join(root, "model", "labels.txt")); err == nil {
+		t.Error("Expected a symlink that escapes the symlinked root to fail to resolve")
+	}
+	if !isWithin(mount, filepath.Join(root, "model")) {
+		t.Error("Expected a path under the symlinked root to be within the directory it points to")
+	}
+	if isWithin(root, filepath.Join(dir, "secret")) {
+		t.Error("Expected a path outside of the directory the symlinked root points to not to be within it")
+	}
+}
+
+func TestParseFilePlacement(t *testing.T) {
+	if p, err := ParseFilePlacement("move"); err != nil || p != FilePlacementMove {
+		t.Errorf("Expected 'move' to parse, got %v: %v", p, err)
+	}
+	if _, err := ParseFilePlacement("hardlink"); err == nil {
+		t.Error("Expected an unknown file placement to fail to parse")
+	}
+}
+
+func TestParseSymlinkPolicy(t *testing.T) {
+	if p, err := ParseSymlinkPolicy("reject"); err != nil || p != SymlinkPolicyReject {
+		t.Errorf("Expected 'reject' to parse, got %v: %v", p, err)
+	}
+	if _, err := ParseSymlinkPolicy("follow"); err == nil {
+		t.Error("Expected an unknown symlink policy to fail to parse")
+	}
+}
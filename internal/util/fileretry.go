@@ -0,0 +1,71 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// errors of filesystem operations that may succeed when retried, for example
+// on a networked filesystem that is briefly unavailable or full
+var transientFileErrors = []error{
+	syscall.EIO,
+	syscall.ENOSPC,
+	syscall.EAGAIN,
+	syscall.EBUSY,
+	syscall.ESTALE,
+	syscall.ETIMEDOUT,
+}
+
+// IsTransientFileError returns true if the error of a filesystem operation
+// may go away when the operation is retried
+//
+// Permission errors are never transient.
+func IsTransientFileError(err error) bool {
+	if err == nil || errors.Is(err, syscall.EACCES) || errors.Is(err, syscall.EPERM) {
+		return false
+	}
+	for _, transient := range transientFileErrors {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryTransientFileErrors calls op and retries it up to retries times while
+// it fails with a transient filesystem error, see IsTransientFileError
+//
+// The first retry waits for backoff, which doubles for each following retry.
+// op must be safe to call again after it failed part way through.
+func RetryTransientFileErrors(ctx context.Context, retries int, backoff time.Duration, log logr.Logger, op func() error) error {
+	err := op()
+	for attempt := 1; attempt <= retries && IsTransientFileError(err); attempt++ {
+		log.Info("Retrying after transient filesystem error", "error", err, "attempt", attempt, "retries", retries, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		err = op()
+	}
+	return err
+}
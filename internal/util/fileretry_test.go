@@ -0,0 +1,102 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestIsTransientFileError(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{&os.PathError{Op: "write", Path: "model.onnx", Err: syscall.EIO}, true},
+		{fmt.Errorf("Error writing config file: %w", &os.PathError{Op: "write", Path: "config.pbtxt", Err: syscall.ENOSPC}), true},
+		{&os.LinkError{Op: "symlink", Old: "a", New: "b", Err: syscall.ESTALE}, true},
+		{&os.PathError{Op: "open", Path: "model.onnx", Err: syscall.EACCES}, false},
+		{&os.PathError{Op: "mkdir", Path: "models", Err: syscall.EPERM}, false},
+		{&os.PathError{Op: "stat", Path: "model.onnx", Err: syscall.ENOENT}, false},
+		{errors.New("Invalid model"), false},
+	}
+	for _, tc := range testCases {
+		if got := IsTransientFileError(tc.err); got != tc.expected {
+			t.Errorf("IsTransientFileError(%v) = %v; expected %v", tc.err, got, tc.expected)
+		}
+	}
+}
+
+func TestRetryTransientFileErrors(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "config.pbtxt")
+
+	// the first write fails like a full networked filesystem would
+	attempts := 0
+	write := func() error {
+		attempts++
+		if attempts == 1 {
+			return fmt.Errorf("Error writing config file %s: %w", target, &os.PathError{Op: "write", Path: target, Err: syscall.EIO})
+		}
+		return os.WriteFile(target, []byte("backend: \"onnxruntime\""), 0644)
+	}
+
+	if err := RetryTransientFileErrors(context.Background(), 3, time.Millisecond, logr.Discard(), write); err != nil {
+		t.Fatalf("Expected the write to succeed on retry but got: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts but got %d", attempts)
+	}
+	if contents, err := os.ReadFile(target); err != nil || string(contents) != "backend: \"onnxruntime\"" {
+		t.Errorf("Expected the file to be written after the retry, got %q and error %v", contents, err)
+	}
+}
+
+func TestRetryTransientFileErrors_GiveUp(t *testing.T) {
+	transientErr := &os.PathError{Op: "write", Path: "model.onnx", Err: syscall.ENOSPC}
+	attempts := 0
+	err := RetryTransientFileErrors(context.Background(), 2, time.Millisecond, logr.Discard(), func() error {
+		attempts++
+		return transientErr
+	})
+	if !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("Expected the last transient error but got: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts but got %d", attempts)
+	}
+}
+
+func TestRetryTransientFileErrors_NoRetryOnPermissionError(t *testing.T) {
+	attempts := 0
+	err := RetryTransientFileErrors(context.Background(), 3, time.Millisecond, logr.Discard(), func() error {
+		attempts++
+		return &os.PathError{Op: "open", Path: "model.onnx", Err: syscall.EACCES}
+	})
+	if !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Expected the permission error but got: %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Expected a permission error not to be retried but got %d attempts", attempts)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"syscall"
 )
 
 // RemoveFileFromListOfFileInfo
@@ -71,3 +72,45 @@ func ClearDirectoryContents(dirPath string, condition func(entry os.DirEntry) bo
 	}
 	return nil
 }
+
+// RemoveGeneratedDir removes a directory of files generated by an adapter in
+// the rootDir, like on a clean shutdown
+//
+// If the rootDir is a mount point, it may be a persistent volume shared with
+// other pods, so the directory is only removed if allowMountedRoot is set and
+// an error is returned otherwise. A missing directory is ignored.
+func RemoveGeneratedDir(rootDir string, dirPath string, allowMountedRoot bool) error {
+	if _, err := os.Stat(dirPath); errors.Is(err, os.ErrNotExist) {
+		return nil // ok
+	}
+	mounted, err := isMountPoint(rootDir)
+	if err != nil {
+		return fmt.Errorf("Error checking if the model root dir %s is a mount point: %w", rootDir, err)
+	}
+	if mounted && !allowMountedRoot {
+		return fmt.Errorf("Refusing to remove the generated dir %s because the model root dir %s is a mount point", dirPath, rootDir)
+	}
+	if err = os.RemoveAll(dirPath); err != nil {
+		return fmt.Errorf("Error removing the generated dir %s: %w", dirPath, err)
+	}
+	return nil
+}
+
+// isMountPoint returns true if the directory is on another device than its
+// parent directory
+func isMountPoint(dirPath string) (bool, error) {
+	dirInfo, err := os.Stat(dirPath)
+	if err != nil {
+		return false, err
+	}
+	parentInfo, err := os.Stat(filepath.Dir(filepath.Clean(dirPath)))
+	if err != nil {
+		return false, err
+	}
+	dirStat, ok1 := dirInfo.Sys().(*syscall.Stat_t)
+	parentStat, ok2 := parentInfo.Sys().(*syscall.Stat_t)
+	if !ok1 || !ok2 {
+		return false, fmt.Errorf("device of %s is unknown", dirPath)
+	}
+	return dirStat.Dev != parentStat.Dev, nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveGeneratedDir(t *testing.T) {
+	rootDir := t.TempDir()
+	generatedDir := filepath.Join(rootDir, "_generated")
+	if err := os.MkdirAll(filepath.Join(generatedDir, "model"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveGeneratedDir(rootDir, generatedDir, false); err != nil {
+		t.Fatalf("Expected the generated dir to be removed, got: %v", err)
+	}
+	if _, err := os.Stat(generatedDir); !os.IsNotExist(err) {
+		t.Errorf("Expected the generated dir to be removed, got: %v", err)
+	}
+
+	// a missing dir is ignored
+	if err := RemoveGeneratedDir(rootDir, generatedDir, false); err != nil {
+		t.Errorf("Expected a missing generated dir to be ignored, got: %v", err)
+	}
+}
+
+func TestRemoveGeneratedDirMountedRoot(t *testing.T) {
+	// /proc is a mount point on Linux, its files are never removed
+	if mounted, err := isMountPoint("/proc"); err != nil || !mounted {
+		t.Skip("/proc is not a mount point")
+	}
+
+	if err := RemoveGeneratedDir("/proc", "/proc/self", false); err == nil {
+		t.Errorf("Expected the generated dir in a mounted root dir to be kept")
+	}
+}
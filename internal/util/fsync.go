@@ -0,0 +1,92 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FsyncPolicy is when files that are replaced with WriteFileAtomic are
+// flushed to disk, trading the durability of the write across a power loss
+// for the time spent waiting on the disk
+type FsyncPolicy string
+
+const (
+	// sync the file before it replaces the previous one, and the directory
+	// after, so that either the previous or the new file survives a crash
+	FsyncAlways FsyncPolicy = "always"
+	// only sync the directory, the rename is durable but the contents of the
+	// file may not be
+	FsyncDirOnly FsyncPolicy = "dir-only"
+	// leave flushing to the operating system
+	FsyncNever FsyncPolicy = "never"
+)
+
+func ParseFsyncPolicy(policy string) (FsyncPolicy, error) {
+	switch p := FsyncPolicy(policy); p {
+	case FsyncAlways, FsyncDirOnly, FsyncNever:
+		return p, nil
+	}
+	return "", fmt.Errorf("Unknown fsync policy '%s', expected one of %s, %s or %s", policy, FsyncAlways, FsyncDirOnly, FsyncNever)
+}
+
+// syncFile flushes a file or directory to disk, replaced in tests
+var syncFile = func(f *os.File) error {
+	return f.Sync()
+}
+
+// WriteFileAtomic replaces the file in one step, so that readers never see a
+// partially written file and the previous file stays in place on errors
+func WriteFileAtomic(filename string, data []byte, perm fs.FileMode, policy FsyncPolicy) error {
+	tempFilename := filename + ".tmp"
+	if err := writeTempFile(tempFilename, data, perm, policy == FsyncAlways); err != nil {
+		os.Remove(tempFilename)
+		return err
+	}
+	if err := os.Rename(tempFilename, filename); err != nil {
+		os.Remove(tempFilename)
+		return err
+	}
+	if policy == FsyncNever {
+		return nil
+	}
+	return syncDir(filepath.Dir(filename))
+}
+
+func writeTempFile(filename string, data []byte, perm fs.FileMode, sync bool) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil && sync {
+		err = syncFile(f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return syncFile(d)
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteFileAtomicFsyncPolicy(t *testing.T) {
+	originalSyncFile := syncFile
+	defer func() { syncFile = originalSyncFile }()
+
+	tests := []struct {
+		policy FsyncPolicy
+		synced []string
+	}{
+		{FsyncAlways, []string{"config.json.tmp", "."}},
+		{FsyncDirOnly, []string{"."}},
+		{FsyncNever, nil},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			dir := t.TempDir()
+			var synced []string
+			syncFile = func(f *os.File) error {
+				rel, err := filepath.Rel(dir, f.Name())
+				if err != nil {
+					t.Fatal(err)
+				}
+				synced = append(synced, rel)
+				return nil
+			}
+
+			filename := filepath.Join(dir, "config.json")
+			if err := os.WriteFile(filename, []byte("old"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := WriteFileAtomic(filename, []byte("new"), 0644, tt.policy); err != nil {
+				t.Fatalf("WriteFileAtomic failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(synced, tt.synced) {
+				t.Errorf("Expected %v to be synced, got %v", tt.synced, synced)
+			}
+			if data, err := os.ReadFile(filename); err != nil || string(data) != "new" {
+				t.Errorf("Expected the file to be replaced, got %q, %v", data, err)
+			}
+			if _, err := os.Stat(filename + ".tmp"); !os.IsNotExist(err) {
+				t.Errorf("Expected the temporary file to be removed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseFsyncPolicy(t *testing.T) {
+	if p, err := ParseFsyncPolicy("dir-only"); err != nil || p != FsyncDirOnly {
+		t.Errorf("Expected dir-only, got %q, %v", p, err)
+	}
+	if _, err := ParseFsyncPolicy("sometimes"); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
@@ -16,38 +16,80 @@ package util
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/go-logr/logr"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/modelkey"
 	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-const (
-	modelTypeJSONKey     string = "model_type"
-	schemaPathJSONKey    string = "schema_path"
-	diskSizeBytesJSONKey string = "disk_size_bytes"
-)
+// ValidateModelKey returns an InvalidArgument error if the LoadModelRequest
+// has a ModelKey that cannot be parsed, instead of the fallbacks that are
+// used by GetModelType and the other helpers
+//
+// If the ModelKey is not valid JSON, the error includes the position of the
+// syntax error. An empty ModelKey is valid.
+func ValidateModelKey(req *mmesh.LoadModelRequest) error {
+	if strings.TrimSpace(req.ModelKey) == "" {
+		return nil
+	}
+	if _, err := modelkey.Parse(req.ModelKey); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, column := jsonPosition(req.ModelKey, syntaxErr.Offset)
+			return status.Errorf(codes.InvalidArgument, "Invalid modelKey in LoadModelRequest, it is not valid JSON at line %d, column %d (offset %d): %s", line, column, syntaxErr.Offset, err)
+		}
+		return status.Errorf(codes.InvalidArgument, "Invalid modelKey in LoadModelRequest: %s", err)
+	}
+	return nil
+}
+
+// CheckModelKeyLimits returns a ResourceExhausted error if the ModelKey of the
+// LoadModelRequest is longer than maxSize bytes, so that an abusive ModelKey is
+// rejected before it is parsed. Decoding JSON is linear in its size, so the
+// limit also bounds the time spent parsing the ModelKey.
+//
+// A maxSize of 0 disables the limit.
+func CheckModelKeyLimits(req *mmesh.LoadModelRequest, maxSize int) error {
+	if maxSize > 0 && len(req.ModelKey) > maxSize {
+		return status.Errorf(codes.ResourceExhausted, "ModelKey in LoadModelRequest is %d bytes, which is more than the limit of %d bytes", len(req.ModelKey), maxSize)
+	}
+	return nil
+}
+
+// jsonPosition converts the byte offset of a json.SyntaxError into a 1-based
+// line and column
+func jsonPosition(data string, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := len(before) - strings.LastIndex(before, "\n") - 1
+	return line, column
+}
+
+// The helpers below only parse the field of the ModelKey that they read, so
+// that a mistake in another field does not affect them. ValidateModelKey
+// checks the whole ModelKey.
 
 // GetModelType first tries to read the type from the LoadModelRequest.ModelKey json
 // If there is an error parsing LoadModelRequest.ModelKey or the type is not found there, this will
 // return the LoadModelRequest.ModelType which could possibly be an empty string
 func GetModelType(req *mmesh.LoadModelRequest, log logr.Logger) string {
 	modelType := req.ModelType
-	var modelKey map[string]interface{}
-	if err := json.Unmarshal([]byte(req.ModelKey), &modelKey); err != nil {
-		log.Info("The model type will fall back to LoadModelRequest.ModelType as LoadModelRequest.ModelKey value is not valid JSON", "LoadModelRequest.ModelType", req.ModelType, "LoadModelRequest.ModelKey", req.ModelKey, "Error", err)
-	} else if modelKey[modelTypeJSONKey] == nil {
-		log.Info("The model type will fall back to LoadModelRequest.ModelType as LoadModelRequest.ModelKey does not have specified attribute", "LoadModelRequest.ModelType", req.ModelType, "attribute", modelTypeJSONKey)
-	} else if modelKeyModelType, ok := modelKey[modelTypeJSONKey].(map[string]interface{}); ok {
-		if str, ok := modelKeyModelType["name"].(string); ok {
-			modelType = str
-		} else {
-			log.Info("The model type will fall back to LoadModelRequest.ModelType as LoadModelRequest.ModelKey attribute is not a string.", "LoadModelRequest.ModelType", req.ModelType, "attribute", modelTypeJSONKey, "attribute value", modelKey[modelTypeJSONKey])
-		}
-	} else if str, ok := modelKey[modelTypeJSONKey].(string); ok {
-		modelType = str
+	if modelKey, err := modelkey.ParseFields(req.ModelKey, modelkey.ModelTypeKey); err != nil {
+		log.Info("The model type will fall back to LoadModelRequest.ModelType as LoadModelRequest.ModelKey value is not valid", "LoadModelRequest.ModelType", req.ModelType, "LoadModelRequest.ModelKey", req.ModelKey, "Error", err)
+	} else if modelKey.ModelType == nil {
+		log.Info("The model type will fall back to LoadModelRequest.ModelType as LoadModelRequest.ModelKey does not have specified attribute", "LoadModelRequest.ModelType", req.ModelType, "attribute", modelkey.ModelTypeKey)
+	} else if name := modelKey.GetModelTypeName(); name == "" {
+		log.Info("The model type will fall back to LoadModelRequest.ModelType as LoadModelRequest.ModelKey attribute does not have a string name.", "LoadModelRequest.ModelType", req.ModelType, "attribute", modelkey.ModelTypeKey, "attribute value", modelKey.ModelType)
 	} else {
-		log.Info("The model type will fall back to LoadModelRequest.ModelType as LoadModelRequest.ModelKey attribute is not a string or map[string].", "LoadModelRequest.ModelType", req.ModelType, "attribute", modelTypeJSONKey, "attribute value", modelKey[modelTypeJSONKey])
+		modelType = name
 	}
 	return modelType
 }
@@ -55,38 +97,101 @@ func GetModelType(req *mmesh.LoadModelRequest, log logr.Logger) string {
 // GetSchemaPath extracts the schema path from the ModelKey field
 // Will return an error if schema path exists but is in an invalid format
 func GetSchemaPath(req *mmesh.LoadModelRequest) (string, error) {
-	var modelKey map[string]interface{}
-	if parseErr := json.Unmarshal([]byte(req.ModelKey), &modelKey); parseErr != nil {
-		return "", fmt.Errorf("Invalid modelKey in LoadModelRequest. ModelKey value '%s' is not valid JSON: %s", req.ModelKey, parseErr)
+	modelKey, parseErr := modelkey.ParseFields(req.ModelKey, modelkey.SchemaPathKey)
+	if parseErr != nil {
+		return "", fmt.Errorf("Invalid modelKey in LoadModelRequest. ModelKey value '%s' is not valid: %s", req.ModelKey, parseErr)
+	}
+
+	if modelKey.SchemaPath == nil {
+		return "", nil
+	}
+	return *modelKey.SchemaPath, nil
+}
+
+// GetVersionPolicy extracts the version policy from the ModelKey field, which
+// is nil if it is not set
+func GetVersionPolicy(req *mmesh.LoadModelRequest) (*modelkey.VersionPolicy, error) {
+	modelKey, parseErr := modelkey.ParseFields(req.ModelKey, modelkey.VersionPolicyKey)
+	if parseErr != nil {
+		return nil, fmt.Errorf("Invalid modelKey in LoadModelRequest. ModelKey value '%s' is not valid: %s", req.ModelKey, parseErr)
+	}
+	return modelKey.VersionPolicy, nil
+}
+
+// GetPluginConfig extracts the runtime plugin config from the ModelKey field,
+// which is nil if it is not set
+func GetPluginConfig(req *mmesh.LoadModelRequest) (map[string]string, error) {
+	modelKey, parseErr := modelkey.ParseFields(req.ModelKey, modelkey.PluginConfigKey)
+	if parseErr != nil {
+		return nil, fmt.Errorf("Invalid modelKey in LoadModelRequest. ModelKey value '%s' is not valid: %s", req.ModelKey, parseErr)
 	}
+	return modelKey.PluginConfig, nil
+}
 
-	schemaPath, ok := modelKey[schemaPathJSONKey].(string)
-	if !ok && modelKey[schemaPathJSONKey] != nil {
-		return "", fmt.Errorf("Invalid schemaPath in LoadModelRequest, '%s' attribute must have a string value. Found value %v", schemaPathJSONKey, modelKey[schemaPathJSONKey])
+// GetServedModelName returns the served_model_name in the ModelKey, which is
+// empty if the model is served with its model id
+func GetServedModelName(req *mmesh.LoadModelRequest) (string, error) {
+	modelKey, parseErr := modelkey.ParseFields(req.ModelKey, modelkey.ServedModelNameKey)
+	if parseErr != nil {
+		return "", fmt.Errorf("Invalid modelKey in LoadModelRequest. ModelKey value '%s' is not valid: %s", req.ModelKey, parseErr)
 	}
+	return modelKey.ServedModelName, nil
+}
 
-	return schemaPath, nil
+// GetModelLabels returns the labels in the ModelKey, which are nil if the
+// model has none
+func GetModelLabels(req *mmesh.LoadModelRequest) (map[string]string, error) {
+	modelKey, parseErr := modelkey.ParseFields(req.ModelKey, modelkey.LabelsKey)
+	if parseErr != nil {
+		return nil, fmt.Errorf("Invalid modelKey in LoadModelRequest. ModelKey value '%s' is not valid: %s", req.ModelKey, parseErr)
+	}
+	return modelKey.Labels, nil
+}
+
+// Precedence between the disk_size_bytes in the ModelKey and the size of the
+// model files on disk, see ResolveDiskSize
+const (
+	DiskSizePrecedenceFile = "file"
+	DiskSizePrecedenceKey  = "key"
+)
+
+// ResolveDiskSize returns the disk size of a model from either the
+// disk_size_bytes in the ModelKey (keySize) or the size of the model files
+// (fileSize), whichever takes precedence
+//
+// With DiskSizePrecedenceFile, the size of the files is used and keySize is
+// only the fallback if the files cannot be measured. With
+// DiskSizePrecedenceKey, keySize is used if it is set, without measuring the
+// files. If the size of the files is needed but cannot be computed, the
+// error is returned together with keySize, or 0 if that is not set.
+func ResolveDiskSize(keySize *int64, fileSize func() (int64, error), precedence string) (int64, error) {
+	var fallback int64
+	if keySize != nil {
+		if precedence == DiskSizePrecedenceKey {
+			return *keySize, nil
+		}
+		fallback = *keySize
+	}
+
+	size, err := fileSize()
+	if err != nil {
+		return fallback, err
+	}
+	return size, nil
 }
 
 func CalcMemCapacity(reqModelKey string, defaultSize int, multiplier float64, log logr.Logger) uint64 {
 	// Try to calculate the model size from the disk size passed in the LoadModelRequest.ModelKey
 	// but first set the default to fall back on if we cannot get the disk size.
 	size := uint64(defaultSize)
-	var modelKey map[string]interface{}
-	if err := json.Unmarshal([]byte(reqModelKey), &modelKey); err != nil {
-		log.Info("'SizeInBytes' will be defaulted as LoadModelRequest.ModelKey value is not valid JSON", "SizeInBytes", size, "model_key", reqModelKey, "error", err)
+	if modelKey, err := modelkey.ParseFields(reqModelKey, modelkey.DiskSizeBytesKey); err != nil {
+		log.Info("'SizeInBytes' will be defaulted as LoadModelRequest.ModelKey value is not valid", "SizeInBytes", size, "model_key", reqModelKey, "error", err)
+	} else if modelKey.DiskSizeBytes != nil {
+		diskSize := *modelKey.DiskSizeBytes
+		size = uint64(float64(diskSize) * multiplier)
+		log.Info("Setting 'SizeInBytes' to a multiple of model disk size", "SizeInBytes", size, "disk_size", diskSize, "multiplier", multiplier)
 	} else {
-		if modelKey[diskSizeBytesJSONKey] != nil {
-			diskSize, ok := modelKey[diskSizeBytesJSONKey].(float64)
-			if ok {
-				size = uint64(diskSize * multiplier)
-				log.Info("Setting 'SizeInBytes' to a multiple of model disk size", "SizeInBytes", size, "disk_size", diskSize, "multiplier", multiplier)
-			} else {
-				log.Info("'SizeInBytes' will be defaulted as LoadModelRequest.ModelKey 'disk_size_bytes' value is not a number", "SizeInBytes", size, "model_key", modelKey)
-			}
-		} else {
-			log.Info("'SizeInBytes' will be defaulted as LoadModelRequest.ModelKey did not contain a value for 'disk_size_bytes'", "SizeInBytes", size, "model_key", modelKey)
-		}
+		log.Info("'SizeInBytes' will be defaulted as LoadModelRequest.ModelKey did not contain a value for 'disk_size_bytes'", "SizeInBytes", size, "model_key", reqModelKey)
 	}
 	return size
 }
@@ -0,0 +1,159 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestResolveDiskSize(t *testing.T) {
+	keySize := int64(100)
+	fileSize := func() (int64, error) { return 60, nil }
+	failedFileSize := func() (int64, error) { return 0, errors.New("walk failed") }
+
+	testCases := []struct {
+		name        string
+		keySize     *int64
+		fileSize    func() (int64, error)
+		precedence  string
+		expected    int64
+		expectError bool
+	}{
+		{"file precedence with both", &keySize, fileSize, DiskSizePrecedenceFile, 60, false},
+		{"key precedence with both", &keySize, fileSize, DiskSizePrecedenceKey, 100, false},
+		{"file precedence without key", nil, fileSize, DiskSizePrecedenceFile, 60, false},
+		{"key precedence without key", nil, fileSize, DiskSizePrecedenceKey, 60, false},
+		{"file precedence falls back to key", &keySize, failedFileSize, DiskSizePrecedenceFile, 100, true},
+		{"key precedence does not measure files", &keySize, failedFileSize, DiskSizePrecedenceKey, 100, false},
+		{"no size", nil, failedFileSize, DiskSizePrecedenceFile, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			size, err := ResolveDiskSize(tc.keySize, tc.fileSize, tc.precedence)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error %v but got %v", tc.expectError, err)
+			}
+			if size != tc.expected {
+				t.Errorf("Expected size %d but got %d", tc.expected, size)
+			}
+		})
+	}
+}
+
+func TestValidateModelKey(t *testing.T) {
+	testCases := []struct {
+		name          string
+		modelKey      string
+		expectError   bool
+		errorContains string
+	}{
+		{"empty", "", false, ""},
+		{"valid", `{"model_type": {"name": "onnx"}, "disk_size_bytes": 100}`, false, ""},
+		{"truncated", `{"model_type": {"name": "onnx"`, true, "line 1, column 30 (offset 30)"},
+		{"malformed", `{"model_type": {"name": onnx}}`, true, "line 1, column 25 (offset 25)"},
+		{"malformed on second line", "{\n  \"model_type\": ,\n}", true, "line 2, column 17 (offset 19)"},
+		{"trailing data", `{"model_type": "onnx"} {}`, true, "offset 24"},
+		{"invalid field", `{"disk_size_bytes": "big"}`, true, "disk_size_bytes"},
+		{"not an object", `["onnx"]`, true, "Invalid modelKey"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateModelKey(&mmesh.LoadModelRequest{ModelId: "model", ModelKey: tc.modelKey})
+			if !tc.expectError {
+				if err != nil {
+					t.Errorf("Expected no error but got %v", err)
+				}
+				return
+			}
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Expected InvalidArgument but got %v", err)
+			}
+			if err != nil && !strings.Contains(err.Error(), tc.errorContains) {
+				t.Errorf("Expected error to contain %q but got %v", tc.errorContains, err)
+			}
+		})
+	}
+}
+
+func TestHelpersIgnoreUnrelatedInvalidFields(t *testing.T) {
+	log := zap.New()
+	for _, invalidField := range []string{
+		`"labels": {"team": 1}`,
+		`"download_concurrency": "4"`,
+		`"parameters": {"x": true}`,
+	} {
+		modelKey := `{"model_type": {"name": "onnx"}, "schema_path": "my_schema", "disk_size_bytes": 100, "plugin_config": {"NIREQ": "4"}, ` + invalidField + `}`
+		req := &mmesh.LoadModelRequest{ModelId: "model", ModelType: "fallback", ModelKey: modelKey}
+
+		if modelType := GetModelType(req, log); modelType != "onnx" {
+			t.Errorf("Expected model type onnx for ModelKey %s but got %s", modelKey, modelType)
+		}
+		if schemaPath, err := GetSchemaPath(req); err != nil || schemaPath != "my_schema" {
+			t.Errorf("Expected schema path my_schema for ModelKey %s but got %q, %v", modelKey, schemaPath, err)
+		}
+		if pluginConfig, err := GetPluginConfig(req); err != nil || pluginConfig["NIREQ"] != "4" {
+			t.Errorf("Expected plugin config for ModelKey %s but got %v, %v", modelKey, pluginConfig, err)
+		}
+		if versionPolicy, err := GetVersionPolicy(req); err != nil || versionPolicy != nil {
+			t.Errorf("Expected no version policy for ModelKey %s but got %v, %v", modelKey, versionPolicy, err)
+		}
+		if size := CalcMemCapacity(modelKey, 1, 2, log); size != 200 {
+			t.Errorf("Expected a size of 200 for ModelKey %s but got %d", modelKey, size)
+		}
+	}
+}
+
+func TestCalcMemCapacityFractionalDiskSize(t *testing.T) {
+	if size := CalcMemCapacity(`{"disk_size_bytes": 1234.5}`, 1, 2, zap.New()); size != 2470 {
+		t.Errorf("Expected a size of 2470 but got %d", size)
+	}
+}
+
+func TestCheckModelKeyLimits(t *testing.T) {
+	modelKey := `{"model_type": {"name": "onnx"}, "disk_size_bytes": 100}`
+	oversizedModelKey := `{"model_type": {"name": "onnx"}, "parameters": {"padding": "` + strings.Repeat("x", 1024) + `"}}`
+
+	testCases := []struct {
+		name         string
+		modelKey     string
+		maxSize      int
+		expectedCode codes.Code
+	}{
+		{"normal", modelKey, 1024, codes.OK},
+		{"empty", "", 1024, codes.OK},
+		{"exactly the limit", modelKey, len(modelKey), codes.OK},
+		{"oversized", oversizedModelKey, 1024, codes.ResourceExhausted},
+		{"no limit", oversizedModelKey, 0, codes.OK},
+		{"invalid JSON is left to the parse", `{"model_type": `, 1024, codes.OK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckModelKeyLimits(&mmesh.LoadModelRequest{ModelId: "model", ModelKey: tc.modelKey}, tc.maxSize)
+			if status.Code(err) != tc.expectedCode {
+				t.Errorf("Expected %v but got %v", tc.expectedCode, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CgroupMemoryUsage returns the memory that the processes in a cgroup are
+// using, read from the cgroup mounted at cgroupDir
+//
+// Like the working set that Kubernetes reports, this is the memory usage of
+// the cgroup minus its inactive page cache, which can be reclaimed. Both
+// cgroup v2 and the memory controller of cgroup v1 are supported.
+func CgroupMemoryUsage(cgroupDir string) (int64, error) {
+	// cgroup v2, then cgroup v1
+	usageFile, statFile, inactiveKey := filepath.Join(cgroupDir, "memory.current"), filepath.Join(cgroupDir, "memory.stat"), "inactive_file"
+	if _, err := os.Stat(usageFile); errors.Is(err, os.ErrNotExist) {
+		memoryDir := filepath.Join(cgroupDir, "memory")
+		usageFile, statFile, inactiveKey = filepath.Join(memoryDir, "memory.usage_in_bytes"), filepath.Join(memoryDir, "memory.stat"), "total_inactive_file"
+	}
+
+	usageBytes, err := os.ReadFile(usageFile)
+	if err != nil {
+		return 0, fmt.Errorf("Error reading the memory usage of the cgroup: %w", err)
+	}
+	usage, err := strconv.ParseInt(strings.TrimSpace(string(usageBytes)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid memory usage in %s: %w", usageFile, err)
+	}
+
+	inactive, err := readMemoryStat(statFile, inactiveKey)
+	if err != nil {
+		return 0, err
+	}
+	if inactive > usage {
+		return 0, nil
+	}
+	return usage - inactive, nil
+}
+
+// readMemoryStat returns the value of a key in a memory.stat file, 0 if the
+// key is not in the file
+func readMemoryStat(statFile string, key string) (int64, error) {
+	f, err := os.Open(statFile)
+	if err != nil {
+		return 0, fmt.Errorf("Error reading the memory stats of the cgroup: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != key {
+			continue
+		}
+		value, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid value of %s in %s: %w", key, statFile, err)
+		}
+		return value, nil
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, fmt.Errorf("Error reading the memory stats of the cgroup: %w", err)
+	}
+	return 0, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCgroupMemoryUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected int64
+	}{
+		{
+			name: "v2",
+			files: map[string]string{
+				"memory.current": "1000000\n",
+				"memory.stat":    "anon 600000\nfile 400000\ninactive_file 300000\n",
+			},
+			expected: 700000,
+		},
+		{
+			name: "v1",
+			files: map[string]string{
+				"memory/memory.usage_in_bytes": "2000000\n",
+				"memory/memory.stat":           "cache 500000\ninactive_file 100000\ntotal_inactive_file 200000\n",
+			},
+			expected: 1800000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, contents := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			usage, err := CgroupMemoryUsage(dir)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if usage != tt.expected {
+				t.Errorf("Expected a memory usage of %d, got %d", tt.expected, usage)
+			}
+		})
+	}
+
+	if _, err := CgroupMemoryUsage(t.TempDir()); err == nil {
+		t.Errorf("Expected an error without a memory cgroup")
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Model formats returned by DetectModelFormat, named after the model types
+const (
+	ModelFormatUnknown    = ""
+	ModelFormatONNX       = "onnx"
+	ModelFormatTensorRT   = "tensorrt"
+	ModelFormatPyTorch    = "pytorch"
+	ModelFormatSavedModel = "tensorflow"
+)
+
+// number of bytes read from the start of a file to detect its format
+const modelFormatHeaderSize = 512
+
+var (
+	// magic of a serialized TensorRT engine, which changed in TensorRT 8.6
+	tensorRTMagics = [][]byte{[]byte("ptrt"), []byte("ftrt")}
+	// signature of a zip local file header
+	zipMagic = []byte("PK\x03\x04")
+	// pickle protocol 2 opcode followed by the magic number that the legacy
+	// (non-zip) torch.save format starts with
+	legacyPyTorchMagic = []byte("\x80\x02\x8a\x0a\x6c\xfc\x9c\x46\xf9\x20\x6a\xa8\x50\x19")
+	// SavedModel proto: saved_model_schema_version = 1, then meta_graphs
+	savedModelMagic = []byte{0x08, 0x01, 0x12}
+)
+
+// tags of the length-delimited and varint fields that follow ir_version in
+// an ONNX ModelProto
+var onnxFieldTags = map[byte]bool{
+	0x12: true, // producer_name
+	0x1a: true, // producer_version
+	0x22: true, // domain
+	0x28: true, // model_version
+	0x32: true, // doc_string
+	0x3a: true, // graph
+	0x42: true, // opset_import
+	0x72: true, // metadata_props
+}
+
+// DetectModelFormat detects the format of the model at path from the
+// signature of its contents rather than from its extension
+//
+// A directory is detected as a SavedModel if it contains a saved_model.pb or
+// saved_model.pbtxt. ModelFormatUnknown is returned if the format is not
+// recognized.
+func DetectModelFormat(modelPath string) (string, error) {
+	info, err := os.Stat(modelPath)
+	if err != nil {
+		return ModelFormatUnknown, fmt.Errorf("Could not stat model path %s: %w", modelPath, err)
+	}
+
+	if info.IsDir() {
+		for _, name := range []string{"saved_model.pb", "saved_model.pbtxt"} {
+			exists, err := FileExists(filepath.Join(modelPath, name))
+			if err != nil {
+				return ModelFormatUnknown, fmt.Errorf("Could not check for %s in %s: %w", name, modelPath, err)
+			}
+			if exists {
+				return ModelFormatSavedModel, nil
+			}
+		}
+		return ModelFormatUnknown, nil
+	}
+
+	f, err := os.Open(modelPath)
+	if err != nil {
+		return ModelFormatUnknown, fmt.Errorf("Could not open model file %s: %w", modelPath, err)
+	}
+	defer f.Close()
+
+	header := make([]byte, modelFormatHeaderSize)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return ModelFormatUnknown, fmt.Errorf("Could not read model file %s: %w", modelPath, err)
+	}
+
+	return DetectModelFormatFromHeader(header[:n]), nil
+}
+
+// DetectModelFormatFromHeader detects the format of a model file from the
+// first bytes of its contents, returning ModelFormatUnknown if the format is
+// not recognized
+func DetectModelFormatFromHeader(header []byte) string {
+	for _, magic := range tensorRTMagics {
+		if bytes.HasPrefix(header, magic) {
+			return ModelFormatTensorRT
+		}
+	}
+
+	if bytes.HasPrefix(header, legacyPyTorchMagic) || isPyTorchZip(header) {
+		return ModelFormatPyTorch
+	}
+
+	// checked before ONNX, which starts the same way with a newer ir_version
+	if bytes.HasPrefix(header, savedModelMagic) {
+		return ModelFormatSavedModel
+	}
+
+	// ONNX ModelProto starts with ir_version (field 1), which is at least 3
+	// for any model exported in the last years, followed by another field
+	if len(header) >= 3 && header[0] == 0x08 && header[1] >= 3 && header[1] < 0x80 && onnxFieldTags[header[2]] {
+		return ModelFormatONNX
+	}
+
+	return ModelFormatUnknown
+}
+
+// isPyTorchZip checks that the header is a zip archive whose first entry is
+// a pickle or the version record, as written by torch.save and torch.jit.save
+func isPyTorchZip(header []byte) bool {
+	if !bytes.HasPrefix(header, zipMagic) || len(header) < 30 {
+		return false
+	}
+
+	nameLen := int(binary.LittleEndian.Uint16(header[26:28]))
+	if len(header) < 30+nameLen {
+		return false
+	}
+	name := string(header[30 : 30+nameLen])
+
+	return strings.HasSuffix(name, ".pkl") || path.Base(name) == "version"
+}
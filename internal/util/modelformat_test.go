@@ -0,0 +1,98 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func zipHeader(t *testing.T, firstEntry string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(firstEntry)
+	if err != nil {
+		t.Fatalf("Unable to create zip entry: %v", err)
+	}
+	w.Write([]byte("contents"))
+	if err = zw.Close(); err != nil {
+		t.Fatalf("Unable to write zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectModelFormatFromHeader(t *testing.T) {
+	testCases := []struct {
+		name     string
+		header   []byte
+		expected string
+	}{
+		// ir_version 8, producer_name "pytorch"
+		{"onnx", []byte("\x08\x08\x12\x07pytorch\x1a\x051.13"), ModelFormatONNX},
+		// ir_version 7, graph
+		{"onnx without producer", []byte("\x08\x07\x3a\x10"), ModelFormatONNX},
+		{"tensorrt", []byte("ptrt\x00\x00\x00\x00"), ModelFormatTensorRT},
+		{"tensorrt 8.6", []byte("ftrt\x00\x00\x00\x00"), ModelFormatTensorRT},
+		{"pytorch zip", zipHeader(t, "archive/data.pkl"), ModelFormatPyTorch},
+		{"torchscript zip", zipHeader(t, "model/version"), ModelFormatPyTorch},
+		{"pytorch legacy", append(append([]byte{}, legacyPyTorchMagic...), 0x2e), ModelFormatPyTorch},
+		{"saved model", []byte("\x08\x01\x12\x80\x01"), ModelFormatSavedModel},
+		{"other zip", zipHeader(t, "readme.txt"), ModelFormatUnknown},
+		{"unknown blob", []byte("just some text"), ModelFormatUnknown},
+		{"empty", []byte{}, ModelFormatUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DetectModelFormatFromHeader(tc.header); got != tc.expected {
+				t.Errorf("Expected format '%s' but got '%s'", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDetectModelFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	// the extension does not matter
+	mislabeled := filepath.Join(dir, "model.pt")
+	if err := os.WriteFile(mislabeled, []byte("\x08\x08\x12\x07pytorch"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := DetectModelFormat(mislabeled); err != nil || got != ModelFormatONNX {
+		t.Errorf("Expected format '%s' but got '%s' (error: %v)", ModelFormatONNX, got, err)
+	}
+
+	savedModelDir := filepath.Join(dir, "saved")
+	if err := os.MkdirAll(savedModelDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := DetectModelFormat(savedModelDir); err != nil || got != ModelFormatUnknown {
+		t.Errorf("Expected an unknown format for an empty directory but got '%s' (error: %v)", got, err)
+	}
+	if err := os.WriteFile(filepath.Join(savedModelDir, "saved_model.pb"), []byte("\x08\x01\x12"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := DetectModelFormat(savedModelDir); err != nil || got != ModelFormatSavedModel {
+		t.Errorf("Expected format '%s' but got '%s' (error: %v)", ModelFormatSavedModel, got, err)
+	}
+
+	if _, err := DetectModelFormat(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for a missing path")
+	}
+}
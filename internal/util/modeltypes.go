@@ -0,0 +1,165 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ModelTypeInfo is a model type that an adapter accepts, with the other
+// names that are accepted for it and the layout of its files
+type ModelTypeInfo struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	// patterns of the files that a model directory of the type contains,
+	// directly or in numbered version directories
+	Files []string `json:"files"`
+	// the ModelPath may also be a single model file
+	SingleFile bool `json:"single_file,omitempty"`
+}
+
+// RuntimeInfo is the runtime that an adapter fronts, which a router can
+// match model types to
+type RuntimeInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// SupportedModelTypes are the runtime of an adapter and the model types that
+// it accepts
+type SupportedModelTypes struct {
+	Runtime    RuntimeInfo     `json:"runtime"`
+	ModelTypes []ModelTypeInfo `json:"model_types"`
+}
+
+// CopyModelTypes returns a deep copy of the model types, so that callers
+// cannot modify the table of an adapter
+func CopyModelTypes(modelTypes []ModelTypeInfo) []ModelTypeInfo {
+	types := make([]ModelTypeInfo, len(modelTypes))
+	for i, t := range modelTypes {
+		types[i] = t
+		types[i].Aliases = append([]string(nil), t.Aliases...)
+		types[i].Files = append([]string(nil), t.Files...)
+	}
+	return types
+}
+
+// The supported model types are served by a gRPC service next to the
+// ModelRuntime service of each adapter. The service is defined without
+// generated code: the request is a google.protobuf.Empty and the response is
+// a google.protobuf.Struct with the JSON fields of the SupportedModelTypes.
+const (
+	modelTypesServiceName = "mmesh.ModelTypes"
+	modelTypesMethodName  = "GetSupportedModelTypes"
+	// ModelTypesMethod is the full name of the gRPC method
+	ModelTypesMethod = "/" + modelTypesServiceName + "/" + modelTypesMethodName
+)
+
+type modelTypesServer interface {
+	getSupportedModelTypes() SupportedModelTypes
+}
+
+// modelTypesFunc returns the SupportedModelTypes on each call, since the
+// runtime version of an adapter is only known once the runtime is ready
+type modelTypesFunc func() SupportedModelTypes
+
+func (f modelTypesFunc) getSupportedModelTypes() SupportedModelTypes {
+	return f()
+}
+
+func modelTypesStruct(types SupportedModelTypes) (*structpb.Struct, error) {
+	b, err := json.Marshal(types)
+	if err != nil {
+		return nil, err
+	}
+	s := &structpb.Struct{}
+	if err = s.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func modelTypesHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return modelTypesStruct(srv.(modelTypesServer).getSupportedModelTypes())
+	}
+	if interceptor == nil {
+		return handler(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModelTypesMethod,
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var modelTypesServiceDesc = grpc.ServiceDesc{
+	ServiceName: modelTypesServiceName,
+	HandlerType: (*modelTypesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: modelTypesMethodName,
+			Handler:    modelTypesHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// RegisterModelTypesServer registers the mmesh.ModelTypes service, which
+// returns the result of types to GetSupportedModelTypes calls
+func RegisterModelTypesServer(s *grpc.Server, types func() SupportedModelTypes) {
+	s.RegisterService(&modelTypesServiceDesc, modelTypesFunc(types))
+}
+
+// GetSupportedModelTypes calls the mmesh.ModelTypes service of an adapter
+func GetSupportedModelTypes(ctx context.Context, cc grpc.ClientConnInterface, opts ...grpc.CallOption) (*SupportedModelTypes, error) {
+	out := &structpb.Struct{}
+	if err := cc.Invoke(ctx, ModelTypesMethod, &emptypb.Empty{}, out, opts...); err != nil {
+		return nil, err
+	}
+	b, err := out.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	types := &SupportedModelTypes{}
+	if err = json.Unmarshal(b, types); err != nil {
+		return nil, fmt.Errorf("Invalid supported model types: %w", err)
+	}
+	return types, nil
+}
+
+// ModelTypesHandler serves the result of types as JSON, eg.
+// {"runtime": {"name": "ovms", "version": "v1"}, "model_types": [{"name": "openvino", "aliases": ["openvino_ir"], "files": ["*.xml", "*.bin"]}, ...]}
+func ModelTypesHandler(types func() SupportedModelTypes) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types())
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestGetSupportedModelTypes(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := "v1"
+	types := func() SupportedModelTypes {
+		return SupportedModelTypes{
+			Runtime: RuntimeInfo{Name: "triton", Version: version},
+			ModelTypes: []ModelTypeInfo{
+				{Name: "onnx", Files: []string{"*.onnx"}, SingleFile: true},
+				{Name: "tensorflow", Aliases: []string{"tf"}, Files: []string{"saved_model.pb", "variables/"}},
+			},
+		}
+	}
+	s := grpc.NewServer()
+	RegisterModelTypesServer(s, types)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	got, err := GetSupportedModelTypes(ctx, conn)
+	if err != nil {
+		t.Fatalf("Unable to get the supported model types: %v", err)
+	}
+	if expected := types(); !reflect.DeepEqual(&expected, got) {
+		t.Errorf("Expected the supported model types %+v but got %+v", expected, *got)
+	}
+
+	// the types are read again on each call
+	version = "v2"
+	got, err = GetSupportedModelTypes(ctx, conn)
+	if err != nil {
+		t.Fatalf("Unable to get the supported model types: %v", err)
+	}
+	if got.Runtime.Version != "v2" {
+		t.Errorf("Expected the runtime version v2 but got %s", got.Runtime.Version)
+	}
+}
+
+func TestModelTypesHandlerMethod(t *testing.T) {
+	handler := ModelTypesHandler(func() SupportedModelTypes { return SupportedModelTypes{} })
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/model-types", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d but got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
+
+func TestCopyModelTypes(t *testing.T) {
+	modelTypes := []ModelTypeInfo{{Name: "onnx", Aliases: []string{"onnx_model"}, Files: []string{"*.onnx"}}}
+	copied := CopyModelTypes(modelTypes)
+	copied[0].Aliases[0] = "changed"
+	copied[0].Files[0] = "changed"
+	if modelTypes[0].Aliases[0] != "onnx_model" || modelTypes[0].Files[0] != "*.onnx" {
+		t.Errorf("Expected the copy not to share slices with the model types but got %+v", modelTypes)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// ModelReadiness is how an adapter interprets the status of a model reported
+// by its runtime
+type ModelReadiness int
+
+const (
+	// the model is still loading
+	ModelNotReady ModelReadiness = iota
+	// the model can serve requests
+	ModelReady
+	// the model failed to load and will not become ready
+	ModelFailed
+)
+
+func (r ModelReadiness) String() string {
+	switch r {
+	case ModelReady:
+		return "Ready"
+	case ModelFailed:
+		return "Failed"
+	default:
+		return "NotReady"
+	}
+}
+
+// WaitForModelsReady polls the models until each of them is ready or failed,
+// or until the timeout
+//
+// Each poll calls readiness for the models that are still not ready, which
+// interprets the status of a model reported by the runtime and returns a
+// message explaining it. Before every poll but the first, refresh fetches the
+// statuses from the runtime; an error is logged and the poll is retried after
+// the next interval.
+//
+// done is called once for every model with its final readiness, as soon as it
+// is ready or failed. The models that are not ready after the timeout are
+// passed to done with ModelNotReady and the message of their last poll.
+func WaitForModelsReady(modelIds []string, timeout time.Duration, interval time.Duration, refresh func() error, readiness func(modelId string) (ModelReadiness, string), done func(modelId string, r ModelReadiness, message string), log logr.Logger) {
+	pending := make(map[string]string, len(modelIds))
+	for _, id := range modelIds {
+		pending[id] = ""
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		for id := range pending {
+			r, message := readiness(id)
+			if r == ModelNotReady {
+				pending[id] = message
+				continue
+			}
+			done(id, r, message)
+			delete(pending, id)
+		}
+
+		if len(pending) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			for id, message := range pending {
+				done(id, ModelNotReady, message)
+			}
+			return
+		}
+
+		time.Sleep(interval)
+		if err := refresh(); err != nil {
+			log.Error(err, "Failed to get the model states from the runtime, will retry")
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestWaitForModelsReady(t *testing.T) {
+	// the runtime reports the statuses of the models for each poll
+	polls := []map[string]ModelReadiness{
+		{"ready-model": ModelReady, "loading-model": ModelNotReady, "failing-model": ModelNotReady, "stuck-model": ModelNotReady},
+		{"loading-model": ModelNotReady, "failing-model": ModelFailed, "stuck-model": ModelNotReady},
+		{"loading-model": ModelReady, "stuck-model": ModelNotReady},
+	}
+	poll, refreshes := 0, 0
+	refresh := func() error {
+		refreshes++
+		if refreshes == 1 {
+			// a failed refresh is retried without changing the statuses
+			return errors.New("Service unavailable")
+		}
+		if poll < len(polls)-1 {
+			poll++
+		}
+		return nil
+	}
+
+	results := map[string]ModelReadiness{}
+	WaitForModelsReady([]string{"ready-model", "loading-model", "failing-model", "stuck-model"}, 100*time.Millisecond, 10*time.Millisecond, refresh,
+		func(id string) (ModelReadiness, string) {
+			return polls[poll][id], ""
+		},
+		func(id string, r ModelReadiness, message string) {
+			if _, ok := results[id]; ok {
+				t.Errorf("Expected done to be called once for '%s'", id)
+			}
+			results[id] = r
+		}, logr.Discard())
+
+	expected := map[string]ModelReadiness{
+		"ready-model":   ModelReady,
+		"loading-model": ModelReady,
+		"failing-model": ModelFailed,
+		"stuck-model":   ModelNotReady,
+	}
+	for id, r := range expected {
+		if results[id] != r {
+			t.Errorf("Expected model '%s' to be %v, got %v", id, r, results[id])
+		}
+	}
+}
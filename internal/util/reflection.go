@@ -0,0 +1,34 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// RegisterReflection registers the gRPC server reflection service if it is
+// enabled, so that tools like grpcurl can call the adapter without its protos
+//
+// Reflection lists every service and message of the server, so it is only
+// meant for debugging.
+func RegisterReflection(s *grpc.Server, enabled bool, log logr.Logger) {
+	if !enabled {
+		return
+	}
+	reflection.Register(s)
+	log.Info("gRPC server reflection is enabled")
+}
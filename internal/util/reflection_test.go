@@ -0,0 +1,92 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+)
+
+// listServices lists the services of an adapter gRPC server through
+// reflection
+func listServices(t *testing.T, enabled bool) ([]string, error) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	mmesh.RegisterModelRuntimeServer(s, mmesh.UnimplementedModelRuntimeServer{})
+	RegisterReflection(s, enabled, logr.Discard())
+	go s.Serve(lis)
+	defer s.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		return nil, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services = append(services, service.Name)
+	}
+	return services, nil
+}
+
+func TestRegisterReflection(t *testing.T) {
+	services, err := listServices(t, true)
+	if err != nil {
+		t.Fatalf("Expected reflection to respond, got error: %v", err)
+	}
+	found := false
+	for _, service := range services {
+		if service == "mmesh.ModelRuntime" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the ModelRuntime service to be listed, got %v", services)
+	}
+
+	if _, err = listServices(t, false); status.Code(err) != codes.Unimplemented {
+		t.Errorf("Expected reflection to be unimplemented when disabled, got: %v", err)
+	}
+}
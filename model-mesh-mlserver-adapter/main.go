@@ -17,9 +17,11 @@ package main
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 
 	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 	"github.com/kserve/modelmesh-runtime-adapter/model-mesh-mlserver-adapter/server"
 	"google.golang.org/grpc"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -46,8 +48,25 @@ func main() {
 
 	log.Info("Adapter will run at port", "port", adapterConfig.Port, "MLServer port", adapterConfig.MLServerPort)
 
-	grpcServer := grpc.NewServer()
+	// the metrics of the embedded puller and the supported model types
+	if adapterConfig.MetricsPort > 0 {
+		mux := http.NewServeMux()
+		if MLServer.Puller != nil {
+			mux.Handle("/metrics", MLServer.Puller.MetricsHandler())
+		}
+		mux.Handle("/v1/model-types", MLServer.ModelTypesHandler())
+		go func() {
+			log.Info("Serving metrics", "port", adapterConfig.MetricsPort)
+			if err := http.ListenAndServe(fmt.Sprintf(":%d", adapterConfig.MetricsPort), mux); err != nil {
+				log.Error(err, "*** Metrics server terminated with error")
+			}
+		}()
+	}
+
+	grpcServer := grpc.NewServer(util.GrpcCompressionServerOptions(adapterConfig.GrpcCompression, log)...)
 	mmesh.RegisterModelRuntimeServer(grpcServer, MLServer)
+	MLServer.RegisterModelTypesServer(grpcServer)
+	util.RegisterReflection(grpcServer, adapterConfig.GrpcReflection, log)
 	log.Info("Adapter gRPC Server registered, now serving")
 
 	if err = grpcServer.Serve(lis); err != nil {
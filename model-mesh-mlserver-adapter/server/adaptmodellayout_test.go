@@ -22,6 +22,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/kserve/modelmesh-runtime-adapter/internal/modelschema"
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 )
 
@@ -39,6 +40,8 @@ type adaptModelLayoutTestCase struct {
 	ExpectedFiles  []string
 	ExpectedConfig map[string]interface{}
 	ExpectError    bool
+	// enables the validation of the model artifacts
+	ValidateArtifacts bool
 }
 
 func (tt adaptModelLayoutTestCase) getSourceDir() string {
@@ -435,6 +438,63 @@ var adaptModelLayoutTests = []adaptModelLayoutTestCase{
 			},
 		},
 	},
+
+	// Group: artifact validation
+
+	{
+		ModelID:   "validated-sklearn-dir",
+		ModelType: "sklearn",
+		ModelPath: "model",
+		InputFiles: []string{
+			"model/model.joblib",
+		},
+		ValidateArtifacts: true,
+		ExpectedFiles: []string{
+			"model",
+			"model-settings.json",
+		},
+		ExpectedConfig: map[string]interface{}{
+			"name":           "validated-sklearn-dir",
+			"implementation": "mlserver_sklearn.SKLearnModel",
+			"parameters": map[string]interface{}{
+				"uri": filepath.Join(generatedMlserverModelsDir, "validated-sklearn-dir", "model"),
+			},
+		},
+	},
+
+	{
+		ModelID:   "validated-sklearn-missing-artifact",
+		ModelType: "sklearn",
+		ModelPath: "model",
+		InputFiles: []string{
+			"model/model.bst",
+		},
+		ValidateArtifacts: true,
+		ExpectError:       true,
+	},
+
+	// a settings file overrides the expected artifacts
+	{
+		ModelID:   "validated-native-layout",
+		ModelType: "sklearn",
+		ModelPath: "data",
+		InputFiles: []string{
+			"data/model-settings.json",
+			"data/model.bin",
+		},
+		InputConfig: map[string]interface{}{
+			"implementation": "custom.Model",
+		},
+		ValidateArtifacts: true,
+		ExpectedFiles: []string{
+			"model-settings.json",
+			"model.bin",
+		},
+		ExpectedConfig: map[string]interface{}{
+			"name":           "validated-native-layout",
+			"implementation": "custom.Model",
+		},
+	},
 }
 
 func TestAdaptModelLayoutForRuntime(t *testing.T) {
@@ -461,7 +521,14 @@ func TestAdaptModelLayoutForRuntime(t *testing.T) {
 			if tt.SchemaPath != "" {
 				schemaFullPath = filepath.Join(tt.getSourceDir(), tt.SchemaPath)
 			}
-			err1 = adaptModelLayoutForRuntime(mlServerRootModelDir, tt.ModelID, tt.ModelType, modelFullPath, schemaFullPath, log)
+			err1 = adaptModelLayoutForRuntime(mlServerRootModelDir, tt.ModelID, tt.ModelType, modelFullPath, schemaFullPath, tt.ValidateArtifacts, util.FilePlacementLink, util.SymlinkPolicyDereference, log)
+
+			if tt.ExpectError {
+				if err1 == nil {
+					t.Fatal("Expected adaptModelLayoutForRuntime to fail")
+				}
+				return
+			}
 
 			// assert no error
 			if err1 != nil {
@@ -610,6 +677,41 @@ func TestAdaptModelLayoutForRuntime(t *testing.T) {
 	}
 }
 
+func TestAdaptModelLayoutForRuntimeFilePlacement(t *testing.T) {
+	for _, placement := range []util.FilePlacement{util.FilePlacementCopy, util.FilePlacementMove} {
+		t.Run(string(placement), func(t *testing.T) {
+			sourceDir := filepath.Join(t.TempDir(), "model")
+			assertCreateEmptyFile(filepath.Join(sourceDir, "model.joblib"), t)
+			if err := createFile(filepath.Join(sourceDir, "model-settings.json"), `{"implementation": "mlserver_sklearn.SKLearnModel"}`); err != nil {
+				t.Fatal(err)
+			}
+			rootModelDir := t.TempDir()
+
+			if err := adaptModelLayoutForRuntime(rootModelDir, "placed-model", "sklearn", sourceDir, "", false, placement, util.SymlinkPolicyDereference, log); err != nil {
+				t.Fatalf("adaptModelLayoutForRuntime failed with error: %v", err)
+			}
+
+			// the model file is placed as a regular file, not a symlink
+			info, err := os.Lstat(filepath.Join(rootModelDir, "placed-model", "model.joblib"))
+			if err != nil {
+				t.Fatalf("Expected the model file to be placed: %v", err)
+			}
+			if !info.Mode().IsRegular() {
+				t.Errorf("Expected the placed model file to be a regular file, got mode %v", info.Mode())
+			}
+
+			// a copy leaves the source files, a move does not
+			exists, err := util.FileExists(filepath.Join(sourceDir, "model.joblib"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := placement == util.FilePlacementCopy; exists != expected {
+				t.Errorf("Expected the source model file to exist: %v, but it exists: %v", expected, exists)
+			}
+		})
+	}
+}
+
 func extractURI(config map[string]interface{}) (string, bool) {
 	var uri string
 	if paramsI, ok := config["parameters"]; !ok {
@@ -650,3 +752,45 @@ func getFilesInDir(root string) ([]os.FileInfo, error) {
 	}
 	return result, nil
 }
+
+func TestProcessSchemaDatatypes(t *testing.T) {
+	testCases := []struct {
+		datatype    string
+		expectError bool
+	}{
+		{modelschema.BYTES, false},
+		{modelschema.FP32, false},
+		{modelschema.BF16, true},
+		{modelschema.STRING, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.datatype, func(t *testing.T) {
+			schema := &modelschema.ModelSchema{
+				Inputs: []modelschema.TensorMetadata{
+					{Name: "INPUT", Datatype: tc.datatype, Shape: []int64{1}},
+				},
+			}
+			config := map[string]interface{}{}
+			err := processSchema(config, schema)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error for unsupported datatype %s", tc.datatype)
+				} else if !strings.Contains(err.Error(), tc.datatype) {
+					t.Errorf("Expected error to name the unsupported datatype, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			inputs, _ := config["inputs"].([]interface{})
+			if len(inputs) != 1 {
+				t.Fatalf("Expected inputs to be set in the config, got %v", config["inputs"])
+			}
+			// the datatype is passed through as it is
+			if datatype := inputs[0].(map[string]interface{})["datatype"]; datatype != tc.datatype {
+				t.Errorf("Expected datatype %s in the config, got %v", tc.datatype, datatype)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// modelTypeArtifactExtensions are the extensions of the artifact files that
+// the MLServer implementation of each model type can load
+var modelTypeArtifactExtensions = map[string][]string{
+	"lightgbm": {".bst", ".txt"},
+	"sklearn":  {".joblib", ".pickle", ".pkl"},
+	"xgboost":  {".bst", ".json", ".ubj"},
+}
+
+// checkModelArtifacts checks that the model path is a file, or a directory
+// containing a file, with one of the extensions expected for the model type
+//
+// Model types without known artifacts are not validated.
+func checkModelArtifacts(modelType, modelPath string) error {
+	extensions, ok := modelTypeArtifactExtensions[modelType]
+	if !ok {
+		return nil
+	}
+
+	modelPathInfo, err := os.Stat(modelPath)
+	if err != nil {
+		return fmt.Errorf("Error calling stat on %s: %w", modelPath, err)
+	}
+
+	filenames := []string{modelPathInfo.Name()}
+	if modelPathInfo.IsDir() {
+		files, err1 := os.ReadDir(modelPath)
+		if err1 != nil {
+			return fmt.Errorf("Could not read files in dir %s: %w", modelPath, err1)
+		}
+		filenames = filenames[:0]
+		for _, f := range files {
+			if !f.IsDir() {
+				filenames = append(filenames, f.Name())
+			}
+		}
+	}
+
+	for _, filename := range filenames {
+		ext := strings.ToLower(filepath.Ext(filename))
+		for _, e := range extensions {
+			if ext == e {
+				return nil
+			}
+		}
+	}
+
+	sort.Strings(filenames)
+	return status.Errorf(codes.InvalidArgument, "Model type '%s' requires an artifact with one of the extensions %v, but none was found in %v",
+		modelType, extensions, filenames)
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheckModelArtifacts(t *testing.T) {
+	testCases := []struct {
+		name      string
+		modelType string
+		files     []string
+		// empty to validate the directory with the files
+		modelPath string
+		missing   bool
+	}{
+		{name: "sklearn joblib", modelType: "sklearn", files: []string{"model.joblib"}},
+		{name: "sklearn pickle file", modelType: "sklearn", files: []string{"model.pkl"}, modelPath: "model.pkl"},
+		{name: "sklearn missing", modelType: "sklearn", files: []string{"model.bst", "README.md"}, missing: true},
+		{name: "xgboost bst", modelType: "xgboost", files: []string{"model.bst"}},
+		{name: "xgboost json", modelType: "xgboost", files: []string{"mushroom-xgboost.json"}},
+		{name: "xgboost missing", modelType: "xgboost", files: []string{"model.joblib"}, missing: true},
+		{name: "lightgbm txt", modelType: "lightgbm", files: []string{"model.txt"}},
+		{name: "lightgbm missing file", modelType: "lightgbm", files: []string{"model.pkl"}, modelPath: "model.pkl", missing: true},
+		{name: "lightgbm empty dir", modelType: "lightgbm", missing: true},
+		{name: "unknown type", modelType: "custom", files: []string{"model.bin"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range tc.files {
+				assertCreateEmptyFile(filepath.Join(dir, f), t)
+			}
+
+			err := checkModelArtifacts(tc.modelType, filepath.Join(dir, tc.modelPath))
+			if !tc.missing {
+				if err != nil {
+					t.Errorf("Expected the artifacts to be valid, got: %v", err)
+				}
+				return
+			}
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("Expected an InvalidArgument error, got: %v", err)
+			}
+			// the error lists the expected extensions
+			for _, ext := range modelTypeArtifactExtensions[tc.modelType] {
+				if !strings.Contains(err.Error(), ext) {
+					t.Errorf("Expected the error to list extension %s, got: %v", ext, err)
+				}
+			}
+		})
+	}
+
+	if err := checkModelArtifacts("sklearn", filepath.Join(os.TempDir(), "does-not-exist")); err == nil {
+		t.Error("Expected an error for a missing model path")
+	}
+}
@@ -16,6 +16,7 @@ package server
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 
@@ -49,6 +50,28 @@ const (
 	defaultRootModelDir                        = "/models"
 	useEmbeddedPuller                   string = "USE_EMBEDDED_PULLER"
 	defaultUseEmbeddedPuller                   = false
+	strictModelKey                      string = "STRICT_MODEL_KEY"
+	defaultStrictModelKey                      = false
+	modelKeyMaxSize                     string = "MODEL_KEY_MAX_SIZE"
+	defaultModelKeyMaxSize                     = 0 // 0 means the size of the ModelKey is not limited
+	grpcReflection                      string = "GRPC_REFLECTION"
+	defaultGrpcReflection                      = false
+	grpcCompression                     string = "GRPC_COMPRESSION"
+	defaultGrpcCompression                     = false
+	metricsPort                         string = "METRICS_PORT"
+	defaultMetricsPort                         = 0 // 0 means the metrics are not served
+	layoutRetries                       string = "LAYOUT_RETRIES"
+	defaultLayoutRetries                       = 0 // 0 means transient filesystem errors are not retried
+	layoutRetryBackoff                  string = "LAYOUT_RETRY_BACKOFF"
+	defaultLayoutRetryBackoff                  = 500 * time.Millisecond
+	modelFilePlacement                  string = "MODEL_FILE_PLACEMENT"
+	defaultModelFilePlacement                  = util.FilePlacementLink
+	modelSymlinkPolicy                  string = "MODEL_SYMLINK_POLICY"
+	defaultModelSymlinkPolicy                  = util.SymlinkPolicyDereference
+	validateModelArtifacts              string = "VALIDATE_MODEL_ARTIFACTS"
+	defaultValidateModelArtifacts              = false
+	modelReadyTimeout                   string = "MODEL_READY_TIMEOUT"
+	defaultModelReadyTimeout                   = 0 * time.Second // 0 means loads do not wait for the model to be ready
 )
 
 func GetAdapterConfigurationFromEnv(log logr.Logger) (*AdapterConfiguration, error) {
@@ -65,8 +88,25 @@ func GetAdapterConfigurationFromEnv(log logr.Logger) (*AdapterConfiguration, err
 	adapterConfig.RuntimeVersion = GetEnvString(runtimeVersion, defaultRuntimeVersion)
 	adapterConfig.LimitModelConcurrency = GetEnvInt(limitPerModelConcurrency, defaultLimitPerModelConcurrency, log)
 	adapterConfig.UseEmbeddedPuller = GetEnvBool(useEmbeddedPuller, defaultUseEmbeddedPuller, log)
+	adapterConfig.StrictModelKey = GetEnvBool(strictModelKey, defaultStrictModelKey, log)
+	adapterConfig.ModelKeyMaxSize = GetEnvInt(modelKeyMaxSize, defaultModelKeyMaxSize, log)
+	adapterConfig.GrpcReflection = GetEnvBool(grpcReflection, defaultGrpcReflection, log)
+	adapterConfig.GrpcCompression = GetEnvBool(grpcCompression, defaultGrpcCompression, log)
+	adapterConfig.MetricsPort = GetEnvInt(metricsPort, defaultMetricsPort, log)
+	adapterConfig.ValidateModelArtifacts = GetEnvBool(validateModelArtifacts, defaultValidateModelArtifacts, log)
+	adapterConfig.LayoutRetries = GetEnvInt(layoutRetries, defaultLayoutRetries, log)
+	adapterConfig.LayoutRetryBackoff = GetEnvDuration(layoutRetryBackoff, defaultLayoutRetryBackoff, log)
+	adapterConfig.ModelReadyTimeout = GetEnvDuration(modelReadyTimeout, defaultModelReadyTimeout, log)
 
 	var err error
+	adapterConfig.ModelFilePlacement, err = util.ParseFilePlacement(GetEnvString(modelFilePlacement, string(defaultModelFilePlacement)))
+	if err != nil {
+		return nil, fmt.Errorf("%s environment variable is invalid: %w", modelFilePlacement, err)
+	}
+	adapterConfig.ModelSymlinkPolicy, err = util.ParseSymlinkPolicy(GetEnvString(modelSymlinkPolicy, string(defaultModelSymlinkPolicy)))
+	if err != nil {
+		return nil, fmt.Errorf("%s environment variable is invalid: %w", modelSymlinkPolicy, err)
+	}
 	adapterConfig.RootModelDir, err = util.SecureJoin(GetEnvString(rootModelDir, defaultRootModelDir), mlserverModelSubdir)
 	if err != nil {
 		return nil, fmt.Errorf("Could not construct root model path: %w", err)
@@ -75,8 +115,23 @@ func GetAdapterConfigurationFromEnv(log logr.Logger) (*AdapterConfiguration, err
 	if adapterConfig.MLServerContainerMemReqBytes < 0 {
 		return nil, fmt.Errorf("%s environment variable must be set to a positive integer, found value %v", mlserverContainerMemReqBytes, adapterConfig.MLServerContainerMemReqBytes)
 	}
+	if adapterConfig.LayoutRetries < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", layoutRetries, adapterConfig.LayoutRetries)
+	}
+	if adapterConfig.LayoutRetryBackoff <= 0 {
+		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", layoutRetryBackoff, adapterConfig.LayoutRetryBackoff)
+	}
+	if adapterConfig.ModelReadyTimeout < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", modelReadyTimeout, adapterConfig.ModelReadyTimeout)
+	}
 	if adapterConfig.ModelSizeMultiplier <= 0 {
 		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", modelSizeMultiplier, adapterConfig.ModelSizeMultiplier)
 	}
+	if adapterConfig.ModelKeyMaxSize < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", modelKeyMaxSize, adapterConfig.ModelKeyMaxSize)
+	}
+	if adapterConfig.MetricsPort < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", metricsPort, adapterConfig.MetricsPort)
+	}
 	return adapterConfig, nil
 }
@@ -0,0 +1,57 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+// name of the runtime in the RuntimeInfo
+const mlserverRuntimeName = "mlserver"
+
+// supportedModelTypes are the model types that the adapter configures an
+// MLServer implementation for, see generateModelConfigJSON
+//
+// The files are the artifacts in modelTypeArtifactExtensions. Models of other
+// types are loaded with the implementation given in their model-settings.json.
+var supportedModelTypes = []util.ModelTypeInfo{
+	{Name: "sklearn", Files: []string{"*.joblib", "*.pickle", "*.pkl"}, SingleFile: true},
+	{Name: "xgboost", Files: []string{"*.bst", "*.json", "*.ubj"}, SingleFile: true},
+	{Name: "lightgbm", Files: []string{"*.bst", "*.txt"}, SingleFile: true},
+	{Name: "mllib", Files: []string{"metadata/", "data/"}},
+}
+
+// SupportedModelTypes returns the runtime and the model types of the adapter
+func (s *MLServerAdapterServer) SupportedModelTypes() util.SupportedModelTypes {
+	runtime := util.RuntimeInfo{Name: mlserverRuntimeName}
+	if s.AdapterConfig != nil {
+		runtime.Version = s.AdapterConfig.RuntimeVersion
+	}
+	return util.SupportedModelTypes{Runtime: runtime, ModelTypes: util.CopyModelTypes(supportedModelTypes)}
+}
+
+// RegisterModelTypesServer registers the mmesh.ModelTypes gRPC service
+func (s *MLServerAdapterServer) RegisterModelTypesServer(grpcServer *grpc.Server) {
+	util.RegisterModelTypesServer(grpcServer, s.SupportedModelTypes)
+}
+
+// ModelTypesHandler serves the SupportedModelTypes as JSON
+func (s *MLServerAdapterServer) ModelTypesHandler() http.Handler {
+	return util.ModelTypesHandler(s.SupportedModelTypes)
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSupportedModelTypes(t *testing.T) {
+	s := &MLServerAdapterServer{AdapterConfig: &AdapterConfiguration{RuntimeVersion: "1.3.2"}}
+	types := s.SupportedModelTypes()
+	if types.Runtime.Name != "mlserver" || types.Runtime.Version != "1.3.2" {
+		t.Errorf("Expected the runtime mlserver 1.3.2 but got %+v", types.Runtime)
+	}
+
+	listed := make(map[string]bool)
+	for _, mt := range types.ModelTypes {
+		listed[mt.Name] = true
+		extensions, ok := modelTypeArtifactExtensions[mt.Name]
+		if !ok {
+			continue
+		}
+		// the file patterns are the artifacts that checkModelArtifacts accepts
+		if len(mt.Files) != len(extensions) {
+			t.Errorf("Expected the files of model type %s to match %v but got %v", mt.Name, extensions, mt.Files)
+			continue
+		}
+		for i, ext := range extensions {
+			if matched, _ := filepath.Match(mt.Files[i], "model"+ext); !matched {
+				t.Errorf("Expected the files of model type %s to match %v but got %v", mt.Name, extensions, mt.Files)
+			}
+		}
+	}
+	for modelType := range modelTypeArtifactExtensions {
+		if !listed[modelType] {
+			t.Errorf("Model type %s has artifacts but is not listed", modelType)
+		}
+	}
+}
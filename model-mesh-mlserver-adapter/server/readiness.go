@@ -0,0 +1,80 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	mlserver "github.com/kserve/modelmesh-runtime-adapter/internal/proto/mlserver/dataplane"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+const modelReadyPollInterval = 250 * time.Millisecond
+
+// modelReadiness interprets the response of MLServer to a ModelReady request
+//
+// The model is ready when the response has ready=true and failed when MLServer
+// does not know the model. Other errors are treated as transient, so the
+// model is still not ready.
+func modelReadiness(resp *mlserver.ModelReadyResponse, err error) (util.ModelReadiness, string) {
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return util.ModelFailed, fmt.Sprintf("MLServer model load failed, model not found: %v", err)
+		}
+		return util.ModelNotReady, fmt.Sprintf("Failed to check whether the model is ready: %v", err)
+	}
+	if resp.GetReady() {
+		return util.ModelReady, ""
+	}
+	return util.ModelNotReady, "Waiting for model to become ready"
+}
+
+// waitForModelReady polls MLServer until the model is ready, for up to the
+// ModelReadyTimeout
+func (s *MLServerAdapterServer) waitForModelReady(ctx context.Context, modelId string, log logr.Logger) error {
+	var resp *mlserver.ModelReadyResponse
+	var respErr error
+	refresh := func() error {
+		resp, respErr = s.Client.ModelReady(ctx, &mlserver.ModelReadyRequest{Name: modelId})
+		return nil
+	}
+	refresh()
+
+	var waitErr error
+	util.WaitForModelsReady([]string{modelId}, s.AdapterConfig.ModelReadyTimeout, modelReadyPollInterval, refresh,
+		func(string) (util.ModelReadiness, string) {
+			if err := ctx.Err(); err != nil {
+				return util.ModelFailed, err.Error()
+			}
+			return modelReadiness(resp, respErr)
+		},
+		func(_ string, readiness util.ModelReadiness, message string) {
+			switch {
+			case ctx.Err() != nil:
+				waitErr = status.FromContextError(ctx.Err()).Err()
+			case readiness == util.ModelFailed:
+				waitErr = status.Errorf(codes.Internal, "Failed to load Model due to MLServer runtime error: %s", message)
+			case readiness == util.ModelNotReady:
+				waitErr = status.Errorf(codes.DeadlineExceeded, "Timed out waiting for MLServer to load the model: %s", message)
+			}
+		}, log)
+	return waitErr
+}
@@ -0,0 +1,44 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	mlserver "github.com/kserve/modelmesh-runtime-adapter/internal/proto/mlserver/dataplane"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+func TestModelReadiness(t *testing.T) {
+	testCases := []struct {
+		name     string
+		resp     *mlserver.ModelReadyResponse
+		err      error
+		expected util.ModelReadiness
+	}{
+		{"ready", &mlserver.ModelReadyResponse{Ready: true}, nil, util.ModelReady},
+		{"not ready", &mlserver.ModelReadyResponse{Ready: false}, nil, util.ModelNotReady},
+		{"unavailable", nil, status.Error(codes.Unavailable, "connection refused"), util.ModelNotReady},
+		{"not found", nil, status.Error(codes.NotFound, "Model model not found"), util.ModelFailed},
+	}
+	for _, tc := range testCases {
+		if got, message := modelReadiness(tc.resp, tc.err); got != tc.expected {
+			t.Errorf("Expected %s model to be %v, got %v: %s", tc.name, tc.expected, got, message)
+		}
+	}
+}
@@ -58,6 +58,17 @@ type AdapterConfiguration struct {
 	LimitModelConcurrency        int // 0 means no limit (default)
 	RootModelDir                 string
 	UseEmbeddedPuller            bool
+	StrictModelKey               bool
+	ModelKeyMaxSize              int
+	GrpcReflection               bool
+	GrpcCompression              bool
+	MetricsPort                  int // 0 means the metrics are not served
+	ValidateModelArtifacts       bool
+	LayoutRetries                int // 0 means transient filesystem errors are not retried
+	LayoutRetryBackoff           time.Duration
+	ModelFilePlacement           util.FilePlacement
+	ModelSymlinkPolicy           util.SymlinkPolicy
+	ModelReadyTimeout            time.Duration // 0 means loads do not wait for the model to be ready
 }
 
 type MLServerAdapterServer struct {
@@ -106,6 +117,17 @@ func NewMLServerAdapterServer(runtimePort int, config *AdapterConfiguration, log
 
 func (s *MLServerAdapterServer) LoadModel(ctx context.Context, req *mmesh.LoadModelRequest) (*mmesh.LoadModelResponse, error) {
 	log := s.Log.WithName("LoadModel").WithValues("modelId", req.ModelId)
+	if err := util.CheckModelKeyLimits(req, s.AdapterConfig.ModelKeyMaxSize); err != nil {
+		log.Error(err, "ModelKey exceeds the limits")
+		return nil, err
+	}
+	if s.AdapterConfig.StrictModelKey {
+		if err := util.ValidateModelKey(req); err != nil {
+			log.Error(err, "Invalid ModelKey")
+			return nil, err
+		}
+	}
+
 	modelType := util.GetModelType(req, log)
 	log.Info("Model details", "modelType", modelType, "modelPath", req.ModelPath)
 
@@ -124,7 +146,9 @@ func (s *MLServerAdapterServer) LoadModel(ctx context.Context, req *mmesh.LoadMo
 	}
 
 	// create a file layout from the files downloaded by the puller that can be loaded by the runtime
-	err = adaptModelLayoutForRuntime(s.AdapterConfig.RootModelDir, req.ModelId, modelType, req.ModelPath, schemaPath, log)
+	err = util.RetryTransientFileErrors(ctx, s.AdapterConfig.LayoutRetries, s.AdapterConfig.LayoutRetryBackoff, log, func() error {
+		return adaptModelLayoutForRuntime(s.AdapterConfig.RootModelDir, req.ModelId, modelType, req.ModelPath, schemaPath, s.AdapterConfig.ValidateModelArtifacts, s.AdapterConfig.ModelFilePlacement, s.AdapterConfig.ModelSymlinkPolicy, log)
+	})
 	if err != nil {
 		log.Error(err, "Failed to create model directory and load model")
 		return nil, status.Errorf(status.Code(err), "Failed to load Model due to adapter error: %v", err)
@@ -138,6 +162,13 @@ func (s *MLServerAdapterServer) LoadModel(ctx context.Context, req *mmesh.LoadMo
 		return nil, status.Errorf(status.Code(mlserverErr), "Failed to load Model due to MLServer runtime error: %v", mlserverErr)
 	}
 
+	if s.AdapterConfig.ModelReadyTimeout > 0 {
+		if err = s.waitForModelReady(ctx, req.ModelId, log); err != nil {
+			log.Error(err, "MLServer model did not become ready")
+			return nil, err
+		}
+	}
+
 	size := util.CalcMemCapacity(req.ModelKey, s.AdapterConfig.DefaultModelSizeInBytes, s.AdapterConfig.ModelSizeMultiplier, log)
 
 	log.Info("MLServer model loaded", "sizeInBytes", size)
@@ -149,7 +180,10 @@ func (s *MLServerAdapterServer) LoadModel(ctx context.Context, req *mmesh.LoadMo
 }
 
 // adaptModelLayoutForRuntime creates a directory that can be loaded by the runtime from the files downloaded by the puller
-func adaptModelLayoutForRuntime(rootModelDir, modelID, modelType, modelPath, schemaPath string, log logr.Logger) error {
+//
+// If validateArtifacts is set, a model without a settings file must include
+// the artifact that the implementation for its model type expects.
+func adaptModelLayoutForRuntime(rootModelDir, modelID, modelType, modelPath, schemaPath string, validateArtifacts bool, placement util.FilePlacement, symlinks util.SymlinkPolicy, log logr.Logger) error {
 	// convert to lower case and remove anything after a :
 	modelType = strings.ToLower(strings.Split(modelType, ":")[0])
 
@@ -177,7 +211,7 @@ func adaptModelLayoutForRuntime(rootModelDir, modelID, modelType, modelPath, sch
 
 	if !modelPathInfo.IsDir() {
 		// simpler case if ModelPath points to a file
-		err = adaptModelLayout(modelID, modelType, modelPath, schemaPath, mlserverModelIDDir, false, log)
+		err = adaptModelLayout(modelID, modelType, modelPath, schemaPath, mlserverModelIDDir, false, validateArtifacts, placement, symlinks, log)
 	} else {
 		// model path is a directory, inspect the files
 		files, err1 := os.ReadDir(modelPath)
@@ -200,9 +234,9 @@ func adaptModelLayoutForRuntime(rootModelDir, modelID, modelType, modelPath, sch
 			files[0], files[configFileIndex] = files[configFileIndex], files[0]
 		}
 		if assumeNativeLayout {
-			err = adaptNativeModelLayout(files, modelID, modelPath, schemaPath, mlserverModelIDDir, log)
+			err = adaptNativeModelLayout(files, modelID, modelPath, schemaPath, mlserverModelIDDir, placement, symlinks, log)
 		} else {
-			err = adaptModelLayout(modelID, modelType, modelPath, schemaPath, mlserverModelIDDir, true, log)
+			err = adaptModelLayout(modelID, modelType, modelPath, schemaPath, mlserverModelIDDir, true, validateArtifacts, placement, symlinks, log)
 		}
 	}
 	if err != nil {
@@ -216,7 +250,7 @@ func adaptModelLayoutForRuntime(rootModelDir, modelID, modelType, modelPath, sch
 // Only minimal changes should be made to the model repo to get it to load. For
 // MLServer, this means writing the model ID into the configuration file and
 // just symlinking all other files
-func adaptNativeModelLayout(files []os.DirEntry, modelID, modelPath, schemaPath, targetDir string, log logr.Logger) error {
+func adaptNativeModelLayout(files []os.DirEntry, modelID, modelPath, schemaPath, targetDir string, placement util.FilePlacement, symlinks util.SymlinkPolicy, log logr.Logger) error {
 	for _, f := range files {
 		filename := f.Name()
 		source, err := util.SecureJoin(modelPath, filename)
@@ -251,15 +285,15 @@ func adaptNativeModelLayout(files []os.DirEntry, modelID, modelPath, schemaPath,
 			}
 			continue
 		}
-		// symlink all other entries
+		// symlink, copy or move all other entries
 		link, err := util.SecureJoin(targetDir, filename)
 		if err != nil {
 			log.Error(err, "Unable to securely join", "targetDir", targetDir, "filename", filename)
 			return err
 		}
-		err = os.Symlink(source, link)
+		err = util.PlaceFile(modelPath, source, link, placement, symlinks)
 		if err != nil {
-			return fmt.Errorf("error creating symlink to %s: %w", source, err)
+			return fmt.Errorf("error placing %s with %s: %w", source, placement, err)
 		}
 	}
 
@@ -324,16 +358,22 @@ func processConfigJSON(jsonIn []byte, modelID string, targetDir string, schemaPa
 // - inject schema information if schemaPath is included
 // - use symlinks to reference files from the source modelPath
 // - use modelPath to construct the model's URI as an absolute path
-func adaptModelLayout(modelID, modelType, modelPath, schemaPath, targetDir string, isDir bool, log logr.Logger) error {
-	// soft-link to either directory or file depending on the input received
+func adaptModelLayout(modelID, modelType, modelPath, schemaPath, targetDir string, isDir bool, validateArtifacts bool, placement util.FilePlacement, symlinks util.SymlinkPolicy, log logr.Logger) error {
+	if validateArtifacts {
+		if err := checkModelArtifacts(modelType, modelPath); err != nil {
+			return err
+		}
+	}
+
+	// soft-link to, copy or move either directory or file depending on the input received
 	linkPath, err := util.SecureJoin(targetDir, filepath.Base(modelPath))
 	if err != nil {
 		log.Error(err, "Unable to securely join", "targetDir", targetDir, "filename", filepath.Base(modelPath))
 		return err
 	}
 
-	if err = os.Symlink(modelPath, linkPath); err != nil {
-		return fmt.Errorf("Error creating symlink: %w", err)
+	if err = util.PlaceFile(modelPath, modelPath, linkPath, placement, symlinks); err != nil {
+		return fmt.Errorf("Error placing model files with %s: %w", placement, err)
 	}
 
 	// generate the required configuration file
@@ -407,7 +447,30 @@ func generateModelConfigJSON(modelID string, modelType string, uri string, schem
 	return jsonOut, nil
 }
 
+// schema datatypes that are part of MLServer's V2 inference protocol, and the
+// STRING extension, which was always passed through to MLServer as it is
+var supportedDatatypes = []string{
+	modelschema.BOOL,
+	modelschema.UINT8,
+	modelschema.UINT16,
+	modelschema.UINT32,
+	modelschema.UINT64,
+	modelschema.INT8,
+	modelschema.INT16,
+	modelschema.INT32,
+	modelschema.INT64,
+	modelschema.FP16,
+	modelschema.FP32,
+	modelschema.FP64,
+	modelschema.BYTES,
+	modelschema.STRING,
+}
+
 func processSchema(c map[string]interface{}, s *modelschema.ModelSchema) error {
+	if err := s.ValidateDatatypes(supportedDatatypes); err != nil {
+		return err
+	}
+
 	if s.Inputs != nil {
 		inputs := make([]interface{}, len(s.Inputs))
 		for i, m := range s.Inputs {
@@ -31,6 +31,8 @@ import (
 	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -267,3 +269,37 @@ func assertGeneratedModelDirIsCorrect(sourceDir string, generatedDir string, mod
 		t.Errorf("Did not find config file [%s] in [%s]", mlserverRepositoryConfigFilename, generatedDir)
 	}
 }
+
+func TestLoadModelStrictModelKey(t *testing.T) {
+	s := &MLServerAdapterServer{
+		AdapterConfig: &AdapterConfiguration{StrictModelKey: true},
+		Log:           log,
+	}
+
+	for _, modelKey := range []string{`{"model_type": {"name": "sklearn"`, `{"model_type": sklearn}`} {
+		_, err := s.LoadModel(context.Background(), &mmesh.LoadModelRequest{
+			ModelId:   "mnist-svm-00000000",
+			ModelType: "sklearn",
+			ModelKey:  modelKey,
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for ModelKey %s but got %v", modelKey, err)
+		}
+	}
+}
+
+func TestLoadModelModelKeyLimits(t *testing.T) {
+	s := &MLServerAdapterServer{
+		AdapterConfig: &AdapterConfiguration{ModelKeyMaxSize: 64},
+		Log:           log,
+	}
+
+	_, err := s.LoadModel(context.Background(), &mmesh.LoadModelRequest{
+		ModelId:   "mnist-svm-00000000",
+		ModelType: "sklearn",
+		ModelKey:  `{"model_type": {"name": "sklearn"}, "labels": {"padding": "` + strings.Repeat("x", 64) + `"}}`,
+	})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted for an oversized ModelKey but got %v", err)
+	}
+}
@@ -17,9 +17,11 @@ package main
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 
 	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 	"github.com/kserve/modelmesh-runtime-adapter/model-mesh-ovms-adapter/server"
 	"google.golang.org/grpc"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -44,13 +46,29 @@ func main() {
 	}
 	log.Info("Adapter will run at port", "port", adapterConfig.Port, "OpenVINO port", adapterConfig.OvmsPort)
 
-	grpcServer := grpc.NewServer()
+	if adapterConfig.MetricsPort > 0 {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", server.MetricsHandler())
+		mux.Handle("/v1/model-types", server.ModelTypesHandler())
+		mux.Handle("/debug/state", server.ModelManager.DebugHandler(adapterConfig.DebugTokenFile))
+		go func() {
+			log.Info("Serving metrics", "port", adapterConfig.MetricsPort)
+			if err := http.ListenAndServe(fmt.Sprintf(":%d", adapterConfig.MetricsPort), mux); err != nil {
+				log.Error(err, "*** Metrics server terminated with error")
+			}
+		}()
+	}
+
+	grpcServer := grpc.NewServer(util.GrpcCompressionServerOptions(adapterConfig.GrpcCompression, log)...)
 	mmesh.RegisterModelRuntimeServer(grpcServer, server)
+	server.RegisterModelTypesServer(grpcServer)
+	util.RegisterReflection(grpcServer, adapterConfig.GrpcReflection, log)
 	log.Info("Adapter gRPC Server Registered, now serving")
 
 	if err = grpcServer.Serve(lis); err != nil {
 		log.Error(err, "*** Adapter terminated with error ")
 	} else {
 		log.Info("*** Adapter terminated")
+		server.CleanupOnShutdown()
 	}
 }
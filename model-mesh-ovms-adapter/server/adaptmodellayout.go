@@ -27,7 +27,12 @@ import (
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 )
 
-func adaptModelLayoutForRuntime(ctx context.Context, rootModelDir, modelID, modelType, modelPath, schemaPath string, log logr.Logger) error {
+// adaptModelLayoutForRuntime creates the layout of a model in the model
+// repository of OVMS. With stage, the layout is created in a staging directory
+// next to it and only renamed into place, the commit, once all of its files
+// are placed, so that a reload of OVMS in the meantime never sees a partial
+// layout.
+func adaptModelLayoutForRuntime(ctx context.Context, rootModelDir, modelID, modelType, modelPath, schemaPath string, placement util.FilePlacement, symlinks util.SymlinkPolicy, verify, stage bool, log logr.Logger) error {
 	// convert to lower case and remove anything after the :
 	modelType = strings.ToLower(strings.Split(modelType, ":")[0])
 
@@ -36,12 +41,21 @@ func adaptModelLayoutForRuntime(ctx context.Context, rootModelDir, modelID, mode
 		log.Error(err, "Unable to securely join", "rootModelDir", rootModelDir, "modelID", modelID)
 		return err
 	}
-	// clean up and then create directory where the rewritten model repo will live
-	if removeErr := os.RemoveAll(ovmsModelIDDir); removeErr != nil {
-		log.Info("Ignoring error trying to remove dir", "Directory", ovmsModelIDDir, "Error", removeErr)
-	}
-	if mkdirErr := os.MkdirAll(ovmsModelIDDir, 0755); mkdirErr != nil {
-		return fmt.Errorf("Error creating directories for path %s: %w", ovmsModelIDDir, mkdirErr)
+	layoutDir := ovmsModelIDDir
+	if stage {
+		if layoutDir, err = createStagingDir(ovmsModelIDDir); err != nil {
+			return err
+		}
+		// nothing is left to remove once the layout is committed
+		defer os.RemoveAll(layoutDir)
+	} else {
+		// clean up and then create directory where the rewritten model repo will live
+		if removeErr := os.RemoveAll(ovmsModelIDDir); removeErr != nil {
+			log.Info("Ignoring error trying to remove dir", "Directory", ovmsModelIDDir, "Error", removeErr)
+		}
+		if mkdirErr := os.MkdirAll(ovmsModelIDDir, 0755); mkdirErr != nil {
+			return fmt.Errorf("Error creating directories for path %s: %w", ovmsModelIDDir, mkdirErr)
+		}
 	}
 
 	modelPathInfo, err := os.Stat(modelPath)
@@ -51,25 +65,58 @@ func adaptModelLayoutForRuntime(ctx context.Context, rootModelDir, modelID, mode
 
 	if !modelPathInfo.IsDir() {
 		// simple case if ModelPath points to a file
-		err = createOvmsModelRepositoryFromPath(modelPath, "1", schemaPath, modelType, ovmsModelIDDir, log)
+		err = createOvmsModelRepositoryFromPath(modelPath, modelPath, "1", schemaPath, modelType, layoutDir, placement, symlinks, verify, log)
 	} else {
 		files, err1 := os.ReadDir(modelPath)
 		if err1 != nil {
 			return fmt.Errorf("Could not read files in dir %s: %w", modelPath, err1)
 		}
-		err = createOvmsModelRepositoryFromDirectory(files, modelPath, schemaPath, modelType, ovmsModelIDDir, log)
+		err = createOvmsModelRepositoryFromDirectory(files, modelPath, schemaPath, modelType, layoutDir, placement, symlinks, verify, log)
 	}
 	if err != nil {
 		return fmt.Errorf("Error processing model/schema files for model %s: %w", modelID, err)
 	}
 
+	if stage {
+		if removeErr := os.RemoveAll(ovmsModelIDDir); removeErr != nil {
+			log.Info("Ignoring error trying to remove dir", "Directory", ovmsModelIDDir, "Error", removeErr)
+		}
+		if err = os.Rename(layoutDir, ovmsModelIDDir); err != nil {
+			return fmt.Errorf("Error committing the staged layout of model %s: %w", modelID, err)
+		}
+		log.V(1).Info("Committed the staged layout", "path", ovmsModelIDDir)
+	}
+
 	return nil
 }
 
+// createStagingDir creates an empty directory next to the layout directory of
+// a model, in which its layout is staged
+func createStagingDir(ovmsModelIDDir string) (string, error) {
+	parentDir := filepath.Dir(ovmsModelIDDir)
+	if err := os.MkdirAll(parentDir, 0755); err != nil {
+		return "", fmt.Errorf("Error creating directories for path %s: %w", parentDir, err)
+	}
+	stagingDir, err := os.MkdirTemp(parentDir, "."+filepath.Base(ovmsModelIDDir)+".staging-")
+	if err != nil {
+		return "", fmt.Errorf("Error creating a staging directory in %s: %w", parentDir, err)
+	}
+	// the layout is read by OVMS once it is committed
+	if err = os.Chmod(stagingDir, 0755); err != nil {
+		os.RemoveAll(stagingDir)
+		return "", fmt.Errorf("Error creating a staging directory in %s: %w", parentDir, err)
+	}
+	return stagingDir, nil
+}
+
 // Creates the ovms model structure /models/_ovms_models/model-id/1/<model files>
-// Within this path there will be a symlink back to the original /models/model-id directory tree.
-func createOvmsModelRepositoryFromDirectory(files []os.DirEntry, modelPath, schemaPath, modelType, ovmsModelIDDir string, log logr.Logger) error {
+// Within this path there will be a symlink back to the original /models/model-id directory tree,
+// or the model files themselves if they are copied or moved. Symlinks in the
+// placed files are checked against the whole ModelPath, since a version
+// directory may link to files next to it.
+func createOvmsModelRepositoryFromDirectory(files []os.DirEntry, modelPath, schemaPath, modelType, ovmsModelIDDir string, placement util.FilePlacement, symlinks util.SymlinkPolicy, verify bool, log logr.Logger) error {
 	var err error
+	rootPath := modelPath
 
 	// allow the directory to contain version directories
 	// try to find the largest version directory
@@ -84,10 +131,10 @@ func createOvmsModelRepositoryFromDirectory(files []os.DirEntry, modelPath, sche
 		versionNumber = "1"
 	}
 
-	return createOvmsModelRepositoryFromPath(modelPath, versionNumber, schemaPath, modelType, ovmsModelIDDir, log)
+	return createOvmsModelRepositoryFromPath(rootPath, modelPath, versionNumber, schemaPath, modelType, ovmsModelIDDir, placement, symlinks, verify, log)
 }
 
-func createOvmsModelRepositoryFromPath(modelPath, versionNumber, schemaPath, modelType, ovmsModelIDDir string, log logr.Logger) error {
+func createOvmsModelRepositoryFromPath(rootPath, modelPath, versionNumber, schemaPath, modelType, ovmsModelIDDir string, placement util.FilePlacement, symlinks util.SymlinkPolicy, verify bool, log logr.Logger) error {
 	var err error
 
 	modelPathInfo, err := os.Stat(modelPath)
@@ -114,8 +161,28 @@ func createOvmsModelRepositoryFromPath(modelPath, versionNumber, schemaPath, mod
 		return fmt.Errorf("Error creating directories for path %s: %w", linkPath, err)
 	}
 
-	if err = os.Symlink(modelPath, linkPath); err != nil {
-		return fmt.Errorf("Error creating symlink: %w", err)
+	// a symlink is listed through to the source, so only the files that are
+	// copied or moved are verified
+	verify = verify && placement != util.FilePlacementLink
+
+	// the source is listed before it is placed, since a move removes it
+	var sourceFiles util.FileListing
+	if verify {
+		if sourceFiles, err = util.ListFiles(modelPath); err != nil {
+			return fmt.Errorf("Error listing model files in %s: %w", modelPath, err)
+		}
+	}
+
+	if err = placeFile(rootPath, modelPath, linkPath, placement, symlinks); err != nil {
+		undoMove(modelPath, linkPath, placement, log)
+		return fmt.Errorf("Error placing model files with %s: %w", placement, err)
+	}
+
+	if verify {
+		if err = checkStagedFiles(sourceFiles, modelPath, linkPath, log); err != nil {
+			undoMove(modelPath, linkPath, placement, log)
+			return err
+		}
 	}
 
 	if schemaPath == "" {
@@ -125,6 +192,40 @@ func createOvmsModelRepositoryFromPath(modelPath, versionNumber, schemaPath, mod
 	return nil
 }
 
+// checkStagedFiles compares the files staged at linkPath with the listing of the
+// source files
+func checkStagedFiles(sourceFiles util.FileListing, modelPath, linkPath string, log logr.Logger) error {
+	stagedFiles, err := util.ListFiles(linkPath)
+	if err != nil {
+		return fmt.Errorf("Error listing staged model files in %s: %w", linkPath, err)
+	}
+	if err = util.DiffFileListings(sourceFiles, stagedFiles); err != nil {
+		return fmt.Errorf("Staged model files in %s do not match the source %s: %w", linkPath, modelPath, err)
+	}
+	log.V(1).Info("Verified the staged model files", "path", linkPath, "numFiles", len(stagedFiles))
+	return nil
+}
+
+// undoMove moves the files of a failed layout back to the source, so that a
+// retry of the layout finds them there again. The files are moved back like
+// they were moved, with a copy if they are on another filesystem.
+func undoMove(modelPath, linkPath string, placement util.FilePlacement, log logr.Logger) {
+	if placement != util.FilePlacementMove {
+		return
+	}
+	if _, err := os.Lstat(modelPath); !os.IsNotExist(err) {
+		// nothing was moved, or a copy was made instead
+		return
+	}
+	if err := util.PlaceFile(linkPath, linkPath, modelPath, util.FilePlacementMove, util.SymlinkPolicyPreserve); err != nil && !os.IsNotExist(err) {
+		log.Error(err, "Failed to move the model files back after a failed layout", "source", linkPath, "target", modelPath)
+	}
+}
+
+// placeFile places the model files in the model repository of OVMS, which is
+// replaced in tests
+var placeFile = util.PlaceFile
+
 // Returns the largest positive int dir as long as all fileInfo dirs are integers (files are ignored).
 // If fileInfos is empty or contains any non-integer dirs, this will return the empty string.
 func largestNumberDir(fileInfos []os.DirEntry) string {
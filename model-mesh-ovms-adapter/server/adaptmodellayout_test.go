@@ -17,10 +17,16 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 )
 
@@ -104,7 +110,7 @@ func TestAdaptModelLayoutForRuntime(t *testing.T) {
 			if tt.SchemaPath != "" {
 				schemaFullPath = filepath.Join(tt.getSourceDir(), tt.SchemaPath)
 			}
-			err = adaptModelLayoutForRuntime(context.Background(), ovmsRootModelDir, tt.ModelID, tt.ModelType, modelFullPath, schemaFullPath, log)
+			err = adaptModelLayoutForRuntime(context.Background(), ovmsRootModelDir, tt.ModelID, tt.ModelType, modelFullPath, schemaFullPath, util.FilePlacementLink, util.SymlinkPolicyDereference, false, false, log)
 
 			if tt.ExpectError && err == nil {
 				t.Fatal("ExpectError is true, but no error was returned")
@@ -143,7 +149,7 @@ func TestAdaptModelLayoutForRuntime_Multiple(t *testing.T) {
 		if tt.SchemaPath != "" {
 			schemaFullPath = filepath.Join(tt.getSourceDir(), tt.SchemaPath)
 		}
-		err = adaptModelLayoutForRuntime(ctx, ovmsRootModelDir, tt.ModelID, tt.ModelType, modelFullPath, schemaFullPath, log)
+		err = adaptModelLayoutForRuntime(ctx, ovmsRootModelDir, tt.ModelID, tt.ModelType, modelFullPath, schemaFullPath, util.FilePlacementLink, util.SymlinkPolicyDereference, false, false, log)
 		if tt.ExpectError && err == nil {
 			t.Fatal("ExpectError is true, but no error was returned")
 		}
@@ -165,6 +171,189 @@ func TestAdaptModelLayoutForRuntime_Multiple(t *testing.T) {
 	}
 }
 
+func TestAdaptModelLayoutVerifiesStagedFiles(t *testing.T) {
+	defaultPlaceFile := placeFile
+	defer func() { placeFile = defaultPlaceFile }()
+
+	for _, placement := range []util.FilePlacement{util.FilePlacementLink, util.FilePlacementCopy, util.FilePlacementMove} {
+		t.Run(string(placement), func(t *testing.T) {
+			newModelDir := func() string {
+				modelDir := filepath.Join(t.TempDir(), "model")
+				for _, f := range []string{"1/saved_model.pb", "1/variables/variables.index", "1/variables/variables.data-00000-of-00001"} {
+					if err := os.MkdirAll(filepath.Dir(filepath.Join(modelDir, f)), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(filepath.Join(modelDir, f), []byte(f), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				return modelDir
+			}
+			rootModelDir := t.TempDir()
+
+			placeFile = defaultPlaceFile
+			if err := adaptModelLayoutForRuntime(context.Background(), rootModelDir, "model", "tensorflow", newModelDir(), "", placement, util.SymlinkPolicyDereference, true, false, log); err != nil {
+				t.Fatalf("Expected the staged files to match, got: %v", err)
+			}
+
+			// a partial copy that dropped a file
+			placeFile = func(root, source, target string, placement util.FilePlacement, symlinks util.SymlinkPolicy) error {
+				if err := util.PlaceFile(root, source, target, util.FilePlacementCopy, symlinks); err != nil {
+					return err
+				}
+				return os.Remove(filepath.Join(target, "variables", "variables.index"))
+			}
+			err := adaptModelLayoutForRuntime(context.Background(), rootModelDir, "model", "tensorflow", newModelDir(), "", placement, util.SymlinkPolicyDereference, true, false, log)
+			expectedMessage := "missing: variables/variables.index (27 bytes)"
+			if placement == util.FilePlacementLink {
+				// a link is not verified, it lists the same as the source
+				if err != nil {
+					t.Errorf("Expected the link placement not to be verified, got: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), expectedMessage) {
+				t.Errorf("Expected the dropped file to fail the layout with '%s', got: %v", expectedMessage, err)
+			}
+
+			// the verification is disabled by default
+			if err = adaptModelLayoutForRuntime(context.Background(), rootModelDir, "model", "tensorflow", newModelDir(), "", placement, util.SymlinkPolicyDereference, false, false, log); err != nil {
+				t.Errorf("Expected the layout without the verification to succeed, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestAdaptModelLayoutRetriesVerifiedMove(t *testing.T) {
+	defaultPlaceFile := placeFile
+	defer func() { placeFile = defaultPlaceFile }()
+
+	modelDir := filepath.Join(t.TempDir(), "model")
+	if err := os.MkdirAll(filepath.Join(modelDir, "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(modelDir, "1", "model.onnx"), []byte("model"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rootModelDir := t.TempDir()
+
+	// the first move succeeds but reports a transient error
+	attempts := 0
+	placeFile = func(root, source, target string, placement util.FilePlacement, symlinks util.SymlinkPolicy) error {
+		attempts++
+		if err := util.PlaceFile(root, source, target, placement, symlinks); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return syscall.EIO
+		}
+		return nil
+	}
+	err := util.RetryTransientFileErrors(context.Background(), 1, time.Millisecond, log, func() error {
+		return adaptModelLayoutForRuntime(context.Background(), rootModelDir, "model", "onnx", modelDir, "", util.FilePlacementMove, util.SymlinkPolicyDereference, true, false, log)
+	})
+	if err != nil {
+		t.Fatalf("Expected the retry to move and verify the files again, got: %v", err)
+	}
+	if contents, err := os.ReadFile(filepath.Join(rootModelDir, "model", "1", "model.onnx")); err != nil || string(contents) != "model" {
+		t.Errorf("Expected the moved model file, got '%s': %v", contents, err)
+	}
+}
+
+func TestLoadModelDefersReloadUntilStaged(t *testing.T) {
+	defaultPlaceFile := placeFile
+	defer func() { placeFile = defaultPlaceFile }()
+
+	m := NewMockOVMS()
+	defer m.Close()
+	available := OvmsModelStatusResponse{
+		ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+	}
+	if err := m.setMockReloadResponse(OvmsConfigResponse{"model": available, "model-other": available}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{
+		BatchWaitTimeMin: 10 * time.Millisecond,
+		BatchWaitTimeMax: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+	rootModelDir := t.TempDir()
+	s := &OvmsAdapterServer{
+		ModelManager: mm,
+		AdapterConfig: &AdapterConfiguration{
+			RootModelDir:           rootModelDir,
+			ModelSizeMultiplier:    1,
+			ModelFilePlacement:     util.FilePlacementCopy,
+			ModelSymlinkPolicy:     util.SymlinkPolicyDereference,
+			DeferReloadUntilStaged: true,
+		},
+		Log: log,
+	}
+
+	// a model with several versions
+	modelPath := filepath.Join(t.TempDir(), "model")
+	for _, version := range []string{"1", "2", "3"} {
+		if err = os.MkdirAll(filepath.Join(modelPath, version), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filepath.Join(modelPath, version, "model.onnx"), []byte("model"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	otherPath := filepath.Join(t.TempDir(), "model-other")
+	if err = os.MkdirAll(filepath.Join(otherPath, "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// another model is loaded while the files are placed, and its reload
+	// sees no partial layout
+	placeFile = func(root, source, target string, placement util.FilePlacement, symlinks util.SymlinkPolicy) error {
+		if err := util.PlaceFile(root, source, target, placement, symlinks); err != nil {
+			return err
+		}
+		if err := mm.LoadModel(context.Background(), otherPath, "model-other", "onnx", "", nil, nil); err != nil {
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(rootModelDir, "model")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected the layout to be staged elsewhere before the commit, got: %v", err)
+		}
+		return nil
+	}
+
+	if _, err = s.LoadModel(context.Background(), &mmesh.LoadModelRequest{
+		ModelId:   "model",
+		ModelPath: modelPath,
+		ModelType: "onnx",
+		ModelKey:  `{"model_type": {"name": "onnx"}}`,
+	}); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+	if count := m.getReloadCount(); count != 2 {
+		t.Errorf("Expected 1 reload for the model after the commit, but got %d", count-1)
+	}
+	if models, writtenBytes := readConfiguredModels(t, configFile); models["model"] != filepath.Join(rootModelDir, "model") {
+		t.Errorf("Expected the committed layout in the config, got: %s", string(writtenBytes))
+	}
+	if contents, err := os.ReadFile(filepath.Join(rootModelDir, "model", "3", "model.onnx")); err != nil || string(contents) != "model" {
+		t.Errorf("Expected the latest version in the committed layout, got '%s': %v", contents, err)
+	}
+
+	// a layout that fails keeps the committed one, and no staging directory
+	placeFile = func(root, source, target string, placement util.FilePlacement, symlinks util.SymlinkPolicy) error {
+		return errors.New("placement failed")
+	}
+	if err = adaptModelLayoutForRuntime(context.Background(), rootModelDir, "model", "onnx", modelPath, "", util.FilePlacementCopy, util.SymlinkPolicyDereference, false, true, log); err == nil {
+		t.Errorf("Expected the failed placement to fail the layout")
+	}
+	if _, err = os.Stat(filepath.Join(rootModelDir, "model", "3", "model.onnx")); err != nil {
+		t.Errorf("Expected the committed layout to be kept: %v", err)
+	}
+	if entries, err := os.ReadDir(rootModelDir); err != nil || len(entries) != 1 {
+		t.Errorf("Expected only the committed layout in %s, got %v: %v", rootModelDir, entries, err)
+	}
+}
+
 //
 // Helper functions
 //
@@ -16,6 +16,8 @@ package server
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
@@ -42,24 +44,107 @@ const (
 	defaultModelSizeInBytes                = 1000000
 	modelSizeMultiplier             string = "MODELSIZE_MULTIPLIER"
 	defaultModelSizeMultiplier             = 1.25
+	modelSizeMultipliers            string = "MODELSIZE_MULTIPLIERS"
+	defaultModelSizeMultipliers            = "" // empty means the models of every type use the MODELSIZE_MULTIPLIER
 	runtimeVersion                  string = "RUNTIME_VERSION"
 	defaultRuntimeVersion                  = "v1"
 	limitPerModelConcurrency        string = "LIMIT_PER_MODEL_CONCURRENCY"
 	defaultLimitPerModelConcurrency        = 0 // 0 means don't limit request concurrency
 	rootModelDir                    string = "ROOT_MODEL_DIR"
 	defaultRootModelDir                    = "/models"
+	modelTypeRootDirs               string = "MODEL_TYPE_ROOT_DIRS"
+	defaultModelTypeRootDirs               = "" // empty means the models of every type are under the ROOT_MODEL_DIR
 	useEmbeddedPuller               string = "USE_EMBEDDED_PULLER"
 	defaultUseEmbeddedPuller               = false
+	strictModelKey                  string = "STRICT_MODEL_KEY"
+	defaultStrictModelKey                  = false
+	strictModelTypes                string = "STRICT_MODEL_TYPES"
+	defaultStrictModelTypes                = false
+	modelKeyMaxSize                 string = "MODEL_KEY_MAX_SIZE"
+	defaultModelKeyMaxSize                 = 0 // 0 means the size of the ModelKey is not limited
+	grpcReflection                  string = "GRPC_REFLECTION"
+	defaultGrpcReflection                  = false
+	grpcCompression                 string = "GRPC_COMPRESSION"
+	defaultGrpcCompression                 = false
+	layoutRetries                   string = "LAYOUT_RETRIES"
+	defaultLayoutRetries                   = 0 // 0 means transient filesystem errors are not retried
+	layoutRetryBackoff              string = "LAYOUT_RETRY_BACKOFF"
+	defaultLayoutRetryBackoff              = 500 * time.Millisecond
+	modelFilePlacement              string = "MODEL_FILE_PLACEMENT"
+	defaultModelFilePlacement              = util.FilePlacementLink
+	modelSymlinkPolicy              string = "MODEL_SYMLINK_POLICY"
+	defaultModelSymlinkPolicy              = util.SymlinkPolicyDereference
+	cleanupOnShutdown               string = "CLEANUP_ON_SHUTDOWN"
+	defaultCleanupOnShutdown               = false
+	cleanupMountedRoot              string = "CLEANUP_MOUNTED_ROOT"
+	defaultCleanupMountedRoot              = false
+	runtimeCgroupDir                string = "CAPACITY_RUNTIME_CGROUP_DIR"
+	defaultRuntimeCgroupDir                = "" // empty means the capacity is static
+	loadSubModels                   string = "LOAD_SUBMODELS"
+	defaultLoadSubModels                   = false
+	verifyStagedFiles               string = "VERIFY_STAGED_FILES"
+	defaultVerifyStagedFiles               = false
+	deferReloadUntilStaged          string = "DEFER_RELOAD_UNTIL_STAGED"
+	defaultDeferReloadUntilStaged          = false
 
 	// OVMS adapter specific
-	modelConfigFile         string = "MODEL_CONFIG_FILE"
-	defaultModelConfigFile         = "/models/model_config_list.json"
-	batchWaitTimeMin        string = "BATCH_WAIT_TIME_MIN"
-	defaultBatchWaitTimeMin        = 100 * time.Millisecond
-	batchWaitTimeMax        string = "BATCH_WAIT_TIME_MAX"
-	defaultBatchWaitTimeMax        = 3 * time.Second
-	reloadTimeout           string = "OVMS_RELOAD_TIMEOUT"
-	defaultReloadTimeout           = 30 * time.Second
+	modelConfigFile                string = "MODEL_CONFIG_FILE"
+	defaultModelConfigFile                = "/models/model_config_list.json"
+	batchWaitTimeMin               string = "BATCH_WAIT_TIME_MIN"
+	defaultBatchWaitTimeMin               = 100 * time.Millisecond
+	batchWaitTimeMax               string = "BATCH_WAIT_TIME_MAX"
+	defaultBatchWaitTimeMax               = 3 * time.Second
+	unloadGracePeriod              string = "UNLOAD_GRACE_PERIOD"
+	defaultUnloadGracePeriod              = defaultBatchWaitTimeMax
+	reloadTimeout                  string = "OVMS_RELOAD_TIMEOUT"
+	defaultReloadTimeout                  = 30 * time.Second
+	ovmsApiVersion                 string = "OVMS_API_VERSION"
+	modelStateTimeout              string = "OVMS_MODEL_STATE_TIMEOUT"
+	defaultModelStateTimeout              = 10 * time.Second
+	waitForMissingModels           string = "OVMS_WAIT_FOR_MISSING_MODELS"
+	defaultWaitForMissingModels           = false
+	pruneStaleModelConfig          string = "PRUNE_STALE_MODEL_CONFIG"
+	defaultPruneStaleModelConfig          = false
+	reconcileOnBoot                string = "RECONCILE_ON_BOOT"
+	defaultReconcileOnBoot                = false
+	reconcileConcurrency           string = "RECONCILE_CONCURRENCY"
+	defaultReconcileConcurrency           = 8
+	reconcileEmptyConfig           string = "RECONCILE_EMPTY_CONFIG"
+	defaultReconcileEmptyConfig           = false
+	startupUnloadMode              string = "STARTUP_UNLOAD_MODE"
+	defaultStartupUnloadMode              = StartupUnloadWipe
+	sanitizeModelNames             string = "SANITIZE_MODEL_NAMES"
+	defaultSanitizeModelNames             = false
+	metricsPort                    string = "METRICS_PORT"
+	defaultMetricsPort                    = 0 // 0 means the metrics are not served
+	debugTokenFile                 string = "DEBUG_TOKEN_FILE"
+	defaultDebugTokenFile                 = "" // empty means the debug endpoint is disabled
+	circuitBreakerThreshold        string = "RUNTIME_CIRCUIT_BREAKER_THRESHOLD"
+	defaultCircuitBreakerThreshold        = 0 // 0 means the breaker is disabled
+	circuitBreakerCooldown         string = "RUNTIME_CIRCUIT_BREAKER_COOLDOWN"
+	defaultCircuitBreakerCooldown         = 30 * time.Second
+	initialReloadDeadline          string = "INITIAL_RELOAD_DEADLINE"
+	defaultInitialReloadDeadline          = 30 * time.Second
+	metricsModelLabels             string = "METRICS_MODEL_LABELS"
+	defaultMetricsModelLabels             = "" // empty means no model labels are attached
+	modelHealthFailurePolls        string = "MODEL_HEALTH_FAILURE_POLLS"
+	defaultModelHealthFailurePolls        = 2
+	modelHealthWindow              string = "MODEL_HEALTH_WINDOW"
+	defaultModelHealthWindow              = 30 * time.Second
+	logDedupWindow                 string = "LOG_DEDUP_WINDOW"
+	defaultLogDedupWindow                 = time.Minute
+	configEditPolicy               string = "CONFIG_EDIT_POLICY"
+	defaultConfigEditPolicy               = ConfigEditOverwrite
+	nameCollisionCode              string = "NAME_COLLISION_CODE"
+	defaultNameCollisionCode              = NameCollisionInvalidArgument
+	skipUnchangedReloads           string = "SKIP_UNCHANGED_RELOADS"
+	defaultSkipUnchangedReloads           = false
+	fileVisibilityGrace            string = "FILE_VISIBILITY_GRACE"
+	defaultFileVisibilityGrace            = 0 * time.Second // 0 means the loads do not wait for the files
+	pluginConfigDefaults           string = "PLUGIN_CONFIG_DEFAULTS"
+	defaultPluginConfigDefaults           = "" // empty means the models of every type have no default plugin_config
+	fsyncPolicy                    string = "FSYNC_POLICY"
+	defaultFsyncPolicy                    = util.FsyncNever
 )
 
 func GetAdapterConfigurationFromEnv(log logr.Logger) (*AdapterConfiguration, error) {
@@ -76,24 +161,166 @@ func GetAdapterConfigurationFromEnv(log logr.Logger) (*AdapterConfiguration, err
 	adapterConfig.RuntimeVersion = GetEnvString(runtimeVersion, defaultRuntimeVersion)
 	adapterConfig.LimitModelConcurrency = GetEnvInt(limitPerModelConcurrency, defaultLimitPerModelConcurrency, log)
 	adapterConfig.UseEmbeddedPuller = GetEnvBool(useEmbeddedPuller, defaultUseEmbeddedPuller, log)
+	adapterConfig.CleanupOnShutdown = GetEnvBool(cleanupOnShutdown, defaultCleanupOnShutdown, log)
+	adapterConfig.CleanupMountedRoot = GetEnvBool(cleanupMountedRoot, defaultCleanupMountedRoot, log)
+	adapterConfig.RuntimeCgroupDir = GetEnvString(runtimeCgroupDir, defaultRuntimeCgroupDir)
+	adapterConfig.LoadSubModels = GetEnvBool(loadSubModels, defaultLoadSubModels, log)
+	adapterConfig.VerifyStagedFiles = GetEnvBool(verifyStagedFiles, defaultVerifyStagedFiles, log)
+	adapterConfig.DeferReloadUntilStaged = GetEnvBool(deferReloadUntilStaged, defaultDeferReloadUntilStaged, log)
+	adapterConfig.StrictModelKey = GetEnvBool(strictModelKey, defaultStrictModelKey, log)
+	adapterConfig.StrictModelTypes = GetEnvBool(strictModelTypes, defaultStrictModelTypes, log)
+	adapterConfig.ModelKeyMaxSize = GetEnvInt(modelKeyMaxSize, defaultModelKeyMaxSize, log)
+	adapterConfig.GrpcReflection = GetEnvBool(grpcReflection, defaultGrpcReflection, log)
+	adapterConfig.GrpcCompression = GetEnvBool(grpcCompression, defaultGrpcCompression, log)
+	adapterConfig.LayoutRetries = GetEnvInt(layoutRetries, defaultLayoutRetries, log)
+	adapterConfig.LayoutRetryBackoff = GetEnvDuration(layoutRetryBackoff, defaultLayoutRetryBackoff, log)
 
 	var err error
+	adapterConfig.ModelFilePlacement, err = util.ParseFilePlacement(GetEnvString(modelFilePlacement, string(defaultModelFilePlacement)))
+	if err != nil {
+		return nil, fmt.Errorf("%s environment variable is invalid: %w", modelFilePlacement, err)
+	}
+	adapterConfig.ModelSymlinkPolicy, err = util.ParseSymlinkPolicy(GetEnvString(modelSymlinkPolicy, string(defaultModelSymlinkPolicy)))
+	if err != nil {
+		return nil, fmt.Errorf("%s environment variable is invalid: %w", modelSymlinkPolicy, err)
+	}
+	adapterConfig.FsyncPolicy, err = util.ParseFsyncPolicy(GetEnvString(fsyncPolicy, string(defaultFsyncPolicy)))
+	if err != nil {
+		return nil, fmt.Errorf("%s environment variable is invalid: %w", fsyncPolicy, err)
+	}
 	adapterConfig.RootModelDir, err = util.SecureJoin(GetEnvString(rootModelDir, defaultRootModelDir), ovmsModelSubdir)
 	if err != nil {
 		return nil, fmt.Errorf("Could not construct model store path: %w", err)
 	}
+	adapterConfig.ModelSizeMultipliers, err = parseModelSizeMultipliers(GetEnvString(modelSizeMultipliers, defaultModelSizeMultipliers))
+	if err != nil {
+		return nil, fmt.Errorf("%s environment variable is invalid: %w", modelSizeMultipliers, err)
+	}
+	adapterConfig.ModelTypeRootDirs, err = parseModelTypeRootDirs(GetEnvString(modelTypeRootDirs, defaultModelTypeRootDirs))
+	if err != nil {
+		return nil, fmt.Errorf("%s environment variable is invalid: %w", modelTypeRootDirs, err)
+	}
 
 	// OVMS adapter specific
 	adapterConfig.ModelConfigFile = GetEnvString(modelConfigFile, defaultModelConfigFile)
 	adapterConfig.BatchWaitTimeMin = GetEnvDuration(batchWaitTimeMin, defaultBatchWaitTimeMin, log)
 	adapterConfig.BatchWaitTimeMax = GetEnvDuration(batchWaitTimeMax, defaultBatchWaitTimeMax, log)
+	adapterConfig.UnloadGracePeriod = GetEnvDuration(unloadGracePeriod, defaultUnloadGracePeriod, log)
 	adapterConfig.ReloadTimeout = GetEnvDuration(reloadTimeout, defaultReloadTimeout, log)
+	adapterConfig.OvmsApiVersion = GetEnvString(ovmsApiVersion, DefaultOvmsApiVersion)
+	adapterConfig.ModelStateTimeout = GetEnvDuration(modelStateTimeout, defaultModelStateTimeout, log)
+	adapterConfig.WaitForMissingModels = GetEnvBool(waitForMissingModels, defaultWaitForMissingModels, log)
+	adapterConfig.PruneStaleModelConfig = GetEnvBool(pruneStaleModelConfig, defaultPruneStaleModelConfig, log)
+	adapterConfig.ReconcileOnBoot = GetEnvBool(reconcileOnBoot, defaultReconcileOnBoot, log)
+	adapterConfig.ReconcileConcurrency = GetEnvInt(reconcileConcurrency, defaultReconcileConcurrency, log)
+	adapterConfig.ReconcileEmptyConfig = GetEnvBool(reconcileEmptyConfig, defaultReconcileEmptyConfig, log)
+	// the wipe would unload the models that the reconcile registered again
+	if adapterConfig.ReconcileOnBoot {
+		adapterConfig.StartupUnloadMode = GetEnvString(startupUnloadMode, StartupUnloadReconcile)
+	} else {
+		adapterConfig.StartupUnloadMode = GetEnvString(startupUnloadMode, defaultStartupUnloadMode)
+	}
+	adapterConfig.SanitizeModelNames = GetEnvBool(sanitizeModelNames, defaultSanitizeModelNames, log)
+	adapterConfig.MetricsPort = GetEnvInt(metricsPort, defaultMetricsPort, log)
+	adapterConfig.DebugTokenFile = GetEnvString(debugTokenFile, defaultDebugTokenFile)
+	adapterConfig.CircuitBreakerThreshold = GetEnvInt(circuitBreakerThreshold, defaultCircuitBreakerThreshold, log)
+	adapterConfig.CircuitBreakerCooldown = GetEnvDuration(circuitBreakerCooldown, defaultCircuitBreakerCooldown, log)
+	adapterConfig.InitialReloadDeadline = GetEnvDuration(initialReloadDeadline, defaultInitialReloadDeadline, log)
+	adapterConfig.MetricsModelLabels = splitList(GetEnvString(metricsModelLabels, defaultMetricsModelLabels))
+	adapterConfig.ModelHealthFailurePolls = GetEnvInt(modelHealthFailurePolls, defaultModelHealthFailurePolls, log)
+	adapterConfig.ModelHealthWindow = GetEnvDuration(modelHealthWindow, defaultModelHealthWindow, log)
+	adapterConfig.LogDedupWindow = GetEnvDuration(logDedupWindow, defaultLogDedupWindow, log)
+	adapterConfig.ConfigEditPolicy = GetEnvString(configEditPolicy, defaultConfigEditPolicy)
+	adapterConfig.NameCollisionCode = GetEnvString(nameCollisionCode, defaultNameCollisionCode)
+	adapterConfig.SkipUnchangedReloads = GetEnvBool(skipUnchangedReloads, defaultSkipUnchangedReloads, log)
+	adapterConfig.FileVisibilityGrace = GetEnvDuration(fileVisibilityGrace, defaultFileVisibilityGrace, log)
+
+	adapterConfig.PluginConfigDefaults, err = parsePluginConfigDefaults(GetEnvString(pluginConfigDefaults, defaultPluginConfigDefaults))
+	if err != nil {
+		return nil, fmt.Errorf("%s environment variable is invalid: %w", pluginConfigDefaults, err)
+	}
 
 	if adapterConfig.OvmsContainerMemReqBytes < 0 {
 		return nil, fmt.Errorf("%s environment variable must be set to a positive integer, found value %v", ovmsContainerMemReqBytes, adapterConfig.OvmsContainerMemReqBytes)
 	}
+	if !isSupportedOvmsApiVersion(adapterConfig.OvmsApiVersion) {
+		return nil, fmt.Errorf("%s environment variable must be one of %s or %s, found value %v", ovmsApiVersion, OvmsApiVersionV1, OvmsApiVersionV2, adapterConfig.OvmsApiVersion)
+	}
+	if p := adapterConfig.ConfigEditPolicy; p != ConfigEditOverwrite && p != ConfigEditMerge && p != ConfigEditReject {
+		return nil, fmt.Errorf("%s environment variable must be one of %s, %s or %s, found value %v", configEditPolicy, ConfigEditOverwrite, ConfigEditMerge, ConfigEditReject, p)
+	}
+	if c := adapterConfig.NameCollisionCode; c != NameCollisionInvalidArgument && c != NameCollisionAlreadyExists {
+		return nil, fmt.Errorf("%s environment variable must be one of %s or %s, found value %v", nameCollisionCode, NameCollisionInvalidArgument, NameCollisionAlreadyExists, c)
+	}
+	if m := adapterConfig.StartupUnloadMode; m != StartupUnloadWipe && m != StartupUnloadReconcile && m != StartupUnloadSkip {
+		return nil, fmt.Errorf("%s environment variable must be one of %s, %s or %s, found value %v", startupUnloadMode, StartupUnloadWipe, StartupUnloadReconcile, StartupUnloadSkip, m)
+	}
+	if adapterConfig.ReconcileOnBoot && adapterConfig.StartupUnloadMode == StartupUnloadWipe {
+		return nil, fmt.Errorf("%s environment variable must not be %s when %s is true, found value %v", startupUnloadMode, StartupUnloadWipe, reconcileOnBoot, adapterConfig.StartupUnloadMode)
+	}
+	if adapterConfig.UnloadGracePeriod <= 0 {
+		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", unloadGracePeriod, adapterConfig.UnloadGracePeriod)
+	}
+	if adapterConfig.ModelStateTimeout <= 0 {
+		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", modelStateTimeout, adapterConfig.ModelStateTimeout)
+	}
+	if adapterConfig.ReconcileConcurrency <= 0 {
+		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", reconcileConcurrency, adapterConfig.ReconcileConcurrency)
+	}
+	if adapterConfig.MetricsPort < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", metricsPort, adapterConfig.MetricsPort)
+	}
+	if adapterConfig.DebugTokenFile != "" && adapterConfig.MetricsPort == 0 {
+		return nil, fmt.Errorf("%s environment variable must be set to serve the debug endpoint enabled with %s", metricsPort, debugTokenFile)
+	}
+	if adapterConfig.CircuitBreakerThreshold < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", circuitBreakerThreshold, adapterConfig.CircuitBreakerThreshold)
+	}
+	if adapterConfig.CircuitBreakerCooldown <= 0 {
+		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", circuitBreakerCooldown, adapterConfig.CircuitBreakerCooldown)
+	}
+	if adapterConfig.InitialReloadDeadline < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", initialReloadDeadline, adapterConfig.InitialReloadDeadline)
+	}
+	if adapterConfig.FileVisibilityGrace < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", fileVisibilityGrace, adapterConfig.FileVisibilityGrace)
+	}
+	for _, key := range adapterConfig.MetricsModelLabels {
+		if !metricsLabelKeyRegex.MatchString(key) || strings.HasPrefix(key, "__") || key == "model_id" || key == "error_code" {
+			return nil, fmt.Errorf("%s environment variable must only list valid label names other than model_id and error_code, found value %v", metricsModelLabels, key)
+		}
+	}
+	if adapterConfig.ModelHealthFailurePolls <= 0 {
+		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", modelHealthFailurePolls, adapterConfig.ModelHealthFailurePolls)
+	}
+	if adapterConfig.ModelHealthWindow <= 0 {
+		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", modelHealthWindow, adapterConfig.ModelHealthWindow)
+	}
+	if adapterConfig.LayoutRetries < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", layoutRetries, adapterConfig.LayoutRetries)
+	}
+	if adapterConfig.LayoutRetryBackoff <= 0 {
+		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", layoutRetryBackoff, adapterConfig.LayoutRetryBackoff)
+	}
 	if adapterConfig.ModelSizeMultiplier <= 0 {
 		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", modelSizeMultiplier, adapterConfig.ModelSizeMultiplier)
 	}
+	if adapterConfig.ModelKeyMaxSize < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", modelKeyMaxSize, adapterConfig.ModelKeyMaxSize)
+	}
 	return adapterConfig, nil
 }
+
+// the names allowed for Prometheus labels
+var metricsLabelKeyRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// What writeConfig does when the config file was modified by something other
+// than the adapter since it last wrote it, see ConfigEditPolicy
+const (
+	// write the config of the adapter over the edits
+	ConfigEditOverwrite string = "overwrite"
+	// keep the models that the edits added, which the adapter does not
+	// manage, next to the models of the adapter
+	ConfigEditMerge string = "merge"
+	// fail the write, and the requests waiting for it, until the file is
+	// restored or the adapter is restarted to adopt the edits
+	ConfigEditReject string = "reject"
+)
+
+// errExternalConfigEdit is wrapped by the error of a write that was rejected
+// because of an edit to the config file
+var errExternalConfigEdit = errors.New("Config file was modified outside of the adapter")
+
+func configHash(configBytes []byte) string {
+	sum := sha256.Sum256(configBytes)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkConfigEdits compares the config file to the config that was last
+// written, and applies the ConfigEditPolicy if it changed
+//
+// With the merge policy, the entries of the file whose names are not of a
+// model of the adapter are saved to externalEntries, to be written with the
+// config of the adapter.
+func (mm *OvmsModelManager) checkConfigEdits() error {
+	policy := mm.config.ConfigEditPolicy
+	if policy == "" || policy == ConfigEditOverwrite || mm.writtenConfigHash == "" {
+		return nil
+	}
+
+	configBytes, err := os.ReadFile(mm.modelConfigFilename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("Error reading config file to check for edits: %w", err)
+	}
+	if configHash(configBytes) == mm.writtenConfigHash {
+		return nil
+	}
+
+	if policy == ConfigEditReject {
+		return fmt.Errorf("%w: '%s' changed since the adapter last wrote it, restore it or restart the adapter to adopt the changes",
+			errExternalConfigEdit, mm.modelConfigFilename)
+	}
+
+	var editedConfig OvmsMultiModelRepositoryConfig
+	if len(configBytes) > 0 {
+		if err = json.Unmarshal(configBytes, &editedConfig); err != nil {
+			return fmt.Errorf("%w: unable to parse the edited config file to merge it: %v", errExternalConfigEdit, err)
+		}
+	}
+
+	// the names of the models of the adapter, which includes the models
+	// unloaded since the last write
+	adapterNames := make(map[string]struct{}, len(mm.writtenConfig)+len(mm.loadedModelsMap))
+	for name := range mm.writtenConfig {
+		if _, ok := mm.externalEntries[name]; !ok {
+			adapterNames[name] = struct{}{}
+		}
+	}
+	for _, entry := range mm.loadedModelsMap {
+		adapterNames[entry.Config.Name] = struct{}{}
+	}
+
+	externalEntries := map[string]OvmsMultiModelConfigListEntry{}
+	for _, entry := range editedConfig.ModelConfigList {
+		if _, ok := adapterNames[entry.Config.Name]; !ok {
+			externalEntries[entry.Config.Name] = entry
+		}
+	}
+	mm.externalEntries = externalEntries
+
+	names := make([]string, 0, len(externalEntries))
+	for name := range externalEntries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	mm.log.Info("Config file was modified outside of the adapter, merging the models it added", "filename", mm.modelConfigFilename, "models", names)
+	return nil
+}
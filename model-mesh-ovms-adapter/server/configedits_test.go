@@ -0,0 +1,179 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// addExternalConfigEntry edits the config file like an operator would, adding
+// a model that the adapter did not load
+func addExternalConfigEntry(t *testing.T, configFile string) {
+	configBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Unable to read config file: %v", err)
+	}
+	var config OvmsMultiModelRepositoryConfig
+	if err = json.Unmarshal(configBytes, &config); err != nil {
+		t.Fatalf("Unable to parse config file: %v", err)
+	}
+	config.ModelConfigList = append(config.ModelConfigList, OvmsMultiModelConfigListEntry{
+		Config: OvmsMultiModelModelConfig{Name: "external", BasePath: testOpenvinoModelPath},
+	})
+	if configBytes, err = json.Marshal(config); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(configFile, configBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExternalConfigEdits(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          string
+		expectedCode    codes.Code
+		expectedModels  []string
+		expectedMissing []string
+	}{
+		{
+			name:            "overwrite by default",
+			policy:          "",
+			expectedModels:  []string{testOpenvinoModelId, testOnnxModelId},
+			expectedMissing: []string{"external"},
+		},
+		{
+			name:           "merge",
+			policy:         ConfigEditMerge,
+			expectedModels: []string{testOpenvinoModelId, testOnnxModelId, "external"},
+		},
+		{
+			name:            "reject",
+			policy:          ConfigEditReject,
+			expectedCode:    codes.FailedPrecondition,
+			expectedModels:  []string{testOpenvinoModelId, "external"},
+			expectedMissing: []string{testOnnxModelId},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMockOVMS()
+			defer m.Close()
+
+			configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+			mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{ConfigEditPolicy: tt.policy})
+			if err != nil {
+				t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+			}
+
+			m.setMockReloadResponse(OvmsConfigResponse{
+				testOpenvinoModelId: OvmsModelStatusResponse{
+					ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+				},
+				testOnnxModelId: OvmsModelStatusResponse{
+					ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+				},
+				"external": OvmsModelStatusResponse{
+					ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+				},
+			}, http.StatusOK)
+
+			if err = mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); err != nil {
+				t.Fatalf("LoadModel call failed: %v", err)
+			}
+
+			addExternalConfigEntry(t, configFile)
+
+			err = mm.LoadModel(context.Background(), testOnnxModelPath, testOnnxModelId, "", "", nil, nil)
+			if tt.expectedCode == codes.OK {
+				if err != nil {
+					t.Fatalf("LoadModel call failed: %v", err)
+				}
+			} else {
+				if status.Code(err) != tt.expectedCode {
+					t.Fatalf("Expected LoadModel to fail with %v, got: %v", tt.expectedCode, err)
+				}
+				if !strings.Contains(err.Error(), "modified outside of the adapter") {
+					t.Errorf("Expected the error to name the external edit, got: %v", err)
+				}
+			}
+
+			models, configBytes := readConfiguredModels(t, configFile)
+			for _, name := range tt.expectedModels {
+				if _, ok := models[name]; !ok {
+					t.Errorf("Expected model '%s' in the config file, got: %s", name, configBytes)
+				}
+			}
+			for _, name := range tt.expectedMissing {
+				if _, ok := models[name]; ok {
+					t.Errorf("Expected model '%s' not to be in the config file, got: %s", name, configBytes)
+				}
+			}
+		})
+	}
+}
+
+func TestMergedConfigEditsAreKept(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{ConfigEditPolicy: ConfigEditMerge})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+		"external": OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+	}, http.StatusOK)
+
+	if err = mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+	addExternalConfigEntry(t, configFile)
+
+	// the merged entry stays through the writes that follow the edit
+	if err = mm.UnloadModel(context.Background(), testOpenvinoModelId); err != nil {
+		t.Fatalf("UnloadModel call failed: %v", err)
+	}
+	if err = mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+
+	models, configBytes := readConfiguredModels(t, configFile)
+	if len(models) != 2 || models["external"] != testOpenvinoModelPath {
+		t.Errorf("Expected the loaded model and the external entry in the config file, got: %s", configBytes)
+	}
+
+	// the adapter does not load a model over the name of an external entry
+	if err = mm.LoadModel(context.Background(), testOnnxModelPath, "external", "", "", nil, nil); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected loading a model named like the external entry to fail with InvalidArgument, got: %v", err)
+	}
+}
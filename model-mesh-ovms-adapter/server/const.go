@@ -19,4 +19,9 @@ const (
 	kServeV2GrpcServiceName string = "inference.GRPCInferenceService"
 	ovmsModelSubdir         string = "_ovms_models"
 	onnxModelFilename       string = "model.onnx"
+	// lists the models bundled in a ModelPath, see subModelManifest
+	subModelManifestFilename string = "submodels.json"
+	// joins the model id and the name of a sub-model, see subModelId; it is
+	// rejected in both, so a sub-model id is never the id of another model
+	subModelIdSeparator string = ":"
 )
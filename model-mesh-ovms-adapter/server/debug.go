@@ -0,0 +1,141 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// number of the most recent reloads that are kept for the debug endpoint
+const debugReloadHistory = 10
+
+type reloadOutcome struct {
+	Time    time.Time `json:"time"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// debugState is a copy of the state owned by the run() loop that can be read
+// by the debug endpoint at any time
+type debugState struct {
+	mutex        sync.Mutex
+	config       OvmsMultiModelRepositoryConfig
+	loadedModels map[string]OvmsMultiModelConfigListEntry
+	reloads      []reloadOutcome
+}
+
+func newDebugState() *debugState {
+	return &debugState{
+		config:       OvmsMultiModelRepositoryConfig{ModelConfigList: []OvmsMultiModelConfigListEntry{}},
+		loadedModels: map[string]OvmsMultiModelConfigListEntry{},
+		reloads:      []reloadOutcome{},
+	}
+}
+
+// setConfig records the config that was written to the config file
+func (d *debugState) setConfig(config OvmsMultiModelRepositoryConfig) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.config = OvmsMultiModelRepositoryConfig{
+		ModelConfigList: append([]OvmsMultiModelConfigListEntry{}, config.ModelConfigList...),
+	}
+}
+
+// setLoadedModels records the models that the adapter wants OVMS to serve
+func (d *debugState) setLoadedModels(loadedModels map[string]OvmsMultiModelConfigListEntry) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.loadedModels = make(map[string]OvmsMultiModelConfigListEntry, len(loadedModels))
+	for id, entry := range loadedModels {
+		d.loadedModels[id] = entry
+	}
+}
+
+// observeReload records the outcome of a config reload, with the error if it
+// failed
+func (d *debugState) observeReload(success bool, reloadError string) {
+	if d == nil {
+		return
+	}
+	outcome := reloadOutcome{Time: time.Now(), Success: success, Error: reloadError}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.reloads = append(d.reloads, outcome)
+	if len(d.reloads) > debugReloadHistory {
+		d.reloads = d.reloads[len(d.reloads)-debugReloadHistory:]
+	}
+}
+
+// DebugHandler serves the state of the model manager as JSON, eg.
+// {"config": {"model_config_list": [...]}, "loaded_models": {...}, "reloads": [...], "model_events": {...}}
+//
+// Requests must have the header "Authorization: Bearer <token>" with the
+// token in tokenFile, which is read for every request so that it can be
+// rotated. If tokenFile is empty, the endpoint is disabled and responds with
+// 404 Not Found.
+func (mm *OvmsModelManager) DebugHandler(tokenFile string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tokenFile == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token, err := os.ReadFile(tokenFile)
+		if err != nil {
+			mm.log.Error(err, "Unable to read the debug endpoint token", "filename", tokenFile)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		expected := strings.TrimSpace(string(token))
+		authorization := r.Header.Get("Authorization")
+		provided := strings.TrimPrefix(authorization, "Bearer ")
+		if provided == authorization || expected == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		modelEvents := mm.events.snapshot()
+
+		mm.debug.mutex.Lock()
+		defer mm.debug.mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Config       OvmsMultiModelRepositoryConfig           `json:"config"`
+			LoadedModels map[string]OvmsMultiModelConfigListEntry `json:"loaded_models"`
+			Reloads      []reloadOutcome                          `json:"reloads"`
+			ModelEvents  map[string][]modelEvent                  `json:"model_events"`
+		}{mm.debug.config, mm.debug.loadedModels, mm.debug.reloads, modelEvents})
+	})
+}
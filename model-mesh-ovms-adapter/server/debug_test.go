@@ -0,0 +1,98 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDebugHandler(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+	if err := m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+	if err = mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+
+	get := func(handler http.Handler, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/debug/state", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// disabled by default
+	if rec := get(mm.DebugHandler(""), "secret"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d when disabled but got %d", http.StatusNotFound, rec.Code)
+	}
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err = os.WriteFile(tokenFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	handler := mm.DebugHandler(tokenFile)
+
+	for _, token := range []string{"", "wrong"} {
+		if rec := get(handler, token); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d with token '%s' but got %d", http.StatusUnauthorized, token, rec.Code)
+		}
+	}
+
+	rec := get(handler, "secret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+	var body struct {
+		Config       OvmsMultiModelRepositoryConfig           `json:"config"`
+		LoadedModels map[string]OvmsMultiModelConfigListEntry `json:"loaded_models"`
+		Reloads      []reloadOutcome                          `json:"reloads"`
+	}
+	if err = json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Unable to parse response '%s': %v", rec.Body.String(), err)
+	}
+
+	expectedEntry := OvmsMultiModelConfigListEntry{Config: OvmsMultiModelModelConfig{Name: testOpenvinoModelId, BasePath: testOpenvinoModelPath}}
+	if !reflect.DeepEqual(body.Config.ModelConfigList, []OvmsMultiModelConfigListEntry{expectedEntry}) {
+		t.Errorf("Expected the written config with model %s but got: %s", testOpenvinoModelId, rec.Body.String())
+	}
+	if !reflect.DeepEqual(body.LoadedModels, map[string]OvmsMultiModelConfigListEntry{testOpenvinoModelId: expectedEntry}) {
+		t.Errorf("Expected model %s to be loaded but got: %s", testOpenvinoModelId, rec.Body.String())
+	}
+	if len(body.Reloads) != 1 || !body.Reloads[0].Success || body.Reloads[0].Error != "" {
+		t.Errorf("Expected one successful reload but got: %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// number of the most recent events that are kept for each model
+	modelEventHistory = 32
+	// number of models that events are kept for, the models with the oldest
+	// events are dropped first
+	maxEventModels = 1000
+)
+
+// Lifecycle events of a model
+const (
+	eventPullStarted     = "pull_started"
+	eventPullFinished    = "pull_finished"
+	eventLayoutDone      = "layout_done"
+	eventReloadRequested = "reload_requested"
+	eventUnloadRequested = "unload_requested"
+	// the state of the model in OVMS changed, eg. to AVAILABLE or END
+	eventState = "state"
+)
+
+type modelEvent struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// modelEvents keeps the recent lifecycle events of each model to diagnose
+// slow or stuck loads
+type modelEvents struct {
+	mutex  sync.Mutex
+	events map[string][]modelEvent
+	// the last state recorded for each model
+	states map[string]string
+}
+
+func newModelEvents() *modelEvents {
+	return &modelEvents{
+		events: map[string][]modelEvent{},
+		states: map[string]string{},
+	}
+}
+
+// record adds an event of the model, with an optional detail like an error
+func (e *modelEvents) record(modelId string, event string, detail string) {
+	if e == nil {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.add(modelId, modelEvent{Time: time.Now(), Event: event, Detail: detail})
+}
+
+// observeState records the state of the model in OVMS if it changed since
+// the last observed state
+func (e *modelEvents) observeState(modelId string, state string) {
+	if e == nil {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if previous, ok := e.states[modelId]; ok && previous == state {
+		return
+	}
+	e.states[modelId] = state
+	e.add(modelId, modelEvent{Time: time.Now(), Event: eventState, Detail: state})
+}
+
+func (e *modelEvents) add(modelId string, event modelEvent) {
+	events, ok := e.events[modelId]
+	if !ok && len(e.events) >= maxEventModels {
+		e.dropOldestModel()
+	}
+	events = append(events, event)
+	if len(events) > modelEventHistory {
+		events = events[len(events)-modelEventHistory:]
+	}
+	e.events[modelId] = events
+}
+
+// dropOldestModel removes the events of the model whose last event is the
+// oldest
+func (e *modelEvents) dropOldestModel() {
+	var oldestId string
+	var oldest time.Time
+	for id, events := range e.events {
+		if last := events[len(events)-1].Time; oldestId == "" || last.Before(oldest) {
+			oldestId, oldest = id, last
+		}
+	}
+	delete(e.events, oldestId)
+	delete(e.states, oldestId)
+}
+
+// get returns a copy of the events of the model, oldest first
+func (e *modelEvents) get(modelId string) []modelEvent {
+	if e == nil {
+		return nil
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return append([]modelEvent(nil), e.events[modelId]...)
+}
+
+// snapshot returns a copy of the events of all models
+func (e *modelEvents) snapshot() map[string][]modelEvent {
+	if e == nil {
+		return map[string][]modelEvent{}
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	snapshot := make(map[string][]modelEvent, len(e.events))
+	for id, events := range e.events {
+		snapshot[id] = append([]modelEvent(nil), events...)
+	}
+	return snapshot
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+)
+
+func TestModelEventsForSuccessfulLoad(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+	if err := m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+	s := &OvmsAdapterServer{
+		ModelManager:  mm,
+		AdapterConfig: &AdapterConfiguration{RootModelDir: t.TempDir()},
+		Log:           log,
+	}
+
+	if _, err = s.LoadModel(context.Background(), &mmesh.LoadModelRequest{
+		ModelId:   testOpenvinoModelId,
+		ModelType: "rt:openvino",
+		ModelPath: testOpenvinoModelPath,
+		ModelKey:  `{"model_type": "openvino"}`,
+	}); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+
+	// the unload takes effect with the reload for the next load
+	if err = mm.UnloadModel(context.Background(), testOpenvinoModelId); err != nil {
+		t.Fatalf("UnloadModel call failed: %v", err)
+	}
+	if err = m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "END"}},
+		},
+		testOnnxModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+	if err = mm.LoadModel(context.Background(), testOnnxModelPath, testOnnxModelId, "", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+
+	var sequence []string
+	for _, e := range mm.events.get(testOpenvinoModelId) {
+		if e.Time.IsZero() {
+			t.Errorf("Expected event %s to have a time", e.Event)
+		}
+		sequence = append(sequence, fmt.Sprintf("%s %s", e.Event, e.Detail))
+	}
+	expected := []string{
+		"layout_done ",
+		"reload_requested ",
+		"state AVAILABLE",
+		"unload_requested ",
+		"state END",
+	}
+	if !reflect.DeepEqual(sequence, expected) {
+		t.Errorf("Expected the events %q but got %q", expected, sequence)
+	}
+}
+
+func TestModelEventsAreBounded(t *testing.T) {
+	e := newModelEvents()
+	for i := 0; i < modelEventHistory+5; i++ {
+		e.record("model", eventReloadRequested, fmt.Sprint(i))
+	}
+	events := e.get("model")
+	if len(events) != modelEventHistory || events[0].Detail != "5" {
+		t.Errorf("Expected the last %d events, starting with the sixth, but got %v", modelEventHistory, events)
+	}
+
+	// the same state is only recorded once
+	e.observeState("model", "AVAILABLE")
+	e.observeState("model", "AVAILABLE")
+	if events = e.get("model"); events[len(events)-2].Event == eventState {
+		t.Errorf("Expected an unchanged state to be recorded once, got %v", events[len(events)-2:])
+	}
+
+	// the model with the oldest events is dropped first
+	time.Sleep(time.Millisecond)
+	for i := 0; i < maxEventModels; i++ {
+		e.record(fmt.Sprintf("model-%d", i), eventLayoutDone, "")
+	}
+	if len(e.snapshot()) != maxEventModels || len(e.get("model")) != 0 {
+		t.Errorf("Expected the events of %d models without the oldest one", maxEventModels)
+	}
+}
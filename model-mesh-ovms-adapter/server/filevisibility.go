@@ -0,0 +1,45 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// waitForFileVisibility waits for FileVisibilityGrace before a load is queued
+// for the next reload, or until ctx is done
+//
+// When OVMS runs in another container than the adapter, the files that the
+// adapter just placed on a shared volume may take a while to appear in the
+// container of OVMS. The adapter only sees its own view of the volume, so it
+// cannot tell when they appear there, and gives them a fixed grace instead.
+// The wait runs in the goroutine of the request, so that it does not hold up
+// the other loads and unloads.
+func (mm *OvmsModelManager) waitForFileVisibility(ctx context.Context) error {
+	if mm.config.FileVisibilityGrace <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(mm.config.FileVisibilityGrace)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
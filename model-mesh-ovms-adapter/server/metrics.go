@@ -0,0 +1,236 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	metricReloadTotal         = "ovms_adapter_reload_total"
+	metricReloadFailuresTotal = "ovms_adapter_reload_failures_total"
+	metricLastReloadSuccess   = "ovms_adapter_last_reload_success"
+	metricLoadFailuresTotal   = "ovms_adapter_model_load_failures_total"
+	metricModelLoaded         = "ovms_adapter_model_loaded"
+	metricUnhealthyModels     = "ovms_adapter_unhealthy_models"
+	metricLoadedModels        = "ovms_adapter_loaded_models"
+
+	// used as the error_code label when OVMS does not report one
+	unknownErrorCode = "UNKNOWN"
+	// used as the model_type label of the models whose type OVMS detects
+	// from the model files
+	unknownModelType = "unknown"
+)
+
+// reloadMetrics tracks the outcome of the OVMS config reloads and of the
+// model loads so that operators can alert on a rising failure rate
+//
+// The metrics are served in the Prometheus text exposition format.
+type reloadMetrics struct {
+	mutex             sync.Mutex
+	reloads           uint64
+	reloadFailures    uint64
+	lastReloadSuccess bool
+	// model load failures by their labels, the error code reported by OVMS
+	// and the model labels of the failed model
+	loadFailures map[string]uint64
+	// the labels of the loaded models by model id
+	loadedModels map[string]string
+	// number of loaded models by model type
+	loadedModelTypes map[string]int
+	// number of loaded models that were unhealthy in the last runtime
+	// status, -1 before the first one
+	unhealthyModels int
+
+	// the keys of the labels from the ModelKey that are attached to the
+	// metrics, other keys are dropped to bound the number of series
+	modelLabelKeys []string
+}
+
+func newReloadMetrics(modelLabelKeys []string) *reloadMetrics {
+	keys := append([]string(nil), modelLabelKeys...)
+	sort.Strings(keys)
+	return &reloadMetrics{
+		loadFailures:     make(map[string]uint64),
+		loadedModels:     make(map[string]string),
+		loadedModelTypes: make(map[string]int),
+		unhealthyModels:  -1,
+		modelLabelKeys:   keys,
+	}
+}
+
+// allowedModelLabels returns the labels with an allowed key and the keys of
+// the labels that were dropped
+func (m *reloadMetrics) allowedModelLabels(labels map[string]string) (allowed map[string]string, dropped []string) {
+	for key, value := range labels {
+		if i := sort.SearchStrings(m.modelLabelKeys, key); i < len(m.modelLabelKeys) && m.modelLabelKeys[i] == key {
+			if allowed == nil {
+				allowed = make(map[string]string, len(m.modelLabelKeys))
+			}
+			allowed[key] = value
+		} else {
+			dropped = append(dropped, key)
+		}
+	}
+	sort.Strings(dropped)
+	return allowed, dropped
+}
+
+func (m *reloadMetrics) observeReload(success bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.reloads++
+	if !success {
+		m.reloadFailures++
+	}
+	m.lastReloadSuccess = success
+}
+
+func (m *reloadMetrics) observeLoadFailure(errorCode string, modelLabels map[string]string) {
+	if errorCode == "" {
+		errorCode = unknownErrorCode
+	}
+	labels := formatLabels("error_code", errorCode, modelLabels)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.loadFailures[labels]++
+}
+
+func (m *reloadMetrics) setUnhealthyModels(count int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.unhealthyModels = count
+}
+
+// setLoadedModels replaces the loaded models with the models in the map,
+// which have the model labels in modelLabels and the types in modelTypes
+func (m *reloadMetrics) setLoadedModels(models map[string]OvmsMultiModelConfigListEntry, modelLabels map[string]map[string]string, modelTypes map[string]string) {
+	loadedModels := make(map[string]string, len(models))
+	loadedModelTypes := make(map[string]int, len(supportedModelTypes)+1)
+	for id := range models {
+		loadedModels[id] = formatLabels("model_id", id, modelLabels[id])
+		modelType := modelTypes[id]
+		if _, err := resolveModelType(modelType); err != nil || modelType == "" {
+			modelType = unknownModelType
+		}
+		loadedModelTypes[modelType]++
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.loadedModels = loadedModels
+	m.loadedModelTypes = loadedModelTypes
+}
+
+// ServeHTTP writes the current values of the metrics
+func (m *reloadMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var sb strings.Builder
+	writeMetricHeader(&sb, metricReloadTotal, "counter", "Total number of OVMS config reloads.")
+	fmt.Fprintf(&sb, "%s %d\n", metricReloadTotal, m.reloads)
+
+	writeMetricHeader(&sb, metricReloadFailuresTotal, "counter", "Total number of OVMS config reloads that failed.")
+	fmt.Fprintf(&sb, "%s %d\n", metricReloadFailuresTotal, m.reloadFailures)
+
+	writeMetricHeader(&sb, metricLastReloadSuccess, "gauge", "Whether the last OVMS config reload succeeded (1) or failed (0).")
+	// no sample until the first reload, so the outcome is not misreported
+	if m.reloads > 0 {
+		var value int
+		if m.lastReloadSuccess {
+			value = 1
+		}
+		fmt.Fprintf(&sb, "%s %d\n", metricLastReloadSuccess, value)
+	}
+
+	writeMetricHeader(&sb, metricLoadFailuresTotal, "counter", "Total number of models that failed to load, by OVMS error code and model labels.")
+	failureLabels := make([]string, 0, len(m.loadFailures))
+	for labels := range m.loadFailures {
+		failureLabels = append(failureLabels, labels)
+	}
+	sort.Strings(failureLabels)
+	for _, labels := range failureLabels {
+		fmt.Fprintf(&sb, "%s{%s} %d\n", metricLoadFailuresTotal, labels, m.loadFailures[labels])
+	}
+
+	writeMetricHeader(&sb, metricModelLoaded, "gauge", "Models loaded in OVMS, with the model labels from the ModelKey.")
+	modelIds := make([]string, 0, len(m.loadedModels))
+	for id := range m.loadedModels {
+		modelIds = append(modelIds, id)
+	}
+	sort.Strings(modelIds)
+	for _, id := range modelIds {
+		fmt.Fprintf(&sb, "%s{%s} 1\n", metricModelLoaded, m.loadedModels[id])
+	}
+
+	// a sample for every type, so that a type without models reads as 0
+	// rather than missing; the types are bounded by the supported types
+	writeMetricHeader(&sb, metricLoadedModels, "gauge", "Number of models loaded in OVMS, by model type.")
+	for _, t := range supportedModelTypes {
+		fmt.Fprintf(&sb, "%s{model_type=\"%s\"} %d\n", metricLoadedModels, t.Name, m.loadedModelTypes[t.Name])
+	}
+	fmt.Fprintf(&sb, "%s{model_type=\"%s\"} %d\n", metricLoadedModels, unknownModelType, m.loadedModelTypes[unknownModelType])
+
+	writeMetricHeader(&sb, metricUnhealthyModels, "gauge", "Number of loaded models that OVMS has not reported as AVAILABLE in the recent runtime status polls.")
+	if m.unhealthyModels >= 0 {
+		fmt.Fprintf(&sb, "%s %d\n", metricUnhealthyModels, m.unhealthyModels)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprint(w, sb.String())
+}
+
+// MetricsHandler serves the metrics of the model manager, followed by the
+// metrics of the embedded puller if it is used
+func (s *OvmsAdapterServer) MetricsHandler() http.Handler {
+	if s.Puller == nil {
+		return s.ModelManager.MetricsHandler()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.ModelManager.MetricsHandler().ServeHTTP(w, r)
+		s.Puller.MetricsHandler().ServeHTTP(w, r)
+	})
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels formats the label and the model labels, sorted by key, as the
+// labels of a sample, eg. error_code="UNKNOWN",team="fraud"
+func formatLabels(key string, value string, modelLabels map[string]string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s=\"%s\"", key, labelValueEscaper.Replace(value))
+
+	keys := make([]string, 0, len(modelLabels))
+	for k := range modelLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, ",%s=\"%s\"", k, labelValueEscaper.Replace(modelLabels[k]))
+	}
+	return sb.String()
+}
+
+func writeMetricHeader(sb *strings.Builder, name string, metricType string, help string) {
+	fmt.Fprintf(sb, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(sb, "# TYPE %s %s\n", name, metricType)
+}
@@ -14,6 +14,21 @@
 
 package server
 
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
 // OvmsMultiModelRepositoryConfig Types defining the structure of the OVMS Multi-Model config file
 //
 // Doc: https://github.com/openvinotoolkit/model_server/blob/main/docs/multiple_models_mode.md
@@ -44,12 +59,125 @@ type OvmsMultiModelRepositoryConfig struct {
 type OvmsMultiModelModelConfig struct {
 	Name     string `json:"name"`
 	BasePath string `json:"base_path"`
+	// performance knobs for the OpenVINO plugin, eg. {"NIREQ": "4"}; OVMS
+	// uses its defaults if not set
+	PluginConfig map[string]string `json:"plugin_config,omitempty"`
 }
 
 type OvmsMultiModelConfigListEntry struct {
 	Config OvmsMultiModelModelConfig `json:"config"`
 }
 
+// errInvalidModelConfig is wrapped by the errors of a config that OVMS would
+// reject
+var errInvalidModelConfig = errors.New("Invalid model config")
+
+// validate checks the fields that the OVMS config schema requires, so that an
+// entry that OVMS would reject is not written to the config
+func (e OvmsMultiModelConfigListEntry) validate() error {
+	if e.Config.Name == "" {
+		return fmt.Errorf("%w: model name must not be empty", errInvalidModelConfig)
+	}
+	if e.Config.BasePath == "" {
+		return fmt.Errorf("%w: base_path of model '%s' must not be empty", errInvalidModelConfig, e.Config.Name)
+	}
+	for key := range e.Config.PluginConfig {
+		if key == "" {
+			return fmt.Errorf("%w: plugin_config of model '%s' must not have an empty key", errInvalidModelConfig, e.Config.Name)
+		}
+	}
+	return nil
+}
+
+// validate checks every entry of the config and that the model names are unique
+func (c OvmsMultiModelRepositoryConfig) validate() error {
+	names := make(map[string]struct{}, len(c.ModelConfigList))
+	for _, entry := range c.ModelConfigList {
+		if err := entry.validate(); err != nil {
+			return err
+		}
+		if _, exists := names[entry.Config.Name]; exists {
+			return fmt.Errorf("%w: model name '%s' is not unique", errInvalidModelConfig, entry.Config.Name)
+		}
+		names[entry.Config.Name] = struct{}{}
+	}
+	return nil
+}
+
+// parsePluginConfigDefaults parses the plugin_config defaults of the model
+// types, a JSON object like {"onnx": {"NIREQ": "4"}}, keyed by the name of
+// the supported model type that each key refers to
+func parsePluginConfigDefaults(value string) (map[string]map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var parsed map[string]map[string]string
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("Invalid JSON: %w", err)
+	}
+	defaults := make(map[string]map[string]string, len(parsed))
+	for modelType, pluginConfig := range parsed {
+		resolved, err := resolveModelType(modelType)
+		if err != nil {
+			return nil, err
+		}
+		if resolved == "" {
+			return nil, fmt.Errorf("Plugin config defaults must be set for a model type, found '%s'", modelType)
+		}
+		if _, exists := defaults[resolved]; exists {
+			return nil, fmt.Errorf("Plugin config defaults of model type '%s' are set more than once", resolved)
+		}
+		for key := range pluginConfig {
+			if key == "" {
+				return nil, fmt.Errorf("Plugin config defaults of model type '%s' must not have an empty key", resolved)
+			}
+		}
+		defaults[resolved] = pluginConfig
+	}
+	return defaults, nil
+}
+
+// mergePluginConfig returns the plugin_config of a model, which is the
+// defaults of its type with the keys of the plugin_config of the request
+// taking precedence
+func mergePluginConfig(defaults map[string]string, pluginConfig map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return pluginConfig
+	}
+	merged := make(map[string]string, len(defaults)+len(pluginConfig))
+	for key, value := range defaults {
+		merged[key] = value
+	}
+	for key, value := range pluginConfig {
+		merged[key] = value
+	}
+	return merged
+}
+
+// invalidModelNameChars matches the characters that are not used in a
+// sanitized model name
+var invalidModelNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// sanitizeModelName returns the name for a model id in the OVMS config
+//
+// The characters of the model id other than letters, digits, '_', '.' and '-'
+// are replaced with '_', and a hash of the model id is appended so that
+// different model ids do not map to the same name. Model ids without such
+// characters are used as is.
+func sanitizeModelName(modelId string) string {
+	if !invalidModelNameChars.MatchString(modelId) {
+		return modelId
+	}
+	sum := sha256.Sum256([]byte(modelId))
+	return fmt.Sprintf("%s_%x", invalidModelNameChars.ReplaceAllString(modelId, "_"), sum[:4])
+}
+
+// modelNamesFilename returns the file that maps the sanitized model names in
+// the config file back to the model ids, eg. model_config_list_names.json
+func modelNamesFilename(modelConfigFilename string) string {
+	return strings.TrimSuffix(modelConfigFilename, filepath.Ext(modelConfigFilename)) + "_names.json"
+}
+
 // Types defining the REST response containing the model config
 //
 // EXAMPLE:
@@ -87,6 +215,189 @@ type OvmsModelVersionStatus struct {
 	Status  OvmsModelStatus `json:"status"`
 }
 
+// Model version states reported by OVMS
+const (
+	ovmsModelStateStart     string = "START"
+	ovmsModelStateLoading   string = "LOADING"
+	ovmsModelStateAvailable string = "AVAILABLE"
+	ovmsModelStateUnloading string = "UNLOADING"
+	ovmsModelStateEnd       string = "END"
+
+	ovmsErrorCodeOK string = "OK"
+)
+
+// hasError returns true if the status reports an error
+//
+// A version without an error has an empty status or the code and message
+// both set to "OK".
+func (s OvmsModelStatus) hasError() bool {
+	return (s.ErrorCode != "" && s.ErrorCode != ovmsErrorCodeOK) ||
+		(s.ErrorMessage != "" && s.ErrorMessage != ovmsErrorCodeOK)
+}
+
+// modelReadiness interprets the status of a model in the config response
+//
+// The model is ready when it is AVAILABLE and failed when OVMS reports an
+// error. In any other known state (START, LOADING, UNLOADING, or END without
+// an error, which can be seen during rapid load/unload cycles) the model is
+// still transitioning. A missing status or an unknown state is a failure.
+func modelReadiness(conf OvmsModelStatusResponse, exists bool) (util.ModelReadiness, string) {
+	if !exists || len(conf.ModelVersionStatus) == 0 {
+		return util.ModelFailed, "Expected model to load, but no status entry found in the config"
+	}
+
+	modelStatus := conf.ModelVersionStatus[0]
+	if modelStatus.State == ovmsModelStateAvailable {
+		return util.ModelReady, ""
+	}
+	if modelStatus.Status.hasError() {
+		return util.ModelFailed, fmt.Sprintf("OVMS model load failed. state: '%s' code: '%s' reason: '%s'", modelStatus.State, modelStatus.Status.ErrorCode, modelStatus.Status.ErrorMessage)
+	}
+
+	switch modelStatus.State {
+	case ovmsModelStateStart, ovmsModelStateLoading, ovmsModelStateUnloading, ovmsModelStateEnd:
+		return util.ModelNotReady, fmt.Sprintf("Waiting for model to become available, state: '%s'", modelStatus.State)
+	default:
+		return util.ModelFailed, fmt.Sprintf("OVMS model load failed. Unexpected state: '%s'", modelStatus.State)
+	}
+}
+
 type OvmsConfigErrorResponse struct {
 	Error string `json:"error"`
 }
+
+// Versions of the OVMS config API that determine the shape of the responses
+// from the config status and config reload endpoints
+const (
+	// field names in snake_case, as in the examples above
+	OvmsApiVersionV1 string = "v1"
+	// field names in lowerCamelCase, following the proto3 JSON mapping of the
+	// TensorFlow Serving GetModelStatusResponse
+	OvmsApiVersionV2 string = "v2"
+
+	DefaultOvmsApiVersion = OvmsApiVersionV1
+)
+
+func isSupportedOvmsApiVersion(version string) bool {
+	return version == OvmsApiVersionV1 || version == OvmsApiVersionV2
+}
+
+// Types for the v2 config response, which are converted to an OvmsConfigResponse
+//
+// EXAMPLE:
+// {
+//   "mnist": {
+//     "modelVersionStatus": [
+//       {
+//         "version": "3",
+//         "state": "AVAILABLE",
+//         "status": {
+//           "errorCode": "OK",
+//           "errorMessage": "OK"
+//         }
+//       }
+//     ]
+//   }
+// }
+
+type ovmsConfigResponseV2 map[string]ovmsModelStatusResponseV2
+
+type ovmsModelStatusResponseV2 struct {
+	ModelVersionStatus []ovmsModelVersionStatusV2 `json:"modelVersionStatus"`
+}
+
+type ovmsModelStatusV2 struct {
+	ErrorCode    string `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+type ovmsModelVersionStatusV2 struct {
+	Version string            `json:"version"`
+	State   string            `json:"state"`
+	Status  ovmsModelStatusV2 `json:"status"`
+}
+
+// parseOvmsConfigResponse parses the body of a config status or config reload
+// response in the shape used by the given API version
+func parseOvmsConfigResponse(body []byte, apiVersion string) (OvmsConfigResponse, error) {
+	// OVMS without any models may respond with an empty body
+	if len(bytes.TrimSpace(body)) == 0 {
+		return OvmsConfigResponse{}, nil
+	}
+	switch apiVersion {
+	case OvmsApiVersionV1:
+		var c OvmsConfigResponse
+		if err := json.Unmarshal(body, &c); err != nil {
+			return nil, err
+		}
+		return c, nil
+
+	case OvmsApiVersionV2:
+		var c2 ovmsConfigResponseV2
+		if err := json.Unmarshal(body, &c2); err != nil {
+			return nil, err
+		}
+		c := make(OvmsConfigResponse, len(c2))
+		for name, msr := range c2 {
+			mvs := make([]OvmsModelVersionStatus, len(msr.ModelVersionStatus))
+			for i, vs := range msr.ModelVersionStatus {
+				mvs[i] = OvmsModelVersionStatus{
+					Version: vs.Version,
+					State:   vs.State,
+					Status: OvmsModelStatus{
+						ErrorCode:    vs.Status.ErrorCode,
+						ErrorMessage: vs.Status.ErrorMessage,
+					},
+				}
+			}
+			c[name] = OvmsModelStatusResponse{ModelVersionStatus: mvs}
+		}
+		return c, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported OVMS API version '%s'", apiVersion)
+}
+
+// modelConfigDiff is the difference between two model configs, by the names
+// of the models
+type modelConfigDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+func (d modelConfigDiff) isEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diffModelConfigs returns the models that were added to, removed from, or
+// changed in the current config compared to the previous config, each sorted
+// by name
+func diffModelConfigs(previous, current map[string]OvmsMultiModelConfigListEntry) modelConfigDiff {
+	var d modelConfigDiff
+	for name, entry := range current {
+		if previousEntry, ok := previous[name]; !ok {
+			d.Added = append(d.Added, name)
+		} else if !reflect.DeepEqual(previousEntry, entry) {
+			d.Changed = append(d.Changed, name)
+		}
+	}
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
+
+// entriesByName returns the entries of the config by the names of the models
+func entriesByName(entries []OvmsMultiModelConfigListEntry) map[string]OvmsMultiModelConfigListEntry {
+	byName := make(map[string]OvmsMultiModelConfigListEntry, len(entries))
+	for _, entry := range entries {
+		byName[entry.Config.Name] = entry
+	}
+	return byName
+}
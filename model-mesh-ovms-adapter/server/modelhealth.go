@@ -0,0 +1,94 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+// modelHealth debounces the readiness of the loaded models in the config
+// responses of OVMS, which can briefly report a model that is not AVAILABLE
+// between reloads under heavy load
+//
+// A model is only unhealthy once it was not ready in failurePolls
+// consecutive polls within window; a poll where it is ready resets it.
+type modelHealth struct {
+	failurePolls int
+	window       time.Duration
+
+	mutex sync.Mutex
+	// config names of the loaded models by model id
+	loadedModels map[string]string
+	// times of the consecutive polls where each model was not ready
+	notReadyPolls map[string][]time.Time
+}
+
+func newModelHealth(failurePolls int, window time.Duration) *modelHealth {
+	return &modelHealth{
+		failurePolls:  failurePolls,
+		window:        window,
+		loadedModels:  map[string]string{},
+		notReadyPolls: map[string][]time.Time{},
+	}
+}
+
+// setLoadedModels records the models whose readiness is observed
+func (h *modelHealth) setLoadedModels(loadedModels map[string]OvmsMultiModelConfigListEntry) {
+	names := make(map[string]string, len(loadedModels))
+	for id, entry := range loadedModels {
+		names[id] = entry.Config.Name
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.loadedModels = names
+	for id := range h.notReadyPolls {
+		if _, loaded := names[id]; !loaded {
+			delete(h.notReadyPolls, id)
+		}
+	}
+}
+
+// observe records the readiness of the loaded models in a config response
+// and returns the ids of the models that are unhealthy, sorted
+func (h *modelHealth) observe(configResponse OvmsConfigResponse, now time.Time) []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var unhealthy []string
+	for id, name := range h.loadedModels {
+		conf, exists := configResponse[name]
+		if readiness, _ := modelReadiness(conf, exists); readiness == util.ModelReady {
+			delete(h.notReadyPolls, id)
+			continue
+		}
+
+		// only the polls within the window count
+		polls := append(h.notReadyPolls[id], now)
+		for len(polls) > 0 && now.Sub(polls[0]) > h.window {
+			polls = polls[1:]
+		}
+		h.notReadyPolls[id] = polls
+		if len(polls) >= h.failurePolls {
+			unhealthy = append(unhealthy, id)
+		}
+	}
+	sort.Strings(unhealthy)
+	return unhealthy
+}
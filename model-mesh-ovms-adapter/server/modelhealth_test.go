@@ -0,0 +1,79 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func modelStatus(state string) OvmsModelStatusResponse {
+	return OvmsModelStatusResponse{ModelVersionStatus: []OvmsModelVersionStatus{{State: state}}}
+}
+
+func TestModelHealthIgnoresTransientNotReady(t *testing.T) {
+	h := newModelHealth(2, 30*time.Second)
+	h.setLoadedModels(map[string]OvmsMultiModelConfigListEntry{
+		"model-a": {Config: OvmsMultiModelModelConfig{Name: "model-a"}},
+	})
+	start := time.Now()
+
+	// a single poll where the model is not AVAILABLE is not reported
+	if unhealthy := h.observe(OvmsConfigResponse{"model-a": modelStatus("LOADING")}, start); len(unhealthy) != 0 {
+		t.Errorf("Expected no unhealthy models after a transient not ready poll but got %v", unhealthy)
+	}
+	if unhealthy := h.observe(OvmsConfigResponse{"model-a": modelStatus("AVAILABLE")}, start.Add(time.Second)); len(unhealthy) != 0 {
+		t.Errorf("Expected no unhealthy models once the model is ready but got %v", unhealthy)
+	}
+
+	// the ready poll reset the model, so this is again the first not ready poll
+	if unhealthy := h.observe(OvmsConfigResponse{"model-a": modelStatus("LOADING")}, start.Add(2*time.Second)); len(unhealthy) != 0 {
+		t.Errorf("Expected no unhealthy models after a ready poll but got %v", unhealthy)
+	}
+}
+
+func TestModelHealthReportsPersistentNotReady(t *testing.T) {
+	h := newModelHealth(2, 30*time.Second)
+	h.setLoadedModels(map[string]OvmsMultiModelConfigListEntry{
+		"model-a": {Config: OvmsMultiModelModelConfig{Name: "model-a"}},
+		"model-b": {Config: OvmsMultiModelModelConfig{Name: "served-b"}},
+	})
+	start := time.Now()
+	configResponse := OvmsConfigResponse{"model-a": modelStatus("AVAILABLE"), "served-b": modelStatus("UNLOADING")}
+
+	if unhealthy := h.observe(configResponse, start); len(unhealthy) != 0 {
+		t.Errorf("Expected no unhealthy models after the first poll but got %v", unhealthy)
+	}
+	if unhealthy := h.observe(configResponse, start.Add(time.Second)); !reflect.DeepEqual(unhealthy, []string{"model-b"}) {
+		t.Errorf("Expected model-b to be unhealthy after two not ready polls but got %v", unhealthy)
+	}
+
+	// polls outside of the window do not count
+	h = newModelHealth(2, 30*time.Second)
+	h.setLoadedModels(map[string]OvmsMultiModelConfigListEntry{
+		"model-b": {Config: OvmsMultiModelModelConfig{Name: "served-b"}},
+	})
+	h.observe(configResponse, start)
+	if unhealthy := h.observe(configResponse, start.Add(time.Minute)); len(unhealthy) != 0 {
+		t.Errorf("Expected the poll outside of the window not to count but got %v", unhealthy)
+	}
+
+	// models that are no longer loaded are not reported
+	h.setLoadedModels(map[string]OvmsMultiModelConfigListEntry{})
+	if unhealthy := h.observe(configResponse, start.Add(time.Minute+time.Second)); len(unhealthy) != 0 {
+		t.Errorf("Expected no unhealthy models once they are unloaded but got %v", unhealthy)
+	}
+}
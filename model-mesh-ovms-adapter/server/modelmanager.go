@@ -15,6 +15,7 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -24,9 +25,14 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -40,12 +46,41 @@ type OvmsModelManager struct {
 	modelConfigFilename string
 	log                 logr.Logger
 	config              ModelManagerConfig
+	// log of the reload failures and retries, which repeat during an outage
+	reloadLog logr.Logger
 
 	// internal
 	cachedModelConfigResponse OvmsConfigResponse
 	client                    *http.Client
 	loadedModelsMap           map[string]OvmsMultiModelConfigListEntry
 	requests                  chan *request
+	metrics                   *reloadMetrics
+	debug                     *debugState
+	events                    *modelEvents
+	breaker                   *util.CircuitBreaker
+	health                    *modelHealth
+	// config names of the models unloaded since the last reload, to
+	// record their state after the next one
+	unloadedNames map[string]string
+	// entries of the last written config by name, to log the changes of the
+	// next write
+	writtenConfig map[string]OvmsMultiModelConfigListEntry
+	// hash of the config file as last written or read, to detect edits by
+	// others; empty until the file is written or read
+	writtenConfigHash string
+	// hash of the config that OVMS last confirmed a reload of, empty if the
+	// last reload failed
+	reloadedConfigHash string
+	// entries that others added to the config file, which are kept by the
+	// merge ConfigEditPolicy
+	externalEntries map[string]OvmsMultiModelConfigListEntry
+	// allowed metrics labels of the models by model id
+	modelLabels map[string]map[string]string
+	// model types of the models by model id, empty if OVMS detects it
+	modelTypes map[string]string
+	// set once OVMS has responded to a reload, after which refused
+	// connections are no longer retried
+	runtimeReached bool
 
 	// optimizations
 	// keep reference to temporary map to avoid re-allocating arrays each
@@ -60,24 +95,123 @@ type OvmsModelManager struct {
 type ModelManagerConfig struct {
 	BatchWaitTimeMin time.Duration
 	BatchWaitTimeMax time.Duration
+	// unloads received within this period of the previous unload join the
+	// same reload, the batch is still limited by BatchWaitTimeMax
+	UnloadGracePeriod time.Duration
 
 	HttpClientMaxConns int
 	ReloadTimeout      time.Duration
 
+	// after a reload, models still in a transitional state are polled every
+	// ModelStatePollInterval until they become AVAILABLE or fail, for up to
+	// ModelStateTimeout
+	ModelStateTimeout      time.Duration
+	ModelStatePollInterval time.Duration
+	// keep polling a model without a status in the config response, as if
+	// it was still loading, instead of failing its load right away
+	WaitForMissingModels bool
+
 	ModelConfigFilePerms fs.FileMode
+	// when the config file and the model names file are flushed to disk
+	FsyncPolicy util.FsyncPolicy
 
 	RequestChannelSize int
+
+	// selects the shape of the responses from the OVMS config API
+	ApiVersion string
+
+	// remove entries whose model directory no longer exists from the
+	// initial config read from disk, before it is first reloaded
+	PruneMissingModels bool
+
+	// reload OVMS with the initial config read from disk before handling
+	// any requests, so that the models loaded before an adapter restart are
+	// served again without ModelMesh loading them again
+	ReconcileOnBoot bool
+	// the number of models whose directories the reconcile checks at once
+	// before its reload
+	ReconcileConcurrency int
+	// reconcile an initial config without any models too, reloading OVMS
+	// with the empty config so that it drops the models it may still serve
+	// from a config file that could not be read
+	ReconcileEmptyConfig bool
+
+	// name the models in the config with sanitizeModelName instead of the
+	// model id, the names are mapped back to the model ids with a file next
+	// to the config file
+	SanitizeModelNames bool
+
+	// after CircuitBreakerThreshold consecutive failed reloads, loads fail
+	// fast with Unavailable and OVMS is probed every CircuitBreakerCooldown
+	// until it responds again; a threshold of 0 disables the breaker
+	CircuitBreakerThreshold int
+	CircuitBreakerCooldown  time.Duration
+
+	// until OVMS has responded to a reload, a reload that cannot connect
+	// because OVMS is not listening yet is retried for up to
+	// InitialReloadDeadline, waiting InitialReloadBackoff before the first
+	// retry and twice as long before each next one; a deadline of 0 disables
+	// the retries
+	InitialReloadDeadline time.Duration
+	InitialReloadBackoff  time.Duration
+
+	// what a write of the config does when the file was modified by others
+	// since the last write, one of the ConfigEdit* values; empty is
+	// ConfigEditOverwrite
+	ConfigEditPolicy string
+
+	// the code that a load fails with when the name of the model in the
+	// config is already used by another model, eg. with a served_model_name,
+	// one of the NameCollision* values; empty is NameCollisionInvalidArgument
+	NameCollisionCode string
+
+	// skip the reload when the config to write is identical to the config
+	// that OVMS last reloaded successfully, eg. when a loaded model is
+	// loaded again
+	SkipUnchangedReloads bool
+
+	// a load waits for FileVisibilityGrace before it is queued for the next
+	// reload, for the files to become visible to OVMS when it reads them
+	// from a volume shared across containers; 0 disables the wait
+	FileVisibilityGrace time.Duration
+
+	// the keys of the labels from the ModelKey that are attached to the
+	// per-model metrics, other labels are dropped
+	MetricsModelLabels []string
+
+	// a loaded model is only reported as unhealthy by UnhealthyModels once
+	// it was not AVAILABLE in ModelHealthFailurePolls consecutive polls of
+	// the config within ModelHealthWindow
+	ModelHealthFailurePolls int
+	ModelHealthWindow       time.Duration
+
+	// failed and retried reloads that log the same error within
+	// LogDedupWindow are collapsed into one log with a count, see
+	// util.NewDedupLogger; a window of 0 logs every one
+	LogDedupWindow time.Duration
 }
 
 var modelManagerConfigDefaults ModelManagerConfig = ModelManagerConfig{
-	BatchWaitTimeMin:     100 * time.Millisecond,
-	BatchWaitTimeMax:     3 * time.Second,
-	HttpClientMaxConns:   100,
-	ReloadTimeout:        30 * time.Second,
-	RequestChannelSize:   25,
-	ModelConfigFilePerms: 0644,
+	BatchWaitTimeMin:        100 * time.Millisecond,
+	BatchWaitTimeMax:        3 * time.Second,
+	HttpClientMaxConns:      100,
+	ReloadTimeout:           30 * time.Second,
+	ModelStateTimeout:       10 * time.Second,
+	ModelStatePollInterval:  250 * time.Millisecond,
+	RequestChannelSize:      25,
+	ModelConfigFilePerms:    0644,
+	FsyncPolicy:             util.FsyncNever,
+	ApiVersion:              DefaultOvmsApiVersion,
+	CircuitBreakerCooldown:  30 * time.Second,
+	InitialReloadBackoff:    100 * time.Millisecond,
+	ModelHealthFailurePolls: 2,
+	ModelHealthWindow:       30 * time.Second,
+	ReconcileConcurrency:    8,
 }
 
+// limit on the wait between the retries of the initial reload
+const maxInitialReloadBackoff = 5 * time.Second
+
 func (c *ModelManagerConfig) applyDefaults() {
 	if c.BatchWaitTimeMin == 0 {
 		c.BatchWaitTimeMin = modelManagerConfigDefaults.BatchWaitTimeMin
@@ -85,45 +219,101 @@ func (c *ModelManagerConfig) applyDefaults() {
 	if c.BatchWaitTimeMax == 0 {
 		c.BatchWaitTimeMax = modelManagerConfigDefaults.BatchWaitTimeMax
 	}
+	if c.UnloadGracePeriod == 0 {
+		// by default, the batch of unloads is only limited by BatchWaitTimeMax
+		c.UnloadGracePeriod = c.BatchWaitTimeMax
+	}
 	if c.HttpClientMaxConns == 0 {
 		c.HttpClientMaxConns = modelManagerConfigDefaults.HttpClientMaxConns
 	}
 	if c.ReloadTimeout == 0 {
 		c.ReloadTimeout = modelManagerConfigDefaults.ReloadTimeout
 	}
+	if c.ModelStateTimeout == 0 {
+		c.ModelStateTimeout = modelManagerConfigDefaults.ModelStateTimeout
+	}
+	if c.ModelStatePollInterval == 0 {
+		c.ModelStatePollInterval = modelManagerConfigDefaults.ModelStatePollInterval
+	}
 	if c.RequestChannelSize == 0 {
 		c.RequestChannelSize = modelManagerConfigDefaults.RequestChannelSize
 	}
 	if c.ModelConfigFilePerms == 0 {
 		c.ModelConfigFilePerms = modelManagerConfigDefaults.ModelConfigFilePerms
 	}
+	if c.FsyncPolicy == "" {
+		c.FsyncPolicy = modelManagerConfigDefaults.FsyncPolicy
+	}
+	if c.ApiVersion == "" {
+		c.ApiVersion = modelManagerConfigDefaults.ApiVersion
+	}
+	if c.CircuitBreakerCooldown == 0 {
+		c.CircuitBreakerCooldown = modelManagerConfigDefaults.CircuitBreakerCooldown
+	}
+	if c.InitialReloadBackoff == 0 {
+		c.InitialReloadBackoff = modelManagerConfigDefaults.InitialReloadBackoff
+	}
+	if c.ModelHealthFailurePolls == 0 {
+		c.ModelHealthFailurePolls = modelManagerConfigDefaults.ModelHealthFailurePolls
+	}
+	if c.ModelHealthWindow == 0 {
+		c.ModelHealthWindow = modelManagerConfigDefaults.ModelHealthWindow
+	}
+	if c.ReconcileConcurrency == 0 {
+		c.ReconcileConcurrency = modelManagerConfigDefaults.ReconcileConcurrency
+	}
 }
 
 func NewOvmsModelManager(address string, multiModelConfigFilename string, log logr.Logger, mmConfig ModelManagerConfig) (*OvmsModelManager, error) {
 
 	mmConfig.applyDefaults()
+	if !isSupportedOvmsApiVersion(mmConfig.ApiVersion) {
+		return nil, fmt.Errorf("Unsupported OVMS API version '%s'", mmConfig.ApiVersion)
+	}
 
 	// try to load the initial config from disk, if it exists
 	// this handles the case where the adapter crashes
 	multiModelConfig := map[string]OvmsMultiModelConfigListEntry{}
+	writtenConfig := map[string]OvmsMultiModelConfigListEntry{}
+	var writtenConfigHash string
+	var emptyConfigFile bool
 	if configBytes, err := os.ReadFile(multiModelConfigFilename); err != nil {
 		// if there is any error in initialization from an existing file, just continue with an empty config
 		// but log if there was an error reading an existing file
 		if !errors.Is(err, os.ErrNotExist) {
 			log.Error(err, "WARNING: could not initialize model config from file, will continue with empty config", "filename", multiModelConfigFilename)
 		}
+	} else if len(bytes.TrimSpace(configBytes)) == 0 {
+		// a file truncated by a crash is an empty config, which is written
+		// out again below because OVMS cannot parse an empty file
+		log.Info("The model config file is empty, continuing with empty config", "filename", multiModelConfigFilename)
+		emptyConfigFile = true
 	} else {
+		writtenConfigHash = configHash(configBytes)
 		var modelRepositoryConfig OvmsMultiModelRepositoryConfig
 		if err := json.Unmarshal(configBytes, &modelRepositoryConfig); err != nil {
 			log.Error(err, "WARNING: could not parse model config JSON, will continue with empty config", "filename", multiModelConfigFilename)
 		} else {
+			writtenConfig = entriesByName(modelRepositoryConfig.ModelConfigList)
+			modelIds := readModelNames(modelNamesFilename(multiModelConfigFilename), log)
 			multiModelConfig = make(map[string]OvmsMultiModelConfigListEntry, len(modelRepositoryConfig.ModelConfigList))
 			for _, mc := range modelRepositoryConfig.ModelConfigList {
-				multiModelConfig[mc.Config.Name] = mc
+				if modelId, ok := modelIds[mc.Config.Name]; ok {
+					multiModelConfig[modelId] = mc
+				} else {
+					multiModelConfig[mc.Config.Name] = mc
+				}
 			}
 		}
 	}
 
+	// a config left by a previous run may reference models whose files are
+	// gone, which OVMS would fail to load
+	var prunedModels map[string]OvmsMultiModelConfigListEntry
+	if mmConfig.PruneMissingModels {
+		prunedModels = pruneMissingModels(multiModelConfig, log)
+	}
+
 	configRequest, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/config", address), http.NoBody)
 	if err != nil {
 		return nil, err
@@ -148,14 +338,26 @@ func NewOvmsModelManager(address string, multiModelConfigFilename string, log lo
 			},
 		},
 		log:                       log,
+		reloadLog:                 util.NewDedupLogger(log, mmConfig.LogDedupWindow),
 		loadedModelsMap:           multiModelConfig,
 		modelConfigFilename:       multiModelConfigFilename,
 		requests:                  make(chan *request, mmConfig.RequestChannelSize),
+		metrics:                   newReloadMetrics(mmConfig.MetricsModelLabels),
+		debug:                     newDebugState(),
+		events:                    newModelEvents(),
+		health:                    newModelHealth(mmConfig.ModelHealthFailurePolls, mmConfig.ModelHealthWindow),
+		unloadedNames:             map[string]string{},
+		writtenConfig:             writtenConfig,
+		writtenConfigHash:         writtenConfigHash,
+		modelLabels:               map[string]map[string]string{},
+		modelTypes:                map[string]string{},
 		modelRepositoryConfigList: make([]OvmsMultiModelConfigListEntry, 0, len(multiModelConfig)),
 	}
+	ovmsMM.breaker = util.NewCircuitBreaker(mmConfig.CircuitBreakerThreshold, mmConfig.CircuitBreakerCooldown, ovmsMM.probeHealth, log)
 
-	// write the config out on boot because OVMS needs it to exist
-	if _, err := os.Stat(multiModelConfigFilename); os.IsNotExist(err) {
+	// write the config out on boot because OVMS needs it to exist, and
+	// rewrite it if it was empty or stale entries were pruned
+	if _, err := os.Stat(multiModelConfigFilename); os.IsNotExist(err) || emptyConfigFile || len(prunedModels) > 0 {
 		if err = ovmsMM.writeConfig(); err != nil {
 			log.Error(err, "Unable to write out empty config file")
 		}
@@ -167,9 +369,56 @@ func NewOvmsModelManager(address string, multiModelConfigFilename string, log lo
 	return ovmsMM, nil
 }
 
+// readModelNames reads the map of sanitized or served model names to model
+// ids written by writeConfig, a missing or invalid file results in an empty
+// map
+func readModelNames(filename string, log logr.Logger) map[string]string {
+	modelIds := map[string]string{}
+	namesBytes, err := os.ReadFile(filename)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Error(err, "WARNING: could not read the model names, models are identified by their name in the config", "filename", filename)
+		}
+		return modelIds
+	}
+	if err = json.Unmarshal(namesBytes, &modelIds); err != nil {
+		log.Error(err, "WARNING: could not parse the model names JSON, models are identified by their name in the config", "filename", filename)
+		return map[string]string{}
+	}
+	return modelIds
+}
+
 // "Client" API
-func (mm *OvmsModelManager) LoadModel(ctx context.Context, modelPath string, modelId string) error {
 
+// LoadModel adds the model to the config and reloads OVMS
+//
+// The model is named servedName in the config, or after its model id if
+// servedName is empty; it is always identified by its model id otherwise.
+// The labels with a key in MetricsModelLabels are attached to the metrics of
+// the model, and the loaded models are counted by their modelType, which is
+// empty if OVMS detects it from the model files.
+func (mm *OvmsModelManager) LoadModel(ctx context.Context, modelPath string, modelId string, modelType string, servedName string, pluginConfig map[string]string, labels map[string]string) error {
+	req, err := mm.newLoadRequest(modelPath, modelId, modelType, servedName, pluginConfig, labels)
+	if err != nil {
+		return fmt.Errorf("LoadModel errored: %w", err)
+	}
+
+	// don't queue up reloads that are likely to time out while OVMS is failing
+	if err := mm.breaker.Allow(); err != nil {
+		return fmt.Errorf("LoadModel errored: %w", err)
+	}
+	if err := mm.waitForFileVisibility(ctx); err != nil {
+		return fmt.Errorf("LoadModel errored: %w", err)
+	}
+
+	if err := mm.handleRequest(ctx, req); err != nil {
+		return fmt.Errorf("LoadModel errored: %w", err)
+	}
+	return nil
+}
+
+// newLoadRequest builds the request to load a model, see LoadModel
+func (mm *OvmsModelManager) newLoadRequest(modelPath string, modelId string, modelType string, servedName string, pluginConfig map[string]string, labels map[string]string) (*request, error) {
 	// BasePath must be a directory
 	var basePath string
 	if fileInfo, err := os.Stat(modelPath); err == nil {
@@ -179,21 +428,103 @@ func (mm *OvmsModelManager) LoadModel(ctx context.Context, modelPath string, mod
 			basePath = filepath.Dir(modelPath)
 		}
 	} else {
-		return fmt.Errorf("Could not stat file at the model_path: %w", err)
+		return nil, fmt.Errorf("Could not stat file at the model_path: %w", err)
 	}
 
-	req := &request{
-		requestType: load,
-		modelId:     modelId,
-		basePath:    basePath,
+	labels, droppedLabels := mm.metrics.allowedModelLabels(labels)
+	if len(droppedLabels) > 0 {
+		mm.log.V(1).Info("Dropping model labels that are not allowed in the metrics", "model_id", modelId, "labels", droppedLabels)
 	}
 
-	if err := mm.handleRequest(ctx, req); err != nil {
-		return fmt.Errorf("LoadModel errored: %w", err)
+	return &request{
+		requestType:  load,
+		modelId:      modelId,
+		modelType:    modelType,
+		servedName:   servedName,
+		basePath:     basePath,
+		pluginConfig: pluginConfig,
+		labels:       labels,
+	}, nil
+}
+
+// LoadBatch collects the loads of models whose files are staged one after
+// the other, which are all loaded with a single reload of OVMS on Commit
+//
+// Nothing is written to the config before the Commit, so a partially staged
+// batch never triggers a reload. Add is safe to call concurrently.
+type LoadBatch struct {
+	mm *OvmsModelManager
+
+	mutex    sync.Mutex
+	requests []*request
+}
+
+// NewLoadBatch returns an empty batch of loads
+func (mm *OvmsModelManager) NewLoadBatch() *LoadBatch {
+	return &LoadBatch{mm: mm}
+}
+
+// Add adds the load of a model to the batch, with the arguments of LoadModel
+func (b *LoadBatch) Add(modelPath string, modelId string, modelType string, servedName string, pluginConfig map[string]string, labels map[string]string) error {
+	req, err := b.mm.newLoadRequest(modelPath, modelId, modelType, servedName, pluginConfig, labels)
+	if err != nil {
+		return fmt.Errorf("LoadBatch errored: %w", err)
 	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.requests = append(b.requests, req)
 	return nil
 }
 
+// Commit loads the models added to the batch with a single reload and waits
+// for all of them, the error of the first model that failed to load in the
+// order they were added is returned
+//
+// The batch is empty again after the Commit.
+func (b *LoadBatch) Commit(ctx context.Context) error {
+	b.mutex.Lock()
+	reqs := b.requests
+	b.requests = nil
+	b.mutex.Unlock()
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	// don't queue up reloads that are likely to time out while OVMS is failing
+	if err := b.mm.breaker.Allow(); err != nil {
+		return fmt.Errorf("LoadBatch errored: %w", err)
+	}
+	if err := b.mm.waitForFileVisibility(ctx); err != nil {
+		return fmt.Errorf("LoadBatch errored: %w", err)
+	}
+
+	results := make([]chan error, len(reqs))
+	for i, r := range reqs {
+		results[i] = make(chan error, 1)
+		r.c = results[i]
+		r.ctx = ctx
+	}
+	b.mm.requests <- &request{
+		requestType: loadBatch,
+		batch:       reqs,
+		ctx:         ctx,
+	}
+
+	var firstErr error
+	for i, c := range results {
+		select {
+		case err := <-c:
+			if err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("LoadBatch errored for model %s: %w", reqs[i].modelId, err)
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("Request was cancelled")
+		}
+	}
+	return firstErr
+}
+
 func (mm *OvmsModelManager) UnloadModel(ctx context.Context, modelId string) error {
 	req := &request{
 		requestType: unload,
@@ -217,6 +548,19 @@ func (mm *OvmsModelManager) UnloadAll(ctx context.Context) error {
 	return nil
 }
 
+// UnloadMissing unloads the models whose directory no longer exists, the
+// other models stay loaded
+func (mm *OvmsModelManager) UnloadMissing(ctx context.Context) error {
+	req := &request{
+		requestType: unloadMissing,
+	}
+
+	if err := mm.handleRequest(ctx, req); err != nil {
+		return fmt.Errorf("UnloadMissing errored: %w", err)
+	}
+	return nil
+}
+
 func (mm *OvmsModelManager) handleRequest(ctx context.Context, r *request) error {
 	c := make(chan error, 1)
 	r.c = c
@@ -235,25 +579,51 @@ func (mm *OvmsModelManager) handleRequest(ctx context.Context, r *request) error
 	}
 }
 
+// MetricsHandler returns an http.Handler serving the reload and model load
+// failure metrics in the Prometheus text format
+func (mm *OvmsModelManager) MetricsHandler() http.Handler {
+	return mm.metrics
+}
+
 func (mm *OvmsModelManager) GetConfig(ctx context.Context) error {
 	return mm.getConfig(ctx)
 }
 
+// UnhealthyModels polls the config from OVMS and returns the ids of the
+// loaded models that have not been AVAILABLE in the recent polls, see
+// ModelHealthFailurePolls
+func (mm *OvmsModelManager) UnhealthyModels(ctx context.Context) ([]string, error) {
+	configResponse, err := mm.fetchConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	unhealthy := mm.health.observe(configResponse, time.Now())
+	mm.metrics.setUnhealthyModels(len(unhealthy))
+	return unhealthy, nil
+}
+
 // internal
 
 type requestType string
 
 const (
-	load      requestType = "Load"
-	unload    requestType = "Unload"
-	unloadAll requestType = "UnloadAll"
+	load          requestType = "Load"
+	unload        requestType = "Unload"
+	unloadAll     requestType = "UnloadAll"
+	unloadMissing requestType = "UnloadMissing"
+	loadBatch     requestType = "LoadBatch"
 )
 
 type request struct {
 	requestType requestType
 
-	modelId  string // for load and unload
-	basePath string // for load
+	modelId      string            // for load and unload
+	modelType    string            // for load
+	servedName   string            // for load
+	basePath     string            // for load
+	pluginConfig map[string]string // for load
+	labels       map[string]string // for load
+	batch        []*request        // for loadBatch, the loads of the batch
 
 	ctx context.Context
 	c   chan<- error
@@ -266,9 +636,18 @@ type request struct {
 // Receives a stream of requests from its channel
 func (mm *OvmsModelManager) run() {
 	log := mm.log.WithValues("thread", "run")
+	reloadLog := mm.reloadLog.WithValues("thread", "run")
 	log.Info("Starting ModelManger thread")
+	if mm.config.ReconcileOnBoot {
+		mm.reconcileOnBoot()
+	}
 	for mm.requests != nil {
+		mm.debug.setLoadedModels(mm.loadedModelsMap)
+		mm.pruneModelLabels()
+		mm.metrics.setLoadedModels(mm.loadedModelsMap, mm.modelLabels, mm.modelTypes)
+		mm.health.setLoadedModels(mm.loadedModelsMap)
 		loadRequestsMap := mm.gatherLoadRequests()
+		mm.debug.setLoadedModels(mm.loadedModelsMap)
 
 		// gatherLoadRequests() collects requests over time, some requests
 		// may have been cancelled by now. Check that here before
@@ -284,56 +663,184 @@ func (mm *OvmsModelManager) run() {
 			}
 		}
 
+		// the states of the other models before the reload, to log the ones
+		// that the reload changed
+		previousConfigResponse := mm.cachedModelConfigResponse
+		requestedModelIds := make(map[string]struct{}, len(loadRequestsMap))
+		loadModelIds := make([]string, 0, len(loadRequestsMap))
+		for id := range loadRequestsMap {
+			requestedModelIds[id] = struct{}{}
+			loadModelIds = append(loadModelIds, id)
+			mm.events.record(id, eventReloadRequested, "")
+		}
+
 		// reload the config
 		if err := mm.updateModelConfig(); err != nil {
 			msg := "Failed to update model configuration with OVMS"
-			log.Error(err, msg)
+			reloadLog.Error(err, msg)
+
+			// an invalid config, or one that would overwrite edits to
+			// the file, is not written, so OVMS was not reloaded and is
+			// not at fault
+			code := codes.Internal
+			if errors.Is(err, errInvalidModelConfig) {
+				code = codes.InvalidArgument
+			} else if errors.Is(err, errExternalConfigEdit) {
+				code = codes.FailedPrecondition
+			} else {
+				mm.breaker.RecordFailure()
+			}
 
 			// at this point, we don't know whether OVMS has
 			// reloaded or not... but we treat it as if the load
 			// failed
 			for id, req := range loadRequestsMap {
-				completeRequest(req, codes.Internal, fmt.Sprintf("%s: %v", msg, err))
+				completeRequest(req, code, fmt.Sprintf("%s: %v", msg, err))
 				delete(mm.loadedModelsMap, id)
 			}
 
 			continue // back to the start of the run() loop
 		}
+		mm.breaker.RecordSuccess()
+
+		// complete the requests, waiting for the models that are still
+		// transitioning between states
+		loadCodes := make(map[string]codes.Code, len(loadRequestsMap))
+		util.WaitForModelsReady(loadModelIds, mm.config.ModelStateTimeout, mm.config.ModelStatePollInterval,
+			func() error {
+				ctx, cancel := context.WithTimeout(context.Background(), mm.config.ReloadTimeout)
+				defer cancel()
+				return mm.getConfig(ctx)
+			},
+			func(id string) (util.ModelReadiness, string) {
+				readiness, code, message := mm.checkLoadState(id)
+				loadCodes[id] = code
+				return readiness, message
+			},
+			func(id string, readiness util.ModelReadiness, message string) {
+				code := loadCodes[id]
+				if readiness == util.ModelNotReady {
+					code = codes.DeadlineExceeded
+					message = fmt.Sprintf("Timed out waiting for OVMS to load the model, last state: '%s'", mm.getModelState(id))
+				}
+				log.V(1).Info("Completing load request", "model_id", id, "grpcCode", code, "message", message)
+				mm.events.observeState(id, mm.getModelState(id))
+				completeRequest(loadRequestsMap[id], code, message)
 
-		// complete the requests
-		for id, req := range loadRequestsMap {
-			var statusExists bool
-			var modelStatus OvmsModelVersionStatus
-			modelState := "_missing_" // default value for logging purposes
-
-			conf, statusExists := mm.cachedModelConfigResponse[id]
-			if statusExists {
-				modelStatus = conf.ModelVersionStatus[0]
-				modelState = modelStatus.State
-			}
+				// if the load failed, cleanup the map entry
+				if code != codes.OK {
+					delete(mm.loadedModelsMap, id)
+				}
+			},
+			log.WithValues("waitFor", "OVMS"))
 
-			var code codes.Code
-			var message string
-			if !statusExists {
-				code = codes.Internal
-				message = "Expected model to load, but no status entry found in the config"
-			} else if modelStatus.State == "AVAILABLE" {
-				code = codes.OK
-			} else {
-				code = codes.Unknown
-				message = fmt.Sprintf("OVMS model load failed. code: '%s' reason: '%s'", modelStatus.Status.ErrorCode, modelStatus.Status.ErrorMessage)
-			}
-			log.V(1).Info("Completing load request", "model_id", id, "state", modelState, "grpcCode", code, "message", message)
-			completeRequest(req, code, message)
+		// a load only fails when the requested model fails, other models
+		// that change state with the reload are just logged
+		mm.logCollateralStateChanges(previousConfigResponse, requestedModelIds)
+		mm.observeUnloadedStates()
+	}
 
-			// if the load failed, cleanup the map entry
-			if code != codes.OK {
-				delete(mm.loadedModelsMap, id)
-			}
+	log.Info("ModelManager thread exiting")
+}
+
+// reconcileOnBoot registers the models of the initial config with OVMS by
+// rewriting the config file and reloading once
+//
+// The directories of the models are checked first, ReconcileConcurrency at a
+// time, and the models whose directory is gone are removed from the config,
+// like with PruneMissingModels. Models that fail to load are removed too. If
+// the reload itself fails, the models are kept and are registered by the next
+// reload.
+//
+// An initial config without models is only reloaded with ReconcileEmptyConfig.
+func (mm *OvmsModelManager) reconcileOnBoot() {
+	if len(mm.loadedModelsMap) == 0 && !mm.config.ReconcileEmptyConfig {
+		return
+	}
+	log := mm.log.WithValues("thread", "reconcile")
+	mm.removeModelsWithoutDirs(log)
+	if len(mm.loadedModelsMap) == 0 {
+		log.Info("Reloading OVMS with the empty initial config")
+	} else {
+		log.Info("Reloading the models of the initial config", "numModels", len(mm.loadedModelsMap))
+	}
+
+	if err := mm.updateModelConfig(); err != nil {
+		log.Error(err, "Failed to reload the models of the initial config, they will be registered by the next reload")
+		mm.breaker.RecordFailure()
+		return
+	}
+	mm.breaker.RecordSuccess()
+
+	for id := range mm.loadedModelsMap {
+		readiness, _, message := mm.checkLoadState(id)
+		if readiness == util.ModelNotReady {
+			log.Info("Model of the initial config is still loading", "model_id", id, "state", mm.getModelState(id))
+			continue
+		}
+		if readiness == util.ModelFailed {
+			log.Info("Removing model of the initial config that failed to load", "model_id", id, "message", message)
+			delete(mm.loadedModelsMap, id)
 		}
 	}
+}
 
-	log.Info("ModelManager thread exiting")
+// removeModelsWithoutDirs removes the models whose base_path directory does
+// not exist from the config, checking ReconcileConcurrency models at once
+//
+// OVMS would fail to load these models, and the stat of each directory can be
+// slow on a network volume.
+func (mm *OvmsModelManager) removeModelsWithoutDirs(log logr.Logger) {
+	ids := make([]string, 0, len(mm.loadedModelsMap))
+	for id := range mm.loadedModelsMap {
+		ids = append(ids, id)
+	}
+	errs := make([]error, len(ids))
+	var g errgroup.Group
+	g.SetLimit(mm.config.ReconcileConcurrency)
+	for i, id := range ids {
+		i, basePath := i, mm.loadedModelsMap[id].Config.BasePath
+		g.Go(func() error {
+			errs[i] = checkModelDir(basePath)
+			return nil
+		})
+	}
+	g.Wait()
+
+	for i, id := range ids {
+		if errs[i] != nil {
+			log.Info("Removing model of the initial config without a directory", "model_id", id, "error", errs[i])
+			delete(mm.loadedModelsMap, id)
+		}
+	}
+}
+
+// checkModelDir returns an error if the base path of a model is not a
+// directory, which is replaced in tests
+var checkModelDir = func(basePath string) error {
+	info, err := os.Stat(basePath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("The model path %s is not a directory", basePath)
+	}
+	return nil
+}
+
+// pruneMissingModels removes the entries whose base_path directory does not
+// exist from the model config and returns the removed entries
+func pruneMissingModels(modelConfig map[string]OvmsMultiModelConfigListEntry, log logr.Logger) map[string]OvmsMultiModelConfigListEntry {
+	pruned := map[string]OvmsMultiModelConfigListEntry{}
+	for id, entry := range modelConfig {
+		if info, err := os.Stat(entry.Config.BasePath); err == nil && info.IsDir() {
+			continue
+		}
+		log.Info("Pruning model from the config because its directory is missing", "model_id", id, "base_path", entry.Config.BasePath)
+		delete(modelConfig, id)
+		pruned[id] = entry
+	}
+	return pruned
 }
 
 // gatherUpdates reads requests from the channel and updates the loadedModelsMap
@@ -346,21 +853,11 @@ func (mm *OvmsModelManager) run() {
 // is needed; here we take the approach of waiting for a period of time after a
 // request is received
 func (mm *OvmsModelManager) gatherLoadRequests() map[string]*request {
-	requestMap := map[string]*request{}
-	// used to signal when to proceed with a reload after a timer
-	var stopChan <-chan time.Time
-	// Load calls need to trigger the model server as part of the request,
-	// but an unload request can be completed immediately by removing the
-	// registration from the models map (state will be synced with the next
-	// updates) Though even unloads should be processed eventually. To
-	// support this the timer duration for the stopChan depends on wether or
-	// not a load request is included in the batch of updates, which is
-	// tracked with this boolean
-	shortTimerSet := false
+	g := &gatheredRequests{requestMap: map[string]*request{}}
 	for {
 		select {
-		case <-stopChan:
-			return requestMap
+		case <-g.stopChan:
+			return g.requestMap
 
 		case req, ok := <-mm.requests:
 			if !ok {
@@ -369,78 +866,294 @@ func (mm *OvmsModelManager) gatherLoadRequests() map[string]*request {
 				break // from select
 			}
 
-			// has the context been cancelled?
-			if err := req.ctx.Err(); err != nil {
-				s := status.FromContextError(err)
-				mm.log.V(1).Info("Aborting request with cancelled context", "model_id", req.modelId, "request_type", req.requestType, "code", s.Code())
-				completeRequest(req, s.Code(), "Request context has been cancelled")
+			// the loads of a batch are gathered together, so that they
+			// join the same reload
+			if req.requestType == loadBatch {
+				for _, r := range req.batch {
+					mm.gatherRequest(g, r)
+				}
 				continue
 			}
+			mm.gatherRequest(g, req)
+		}
+	}
+}
+
+// gatheredRequests are the requests gathered for the next reload, with the
+// timer that ends the gathering
+type gatheredRequests struct {
+	requestMap map[string]*request
+	// used to signal when to proceed with a reload after a timer
+	stopChan <-chan time.Time
+	// Load calls need to trigger the model server as part of the request,
+	// but an unload request can be completed immediately by removing the
+	// registration from the models map (state will be synced with the next
+	// updates) Though even unloads should be processed eventually. To
+	// support this the timer duration for the stopChan depends on wether or
+	// not a load request is included in the batch of updates, which is
+	// tracked with this boolean
+	shortTimerSet bool
+	// Unloads extend the wait by UnloadGracePeriod so that a burst of
+	// unloads joins the same reload, up to unloadDeadline
+	unloadGraceSet bool
+	unloadDeadline time.Time
+}
 
-			switch req.requestType {
-			case unloadAll:
-				mm.log.V(1).Info("Processing UnloadAll", "numRequests", len(requestMap))
+// gatherRequest applies a request to the desired model state and adds it to
+// the gathered requests, setting the timer for the reload
+func (mm *OvmsModelManager) gatherRequest(g *gatheredRequests, req *request) {
+	// has the context been cancelled?
+	if err := req.ctx.Err(); err != nil {
+		s := status.FromContextError(err)
+		mm.log.V(1).Info("Aborting request with cancelled context", "model_id", req.modelId, "request_type", req.requestType, "code", s.Code())
+		completeRequest(req, s.Code(), "Request context has been cancelled")
+		return
+	}
 
-				// abort all pending requests
-				for _, r := range requestMap {
-					mm.log.V(1).Info("Aborting request to reset the Model Server", "model_id", r.modelId, "request_type", r.requestType, "context_error", r.ctx.Err().Error())
-					completeRequest(r, codes.Aborted, "Model Server is being reset")
-				}
-				requestMap = map[string]*request{}
+	switch req.requestType {
+	case unloadAll:
+		mm.log.V(1).Info("Processing UnloadAll", "numRequests", len(g.requestMap))
 
-				// an UnloadAll does not need to trigger a reload right now, we can report success
-				// and will sync state with the model server on the next reload
-				completeRequest(req, codes.OK, "")
+		// abort all pending requests
+		for _, r := range g.requestMap {
+			mm.log.V(1).Info("Aborting request to reset the Model Server", "model_id", r.modelId, "request_type", r.requestType, "context_error", r.ctx.Err().Error())
+			completeRequest(r, codes.Aborted, "Model Server is being reset")
+		}
+		g.requestMap = map[string]*request{}
 
-				// reset the desired model state to be empty
-				mm.loadedModelsMap = map[string]OvmsMultiModelConfigListEntry{}
+		// an UnloadAll does not need to trigger a reload right now, we can report success
+		// and will sync state with the model server on the next reload
+		completeRequest(req, codes.OK, "")
 
-				// reset the stop timer
-				if stopChan == nil {
-					shortTimerSet = false
-					stopChan = time.NewTimer(mm.config.BatchWaitTimeMax).C
-				}
+		// reset the desired model state to be empty
+		mm.loadedModelsMap = map[string]OvmsMultiModelConfigListEntry{}
 
-			case unload:
-				// abort any pending load requests for this model
-				if requestMap[req.modelId] != nil {
-					mm.log.V(1).Info("Aborting request due to subsequent unload", "model_id", requestMap[req.modelId].modelId, "request_type", requestMap[req.modelId].requestType, "context_error", requestMap[req.modelId].ctx.Err().Error())
-					completeRequest(requestMap[req.modelId], codes.Aborted, "Aborting due to subsequent unload request")
-					delete(requestMap, req.modelId)
-				}
+		// reset the stop timer
+		if g.stopChan == nil {
+			g.shortTimerSet = false
+			g.stopChan = time.NewTimer(mm.config.BatchWaitTimeMax).C
+		}
 
-				delete(mm.loadedModelsMap, req.modelId)
-				// an Unload does not need to trigger a config reload, we can report
-				// success and will sync state with the model server on the next reload
-				completeRequest(req, codes.OK, "")
+	case unloadMissing:
+		mm.log.V(1).Info("Processing UnloadMissing", "numRequests", len(g.requestMap))
 
-				// set the stop timer, if not already set
-				if stopChan == nil {
-					stopChan = time.NewTimer(mm.config.BatchWaitTimeMax).C
-				}
+		for modelId, entry := range pruneMissingModels(mm.loadedModelsMap, mm.log) {
+			mm.events.record(modelId, eventUnloadRequested, "")
+			mm.unloadedNames[modelId] = entry.Config.Name
+		}
+		// like an UnloadAll, the remaining models are synced with the
+		// model server on the next reload
+		completeRequest(req, codes.OK, "")
 
-			case load:
-				// abort any pending load requests for this model
-				if requestMap[req.modelId] != nil {
-					mm.log.V(1).Info("Aborting request due to subsequent load", "model_id", requestMap[req.modelId].modelId, "request_type", requestMap[req.modelId].requestType, "context_error", requestMap[req.modelId].ctx.Err().Error())
-					completeRequest(requestMap[req.modelId], codes.Aborted, "Aborting due to concurrent load request")
-				}
+		if g.stopChan == nil {
+			g.shortTimerSet = false
+			g.stopChan = time.NewTimer(mm.config.BatchWaitTimeMax).C
+		}
 
-				// set the stop timer, if not already set with the short timer
-				if stopChan == nil || !shortTimerSet {
-					shortTimerSet = true
-					stopChan = time.NewTimer(mm.config.BatchWaitTimeMin).C
-				}
+	case unload:
+		// abort any pending load requests for this model
+		if g.requestMap[req.modelId] != nil {
+			mm.log.V(1).Info("Aborting request due to subsequent unload", "model_id", g.requestMap[req.modelId].modelId, "request_type", g.requestMap[req.modelId].requestType, "context_error", g.requestMap[req.modelId].ctx.Err().Error())
+			completeRequest(g.requestMap[req.modelId], codes.Aborted, "Aborting due to subsequent unload request")
+			delete(g.requestMap, req.modelId)
+		}
 
-				requestMap[req.modelId] = req
-				mm.loadedModelsMap[req.modelId] = OvmsMultiModelConfigListEntry{
-					Config: OvmsMultiModelModelConfig{
-						Name:     req.modelId,
-						BasePath: req.basePath,
-					},
-				}
+		mm.events.record(req.modelId, eventUnloadRequested, "")
+		mm.unloadedNames[req.modelId] = mm.configName(req.modelId)
+		delete(mm.loadedModelsMap, req.modelId)
+		// an Unload does not need to trigger a config reload, we can report
+		// success and will sync state with the model server on the next reload
+		completeRequest(req, codes.OK, "")
+
+		// set or extend the stop timer, unless it was set by another request type
+		if g.stopChan == nil || g.unloadGraceSet {
+			now := time.Now()
+			if !g.unloadGraceSet {
+				g.unloadGraceSet = true
+				g.unloadDeadline = now.Add(mm.config.BatchWaitTimeMax)
+			}
+			wait := mm.config.UnloadGracePeriod
+			if remaining := g.unloadDeadline.Sub(now); remaining < wait {
+				wait = remaining
+			}
+			g.stopChan = time.NewTimer(wait).C
+		}
+
+	case load:
+		name := req.modelId
+		if req.servedName != "" {
+			name = req.servedName
+		} else if mm.config.SanitizeModelNames {
+			name = sanitizeModelName(req.modelId)
+		}
+		// an entry that OVMS would reject fails the reload for all
+		// models, so it is not added to the config
+		entry := OvmsMultiModelConfigListEntry{
+			Config: OvmsMultiModelModelConfig{
+				Name:         name,
+				BasePath:     req.basePath,
+				PluginConfig: req.pluginConfig,
+			},
+		}
+		code := codes.InvalidArgument
+		err := entry.validate()
+		if err == nil {
+			if err = mm.checkNameIsUnique(req.modelId, name); err != nil {
+				code = mm.nameCollisionCode()
+			}
+		}
+		if err != nil {
+			mm.log.Info("Rejecting load request with an invalid model config", "model_id", req.modelId, "error", err)
+			completeRequest(req, code, err.Error())
+			return
+		}
+
+		// abort any pending load requests for this model
+		if g.requestMap[req.modelId] != nil {
+			mm.log.V(1).Info("Aborting request due to subsequent load", "model_id", g.requestMap[req.modelId].modelId, "request_type", g.requestMap[req.modelId].requestType, "context_error", g.requestMap[req.modelId].ctx.Err().Error())
+			completeRequest(g.requestMap[req.modelId], codes.Aborted, "Aborting due to concurrent load request")
+		}
+
+		// set the stop timer, if not already set with the short timer
+		if g.stopChan == nil || !g.shortTimerSet {
+			g.shortTimerSet = true
+			g.unloadGraceSet = false
+			g.stopChan = time.NewTimer(mm.config.BatchWaitTimeMin).C
+		}
+
+		g.requestMap[req.modelId] = req
+		mm.loadedModelsMap[req.modelId] = entry
+		mm.modelLabels[req.modelId] = req.labels
+		mm.modelTypes[req.modelId] = req.modelType
+	}
+}
+
+// pruneModelLabels removes the labels and the types of the models that are
+// no longer loaded
+func (mm *OvmsModelManager) pruneModelLabels() {
+	for id := range mm.modelLabels {
+		if _, loaded := mm.loadedModelsMap[id]; !loaded {
+			delete(mm.modelLabels, id)
+		}
+	}
+	for id := range mm.modelTypes {
+		if _, loaded := mm.loadedModelsMap[id]; !loaded {
+			delete(mm.modelTypes, id)
+		}
+	}
+}
+
+// checkNameIsUnique returns an error if another model has the name in the
+// config
+func (mm *OvmsModelManager) checkNameIsUnique(modelId string, name string) error {
+	for id, entry := range mm.loadedModelsMap {
+		if id != modelId && entry.Config.Name == name {
+			return fmt.Errorf("%w: model name '%s' is already used by model '%s'", errInvalidModelConfig, name, id)
+		}
+	}
+	if _, ok := mm.externalEntries[name]; ok {
+		return fmt.Errorf("%w: model name '%s' is already used by a model added to the config file outside of the adapter", errInvalidModelConfig, name)
+	}
+	return nil
+}
+
+// Values of NameCollisionCode, the code that a load fails with when the name
+// of the model is already used by another model
+const (
+	NameCollisionInvalidArgument string = "invalid-argument"
+	NameCollisionAlreadyExists   string = "already-exists"
+)
+
+// nameCollisionCode returns the code of a load whose model name is already
+// used by another model
+func (mm *OvmsModelManager) nameCollisionCode() codes.Code {
+	if mm.config.NameCollisionCode == NameCollisionAlreadyExists {
+		return codes.AlreadyExists
+	}
+	return codes.InvalidArgument
+}
+
+// configName returns the name of a loaded model in the config, which is the
+// key of its status in the config response
+func (mm *OvmsModelManager) configName(modelId string) string {
+	if entry, ok := mm.loadedModelsMap[modelId]; ok {
+		return entry.Config.Name
+	}
+	return modelId
+}
+
+// checkLoadState checks the state of a loaded model in the cached config
+// response
+//
+// The code is OK for a ready model, Internal if OVMS has no status for the
+// model and Unknown if the load failed. With WaitForMissingModels, a model
+// without a status is not ready yet instead.
+func (mm *OvmsModelManager) checkLoadState(modelId string) (readiness util.ModelReadiness, code codes.Code, message string) {
+	conf, statusExists := mm.cachedModelConfigResponse[mm.configName(modelId)]
+	readiness, message = modelReadiness(conf, statusExists)
+	if !statusExists && mm.config.WaitForMissingModels {
+		readiness, message = util.ModelNotReady, "Waiting for a status entry of the model in the config"
+	}
+	switch {
+	case readiness == util.ModelNotReady:
+		mm.log.V(1).Info("Waiting for model to become available", "model_id", modelId, "state", mm.getModelState(modelId))
+		return readiness, codes.OK, message
+	case readiness == util.ModelReady:
+		return readiness, codes.OK, message
+	case !statusExists || len(conf.ModelVersionStatus) == 0:
+		return readiness, codes.Internal, message
+	}
+
+	if modelStatus := conf.ModelVersionStatus[0]; modelStatus.Status.hasError() {
+		mm.metrics.observeLoadFailure(modelStatus.Status.ErrorCode, mm.modelLabels[modelId])
+	}
+	return readiness, codes.Unknown, message
+}
+
+// getModelState returns the state of the model in the cached config
+// response, for logging purposes
+func (mm *OvmsModelManager) getModelState(modelId string) string {
+	return modelState(mm.cachedModelConfigResponse, mm.configName(modelId))
+}
+
+// the state of a model without a status in the config response
+const modelStateMissing = "_missing_"
+
+func modelState(configResponse OvmsConfigResponse, name string) string {
+	conf, ok := configResponse[name]
+	if !ok || len(conf.ModelVersionStatus) == 0 {
+		return modelStateMissing
+	}
+	return conf.ModelVersionStatus[0].State
+}
+
+// logCollateralStateChanges logs the loaded models that were not requested
+// in the reload but whose state changed compared to the previous config
+// response
+func (mm *OvmsModelManager) logCollateralStateChanges(previous OvmsConfigResponse, requestedModelIds map[string]struct{}) {
+	for id := range mm.loadedModelsMap {
+		if _, requested := requestedModelIds[id]; requested {
+			continue
+		}
+		name := mm.configName(id)
+		previousState, state := modelState(previous, name), modelState(mm.cachedModelConfigResponse, name)
+		if previousState != state {
+			mm.log.Info("Model that was not requested changed state with the reload", "model_id", id, "previous_state", previousState, "state", state)
+			mm.events.observeState(id, state)
+		}
+	}
+}
+
+// observeUnloadedStates records the state of the models that were unloaded
+// since the last reload, eg. END
+func (mm *OvmsModelManager) observeUnloadedStates() {
+	for id, name := range mm.unloadedNames {
+		if _, loaded := mm.loadedModelsMap[id]; !loaded {
+			if state := modelState(mm.cachedModelConfigResponse, name); state != modelStateMissing {
+				mm.events.observeState(id, state)
 			}
 		}
+		delete(mm.unloadedNames, id)
 	}
 }
 
@@ -450,10 +1163,21 @@ func completeRequest(req *request, code codes.Code, reason string) {
 }
 
 func (mm *OvmsModelManager) getConfig(ctx context.Context) error {
+	c, err := mm.fetchConfig(ctx)
+	if err != nil {
+		return err
+	}
+	mm.cachedModelConfigResponse = c
+	return nil
+}
+
+// fetchConfig queries the config status API without updating the cached
+// config response
+func (mm *OvmsModelManager) fetchConfig(ctx context.Context) (OvmsConfigResponse, error) {
 	// query the Config Status API
 	resp, err := mm.client.Do(mm.configRequest.WithContext(ctx))
 	if err != nil {
-		return fmt.Errorf("Protocol error getting the config: %w", err)
+		return nil, fmt.Errorf("Protocol error getting the config: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -462,41 +1186,61 @@ func (mm *OvmsModelManager) getConfig(ctx context.Context) error {
 	// we read the body regardless of the status of the response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("Error reading config status response body: %w", err)
+		return nil, fmt.Errorf("Error reading config status response body: %w", err)
 	}
 
 	// handle successful request
 	if resp.StatusCode == http.StatusOK {
-		var c OvmsConfigResponse
-		if err1 := json.Unmarshal(body, &c); err1 != nil {
+		c, err1 := parseOvmsConfigResponse(body, mm.config.ApiVersion)
+		if err1 != nil {
 			const msg string = "Error parsing /config response"
 			mm.log.V(1).Error(err1, msg, "responseBody", string(body))
-			return fmt.Errorf("%s: %w", msg, err1)
+			return nil, fmt.Errorf("%s: %w", msg, err1)
 		}
-		mm.cachedModelConfigResponse = c
 
-		return nil
+		return c, nil
 	}
 
 	var errorResponse OvmsConfigErrorResponse
 	if err = json.Unmarshal(body, &errorResponse); err != nil {
 		const msg string = "Error parsing /config error response"
 		mm.log.V(1).Error(err, msg, "responseBody", string(body))
-		return fmt.Errorf("%s: %w", msg, err)
+		return nil, fmt.Errorf("%s: %w", msg, err)
 	}
 
 	errDesc := fmt.Errorf("Error response when getting the config: %s", errorResponse.Error)
 	mm.log.Error(errDesc, "Call to /v1/config returned an error", "code", resp.StatusCode)
 
-	return status.Error(codes.Internal, errDesc.Error())
+	return nil, status.Error(codes.Internal, errDesc.Error())
+}
+
+// probeHealth checks that OVMS responds to the config API without updating
+// the cached config, which is owned by the run() loop
+func (mm *OvmsModelManager) probeHealth(ctx context.Context) error {
+	resp, err := mm.client.Do(mm.configRequest.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("Protocol error getting the config: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status getting the config: %s", resp.Status)
+	}
+	return nil
 }
 
 func (mm *OvmsModelManager) writeConfig() error {
+	if err := mm.checkConfigEdits(); err != nil {
+		return err
+	}
+
 	// reset the stored list and ensure sufficient capacity
-	if cap(mm.modelRepositoryConfigList) < len(mm.loadedModelsMap) {
-		mm.modelRepositoryConfigList = make([]OvmsMultiModelConfigListEntry, len(mm.loadedModelsMap))
+	numEntries := len(mm.loadedModelsMap) + len(mm.externalEntries)
+	if cap(mm.modelRepositoryConfigList) < numEntries {
+		mm.modelRepositoryConfigList = make([]OvmsMultiModelConfigListEntry, numEntries)
 	} else {
-		mm.modelRepositoryConfigList = mm.modelRepositoryConfigList[:len(mm.loadedModelsMap)]
+		mm.modelRepositoryConfigList = mm.modelRepositoryConfigList[:numEntries]
 	}
 
 	listIndex := 0
@@ -504,30 +1248,122 @@ func (mm *OvmsModelManager) writeConfig() error {
 		mm.modelRepositoryConfigList[listIndex] = model
 		listIndex++
 	}
+	for _, entry := range mm.externalEntries {
+		mm.modelRepositoryConfigList[listIndex] = entry
+		listIndex++
+	}
+	// the entries come from maps, sort them so that the same config is
+	// always written with the same bytes
+	sort.Slice(mm.modelRepositoryConfigList, func(i, j int) bool {
+		return mm.modelRepositoryConfigList[i].Config.Name < mm.modelRepositoryConfigList[j].Config.Name
+	})
+
+	modelRepositoryConfig := OvmsMultiModelRepositoryConfig{mm.modelRepositoryConfigList}
+	if err := modelRepositoryConfig.validate(); err != nil {
+		return err
+	}
 
-	modelRepositoryConfigJSON, err := json.Marshal(OvmsMultiModelRepositoryConfig{mm.modelRepositoryConfigList})
+	modelRepositoryConfigJSON, err := json.Marshal(modelRepositoryConfig)
 	if err != nil {
 		return fmt.Errorf("Error marshalling config file: %w", err)
 	}
 
-	if err := os.WriteFile(mm.modelConfigFilename, modelRepositoryConfigJSON, mm.config.ModelConfigFilePerms); err != nil {
+	// record the model ids before the config refers to their names, so that
+	// they can be mapped back after a restart
+	modelIds := make(map[string]string)
+	for id, model := range mm.loadedModelsMap {
+		if model.Config.Name != id {
+			modelIds[model.Config.Name] = id
+		}
+	}
+	if len(modelIds) == 0 && !mm.config.SanitizeModelNames {
+		// a file left from models with served names would map the names of
+		// later models to the wrong ids
+		if err := os.Remove(modelNamesFilename(mm.modelConfigFilename)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("Error removing model names file: %w", err)
+		}
+	} else {
+		modelIdsJSON, err := json.Marshal(modelIds)
+		if err != nil {
+			return fmt.Errorf("Error marshalling model names file: %w", err)
+		}
+		if err = util.WriteFileAtomic(modelNamesFilename(mm.modelConfigFilename), modelIdsJSON, mm.config.ModelConfigFilePerms, mm.config.FsyncPolicy); err != nil {
+			return fmt.Errorf("Error writing model names file: %w", err)
+		}
+	}
+
+	// log the changes instead of the whole config, which may list many models
+	currentConfig := entriesByName(mm.modelRepositoryConfigList)
+	if diff := diffModelConfigs(mm.writtenConfig, currentConfig); !diff.isEmpty() {
+		mm.log.V(1).Info("Writing model config changes", "added", diff.Added, "removed", diff.Removed, "changed", diff.Changed)
+	}
+
+	if mm.configUnchanged(modelRepositoryConfigJSON) {
+		return errConfigUnchanged
+	}
+	if err := util.WriteFileAtomic(mm.modelConfigFilename, modelRepositoryConfigJSON, mm.config.ModelConfigFilePerms, mm.config.FsyncPolicy); err != nil {
 		return fmt.Errorf("Error writing config file: %w", err)
 	}
+	mm.writtenConfig = currentConfig
+	mm.writtenConfigHash = configHash(modelRepositoryConfigJSON)
+	mm.debug.setConfig(modelRepositoryConfig)
 
 	return nil
 }
 
+// errConfigUnchanged is returned by writeConfig when the config was not
+// written because OVMS already reloaded the same config
+var errConfigUnchanged = errors.New("Config is unchanged")
+
+// configUnchanged returns whether the reload of a config can be skipped with
+// SkipUnchangedReloads, because both the last successfully reloaded config
+// and the config file are identical to it
+func (mm *OvmsModelManager) configUnchanged(configBytes []byte) bool {
+	if !mm.config.SkipUnchangedReloads || mm.reloadedConfigHash == "" || configHash(configBytes) != mm.reloadedConfigHash {
+		return false
+	}
+	current, err := os.ReadFile(mm.modelConfigFilename)
+	return err == nil && bytes.Equal(current, configBytes)
+}
+
 // updateModelConfig updates the model configuration for OVMS
 //
 // An error is returned if the reload was not confirmed to be completed; the
 // error will be nil even if a model load fails.
 //
 // The returned config is saved to cachedModelConfigResponse.
-func (mm *OvmsModelManager) updateModelConfig() error {
-	ctx, cancel := context.WithTimeout(context.Background(), mm.config.ReloadTimeout)
+func (mm *OvmsModelManager) updateModelConfig() (err error) {
+	timeout := mm.config.ReloadTimeout
+	if !mm.runtimeReached {
+		timeout += mm.config.InitialReloadDeadline
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	if err := mm.writeConfig(); err != nil {
+	// any outcome other than OVMS confirming the reload counts as a failure
+	var reloaded, skipped bool
+	var reloadError string
+	defer func() {
+		if skipped {
+			return
+		}
+		if reloaded {
+			mm.reloadedConfigHash = mm.writtenConfigHash
+		} else {
+			mm.reloadedConfigHash = ""
+		}
+		mm.metrics.observeReload(reloaded)
+		if err != nil {
+			reloadError = err.Error()
+		}
+		mm.debug.observeReload(reloaded, reloadError)
+	}()
+
+	if err := mm.writeConfig(); errors.Is(err, errConfigUnchanged) {
+		mm.log.V(1).Info("Skipping the reload, the config is unchanged")
+		skipped = true
+		return nil
+	} else if err != nil {
 		return fmt.Errorf("Error updating model config when writing config file: %w", err)
 	}
 
@@ -540,11 +1376,12 @@ func (mm *OvmsModelManager) updateModelConfig() error {
 	// - If other HTTP error, check error message in JSON
 	//    If model load error: query the config status API
 	//    If other error, just return it?
-	resp, err := mm.client.Do(mm.reloadRequest.WithContext(ctx))
+	resp, err := mm.sendReload(ctx)
 	if err != nil {
 		return fmt.Errorf("Communication error reloading the config: %w", err)
 	}
 	defer resp.Body.Close()
+	mm.runtimeReached = true
 
 	// Read the body
 	// NOTE: if the body is not read, the connection cannot be re-used, so
@@ -556,13 +1393,14 @@ func (mm *OvmsModelManager) updateModelConfig() error {
 
 	// Successful config reload
 	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated {
-		var c OvmsConfigResponse
-		if err = json.Unmarshal(body, &c); err != nil {
+		c, err := parseOvmsConfigResponse(body, mm.config.ApiVersion)
+		if err != nil {
 			const msg string = "Error parsing /config/reload response"
 			mm.log.V(1).Error(err, msg, "responseBody", string(body))
 			return fmt.Errorf("%s: %w", msg, err)
 		}
 		mm.cachedModelConfigResponse = c
+		reloaded = true
 
 		return nil
 	}
@@ -577,8 +1415,38 @@ func (mm *OvmsModelManager) updateModelConfig() error {
 		return fmt.Errorf("%s: %w", msg, err)
 	}
 
-	mm.log.Error(fmt.Errorf("Error response when reloading the config: %s", errorResponse.Error), "Call to /v1/config/reload returned an error", "code", resp.StatusCode)
+	reloadError = fmt.Sprintf("Error response when reloading the config: %s", errorResponse.Error)
+	mm.reloadLog.Error(errors.New(reloadError), "Call to /v1/config/reload returned an error", "code", resp.StatusCode)
 
 	// we rely on the fact that getConfig updates cachedModelConfigResponse
 	return mm.getConfig(ctx)
 }
+
+// sendReload sends the config reload request to OVMS
+//
+// OVMS may not be listening yet when the adapter starts, so until OVMS has
+// responded to a reload, a refused connection is retried with exponential
+// backoff for up to InitialReloadDeadline.
+func (mm *OvmsModelManager) sendReload(ctx context.Context) (*http.Response, error) {
+	var deadline time.Time
+	if !mm.runtimeReached {
+		deadline = time.Now().Add(mm.config.InitialReloadDeadline)
+	}
+	backoff := mm.config.InitialReloadBackoff
+	for {
+		resp, err := mm.client.Do(mm.reloadRequest.WithContext(ctx))
+		if err == nil || !errors.Is(err, syscall.ECONNREFUSED) || time.Now().Add(backoff).After(deadline) {
+			return resp, err
+		}
+		mm.reloadLog.Info("OVMS is not listening yet, retrying the config reload", "backoff", backoff, "error", err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, err
+		}
+		if backoff *= 2; backoff > maxInitialReloadBackoff {
+			backoff = maxInitialReloadBackoff
+		}
+	}
+}
@@ -17,12 +17,27 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 )
 
 // Make it easy to mock OVMS HTTP responses
@@ -32,9 +47,26 @@ type MockOVMS struct {
 	reloadResponseCode int
 	configResponse     string
 	configResponseCode int
+	reloadCount        int32
+	// called by the reload endpoint before it responds, if set
+	reloadHook func()
+
+	// responses returned in order by the config endpoint before falling
+	// back to configResponse
+	configSequenceMutex sync.Mutex
+	configSequence      []string
 }
 
 func NewMockOVMS() *MockOVMS {
+	m := newUnstartedMockOVMS()
+	m.server.Start()
+
+	return m
+}
+
+// newUnstartedMockOVMS returns a mock that does not accept connections until
+// its server is started
+func newUnstartedMockOVMS() *MockOVMS {
 	m := &MockOVMS{
 		configResponse:     "{}",
 		configResponseCode: http.StatusOK,
@@ -45,6 +77,16 @@ func NewMockOVMS() *MockOVMS {
 	serverMux := http.NewServeMux()
 
 	serverMux.HandleFunc("/v1/config", func(w http.ResponseWriter, r *http.Request) {
+		m.configSequenceMutex.Lock()
+		if len(m.configSequence) > 0 {
+			response := m.configSequence[0]
+			m.configSequence = m.configSequence[1:]
+			m.configSequenceMutex.Unlock()
+			fmt.Fprintln(w, response)
+			return
+		}
+		m.configSequenceMutex.Unlock()
+
 		if m.configResponseCode == http.StatusOK {
 			fmt.Fprintln(w, m.configResponse)
 		} else {
@@ -53,6 +95,10 @@ func NewMockOVMS() *MockOVMS {
 	})
 
 	serverMux.HandleFunc("/v1/config/reload", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&m.reloadCount, 1)
+		if m.reloadHook != nil {
+			m.reloadHook()
+		}
 		if m.reloadResponseCode == http.StatusOK {
 			fmt.Fprintln(w, m.reloadResponse)
 		} else {
@@ -60,7 +106,7 @@ func NewMockOVMS() *MockOVMS {
 		}
 	})
 
-	m.server = httptest.NewServer(serverMux)
+	m.server = httptest.NewUnstartedServer(serverMux)
 
 	return m
 }
@@ -73,6 +119,10 @@ func (m *MockOVMS) GetAddress() string {
 	return m.server.URL
 }
 
+func (m *MockOVMS) getReloadCount() int32 {
+	return atomic.LoadInt32(&m.reloadCount)
+}
+
 func (m *MockOVMS) setMockReloadResponse(c interface{}, code int) error {
 	mockResponseBytes, err := json.Marshal(c)
 	if err != nil {
@@ -95,6 +145,23 @@ func (m *MockOVMS) setMockConfigResponse(c OvmsConfigResponse, code int) error {
 	return nil
 }
 
+func (m *MockOVMS) setMockConfigResponseSequence(cs ...OvmsConfigResponse) error {
+	sequence := make([]string, 0, len(cs))
+	for _, c := range cs {
+		mockResponseBytes, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		sequence = append(sequence, string(mockResponseBytes))
+	}
+
+	m.configSequenceMutex.Lock()
+	defer m.configSequenceMutex.Unlock()
+	m.configSequence = sequence
+
+	return nil
+}
+
 // shared instance of the mock server
 var mockOVMS *MockOVMS
 
@@ -118,7 +185,7 @@ func TestHappyPathLoadAndUnload(t *testing.T) {
 	}, http.StatusOK)
 
 	ctx := context.Background()
-	if err := mm.LoadModel(ctx, filepath.Join(testdataDir, "models", testOpenvinoModelId), testOpenvinoModelId); err != nil {
+	if err := mm.LoadModel(ctx, filepath.Join(testdataDir, "models", testOpenvinoModelId), testOpenvinoModelId, "", "", nil, nil); err != nil {
 		t.Errorf("LoadModel call failed: %v", err)
 	}
 
@@ -140,6 +207,118 @@ func TestHappyPathLoadAndUnload(t *testing.T) {
 	}
 }
 
+func TestLoadWithPluginConfig(t *testing.T) {
+	mm := setupModelManager(t)
+
+	mockOVMS.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{
+				{State: "AVAILABLE"},
+			},
+		},
+	}, http.StatusOK)
+
+	pluginConfig := map[string]string{"CPU_THROUGHPUT_STREAMS": "2", "NIREQ": "4"}
+	if err := mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", pluginConfig, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+
+	configBytes, err := os.ReadFile(testModelConfigFile)
+	if err != nil {
+		t.Fatalf("Unable to read config file: %v", err)
+	}
+	var config OvmsMultiModelRepositoryConfig
+	if err := json.Unmarshal(configBytes, &config); err != nil {
+		t.Fatalf("Unable to parse config file: %v", err)
+	}
+	if len(config.ModelConfigList) != 1 {
+		t.Fatalf("Expected one model in config but got '%s'", string(configBytes))
+	}
+	if !reflect.DeepEqual(config.ModelConfigList[0].Config.PluginConfig, pluginConfig) {
+		t.Errorf("Expected plugin_config %v in config but got '%s'", pluginConfig, string(configBytes))
+	}
+}
+
+func TestPluginConfigDefaults(t *testing.T) {
+	defaults, err := parsePluginConfigDefaults(`{"ONNX": {"NIREQ": "2", "CPU_THROUGHPUT_STREAMS": "1"}, "openvino_ir": {"NIREQ": "8"}}`)
+	if err != nil {
+		t.Fatalf("Unable to parse plugin config defaults: %v", err)
+	}
+	expectedDefaults := map[string]map[string]string{
+		"onnx":     {"NIREQ": "2", "CPU_THROUGHPUT_STREAMS": "1"},
+		"openvino": {"NIREQ": "8"},
+	}
+	if !reflect.DeepEqual(defaults, expectedDefaults) {
+		t.Errorf("Expected plugin config defaults %v, got %v", expectedDefaults, defaults)
+	}
+
+	for _, invalid := range []string{
+		`{"onnx": "NIREQ=2"}`,
+		`{"pytorch": {"NIREQ": "2"}}`,
+		`{"rt:ovms": {"NIREQ": "2"}}`,
+		`{"openvino": {"NIREQ": "2"}, "openvino_ir": {"NIREQ": "4"}}`,
+		`{"onnx": {"": "2"}}`,
+	} {
+		if _, err = parsePluginConfigDefaults(invalid); err == nil {
+			t.Errorf("Expected plugin config defaults %s to be invalid", invalid)
+		}
+	}
+
+	// the default of the type is applied by LoadModel of the adapter, and
+	// overridden by the plugin_config of the ModelKey
+	mm := setupModelManager(t)
+	s := &OvmsAdapterServer{
+		ModelManager: mm,
+		AdapterConfig: &AdapterConfiguration{
+			RootModelDir:         t.TempDir(),
+			ModelSizeMultiplier:  1,
+			PluginConfigDefaults: defaults,
+		},
+		Log: log,
+	}
+	mockOVMS.setMockReloadResponse(OvmsConfigResponse{
+		testOnnxModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{
+				{State: "AVAILABLE"},
+			},
+		},
+	}, http.StatusOK)
+
+	if _, err = s.LoadModel(context.Background(), &mmesh.LoadModelRequest{
+		ModelId:   testOnnxModelId,
+		ModelPath: testOnnxModelPath,
+		ModelType: "onnx",
+		ModelKey:  `{"model_type": {"name": "onnx"}, "plugin_config": {"NIREQ": "4"}}`,
+	}); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+
+	configBytes, err := os.ReadFile(testModelConfigFile)
+	if err != nil {
+		t.Fatalf("Unable to read config file: %v", err)
+	}
+	var config OvmsMultiModelRepositoryConfig
+	if err := json.Unmarshal(configBytes, &config); err != nil {
+		t.Fatalf("Unable to parse config file: %v", err)
+	}
+	expected := map[string]string{"NIREQ": "4", "CPU_THROUGHPUT_STREAMS": "1"}
+	found := false
+	for _, entry := range config.ModelConfigList {
+		if entry.Config.Name == testOnnxModelId {
+			found = true
+			if !reflect.DeepEqual(entry.Config.PluginConfig, expected) {
+				t.Errorf("Expected plugin_config %v in config but got '%s'", expected, string(configBytes))
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("Expected model '%s' in config but got '%s'", testOnnxModelId, string(configBytes))
+	}
+	if defaults["onnx"]["NIREQ"] != "2" {
+		t.Errorf("Expected the defaults not to be modified by the merge, got %v", defaults["onnx"])
+	}
+}
+
 func TestLoadFailure(t *testing.T) {
 	mm := setupModelManager(t)
 
@@ -161,7 +340,7 @@ func TestLoadFailure(t *testing.T) {
 
 	ctx := context.Background()
 
-	err := mm.LoadModel(ctx, filepath.Join(testdataDir, "models", testOpenvinoModelId), testOpenvinoModelId)
+	err := mm.LoadModel(ctx, filepath.Join(testdataDir, "models", testOpenvinoModelId), testOpenvinoModelId, "", "", nil, nil)
 
 	if err == nil {
 		t.Errorf("Model should have failed to load")
@@ -172,3 +351,1331 @@ func TestLoadFailure(t *testing.T) {
 	}
 
 }
+
+func TestUnloadGracePeriodBatchesReload(t *testing.T) {
+	// use a separate mock to count the reloads from this test only
+	m := NewMockOVMS()
+	defer m.Close()
+
+	mm, err := NewOvmsModelManager(m.GetAddress(), testModelConfigFile, log, ModelManagerConfig{
+		BatchWaitTimeMax:  5 * time.Second,
+		UnloadGracePeriod: 300 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	// each unload arrives within the grace period of the previous one
+	for _, id := range []string{"model-a", "model-b", "model-c"} {
+		if err = mm.UnloadModel(context.Background(), id); err != nil {
+			t.Fatalf("Unexpected error from UnloadModel: %v", err)
+		}
+		time.Sleep(150 * time.Millisecond)
+	}
+
+	// wait for the reload triggered after the grace period
+	deadline := time.Now().Add(3 * time.Second)
+	for m.getReloadCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	// and make sure that no other reload follows
+	time.Sleep(500 * time.Millisecond)
+
+	if count := m.getReloadCount(); count != 1 {
+		t.Errorf("Expected 1 reload for the batch of unloads, but got %d", count)
+	}
+}
+
+func TestParseConfigResponseVersions(t *testing.T) {
+	expected := OvmsConfigResponse{
+		"mnist": OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{
+				{
+					Version: "3",
+					State:   "LOADING",
+					Status: OvmsModelStatus{
+						ErrorCode:    "UNKNOWN",
+						ErrorMessage: "Could not load model",
+					},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		apiVersion string
+		body       string
+	}{
+		{
+			apiVersion: OvmsApiVersionV1,
+			body: `{"mnist": {"model_version_status": [
+				{"version": "3", "state": "LOADING", "status": {"error_code": "UNKNOWN", "error_message": "Could not load model"}}
+			]}}`,
+		},
+		{
+			apiVersion: OvmsApiVersionV2,
+			body: `{"mnist": {"modelVersionStatus": [
+				{"version": "3", "state": "LOADING", "status": {"errorCode": "UNKNOWN", "errorMessage": "Could not load model"}}
+			]}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.apiVersion, func(t *testing.T) {
+			c, err := parseOvmsConfigResponse([]byte(tc.body), tc.apiVersion)
+			if err != nil {
+				t.Fatalf("Unexpected error parsing config response: %v", err)
+			}
+			if !reflect.DeepEqual(c, expected) {
+				t.Errorf("Expected config response %v but got %v", expected, c)
+			}
+		})
+	}
+
+	if _, err := parseOvmsConfigResponse([]byte("{}"), "v0"); err == nil {
+		t.Error("Expected an error for an unsupported API version")
+	}
+}
+
+func TestDiffModelConfigs(t *testing.T) {
+	entry := func(name, basePath string) OvmsMultiModelConfigListEntry {
+		return OvmsMultiModelConfigListEntry{Config: OvmsMultiModelModelConfig{Name: name, BasePath: basePath}}
+	}
+	previous := entriesByName([]OvmsMultiModelConfigListEntry{
+		entry("kept", "/models/kept"),
+		entry("removed", "/models/removed"),
+		entry("changed", "/models/changed"),
+	})
+	current := entriesByName([]OvmsMultiModelConfigListEntry{
+		entry("kept", "/models/kept"),
+		entry("changed", "/models/changed-v2"),
+		entry("added", "/models/added"),
+	})
+
+	diff := diffModelConfigs(previous, current)
+	expected := modelConfigDiff{
+		Added:   []string{"added"},
+		Removed: []string{"removed"},
+		Changed: []string{"changed"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected config diff %+v but got %+v", expected, diff)
+	}
+
+	if diff := diffModelConfigs(current, current); !diff.isEmpty() {
+		t.Errorf("Expected no changes between identical configs but got %+v", diff)
+	}
+}
+
+func TestLoadWithApiVersionV2(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+
+	mm, err := NewOvmsModelManager(m.GetAddress(), testModelConfigFile, log, ModelManagerConfig{ApiVersion: OvmsApiVersionV2})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	m.setMockReloadResponse(map[string]interface{}{
+		testOpenvinoModelId: map[string]interface{}{
+			"modelVersionStatus": []map[string]interface{}{
+				{"version": "1", "state": "AVAILABLE"},
+			},
+		},
+	}, http.StatusOK)
+
+	if err := mm.LoadModel(context.Background(), filepath.Join(testdataDir, "models", testOpenvinoModelId), testOpenvinoModelId, "", "", nil, nil); err != nil {
+		t.Errorf("LoadModel call failed: %v", err)
+	}
+}
+
+func TestReloadFailureMetrics(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+
+	mm, err := NewOvmsModelManager(m.GetAddress(), testModelConfigFile, log, ModelManagerConfig{})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	m.setMockReloadResponse(OvmsConfigErrorResponse{Error: "Reloading models versions failed"}, http.StatusBadRequest)
+	m.setMockConfigResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{
+				{
+					State: "LOADING",
+					Status: OvmsModelStatus{
+						ErrorCode:    "LOADING_FAILED",
+						ErrorMessage: "Test model load failure",
+					},
+				},
+			},
+		},
+	}, http.StatusOK)
+
+	if err = mm.LoadModel(context.Background(), filepath.Join(testdataDir, "models", testOpenvinoModelId), testOpenvinoModelId, "", "", nil, nil); err == nil {
+		t.Fatal("Model should have failed to load")
+	}
+
+	rec := httptest.NewRecorder()
+	mm.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, expected := range []string{
+		"ovms_adapter_reload_total 1\n",
+		"ovms_adapter_reload_failures_total 1\n",
+		"ovms_adapter_last_reload_success 0\n",
+		"ovms_adapter_model_load_failures_total{error_code=\"LOADING_FAILED\"} 1\n",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", expected, body)
+		}
+	}
+
+	// a successful reload updates the last outcome
+	m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{
+				{State: "AVAILABLE"},
+			},
+		},
+	}, http.StatusOK)
+
+	if err = mm.LoadModel(context.Background(), filepath.Join(testdataDir, "models", testOpenvinoModelId), testOpenvinoModelId, "", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	mm.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body = rec.Body.String()
+
+	for _, expected := range []string{
+		"ovms_adapter_reload_total 2\n",
+		"ovms_adapter_reload_failures_total 1\n",
+		"ovms_adapter_last_reload_success 1\n",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", expected, body)
+		}
+	}
+}
+
+func TestModelLabelsMetrics(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+
+	mm, err := NewOvmsModelManager(m.GetAddress(), filepath.Join(t.TempDir(), "model_config_list.json"), log, ModelManagerConfig{
+		MetricsModelLabels: []string{"team", "family"},
+	})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{
+				{State: "AVAILABLE"},
+			},
+		},
+		testOnnxModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{
+				{
+					State: "LOADING",
+					Status: OvmsModelStatus{
+						ErrorCode:    "LOADING_FAILED",
+						ErrorMessage: "Test model load failure",
+					},
+				},
+			},
+		},
+	}, http.StatusOK)
+
+	labels := map[string]string{"team": "fraud", "family": "resnet", "owner": "alice"}
+	if err = mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, labels); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+	if err = mm.LoadModel(context.Background(), testOnnxModelPath, testOnnxModelId, "", "", nil, map[string]string{"team": "search"}); err == nil {
+		t.Fatal("Model should have failed to load")
+	}
+
+	// the metrics of the loaded models are updated before the next batch is
+	// gathered, which has started once another request completes
+	if err = mm.UnloadModel(context.Background(), "unknown-model"); err != nil {
+		t.Fatalf("UnloadModel call failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mm.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, expected := range []string{
+		fmt.Sprintf("ovms_adapter_model_loaded{model_id=\"%s\",family=\"resnet\",team=\"fraud\"} 1\n", testOpenvinoModelId),
+		"ovms_adapter_model_load_failures_total{error_code=\"LOADING_FAILED\",team=\"search\"} 1\n",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", expected, body)
+		}
+	}
+	if strings.Contains(body, "owner") {
+		t.Errorf("Expected the owner label to be dropped, got:\n%s", body)
+	}
+	if strings.Contains(body, fmt.Sprintf("ovms_adapter_model_loaded{model_id=\"%s\"", testOnnxModelId)) {
+		t.Errorf("Expected the model that failed to load not to be loaded, got:\n%s", body)
+	}
+}
+
+func TestLoadedModelTypesMetrics(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+
+	mm, err := NewOvmsModelManager(m.GetAddress(), filepath.Join(t.TempDir(), "model_config_list.json"), log, ModelManagerConfig{})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+		testOnnxModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+		"onnx-auto": OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+	}, http.StatusOK)
+
+	assertLoadedModelTypes := func(expected map[string]int) {
+		t.Helper()
+		// the metrics of the loaded models are updated before the next batch
+		// is gathered, which starts shortly after the last request completes
+		var body string
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			rec := httptest.NewRecorder()
+			mm.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			body = rec.Body.String()
+			missing := false
+			for modelType, count := range expected {
+				if !strings.Contains(body, fmt.Sprintf("ovms_adapter_loaded_models{model_type=\"%s\"} %d\n", modelType, count)) {
+					missing = true
+				}
+			}
+			if !missing {
+				return
+			}
+		}
+		t.Errorf("Expected metrics to count the loaded models by type as %v, got:\n%s", expected, body)
+	}
+
+	if err = mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "openvino", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+	if err = mm.LoadModel(context.Background(), testOnnxModelPath, testOnnxModelId, "onnx", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+	if err = mm.LoadModel(context.Background(), testOnnxModelPath, "onnx-auto", "", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+	assertLoadedModelTypes(map[string]int{"openvino": 1, "onnx": 1, "tensorflow": 0, unknownModelType: 1})
+
+	if err = mm.UnloadModel(context.Background(), testOnnxModelId); err != nil {
+		t.Fatalf("UnloadModel call failed: %v", err)
+	}
+	if err = mm.UnloadModel(context.Background(), "onnx-auto"); err != nil {
+		t.Fatalf("UnloadModel call failed: %v", err)
+	}
+	assertLoadedModelTypes(map[string]int{"openvino": 1, "onnx": 0, unknownModelType: 0})
+}
+
+func TestPruneMissingModelsOnStartup(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	staleConfig := OvmsMultiModelRepositoryConfig{
+		ModelConfigList: []OvmsMultiModelConfigListEntry{
+			{Config: OvmsMultiModelModelConfig{Name: testOpenvinoModelId, BasePath: testOpenvinoModelPath}},
+			{Config: OvmsMultiModelModelConfig{Name: "missing-model", BasePath: filepath.Join(testdataDir, "models", "missing-model")}},
+		},
+	}
+	configBytes, err := json.Marshal(staleConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(configFile, configBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{PruneMissingModels: true}); err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	prunedBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Unable to read config file: %v", err)
+	}
+	var prunedConfig OvmsMultiModelRepositoryConfig
+	if err = json.Unmarshal(prunedBytes, &prunedConfig); err != nil {
+		t.Fatalf("Unable to parse config file: %v", err)
+	}
+
+	if len(prunedConfig.ModelConfigList) != 1 || prunedConfig.ModelConfigList[0].Config.Name != testOpenvinoModelId {
+		t.Errorf("Expected only '%s' to remain in the config, got: %s", testOpenvinoModelId, string(prunedBytes))
+	}
+}
+
+func TestReconcileOnBoot(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+
+	available := OvmsModelStatusResponse{
+		ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+	}
+	if err := m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: available,
+		testOnnxModelId:     available,
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	// the config left by the previous run of the adapter
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	manifestedConfig := OvmsMultiModelRepositoryConfig{
+		ModelConfigList: []OvmsMultiModelConfigListEntry{
+			{Config: OvmsMultiModelModelConfig{Name: testOpenvinoModelId, BasePath: testOpenvinoModelPath}},
+			{Config: OvmsMultiModelModelConfig{Name: testOnnxModelId, BasePath: testOnnxModelPath}},
+		},
+	}
+	configBytes, err := json.Marshal(manifestedConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(configFile, configBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{ReconcileOnBoot: true})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	// the reconcile reloads OVMS once without any request
+	deadline := time.Now().Add(5 * time.Second)
+	for m.getReloadCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if count := m.getReloadCount(); count != 1 {
+		t.Fatalf("Expected 1 reload on boot, got %d", count)
+	}
+
+	// the models are still registered after the reconcile, a load of one of
+	// them reloads the config with both
+	if err = mm.LoadModel(context.Background(), testOnnxModelPath, testOnnxModelId, "", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+	reconciledBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Unable to read config file: %v", err)
+	}
+	var reconciledConfig OvmsMultiModelRepositoryConfig
+	if err = json.Unmarshal(reconciledBytes, &reconciledConfig); err != nil {
+		t.Fatalf("Unable to parse config file: %v", err)
+	}
+	models := map[string]string{}
+	for _, entry := range reconciledConfig.ModelConfigList {
+		models[entry.Config.Name] = entry.Config.BasePath
+	}
+	expected := map[string]string{testOpenvinoModelId: testOpenvinoModelPath, testOnnxModelId: testOnnxModelPath}
+	if !reflect.DeepEqual(expected, models) {
+		t.Errorf("Expected both models in the config after the reconcile, got: %s", string(reconciledBytes))
+	}
+}
+
+func TestReconcileOnBootChecksModelsConcurrently(t *testing.T) {
+	const concurrency = 3
+	m := NewMockOVMS()
+	defer m.Close()
+
+	// the config left by the previous run of the adapter, with one model
+	// whose directory is gone and one whose directory is empty
+	modelsDir := t.TempDir()
+	var manifestedConfig OvmsMultiModelRepositoryConfig
+	reloadResponse := OvmsConfigResponse{}
+	expected := map[string]string{}
+	for i := 0; i < 6; i++ {
+		id := fmt.Sprintf("model-%d", i)
+		basePath := filepath.Join(modelsDir, id)
+		if err := os.MkdirAll(filepath.Join(basePath, "1"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		manifestedConfig.ModelConfigList = append(manifestedConfig.ModelConfigList,
+			OvmsMultiModelConfigListEntry{Config: OvmsMultiModelModelConfig{Name: id, BasePath: basePath}})
+		reloadResponse[id] = OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		}
+		expected[id] = basePath
+	}
+	manifestedConfig.ModelConfigList = append(manifestedConfig.ModelConfigList,
+		OvmsMultiModelConfigListEntry{Config: OvmsMultiModelModelConfig{Name: "model-gone", BasePath: filepath.Join(modelsDir, "model-gone")}})
+	emptyPath := filepath.Join(modelsDir, "model-empty")
+	if err := os.Mkdir(emptyPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifestedConfig.ModelConfigList = append(manifestedConfig.ModelConfigList,
+		OvmsMultiModelConfigListEntry{Config: OvmsMultiModelModelConfig{Name: "model-empty", BasePath: emptyPath}})
+	reloadResponse["model-empty"] = OvmsModelStatusResponse{
+		ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+	}
+	expected["model-empty"] = emptyPath
+
+	if err := m.setMockReloadResponse(reloadResponse, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	configBytes, err := json.Marshal(manifestedConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(configFile, configBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// each check waits for the others up to the concurrency
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	defaultCheckModelDir := checkModelDir
+	defer func() { checkModelDir = defaultCheckModelDir }()
+	checkModelDir = func(basePath string) error {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			reached := maxInFlight >= concurrency
+			mu.Unlock()
+			if reached {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return defaultCheckModelDir(basePath)
+	}
+
+	// a model loaded once the manager is running
+	newPath := filepath.Join(modelsDir, "model-new")
+	if err = os.MkdirAll(filepath.Join(newPath, "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	reloadResponse["model-new"] = OvmsModelStatusResponse{
+		ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+	}
+	if err = m.setMockReloadResponse(reloadResponse, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{
+		ReconcileOnBoot:      true,
+		ReconcileConcurrency: concurrency,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	// requests are only handled once the reconcile is done, so the load is
+	// the second reload if the reconcile reloaded once
+	if err = mm.LoadModel(context.Background(), newPath, "model-new", "", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+	if count := m.getReloadCount(); count != 2 {
+		t.Fatalf("Expected 1 reload on boot and 1 for the load, got %d", count)
+	}
+	mu.Lock()
+	if maxInFlight != concurrency {
+		t.Errorf("Expected the models to be checked %d at a time, got %d", concurrency, maxInFlight)
+	}
+	mu.Unlock()
+
+	// the model whose directory is gone is removed before the reload, the
+	// model with an empty directory is kept
+	expected["model-new"] = newPath
+	models, writtenBytes := readConfiguredModels(t, configFile)
+	if !reflect.DeepEqual(expected, models) {
+		t.Errorf("Expected the models %v in the config, got: %s", expected, string(writtenBytes))
+	}
+}
+
+func TestInvalidModelConfigKeepsPreviousConfig(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+	if err := m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	ctx := context.Background()
+	if err = mm.LoadModel(ctx, testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+	liveConfig, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Unable to read config file: %v", err)
+	}
+
+	// a load with a bad entry is rejected without replacing the config
+	err = mm.LoadModel(ctx, testOnnxModelPath, testOnnxModelId, "", "", map[string]string{"": "4"}, nil)
+	if status.Code(errors.Unwrap(err)) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for the bad entry, got: %v", err)
+	}
+	if configBytes, _ := os.ReadFile(configFile); string(configBytes) != string(liveConfig) {
+		t.Errorf("Expected the previous config to stay live, got: %s", string(configBytes))
+	}
+
+	if count := m.getReloadCount(); count != 1 {
+		t.Errorf("Expected no reload for the bad entry, got %d reloads", count)
+	}
+
+	// writing a config with a bad entry keeps the previous file
+	writer := &OvmsModelManager{
+		config:              mm.config,
+		modelConfigFilename: configFile,
+		loadedModelsMap: map[string]OvmsMultiModelConfigListEntry{
+			testOpenvinoModelId: {Config: OvmsMultiModelModelConfig{Name: testOpenvinoModelId, BasePath: testOpenvinoModelPath}},
+			"":                  {Config: OvmsMultiModelModelConfig{BasePath: testOnnxModelPath}},
+		},
+	}
+	if err = writer.writeConfig(); !errors.Is(err, errInvalidModelConfig) {
+		t.Errorf("Expected an invalid model config error, got: %v", err)
+	}
+	if configBytes, _ := os.ReadFile(configFile); string(configBytes) != string(liveConfig) {
+		t.Errorf("Expected the previous config to stay live, got: %s", string(configBytes))
+	}
+}
+
+func TestSkipUnchangedReloads(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+	if err := m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, skip := range []bool{false, true} {
+		configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+		mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{SkipUnchangedReloads: skip})
+		if err != nil {
+			t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+		}
+
+		ctx := context.Background()
+		if err = mm.LoadModel(ctx, testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); err != nil {
+			t.Fatalf("LoadModel call failed: %v", err)
+		}
+		reloads := m.getReloadCount()
+
+		// loading the same model again leaves the config as it is
+		if err = mm.LoadModel(ctx, testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); err != nil {
+			t.Fatalf("LoadModel call of the loaded model failed: %v", err)
+		}
+		expected := reloads + 1
+		if skip {
+			expected = reloads
+		}
+		if count := m.getReloadCount(); count != expected {
+			t.Errorf("Expected %d reloads with SkipUnchangedReloads=%v, got %d", expected, skip, count)
+		}
+	}
+}
+
+func TestFileVisibilityGrace(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+	if err := m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	// the files placed by the adapter only become visible in the container
+	// of OVMS some time later, which OVMS checks when it is reloaded
+	ovmsView := filepath.Join(t.TempDir(), "visible")
+	var visibleAtReload atomic.Value
+	m.reloadHook = func() {
+		_, err := os.Stat(ovmsView)
+		visibleAtReload.Store(err == nil)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{FileVisibilityGrace: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		if err := os.WriteFile(ovmsView, nil, 0644); err != nil {
+			t.Error(err)
+		}
+	}()
+	ctx := context.Background()
+	if err = mm.LoadModel(ctx, testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+	if count := m.getReloadCount(); count != 1 {
+		t.Errorf("Expected a single reload, got %d reloads", count)
+	}
+	if visible, _ := visibleAtReload.Load().(bool); !visible {
+		t.Error("Expected the files to be visible to OVMS when it is reloaded")
+	}
+
+	// a load cancelled during the grace fails without a reload
+	cancelCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err = mm.LoadModel(cancelCtx, testOnnxModelPath, testOnnxModelId, "", "", nil, nil)
+	if status.Code(errors.Unwrap(err)) != codes.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded for the load cancelled during the grace, got: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if count := m.getReloadCount(); count != 1 {
+		t.Errorf("Expected no reload for the cancelled load, got %d reloads", count)
+	}
+}
+
+func TestSanitizeModelNames(t *testing.T) {
+	const modelId = "my model/v1:latest"
+	name := sanitizeModelName(modelId)
+	if !regexp.MustCompile(`^[A-Za-z0-9_.-]+$`).MatchString(name) {
+		t.Fatalf("Expected a name without disallowed characters, got '%s'", name)
+	}
+	if other := sanitizeModelName("my model/v1_latest"); other == name {
+		t.Fatalf("Expected different model ids to get different names, both got '%s'", name)
+	}
+	if sanitizeModelName(testOpenvinoModelId) != testOpenvinoModelId {
+		t.Errorf("Expected a valid model id to be used as the name, got '%s'", sanitizeModelName(testOpenvinoModelId))
+	}
+
+	m := NewMockOVMS()
+	defer m.Close()
+	if err := m.setMockReloadResponse(OvmsConfigResponse{
+		name: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mmConfig := ModelManagerConfig{SanitizeModelNames: true}
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, mmConfig)
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+	if err = mm.LoadModel(context.Background(), testOpenvinoModelPath, modelId, "", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+
+	configBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Unable to read config file: %v", err)
+	}
+	var config OvmsMultiModelRepositoryConfig
+	if err = json.Unmarshal(configBytes, &config); err != nil {
+		t.Fatalf("Unable to parse config file: %v", err)
+	}
+	if len(config.ModelConfigList) != 1 || config.ModelConfigList[0].Config.Name != name {
+		t.Fatalf("Expected the model to be named '%s' in the config, got: %s", name, string(configBytes))
+	}
+
+	// the model id is mapped back from the name after a restart
+	restarted, err := NewOvmsModelManager(m.GetAddress(), configFile, log, mmConfig)
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+	if entry, ok := restarted.loadedModelsMap[modelId]; !ok || entry.Config.Name != name {
+		t.Fatalf("Expected model '%s' named '%s' after a restart, got: %v", modelId, name, restarted.loadedModelsMap)
+	}
+
+	if err = restarted.UnloadModel(context.Background(), modelId); err != nil {
+		t.Fatalf("UnloadModel call failed: %v", err)
+	}
+	// the config without the unloaded model is written by the next reload,
+	// which is triggered by a load of another model
+	if err = restarted.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); status.Code(errors.Unwrap(err)) != codes.Internal {
+		t.Fatalf("Expected the load to fail without a status for the model, got: %v", err)
+	}
+	if configBytes, err = os.ReadFile(configFile); err != nil {
+		t.Fatalf("Unable to read config file: %v", err)
+	}
+	if strings.Contains(string(configBytes), name) {
+		t.Errorf("Expected the unloaded model to be removed from the config, got: %s", string(configBytes))
+	}
+}
+
+func TestServedModelName(t *testing.T) {
+	const modelId = "mnist__isvc-6b2c6c5a4b"
+	const servedName = "mnist"
+
+	m := NewMockOVMS()
+	defer m.Close()
+	if err := m.setMockReloadResponse(OvmsConfigResponse{
+		servedName: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+		},
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{UnloadGracePeriod: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+	// the status of the model is found by its served name
+	if err = mm.LoadModel(context.Background(), testOpenvinoModelPath, modelId, "", servedName, nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+
+	configBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Unable to read config file: %v", err)
+	}
+	var config OvmsMultiModelRepositoryConfig
+	if err = json.Unmarshal(configBytes, &config); err != nil {
+		t.Fatalf("Unable to parse config file: %v", err)
+	}
+	if len(config.ModelConfigList) != 1 || config.ModelConfigList[0].Config.Name != servedName {
+		t.Fatalf("Expected the model to be named '%s' in the config, got: %s", servedName, string(configBytes))
+	}
+	if modelIds := readModelNames(modelNamesFilename(configFile), log); modelIds[servedName] != modelId {
+		t.Fatalf("Expected the served name to be mapped to the model id, got: %v", modelIds)
+	}
+
+	reloads := m.getReloadCount()
+	if err = mm.UnloadModel(context.Background(), modelId); err != nil {
+		t.Fatalf("UnloadModel call failed: %v", err)
+	}
+	// the config is written by the reload after the unload
+	deadline := time.Now().Add(5 * time.Second)
+	for m.getReloadCount() == reloads && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if configBytes, err = os.ReadFile(configFile); err != nil {
+		t.Fatalf("Unable to read config file: %v", err)
+	}
+	if strings.Contains(string(configBytes), servedName) {
+		t.Errorf("Expected the unloaded model to be removed from the config, got: %s", string(configBytes))
+	}
+	if _, err = os.Stat(modelNamesFilename(configFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected the model names file to be removed without served names, got: %v", err)
+	}
+}
+
+func TestServedModelNameCollision(t *testing.T) {
+	const servedName = "mnist"
+
+	for _, tt := range []struct {
+		nameCollisionCode string
+		expectedCode      codes.Code
+	}{
+		{"", codes.InvalidArgument},
+		{NameCollisionAlreadyExists, codes.AlreadyExists},
+	} {
+		t.Run(tt.expectedCode.String(), func(t *testing.T) {
+			m := NewMockOVMS()
+			defer m.Close()
+			if err := m.setMockReloadResponse(OvmsConfigResponse{
+				servedName: OvmsModelStatusResponse{
+					ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+				},
+			}, http.StatusOK); err != nil {
+				t.Fatal(err)
+			}
+
+			configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+			mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{NameCollisionCode: tt.nameCollisionCode})
+			if err != nil {
+				t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+			}
+			s := &OvmsAdapterServer{
+				ModelManager:  mm,
+				AdapterConfig: &AdapterConfiguration{RootModelDir: t.TempDir()},
+				Log:           log,
+			}
+			loadModel := func(modelId string) error {
+				_, err := s.LoadModel(context.Background(), &mmesh.LoadModelRequest{
+					ModelId:   modelId,
+					ModelPath: testOpenvinoModelPath,
+					ModelType: "openvino",
+					ModelKey:  `{"model_type": {"name": "openvino"}, "served_model_name": "` + servedName + `"}`,
+				})
+				return err
+			}
+
+			if err = loadModel("mnist__isvc-1"); err != nil {
+				t.Fatalf("LoadModel call failed: %v", err)
+			}
+			err = loadModel("mnist__isvc-2")
+			if code := status.Code(err); code != tt.expectedCode {
+				t.Fatalf("Expected the second model with the served name to fail with %v, got: %v", tt.expectedCode, err)
+			}
+
+			// the first model keeps the name in the config
+			models, configBytes := readConfiguredModels(t, configFile)
+			if len(models) != 1 {
+				t.Fatalf("Expected only the first model in the config, got: %s", string(configBytes))
+			}
+			if modelIds := readModelNames(modelNamesFilename(configFile), log); modelIds[servedName] != "mnist__isvc-1" {
+				t.Errorf("Expected the served name to stay mapped to the first model, got: %v", modelIds)
+			}
+		})
+	}
+}
+
+func TestLoadBatchReloadsOnceOnCommit(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+	available := OvmsModelStatusResponse{
+		ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+	}
+	modelIds := []string{"model-a", "model-b", "model-c"}
+	reloadResponse := OvmsConfigResponse{"model-other": available}
+	for _, id := range modelIds {
+		reloadResponse[id] = available
+	}
+	if err := m.setMockReloadResponse(reloadResponse, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{
+		BatchWaitTimeMin: 10 * time.Millisecond,
+		BatchWaitTimeMax: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	rootDir := t.TempDir()
+	for _, id := range append(modelIds, "model-other") {
+		if err = os.MkdirAll(filepath.Join(rootDir, id, "1"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	batch := mm.NewLoadBatch()
+	for i, id := range modelIds {
+		if err = batch.Add(filepath.Join(rootDir, id), id, "onnx", "", nil, nil); err != nil {
+			t.Fatalf("Unexpected error from Add: %v", err)
+		}
+		// a reload for another model while the batch is partial does not
+		// load any model of the batch
+		if i == 0 {
+			if err = mm.LoadModel(context.Background(), filepath.Join(rootDir, "model-other"), "model-other", "onnx", "", nil, nil); err != nil {
+				t.Fatalf("LoadModel call failed: %v", err)
+			}
+			if models, writtenBytes := readConfiguredModels(t, configFile); len(models) != 1 {
+				t.Errorf("Expected only model-other in the config, got: %s", string(writtenBytes))
+			}
+		}
+	}
+
+	if err = batch.Commit(context.Background()); err != nil {
+		t.Fatalf("Commit call failed: %v", err)
+	}
+	if count := m.getReloadCount(); count != 2 {
+		t.Errorf("Expected 1 reload for the batch, but got %d", count-1)
+	}
+	models, writtenBytes := readConfiguredModels(t, configFile)
+	if len(models) != len(modelIds)+1 {
+		t.Errorf("Expected the models %v in the config, got: %s", modelIds, string(writtenBytes))
+	}
+
+	// the batch is empty after the commit
+	if err = batch.Commit(context.Background()); err != nil {
+		t.Errorf("Unexpected error committing an empty batch: %v", err)
+	}
+	if count := m.getReloadCount(); count != 2 {
+		t.Errorf("Expected no reload for an empty batch, but got %d", count-2)
+	}
+
+	// a model that fails to load fails the commit, the others are loaded
+	// with the same reload
+	if err = batch.Add(filepath.Join(rootDir, "model-a"), "model-a", "onnx", "", nil, nil); err != nil {
+		t.Fatalf("Unexpected error from Add: %v", err)
+	}
+	if err = batch.Add(filepath.Join(rootDir, "model-b"), "model-d", "onnx", "", nil, nil); err != nil {
+		t.Fatalf("Unexpected error from Add: %v", err)
+	}
+	err = batch.Commit(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "model-d") {
+		t.Errorf("Expected the commit to fail for model-d, got: %v", err)
+	}
+	if count := m.getReloadCount(); count != 3 {
+		t.Errorf("Expected 1 reload for the second batch, but got %d", count-2)
+	}
+
+	// a model path that does not exist is rejected by Add
+	if err = batch.Add(filepath.Join(rootDir, "missing"), "missing", "onnx", "", nil, nil); err == nil {
+		t.Errorf("Expected an error adding a missing model path")
+	}
+}
+
+func TestLoadIgnoresCollateralStateChanges(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+	available := OvmsModelStatusResponse{
+		ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+	}
+	if err := m.setMockReloadResponse(OvmsConfigResponse{testOpenvinoModelId: available}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	var logMutex sync.Mutex
+	var logLines []string
+	capturingLog := funcr.New(func(prefix, args string) {
+		logMutex.Lock()
+		defer logMutex.Unlock()
+		logLines = append(logLines, args)
+	}, funcr.Options{})
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, capturingLog, ModelManagerConfig{})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+	ctx := context.Background()
+	if err = mm.LoadModel(ctx, testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+
+	// the reload for the second model unloads the first one
+	if err = m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{{State: "END"}},
+		},
+		testOnnxModelId: available,
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+	if err = mm.LoadModel(ctx, testOnnxModelPath, testOnnxModelId, "", "", nil, nil); err != nil {
+		t.Fatalf("Expected the load of the requested model to succeed, got: %v", err)
+	}
+
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	var logged bool
+	for _, line := range logLines {
+		if strings.Contains(line, "Model that was not requested changed state") && strings.Contains(line, testOpenvinoModelId) &&
+			strings.Contains(line, `"previous_state"="AVAILABLE"`) && strings.Contains(line, `"state"="END"`) {
+			logged = true
+		}
+	}
+	if !logged {
+		t.Errorf("Expected the state change of model %s to be logged, got: %v", testOpenvinoModelId, logLines)
+	}
+}
+
+func modelStateResponse(state string, status OvmsModelStatus) OvmsConfigResponse {
+	return OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{
+				{State: state, Status: status},
+			},
+		},
+	}
+}
+
+func TestLoadWaitsForTransitionalStates(t *testing.T) {
+	okStatus := OvmsModelStatus{ErrorCode: "OK", ErrorMessage: "OK"}
+
+	tests := []struct {
+		name          string
+		sequence      []OvmsConfigResponse
+		expectedError string
+	}{
+		{
+			name: "LOADING to AVAILABLE",
+			sequence: []OvmsConfigResponse{
+				modelStateResponse("LOADING", okStatus),
+				modelStateResponse("AVAILABLE", okStatus),
+			},
+		},
+		{
+			name: "UNLOADING to LOADING to AVAILABLE",
+			sequence: []OvmsConfigResponse{
+				modelStateResponse("UNLOADING", okStatus),
+				modelStateResponse("END", okStatus),
+				modelStateResponse("LOADING", okStatus),
+				modelStateResponse("AVAILABLE", okStatus),
+			},
+		},
+		{
+			name: "LOADING to END with error",
+			sequence: []OvmsConfigResponse{
+				modelStateResponse("LOADING", okStatus),
+				modelStateResponse("END", OvmsModelStatus{ErrorCode: "UNKNOWN", ErrorMessage: "Could not load model"}),
+			},
+			expectedError: "Could not load model",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMockOVMS()
+			defer m.Close()
+
+			mm, err := NewOvmsModelManager(m.GetAddress(), testModelConfigFile, log, ModelManagerConfig{
+				ModelStatePollInterval: 10 * time.Millisecond,
+			})
+			if err != nil {
+				t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+			}
+
+			// the reload returns while the model is still loading
+			m.setMockReloadResponse(modelStateResponse("LOADING", okStatus), http.StatusOK)
+			m.setMockConfigResponseSequence(tt.sequence...)
+
+			err = mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil)
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Errorf("LoadModel call failed: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("Expected LoadModel to fail with '%s', got: %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestLoadWaitsForMissingModels(t *testing.T) {
+	okStatus := OvmsModelStatus{ErrorCode: "OK", ErrorMessage: "OK"}
+
+	tests := []struct {
+		name          string
+		wait          bool
+		expectedError string
+	}{
+		{
+			name:          "fails by default",
+			expectedError: "no status entry found",
+		},
+		{
+			name: "waits until the model is available",
+			wait: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMockOVMS()
+			defer m.Close()
+
+			mm, err := NewOvmsModelManager(m.GetAddress(), filepath.Join(t.TempDir(), "model_config_list.json"), log, ModelManagerConfig{
+				ModelStatePollInterval: 10 * time.Millisecond,
+				WaitForMissingModels:   tt.wait,
+			})
+			if err != nil {
+				t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+			}
+
+			// the reload succeeds before OVMS reports a status for the model
+			m.setMockReloadResponse(OvmsConfigResponse{}, http.StatusOK)
+			m.setMockConfigResponseSequence(
+				OvmsConfigResponse{},
+				modelStateResponse("AVAILABLE", okStatus),
+			)
+
+			err = mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil)
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Errorf("LoadModel call failed: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("Expected LoadModel to fail with '%s', got: %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestLoadTimesOutInTransitionalState(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+
+	mm, err := NewOvmsModelManager(m.GetAddress(), testModelConfigFile, log, ModelManagerConfig{
+		ModelStateTimeout:      100 * time.Millisecond,
+		ModelStatePollInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	m.setMockReloadResponse(modelStateResponse("LOADING", OvmsModelStatus{}), http.StatusOK)
+	m.setMockConfigResponse(modelStateResponse("LOADING", OvmsModelStatus{}), http.StatusOK)
+
+	err = mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "Timed out waiting for OVMS to load the model") {
+		t.Errorf("Expected LoadModel to time out, got: %v", err)
+	}
+}
+
+func TestCircuitBreakerFailsFastAndRecovers(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+
+	mm, err := NewOvmsModelManager(m.GetAddress(), testModelConfigFile, log, ModelManagerConfig{
+		CircuitBreakerThreshold: 2,
+		CircuitBreakerCooldown:  50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	// OVMS fails both the reload and the config query
+	m.setMockReloadResponse(OvmsConfigErrorResponse{Error: "Service unavailable"}, http.StatusServiceUnavailable)
+	m.setMockConfigResponse(OvmsConfigResponse{}, http.StatusServiceUnavailable)
+
+	for i := 0; i < 2; i++ {
+		if err = mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); status.Code(err) != codes.Internal {
+			t.Fatalf("Expected load %d to fail with Internal, got: %v", i, err)
+		}
+	}
+
+	// the breaker is open, so the load fails without a reload
+	reloads := m.getReloadCount()
+	if err = mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected load to fail fast with Unavailable, got: %v", err)
+	}
+	if count := m.getReloadCount(); count != reloads {
+		t.Errorf("Expected no reload while the breaker is open, but got %d", count-reloads)
+	}
+
+	// once OVMS is healthy, the probe closes the breaker
+	m.setMockReloadResponse(modelStateResponse("AVAILABLE", OvmsModelStatus{}), http.StatusOK)
+	m.setMockConfigResponse(modelStateResponse("AVAILABLE", OvmsModelStatus{}), http.StatusOK)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for mm.breaker.IsOpen() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the circuit breaker to close")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err = mm.LoadModel(context.Background(), testOpenvinoModelPath, testOpenvinoModelId, "", "", nil, nil); err != nil {
+		t.Errorf("Expected load to succeed after recovery, got: %v", err)
+	}
+}
+
+// unusedAddress returns an address that nothing listens on
+func unusedAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to reserve an address: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestInitialReloadWaitsForRuntime(t *testing.T) {
+	address := unusedAddress(t)
+
+	// the mock starts accepting connections after a delay, like OVMS starting
+	// after the adapter
+	m := newUnstartedMockOVMS()
+	defer m.Close()
+	m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: OvmsModelStatusResponse{
+			ModelVersionStatus: []OvmsModelVersionStatus{
+				{State: "AVAILABLE"},
+			},
+		},
+	}, http.StatusOK)
+	time.AfterFunc(300*time.Millisecond, func() {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			t.Errorf("Unable to listen at %s: %v", address, err)
+			return
+		}
+		m.server.Listener.Close()
+		m.server.Listener = listener
+		m.server.Start()
+	})
+
+	mm, err := NewOvmsModelManager("http://"+address, filepath.Join(t.TempDir(), "model_config_list.json"), log, ModelManagerConfig{
+		InitialReloadDeadline: 5 * time.Second,
+		InitialReloadBackoff:  20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	if err := mm.LoadModel(context.Background(), filepath.Join(testdataDir, "models", testOpenvinoModelId), testOpenvinoModelId, "", "", nil, nil); err != nil {
+		t.Fatalf("Expected the initial reload to wait for OVMS to listen but got: %v", err)
+	}
+	if m.getReloadCount() != 1 {
+		t.Errorf("Expected 1 reload but got %d", m.getReloadCount())
+	}
+}
+
+func TestInitialReloadNotRetriedWithoutDeadline(t *testing.T) {
+	mm, err := NewOvmsModelManager("http://"+unusedAddress(t), filepath.Join(t.TempDir(), "model_config_list.json"), log, ModelManagerConfig{})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+
+	err = mm.LoadModel(context.Background(), filepath.Join(testdataDir, "models", testOpenvinoModelId), testOpenvinoModelId, "", "", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "Communication error") {
+		t.Errorf("Expected a communication error without an initial reload deadline but got: %v", err)
+	}
+}
+
+func TestModelReadiness(t *testing.T) {
+	versionStatus := func(state, errorCode, errorMessage string) OvmsModelStatusResponse {
+		return OvmsModelStatusResponse{ModelVersionStatus: []OvmsModelVersionStatus{
+			{Version: "1", State: state, Status: OvmsModelStatus{ErrorCode: errorCode, ErrorMessage: errorMessage}},
+		}}
+	}
+	testCases := []struct {
+		name     string
+		conf     OvmsModelStatusResponse
+		exists   bool
+		expected util.ModelReadiness
+	}{
+		{"available", versionStatus(ovmsModelStateAvailable, ovmsErrorCodeOK, ovmsErrorCodeOK), true, util.ModelReady},
+		{"available with empty status", versionStatus(ovmsModelStateAvailable, "", ""), true, util.ModelReady},
+		{"start", versionStatus(ovmsModelStateStart, "", ""), true, util.ModelNotReady},
+		{"loading", versionStatus(ovmsModelStateLoading, ovmsErrorCodeOK, ovmsErrorCodeOK), true, util.ModelNotReady},
+		{"unloading", versionStatus(ovmsModelStateUnloading, "", ""), true, util.ModelNotReady},
+		{"end without error", versionStatus(ovmsModelStateEnd, ovmsErrorCodeOK, ovmsErrorCodeOK), true, util.ModelNotReady},
+		{"loading with error", versionStatus(ovmsModelStateLoading, "UNKNOWN", "Model file is corrupted"), true, util.ModelFailed},
+		{"end with error", versionStatus(ovmsModelStateEnd, "UNKNOWN", ""), true, util.ModelFailed},
+		{"unknown state", versionStatus("BROKEN", "", ""), true, util.ModelFailed},
+		{"no versions", OvmsModelStatusResponse{}, true, util.ModelFailed},
+		{"missing", OvmsModelStatusResponse{}, false, util.ModelFailed},
+	}
+	for _, tc := range testCases {
+		if got, message := modelReadiness(tc.conf, tc.exists); got != tc.expected {
+			t.Errorf("Expected %s status to be %v, got %v: %s", tc.name, tc.expected, got, message)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// parseModelSizeMultipliers parses the multipliers of the disk sizes of the
+// models by model type from a JSON object like {"onnx": 2, "openvino": 1.5}
+func parseModelSizeMultipliers(value string) (map[string]float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var parsed map[string]float64
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("Invalid JSON: %w", err)
+	}
+	multipliers := make(map[string]float64, len(parsed))
+	for modelType, multiplier := range parsed {
+		resolved, err := resolveModelType(modelType)
+		if err != nil {
+			return nil, err
+		}
+		if resolved == "" {
+			return nil, fmt.Errorf("Size multipliers must be set for a model type, found '%s'", modelType)
+		}
+		if _, exists := multipliers[resolved]; exists {
+			return nil, fmt.Errorf("Size multiplier of model type '%s' is set more than once", resolved)
+		}
+		if multiplier <= 0 {
+			return nil, fmt.Errorf("Size multiplier of model type '%s' must be greater than 0, found %v", resolved, multiplier)
+		}
+		multipliers[resolved] = multiplier
+	}
+	return multipliers, nil
+}
+
+// modelSizeMultiplier returns the multiplier of the disk size of the models
+// of a type, which is the ModelSizeMultiplier unless the type has its own
+//
+// The applied multiplier is logged so that the sizes reported for the models
+// can be audited.
+func (s *OvmsAdapterServer) modelSizeMultiplier(modelType string, log logr.Logger) float64 {
+	if multiplier, ok := s.AdapterConfig.ModelSizeMultipliers[modelType]; ok {
+		log.Info("Applying the model size multiplier of the model type", "model_type", modelType, "multiplier", multiplier)
+		return multiplier
+	}
+	log.Info("Applying the default model size multiplier", "model_type", modelType, "multiplier", s.AdapterConfig.ModelSizeMultiplier)
+	return s.AdapterConfig.ModelSizeMultiplier
+}
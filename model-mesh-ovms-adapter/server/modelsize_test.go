@@ -0,0 +1,76 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+)
+
+func TestParseModelSizeMultipliers(t *testing.T) {
+	multipliers, err := parseModelSizeMultipliers(`{"openvino_ir": 1.5, "ONNX": 2, "mediapipe_graph": 3}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]float64{"openvino": 1.5, "onnx": 2, "mediapipe_graph": 3}
+	if !reflect.DeepEqual(expected, multipliers) {
+		t.Errorf("Expected multipliers %v, got %v", expected, multipliers)
+	}
+
+	for _, value := range []string{
+		`not json`,
+		`{"onnx": "2"}`,
+		`["onnx", 2]`,
+		`{"unknown": 2}`,
+		`{"": 2}`,
+		`{"openvino": 1, "openvino_ir": 2}`,
+		`{"onnx": 0}`,
+		`{"onnx": -1.5}`,
+	} {
+		if _, err := parseModelSizeMultipliers(value); err == nil {
+			t.Errorf("Expected an error for %s", value)
+		}
+	}
+}
+
+func TestModelSizeMultiplier(t *testing.T) {
+	s := &OvmsAdapterServer{
+		AdapterConfig: &AdapterConfiguration{
+			ModelSizeMultiplier:  1.25,
+			ModelSizeMultipliers: map[string]float64{"onnx": 2},
+		},
+	}
+	var logLines []string
+	log := funcr.New(func(prefix, args string) {
+		logLines = append(logLines, args)
+	}, funcr.Options{})
+
+	// a configured type uses its own multiplier
+	if multiplier := s.modelSizeMultiplier("onnx", log); multiplier != 2 {
+		t.Errorf("Expected the multiplier 2 of the onnx type, got %v", multiplier)
+	}
+	// other types fall back to the global multiplier
+	if multiplier := s.modelSizeMultiplier("openvino", log); multiplier != 1.25 {
+		t.Errorf("Expected the default multiplier 1.25, got %v", multiplier)
+	}
+
+	// the applied multipliers are logged
+	if len(logLines) != 2 || !strings.Contains(logLines[0], `"multiplier"=2`) || !strings.Contains(logLines[1], `"multiplier"=1.25`) {
+		t.Errorf("Expected the applied multipliers to be logged, got %v", logLines)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+// name of the runtime in the RuntimeInfo
+const ovmsRuntimeName = "ovms"
+
+// prefix of the model types that ModelMesh assigns to models that only
+// specify the runtime to serve them
+const runtimeModelTypePrefix = "rt:"
+
+// supportedModelTypes are the model types that OVMS can load
+var supportedModelTypes = []util.ModelTypeInfo{
+	{Name: "openvino", Aliases: []string{"openvino_ir"}, Files: []string{"*.xml", "*.bin"}},
+	{Name: "onnx", Files: []string{"*.onnx"}, SingleFile: true},
+	{Name: "tensorflow", Files: []string{"saved_model.pb", "variables/"}},
+	{Name: "mediapipe_graph", Files: []string{"graph.pbtxt"}},
+}
+
+// SupportedModelTypes returns the model types accepted by LoadModel
+func SupportedModelTypes() []util.ModelTypeInfo {
+	return util.CopyModelTypes(supportedModelTypes)
+}
+
+// supportedModelTypesWithRuntime returns the runtime along with the
+// SupportedModelTypes, as served by the mmesh.ModelTypes service and the
+// ModelTypesHandler
+func (s *OvmsAdapterServer) supportedModelTypesWithRuntime() util.SupportedModelTypes {
+	runtime := util.RuntimeInfo{Name: ovmsRuntimeName}
+	if s.AdapterConfig != nil {
+		runtime.Version = s.AdapterConfig.RuntimeVersion
+	}
+	return util.SupportedModelTypes{Runtime: runtime, ModelTypes: SupportedModelTypes()}
+}
+
+// RegisterModelTypesServer registers the mmesh.ModelTypes gRPC service
+func (s *OvmsAdapterServer) RegisterModelTypesServer(grpcServer *grpc.Server) {
+	util.RegisterModelTypesServer(grpcServer, s.supportedModelTypesWithRuntime)
+}
+
+// resolveModelType returns the name of the supported model type that the
+// model type or one of its aliases refers to
+//
+// An empty model type is allowed, in which case OVMS detects the type from
+// the model files. So is a type like "rt:ovms", which only names the runtime.
+// A model type that is not supported is returned in lower case along with the
+// error, since OVMS may still load it.
+func resolveModelType(modelType string) (string, error) {
+	if strings.HasPrefix(modelType, runtimeModelTypePrefix) {
+		return "", nil
+	}
+	// convert to lower case and remove anything after the :
+	modelType = strings.ToLower(strings.Split(modelType, ":")[0])
+	if modelType == "" {
+		return "", nil
+	}
+
+	for _, t := range supportedModelTypes {
+		if t.Name == modelType {
+			return t.Name, nil
+		}
+		for _, alias := range t.Aliases {
+			if alias == modelType {
+				return t.Name, nil
+			}
+		}
+	}
+	return modelType, fmt.Errorf("Model type '%s' is not supported by OVMS", modelType)
+}
+
+// ModelTypesHandler serves the runtime and the SupportedModelTypes as JSON
+func (s *OvmsAdapterServer) ModelTypesHandler() http.Handler {
+	return util.ModelTypesHandler(s.supportedModelTypesWithRuntime)
+}
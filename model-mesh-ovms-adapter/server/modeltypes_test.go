@@ -0,0 +1,136 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+func TestModelTypesHandler(t *testing.T) {
+	s := &OvmsAdapterServer{}
+	rec := httptest.NewRecorder()
+	s.ModelTypesHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/model-types", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+	var body struct {
+		ModelTypes []util.ModelTypeInfo `json:"model_types"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Unable to parse response '%s': %v", rec.Body.String(), err)
+	}
+
+	var names []string
+	for _, mt := range body.ModelTypes {
+		names = append(names, mt.Name)
+	}
+	expectedNames := []string{"openvino", "onnx", "tensorflow", "mediapipe_graph"}
+	if len(names) != len(expectedNames) {
+		t.Fatalf("Expected model types %v but got %v", expectedNames, names)
+	}
+	for i := range expectedNames {
+		if names[i] != expectedNames[i] {
+			t.Errorf("Expected model types %v but got %v", expectedNames, names)
+			break
+		}
+	}
+	if aliases := body.ModelTypes[0].Aliases; len(aliases) != 1 || aliases[0] != "openvino_ir" {
+		t.Errorf("Expected alias openvino_ir for openvino but got %v", aliases)
+	}
+}
+
+func TestModelTypesHandlerRoutingMetadata(t *testing.T) {
+	s := &OvmsAdapterServer{AdapterConfig: &AdapterConfiguration{RuntimeVersion: "v2"}}
+	rec := httptest.NewRecorder()
+	s.ModelTypesHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/model-types", nil))
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Unable to parse response '%s': %v", rec.Body.String(), err)
+	}
+	expectedRuntime := map[string]interface{}{"name": "ovms", "version": "v2"}
+	if !reflect.DeepEqual(expectedRuntime, body["runtime"]) {
+		t.Errorf("Expected the runtime %v but got %v", expectedRuntime, body["runtime"])
+	}
+
+	modelTypes, ok := body["model_types"].([]interface{})
+	if !ok || len(modelTypes) == 0 {
+		t.Fatalf("Expected a list of model types but got %v", body["model_types"])
+	}
+	for _, mt := range modelTypes {
+		info, ok := mt.(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected a model type object but got %v", mt)
+		}
+		if files, ok := info["files"].([]interface{}); !ok || len(files) == 0 {
+			t.Errorf("Expected the files of model type %v but got %v", info["name"], info["files"])
+		}
+	}
+	expectedOnnx := map[string]interface{}{"name": "onnx", "files": []interface{}{"*.onnx"}, "single_file": true}
+	if !reflect.DeepEqual(expectedOnnx, modelTypes[1]) {
+		t.Errorf("Expected the onnx model type %v but got %v", expectedOnnx, modelTypes[1])
+	}
+}
+
+func TestResolveModelType(t *testing.T) {
+	testCases := []struct {
+		modelType string
+		expected  string
+		expectErr bool
+	}{
+		{"openvino", "openvino", false},
+		{"OpenVINO_IR", "openvino", false},
+		{"rt:ovms", "", false},
+		{"onnx:1", "onnx", false},
+		{"mediapipe_graph", "mediapipe_graph", false},
+		{"", "", false},
+		{"Paddle", "paddle", true},
+	}
+
+	for _, tc := range testCases {
+		got, err := resolveModelType(tc.modelType)
+		if tc.expectErr != (err != nil) || got != tc.expected {
+			t.Errorf("Expected model type '%s' to resolve to '%s' but got '%s' (error: %v)", tc.modelType, tc.expected, got, err)
+		}
+	}
+}
+
+func TestLoadModelStrictModelTypes(t *testing.T) {
+	s := &OvmsAdapterServer{
+		AdapterConfig: &AdapterConfiguration{StrictModelTypes: true},
+		Log:           log,
+	}
+
+	_, err := s.LoadModel(context.Background(), &mmesh.LoadModelRequest{
+		ModelId:   "paddle-model",
+		ModelType: "rt:ovms",
+		ModelKey:  `{"model_type": {"name": "paddle"}}`,
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a model type that is not supported but got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+// parseModelTypeRootDirs parses the root dirs of the models by model type
+// from a JSON object like {"mediapipe_graph": "/models/mediapipe"}, the dir
+// of each type is joined with ovmsModelSubdir like the RootModelDir
+func parseModelTypeRootDirs(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var parsed map[string]string
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("Invalid JSON: %w", err)
+	}
+	rootDirs := make(map[string]string, len(parsed))
+	for modelType, dir := range parsed {
+		resolved, err := resolveModelType(modelType)
+		if err != nil {
+			return nil, err
+		}
+		if resolved == "" {
+			return nil, fmt.Errorf("Root dirs must be set for a model type, found '%s'", modelType)
+		}
+		if _, exists := rootDirs[resolved]; exists {
+			return nil, fmt.Errorf("Root dir of model type '%s' is set more than once", resolved)
+		}
+		if !filepath.IsAbs(dir) {
+			return nil, fmt.Errorf("Root dir of model type '%s' must be an absolute path, found '%s'", resolved, dir)
+		}
+		if rootDirs[resolved], err = util.SecureJoin(dir, ovmsModelSubdir); err != nil {
+			return nil, err
+		}
+	}
+	return rootDirs, nil
+}
+
+// modelRootDir returns the dir in which the layouts of the models of a type
+// are created
+func (s *OvmsAdapterServer) modelRootDir(modelType string) string {
+	if dir, ok := s.AdapterConfig.ModelTypeRootDirs[modelType]; ok {
+		return dir
+	}
+	return s.AdapterConfig.RootModelDir
+}
+
+// modelRootDirs returns the RootModelDir followed by the other root dirs of
+// the model types, without duplicates
+func (s *OvmsAdapterServer) modelRootDirs() []string {
+	dirs := []string{s.AdapterConfig.RootModelDir}
+	seen := map[string]bool{s.AdapterConfig.RootModelDir: true}
+	for _, dir := range s.AdapterConfig.ModelTypeRootDirs {
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs[1:])
+	return dirs
+}
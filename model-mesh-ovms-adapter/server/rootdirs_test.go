@@ -0,0 +1,124 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+)
+
+func TestParseModelTypeRootDirs(t *testing.T) {
+	rootDirs, err := parseModelTypeRootDirs(`{"mediapipe_graph": "/models/mediapipe", "openvino_ir": "/models/openvino"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"mediapipe_graph": filepath.Join("/models/mediapipe", ovmsModelSubdir),
+		"openvino":        filepath.Join("/models/openvino", ovmsModelSubdir),
+	}
+	if !reflect.DeepEqual(expected, rootDirs) {
+		t.Errorf("Expected root dirs %v, got %v", expected, rootDirs)
+	}
+
+	for _, value := range []string{
+		`not json`,
+		`{"unknown": "/models/unknown"}`,
+		`{"": "/models/none"}`,
+		`{"openvino": "/models/a", "openvino_ir": "/models/b"}`,
+		`{"onnx": "relative/dir"}`,
+	} {
+		if _, err := parseModelTypeRootDirs(value); err == nil {
+			t.Errorf("Expected an error for %s", value)
+		}
+	}
+}
+
+func TestLoadModelUnderModelTypeRootDir(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+	available := OvmsModelStatusResponse{
+		ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+	}
+	if err := m.setMockReloadResponse(OvmsConfigResponse{
+		"graph":       available,
+		"openvino-ir": available,
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	graphPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(graphPath, "graph.pbtxt"), []byte("input_stream: \"in\""), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{BatchWaitTimeMax: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+	rootModelDir := t.TempDir()
+	mediapipeRootDir := t.TempDir()
+	s := &OvmsAdapterServer{
+		ModelManager: mm,
+		AdapterConfig: &AdapterConfiguration{
+			RootModelDir:      rootModelDir,
+			ModelTypeRootDirs: map[string]string{"mediapipe_graph": mediapipeRootDir},
+		},
+		Log: log,
+	}
+
+	for _, req := range []*mmesh.LoadModelRequest{
+		{
+			ModelId:   "graph",
+			ModelPath: graphPath,
+			ModelType: "mediapipe_graph",
+			ModelKey:  `{"model_type": {"name": "mediapipe_graph"}}`,
+		},
+		{
+			ModelId:   "openvino-ir",
+			ModelPath: testOpenvinoModelPath,
+			ModelType: "openvino",
+			ModelKey:  `{"model_type": {"name": "openvino"}}`,
+		},
+	} {
+		if _, err = s.LoadModel(context.Background(), req); err != nil {
+			t.Fatalf("LoadModel call of %s failed: %v", req.ModelId, err)
+		}
+	}
+
+	models, writtenBytes := readConfiguredModels(t, configFile)
+	expectedModels := map[string]string{
+		"graph":       filepath.Join(mediapipeRootDir, "graph"),
+		"openvino-ir": filepath.Join(rootModelDir, "openvino-ir"),
+	}
+	if !reflect.DeepEqual(expectedModels, models) {
+		t.Errorf("Expected the models %v in the config, got: %s", expectedModels, string(writtenBytes))
+	}
+
+	// the model dir is removed from the root dir of its type on unload
+	if _, err = s.UnloadModel(context.Background(), &mmesh.UnloadModelRequest{ModelId: "graph"}); err != nil {
+		t.Fatalf("UnloadModel call failed: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(mediapipeRootDir, "graph")); !os.IsNotExist(err) {
+		t.Errorf("Expected the model dir to be removed, got: %v", err)
+	}
+}
@@ -18,6 +18,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -39,18 +41,86 @@ type AdapterConfiguration struct {
 	ModelLoadingTimeoutMS    int
 	DefaultModelSizeInBytes  int
 	ModelSizeMultiplier      float64
+	ModelSizeMultipliers     map[string]float64 // by model type, the models of other types use the ModelSizeMultiplier
 	RuntimeVersion           string
 	LimitModelConcurrency    int // 0 means no limit (default)
 	RootModelDir             string
 	UseEmbeddedPuller        bool
+	StrictModelKey           bool
+	StrictModelTypes         bool // reject the model types that are not in the SupportedModelTypes
+	ModelKeyMaxSize          int
+	GrpcReflection           bool
+	GrpcCompression          bool
+	LayoutRetries            int // 0 means transient filesystem errors are not retried
+	LayoutRetryBackoff       time.Duration
+	ModelFilePlacement       util.FilePlacement
+	ModelSymlinkPolicy       util.SymlinkPolicy
+	FsyncPolicy              util.FsyncPolicy
+	// remove the RootModelDir on a clean shutdown
+	CleanupOnShutdown bool
+	// also remove it if the ROOT_MODEL_DIR that contains it is a mount point
+	CleanupMountedRoot bool
+	// report the CapacityInBytes less the memory that OVMS already uses, read
+	// from its cgroup at this path; empty means the capacity is static
+	RuntimeCgroupDir string
+	// load the models listed in the sub-model manifest of a ModelPath as
+	// separate models of the runtime
+	LoadSubModels bool
+	// compare the files staged in the model repository with the files of
+	// the ModelPath after the layout, failing the load on any difference
+	VerifyStagedFiles bool
+	// stage the complete layout of a model, or of all the sub-models of a
+	// ModelPath, before committing it and loading it with a single reload
+	DeferReloadUntilStaged bool
 
 	// OVMS adapter specific
-	ModelConfigFile  string
-	BatchWaitTimeMin time.Duration
-	BatchWaitTimeMax time.Duration
-	ReloadTimeout    time.Duration
+	ModelConfigFile         string
+	BatchWaitTimeMin        time.Duration
+	BatchWaitTimeMax        time.Duration
+	UnloadGracePeriod       time.Duration
+	ReloadTimeout           time.Duration
+	OvmsApiVersion          string
+	ModelStateTimeout       time.Duration
+	WaitForMissingModels    bool
+	PruneStaleModelConfig   bool
+	ReconcileOnBoot         bool
+	ReconcileConcurrency    int
+	ReconcileEmptyConfig    bool
+	StartupUnloadMode       string
+	SanitizeModelNames      bool
+	MetricsPort             int    // 0 means the metrics are not served
+	DebugTokenFile          string // empty means the debug endpoint is disabled
+	CircuitBreakerThreshold int    // 0 means the circuit breaker is disabled
+	CircuitBreakerCooldown  time.Duration
+	InitialReloadDeadline   time.Duration // 0 means a refused initial reload is not retried
+	MetricsModelLabels      []string
+	ModelHealthFailurePolls int
+	ModelHealthWindow       time.Duration
+	LogDedupWindow          time.Duration // 0 means repeated errors are all logged
+	ConfigEditPolicy        string
+	NameCollisionCode       string
+	SkipUnchangedReloads    bool
+	FileVisibilityGrace     time.Duration // 0 means the loads do not wait for the files
+	// plugin_config of the models by model type, under the plugin_config of
+	// the ModelKey
+	PluginConfigDefaults map[string]map[string]string
+	// the RootModelDir of the models by model type, the models of other
+	// types are under the RootModelDir
+	ModelTypeRootDirs map[string]string
 }
 
+// What the first RuntimeStatus does with the models loaded by a previous run
+// of the adapter, see StartupUnloadMode
+const (
+	// unload every model and clear the model dirs
+	StartupUnloadWipe string = "wipe"
+	// unload only the models whose directory is gone, keep the other models
+	// and the model dirs
+	StartupUnloadReconcile string = "reconcile"
+	// keep the model config and the model dirs as they are
+	StartupUnloadSkip string = "skip"
+)
+
 type OvmsAdapterServer struct {
 	ModelManager  *OvmsModelManager
 	Puller        *puller.Puller
@@ -74,9 +144,30 @@ func NewOvmsAdapterServer(runtimePort int, config *AdapterConfiguration, log log
 		config.ModelConfigFile,
 		log,
 		ModelManagerConfig{
-			BatchWaitTimeMin: config.BatchWaitTimeMin,
-			BatchWaitTimeMax: config.BatchWaitTimeMax,
-			ReloadTimeout:    config.ReloadTimeout,
+			BatchWaitTimeMin:        config.BatchWaitTimeMin,
+			BatchWaitTimeMax:        config.BatchWaitTimeMax,
+			UnloadGracePeriod:       config.UnloadGracePeriod,
+			ReloadTimeout:           config.ReloadTimeout,
+			ApiVersion:              config.OvmsApiVersion,
+			ModelStateTimeout:       config.ModelStateTimeout,
+			WaitForMissingModels:    config.WaitForMissingModels,
+			PruneMissingModels:      config.PruneStaleModelConfig,
+			ReconcileOnBoot:         config.ReconcileOnBoot,
+			ReconcileConcurrency:    config.ReconcileConcurrency,
+			ReconcileEmptyConfig:    config.ReconcileEmptyConfig,
+			SanitizeModelNames:      config.SanitizeModelNames,
+			CircuitBreakerThreshold: config.CircuitBreakerThreshold,
+			CircuitBreakerCooldown:  config.CircuitBreakerCooldown,
+			FsyncPolicy:             config.FsyncPolicy,
+			InitialReloadDeadline:   config.InitialReloadDeadline,
+			MetricsModelLabels:      config.MetricsModelLabels,
+			ModelHealthFailurePolls: config.ModelHealthFailurePolls,
+			ModelHealthWindow:       config.ModelHealthWindow,
+			LogDedupWindow:          config.LogDedupWindow,
+			ConfigEditPolicy:        config.ConfigEditPolicy,
+			NameCollisionCode:       config.NameCollisionCode,
+			SkipUnchangedReloads:    config.SkipUnchangedReloads,
+			FileVisibilityGrace:     config.FileVisibilityGrace,
 		},
 	); err != nil {
 		panic(err)
@@ -94,46 +185,153 @@ func NewOvmsAdapterServer(runtimePort int, config *AdapterConfiguration, log log
 	return s
 }
 
+// capacityInBytes returns the capacity to report to ModelMesh, which is the
+// static CapacityInBytes unless RuntimeCgroupDir is set
+//
+// The capacity is derived from the memory of the OVMS container, so memory
+// that is already used in that container is not available for models and is
+// subtracted from the capacity if it can be read. The cgroup of the adapter
+// container is not the one of OVMS, so it is never read by default.
+func (s *OvmsAdapterServer) capacityInBytes() uint64 {
+	capacity := int64(s.AdapterConfig.CapacityInBytes)
+	if dir := s.AdapterConfig.RuntimeCgroupDir; dir != "" {
+		usage, err := util.CgroupMemoryUsage(dir)
+		if err != nil {
+			s.Log.Info("Unable to read the memory usage of the runtime, reporting the static capacity", "cgroupDir", dir, "error", err)
+		} else {
+			s.Log.Info("Subtracting the memory usage of the runtime from the capacity", "capacity", capacity, "usage", usage)
+			capacity -= usage
+		}
+	}
+	if capacity < 0 {
+		return 0
+	}
+	return uint64(capacity)
+}
+
+// CleanupOnShutdown removes the files generated for OVMS in the RootModelDir
+// and the root dirs of the model types if enabled, once the adapter has
+// stopped serving requests
+func (s *OvmsAdapterServer) CleanupOnShutdown() {
+	if !s.AdapterConfig.CleanupOnShutdown {
+		return
+	}
+	for _, dir := range s.modelRootDirs() {
+		s.Log.Info("Removing the generated model dir", "dir", dir)
+		if err := util.RemoveGeneratedDir(filepath.Dir(dir), dir, s.AdapterConfig.CleanupMountedRoot); err != nil {
+			s.Log.Error(err, "Error cleaning up the generated model dir on shutdown", "dir", dir)
+		}
+	}
+}
+
 func (s *OvmsAdapterServer) LoadModel(ctx context.Context, req *mmesh.LoadModelRequest) (*mmesh.LoadModelResponse, error) {
 	log := s.Log.WithName("Load Model").WithValues("model_id", req.ModelId)
-	modelType := util.GetModelType(req, log)
+	if err := util.CheckModelKeyLimits(req, s.AdapterConfig.ModelKeyMaxSize); err != nil {
+		log.Error(err, "ModelKey exceeds the limits")
+		return nil, err
+	}
+	if s.AdapterConfig.StrictModelKey {
+		if err := util.ValidateModelKey(req); err != nil {
+			log.Error(err, "Invalid ModelKey")
+			return nil, err
+		}
+	}
+
+	modelType, err := resolveModelType(util.GetModelType(req, log))
+	if err != nil {
+		if s.AdapterConfig.StrictModelTypes {
+			log.Error(err, "Invalid model type")
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid model type: %s", err)
+		}
+		// OVMS may support more model types than the adapter knows about
+		log.Info("Passing a model type that the adapter does not know to OVMS", "model_type", modelType)
+	}
 	log.Info("Using model type", "model_type", modelType)
 
 	if s.AdapterConfig.UseEmbeddedPuller {
-		var pullerErr error
-		req, pullerErr = s.Puller.ProcessLoadModelRequest(ctx, req)
+		s.ModelManager.events.record(req.ModelId, eventPullStarted, "")
+		pulledReq, pullerErr := s.Puller.ProcessLoadModelRequest(ctx, req)
 		if pullerErr != nil {
+			s.ModelManager.events.record(req.ModelId, eventPullFinished, pullerErr.Error())
 			log.Error(pullerErr, "Failed to pull model from storage")
 			return nil, pullerErr
 		}
+		req = pulledReq
+		s.ModelManager.events.record(req.ModelId, eventPullFinished, "")
 	}
 
-	var err error
 	schemaPath, err := util.GetSchemaPath(req)
 	if err != nil {
 		return nil, err
 	}
 
+	pluginConfig, err := util.GetPluginConfig(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid plugin_config in ModelKey: %s", err)
+	}
+	pluginConfig = mergePluginConfig(s.AdapterConfig.PluginConfigDefaults[modelType], pluginConfig)
+
+	servedName, err := util.GetServedModelName(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid served_model_name in ModelKey: %s", err)
+	}
+
+	labels, err := util.GetModelLabels(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid labels in ModelKey: %s", err)
+	}
+
+	if s.AdapterConfig.LoadSubModels {
+		// a model id with the separator could be the id of a sub-model
+		if strings.Contains(req.ModelId, subModelIdSeparator) {
+			return nil, status.Errorf(codes.InvalidArgument, "Model id %s contains '%s', which is reserved for the ids of sub-models", req.ModelId, subModelIdSeparator)
+		}
+		manifest, manifestErr := readSubModelManifest(req.ModelPath)
+		if manifestErr != nil {
+			log.Error(manifestErr, "Invalid sub-model manifest")
+			return nil, status.Error(codes.InvalidArgument, manifestErr.Error())
+		}
+		if manifest != nil {
+			if servedName != "" {
+				log.Info("Ignoring the served_model_name, the sub-models are named after the model id", "served_model_name", servedName)
+			}
+			size, loadErr := s.loadSubModels(ctx, req, modelType, manifest, pluginConfig, labels, log)
+			if loadErr != nil {
+				log.Error(loadErr, "OVMS failed to load sub-models")
+				return nil, status.Errorf(status.Code(loadErr), "Failed to load sub-models due to error: %s", loadErr)
+			}
+			log.Info("OVMS sub-models loaded", "count", len(manifest.Models), "sizeInBytes", size)
+			return &mmesh.LoadModelResponse{
+				SizeInBytes:    size,
+				MaxConcurrency: uint32(s.AdapterConfig.LimitModelConcurrency),
+			}, nil
+		}
+	}
+
 	// using the files downloaded by the puller, create a file layout that the runtime can understand and load from
-	err = adaptModelLayoutForRuntime(ctx, s.AdapterConfig.RootModelDir, req.ModelId, modelType, req.ModelPath, schemaPath, log)
+	modelRootDir := s.modelRootDir(modelType)
+	err = util.RetryTransientFileErrors(ctx, s.AdapterConfig.LayoutRetries, s.AdapterConfig.LayoutRetryBackoff, log, func() error {
+		return adaptModelLayoutForRuntime(ctx, modelRootDir, req.ModelId, modelType, req.ModelPath, schemaPath, s.AdapterConfig.ModelFilePlacement, s.AdapterConfig.ModelSymlinkPolicy, s.AdapterConfig.VerifyStagedFiles, s.AdapterConfig.DeferReloadUntilStaged, log)
+	})
 	if err != nil {
 		log.Error(err, "Failed to create model directory and load model")
 		return nil, status.Errorf(status.Code(err), "Failed to load Model due to adapter error: %s", err)
 	}
+	s.ModelManager.events.record(req.ModelId, eventLayoutDone, "")
 
-	adaptedModelPath, err := util.SecureJoin(s.AdapterConfig.RootModelDir, req.ModelId)
+	adaptedModelPath, err := util.SecureJoin(modelRootDir, req.ModelId)
 	if err != nil {
-		log.Error(err, "Unable to securely join", "rootModelDir", rootModelDir, "modelID", req.ModelId)
+		log.Error(err, "Unable to securely join", "rootModelDir", modelRootDir, "modelID", req.ModelId)
 		return nil, err
 	}
 
-	loadErr := s.ModelManager.LoadModel(ctx, adaptedModelPath, req.ModelId)
+	loadErr := s.ModelManager.LoadModel(ctx, adaptedModelPath, req.ModelId, modelType, servedName, pluginConfig, labels)
 	if loadErr != nil {
 		log.Error(loadErr, "OVMS failed to load model")
 		return nil, status.Errorf(status.Code(loadErr), "Failed to load model due to error: %s", loadErr)
 	}
 
-	size := util.CalcMemCapacity(req.ModelKey, s.AdapterConfig.DefaultModelSizeInBytes, s.AdapterConfig.ModelSizeMultiplier, log)
+	size := util.CalcMemCapacity(req.ModelKey, s.AdapterConfig.DefaultModelSizeInBytes, s.modelSizeMultiplier(modelType, log), log)
 
 	log.Info("OVMS model loaded", "sizeInBytes", size)
 
@@ -144,7 +342,18 @@ func (s *OvmsAdapterServer) LoadModel(ctx context.Context, req *mmesh.LoadModelR
 }
 
 func (s *OvmsAdapterServer) UnloadModel(ctx context.Context, req *mmesh.UnloadModelRequest) (*mmesh.UnloadModelResponse, error) {
-	if unloadErr := s.ModelManager.UnloadModel(ctx, req.ModelId); unloadErr != nil {
+	// a model loaded from a sub-model manifest is unloaded with its sub-models
+	manifest, err := s.loadedSubModels(req.ModelId)
+	if err != nil {
+		s.Log.Error(err, "Unable to read the sub-models of the model, unloading it as a single model", "model_id", req.ModelId)
+	}
+	var unloadErr error
+	if manifest != nil {
+		unloadErr = s.unloadSubModels(ctx, manifest, req.ModelId)
+	} else {
+		unloadErr = s.ModelManager.UnloadModel(ctx, req.ModelId)
+	}
+	if unloadErr != nil {
 		// check if we got a gRPC error as a response that indicates that OVMS
 		// does not have the model registered. In that case we still want to proceed
 		// with removing the model files.
@@ -156,13 +365,17 @@ func (s *OvmsAdapterServer) UnloadModel(ctx context.Context, req *mmesh.UnloadMo
 		}
 	}
 
-	ovmsModelIDDir, err := util.SecureJoin(s.AdapterConfig.RootModelDir, req.ModelId)
-	if err != nil {
-		s.Log.Error(err, "Unable to securely join", "rootModelDir", s.AdapterConfig.RootModelDir, "modelId", req.ModelId)
-		return nil, err
-	}
-	if err = os.RemoveAll(ovmsModelIDDir); err != nil {
-		return nil, status.Errorf(status.Code(err), "Error while deleting the %s dir: %v", ovmsModelIDDir, err)
+	// the model type is not known here, so the dir of the model is removed
+	// from every root dir
+	for _, dir := range s.modelRootDirs() {
+		ovmsModelIDDir, err := util.SecureJoin(dir, req.ModelId)
+		if err != nil {
+			s.Log.Error(err, "Unable to securely join", "rootModelDir", dir, "modelId", req.ModelId)
+			return nil, err
+		}
+		if err = os.RemoveAll(ovmsModelIDDir); err != nil {
+			return nil, status.Errorf(status.Code(err), "Error while deleting the %s dir: %v", ovmsModelIDDir, err)
+		}
 	}
 
 	if s.AdapterConfig.UseEmbeddedPuller {
@@ -180,10 +393,14 @@ func (s *OvmsAdapterServer) RuntimeStatus(ctx context.Context, req *mmesh.Runtim
 	log := s.Log
 	runtimeStatus := &mmesh.RuntimeStatusResponse{Status: mmesh.RuntimeStatusResponse_STARTING}
 
-	if ovmsErr := s.ModelManager.GetConfig(ctx); ovmsErr != nil {
+	unhealthyModels, ovmsErr := s.ModelManager.UnhealthyModels(ctx)
+	if ovmsErr != nil {
 		log.Info("Failed to ping OVMS", "error", ovmsErr)
 		return runtimeStatus, nil
 	}
+	if len(unhealthyModels) > 0 {
+		log.Info("Loaded models are not available in OVMS", "model_ids", unhealthyModels)
+	}
 
 	// if the context is cancelled, we could not connect
 	if ctx.Err() != nil {
@@ -191,27 +408,42 @@ func (s *OvmsAdapterServer) RuntimeStatus(ctx context.Context, req *mmesh.Runtim
 		return runtimeStatus, nil
 	}
 
-	// Reset OVMS, unloading any existing models
-	if unloadErr := s.ModelManager.UnloadAll(ctx); unloadErr != nil {
-		log.Info("Unloading all OVMS models failed", "error", unloadErr)
-		return runtimeStatus, nil
-	}
+	switch s.AdapterConfig.StartupUnloadMode {
+	case StartupUnloadSkip:
+		log.Info("Keeping the models loaded by a previous run")
 
-	// Clear adapted model dirs
-	if err := util.ClearDirectoryContents(s.AdapterConfig.RootModelDir, nil); err != nil {
-		log.Error(err, "Error cleaning up local model dir")
-		return &mmesh.RuntimeStatusResponse{Status: mmesh.RuntimeStatusResponse_FAILING}, nil
-	}
+	case StartupUnloadReconcile:
+		// Unload the models whose files are gone, keeping the others
+		if unloadErr := s.ModelManager.UnloadMissing(ctx); unloadErr != nil {
+			log.Info("Unloading missing OVMS models failed", "error", unloadErr)
+			return runtimeStatus, nil
+		}
 
-	if s.AdapterConfig.UseEmbeddedPuller {
-		if err := s.Puller.ClearLocalModelStorage(ovmsModelSubdir); err != nil {
-			log.Error(err, "Error cleaning up local model dir")
-			return &mmesh.RuntimeStatusResponse{Status: mmesh.RuntimeStatusResponse_FAILING}, nil
+	default:
+		// Reset OVMS, unloading any existing models
+		if unloadErr := s.ModelManager.UnloadAll(ctx); unloadErr != nil {
+			log.Info("Unloading all OVMS models failed", "error", unloadErr)
+			return runtimeStatus, nil
+		}
+
+		// Clear adapted model dirs
+		for _, dir := range s.modelRootDirs() {
+			if err := util.ClearDirectoryContents(dir, nil); err != nil {
+				log.Error(err, "Error cleaning up local model dir", "dir", dir)
+				return &mmesh.RuntimeStatusResponse{Status: mmesh.RuntimeStatusResponse_FAILING}, nil
+			}
+		}
+
+		if s.AdapterConfig.UseEmbeddedPuller {
+			if err := s.Puller.ClearLocalModelStorage(ovmsModelSubdir); err != nil {
+				log.Error(err, "Error cleaning up local model dir")
+				return &mmesh.RuntimeStatusResponse{Status: mmesh.RuntimeStatusResponse_FAILING}, nil
+			}
 		}
 	}
 
 	runtimeStatus.Status = mmesh.RuntimeStatusResponse_READY
-	runtimeStatus.CapacityInBytes = uint64(s.AdapterConfig.CapacityInBytes)
+	runtimeStatus.CapacityInBytes = s.capacityInBytes()
 	runtimeStatus.MaxLoadingConcurrency = uint32(s.AdapterConfig.MaxLoadingConcurrency)
 	runtimeStatus.ModelLoadingTimeoutMs = uint32(s.AdapterConfig.ModelLoadingTimeoutMS)
 	runtimeStatus.DefaultModelSizeInBytes = uint64(s.AdapterConfig.DefaultModelSizeInBytes)
@@ -23,6 +23,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -280,3 +281,232 @@ func checkEntryExistsInModelConfig(modelid string, path string) error {
 
 	return nil
 }
+
+func TestCleanupOnShutdown(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			generatedDir := filepath.Join(t.TempDir(), ovmsModelSubdir)
+			if err := os.MkdirAll(filepath.Join(generatedDir, testOpenvinoModelId), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			s := &OvmsAdapterServer{
+				AdapterConfig: &AdapterConfiguration{RootModelDir: generatedDir, CleanupOnShutdown: enabled},
+				Log:           log,
+			}
+			s.CleanupOnShutdown()
+
+			_, err := os.Stat(generatedDir)
+			if enabled && !os.IsNotExist(err) {
+				t.Errorf("Expected the generated dir to be removed on shutdown, got: %v", err)
+			}
+			if !enabled && err != nil {
+				t.Errorf("Expected the generated dir to be kept without the cleanup, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestStartupUnloadModes(t *testing.T) {
+	m := NewMockOVMS()
+	defer m.Close()
+	available := OvmsModelStatusResponse{
+		ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+	}
+	if err := m.setMockReloadResponse(OvmsConfigResponse{
+		testOpenvinoModelId: available,
+		testOnnxModelId:     available,
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	missingModelPath := filepath.Join(testdataDir, "models", "missing-model")
+	testCases := []struct {
+		mode           string
+		expectedModels map[string]string
+		dirsCleared    bool
+	}{
+		{
+			mode:           StartupUnloadWipe,
+			expectedModels: map[string]string{testOnnxModelId: testOnnxModelPath},
+			dirsCleared:    true,
+		},
+		{
+			mode:           StartupUnloadReconcile,
+			expectedModels: map[string]string{testOpenvinoModelId: testOpenvinoModelPath, testOnnxModelId: testOnnxModelPath},
+		},
+		{
+			mode:           StartupUnloadSkip,
+			expectedModels: map[string]string{testOpenvinoModelId: testOpenvinoModelPath, "missing-model": missingModelPath, testOnnxModelId: testOnnxModelPath},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.mode, func(t *testing.T) {
+			// the config and a model dir left by a previous run of the adapter
+			configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+			previousConfig := OvmsMultiModelRepositoryConfig{
+				ModelConfigList: []OvmsMultiModelConfigListEntry{
+					{Config: OvmsMultiModelModelConfig{Name: testOpenvinoModelId, BasePath: testOpenvinoModelPath}},
+					{Config: OvmsMultiModelModelConfig{Name: "missing-model", BasePath: missingModelPath}},
+				},
+			}
+			configBytes, err := json.Marshal(previousConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = os.WriteFile(configFile, configBytes, 0644); err != nil {
+				t.Fatal(err)
+			}
+			rootModelDir := t.TempDir()
+			generatedModelDir := filepath.Join(rootModelDir, testOpenvinoModelId)
+			if err = os.MkdirAll(generatedModelDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{})
+			if err != nil {
+				t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+			}
+			s := &OvmsAdapterServer{
+				ModelManager:  mm,
+				AdapterConfig: &AdapterConfiguration{RootModelDir: rootModelDir, StartupUnloadMode: tc.mode},
+				Log:           log,
+			}
+
+			statusResp, err := s.RuntimeStatus(context.Background(), &mmesh.RuntimeStatusRequest{})
+			if err != nil || statusResp.Status != mmesh.RuntimeStatusResponse_READY {
+				t.Fatalf("Expected the runtime to be ready, got %v: %v", statusResp, err)
+			}
+
+			// a load reloads OVMS with the config that RuntimeStatus left
+			if err = mm.LoadModel(context.Background(), testOnnxModelPath, testOnnxModelId, "", "", nil, nil); err != nil {
+				t.Fatalf("LoadModel call failed: %v", err)
+			}
+			writtenBytes, err := os.ReadFile(configFile)
+			if err != nil {
+				t.Fatalf("Unable to read config file: %v", err)
+			}
+			var writtenConfig OvmsMultiModelRepositoryConfig
+			if err = json.Unmarshal(writtenBytes, &writtenConfig); err != nil {
+				t.Fatalf("Unable to parse config file: %v", err)
+			}
+			models := map[string]string{}
+			for _, entry := range writtenConfig.ModelConfigList {
+				models[entry.Config.Name] = entry.Config.BasePath
+			}
+			if !reflect.DeepEqual(tc.expectedModels, models) {
+				t.Errorf("Expected the models %v in the config, got: %s", tc.expectedModels, string(writtenBytes))
+			}
+
+			_, err = os.Stat(generatedModelDir)
+			if tc.dirsCleared && !os.IsNotExist(err) {
+				t.Errorf("Expected the model dir to be cleared, got: %v", err)
+			}
+			if !tc.dirsCleared && err != nil {
+				t.Errorf("Expected the model dir to be kept, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestRuntimeStatusAfterEmptyReconcile(t *testing.T) {
+	const capacity = 1024 * 1024 * 1024
+	missingModelConfig := `{"model_config_list":[{"config":{"name":"missing-model","base_path":"` +
+		filepath.Join(testdataDir, "models", "missing-model") + `"}}]}`
+
+	testCases := []struct {
+		name            string
+		configContent   string // the config file is not created if empty
+		emptyFile       bool
+		reconcileEmpty  bool
+		emptyResponses  bool
+		expectedReloads int32
+	}{
+		{name: "no config file"},
+		{name: "no config file reconciled", reconcileEmpty: true, expectedReloads: 1},
+		{name: "empty config file", emptyFile: true},
+		{name: "empty config file reconciled", emptyFile: true, reconcileEmpty: true, expectedReloads: 1},
+		{name: "only models without files", configContent: missingModelConfig, expectedReloads: 1},
+		{name: "empty responses from OVMS", emptyFile: true, reconcileEmpty: true, emptyResponses: true, expectedReloads: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewMockOVMS()
+			defer m.Close()
+			if tc.emptyResponses {
+				m.reloadResponse = ""
+				m.configResponse = ""
+			}
+
+			configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+			if tc.emptyFile || tc.configContent != "" {
+				if err := os.WriteFile(configFile, []byte(tc.configContent), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{
+				ReconcileOnBoot:      true,
+				ReconcileEmptyConfig: tc.reconcileEmpty,
+			})
+			if err != nil {
+				t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+			}
+			s := &OvmsAdapterServer{
+				ModelManager: mm,
+				AdapterConfig: &AdapterConfiguration{
+					RootModelDir:      t.TempDir(),
+					StartupUnloadMode: StartupUnloadReconcile,
+					CapacityInBytes:   capacity,
+				},
+				Log: log,
+			}
+
+			statusResp, err := s.RuntimeStatus(context.Background(), &mmesh.RuntimeStatusRequest{})
+			if err != nil || statusResp.Status != mmesh.RuntimeStatusResponse_READY {
+				t.Fatalf("Expected the runtime to be ready, got %v: %v", statusResp, err)
+			}
+			if statusResp.CapacityInBytes != capacity {
+				t.Errorf("Expected the full capacity %d, got %d", capacity, statusResp.CapacityInBytes)
+			}
+			if reloads := m.getReloadCount(); reloads != tc.expectedReloads {
+				t.Errorf("Expected %d reloads of OVMS, got %d", tc.expectedReloads, reloads)
+			}
+			// the config that OVMS reads is valid and lists no models
+			if models, writtenBytes := readConfiguredModels(t, configFile); len(models) != 0 {
+				t.Errorf("Expected no models in the config, got: %s", string(writtenBytes))
+			}
+		})
+	}
+}
+
+func TestCapacitySubtractsMemoryUsage(t *testing.T) {
+	// the cgroup v2 of the runtime with 100MiB in use, of which 10MiB is
+	// reclaimable cache
+	cgroupDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cgroupDir, "memory.current"), []byte("104857600\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cgroupDir, "memory.stat"), []byte("anon 94371840\ninactive_file 10485760\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	capacity := 1024 * 1024 * 1024
+	for _, tc := range []struct {
+		cgroupDir string
+		expected  uint64
+	}{
+		{"", uint64(capacity)},
+		{cgroupDir, uint64(capacity - 90*1024*1024)},
+		// an unreadable cgroup falls back to the static capacity
+		{filepath.Join(cgroupDir, "missing"), uint64(capacity)},
+	} {
+		s := &OvmsAdapterServer{
+			AdapterConfig: &AdapterConfiguration{CapacityInBytes: capacity, RuntimeCgroupDir: tc.cgroupDir},
+			Log:           log,
+		}
+		if got := s.capacityInBytes(); got != tc.expected {
+			t.Errorf("Expected a capacity of %d with RuntimeCgroupDir=%q, got %d", tc.expected, tc.cgroupDir, got)
+		}
+	}
+}
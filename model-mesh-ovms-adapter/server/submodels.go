@@ -0,0 +1,245 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+// subModelManifest lists the models bundled in a ModelPath, which are loaded
+// as one model by ModelMesh, eg.
+// {"models": [{"name": "detector", "path": "detector"}, {"name": "classifier", "path": "classifier", "disk_size_bytes": 1048576}]}
+type subModelManifest struct {
+	Models []subModel `json:"models"`
+}
+
+type subModel struct {
+	// part of the name of the sub-model in the config, see subModelId
+	Name string `json:"name"`
+	// path of the model files relative to the ModelPath
+	Path string `json:"path"`
+	// size of the model files, measured on disk if not set
+	DiskSizeBytes *int64 `json:"disk_size_bytes,omitempty"`
+}
+
+// subModelId returns the id that the sub-model is registered with in OVMS
+//
+// Neither the model id nor the name contain the separator, so the id of a
+// sub-model cannot be the id of another model or of another sub-model.
+func subModelId(modelId, name string) string {
+	return modelId + subModelIdSeparator + name
+}
+
+// readSubModelManifest reads the manifest in the directory at modelPath,
+// which is nil if there is none
+func readSubModelManifest(modelPath string) (*subModelManifest, error) {
+	manifestPath, err := util.SecureJoin(modelPath, subModelManifestFilename)
+	if err != nil {
+		return nil, fmt.Errorf("Error joining path to the sub-model manifest: %w", err)
+	}
+	manifestBytes, err := os.ReadFile(manifestPath)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the sub-model manifest %s: %w", manifestPath, err)
+	}
+
+	var manifest subModelManifest
+	if err = json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, fmt.Errorf("Invalid sub-model manifest %s: %w", manifestPath, err)
+	}
+	if len(manifest.Models) == 0 {
+		return nil, fmt.Errorf("Invalid sub-model manifest %s: no models are listed", manifestPath)
+	}
+	names := make(map[string]struct{}, len(manifest.Models))
+	for _, m := range manifest.Models {
+		if m.Name == "" || m.Name != filepath.Base(m.Name) || m.Name == "." || m.Name == ".." || m.Name == subModelManifestFilename {
+			return nil, fmt.Errorf("Invalid sub-model manifest %s: invalid model name '%s'", manifestPath, m.Name)
+		}
+		if strings.Contains(m.Name, subModelIdSeparator) {
+			return nil, fmt.Errorf("Invalid sub-model manifest %s: model name '%s' contains the reserved '%s'", manifestPath, m.Name, subModelIdSeparator)
+		}
+		if _, ok := names[m.Name]; ok {
+			return nil, fmt.Errorf("Invalid sub-model manifest %s: duplicate model name '%s'", manifestPath, m.Name)
+		}
+		names[m.Name] = struct{}{}
+		if m.Path == "" {
+			return nil, fmt.Errorf("Invalid sub-model manifest %s: model '%s' has no path", manifestPath, m.Name)
+		}
+		if m.DiskSizeBytes != nil && *m.DiskSizeBytes < 0 {
+			return nil, fmt.Errorf("Invalid sub-model manifest %s: model '%s' has a negative disk_size_bytes", manifestPath, m.Name)
+		}
+	}
+	return &manifest, nil
+}
+
+// loadSubModels lays out and loads each model of the manifest under its
+// subModelId, and returns the sum of their sizes
+//
+// The layouts of the sub-models are created within the directory of the
+// model, together with a copy of the manifest to unload them. Each sub-model
+// is loaded once its layout is created, or with DeferReloadUntilStaged all of
+// them are loaded after the last layout. If any of the sub-models fails to
+// load, the others are unloaded again.
+func (s *OvmsAdapterServer) loadSubModels(ctx context.Context, req *mmesh.LoadModelRequest, modelType string, manifest *subModelManifest, pluginConfig, labels map[string]string, log logr.Logger) (uint64, error) {
+	parentDir, err := util.SecureJoin(s.modelRootDir(modelType), req.ModelId)
+	if err != nil {
+		return 0, err
+	}
+	if err = os.RemoveAll(parentDir); err != nil {
+		log.Info("Ignoring error trying to remove dir", "Directory", parentDir, "Error", err)
+	}
+	if err = os.MkdirAll(parentDir, 0755); err != nil {
+		return 0, fmt.Errorf("Error creating directories for path %s: %w", parentDir, err)
+	}
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return 0, fmt.Errorf("Error marshalling the sub-model manifest: %w", err)
+	}
+	if err = util.WriteFileAtomic(filepath.Join(parentDir, subModelManifestFilename), manifestBytes, 0644, s.AdapterConfig.FsyncPolicy); err != nil {
+		return 0, fmt.Errorf("Error writing the sub-model manifest: %w", err)
+	}
+
+	// with DeferReloadUntilStaged, the sub-models are only loaded once all of
+	// them are staged, so that OVMS is reloaded once for the model
+	var batch *LoadBatch
+	if s.AdapterConfig.DeferReloadUntilStaged {
+		batch = s.ModelManager.NewLoadBatch()
+	}
+
+	multiplier := s.modelSizeMultiplier(modelType, log)
+	sizes := make([]uint64, len(manifest.Models))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, m := range manifest.Models {
+		i, m := i, m
+		g.Go(func() error {
+			id := subModelId(req.ModelId, m.Name)
+			subPath, err := util.SecureJoin(req.ModelPath, m.Path)
+			if err != nil {
+				return fmt.Errorf("Invalid path of the sub-model %s: %w", m.Name, err)
+			}
+			err = util.RetryTransientFileErrors(gctx, s.AdapterConfig.LayoutRetries, s.AdapterConfig.LayoutRetryBackoff, log, func() error {
+				return adaptModelLayoutForRuntime(gctx, parentDir, m.Name, modelType, subPath, "", s.AdapterConfig.ModelFilePlacement, s.AdapterConfig.ModelSymlinkPolicy, s.AdapterConfig.VerifyStagedFiles, s.AdapterConfig.DeferReloadUntilStaged, log)
+			})
+			if err != nil {
+				return fmt.Errorf("Error creating the layout of the sub-model %s: %w", m.Name, err)
+			}
+			if batch != nil {
+				err = batch.Add(filepath.Join(parentDir, m.Name), id, modelType, "", pluginConfig, labels)
+			} else {
+				err = s.ModelManager.LoadModel(gctx, filepath.Join(parentDir, m.Name), id, modelType, "", pluginConfig, labels)
+			}
+			if err != nil {
+				return fmt.Errorf("Error loading the sub-model %s: %w", m.Name, err)
+			}
+			sizes[i] = s.subModelSize(m, subPath, multiplier, log)
+			return nil
+		})
+	}
+	err = g.Wait()
+	if err == nil && batch != nil {
+		if err = batch.Commit(ctx); err != nil {
+			err = fmt.Errorf("Error loading the sub-models: %w", err)
+		}
+	}
+	if err != nil {
+		if unloadErr := s.unloadSubModels(ctx, manifest, req.ModelId); unloadErr != nil {
+			log.Error(unloadErr, "Failed to unload the sub-models after a failed load")
+		}
+		return 0, err
+	}
+
+	for i, m := range manifest.Models {
+		log.Info("OVMS sub-model loaded", "sub_model_id", subModelId(req.ModelId, m.Name), "sizeInBytes", sizes[i])
+	}
+
+	var size uint64
+	for _, subSize := range sizes {
+		size += subSize
+	}
+	return size, nil
+}
+
+// subModelSize estimates the size of a loaded sub-model from its disk size
+// in the manifest or on disk, multiplied by the multiplier of its model type
+func (s *OvmsAdapterServer) subModelSize(m subModel, subPath string, multiplier float64, log logr.Logger) uint64 {
+	var diskSize int64
+	if m.DiskSizeBytes != nil {
+		diskSize = *m.DiskSizeBytes
+	} else {
+		err := filepath.WalkDir(subPath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			diskSize += info.Size()
+			return nil
+		})
+		if err != nil {
+			log.Info("Defaulting the size of the sub-model, its disk size could not be measured", "sub_model", m.Name, "error", err)
+			return uint64(s.AdapterConfig.DefaultModelSizeInBytes)
+		}
+	}
+	return uint64(float64(diskSize) * multiplier)
+}
+
+// loadedSubModels reads the manifest that loadSubModels copied into the
+// directory of the model in any of the root dirs, which is nil if the model
+// has no sub-models
+func (s *OvmsAdapterServer) loadedSubModels(modelId string) (*subModelManifest, error) {
+	for _, dir := range s.modelRootDirs() {
+		parentDir, err := util.SecureJoin(dir, modelId)
+		if err != nil {
+			return nil, err
+		}
+		if manifest, err := readSubModelManifest(parentDir); manifest != nil || err != nil {
+			return manifest, err
+		}
+	}
+	return nil, nil
+}
+
+// unloadSubModels unloads each model of the manifest, a sub-model that OVMS
+// does not have is skipped
+func (s *OvmsAdapterServer) unloadSubModels(ctx context.Context, manifest *subModelManifest, modelId string) error {
+	for _, m := range manifest.Models {
+		if err := s.ModelManager.UnloadModel(ctx, subModelId(modelId, m.Name)); err != nil {
+			if grpcStatus, ok := status.FromError(err); !ok || grpcStatus.Code() != codes.NotFound {
+				return err
+			}
+		}
+	}
+	return nil
+}
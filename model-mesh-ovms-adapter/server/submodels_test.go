@@ -0,0 +1,276 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+func TestLoadSubModels(t *testing.T) {
+	const modelId = "bundle"
+	m := NewMockOVMS()
+	defer m.Close()
+	available := OvmsModelStatusResponse{
+		ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+	}
+	if err := m.setMockReloadResponse(OvmsConfigResponse{
+		subModelId(modelId, "detector"):   available,
+		subModelId(modelId, "classifier"): available,
+	}, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+
+	// a bundle with the detector measured on disk and the classifier sized
+	// by the manifest
+	modelPath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(modelPath, "detector", "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(modelPath, "detector", "1", "model.onnx"), make([]byte, 1000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(modelPath, "classifier"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(modelPath, "classifier", "model.onnx"), make([]byte, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"models": [
+		{"name": "detector", "path": "detector"},
+		{"name": "classifier", "path": "classifier", "disk_size_bytes": 3000}
+	]}`
+	if err := os.WriteFile(filepath.Join(modelPath, subModelManifestFilename), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{BatchWaitTimeMax: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+	rootModelDir := t.TempDir()
+	s := &OvmsAdapterServer{
+		ModelManager: mm,
+		AdapterConfig: &AdapterConfiguration{
+			RootModelDir:        rootModelDir,
+			ModelSizeMultiplier: 2,
+			LoadSubModels:       true,
+		},
+		Log: log,
+	}
+
+	resp, err := s.LoadModel(context.Background(), &mmesh.LoadModelRequest{
+		ModelId:   modelId,
+		ModelPath: modelPath,
+		ModelType: "onnx",
+		ModelKey:  `{"model_type": {"name": "onnx"}}`,
+	})
+	if err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+	if expected := uint64(2*1000 + 2*3000); resp.SizeInBytes != expected {
+		t.Errorf("Expected the summed size %d of the sub-models, got %d", expected, resp.SizeInBytes)
+	}
+
+	models, writtenBytes := readConfiguredModels(t, configFile)
+	expectedModels := map[string]string{
+		subModelId(modelId, "detector"):   filepath.Join(rootModelDir, modelId, "detector"),
+		subModelId(modelId, "classifier"): filepath.Join(rootModelDir, modelId, "classifier"),
+	}
+	if !reflect.DeepEqual(expectedModels, models) {
+		t.Errorf("Expected the sub-models %v in the config, got: %s", expectedModels, string(writtenBytes))
+	}
+
+	// unloading the model unloads both sub-models
+	if _, err = s.UnloadModel(context.Background(), &mmesh.UnloadModelRequest{ModelId: modelId}); err != nil {
+		t.Fatalf("UnloadModel call failed: %v", err)
+	}
+	// the unloads are written to the config with the next reload
+	deadline := time.Now().Add(2 * time.Second)
+	models, writtenBytes = readConfiguredModels(t, configFile)
+	for len(models) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		models, writtenBytes = readConfiguredModels(t, configFile)
+	}
+	if len(models) > 0 {
+		t.Errorf("Expected the sub-models to be unloaded, got: %s", string(writtenBytes))
+	}
+	if _, err = os.Stat(filepath.Join(rootModelDir, modelId)); !os.IsNotExist(err) {
+		t.Errorf("Expected the model dir to be removed, got: %v", err)
+	}
+}
+
+func TestLoadSubModelsBatched(t *testing.T) {
+	const modelId = "bundle"
+	m := NewMockOVMS()
+	defer m.Close()
+	available := OvmsModelStatusResponse{
+		ModelVersionStatus: []OvmsModelVersionStatus{{State: "AVAILABLE"}},
+	}
+	names := []string{"detector", "classifier", "embedder"}
+	reloadResponse := OvmsConfigResponse{}
+	manifest := subModelManifest{}
+	modelPath := t.TempDir()
+	for _, name := range names {
+		reloadResponse[subModelId(modelId, name)] = available
+		manifest.Models = append(manifest.Models, subModel{Name: name, Path: name})
+		for _, version := range []string{"1", "2"} {
+			if err := os.MkdirAll(filepath.Join(modelPath, name, version), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(modelPath, name, version, "model.onnx"), make([]byte, 100), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := m.setMockReloadResponse(reloadResponse, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(modelPath, subModelManifestFilename), manifestBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the layouts of the sub-models are staged one after the other, so that
+	// each sub-model would be loaded with its own reload
+	defer func(f func(string, string, string, util.FilePlacement, util.SymlinkPolicy) error) { placeFile = f }(placeFile)
+	placeFile = func(root, src, dst string, placement util.FilePlacement, symlinkPolicy util.SymlinkPolicy) error {
+		for i, name := range names {
+			if filepath.Base(filepath.Dir(dst)) == name {
+				time.Sleep(time.Duration(i) * 100 * time.Millisecond)
+			}
+		}
+		return util.PlaceFile(root, src, dst, placement, symlinkPolicy)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "model_config_list.json")
+	mm, err := NewOvmsModelManager(m.GetAddress(), configFile, log, ModelManagerConfig{
+		BatchWaitTimeMin: 10 * time.Millisecond,
+		BatchWaitTimeMax: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create ModelManager with Mock: %v", err)
+	}
+	rootModelDir := t.TempDir()
+	s := &OvmsAdapterServer{
+		ModelManager: mm,
+		AdapterConfig: &AdapterConfiguration{
+			RootModelDir:           rootModelDir,
+			ModelSizeMultiplier:    1,
+			LoadSubModels:          true,
+			DeferReloadUntilStaged: true,
+		},
+		Log: log,
+	}
+
+	resp, err := s.LoadModel(context.Background(), &mmesh.LoadModelRequest{
+		ModelId:   modelId,
+		ModelPath: modelPath,
+		ModelType: "onnx",
+		ModelKey:  `{"model_type": {"name": "onnx"}}`,
+	})
+	if err != nil {
+		t.Fatalf("LoadModel call failed: %v", err)
+	}
+	if expected := uint64(len(names) * 2 * 100); resp.SizeInBytes != expected {
+		t.Errorf("Expected the summed size %d of the sub-models, got %d", expected, resp.SizeInBytes)
+	}
+	if count := m.getReloadCount(); count != 1 {
+		t.Errorf("Expected the sub-models to be loaded with 1 reload, but got %d", count)
+	}
+	if models, writtenBytes := readConfiguredModels(t, configFile); len(models) != len(names) {
+		t.Errorf("Expected the sub-models %v in the config, got: %s", names, string(writtenBytes))
+	}
+}
+
+// readConfiguredModels returns the base paths of the models in the config
+// file by their name
+func readConfiguredModels(t *testing.T, configFile string) (map[string]string, []byte) {
+	writtenBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Unable to read config file: %v", err)
+	}
+	var writtenConfig OvmsMultiModelRepositoryConfig
+	if err = json.Unmarshal(writtenBytes, &writtenConfig); err != nil {
+		t.Fatalf("Unable to parse config file: %v", err)
+	}
+	models := map[string]string{}
+	for _, entry := range writtenConfig.ModelConfigList {
+		models[entry.Config.Name] = entry.Config.BasePath
+	}
+	return models, writtenBytes
+}
+
+func TestLoadSubModelsReservedModelId(t *testing.T) {
+	s := &OvmsAdapterServer{
+		AdapterConfig: &AdapterConfiguration{
+			RootModelDir:        t.TempDir(),
+			ModelSizeMultiplier: 1,
+			LoadSubModels:       true,
+		},
+		Log: log,
+	}
+
+	// the id of the model could be the id of the sub-model "detector" of the
+	// model "bundle"
+	_, err := s.LoadModel(context.Background(), &mmesh.LoadModelRequest{
+		ModelId:   subModelId("bundle", "detector"),
+		ModelPath: t.TempDir(),
+		ModelType: "onnx",
+		ModelKey:  `{"model_type": {"name": "onnx"}}`,
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected the load to fail with InvalidArgument, got: %v", err)
+	}
+}
+
+func TestReadSubModelManifest(t *testing.T) {
+	modelPath := t.TempDir()
+	if manifest, err := readSubModelManifest(modelPath); manifest != nil || err != nil {
+		t.Errorf("Expected no manifest without the file, got %v (error: %v)", manifest, err)
+	}
+
+	for _, manifest := range []string{
+		`{"models": []}`,
+		`{"models": [{"name": "a", "path": "a"}, {"name": "a", "path": "b"}]}`,
+		`{"models": [{"name": "../a", "path": "a"}]}`,
+		`{"models": [{"name": "a:b", "path": "a"}]}`,
+		`{"models": [{"name": "a"}]}`,
+		`{"models": [{"name": "a", "path": "a", "disk_size_bytes": -1}]}`,
+	} {
+		if err := os.WriteFile(filepath.Join(modelPath, subModelManifestFilename), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readSubModelManifest(modelPath); err == nil {
+			t.Errorf("Expected an error reading the manifest %s", manifest)
+		}
+	}
+}
@@ -52,6 +52,16 @@ const (
 	torchServeModelStoreDirName           string = "_torchserve_models"
 	useEmbeddedPuller                     string = "USE_EMBEDDED_PULLER"
 	defaultUseEmbeddedPuller                     = false
+	strictModelKey                        string = "STRICT_MODEL_KEY"
+	defaultStrictModelKey                        = false
+	modelKeyMaxSize                       string = "MODEL_KEY_MAX_SIZE"
+	defaultModelKeyMaxSize                       = 0 // 0 means the size of the ModelKey is not limited
+	grpcReflection                        string = "GRPC_REFLECTION"
+	defaultGrpcReflection                        = false
+	grpcCompression                       string = "GRPC_COMPRESSION"
+	defaultGrpcCompression                       = false
+	metricsPort                           string = "METRICS_PORT"
+	defaultMetricsPort                           = 0 // 0 means the metrics are not served
 
 	// TorchServe specific
 	requestBatchSize         string = "REQUEST_BATCH_SIZE"
@@ -75,6 +85,11 @@ func GetAdapterConfigurationFromEnv(log logr.Logger) (*AdapterConfiguration, err
 	adapterConfig.RuntimeVersion = GetEnvString(runtimeVersion, defaultRuntimeVersion)
 	adapterConfig.LimitModelConcurrency = GetEnvInt(limitPerModelConcurrency, defaultLimitPerModelConcurrency, log)
 	adapterConfig.UseEmbeddedPuller = GetEnvBool(useEmbeddedPuller, defaultUseEmbeddedPuller, log)
+	adapterConfig.StrictModelKey = GetEnvBool(strictModelKey, defaultStrictModelKey, log)
+	adapterConfig.ModelKeyMaxSize = GetEnvInt(modelKeyMaxSize, defaultModelKeyMaxSize, log)
+	adapterConfig.GrpcReflection = GetEnvBool(grpcReflection, defaultGrpcReflection, log)
+	adapterConfig.GrpcCompression = GetEnvBool(grpcCompression, defaultGrpcCompression, log)
+	adapterConfig.MetricsPort = GetEnvInt(metricsPort, defaultMetricsPort, log)
 
 	var err error
 	adapterConfig.ModelStoreDir, err = util.SecureJoin(GetEnvString(rootModelDir, defaultRootModelDir), torchServeModelStoreDirName)
@@ -93,5 +108,11 @@ func GetAdapterConfigurationFromEnv(log logr.Logger) (*AdapterConfiguration, err
 		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v",
 			modelSizeMultiplier, adapterConfig.ModelSizeMultiplier)
 	}
+	if adapterConfig.ModelKeyMaxSize < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", modelKeyMaxSize, adapterConfig.ModelKeyMaxSize)
+	}
+	if adapterConfig.MetricsPort < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", metricsPort, adapterConfig.MetricsPort)
+	}
 	return adapterConfig, nil
 }
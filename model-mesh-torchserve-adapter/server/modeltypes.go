@@ -0,0 +1,51 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+// name of the runtime in the RuntimeInfo
+const torchserveRuntimeName = "torchserve"
+
+// supportedModelTypes are the model types that TorchServe can load, which is
+// only a model archive (MAR) file, or a directory containing one
+var supportedModelTypes = []util.ModelTypeInfo{
+	{Name: "pytorch-mar", Files: []string{"*.mar"}, SingleFile: true},
+}
+
+// SupportedModelTypes returns the runtime and the model types of the adapter
+func (s *TorchServeAdapterServer) SupportedModelTypes() util.SupportedModelTypes {
+	runtime := util.RuntimeInfo{Name: torchserveRuntimeName}
+	if s.AdapterConfig != nil {
+		runtime.Version = s.AdapterConfig.RuntimeVersion
+	}
+	return util.SupportedModelTypes{Runtime: runtime, ModelTypes: util.CopyModelTypes(supportedModelTypes)}
+}
+
+// RegisterModelTypesServer registers the mmesh.ModelTypes gRPC service
+func (s *TorchServeAdapterServer) RegisterModelTypesServer(grpcServer *grpc.Server) {
+	util.RegisterModelTypesServer(grpcServer, s.SupportedModelTypes)
+}
+
+// ModelTypesHandler serves the SupportedModelTypes as JSON
+func (s *TorchServeAdapterServer) ModelTypesHandler() http.Handler {
+	return util.ModelTypesHandler(s.SupportedModelTypes)
+}
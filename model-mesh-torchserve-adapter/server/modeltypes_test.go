@@ -0,0 +1,33 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+func TestSupportedModelTypes(t *testing.T) {
+	s := &TorchServeAdapterServer{AdapterConfig: &AdapterConfiguration{RuntimeVersion: "0.7.1"}}
+	expected := util.SupportedModelTypes{
+		Runtime:    util.RuntimeInfo{Name: "torchserve", Version: "0.7.1"},
+		ModelTypes: []util.ModelTypeInfo{{Name: "pytorch-mar", Files: []string{"*.mar"}, SingleFile: true}},
+	}
+	if types := s.SupportedModelTypes(); !reflect.DeepEqual(expected, types) {
+		t.Errorf("Expected the supported model types %+v but got %+v", expected, types)
+	}
+}
@@ -64,6 +64,11 @@ type AdapterConfiguration struct {
 	LimitModelConcurrency          int // 0 means no limit (default)
 	ModelStoreDir                  string
 	UseEmbeddedPuller              bool
+	StrictModelKey                 bool
+	ModelKeyMaxSize                int
+	GrpcReflection                 bool
+	GrpcCompression                bool
+	MetricsPort                    int // 0 means the metrics are not served
 	RequestBatchSize               int32
 	MaxBatchDelaySecs              int32
 }
@@ -158,6 +163,17 @@ job_queue_size=512
 
 func (s *TorchServeAdapterServer) LoadModel(ctx context.Context, req *mmesh.LoadModelRequest) (*mmesh.LoadModelResponse, error) {
 	log := s.Log.WithName("LoadModel").WithValues("modelId", req.ModelId)
+	if err := util.CheckModelKeyLimits(req, s.AdapterConfig.ModelKeyMaxSize); err != nil {
+		log.Error(err, "ModelKey exceeds the limits")
+		return nil, err
+	}
+	if s.AdapterConfig.StrictModelKey {
+		if err := util.ValidateModelKey(req); err != nil {
+			log.Error(err, "Invalid ModelKey")
+			return nil, err
+		}
+	}
+
 	modelType := util.GetModelType(req, log)
 	log.Info("Using model type", "modelType", modelType)
 
@@ -17,9 +17,11 @@ package main
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 
 	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 	"github.com/kserve/modelmesh-runtime-adapter/model-mesh-triton-adapter/server"
 	"google.golang.org/grpc"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -36,7 +38,9 @@ func main() {
 	log.Info("Starting Triton Adapter Server", "adapter_config", adapterConfig)
 
 	TAServer := server.NewTritonAdapterServer(adapterConfig.TritonPort, adapterConfig, log)
-	defer TAServer.Conn.Close()
+	if TAServer.Conn != nil {
+		defer TAServer.Conn.Close()
+	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", adapterConfig.Port))
 	if err != nil {
@@ -46,8 +50,25 @@ func main() {
 
 	log.Info("Adapter will run at port", "port", adapterConfig.Port, "Triton port", adapterConfig.TritonPort)
 
-	grpcServer := grpc.NewServer()
+	// the metrics of the embedded puller and the supported model types
+	if adapterConfig.MetricsPort > 0 {
+		mux := http.NewServeMux()
+		if TAServer.Puller != nil {
+			mux.Handle("/metrics", TAServer.Puller.MetricsHandler())
+		}
+		mux.Handle("/v1/model-types", TAServer.ModelTypesHandler())
+		go func() {
+			log.Info("Serving metrics", "port", adapterConfig.MetricsPort)
+			if err := http.ListenAndServe(fmt.Sprintf(":%d", adapterConfig.MetricsPort), mux); err != nil {
+				log.Error(err, "*** Metrics server terminated with error")
+			}
+		}()
+	}
+
+	grpcServer := grpc.NewServer(util.GrpcCompressionServerOptions(adapterConfig.GrpcCompression, log)...)
 	mmesh.RegisterModelRuntimeServer(grpcServer, TAServer)
+	TAServer.RegisterModelTypesServer(grpcServer)
+	util.RegisterReflection(grpcServer, adapterConfig.GrpcReflection, log)
 	log.Info("Adapter gRPC Server registered, now serving")
 
 	if err = grpcServer.Serve(lis); err != nil {
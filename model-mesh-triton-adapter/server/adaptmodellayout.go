@@ -20,12 +20,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
 
+	"github.com/kserve/modelmesh-runtime-adapter/internal/modelkey"
 	"github.com/kserve/modelmesh-runtime-adapter/internal/modelschema"
 	triton "github.com/kserve/modelmesh-runtime-adapter/internal/proto/triton"
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
@@ -49,6 +53,18 @@ var modelTypeToBackendMapping = map[string]string{
 	"keras":      "tensorflow",
 }
 
+// gpuExecutionAcceleratorMapping and cpuExecutionAcceleratorMapping contain the
+// execution accelerators that each backend supports, which may be given in the
+// optimization of the ModelKey
+var gpuExecutionAcceleratorMapping = map[string][]string{
+	"tensorflow":  {"tensorrt", "auto_mixed_precision", "gpu_io"},
+	"onnxruntime": {"tensorrt", "cuda"},
+}
+
+var cpuExecutionAcceleratorMapping = map[string][]string{
+	"onnxruntime": {"openvino"},
+}
+
 var modelTypeToFileNameMapping = map[string]string{
 	"tensorflow": "model.graphdef",
 	"tensorrt":   "model.plan",
@@ -56,7 +72,7 @@ var modelTypeToFileNameMapping = map[string]string{
 	"pytorch":    "model.pt",
 }
 
-func adaptModelLayoutForRuntime(ctx context.Context, rootModelDir, modelID, modelType, modelPath, schemaPath string, log logr.Logger) error {
+func adaptModelLayoutForRuntime(ctx context.Context, rootModelDir, modelID, modelType, modelPath, schemaPath string, versionPolicy *modelkey.VersionPolicy, keyConfig, configTemplate *triton.ModelConfig, log logr.Logger) error {
 	// convert to lower case and remove anything after the :
 	modelType = strings.ToLower(strings.Split(modelType, ":")[0])
 
@@ -98,7 +114,7 @@ func adaptModelLayoutForRuntime(ctx context.Context, rootModelDir, modelID, mode
 
 	if !modelPathInfo.IsDir() {
 		// simple case if ModelPath points to a file
-		err = createTritonModelRepositoryFromPath(modelPath, "1", schemaPath, modelType, tritonModelIDDir, log)
+		err = createTritonModelRepositoryFromPath(modelPath, "1", schemaPath, modelType, keyConfig, configTemplate, tritonModelIDDir, log)
 	} else {
 		files, err1 := os.ReadDir(modelPath)
 		if err1 != nil {
@@ -106,9 +122,14 @@ func adaptModelLayoutForRuntime(ctx context.Context, rootModelDir, modelID, mode
 		}
 
 		if isTritonModelRepository(files) {
+			// the model's own config.pbtxt wins over the config from the
+			// ModelKey
+			if keyConfig != nil {
+				log.Info("Ignoring the model config from the ModelKey, the model has its own config", "config", tritonRepositoryConfigFilename)
+			}
 			err = adaptNativeModelLayout(files, modelPath, schemaPath, tritonModelIDDir, log)
 		} else {
-			err = createTritonModelRepositoryFromDirectory(files, modelPath, schemaPath, modelType, tritonModelIDDir, log)
+			err = createTritonModelRepositoryFromDirectory(files, modelPath, schemaPath, modelType, versionPolicy, keyConfig, configTemplate, tritonModelIDDir, log)
 		}
 	}
 	if err != nil {
@@ -121,7 +142,10 @@ func adaptModelLayoutForRuntime(ctx context.Context, rootModelDir, modelID, mode
 // Creates the triton model structure /models/_triton_models/model-id/1/model.X where
 // model.X is a file or directory with a name defined by the model type (see modelTypeToDirNameMapping and modelTypeToFileNameMapping).
 // Within this path there will be a symlink back to the original /models/model-id directory tree.
-func createTritonModelRepositoryFromDirectory(files []os.DirEntry, modelPath, schemaPath, modelType, tritonModelIDDir string, log logr.Logger) error {
+//
+// If the directory contains version directories, each of them is staged and
+// the version policy selects which ones Triton serves, see getTritonVersionPolicy.
+func createTritonModelRepositoryFromDirectory(files []os.DirEntry, modelPath, schemaPath, modelType string, versionPolicy *modelkey.VersionPolicy, keyConfig, configTemplate *triton.ModelConfig, tritonModelIDDir string, log logr.Logger) error {
 	var err error
 
 	// for backwards compatibility, remove any file called _schema.json from
@@ -129,25 +153,51 @@ func createTritonModelRepositoryFromDirectory(files []os.DirEntry, modelPath, sc
 	_, files = util.RemoveFileFromListOfFileInfo(modelschema.ModelSchemaFile, files)
 
 	// allow the directory to contain version directories
-	// try to find the largest version directory
-	versionNumber := largestNumberDir(files)
-	if versionNumber != "" {
+	versions := numberDirs(files)
+	modelFilename := keyConfig.GetDefaultModelFilename()
+	if len(versions) == 0 {
+		if err = linkModelVersion(files, modelPath, "1", modelType, modelFilename, tritonModelIDDir, log); err != nil {
+			return err
+		}
+		return writeGeneratedModelConfig(schemaPath, modelType, nil, keyConfig, configTemplate, tritonModelIDDir, log)
+	}
+
+	for _, versionNumber := range versions {
 		// found a version directory so step into it
-		if modelPath, err = util.SecureJoin(modelPath, versionNumber); err != nil {
-			log.Error(err, "Unable to securely join", "modelPath", modelPath, "versionNumber", versionNumber)
+		versionPath, jerr := util.SecureJoin(modelPath, versionNumber)
+		if jerr != nil {
+			log.Error(jerr, "Unable to securely join", "modelPath", modelPath, "versionNumber", versionNumber)
+			return jerr
+		}
+
+		versionFiles, rerr := os.ReadDir(versionPath)
+		if rerr != nil {
+			return fmt.Errorf("Could not read files in dir %s: %w", versionPath, rerr)
+		}
+
+		if err = linkModelVersion(versionFiles, versionPath, versionNumber, modelType, modelFilename, tritonModelIDDir, log); err != nil {
 			return err
 		}
+	}
 
-		if files, err = os.ReadDir(modelPath); err != nil {
-			return fmt.Errorf("Could not read files in dir %s: %w", modelPath, err)
+	// Triton serves the latest version by default, so the policy is only
+	// needed in the config if one is requested or the config is written anyway
+	var tritonVersionPolicy *triton.ModelVersionPolicy
+	if versionPolicy != nil || (schemaPath != "" && len(versions) > 1) {
+		if tritonVersionPolicy, err = getTritonVersionPolicy(versionPolicy, versions); err != nil {
+			return err
 		}
-	} else {
-		versionNumber = "1"
 	}
 
+	return writeGeneratedModelConfig(schemaPath, modelType, tritonVersionPolicy, keyConfig, configTemplate, tritonModelIDDir, log)
+}
+
+// linkModelVersion stages the model files of a single version
+func linkModelVersion(files []os.DirEntry, modelPath, versionNumber, modelType, modelFilename, tritonModelIDDir string, log logr.Logger) error {
 	// for backwards compatibility, special handling for known model types
-	// with a directory with a single entry
-	if len(files) == 1 {
+	// with a directory with a single entry, which is renamed unless the
+	// config names the model file
+	if len(files) == 1 && modelFilename == "" {
 		_, okd := modelTypeToDirNameMapping[modelType]
 		_, okf := modelTypeToFileNameMapping[modelType]
 		if okd || okf {
@@ -160,10 +210,22 @@ func createTritonModelRepositoryFromDirectory(files []os.DirEntry, modelPath, sc
 		}
 	}
 
-	return createTritonModelRepositoryFromPath(modelPath, versionNumber, schemaPath, modelType, tritonModelIDDir, log)
+	return linkModelPath(modelPath, versionNumber, modelType, modelFilename, tritonModelIDDir)
+}
+
+func createTritonModelRepositoryFromPath(modelPath, versionNumber, schemaPath, modelType string, keyConfig, configTemplate *triton.ModelConfig, tritonModelIDDir string, log logr.Logger) error {
+	if err := linkModelPath(modelPath, versionNumber, modelType, keyConfig.GetDefaultModelFilename(), tritonModelIDDir); err != nil {
+		return err
+	}
+	return writeGeneratedModelConfig(schemaPath, modelType, nil, keyConfig, configTemplate, tritonModelIDDir, log)
 }
 
-func createTritonModelRepositoryFromPath(modelPath, versionNumber, schemaPath, modelType, tritonModelIDDir string, log logr.Logger) error {
+// linkModelPath links the model file or directory into the version directory
+//
+// If modelFilename is set, the default_model_filename of the config points at
+// the model file, so the files keep their names and the model file must be
+// one of them.
+func linkModelPath(modelPath, versionNumber, modelType, modelFilename, tritonModelIDDir string) error {
 	var err error
 
 	modelPathInfo, err := os.Stat(modelPath)
@@ -172,7 +234,23 @@ func createTritonModelRepositoryFromPath(modelPath, versionNumber, schemaPath, m
 	}
 
 	var linkPath string
-	if modelPathInfo.IsDir() {
+	if modelFilename != "" {
+		if modelPathInfo.IsDir() {
+			exists, existsErr := util.FileExists(filepath.Join(modelPath, modelFilename))
+			if existsErr != nil {
+				return fmt.Errorf("Error checking for the model file %s: %w", modelFilename, existsErr)
+			}
+			if !exists {
+				return fmt.Errorf("The model file %s from the ModelKey %s was not found in version %s", modelFilename, modelkey.ModelFilenameKey, versionNumber)
+			}
+			linkPath = versionNumber
+		} else {
+			if filepath.Base(modelPath) != modelFilename {
+				return fmt.Errorf("The model file %s from the ModelKey %s does not match the model path %s", modelFilename, modelkey.ModelFilenameKey, filepath.Base(modelPath))
+			}
+			linkPath = filepath.Join(versionNumber, modelFilename)
+		}
+	} else if modelPathInfo.IsDir() {
 		// if there is a known directory name for the model type, use
 		// it, otherwise use the directory's contents as the model data
 		if dirName, ok := modelTypeToDirNameMapping[modelType]; ok {
@@ -203,33 +281,288 @@ func createTritonModelRepositoryFromPath(modelPath, versionNumber, schemaPath, m
 		return fmt.Errorf("Error creating symlink: %w", err)
 	}
 
-	// if schemaPath doesn't exist don't write out a config.pbtxt
-	if schemaPath == "" {
+	return nil
+}
+
+// writeGeneratedModelConfig writes a config.pbtxt from the schema, the
+// version policy, the config from the ModelKey and the config template, if
+// any of them is given
+//
+// The config template has the defaults of the config, the backend of the
+// model type, the version policy and the schema take precedence over it, and
+// the config from the ModelKey takes precedence over all of them. The
+// parameters are merged by name.
+func writeGeneratedModelConfig(schemaPath, modelType string, versionPolicy *triton.ModelVersionPolicy, keyConfig, configTemplate *triton.ModelConfig, tritonModelIDDir string, log logr.Logger) error {
+	if schemaPath == "" && versionPolicy == nil && keyConfig == nil && configTemplate == nil {
 		return nil
 	}
 
-	sm, err := convertSchemaToConfigFromFile(schemaPath, log)
-	if err != nil {
-		return err
+	m := &triton.ModelConfig{}
+	if configTemplate != nil {
+		m = proto.Clone(configTemplate).(*triton.ModelConfig)
 	}
-
-	m := triton.ModelConfig{
-		Backend: modelTypeToBackendMapping[modelType],
-		Input:   sm.Input,
-		Output:  sm.Output,
+	if backend, ok := modelTypeToBackendMapping[modelType]; ok {
+		m.Backend = backend
+	}
+	if versionPolicy != nil {
+		m.VersionPolicy = versionPolicy
+	}
+	if keyConfig != nil {
+		if keyConfig.Backend != "" {
+			m.Backend = keyConfig.Backend
+		}
+		if len(keyConfig.Parameters) > 0 && m.Parameters == nil {
+			m.Parameters = make(map[string]*triton.ModelParameter, len(keyConfig.Parameters))
+		}
+		for name, value := range keyConfig.Parameters {
+			m.Parameters[name] = value
+		}
+		if keyConfig.SchedulingChoice != nil {
+			m.SchedulingChoice = keyConfig.SchedulingChoice
+		}
+		if len(keyConfig.InstanceGroup) > 0 {
+			m.InstanceGroup = keyConfig.InstanceGroup
+		}
+		if keyConfig.DefaultModelFilename != "" {
+			m.DefaultModelFilename = keyConfig.DefaultModelFilename
+		}
+		if keyConfig.Optimization != nil {
+			m.Optimization = keyConfig.Optimization
+		}
+	}
+	if schemaPath != "" {
+		sm, err := convertSchemaToConfigFromFile(schemaPath, log)
+		if err != nil {
+			return err
+		}
+		// the schema has the batch dimension, which Triton adds to the
+		// dims if the config template sets a max_batch_size, see
+		// processModelConfig
+		if m.MaxBatchSize > 0 {
+			if !allInputsAndOuputsHaveBatchDimension(sm) {
+				return errors.New("Conflicting model configuration: If model has schema and the config template has max_batch_size > 0, then the first dimension of all inputs and outputs must have size -1.")
+			}
+			removeFirstDimensionFromInputsAndOutputs(sm)
+		}
+		m.Input = sm.Input
+		m.Output = sm.Output
 	}
 
 	configFile, err := util.SecureJoin(tritonModelIDDir, tritonRepositoryConfigFilename)
 	if err != nil {
 		return fmt.Errorf("Error joining path to config file: %w", err)
 	}
-	if err = writeConfigPbtxt(configFile, &m); err != nil {
-		return err
+	return writeConfigPbtxt(configFile, m)
+}
+
+// readModelConfigTemplate reads the config template from a config.pbtxt
+// file, which has the defaults of the configs that the adapter generates
+//
+// The name and the default_model_filename are set per model, so they must
+// not be in the template.
+func readModelConfigTemplate(templateFile string) (*triton.ModelConfig, error) {
+	pbtxt, err := os.ReadFile(templateFile)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading config template %s: %w", templateFile, err)
+	}
+	m := &triton.ModelConfig{}
+	if err = prototext.Unmarshal(pbtxt, m); err != nil {
+		return nil, fmt.Errorf("Error parsing config template %s: %w", templateFile, err)
+	}
+	if m.Name != "" {
+		return nil, fmt.Errorf("Config template %s must not set the name, which Triton takes from the model directory", templateFile)
+	}
+	if m.DefaultModelFilename != "" {
+		return nil, fmt.Errorf("Config template %s must not set the default_model_filename, which is set per model with the ModelKey %s", templateFile, modelkey.ModelFilenameKey)
+	}
+	return m, nil
+}
+
+// getTritonVersionPolicy converts the version policy from the ModelKey to the
+// Triton config, defaulting to the latest version
+//
+// A specific version that is not one of the staged versions is an error
+// because Triton would fail to load it.
+func getTritonVersionPolicy(versionPolicy *modelkey.VersionPolicy, versions []string) (*triton.ModelVersionPolicy, error) {
+	if versionPolicy == nil {
+		versionPolicy = &modelkey.VersionPolicy{Latest: &modelkey.VersionPolicyLatest{NumVersions: 1}}
+	}
+
+	var policies []*triton.ModelVersionPolicy
+	if versionPolicy.All != nil {
+		policies = append(policies, &triton.ModelVersionPolicy{
+			PolicyChoice: &triton.ModelVersionPolicy_All_{All: &triton.ModelVersionPolicy_All{}},
+		})
+	}
+	if versionPolicy.Latest != nil {
+		numVersions := versionPolicy.Latest.NumVersions
+		if numVersions == 0 {
+			numVersions = 1
+		}
+		policies = append(policies, &triton.ModelVersionPolicy{
+			PolicyChoice: &triton.ModelVersionPolicy_Latest_{Latest: &triton.ModelVersionPolicy_Latest{NumVersions: numVersions}},
+		})
+	}
+	if versionPolicy.Specific != nil {
+		if len(versionPolicy.Specific.Versions) == 0 {
+			return nil, errors.New("Invalid version policy: 'specific' must list at least one version")
+		}
+		for _, v := range versionPolicy.Specific.Versions {
+			found := false
+			for _, staged := range versions {
+				if strconv.FormatInt(v, 10) == staged {
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("Invalid version policy: version %d is not one of the model versions %v", v, versions)
+			}
+		}
+		policies = append(policies, &triton.ModelVersionPolicy{
+			PolicyChoice: &triton.ModelVersionPolicy_Specific_{Specific: &triton.ModelVersionPolicy_Specific{Versions: versionPolicy.Specific.Versions}},
+		})
 	}
 
+	if len(policies) != 1 {
+		return nil, errors.New("Invalid version policy: exactly one of 'all', 'latest' or 'specific' must be set")
+	}
+	return policies[0], nil
+}
+
+// getSequenceBatching parses the sequence batching for stateful models from
+// the ModelKey, which is nil if it is not set
+//
+// The spec has the JSON shape of the Triton config, eg.
+// {"max_sequence_idle_microseconds": 5000000, "control_input": [{"name": "START", "control": [{"kind": "CONTROL_SEQUENCE_START", "int32_false_true": [0, 1]}]}]}
+func getSequenceBatching(mk *modelkey.ModelKey) (*triton.ModelSequenceBatching, error) {
+	if len(mk.SequenceBatching) == 0 || string(mk.SequenceBatching) == "null" {
+		return nil, nil
+	}
+
+	var sb triton.ModelSequenceBatching
+	if err := protojson.Unmarshal(mk.SequenceBatching, &sb); err != nil {
+		return nil, fmt.Errorf("Invalid sequence_batching: %w", err)
+	}
+	for _, ci := range sb.ControlInput {
+		if ci.Name == "" {
+			return nil, errors.New("Invalid sequence_batching: each control_input must have a name")
+		}
+	}
+	return &sb, nil
+}
+
+// getInstanceGroup returns the instance group for the instances_per_gpu of the
+// ModelKey, which is nil if it is not set
+//
+// Triton creates the count of a GPU instance group on each of its GPUs, so
+// listing all gpuCount GPUs runs instances_per_gpu times gpuCount instances.
+// Without GPUs, the model runs instances_per_gpu instances on the CPU.
+func getInstanceGroup(mk *modelkey.ModelKey, gpuCount int) ([]*triton.ModelInstanceGroup, error) {
+	if mk.InstancesPerGpu == 0 {
+		return nil, nil
+	}
+	if mk.InstancesPerGpu < 0 {
+		return nil, fmt.Errorf("Invalid %s: must not be negative, got %d", modelkey.InstancesPerGpuKey, mk.InstancesPerGpu)
+	}
+
+	if gpuCount <= 0 {
+		return []*triton.ModelInstanceGroup{{Kind: triton.ModelInstanceGroup_KIND_CPU, Count: int32(mk.InstancesPerGpu)}}, nil
+	}
+	gpus := make([]int32, gpuCount)
+	for i := range gpus {
+		gpus[i] = int32(i)
+	}
+	return []*triton.ModelInstanceGroup{{Kind: triton.ModelInstanceGroup_KIND_GPU, Count: int32(mk.InstancesPerGpu), Gpus: gpus}}, nil
+}
+
+// getOptimization parses the optimization policy from the ModelKey, which is
+// nil if it is not set
+//
+// The spec has the JSON shape of the Triton config, eg.
+// {"execution_accelerators": {"gpu_execution_accelerator": [{"name": "tensorrt", "parameters": {"precision_mode": "FP16"}}]}}
+// The graph optimization and the execution accelerators must be supported by
+// the backend of the model.
+func getOptimization(mk *modelkey.ModelKey, modelType string) (*triton.ModelOptimizationPolicy, error) {
+	if len(mk.Optimization) == 0 || string(mk.Optimization) == "null" {
+		return nil, nil
+	}
+
+	var op triton.ModelOptimizationPolicy
+	if err := protojson.Unmarshal(mk.Optimization, &op); err != nil {
+		return nil, fmt.Errorf("Invalid optimization: %w", err)
+	}
+
+	backend := mk.Backend
+	if backend == "" {
+		backend = modelTypeToBackendMapping[strings.ToLower(strings.Split(modelType, ":")[0])]
+	}
+	_, gpuOk := gpuExecutionAcceleratorMapping[backend]
+	_, cpuOk := cpuExecutionAcceleratorMapping[backend]
+	if op.Graph != nil && !gpuOk && !cpuOk {
+		return nil, fmt.Errorf("Invalid optimization: graph optimization is not supported by the backend '%s'", backend)
+	}
+	if err := checkExecutionAccelerators(op.GetExecutionAccelerators().GetGpuExecutionAccelerator(), gpuExecutionAcceleratorMapping[backend], "GPU", backend); err != nil {
+		return nil, err
+	}
+	if err := checkExecutionAccelerators(op.GetExecutionAccelerators().GetCpuExecutionAccelerator(), cpuExecutionAcceleratorMapping[backend], "CPU", backend); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+func checkExecutionAccelerators(accelerators []*triton.ModelOptimizationPolicy_ExecutionAccelerators_Accelerator, supported []string, device, backend string) error {
+	for _, accelerator := range accelerators {
+		found := false
+		for _, name := range supported {
+			if accelerator.Name == name {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("Invalid optimization: %s execution accelerator '%s' is not supported by the backend '%s', supported accelerators are %v", device, accelerator.Name, backend, supported)
+		}
+	}
 	return nil
 }
 
+// getModelKeyConfig returns the parts of the Triton model config that are
+// given in the ModelKey, which is nil if there are none: the sequence
+// batching, the optimization for the backend of the modelType, the backend
+// with its parameters, and the instance group for the gpuCount GPUs of the
+// runtime
+func getModelKeyConfig(mk *modelkey.ModelKey, modelType string, gpuCount int) (*triton.ModelConfig, error) {
+	sequenceBatching, err := getSequenceBatching(mk)
+	if err != nil {
+		return nil, err
+	}
+	optimization, err := getOptimization(mk, modelType)
+	if err != nil {
+		return nil, err
+	}
+	instanceGroup, err := getInstanceGroup(mk, gpuCount)
+	if err != nil {
+		return nil, err
+	}
+	if mk.ModelFilename != "" && (mk.ModelFilename != filepath.Base(mk.ModelFilename) || mk.ModelFilename == "." || mk.ModelFilename == "..") {
+		return nil, fmt.Errorf("Invalid %s: must be a file name, got %s", modelkey.ModelFilenameKey, mk.ModelFilename)
+	}
+	if sequenceBatching == nil && optimization == nil && mk.Backend == "" && len(mk.Parameters) == 0 && instanceGroup == nil && mk.ModelFilename == "" {
+		return nil, nil
+	}
+
+	m := &triton.ModelConfig{Backend: mk.Backend, InstanceGroup: instanceGroup, DefaultModelFilename: mk.ModelFilename, Optimization: optimization}
+	if sequenceBatching != nil {
+		m.SchedulingChoice = &triton.ModelConfig_SequenceBatching{SequenceBatching: sequenceBatching}
+	}
+	if len(mk.Parameters) > 0 {
+		m.Parameters = make(map[string]*triton.ModelParameter, len(mk.Parameters))
+		for name, value := range mk.Parameters {
+			m.Parameters[name] = &triton.ModelParameter{StringValue: value}
+		}
+	}
+	return m, nil
+}
+
 // If the Triton specific config file exists, assume the model files has the
 // proper structure, but process the config.pbtxt to remove the `name` field.
 // All other files are symlinked to their source
@@ -250,7 +583,7 @@ func adaptNativeModelLayout(files []os.DirEntry, sourceModelIDDir, schemaPath, t
 				return fmt.Errorf("Error reading config file %s: %w", source, err2)
 			}
 
-			processedPbtxt, err2 := processModelConfig(pbtxt, schemaPath, log)
+			processedPbtxt, err2 := processModelConfig(pbtxt, schemaPath, sourceModelIDDir, tritonModelIDDir, log)
 			if err2 != nil {
 				return err2
 			}
@@ -283,11 +616,10 @@ func adaptNativeModelLayout(files []os.DirEntry, sourceModelIDDir, schemaPath, t
 	return nil
 }
 
-// Returns the largest positive int dir as long as all fileInfo dirs are integers (files are ignored).
-// If fileInfos is empty or contains any non-integer dirs, this will return the empty string.
-func largestNumberDir(fileInfos []os.DirEntry) string {
-	largestInt := 0
-	largestDir := ""
+// Returns the positive int dirs, sorted numerically, as long as all fileInfo dirs are integers (files are ignored).
+// If fileInfos is empty or contains any non-integer dirs, this will return nil.
+func numberDirs(fileInfos []os.DirEntry) []string {
+	var dirs []string
 	for _, f := range fileInfos {
 		if !f.IsDir() {
 			continue
@@ -295,14 +627,18 @@ func largestNumberDir(fileInfos []os.DirEntry) string {
 		i, err := strconv.Atoi(f.Name())
 		if err != nil {
 			// must all be numbers
-			return ""
+			return nil
 		}
-		if i > largestInt {
-			largestInt = i
-			largestDir = f.Name()
+		if i > 0 {
+			dirs = append(dirs, f.Name())
 		}
 	}
-	return largestDir
+	sort.Slice(dirs, func(a, b int) bool {
+		ia, _ := strconv.Atoi(dirs[a])
+		ib, _ := strconv.Atoi(dirs[b])
+		return ia < ib
+	})
+	return dirs
 }
 
 // Returns true if these the files make up the top level of a triton model repository.
@@ -320,13 +656,16 @@ func isTritonModelRepository(files []os.DirEntry) bool {
 
 // processModelConfig removes the `name` field from a config.pbtxt file and updates schema if required
 //
+// For Python backend models, the referenced execution environment is also
+// staged in the Triton model directory, see stagePythonExecutionEnv.
+//
 // If `name` exists, Triton asserts that it matches the model id which is the
 // same as the directory in the model repository. Model Mesh generates a model
 // id that we must use instead. To work around this, we rely on the fact that,
 // if `name` is not specified, Triton sets it the name of the directory.
 //
 // Ref: https://github.com/triton-inference-server/server/blob/master/docs/model_configuration.md#name-platform-and-backend
-func processModelConfig(pbtxtIn []byte, schemaPath string, log logr.Logger) ([]byte, error) {
+func processModelConfig(pbtxtIn []byte, schemaPath, sourceModelIDDir, tritonModelIDDir string, log logr.Logger) ([]byte, error) {
 	var err error
 	// parse the pbtxt into a ModelConfig
 	m := triton.ModelConfig{}
@@ -373,6 +712,10 @@ func processModelConfig(pbtxtIn []byte, schemaPath string, log logr.Logger) ([]b
 		}
 	}
 
+	if err = stagePythonExecutionEnv(&m, sourceModelIDDir, tritonModelIDDir, log); err != nil {
+		return pbtxtIn, err
+	}
+
 	// for some level of human readability...
 	marshalOpts := prototext.MarshalOptions{
 		Multiline: true,
@@ -387,6 +730,62 @@ func processModelConfig(pbtxtIn []byte, schemaPath string, log logr.Logger) ([]b
 	return pbtxtOut, nil
 }
 
+// stagePythonExecutionEnv links the conda-pack environment tarball referenced
+// by a Python backend model into the Triton model directory and rewrites
+// EXECUTION_ENV_PATH to point to the staged file
+//
+// The tarball must be included in the model files, and the path must be
+// relative to the model directory, optionally prefixed with
+// $$TRITON_MODEL_DIRECTORY.
+// Ref: https://github.com/triton-inference-server/python_backend#using-custom-python-execution-environments
+func stagePythonExecutionEnv(m *triton.ModelConfig, sourceModelIDDir, tritonModelIDDir string, log logr.Logger) error {
+	if m.Backend != pythonBackendName {
+		return nil
+	}
+	param, ok := m.Parameters[pythonExecutionEnvParameter]
+	if !ok || param.GetStringValue() == "" {
+		return nil
+	}
+
+	envPath := param.GetStringValue()
+	relativeEnvPath := strings.TrimPrefix(envPath, tritonModelDirectoryVariable)
+	if relativeEnvPath == envPath && filepath.IsAbs(envPath) {
+		return fmt.Errorf("Python execution environment path %s must be relative to the model directory", envPath)
+	}
+
+	source, err := util.SecureJoin(sourceModelIDDir, relativeEnvPath)
+	if err != nil {
+		log.Error(err, "Unable to securely join", "sourceModelIDDir", sourceModelIDDir, "envPath", relativeEnvPath)
+		return err
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("Python execution environment %s was not found in the model files: %w", envPath, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("Python execution environment %s is not a file", envPath)
+	}
+
+	envFilename := filepath.Base(source)
+	link, err := util.SecureJoin(tritonModelIDDir, pythonExecutionEnvDirName, envFilename)
+	if err != nil {
+		log.Error(err, "Unable to securely join", "tritonModelIDDir", tritonModelIDDir, "envFilename", envFilename)
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		return fmt.Errorf("Error creating directories for path %s: %w", link, err)
+	}
+	if err = os.Symlink(source, link); err != nil {
+		return fmt.Errorf("Error creating symlink to %s: %w", source, err)
+	}
+
+	stagedEnvPath := strings.Join([]string{tritonModelDirectoryVariable, pythonExecutionEnvDirName, envFilename}, "/")
+	log.Info("Staged Python execution environment", "source", envPath, "rewritten_path", stagedEnvPath)
+	param.StringValue = stagedEnvPath
+
+	return nil
+}
+
 func allInputsAndOuputsHaveBatchDimension(m *triton.ModelConfig) bool {
 	for _, in := range m.Input {
 		if in.Dims[0] != -1 {
@@ -401,11 +800,21 @@ func allInputsAndOuputsHaveBatchDimension(m *triton.ModelConfig) bool {
 	return true
 }
 
+// removeFirstDimensionFromInputsAndOutputs removes the batch dimension, a
+// tensor that is a scalar without it keeps the dims [1] with a reshape to the
+// empty shape, as for a scalar in the schema
 func removeFirstDimensionFromInputsAndOutputs(m *triton.ModelConfig) {
 	for _, in := range m.Input {
-		in.Dims = in.Dims[1:]
+		in.Dims, in.Reshape = removeFirstDimension(in.Dims, in.Reshape)
 	}
 	for _, out := range m.Output {
-		out.Dims = out.Dims[1:]
+		out.Dims, out.Reshape = removeFirstDimension(out.Dims, out.Reshape)
+	}
+}
+
+func removeFirstDimension(dims []int64, reshape *triton.ModelTensorReshape) ([]int64, *triton.ModelTensorReshape) {
+	if len(dims) == 1 {
+		return []int64{1}, &triton.ModelTensorReshape{Shape: []int64{}}
 	}
+	return dims[1:], reshape
 }
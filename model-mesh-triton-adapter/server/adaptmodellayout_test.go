@@ -23,8 +23,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/kserve/modelmesh-runtime-adapter/internal/modelkey"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
 	triton "github.com/kserve/modelmesh-runtime-adapter/internal/proto/triton"
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
 )
@@ -56,7 +60,7 @@ instance_group [
 `
 	pbtxt := []byte(pbtxtIn)
 	var err error
-	pbtxt, err = processModelConfig(pbtxt, "", log)
+	pbtxt, err = processModelConfig(pbtxt, "", "", "", log)
 
 	if err != nil {
 		t.Errorf("Expected `name` field to be removed from ModelConfig pbtxt")
@@ -83,6 +87,13 @@ type adaptModelLayoutTestCase struct {
 	InputFiles         []string
 	InputConfig        *triton.ModelConfig
 	InputSchema        map[string]interface{}
+	VersionPolicy      *modelkey.VersionPolicy
+	SequenceBatching   *triton.ModelSequenceBatching
+	Optimization       *triton.ModelOptimizationPolicy
+	Backend            string
+	Parameters         map[string]string
+	ModelFilename      string
+	ConfigTemplate     *triton.ModelConfig
 	ExpectedLinkPath   string
 	ExpectedLinkTarget string
 	ExpectedFiles      []string
@@ -90,6 +101,30 @@ type adaptModelLayoutTestCase struct {
 	ExpectError        bool
 }
 
+// getKeyConfig returns the model config from the ModelKey fields of the test case
+func (tt adaptModelLayoutTestCase) getKeyConfig(t *testing.T) *triton.ModelConfig {
+	mk := &modelkey.ModelKey{Backend: tt.Backend, Parameters: tt.Parameters, ModelFilename: tt.ModelFilename}
+	if tt.SequenceBatching != nil {
+		sb, err := protojson.Marshal(tt.SequenceBatching)
+		if err != nil {
+			t.Fatalf("Unable to marshal sequence batching: %v", err)
+		}
+		mk.SequenceBatching = sb
+	}
+	if tt.Optimization != nil {
+		op, err := protojson.Marshal(tt.Optimization)
+		if err != nil {
+			t.Fatalf("Unable to marshal optimization: %v", err)
+		}
+		mk.Optimization = op
+	}
+	keyConfig, err := getModelKeyConfig(mk, tt.ModelType, 0)
+	if err != nil {
+		t.Fatalf("Unable to get the model config from the ModelKey: %v", err)
+	}
+	return keyConfig
+}
+
 func (tt adaptModelLayoutTestCase) getSourceDir() string {
 	return filepath.Join(generatedTestdataDir, tt.ModelID)
 }
@@ -194,7 +229,7 @@ func TestAdaptModelLayoutForRuntime(t *testing.T) {
 			if tt.SchemaPath != "" {
 				schemaFullPath = filepath.Join(tt.getSourceDir(), tt.SchemaPath)
 			}
-			err = adaptModelLayoutForRuntime(context.Background(), tritonRootModelDir, tt.ModelID, tt.ModelType, modelFullPath, schemaFullPath, log)
+			err = adaptModelLayoutForRuntime(context.Background(), tritonRootModelDir, tt.ModelID, tt.ModelType, modelFullPath, schemaFullPath, tt.VersionPolicy, tt.getKeyConfig(t), tt.ConfigTemplate, log)
 
 			if tt.ExpectError && err == nil {
 				t.Fatal("ExpectError is true, but no error was returned")
@@ -233,7 +268,7 @@ func TestAdaptModelLayoutForRuntime_Multiple(t *testing.T) {
 		if tt.SchemaPath != "" {
 			schemaFullPath = filepath.Join(tt.getSourceDir(), tt.SchemaPath)
 		}
-		err = adaptModelLayoutForRuntime(ctx, tritonRootModelDir, tt.ModelID, tt.ModelType, modelFullPath, schemaFullPath, log)
+		err = adaptModelLayoutForRuntime(ctx, tritonRootModelDir, tt.ModelID, tt.ModelType, modelFullPath, schemaFullPath, tt.VersionPolicy, tt.getKeyConfig(t), tt.ConfigTemplate, log)
 		if tt.ExpectError && err == nil {
 			t.Fatal("ExpectError is true, but no error was returned")
 		}
@@ -255,6 +290,90 @@ func TestAdaptModelLayoutForRuntime_Multiple(t *testing.T) {
 	}
 }
 
+// loadRecordingTritonClient records the models that the adapter asks Triton
+// to load from the model repository
+type loadRecordingTritonClient struct {
+	triton.GRPCInferenceServiceClient
+	loads []*triton.RepositoryModelLoadRequest
+}
+
+func (c *loadRecordingTritonClient) RepositoryModelLoad(ctx context.Context, in *triton.RepositoryModelLoadRequest, opts ...grpc.CallOption) (*triton.RepositoryModelLoadResponse, error) {
+	c.loads = append(c.loads, in)
+	return &triton.RepositoryModelLoadResponse{}, nil
+}
+
+// Models that share a mounted repository are each staged in their own
+// subdirectory of the Triton model repository that only links back to the
+// shared files, and are loaded from it by their id
+func TestLoadModelFromSharedRepository(t *testing.T) {
+	sharedDir := filepath.Join(generatedTestdataDir, "shared-repository")
+	tritonRootModelDir := filepath.Join(generatedTestdataDir, tritonModelSubdir)
+	if err := os.RemoveAll(sharedDir); err != nil {
+		t.Fatalf("Could not remove shared repository dir %s due to error %v", sharedDir, err)
+	}
+	createEmptyFile(filepath.Join(sharedDir, "mnist", "1", "model.onnx"), t)
+	createEmptyFile(filepath.Join(sharedDir, "mnist", "2", "model.onnx"), t)
+	createEmptyFile(filepath.Join(sharedDir, "resnet", "config.pbtxt"), t)
+	createEmptyFile(filepath.Join(sharedDir, "resnet", "1", "model.plan"), t)
+
+	client := &loadRecordingTritonClient{}
+	s := &TritonAdapterServer{
+		Client:        client,
+		AdapterConfig: &AdapterConfiguration{RootModelDir: tritonRootModelDir},
+		Log:           log,
+	}
+
+	models := map[string]string{"mnist-onnx": "mnist", "resnet-trt": "resnet"}
+	var modelIDs []string
+	for modelID, subdir := range models {
+		_, err := s.LoadModel(context.Background(), &mmesh.LoadModelRequest{
+			ModelId:   modelID,
+			ModelType: "onnx",
+			ModelPath: filepath.Join(sharedDir, subdir),
+			ModelKey:  "{}",
+		})
+		if err != nil {
+			t.Fatalf("Failed to load model %s: %v", modelID, err)
+		}
+		modelIDs = append(modelIDs, modelID)
+
+		// only the generated config.pbtxt is written, every model file is a
+		// link into the shared repository
+		tritonModelIDDir := filepath.Join(tritonRootModelDir, modelID)
+		err = filepath.WalkDir(tritonModelIDDir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			if d.Type()&os.ModeSymlink != 0 {
+				target, lerr := os.Readlink(path)
+				if lerr != nil {
+					return lerr
+				}
+				if !strings.HasPrefix(target, filepath.Join(sharedDir, subdir)+string(filepath.Separator)) {
+					t.Errorf("Expected %s to link into the shared repository but it links to %s", path, target)
+				}
+				return nil
+			}
+			if path != filepath.Join(tritonModelIDDir, tritonRepositoryConfigFilename) {
+				t.Errorf("Expected only links and the config file in %s but found the file %s", tritonModelIDDir, path)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Could not walk the model dir %s: %v", tritonModelIDDir, err)
+		}
+	}
+
+	if len(client.loads) != len(modelIDs) {
+		t.Fatalf("Expected %d loads from the model repository but got %d", len(modelIDs), len(client.loads))
+	}
+	for i, modelID := range modelIDs {
+		if client.loads[i].ModelName != modelID {
+			t.Errorf("Expected model %s to be loaded but got %s", modelID, client.loads[i].ModelName)
+		}
+	}
+}
+
 // If running as a suite, remove the generated files
 func TestCleanupGeneratedDir(t *testing.T) {
 	err := os.RemoveAll(generatedTestdataDir)
@@ -267,6 +386,234 @@ func TestCleanupGeneratedDir(t *testing.T) {
 // Helper functions
 //
 
+func testSequenceBatching(maxSequenceIdleMicroseconds uint64) *triton.ModelSequenceBatching {
+	return &triton.ModelSequenceBatching{
+		MaxSequenceIdleMicroseconds: maxSequenceIdleMicroseconds,
+		ControlInput: []*triton.ModelSequenceBatching_ControlInput{
+			{
+				Name: "START",
+				Control: []*triton.ModelSequenceBatching_Control{
+					{
+						Kind:           triton.ModelSequenceBatching_Control_CONTROL_SEQUENCE_START,
+						Int32FalseTrue: []int32{0, 1},
+					},
+				},
+			},
+			{
+				Name: "READY",
+				Control: []*triton.ModelSequenceBatching_Control{
+					{
+						Kind:           triton.ModelSequenceBatching_Control_CONTROL_SEQUENCE_READY,
+						Int32FalseTrue: []int32{0, 1},
+					},
+				},
+			},
+		},
+	}
+}
+
+// testConfigTemplate returns a config template with dynamic batching and
+// defaults that the configs of the models can override
+func testConfigTemplate() *triton.ModelConfig {
+	return &triton.ModelConfig{
+		Backend:      "template_backend",
+		MaxBatchSize: 8,
+		SchedulingChoice: &triton.ModelConfig_DynamicBatching{
+			DynamicBatching: &triton.ModelDynamicBatching{MaxQueueDelayMicroseconds: 100},
+		},
+		Parameters: map[string]*triton.ModelParameter{
+			"threads": {StringValue: "2"},
+			"mode":    {StringValue: "default"},
+		},
+	}
+}
+
+func TestReadModelConfigTemplate(t *testing.T) {
+	templateFile := filepath.Join(t.TempDir(), "config.pbtxt")
+	if err := writeConfigPbtxt(templateFile, testConfigTemplate()); err != nil {
+		t.Fatal(err)
+	}
+	template, err := readModelConfigTemplate(templateFile)
+	if err != nil {
+		t.Fatalf("Unable to read the config template: %v", err)
+	}
+	if !proto.Equal(template, testConfigTemplate()) {
+		t.Errorf("Expected config template %v, got %v", testConfigTemplate(), template)
+	}
+
+	for _, invalid := range []*triton.ModelConfig{
+		{Name: "my-model", MaxBatchSize: 8},
+		{DefaultModelFilename: "model.onnx"},
+	} {
+		if err = writeConfigPbtxt(templateFile, invalid); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = readModelConfigTemplate(templateFile); err == nil {
+			t.Errorf("Expected an error for the config template %v", invalid)
+		}
+	}
+	if _, err = readModelConfigTemplate(filepath.Join(t.TempDir(), "missing.pbtxt")); err == nil {
+		t.Error("Expected an error for a missing config template")
+	}
+}
+
+func TestGetSequenceBatching(t *testing.T) {
+	mk, err := modelkey.Parse(`{"sequence_batching": {
+		"max_sequence_idle_microseconds": 5000000,
+		"control_input": [
+			{"name": "START", "control": [{"kind": "CONTROL_SEQUENCE_START", "int32_false_true": [0, 1]}]},
+			{"name": "READY", "control": [{"kind": "CONTROL_SEQUENCE_READY", "int32_false_true": [0, 1]}]}
+		]
+	}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing ModelKey: %v", err)
+	}
+	sb, err := getSequenceBatching(mk)
+	if err != nil {
+		t.Fatalf("Unexpected error getting sequence batching: %v", err)
+	}
+	if expected := testSequenceBatching(5000000); !proto.Equal(expected, sb) {
+		t.Errorf("Expected sequence batching %v but got %v", expected, sb)
+	}
+
+	for _, modelKey := range []string{
+		`{"sequence_batching": {"control_input": [{"control": [{"kind": "CONTROL_SEQUENCE_START"}]}]}}`,
+		`{"sequence_batching": {"control_input": [{"name": "START", "control": [{"kind": "NOT_A_KIND"}]}]}}`,
+		`{"sequence_batching": {"max_sequence_idle": 5}}`,
+	} {
+		mk, err := modelkey.Parse(modelKey)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing ModelKey: %v", err)
+		}
+		if _, err = getSequenceBatching(mk); err == nil {
+			t.Errorf("Expected an error getting sequence batching from %s", modelKey)
+		}
+	}
+
+	mk, _ = modelkey.Parse(`{}`)
+	if sb, err = getSequenceBatching(mk); sb != nil || err != nil {
+		t.Errorf("Expected no sequence batching but got %v (error: %v)", sb, err)
+	}
+}
+
+func testOptimization() *triton.ModelOptimizationPolicy {
+	return &triton.ModelOptimizationPolicy{
+		Graph: &triton.ModelOptimizationPolicy_Graph{Level: 1},
+		ExecutionAccelerators: &triton.ModelOptimizationPolicy_ExecutionAccelerators{
+			GpuExecutionAccelerator: []*triton.ModelOptimizationPolicy_ExecutionAccelerators_Accelerator{
+				{Name: "tensorrt", Parameters: map[string]string{"precision_mode": "FP16"}},
+			},
+		},
+	}
+}
+
+func TestGetOptimization(t *testing.T) {
+	mk, err := modelkey.Parse(`{"optimization": {
+		"graph": {"level": 1},
+		"execution_accelerators": {"gpu_execution_accelerator": [{"name": "tensorrt", "parameters": {"precision_mode": "FP16"}}]}
+	}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing ModelKey: %v", err)
+	}
+	op, err := getOptimization(mk, "tensorflow:2")
+	if err != nil {
+		t.Fatalf("Unexpected error getting optimization: %v", err)
+	}
+	if expected := testOptimization(); !proto.Equal(expected, op) {
+		t.Errorf("Expected optimization %v but got %v", expected, op)
+	}
+
+	for _, tc := range []struct {
+		modelKey  string
+		modelType string
+	}{
+		{`{"optimization": {"graph": {"level": "high"}}}`, "tensorflow"},
+		{`{"optimization": {"execution_accelerators": {"gpu_execution_accelerator": [{"name": "openvino"}]}}}`, "tensorflow"},
+		{`{"optimization": {"execution_accelerators": {"cpu_execution_accelerator": [{"name": "openvino"}]}}}`, "tensorflow"},
+		{`{"optimization": {"execution_accelerators": {"gpu_execution_accelerator": [{"name": "tensorrt"}]}}}`, "pytorch"},
+		{`{"optimization": {"graph": {"level": 1}}}`, "tensorrt"},
+		{`{"backend": "mybackend", "optimization": {"execution_accelerators": {"gpu_execution_accelerator": [{"name": "tensorrt"}]}}}`, "onnx"},
+	} {
+		mk, err := modelkey.Parse(tc.modelKey)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing ModelKey: %v", err)
+		}
+		if _, err = getOptimization(mk, tc.modelType); err == nil {
+			t.Errorf("Expected an error getting optimization from %s for model type %s", tc.modelKey, tc.modelType)
+		}
+	}
+
+	mk, _ = modelkey.Parse(`{"optimization": {"execution_accelerators": {"cpu_execution_accelerator": [{"name": "openvino"}]}}}`)
+	if _, err = getOptimization(mk, "onnx"); err != nil {
+		t.Errorf("Unexpected error getting the openvino accelerator for an onnx model: %v", err)
+	}
+
+	mk, _ = modelkey.Parse(`{}`)
+	if op, err = getOptimization(mk, "tensorflow"); op != nil || err != nil {
+		t.Errorf("Expected no optimization but got %v (error: %v)", op, err)
+	}
+}
+
+func TestGetModelKeyConfigInstancesPerGpu(t *testing.T) {
+	mk, err := modelkey.Parse(`{"instances_per_gpu": 3}`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing ModelKey: %v", err)
+	}
+
+	testCases := []struct {
+		gpuCount      int
+		expectedKind  triton.ModelInstanceGroup_Kind
+		expectedCount int
+	}{
+		{4, triton.ModelInstanceGroup_KIND_GPU, 12},
+		{1, triton.ModelInstanceGroup_KIND_GPU, 3},
+		{0, triton.ModelInstanceGroup_KIND_CPU, 3},
+	}
+	for _, tc := range testCases {
+		keyConfig, err := getModelKeyConfig(mk, "", tc.gpuCount)
+		if err != nil {
+			t.Fatalf("Unexpected error getting the model config with %d GPUs: %v", tc.gpuCount, err)
+		}
+		if len(keyConfig.InstanceGroup) != 1 {
+			t.Fatalf("Expected one instance group with %d GPUs but got %v", tc.gpuCount, keyConfig.InstanceGroup)
+		}
+		group := keyConfig.InstanceGroup[0]
+		// Triton creates the count of a GPU group on each of its GPUs
+		count := int(group.Count)
+		if group.Kind == triton.ModelInstanceGroup_KIND_GPU {
+			count *= len(group.Gpus)
+		}
+		if group.Kind != tc.expectedKind || count != tc.expectedCount {
+			t.Errorf("Expected %d instances of kind %v with %d GPUs but got %d of kind %v", tc.expectedCount, tc.expectedKind, tc.gpuCount, count, group.Kind)
+		}
+	}
+
+	mk, _ = modelkey.Parse(`{"instances_per_gpu": -1}`)
+	if _, err = getModelKeyConfig(mk, "", 2); err == nil {
+		t.Error("Expected an error for a negative instances_per_gpu")
+	}
+
+	mk, _ = modelkey.Parse(`{}`)
+	if keyConfig, err := getModelKeyConfig(mk, "", 2); keyConfig != nil || err != nil {
+		t.Errorf("Expected no model config but got %v (error: %v)", keyConfig, err)
+	}
+}
+
+func TestGetModelKeyConfigModelFilename(t *testing.T) {
+	mk, _ := modelkey.Parse(`{"model_filename": "custom.onnx"}`)
+	keyConfig, err := getModelKeyConfig(mk, "", 0)
+	if err != nil || keyConfig.DefaultModelFilename != "custom.onnx" {
+		t.Errorf("Expected default_model_filename custom.onnx but got %v (error: %v)", keyConfig, err)
+	}
+
+	for _, filename := range []string{"../model.onnx", "1/model.onnx", ".."} {
+		mk.ModelFilename = filename
+		if _, err = getModelKeyConfig(mk, "", 0); err == nil {
+			t.Errorf("Expected an error for the model_filename %s that is not a file name", filename)
+		}
+	}
+}
+
 func assertConfigFileContents(t *testing.T, tt adaptModelLayoutTestCase) {
 	var err error
 
@@ -944,6 +1291,388 @@ var adaptModelLayoutTests = []adaptModelLayoutTestCase{
 		},
 	},
 
+	// Group: version policy
+	{
+		ModelID:   "versionPolicyAll",
+		ModelType: "onnx",
+		InputFiles: []string{
+			"1/model.onnx",
+			"2/model.onnx",
+		},
+		VersionPolicy: &modelkey.VersionPolicy{All: &modelkey.VersionPolicyAll{}},
+		ExpectedFiles: []string{
+			"1/model.onnx",
+			"2/model.onnx",
+			"config.pbtxt",
+		},
+		ExpectedConfig: &triton.ModelConfig{
+			Backend: "onnxruntime",
+			VersionPolicy: &triton.ModelVersionPolicy{
+				PolicyChoice: &triton.ModelVersionPolicy_All_{All: &triton.ModelVersionPolicy_All{}},
+			},
+		},
+	},
+	{
+		ModelID:   "versionPolicyLatest",
+		ModelType: "onnx",
+		InputFiles: []string{
+			"1/model.onnx",
+			"2/model.onnx",
+		},
+		VersionPolicy: &modelkey.VersionPolicy{Latest: &modelkey.VersionPolicyLatest{NumVersions: 2}},
+		ExpectedFiles: []string{
+			"1/model.onnx",
+			"2/model.onnx",
+			"config.pbtxt",
+		},
+		ExpectedConfig: &triton.ModelConfig{
+			Backend: "onnxruntime",
+			VersionPolicy: &triton.ModelVersionPolicy{
+				PolicyChoice: &triton.ModelVersionPolicy_Latest_{Latest: &triton.ModelVersionPolicy_Latest{NumVersions: 2}},
+			},
+		},
+	},
+	{
+		ModelID:   "versionPolicySpecific",
+		ModelType: "onnx",
+		InputFiles: []string{
+			"1/model.onnx",
+			"2/model.onnx",
+		},
+		VersionPolicy: &modelkey.VersionPolicy{Specific: &modelkey.VersionPolicySpecific{Versions: []int64{1}}},
+		ExpectedFiles: []string{
+			"1/model.onnx",
+			"2/model.onnx",
+			"config.pbtxt",
+		},
+		ExpectedConfig: &triton.ModelConfig{
+			Backend: "onnxruntime",
+			VersionPolicy: &triton.ModelVersionPolicy{
+				PolicyChoice: &triton.ModelVersionPolicy_Specific_{Specific: &triton.ModelVersionPolicy_Specific{Versions: []int64{1}}},
+			},
+		},
+	},
+	{
+		// the generated config defaults to the latest version
+		ModelID:     "versionPolicyDefaultWithSchema",
+		ModelType:   "onnx",
+		SchemaPath:  "schema.json",
+		InputSchema: map[string]interface{}{},
+		InputFiles: []string{
+			"1/model.onnx",
+			"2/model.onnx",
+			"schema.json",
+		},
+		ExpectedFiles: []string{
+			"1/model.onnx",
+			"2/model.onnx",
+			"config.pbtxt",
+		},
+		ExpectedConfig: &triton.ModelConfig{
+			Backend: "onnxruntime",
+			VersionPolicy: &triton.ModelVersionPolicy{
+				PolicyChoice: &triton.ModelVersionPolicy_Latest_{Latest: &triton.ModelVersionPolicy_Latest{NumVersions: 1}},
+			},
+		},
+	},
+	{
+		ModelID:   "versionPolicySpecificMissingVersion",
+		ModelType: "onnx",
+		InputFiles: []string{
+			"1/model.onnx",
+			"2/model.onnx",
+		},
+		VersionPolicy: &modelkey.VersionPolicy{Specific: &modelkey.VersionPolicySpecific{Versions: []int64{3}}},
+		ExpectError:   true,
+	},
+
+	// Group: sequence batching
+	{
+		ModelID:          "sequenceBatchingOnnx",
+		ModelType:        "onnx",
+		ModelPath:        "my-model.onnx",
+		SequenceBatching: testSequenceBatching(5000000),
+		InputFiles: []string{
+			"my-model.onnx",
+		},
+		ExpectedLinkPath:   "1/model.onnx",
+		ExpectedLinkTarget: "my-model.onnx",
+		ExpectedFiles: []string{
+			"1/model.onnx",
+			"config.pbtxt",
+		},
+		ExpectedConfig: &triton.ModelConfig{
+			Backend: "onnxruntime",
+			SchedulingChoice: &triton.ModelConfig_SequenceBatching{
+				SequenceBatching: testSequenceBatching(5000000),
+			},
+		},
+	},
+	{
+		ModelID:          "sequenceBatchingWithConfig",
+		ModelType:        "onnx",
+		SequenceBatching: testSequenceBatching(5000000),
+		InputConfig: &triton.ModelConfig{
+			Backend: "onnxruntime",
+			SchedulingChoice: &triton.ModelConfig_SequenceBatching{
+				SequenceBatching: testSequenceBatching(100),
+			},
+		},
+		InputFiles: []string{
+			"1/model.onnx",
+			"config.pbtxt",
+		},
+		ExpectedFiles: []string{
+			"1/model.onnx",
+			"config.pbtxt",
+		},
+		// the model's config wins
+		ExpectedConfig: &triton.ModelConfig{
+			Backend: "onnxruntime",
+			SchedulingChoice: &triton.ModelConfig_SequenceBatching{
+				SequenceBatching: testSequenceBatching(100),
+			},
+		},
+	},
+
+	// Group: optimization
+	{
+		ModelID:      "optimizationTensorflow",
+		ModelType:    "tensorflow",
+		ModelPath:    "my-model.graphdef",
+		Optimization: testOptimization(),
+		InputFiles: []string{
+			"my-model.graphdef",
+		},
+		ExpectedLinkPath:   "1/model.graphdef",
+		ExpectedLinkTarget: "my-model.graphdef",
+		ExpectedFiles: []string{
+			"1/model.graphdef",
+			"config.pbtxt",
+		},
+		ExpectedConfig: &triton.ModelConfig{
+			Backend:      "tensorflow",
+			Optimization: testOptimization(),
+		},
+	},
+
+	// Group: custom backend
+	{
+		ModelID:    "customBackendFile",
+		ModelType:  "custom",
+		ModelPath:  "model.bin",
+		Backend:    "mybackend",
+		Parameters: map[string]string{"threads": "4"},
+		InputFiles: []string{
+			"model.bin",
+		},
+		ExpectedLinkPath:   "1/model.bin",
+		ExpectedLinkTarget: "model.bin",
+		ExpectedFiles: []string{
+			"1/model.bin",
+			"config.pbtxt",
+		},
+		ExpectedConfig: &triton.ModelConfig{
+			Backend: "mybackend",
+			Parameters: map[string]*triton.ModelParameter{
+				"threads": {StringValue: "4"},
+			},
+		},
+	},
+	{
+		ModelID:    "customBackendOverridesModelType",
+		ModelType:  "onnx",
+		ModelPath:  "my-model.onnx",
+		Backend:    "onnxruntime_custom",
+		Parameters: map[string]string{"mode": "fast"},
+		InputFiles: []string{
+			"my-model.onnx",
+		},
+		ExpectedLinkPath:   "1/model.onnx",
+		ExpectedLinkTarget: "my-model.onnx",
+		ExpectedFiles: []string{
+			"1/model.onnx",
+			"config.pbtxt",
+		},
+		ExpectedConfig: &triton.ModelConfig{
+			Backend: "onnxruntime_custom",
+			Parameters: map[string]*triton.ModelParameter{
+				"mode": {StringValue: "fast"},
+			},
+		},
+	},
+
+	// Group: config template
+	{
+		ModelID:        "configTemplateDefaults",
+		ModelType:      "onnx",
+		ModelPath:      "my-model.onnx",
+		ConfigTemplate: testConfigTemplate(),
+		InputFiles: []string{
+			"my-model.onnx",
+		},
+		ExpectedLinkPath:   "1/model.onnx",
+		ExpectedLinkTarget: "my-model.onnx",
+		ExpectedFiles: []string{
+			"1/model.onnx",
+			"config.pbtxt",
+		},
+		// the backend of the model type takes precedence over the template
+		ExpectedConfig: &triton.ModelConfig{
+			Backend:      "onnxruntime",
+			MaxBatchSize: 8,
+			SchedulingChoice: &triton.ModelConfig_DynamicBatching{
+				DynamicBatching: &triton.ModelDynamicBatching{MaxQueueDelayMicroseconds: 100},
+			},
+			Parameters: map[string]*triton.ModelParameter{
+				"threads": {StringValue: "2"},
+				"mode":    {StringValue: "default"},
+			},
+		},
+	},
+	{
+		ModelID:          "configTemplateOverriddenByModelKey",
+		ModelType:        "onnx",
+		ModelPath:        "my-model.onnx",
+		ConfigTemplate:   testConfigTemplate(),
+		Backend:          "onnxruntime_custom",
+		Parameters:       map[string]string{"threads": "4"},
+		SequenceBatching: testSequenceBatching(5000000),
+		InputFiles: []string{
+			"my-model.onnx",
+		},
+		ExpectedLinkPath:   "1/model.onnx",
+		ExpectedLinkTarget: "my-model.onnx",
+		ExpectedFiles: []string{
+			"1/model.onnx",
+			"config.pbtxt",
+		},
+		ExpectedConfig: &triton.ModelConfig{
+			Backend:      "onnxruntime_custom",
+			MaxBatchSize: 8,
+			SchedulingChoice: &triton.ModelConfig_SequenceBatching{
+				SequenceBatching: testSequenceBatching(5000000),
+			},
+			Parameters: map[string]*triton.ModelParameter{
+				"threads": {StringValue: "4"},
+				"mode":    {StringValue: "default"},
+			},
+		},
+	},
+	{
+		ModelID:        "configTemplateBatchedSchema",
+		ModelType:      "custom",
+		ModelPath:      "model.bin",
+		SchemaPath:     "schema.json",
+		ConfigTemplate: testConfigTemplate(),
+		InputSchema: map[string]interface{}{
+			"inputs": []map[string]interface{}{
+				{
+					"name":     "INPUT",
+					"datatype": "FP32",
+					"shape":    []int{-1, 3},
+				},
+			},
+			"outputs": []map[string]interface{}{
+				{
+					"name":     "OUTPUT",
+					"datatype": "INT64",
+					"shape":    []int{-1},
+				},
+			},
+		},
+		InputFiles: []string{
+			"model.bin",
+			"schema.json",
+		},
+		ExpectedLinkPath:   "1/model.bin",
+		ExpectedLinkTarget: "model.bin",
+		ExpectedFiles: []string{
+			"1/model.bin",
+			"config.pbtxt",
+		},
+		// the template sets the backend of a model type without one, and
+		// its max_batch_size removes the batch dimension of the schema
+		ExpectedConfig: &triton.ModelConfig{
+			Backend:      "template_backend",
+			MaxBatchSize: 8,
+			SchedulingChoice: &triton.ModelConfig_DynamicBatching{
+				DynamicBatching: &triton.ModelDynamicBatching{MaxQueueDelayMicroseconds: 100},
+			},
+			Parameters: map[string]*triton.ModelParameter{
+				"threads": {StringValue: "2"},
+				"mode":    {StringValue: "default"},
+			},
+			Input: []*triton.ModelInput{
+				{
+					Name:     "INPUT",
+					DataType: triton.DataType_TYPE_FP32,
+					Dims:     []int64{3},
+				},
+			},
+			Output: []*triton.ModelOutput{
+				{
+					Name:     "OUTPUT",
+					DataType: triton.DataType_TYPE_INT64,
+					Dims:     []int64{1},
+					Reshape:  &triton.ModelTensorReshape{Shape: []int64{}},
+				},
+			},
+		},
+	},
+
+	// Group: model filename
+	{
+		ModelID:       "modelFilenameFile",
+		ModelType:     "onnx",
+		ModelPath:     "my-model.onnx",
+		ModelFilename: "my-model.onnx",
+		InputFiles: []string{
+			"my-model.onnx",
+		},
+		// the file is not renamed to model.onnx
+		ExpectedLinkPath:   "1/my-model.onnx",
+		ExpectedLinkTarget: "my-model.onnx",
+		ExpectedFiles: []string{
+			"1/my-model.onnx",
+			"config.pbtxt",
+		},
+		ExpectedConfig: &triton.ModelConfig{
+			Backend:              "onnxruntime",
+			DefaultModelFilename: "my-model.onnx",
+		},
+	},
+	{
+		ModelID:       "modelFilenameDirectory",
+		ModelType:     "onnx",
+		ModelPath:     "modeldir",
+		ModelFilename: "custom.onnx",
+		InputFiles: []string{
+			"modeldir/custom.onnx",
+			"modeldir/labels.txt",
+		},
+		ExpectedLinkPath:   "1",
+		ExpectedLinkTarget: "modeldir",
+		ExpectedFiles: []string{
+			"1/custom.onnx",
+			"config.pbtxt",
+		},
+		ExpectedConfig: &triton.ModelConfig{
+			Backend:              "onnxruntime",
+			DefaultModelFilename: "custom.onnx",
+		},
+	},
+	{
+		ModelID:       "modelFilenameMissingInVersion",
+		ModelType:     "onnx",
+		ModelFilename: "custom.onnx",
+		InputFiles: []string{
+			"1/custom.onnx",
+			"2/model.onnx",
+		},
+		ExpectError: true,
+	},
+
 	// Group: schema
 	{
 		ModelID:     "schemaOnnxSimpleRename",
@@ -1243,4 +1972,70 @@ var adaptModelLayoutTests = []adaptModelLayoutTestCase{
 		},
 		ExpectError: true,
 	},
+	// Group: python backend
+	{
+		ModelID:   "pythonExecutionEnv",
+		ModelType: "python",
+		InputConfig: &triton.ModelConfig{
+			Backend: "python",
+			Parameters: map[string]*triton.ModelParameter{
+				"EXECUTION_ENV_PATH": {StringValue: "$$TRITON_MODEL_DIRECTORY/envs/python3.8.tar.gz"},
+			},
+		},
+		InputFiles: []string{
+			"1/model.py",
+			"envs/python3.8.tar.gz",
+			"config.pbtxt",
+		},
+		ExpectedLinkPath:   "_python_env/python3.8.tar.gz",
+		ExpectedLinkTarget: "envs/python3.8.tar.gz",
+		ExpectedFiles: []string{
+			"1/model.py",
+			"config.pbtxt",
+		},
+		ExpectedConfig: &triton.ModelConfig{
+			Backend: "python",
+			Parameters: map[string]*triton.ModelParameter{
+				"EXECUTION_ENV_PATH": {StringValue: "$$TRITON_MODEL_DIRECTORY/_python_env/python3.8.tar.gz"},
+			},
+		},
+	},
+	{
+		ModelID:   "pythonExecutionEnvRelativePath",
+		ModelType: "python",
+		InputConfig: &triton.ModelConfig{
+			Backend: "python",
+			Parameters: map[string]*triton.ModelParameter{
+				"EXECUTION_ENV_PATH": {StringValue: "python3.8.tar.gz"},
+			},
+		},
+		InputFiles: []string{
+			"1/model.py",
+			"python3.8.tar.gz",
+			"config.pbtxt",
+		},
+		ExpectedLinkPath:   "_python_env/python3.8.tar.gz",
+		ExpectedLinkTarget: "python3.8.tar.gz",
+		ExpectedConfig: &triton.ModelConfig{
+			Backend: "python",
+			Parameters: map[string]*triton.ModelParameter{
+				"EXECUTION_ENV_PATH": {StringValue: "$$TRITON_MODEL_DIRECTORY/_python_env/python3.8.tar.gz"},
+			},
+		},
+	},
+	{
+		ModelID:   "pythonExecutionEnvMissingError",
+		ModelType: "python",
+		InputConfig: &triton.ModelConfig{
+			Backend: "python",
+			Parameters: map[string]*triton.ModelParameter{
+				"EXECUTION_ENV_PATH": {StringValue: "$$TRITON_MODEL_DIRECTORY/envs/python3.8.tar.gz"},
+			},
+		},
+		InputFiles: []string{
+			"1/model.py",
+			"config.pbtxt",
+		},
+		ExpectError: true,
+	},
 }
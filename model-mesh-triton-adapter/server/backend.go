@@ -0,0 +1,63 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkBackendAvailable returns an InvalidArgument error if Triton would not
+// find the backend to load the model with
+//
+// Triton's server metadata does not list the backends, so the places that
+// Triton searches are checked instead: the backends of the model types that
+// Triton ships with, a shared library included in the model directory or one
+// of its version directories, and the backend directory. Custom backends are
+// not checked if the backend directory is not known.
+func checkBackendAvailable(backend, modelPath, backendDir string) error {
+	if backend == pythonBackendName {
+		return nil
+	}
+	for _, b := range modelTypeToBackendMapping {
+		if b == backend {
+			return nil
+		}
+	}
+
+	// Triton looks for libtriton_<backend>.so next to the model versions
+	libraryName := fmt.Sprintf("libtriton_%s.so", backend)
+	candidates := []string{
+		filepath.Join(modelPath, libraryName),
+		filepath.Join(modelPath, "*", libraryName),
+	}
+	for _, pattern := range candidates {
+		if matches, _ := filepath.Glob(pattern); len(matches) > 0 {
+			return nil
+		}
+	}
+
+	if backendDir == "" {
+		return nil
+	}
+	if info, err := os.Stat(filepath.Join(backendDir, backend)); err == nil && info.IsDir() {
+		return nil
+	}
+	return status.Errorf(codes.InvalidArgument, "Backend '%s' is not available, it is not included with the model or found in the backend directory %s", backend, backendDir)
+}
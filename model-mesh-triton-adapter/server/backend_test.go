@@ -0,0 +1,50 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheckBackendAvailable(t *testing.T) {
+	backendDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(backendDir, "installed"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	modelDir := t.TempDir()
+	createEmptyFile(filepath.Join(modelDir, "1", "libtriton_bundled.so"), t)
+
+	for _, backend := range []string{"onnxruntime", "python", "installed", "bundled"} {
+		if err := checkBackendAvailable(backend, modelDir, backendDir); err != nil {
+			t.Errorf("Expected backend %s to be available, got: %v", backend, err)
+		}
+	}
+
+	err := checkBackendAvailable("missing", modelDir, backendDir)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected an InvalidArgument error for a missing backend, got: %v", err)
+	}
+
+	// without the backend directory, custom backends are not checked
+	if err = checkBackendAvailable("missing", modelDir, ""); err != nil {
+		t.Errorf("Expected no error without a backend directory, got: %v", err)
+	}
+}
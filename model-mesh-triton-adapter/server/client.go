@@ -0,0 +1,58 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials/insecure"
+
+	triton "github.com/kserve/modelmesh-runtime-adapter/internal/proto/triton"
+)
+
+// The protocols of the Triton API that the adapter can load and unload the
+// models with, see TritonClient
+const (
+	ControlProtocolGrpc string = "grpc"
+	ControlProtocolHttp string = "http"
+)
+
+// TritonClient is the part of the Triton API that the adapter calls, which
+// is implemented over gRPC by triton.GRPCInferenceServiceClient and over HTTP
+// by tritonHttpClient
+type TritonClient interface {
+	ServerReady(ctx context.Context, in *triton.ServerReadyRequest, opts ...grpc.CallOption) (*triton.ServerReadyResponse, error)
+	ServerMetadata(ctx context.Context, in *triton.ServerMetadataRequest, opts ...grpc.CallOption) (*triton.ServerMetadataResponse, error)
+	ModelStatistics(ctx context.Context, in *triton.ModelStatisticsRequest, opts ...grpc.CallOption) (*triton.ModelStatisticsResponse, error)
+	RepositoryIndex(ctx context.Context, in *triton.RepositoryIndexRequest, opts ...grpc.CallOption) (*triton.RepositoryIndexResponse, error)
+	RepositoryModelLoad(ctx context.Context, in *triton.RepositoryModelLoadRequest, opts ...grpc.CallOption) (*triton.RepositoryModelLoadResponse, error)
+	RepositoryModelUnload(ctx context.Context, in *triton.RepositoryModelUnloadRequest, opts ...grpc.CallOption) (*triton.RepositoryModelUnloadResponse, error)
+}
+
+// the largest response of the control API that the gRPC client accepts,
+// which is above the default of 4MiB for the index of large repositories
+const grpcMaxRecvMsgSize = 64 * 1024 * 1024
+
+// dialTritonGrpc connects to the gRPC API of Triton at the address, the
+// connection is established in the background and retried with backoff
+func dialTritonGrpc(address string) (*grpc.ClientConn, error) {
+	return grpc.DialContext(context.Background(), address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoff.DefaultConfig, MinConnectTimeout: 10 * time.Second}),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(grpcMaxRecvMsgSize)))
+}
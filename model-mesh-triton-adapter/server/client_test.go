@@ -0,0 +1,308 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	triton "github.com/kserve/modelmesh-runtime-adapter/internal/proto/triton"
+)
+
+const (
+	mockTritonBrokenModel      = "broken"
+	mockTritonModelMemoryUsage = 5000000
+)
+
+// mockTritonRepository is the model repository of a mock Triton, which
+// fails to load the broken model and reports the same memory usage for every
+// loaded model
+type mockTritonRepository struct {
+	mutex  sync.Mutex
+	states map[string]string
+	calls  map[string]int
+}
+
+func newMockTritonRepository() *mockTritonRepository {
+	return &mockTritonRepository{states: map[string]string{}, calls: map[string]int{}}
+}
+
+func (r *mockTritonRepository) load(name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.calls["load"]++
+	if name == mockTritonBrokenModel {
+		r.states[name] = tritonModelStateUnavailable
+		return status.Errorf(codes.InvalidArgument, "failed to load '%s', no version is available", name)
+	}
+	r.states[name] = tritonModelStateReady
+	return nil
+}
+
+func (r *mockTritonRepository) unload(name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.calls["unload"]++
+	if _, ok := r.states[name]; !ok {
+		return status.Errorf(codes.NotFound, "failed to unload '%s', model is not loaded", name)
+	}
+	delete(r.states, name)
+	return nil
+}
+
+func (r *mockTritonRepository) index() []*triton.RepositoryIndexResponse_ModelIndex {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.calls["index"]++
+	var models []*triton.RepositoryIndexResponse_ModelIndex
+	for name, state := range r.states {
+		models = append(models, &triton.RepositoryIndexResponse_ModelIndex{Name: name, Version: "1", State: state})
+	}
+	return models
+}
+
+func (r *mockTritonRepository) state(name string) (string, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	state, ok := r.states[name]
+	return state, ok
+}
+
+func (r *mockTritonRepository) callCount(call string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.calls[call]
+}
+
+// mockTritonGrpcServer serves the repository over the gRPC API of Triton
+type mockTritonGrpcServer struct {
+	triton.UnimplementedGRPCInferenceServiceServer
+	repository *mockTritonRepository
+}
+
+func (m *mockTritonGrpcServer) RepositoryModelLoad(ctx context.Context, in *triton.RepositoryModelLoadRequest) (*triton.RepositoryModelLoadResponse, error) {
+	return &triton.RepositoryModelLoadResponse{}, m.repository.load(in.ModelName)
+}
+
+func (m *mockTritonGrpcServer) RepositoryModelUnload(ctx context.Context, in *triton.RepositoryModelUnloadRequest) (*triton.RepositoryModelUnloadResponse, error) {
+	return &triton.RepositoryModelUnloadResponse{}, m.repository.unload(in.ModelName)
+}
+
+func (m *mockTritonGrpcServer) RepositoryIndex(ctx context.Context, in *triton.RepositoryIndexRequest) (*triton.RepositoryIndexResponse, error) {
+	return &triton.RepositoryIndexResponse{Models: m.repository.index()}, nil
+}
+
+func (m *mockTritonGrpcServer) ModelStatistics(ctx context.Context, in *triton.ModelStatisticsRequest) (*triton.ModelStatisticsResponse, error) {
+	var memoryUsage []byte
+	memoryUsage = protowire.AppendTag(memoryUsage, memoryUsageByteSizeField, protowire.VarintType)
+	memoryUsage = protowire.AppendVarint(memoryUsage, mockTritonModelMemoryUsage)
+	var unknown []byte
+	unknown = protowire.AppendTag(unknown, modelStatisticsMemoryUsageField, protowire.BytesType)
+	unknown = protowire.AppendBytes(unknown, memoryUsage)
+
+	stats := &triton.ModelStatistics{Name: in.Name, Version: "1"}
+	stats.ProtoReflect().SetUnknown(unknown)
+	return &triton.ModelStatisticsResponse{ModelStats: []*triton.ModelStatistics{stats}}, nil
+}
+
+func startMockTritonGrpc(t *testing.T, repository *mockTritonRepository) TritonClient {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	triton.RegisterGRPCInferenceServiceServer(server, &mockTritonGrpcServer{repository: repository})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := dialTritonGrpc(lis.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect to the mock Triton: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return triton.NewGRPCInferenceServiceClient(conn)
+}
+
+// startMockTritonHttp serves the repository over the HTTP API of Triton,
+// with the errors in the JSON body that Triton responds with
+func startMockTritonHttp(t *testing.T, repository *mockTritonRepository) TritonClient {
+	writeError := func(w http.ResponseWriter, err error) {
+		httpStatus := http.StatusBadRequest
+		if status.Code(err) == codes.NotFound {
+			httpStatus = http.StatusNotFound
+		}
+		w.WriteHeader(httpStatus)
+		json.NewEncoder(w).Encode(map[string]string{"error": status.Convert(err).Message()})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/repository/index", func(w http.ResponseWriter, r *http.Request) {
+		var models []map[string]string
+		for _, model := range repository.index() {
+			models = append(models, map[string]string{"name": model.Name, "version": model.Version, "state": model.State})
+		}
+		json.NewEncoder(w).Encode(models)
+	})
+	mux.HandleFunc("/v2/repository/models/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var err error
+		name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/repository/models/"), "/")
+		switch action {
+		case "load":
+			err = repository.load(name)
+		case "unload":
+			err = repository.unload(name)
+		default:
+			err = status.Errorf(codes.NotFound, "unknown action %s", action)
+		}
+		if err != nil {
+			writeError(w, err)
+		}
+	})
+	mux.HandleFunc("/v2/models/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/models/"), "/stats")
+		fmt.Fprintf(w, `{"model_stats": [{"name": %q, "version": "1", "inference_count": 0,
+			"inference_stats": {"success": {"count": 0, "ns": 0}},
+			"memory_usage": [{"type": "GPU", "id": 0, "byte_size": %d}]}]}`, name, mockTritonModelMemoryUsage)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return newTritonHttpClient(server.URL)
+}
+
+func TestLoadModelControlProtocols(t *testing.T) {
+	for protocol, startMockTriton := range map[string]func(*testing.T, *mockTritonRepository) TritonClient{
+		ControlProtocolGrpc: startMockTritonGrpc,
+		ControlProtocolHttp: startMockTritonHttp,
+	} {
+		t.Run(protocol, func(t *testing.T) {
+			modelDir := t.TempDir()
+			createEmptyFile(filepath.Join(modelDir, "mnist", "1", "model.onnx"), t)
+
+			repository := newMockTritonRepository()
+			s := &TritonAdapterServer{
+				Client: startMockTriton(t, repository),
+				AdapterConfig: &AdapterConfiguration{
+					RootModelDir:            t.TempDir(),
+					DefaultModelSizeInBytes: defaultModelSizeInBytes,
+					ModelSizeMultiplier:     defaultModelSizeMultiplier,
+					ModelReadyTimeout:       5 * time.Second,
+					UseRuntimeModelSize:     true,
+					ControlProtocol:         protocol,
+				},
+				Log: log,
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			loadRequest := func(modelId string) *mmesh.LoadModelRequest {
+				return &mmesh.LoadModelRequest{
+					ModelId:   modelId,
+					ModelType: "onnx",
+					ModelPath: filepath.Join(modelDir, "mnist"),
+					ModelKey:  "{}",
+				}
+			}
+
+			resp, err := s.LoadModel(ctx, loadRequest("mnist"))
+			if err != nil {
+				t.Fatalf("Failed to load model: %v", err)
+			}
+			if state, _ := repository.state("mnist"); state != tritonModelStateReady {
+				t.Errorf("Expected the model to be %s in Triton, got '%s'", tritonModelStateReady, state)
+			}
+			if repository.callCount("index") == 0 {
+				t.Error("Expected the load to wait for the model in the repository index")
+			}
+			if resp.SizeInBytes != mockTritonModelMemoryUsage {
+				t.Errorf("Expected SizeInBytes to be the memory reported by Triton %d but got %d", mockTritonModelMemoryUsage, resp.SizeInBytes)
+			}
+
+			// the error of Triton is returned with its code
+			_, err = s.LoadModel(ctx, loadRequest(mockTritonBrokenModel))
+			if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "no version is available") {
+				t.Errorf("Expected the load of the broken model to fail with the error of Triton, got %v", err)
+			}
+
+			if _, err = s.UnloadModel(ctx, &mmesh.UnloadModelRequest{ModelId: "mnist"}); err != nil {
+				t.Fatalf("Failed to unload model: %v", err)
+			}
+			if _, loaded := repository.state("mnist"); loaded {
+				t.Error("Expected the model to be unloaded from Triton")
+			}
+			// a model that Triton does not have is unloaded all the same
+			if _, err = s.UnloadModel(ctx, &mmesh.UnloadModelRequest{ModelId: "mnist"}); err != nil {
+				t.Errorf("Expected the unload of a model not in Triton to succeed, got %v", err)
+			}
+			if calls := repository.callCount("unload"); calls != 2 {
+				t.Errorf("Expected 2 unloads in Triton, got %d", calls)
+			}
+		})
+	}
+}
+
+func TestTritonHttpClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/health/ready":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/v2/repository/models/unavailable/load":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "server is shutting down"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("not json"))
+		}
+	}))
+	defer server.Close()
+	client := newTritonHttpClient(server.URL)
+	ctx := context.Background()
+
+	// a server that is not ready is not an error
+	if resp, err := client.ServerReady(ctx, &triton.ServerReadyRequest{}); err != nil || resp.Ready {
+		t.Errorf("Expected the server not to be ready, got %v, %v", resp, err)
+	}
+	_, err := client.RepositoryModelLoad(ctx, &triton.RepositoryModelLoadRequest{ModelName: "unavailable"})
+	if s := status.Convert(err); s.Code() != codes.Unavailable || s.Message() != "server is shutting down" {
+		t.Errorf("Expected the error of the JSON response with code Unavailable, got %v", err)
+	}
+	_, err = client.RepositoryModelUnload(ctx, &triton.RepositoryModelUnloadRequest{ModelName: "other"})
+	if s := status.Convert(err); s.Code() != codes.Internal || s.Message() != "not json" {
+		t.Errorf("Expected the body of the response with code Internal, got %v", err)
+	}
+
+	server.Close()
+	if _, err = client.ServerMetadata(ctx, &triton.ServerMetadataRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected an unreachable server to be Unavailable, got %v", err)
+	}
+}
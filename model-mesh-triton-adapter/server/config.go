@@ -16,6 +16,7 @@ package server
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 
@@ -49,6 +50,38 @@ const (
 	defaultRootModelDir                      = "/models"
 	useEmbeddedPuller                 string = "USE_EMBEDDED_PULLER"
 	defaultUseEmbeddedPuller                 = false
+	strictModelKey                    string = "STRICT_MODEL_KEY"
+	defaultStrictModelKey                    = false
+	modelKeyMaxSize                   string = "MODEL_KEY_MAX_SIZE"
+	defaultModelKeyMaxSize                   = 0 // 0 means the size of the ModelKey is not limited
+	grpcReflection                    string = "GRPC_REFLECTION"
+	defaultGrpcReflection                    = false
+	grpcCompression                   string = "GRPC_COMPRESSION"
+	defaultGrpcCompression                   = false
+	metricsPort                       string = "METRICS_PORT"
+	defaultMetricsPort                       = 0 // 0 means the metrics are not served
+	layoutRetries                     string = "LAYOUT_RETRIES"
+	defaultLayoutRetries                     = 0 // 0 means transient filesystem errors are not retried
+	layoutRetryBackoff                string = "LAYOUT_RETRY_BACKOFF"
+	defaultLayoutRetryBackoff                = 500 * time.Millisecond
+	circuitBreakerThreshold           string = "RUNTIME_CIRCUIT_BREAKER_THRESHOLD"
+	defaultCircuitBreakerThreshold           = 0 // 0 means the breaker is disabled
+	circuitBreakerCooldown            string = "RUNTIME_CIRCUIT_BREAKER_COOLDOWN"
+	defaultCircuitBreakerCooldown            = 30 * time.Second
+	backendDirectory                  string = "TRITON_BACKEND_DIRECTORY"
+	defaultBackendDirectory                  = ""
+	modelReadyTimeout                 string = "MODEL_READY_TIMEOUT"
+	defaultModelReadyTimeout                 = 0 * time.Second // 0 means loads do not wait for the model to be ready
+	gpuCount                          string = "GPU_COUNT"
+	defaultGpuCount                          = -1 // -1 means the GPUs are counted from the NVIDIA devices
+	useRuntimeModelSize               string = "USE_RUNTIME_MODEL_SIZE"
+	defaultUseRuntimeModelSize               = false
+	modelConfigTemplateFile           string = "MODEL_CONFIG_TEMPLATE_FILE"
+	defaultModelConfigTemplateFile           = "" // empty means the generated configs have no defaults
+	controlProtocol                   string = "TRITON_CONTROL_PROTOCOL"
+	defaultControlProtocol                   = ControlProtocolGrpc
+	runtimeHttpPort                   string = "RUNTIME_HTTP_PORT"
+	defaultRuntimeHttpPort                   = 8000
 )
 
 func GetAdapterConfigurationFromEnv(log logr.Logger) (*AdapterConfiguration, error) {
@@ -65,18 +98,75 @@ func GetAdapterConfigurationFromEnv(log logr.Logger) (*AdapterConfiguration, err
 	adapterConfig.RuntimeVersion = GetEnvString(runtimeVersion, defaultRuntimeVersion)
 	adapterConfig.LimitModelConcurrency = GetEnvInt(limitPerModelConcurrency, defaultLimitPerModelConcurrency, log)
 	adapterConfig.UseEmbeddedPuller = GetEnvBool(useEmbeddedPuller, defaultUseEmbeddedPuller, log)
+	adapterConfig.StrictModelKey = GetEnvBool(strictModelKey, defaultStrictModelKey, log)
+	adapterConfig.ModelKeyMaxSize = GetEnvInt(modelKeyMaxSize, defaultModelKeyMaxSize, log)
+	adapterConfig.GrpcReflection = GetEnvBool(grpcReflection, defaultGrpcReflection, log)
+	adapterConfig.GrpcCompression = GetEnvBool(grpcCompression, defaultGrpcCompression, log)
+	adapterConfig.MetricsPort = GetEnvInt(metricsPort, defaultMetricsPort, log)
+	adapterConfig.CircuitBreakerThreshold = GetEnvInt(circuitBreakerThreshold, defaultCircuitBreakerThreshold, log)
+	adapterConfig.CircuitBreakerCooldown = GetEnvDuration(circuitBreakerCooldown, defaultCircuitBreakerCooldown, log)
+	adapterConfig.BackendDirectory = GetEnvString(backendDirectory, defaultBackendDirectory)
+	adapterConfig.LayoutRetries = GetEnvInt(layoutRetries, defaultLayoutRetries, log)
+	adapterConfig.LayoutRetryBackoff = GetEnvDuration(layoutRetryBackoff, defaultLayoutRetryBackoff, log)
+	adapterConfig.ModelReadyTimeout = GetEnvDuration(modelReadyTimeout, defaultModelReadyTimeout, log)
+	adapterConfig.GpuCount = GetEnvInt(gpuCount, defaultGpuCount, log)
+	adapterConfig.UseRuntimeModelSize = GetEnvBool(useRuntimeModelSize, defaultUseRuntimeModelSize, log)
+	adapterConfig.ControlProtocol = GetEnvString(controlProtocol, defaultControlProtocol)
+	adapterConfig.TritonHttpPort = GetEnvInt(runtimeHttpPort, defaultRuntimeHttpPort, log)
 
 	var err error
 	adapterConfig.RootModelDir, err = util.SecureJoin(GetEnvString(rootModelDir, defaultRootModelDir), tritonModelSubdir)
 	if err != nil {
 		return nil, fmt.Errorf("Could not construct root model path: %w", err)
 	}
+	if templateFile := GetEnvString(modelConfigTemplateFile, defaultModelConfigTemplateFile); templateFile != "" {
+		if adapterConfig.ModelConfigTemplate, err = readModelConfigTemplate(templateFile); err != nil {
+			return nil, fmt.Errorf("%s environment variable is invalid: %w", modelConfigTemplateFile, err)
+		}
+	}
 
 	if adapterConfig.TritonContainerMemReqBytes < 0 {
 		return nil, fmt.Errorf("%s environment variable must be set to a positive integer, found value %v", tritonContainerMemReqBytes, adapterConfig.TritonContainerMemReqBytes)
 	}
+	if adapterConfig.CircuitBreakerThreshold < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", circuitBreakerThreshold, adapterConfig.CircuitBreakerThreshold)
+	}
+	if adapterConfig.CircuitBreakerCooldown <= 0 {
+		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", circuitBreakerCooldown, adapterConfig.CircuitBreakerCooldown)
+	}
+	if adapterConfig.LayoutRetries < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", layoutRetries, adapterConfig.LayoutRetries)
+	}
+	if adapterConfig.LayoutRetryBackoff <= 0 {
+		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", layoutRetryBackoff, adapterConfig.LayoutRetryBackoff)
+	}
+	if adapterConfig.ModelReadyTimeout < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", modelReadyTimeout, adapterConfig.ModelReadyTimeout)
+	}
+	if adapterConfig.GpuCount < defaultGpuCount {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", gpuCount, adapterConfig.GpuCount)
+	}
+	if adapterConfig.GpuCount == defaultGpuCount {
+		if adapterConfig.GpuCount, err = countGpus(gpuDeviceDir); err != nil {
+			log.Info("Unable to count the GPUs, assuming there are none", "error", err)
+			adapterConfig.GpuCount = 0
+		}
+		log.Info("Counted the GPUs from the NVIDIA devices", "gpuCount", adapterConfig.GpuCount)
+	}
 	if adapterConfig.ModelSizeMultiplier <= 0 {
 		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", modelSizeMultiplier, adapterConfig.ModelSizeMultiplier)
 	}
+	if adapterConfig.ModelKeyMaxSize < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", modelKeyMaxSize, adapterConfig.ModelKeyMaxSize)
+	}
+	if adapterConfig.ControlProtocol != ControlProtocolGrpc && adapterConfig.ControlProtocol != ControlProtocolHttp {
+		return nil, fmt.Errorf("%s environment variable must be %q or %q, found value %q", controlProtocol, ControlProtocolGrpc, ControlProtocolHttp, adapterConfig.ControlProtocol)
+	}
+	if adapterConfig.TritonHttpPort <= 0 {
+		return nil, fmt.Errorf("%s environment variable must be greater than 0, found value %v", runtimeHttpPort, adapterConfig.TritonHttpPort)
+	}
+	if adapterConfig.MetricsPort < 0 {
+		return nil, fmt.Errorf("%s environment variable must not be negative, found value %v", metricsPort, adapterConfig.MetricsPort)
+	}
 	return adapterConfig, nil
 }
@@ -16,8 +16,11 @@ package server
 
 const (
 	tritonServiceName              string = "inference.GRPCInferenceService"
-	modelTypeJSONKey               string = "model_type"
 	tritonModelSubdir              string = "_triton_models"
 	tritonRepositoryConfigFilename string = "config.pbtxt"
 	tensorflowSavedModelDirName    string = "model.savedmodel"
+	tritonModelDirectoryVariable   string = "$$TRITON_MODEL_DIRECTORY"
+	pythonBackendName              string = "python"
+	pythonExecutionEnvParameter    string = "EXECUTION_ENV_PATH"
+	pythonExecutionEnvDirName      string = "_python_env"
 )
@@ -0,0 +1,43 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"os"
+	"regexp"
+)
+
+// directory with the device files of the container
+const gpuDeviceDir = "/dev"
+
+// the device files of the NVIDIA GPUs, eg. /dev/nvidia0, as opposed to the
+// control devices like /dev/nvidiactl and /dev/nvidia-uvm
+var nvidiaGpuDevicePattern = regexp.MustCompile(`^nvidia[0-9]+$`)
+
+// countGpus counts the NVIDIA GPUs with a device file in devDir, which are
+// the GPUs that are visible to the container
+func countGpus(devDir string) (int, error) {
+	entries, err := os.ReadDir(devDir)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, entry := range entries {
+		if nvidiaGpuDevicePattern.MatchString(entry.Name()) {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCountGpus(t *testing.T) {
+	devDir := t.TempDir()
+	for _, name := range []string{"nvidia0", "nvidia1", "nvidiactl", "nvidia-uvm", "nvidia-uvm-tools", "null"} {
+		if err := os.WriteFile(filepath.Join(devDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count, err := countGpus(devDir); err != nil || count != 2 {
+		t.Errorf("Expected 2 GPUs but got %d (error: %v)", count, err)
+	}
+	if count, err := countGpus(t.TempDir()); err != nil || count != 0 {
+		t.Errorf("Expected no GPUs but got %d (error: %v)", count, err)
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	triton "github.com/kserve/modelmesh-runtime-adapter/internal/proto/triton"
+)
+
+// the size of the pool of connections to the HTTP API of Triton, which is
+// enough for the concurrent loads and the polls of the repository index
+const httpClientMaxConns = 16
+
+// tritonHttpClient calls the HTTP/REST API of Triton, see
+// https://github.com/triton-inference-server/server/blob/main/docs/protocol/extension_model_repository.md
+//
+// The errors have the gRPC status codes of the errors that the gRPC API
+// returns for the same failures, so that they are handled alike.
+type tritonHttpClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// tritonHttpClient implements TritonClient
+var _ TritonClient = (*tritonHttpClient)(nil)
+
+func newTritonHttpClient(baseURL string) *tritonHttpClient {
+	return &tritonHttpClient{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			// Triton runs next to the adapter, so the requests are never
+			// proxied and the connections are kept open between the calls
+			Transport: &http.Transport{
+				MaxIdleConns:        httpClientMaxConns,
+				MaxIdleConnsPerHost: httpClientMaxConns,
+				MaxConnsPerHost:     httpClientMaxConns,
+				IdleConnTimeout:     90 * time.Second,
+				DisableCompression:  true,
+			},
+		},
+	}
+}
+
+func (c *tritonHttpClient) ServerReady(ctx context.Context, in *triton.ServerReadyRequest, opts ...grpc.CallOption) (*triton.ServerReadyResponse, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/v2/health/ready", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	// a server that is not ready responds with an error status
+	return &triton.ServerReadyResponse{Ready: resp.StatusCode == http.StatusOK}, nil
+}
+
+func (c *tritonHttpClient) ServerMetadata(ctx context.Context, in *triton.ServerMetadataRequest, opts ...grpc.CallOption) (*triton.ServerMetadataResponse, error) {
+	out := &triton.ServerMetadataResponse{}
+	if err := c.call(ctx, http.MethodGet, "/v2", nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tritonHttpClient) ModelStatistics(ctx context.Context, in *triton.ModelStatisticsRequest, opts ...grpc.CallOption) (*triton.ModelStatisticsResponse, error) {
+	path := "/v2/models/stats"
+	if in.Name != "" {
+		path = "/v2/models/" + url.PathEscape(in.Name)
+		if in.Version != "" {
+			path += "/versions/" + url.PathEscape(in.Version)
+		}
+		path += "/stats"
+	}
+	var body json.RawMessage
+	if err := c.call(ctx, http.MethodGet, path, nil, &body); err != nil {
+		return nil, err
+	}
+
+	out := &triton.ModelStatisticsResponse{}
+	if err := unmarshalResponse(body, out); err != nil {
+		return nil, err
+	}
+	// the memory usage is not in the generated messages, so it is added to
+	// the unknown fields as the gRPC API would send it, see runtimeModelSize
+	var memoryUsages struct {
+		ModelStats []struct {
+			MemoryUsage []struct {
+				Type     string `json:"type"`
+				Id       int64  `json:"id"`
+				ByteSize uint64 `json:"byte_size"`
+			} `json:"memory_usage"`
+		} `json:"model_stats"`
+	}
+	if err := json.Unmarshal(body, &memoryUsages); err != nil || len(memoryUsages.ModelStats) != len(out.ModelStats) {
+		return nil, status.Errorf(codes.Internal, "Invalid model statistics from Triton: %v", err)
+	}
+	for i, stats := range memoryUsages.ModelStats {
+		unknown := out.ModelStats[i].ProtoReflect().GetUnknown()
+		for _, memoryUsage := range stats.MemoryUsage {
+			var b []byte
+			b = protowire.AppendTag(b, 1, protowire.BytesType)
+			b = protowire.AppendString(b, memoryUsage.Type)
+			b = protowire.AppendTag(b, 2, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(memoryUsage.Id))
+			b = protowire.AppendTag(b, memoryUsageByteSizeField, protowire.VarintType)
+			b = protowire.AppendVarint(b, memoryUsage.ByteSize)
+
+			unknown = protowire.AppendTag(unknown, modelStatisticsMemoryUsageField, protowire.BytesType)
+			unknown = protowire.AppendBytes(unknown, b)
+		}
+		out.ModelStats[i].ProtoReflect().SetUnknown(unknown)
+	}
+	return out, nil
+}
+
+func (c *tritonHttpClient) RepositoryIndex(ctx context.Context, in *triton.RepositoryIndexRequest, opts ...grpc.CallOption) (*triton.RepositoryIndexResponse, error) {
+	var models []json.RawMessage
+	if err := c.call(ctx, http.MethodPost, repositoryPath(in.RepositoryName)+"/index", map[string]bool{"ready": in.Ready}, &models); err != nil {
+		return nil, err
+	}
+	out := &triton.RepositoryIndexResponse{Models: make([]*triton.RepositoryIndexResponse_ModelIndex, len(models))}
+	for i, model := range models {
+		out.Models[i] = &triton.RepositoryIndexResponse_ModelIndex{}
+		if err := unmarshalResponse(model, out.Models[i]); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (c *tritonHttpClient) RepositoryModelLoad(ctx context.Context, in *triton.RepositoryModelLoadRequest, opts ...grpc.CallOption) (*triton.RepositoryModelLoadResponse, error) {
+	path := repositoryPath(in.RepositoryName) + "/models/" + url.PathEscape(in.ModelName) + "/load"
+	if err := c.call(ctx, http.MethodPost, path, nil, nil); err != nil {
+		return nil, err
+	}
+	return &triton.RepositoryModelLoadResponse{}, nil
+}
+
+func (c *tritonHttpClient) RepositoryModelUnload(ctx context.Context, in *triton.RepositoryModelUnloadRequest, opts ...grpc.CallOption) (*triton.RepositoryModelUnloadResponse, error) {
+	path := repositoryPath(in.RepositoryName) + "/models/" + url.PathEscape(in.ModelName) + "/unload"
+	if err := c.call(ctx, http.MethodPost, path, nil, nil); err != nil {
+		return nil, err
+	}
+	return &triton.RepositoryModelUnloadResponse{}, nil
+}
+
+// repositoryPath returns the path of the repository API, for all the
+// repositories if the name is empty
+func repositoryPath(repositoryName string) string {
+	if repositoryName == "" {
+		return "/v2/repository"
+	}
+	return "/v2/repository/" + url.PathEscape(repositoryName)
+}
+
+// call sends the request with the JSON of in as the body, if it is not nil,
+// and decodes the JSON response into out, if it is not nil
+func (c *tritonHttpClient) call(ctx context.Context, method, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		inBytes, err := json.Marshal(in)
+		if err != nil {
+			return status.Errorf(codes.Internal, "Unable to marshal the request to Triton: %v", err)
+		}
+		body = bytes.NewReader(inBytes)
+	}
+	resp, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "Unable to read the response from Triton: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		message := string(respBytes)
+		if json.Unmarshal(respBytes, &errResp) == nil && errResp.Error != "" {
+			message = errResp.Error
+		}
+		return status.Error(httpStatusCode(resp.StatusCode), message)
+	}
+	if out == nil {
+		return nil
+	}
+	if err = json.Unmarshal(respBytes, out); err != nil {
+		return status.Errorf(codes.Internal, "Invalid response from Triton: %v", err)
+	}
+	return nil
+}
+
+// do sends the request, an error is returned only if there is no response
+func (c *tritonHttpClient) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Unable to create the request to Triton: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, status.FromContextError(ctxErr).Err()
+		}
+		return nil, status.Errorf(codes.Unavailable, "Unable to reach Triton: %v", err)
+	}
+	return resp, nil
+}
+
+// unmarshalResponse decodes a JSON object of the HTTP API into the message
+// of the gRPC API with the same fields, ignoring the fields it does not have
+func unmarshalResponse(b []byte, m proto.Message) error {
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(b, m); err != nil {
+		return status.Errorf(codes.Internal, "Invalid response from Triton: %v", err)
+	}
+	return nil
+}
+
+// httpStatusCode maps the status of an HTTP response of Triton to the code of
+// the gRPC API
+func httpStatusCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
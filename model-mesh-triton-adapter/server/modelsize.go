@@ -0,0 +1,119 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	triton "github.com/kserve/modelmesh-runtime-adapter/internal/proto/triton"
+)
+
+// Field numbers of the memory usage that newer versions of Triton report in
+// the ModelStatistics of a model, which the vendored proto does not define
+//
+//	message ModelStatistics { repeated MemoryUsage memory_usage = 8; }
+//	message MemoryUsage { string type = 1; int64 id = 2; uint64 byte_size = 3; }
+const (
+	modelStatisticsMemoryUsageField protowire.Number = 8
+	memoryUsageByteSizeField        protowire.Number = 3
+)
+
+// runtimeModelSize returns the memory that Triton reports the loaded model to
+// use, summed over the devices that it is loaded on, or 0 if Triton does not
+// report it
+func (s *TritonAdapterServer) runtimeModelSize(ctx context.Context, modelId string) (uint64, error) {
+	resp, err := s.Client.ModelStatistics(ctx, &triton.ModelStatisticsRequest{Name: modelId})
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get the model statistics from Triton: %w", err)
+	}
+
+	var size uint64
+	for _, stats := range resp.ModelStats {
+		if stats.Name != modelId {
+			continue
+		}
+		memoryUsages, err := unknownMessageFields(stats.ProtoReflect().GetUnknown(), modelStatisticsMemoryUsageField)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid model statistics from Triton: %w", err)
+		}
+		for _, memoryUsage := range memoryUsages {
+			byteSize, err := unknownVarintField(memoryUsage, memoryUsageByteSizeField)
+			if err != nil {
+				return 0, fmt.Errorf("Invalid memory usage in the model statistics from Triton: %w", err)
+			}
+			size += byteSize
+		}
+	}
+	return size, nil
+}
+
+// unknownMessageFields returns the encoded messages of the field in the
+// unknown fields of a message
+func unknownMessageFields(b []byte, field protowire.Number) ([][]byte, error) {
+	var messages [][]byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == field && typ == protowire.BytesType {
+			message, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			messages = append(messages, message)
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return messages, nil
+}
+
+// unknownVarintField returns the last value of a varint field of an encoded
+// message, 0 if it is not set
+func unknownVarintField(b []byte, field protowire.Number) (uint64, error) {
+	var value uint64
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == field && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+			value = v
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return value, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	triton "github.com/kserve/modelmesh-runtime-adapter/internal/proto/triton"
+)
+
+// statisticsTritonClient loads every model and reports the memory usage of
+// the models in their statistics, in bytes per device
+type statisticsTritonClient struct {
+	triton.GRPCInferenceServiceClient
+	memoryUsage map[string][]uint64
+}
+
+func (c *statisticsTritonClient) RepositoryModelLoad(ctx context.Context, in *triton.RepositoryModelLoadRequest, opts ...grpc.CallOption) (*triton.RepositoryModelLoadResponse, error) {
+	return &triton.RepositoryModelLoadResponse{}, nil
+}
+
+func (c *statisticsTritonClient) ModelStatistics(ctx context.Context, in *triton.ModelStatisticsRequest, opts ...grpc.CallOption) (*triton.ModelStatisticsResponse, error) {
+	stats := &triton.ModelStatistics{Name: in.Name, Version: "1"}
+	var unknown []byte
+	for i, byteSize := range c.memoryUsage[in.Name] {
+		var memoryUsage []byte
+		memoryUsage = protowire.AppendTag(memoryUsage, 1, protowire.BytesType)
+		memoryUsage = protowire.AppendString(memoryUsage, "GPU")
+		memoryUsage = protowire.AppendTag(memoryUsage, 2, protowire.VarintType)
+		memoryUsage = protowire.AppendVarint(memoryUsage, uint64(i))
+		memoryUsage = protowire.AppendTag(memoryUsage, memoryUsageByteSizeField, protowire.VarintType)
+		memoryUsage = protowire.AppendVarint(memoryUsage, byteSize)
+
+		unknown = protowire.AppendTag(unknown, modelStatisticsMemoryUsageField, protowire.BytesType)
+		unknown = protowire.AppendBytes(unknown, memoryUsage)
+	}
+	stats.ProtoReflect().SetUnknown(unknown)
+	return &triton.ModelStatisticsResponse{ModelStats: []*triton.ModelStatistics{stats}}, nil
+}
+
+func TestLoadModelRuntimeModelSize(t *testing.T) {
+	modelDir := t.TempDir()
+	createEmptyFile(filepath.Join(modelDir, "mnist", "1", "model.onnx"), t)
+
+	client := &statisticsTritonClient{memoryUsage: map[string][]uint64{
+		"reported":    {3000000, 4000000},
+		"notreported": nil,
+	}}
+	s := &TritonAdapterServer{
+		Client: client,
+		AdapterConfig: &AdapterConfiguration{
+			RootModelDir:            t.TempDir(),
+			DefaultModelSizeInBytes: defaultModelSizeInBytes,
+			ModelSizeMultiplier:     2,
+			UseRuntimeModelSize:     true,
+		},
+		Log: log,
+	}
+
+	testCases := []struct {
+		modelID      string
+		expectedSize uint64
+	}{
+		// the memory used on each device is added up
+		{"reported", 7000000},
+		// the estimated size is used if Triton does not report any memory
+		{"notreported", 2 * 12345},
+	}
+	for _, tc := range testCases {
+		resp, err := s.LoadModel(context.Background(), &mmesh.LoadModelRequest{
+			ModelId:   tc.modelID,
+			ModelType: "onnx",
+			ModelPath: filepath.Join(modelDir, "mnist"),
+			ModelKey:  `{"disk_size_bytes": 12345}`,
+		})
+		if err != nil {
+			t.Fatalf("Failed to load model %s: %v", tc.modelID, err)
+		}
+		if resp.SizeInBytes != tc.expectedSize {
+			t.Errorf("Expected SizeInBytes of model %s to be %d but got %d", tc.modelID, tc.expectedSize, resp.SizeInBytes)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+// name of the runtime in the RuntimeInfo
+const tritonRuntimeName = "triton"
+
+// supportedModelTypes are the model types that the adapter lays out for a
+// Triton backend, see modelTypeToBackendMapping
+//
+// A single model file is placed in the version directory under the file name
+// that Triton expects for the backend, and a .h5 Keras model is converted to a
+// TensorFlow SavedModel first.
+var supportedModelTypes = []util.ModelTypeInfo{
+	{Name: "tensorflow", Files: []string{"saved_model.pb", "variables/"}, SingleFile: true},
+	{Name: "onnx", Files: []string{"*.onnx"}, SingleFile: true},
+	{Name: "pytorch", Files: []string{"*.pt"}, SingleFile: true},
+	{Name: "tensorrt", Files: []string{"*.plan"}, SingleFile: true},
+	{Name: "keras", Files: []string{"*.h5"}, SingleFile: true},
+}
+
+// SupportedModelTypes returns the runtime and the model types of the adapter
+func (s *TritonAdapterServer) SupportedModelTypes() util.SupportedModelTypes {
+	runtime := util.RuntimeInfo{Name: tritonRuntimeName}
+	if s.AdapterConfig != nil {
+		runtime.Version = s.AdapterConfig.RuntimeVersion
+	}
+	return util.SupportedModelTypes{Runtime: runtime, ModelTypes: util.CopyModelTypes(supportedModelTypes)}
+}
+
+// RegisterModelTypesServer registers the mmesh.ModelTypes gRPC service
+func (s *TritonAdapterServer) RegisterModelTypesServer(grpcServer *grpc.Server) {
+	util.RegisterModelTypesServer(grpcServer, s.SupportedModelTypes)
+}
+
+// ModelTypesHandler serves the SupportedModelTypes as JSON
+func (s *TritonAdapterServer) ModelTypesHandler() http.Handler {
+	return util.ModelTypesHandler(s.SupportedModelTypes)
+}
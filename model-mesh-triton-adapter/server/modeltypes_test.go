@@ -0,0 +1,43 @@
+// Copyright 2022 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+)
+
+func TestSupportedModelTypes(t *testing.T) {
+	s := &TritonAdapterServer{AdapterConfig: &AdapterConfiguration{RuntimeVersion: "2.30.0"}}
+	types := s.SupportedModelTypes()
+	if types.Runtime.Name != "triton" || types.Runtime.Version != "2.30.0" {
+		t.Errorf("Expected the runtime triton 2.30.0 but got %+v", types.Runtime)
+	}
+
+	listed := make(map[string]bool)
+	for _, mt := range types.ModelTypes {
+		if len(mt.Files) == 0 {
+			t.Errorf("Expected the files of model type %s", mt.Name)
+		}
+		if _, ok := modelTypeToBackendMapping[mt.Name]; !ok {
+			t.Errorf("Model type %s is listed but has no Triton backend", mt.Name)
+		}
+		listed[mt.Name] = true
+	}
+	for modelType := range modelTypeToBackendMapping {
+		if !listed[modelType] {
+			t.Errorf("Model type %s has a Triton backend but is not listed", modelType)
+		}
+	}
+}
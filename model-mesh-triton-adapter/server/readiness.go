@@ -0,0 +1,105 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	triton "github.com/kserve/modelmesh-runtime-adapter/internal/proto/triton"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+// Model states reported in the repository index of Triton
+const (
+	tritonModelStateReady       string = "READY"
+	tritonModelStateLoading     string = "LOADING"
+	tritonModelStateUnavailable string = "UNAVAILABLE"
+)
+
+const modelReadyPollInterval = 250 * time.Millisecond
+
+// modelReadiness interprets the entry of a model in the repository index of
+// Triton, nil if the model is not in the index
+//
+// The model is ready when it is READY and failed when it is UNAVAILABLE. A
+// model without a state has not been loaded yet.
+func modelReadiness(index *triton.RepositoryIndexResponse_ModelIndex) (util.ModelReadiness, string) {
+	if index == nil {
+		return util.ModelFailed, "Expected model to load, but it is not in the repository index"
+	}
+
+	switch index.State {
+	case tritonModelStateReady:
+		return util.ModelReady, ""
+	case "", tritonModelStateLoading:
+		return util.ModelNotReady, fmt.Sprintf("Waiting for model to become ready, state: '%s'", index.State)
+	case tritonModelStateUnavailable:
+		return util.ModelFailed, fmt.Sprintf("Triton model load failed. state: '%s' reason: '%s'", index.State, index.Reason)
+	default:
+		return util.ModelFailed, fmt.Sprintf("Triton model load failed. Unexpected state: '%s'", index.State)
+	}
+}
+
+// waitForModelReady polls the repository index until the model is ready, for
+// up to the ModelReadyTimeout
+func (s *TritonAdapterServer) waitForModelReady(ctx context.Context, modelId string, log logr.Logger) error {
+	var index *triton.RepositoryIndexResponse_ModelIndex
+	refresh := func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		resp, err := s.Client.RepositoryIndex(ctx, &triton.RepositoryIndexRequest{})
+		if err != nil {
+			return err
+		}
+		index = nil
+		for _, model := range resp.Models {
+			if model.Name == modelId {
+				index = model
+				break
+			}
+		}
+		return nil
+	}
+	if err := refresh(); err != nil {
+		return status.Errorf(status.Code(err), "Failed to get the model state from Triton: %s", err)
+	}
+
+	var waitErr error
+	util.WaitForModelsReady([]string{modelId}, s.AdapterConfig.ModelReadyTimeout, modelReadyPollInterval, refresh,
+		func(string) (util.ModelReadiness, string) {
+			if err := ctx.Err(); err != nil {
+				return util.ModelFailed, err.Error()
+			}
+			return modelReadiness(index)
+		},
+		func(_ string, readiness util.ModelReadiness, message string) {
+			switch {
+			case ctx.Err() != nil:
+				waitErr = status.FromContextError(ctx.Err()).Err()
+			case readiness == util.ModelFailed:
+				waitErr = status.Errorf(codes.Internal, "Failed to load Model due to Triton runtime error: %s", message)
+			case readiness == util.ModelNotReady:
+				waitErr = status.Errorf(codes.DeadlineExceeded, "Timed out waiting for Triton to load the model: %s", message)
+			}
+		}, log)
+	return waitErr
+}
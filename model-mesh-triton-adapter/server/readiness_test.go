@@ -0,0 +1,42 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	triton "github.com/kserve/modelmesh-runtime-adapter/internal/proto/triton"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+func TestModelReadiness(t *testing.T) {
+	testCases := []struct {
+		name     string
+		index    *triton.RepositoryIndexResponse_ModelIndex
+		expected util.ModelReadiness
+	}{
+		{"ready", &triton.RepositoryIndexResponse_ModelIndex{Name: "model", Version: "1", State: "READY"}, util.ModelReady},
+		{"loading", &triton.RepositoryIndexResponse_ModelIndex{Name: "model", State: "LOADING"}, util.ModelNotReady},
+		{"not loaded yet", &triton.RepositoryIndexResponse_ModelIndex{Name: "model"}, util.ModelNotReady},
+		{"unavailable", &triton.RepositoryIndexResponse_ModelIndex{Name: "model", State: "UNAVAILABLE", Reason: "failed to load 'model'"}, util.ModelFailed},
+		{"unknown state", &triton.RepositoryIndexResponse_ModelIndex{Name: "model", State: "BROKEN"}, util.ModelFailed},
+		{"missing", nil, util.ModelFailed},
+	}
+	for _, tc := range testCases {
+		if got, message := modelReadiness(tc.index); got != tc.expected {
+			t.Errorf("Expected %s model to be %v, got %v: %s", tc.name, tc.expected, got, message)
+		}
+	}
+}
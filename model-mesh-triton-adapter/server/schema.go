@@ -41,6 +41,25 @@ var (
 		modelschema.FP32:   triton.DataType_TYPE_FP32,
 		modelschema.FP64:   triton.DataType_TYPE_FP64,
 		modelschema.STRING: triton.DataType_TYPE_STRING,
+		modelschema.BYTES:  triton.DataType_TYPE_STRING,
+	}
+
+	// schema datatypes that can be mapped to a Triton DataType
+	supportedDatatypes = []string{
+		modelschema.BOOL,
+		modelschema.UINT8,
+		modelschema.UINT16,
+		modelschema.UINT32,
+		modelschema.UINT64,
+		modelschema.INT8,
+		modelschema.INT16,
+		modelschema.INT32,
+		modelschema.INT64,
+		modelschema.FP16,
+		modelschema.FP32,
+		modelschema.FP64,
+		modelschema.STRING,
+		modelschema.BYTES,
 	}
 )
 
@@ -54,12 +73,20 @@ func convertSchemaToConfigFromFile(schemaFile string, log logr.Logger) (*triton.
 }
 
 func convertSchemaToConfig(schema modelschema.ModelSchema, log logr.Logger) (*triton.ModelConfig, error) {
+	if err := schema.ValidateDatatypes(supportedDatatypes); err != nil {
+		return nil, err
+	}
+
 	config := triton.ModelConfig{}
 
 	if schema.Inputs != nil {
 		config.Input = make([]*triton.ModelInput, len(schema.Inputs))
 		for index, input := range schema.Inputs {
-			modelInput := triton.ModelInput{Name: input.Name, Dims: input.Shape, DataType: TensorType[input.Datatype]}
+			dims, reshape, err := convertShapeToDims(input.Shape)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid shape for input tensor '%s' in model schema: %w", input.Name, err)
+			}
+			modelInput := triton.ModelInput{Name: input.Name, Dims: dims, Reshape: reshape, DataType: TensorType[input.Datatype]}
 			config.Input[index] = &modelInput
 		}
 	}
@@ -67,10 +94,32 @@ func convertSchemaToConfig(schema modelschema.ModelSchema, log logr.Logger) (*tr
 	if schema.Outputs != nil {
 		config.Output = make([]*triton.ModelOutput, len(schema.Outputs))
 		for index, output := range schema.Outputs {
-			modelOutput := triton.ModelOutput{Name: output.Name, Dims: output.Shape, DataType: TensorType[output.Datatype]}
+			dims, reshape, err := convertShapeToDims(output.Shape)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid shape for output tensor '%s' in model schema: %w", output.Name, err)
+			}
+			modelOutput := triton.ModelOutput{Name: output.Name, Dims: dims, Reshape: reshape, DataType: TensorType[output.Datatype]}
 			config.Output[index] = &modelOutput
 		}
 	}
 
 	return &config, nil
 }
+
+// convertShapeToDims returns the Triton dims of a tensor with the shape from
+// a schema, where -1 is a dynamic dimension and any other dimension must be
+// positive
+//
+// Triton requires at least one dimension, so a scalar, with an empty shape,
+// has the dims [1] with a reshape to the empty shape.
+func convertShapeToDims(shape []int64) ([]int64, *triton.ModelTensorReshape, error) {
+	for _, dim := range shape {
+		if dim <= 0 && dim != -1 {
+			return nil, nil, fmt.Errorf("dimension %d of shape %v must be positive or -1 for a dynamic dimension", dim, shape)
+		}
+	}
+	if len(shape) == 0 {
+		return []int64{1}, &triton.ModelTensorReshape{Shape: []int64{}}, nil
+	}
+	return append([]int64{}, shape...), nil, nil
+}
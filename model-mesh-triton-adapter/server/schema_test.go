@@ -15,6 +15,7 @@
 package server
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/kserve/modelmesh-runtime-adapter/internal/modelschema"
@@ -66,3 +67,112 @@ func TestConvertSchema(t *testing.T) {
 		t.Errorf("config: %s", config)
 	}
 }
+
+func TestConvertSchemaUnsupportedDatatype(t *testing.T) {
+	schema := modelschema.ModelSchema{
+		Inputs: []modelschema.TensorMetadata{
+			{
+				Name:     "inputs",
+				Datatype: modelschema.BF16,
+				Shape:    []int64{784},
+			},
+		},
+	}
+
+	if _, err := convertSchemaToConfig(schema, log); err == nil {
+		t.Error("Expected an error for unsupported datatype BF16")
+	} else if !strings.Contains(err.Error(), modelschema.BF16) {
+		t.Errorf("Expected error to name the unsupported datatype, got: %v", err)
+	}
+}
+
+func TestConvertSchemaShapes(t *testing.T) {
+	testCases := []struct {
+		name            string
+		shape           []int64
+		expectedDims    []int64
+		expectedReshape *triton.ModelTensorReshape
+		expectedError   string
+	}{
+		{
+			name:            "scalar",
+			shape:           []int64{},
+			expectedDims:    []int64{1},
+			expectedReshape: &triton.ModelTensorReshape{Shape: []int64{}},
+		},
+		{
+			name:         "fixed-shape",
+			shape:        []int64{3, 224, 224},
+			expectedDims: []int64{3, 224, 224},
+		},
+		{
+			name:         "dynamic-shape",
+			shape:        []int64{-1, 3, -1, -1},
+			expectedDims: []int64{-1, 3, -1, -1},
+		},
+		{
+			name:          "zero-dimension",
+			shape:         []int64{-1, 0},
+			expectedError: "dimension 0 of shape [-1 0]",
+		},
+		{
+			name:          "negative-dimension",
+			shape:         []int64{-2},
+			expectedError: "dimension -2 of shape [-2]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			schema := modelschema.ModelSchema{
+				Inputs:  []modelschema.TensorMetadata{{Name: "input", Datatype: modelschema.FP32, Shape: tc.shape}},
+				Outputs: []modelschema.TensorMetadata{{Name: "output", Datatype: modelschema.FP32, Shape: tc.shape}},
+			}
+
+			config, err := convertSchemaToConfig(schema, log)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("Expected error containing %q, got: %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expectedConfig := &triton.ModelConfig{
+				Input: []*triton.ModelInput{
+					{Name: "input", DataType: triton.DataType_TYPE_FP32, Dims: tc.expectedDims, Reshape: tc.expectedReshape},
+				},
+				Output: []*triton.ModelOutput{
+					{Name: "output", DataType: triton.DataType_TYPE_FP32, Dims: tc.expectedDims, Reshape: tc.expectedReshape},
+				},
+			}
+			if !proto.Equal(config, expectedConfig) {
+				t.Errorf("Expected config %s but got %s", expectedConfig, config)
+			}
+		})
+	}
+}
+
+func TestRemoveBatchDimensionOfScalar(t *testing.T) {
+	config, err := convertSchemaToConfig(modelschema.ModelSchema{
+		Outputs: []modelschema.TensorMetadata{
+			{Name: "score", Datatype: modelschema.FP32, Shape: []int64{-1}},
+			{Name: "probabilities", Datatype: modelschema.FP32, Shape: []int64{-1, 10}},
+		},
+	}, log)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	removeFirstDimensionFromInputsAndOutputs(config)
+
+	expectedOutputs := []*triton.ModelOutput{
+		{Name: "score", DataType: triton.DataType_TYPE_FP32, Dims: []int64{1}, Reshape: &triton.ModelTensorReshape{Shape: []int64{}}},
+		{Name: "probabilities", DataType: triton.DataType_TYPE_FP32, Dims: []int64{10}},
+	}
+	if !proto.Equal(config, &triton.ModelConfig{Output: expectedOutputs}) {
+		t.Errorf("Expected the scalar output to be reshaped, got %s", config)
+	}
+}
@@ -16,19 +16,16 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"time"
 
-	"google.golang.org/grpc/credentials/insecure"
-
 	"github.com/go-logr/logr"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/kserve/modelmesh-runtime-adapter/internal/modelkey"
 	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
 	triton "github.com/kserve/modelmesh-runtime-adapter/internal/proto/triton"
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
@@ -49,15 +46,36 @@ type AdapterConfiguration struct {
 	LimitModelConcurrency      int // 0 means no limit (default)
 	RootModelDir               string
 	UseEmbeddedPuller          bool
+	StrictModelKey             bool
+	ModelKeyMaxSize            int
+	GrpcReflection             bool
+	GrpcCompression            bool
+	MetricsPort                int // 0 means the metrics are not served
+	CircuitBreakerThreshold    int // 0 means the circuit breaker is disabled
+	CircuitBreakerCooldown     time.Duration
+	BackendDirectory           string // the --backend-directory of Triton, empty to skip checking that custom backends exist
+	LayoutRetries              int    // 0 means transient filesystem errors are not retried
+	LayoutRetryBackoff         time.Duration
+	ModelReadyTimeout          time.Duration // 0 means loads do not wait for the model to be ready
+	GpuCount                   int           // the GPUs that the instances_per_gpu of a ModelKey is multiplied by
+	UseRuntimeModelSize        bool          // report the memory that Triton reports a loaded model to use as its size
+	// defaults of the configs that the adapter generates, nil if there are none
+	ModelConfigTemplate *triton.ModelConfig
+	// the API of Triton that the models are loaded and unloaded with
+	ControlProtocol string
+	TritonHttpPort  int
 }
 
 type TritonAdapterServer struct {
-	Client        triton.GRPCInferenceServiceClient
-	Conn          *grpc.ClientConn
+	Client        TritonClient
+	Conn          *grpc.ClientConn // nil if Triton is called over HTTP
 	Puller        *puller.Puller
 	AdapterConfig *AdapterConfiguration
 	Log           logr.Logger
 
+	// fails loads fast while Triton keeps failing, nil if not configured
+	breaker *util.CircuitBreaker
+
 	// embed generated Unimplemented type for forward-compatibility for gRPC
 	mmesh.UnimplementedModelRuntimeServer
 }
@@ -65,24 +83,29 @@ type TritonAdapterServer struct {
 func NewTritonAdapterServer(runtimePort int, config *AdapterConfiguration, log logr.Logger) *TritonAdapterServer {
 	log = log.WithName("Triton Adapter Server")
 
-	log.Info("Connecting to Triton...", "port", runtimePort)
-	conn, err := grpc.DialContext(context.Background(), fmt.Sprintf("localhost:%d", runtimePort),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoff.DefaultConfig, MinConnectTimeout: 10 * time.Second}))
-	if err != nil {
-		log.Error(err, "Can not connect to Triton Runtime")
-		os.Exit(1)
-	}
-
 	s := new(TritonAdapterServer)
 	s.Log = log
 	s.AdapterConfig = config
-	s.Client = triton.NewGRPCInferenceServiceClient(conn)
-	s.Conn = conn
+	if config.ControlProtocol == ControlProtocolHttp {
+		log.Info("Connecting to the HTTP API of Triton...", "port", config.TritonHttpPort)
+		s.Client = newTritonHttpClient(fmt.Sprintf("http://localhost:%d", config.TritonHttpPort))
+	} else {
+		log.Info("Connecting to Triton...", "port", runtimePort)
+		conn, err := dialTritonGrpc(fmt.Sprintf("localhost:%d", runtimePort))
+		if err != nil {
+			log.Error(err, "Can not connect to Triton Runtime")
+			os.Exit(1)
+		}
+		s.Client = triton.NewGRPCInferenceServiceClient(conn)
+		s.Conn = conn
+	}
 	if s.AdapterConfig.UseEmbeddedPuller {
 		// puller is configured from its own env vars
 		s.Puller = puller.NewPuller(log)
 	}
+	if config.CircuitBreakerThreshold > 0 {
+		s.breaker = util.NewCircuitBreaker(config.CircuitBreakerThreshold, config.CircuitBreakerCooldown, s.probeHealth, log)
+	}
 
 	log.Info("Triton runtime adapter started")
 	return s
@@ -90,9 +113,26 @@ func NewTritonAdapterServer(runtimePort int, config *AdapterConfiguration, log l
 
 func (s *TritonAdapterServer) LoadModel(ctx context.Context, req *mmesh.LoadModelRequest) (*mmesh.LoadModelResponse, error) {
 	log := s.Log.WithName("Load Model").WithValues("model_id", req.ModelId)
-	modelType := getModelType(req, log)
+	if err := util.CheckModelKeyLimits(req, s.AdapterConfig.ModelKeyMaxSize); err != nil {
+		log.Error(err, "ModelKey exceeds the limits")
+		return nil, err
+	}
+	if s.AdapterConfig.StrictModelKey {
+		if err := util.ValidateModelKey(req); err != nil {
+			log.Error(err, "Invalid ModelKey")
+			return nil, err
+		}
+	}
+
+	modelType := util.GetModelType(req, log)
 	log.Info("Using model type", "model_type", modelType)
 
+	// don't pull and load models that are likely to time out while Triton is failing
+	if err := s.breaker.Allow(); err != nil {
+		log.Info("Failing load fast", "error", err)
+		return nil, err
+	}
+
 	if s.AdapterConfig.UseEmbeddedPuller {
 		var pullerErr error
 		req, pullerErr = s.Puller.ProcessLoadModelRequest(ctx, req)
@@ -108,8 +148,31 @@ func (s *TritonAdapterServer) LoadModel(ctx context.Context, req *mmesh.LoadMode
 		return nil, err
 	}
 
+	versionPolicy, err := util.GetVersionPolicy(req)
+	if err != nil {
+		return nil, err
+	}
+
+	modelKey, err := modelkey.ParseFields(req.ModelKey, modelkey.SequenceBatchingKey, modelkey.OptimizationKey,
+		modelkey.BackendKey, modelkey.ParametersKey, modelkey.InstancesPerGpuKey, modelkey.ModelFilenameKey)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid modelKey in LoadModelRequest. ModelKey value '%s' is not valid: %s", req.ModelKey, err)
+	}
+	keyConfig, err := getModelKeyConfig(modelKey, modelType, s.AdapterConfig.GpuCount)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if modelKey.Backend != "" {
+		if err = checkBackendAvailable(modelKey.Backend, req.ModelPath, s.AdapterConfig.BackendDirectory); err != nil {
+			log.Error(err, "Backend is not available")
+			return nil, err
+		}
+	}
+
 	// using the files downloaded by the puller, create a file layout that the runtime can understand and load from
-	err = adaptModelLayoutForRuntime(ctx, s.AdapterConfig.RootModelDir, req.ModelId, modelType, req.ModelPath, schemaPath, log)
+	err = util.RetryTransientFileErrors(ctx, s.AdapterConfig.LayoutRetries, s.AdapterConfig.LayoutRetryBackoff, log, func() error {
+		return adaptModelLayoutForRuntime(ctx, s.AdapterConfig.RootModelDir, req.ModelId, modelType, req.ModelPath, schemaPath, versionPolicy, keyConfig, s.AdapterConfig.ModelConfigTemplate, log)
+	})
 	if err != nil {
 		log.Error(err, "Failed to create model directory and load model")
 		return nil, status.Errorf(status.Code(err), "Failed to load Model due to adapter error: %s", err)
@@ -118,12 +181,30 @@ func (s *TritonAdapterServer) LoadModel(ctx context.Context, req *mmesh.LoadMode
 	_, tritonErr := s.Client.RepositoryModelLoad(ctx, &triton.RepositoryModelLoadRequest{
 		ModelName: req.ModelId,
 	})
+	s.recordRuntimeOutcome(tritonErr)
 	if tritonErr != nil {
 		log.Error(tritonErr, "Triton failed to load model")
 		return nil, status.Errorf(status.Code(tritonErr), "Failed to load Model due to Triton runtime error: %s", tritonErr)
 	}
 
+	if s.AdapterConfig.ModelReadyTimeout > 0 {
+		if err = s.waitForModelReady(ctx, req.ModelId, log); err != nil {
+			log.Error(err, "Triton model did not become ready")
+			return nil, err
+		}
+	}
+
 	size := util.CalcMemCapacity(req.ModelKey, s.AdapterConfig.DefaultModelSizeInBytes, s.AdapterConfig.ModelSizeMultiplier, log)
+	if s.AdapterConfig.UseRuntimeModelSize {
+		if runtimeSize, sizeErr := s.runtimeModelSize(ctx, req.ModelId); sizeErr != nil {
+			log.Info("Unable to get the model size from Triton, using the estimated size", "error", sizeErr, "sizeInBytes", size)
+		} else if runtimeSize == 0 {
+			log.Info("Triton did not report the memory used by the model, using the estimated size", "sizeInBytes", size)
+		} else {
+			log.Info("Using the memory that Triton reports the model to use as its size", "sizeInBytes", runtimeSize, "estimatedSizeInBytes", size)
+			size = runtimeSize
+		}
+	}
 
 	log.Info("Triton model loaded")
 
@@ -133,35 +214,11 @@ func (s *TritonAdapterServer) LoadModel(ctx context.Context, req *mmesh.LoadMode
 	}, nil
 }
 
-// getModelType first tries to read the type from the LoadModelRequest.ModelKey json
-// If there is an error parsing LoadModelRequest.ModelKey or the type is not found there, this will
-// return the LoadModelRequest.ModelType which could possibly be an empty string
-func getModelType(req *mmesh.LoadModelRequest, log logr.Logger) string {
-	modelType := req.ModelType
-	var modelKey map[string]interface{}
-	err := json.Unmarshal([]byte(req.ModelKey), &modelKey)
-	if err != nil {
-		log.Info("The model type will fall back to LoadModelRequest.ModelType as LoadModelRequest.ModelKey value is not valid JSON", "model_type", req.ModelType, "model_key", req.ModelKey, "error", err)
-	} else if modelKey[modelTypeJSONKey] == nil {
-		log.Info("The model type will fall back to LoadModelRequest.ModelType as LoadModelRequest.ModelKey does not have specified attribute", "model_type", req.ModelType, "attribute", modelTypeJSONKey)
-	} else if modelKeyModelType, ok := modelKey[modelTypeJSONKey].(map[string]interface{}); ok {
-		if str, ok := modelKeyModelType["name"].(string); ok {
-			modelType = str
-		} else {
-			log.Info("The model type will fall back to LoadModelRequest.ModelType as LoadModelRequest.ModelKey attribute is not a string.", "model_type", req.ModelType, "attribute", modelTypeJSONKey, "attribute_value", modelKey[modelTypeJSONKey])
-		}
-	} else if str, ok := modelKey[modelTypeJSONKey].(string); ok {
-		modelType = str
-	} else {
-		log.Info("The model type will fall back to LoadModelRequest.ModelType as LoadModelRequest.ModelKey attribute is not a string or map[string].", "model_type", req.ModelType, "attribute", modelTypeJSONKey, "attribute_value", modelKey[modelTypeJSONKey])
-	}
-	return modelType
-}
-
 func (s *TritonAdapterServer) UnloadModel(ctx context.Context, req *mmesh.UnloadModelRequest) (*mmesh.UnloadModelResponse, error) {
 	_, tritonErr := s.Client.RepositoryModelUnload(ctx, &triton.RepositoryModelUnloadRequest{
 		ModelName: req.ModelId,
 	})
+	s.recordRuntimeOutcome(tritonErr)
 
 	if tritonErr != nil {
 		// check if we got a gRPC error as a response that indicates that Triton
@@ -273,3 +330,25 @@ func (s *TritonAdapterServer) RuntimeStatus(ctx context.Context, req *mmesh.Runt
 	log.Info("runtimeStatus", "Status", runtimeStatus)
 	return runtimeStatus, nil
 }
+
+// recordRuntimeOutcome counts errors that indicate that Triton itself is
+// failing towards the circuit breaker; a model that fails to load does not
+func (s *TritonAdapterServer) recordRuntimeOutcome(tritonErr error) {
+	switch status.Code(tritonErr) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		s.breaker.RecordFailure()
+	default:
+		s.breaker.RecordSuccess()
+	}
+}
+
+func (s *TritonAdapterServer) probeHealth(ctx context.Context) error {
+	resp, err := s.Client.ServerReady(ctx, &triton.ServerReadyRequest{})
+	if err != nil {
+		return err
+	}
+	if !resp.Ready {
+		return fmt.Errorf("Triton is not ready")
+	}
+	return nil
+}
@@ -49,6 +49,21 @@ func (m *MockPullerInterface) EXPECT() *MockPullerInterfaceMockRecorder {
 	return m.recorder
 }
 
+// List mocks base method.
+func (m *MockPullerInterface) List(arg0 context.Context, arg1 pullman.ListCommand) ([]pullman.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1)
+	ret0, _ := ret[0].([]pullman.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockPullerInterfaceMockRecorder) List(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockPullerInterface)(nil).List), arg0, arg1)
+}
+
 // Pull mocks base method.
 func (m *MockPullerInterface) Pull(arg0 context.Context, arg1 pullman.PullCommand) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,102 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+// Values of KeyCaseCollisions, what to do with the objects of a model whose
+// keys differ only by case
+const (
+	KeyCaseCollisionsIgnore = "ignore"
+	KeyCaseCollisionsWarn   = "warn"
+	KeyCaseCollisionsReject = "reject"
+)
+
+// checkKeyCaseCollisions lists the objects of the model target and looks for
+// keys that differ only by case, which are the same key on a case-insensitive
+// storage gateway and the same file on a case-insensitive filesystem, so that
+// one file silently overwrites the other
+//
+// The collisions are logged, or with KeyCaseCollisionsReject fail the pull
+// with FailedPrecondition. Storage that cannot be listed is not checked, and
+// neither are targets of a single object like a tar archive or a version.
+func (s *Puller) checkKeyCaseCollisions(ctx context.Context, modelID string, repositoryConfig pullman.Config, target pullman.Target) error {
+	policy := s.PullerConfig.KeyCaseCollisions
+	if policy == "" || policy == KeyCaseCollisionsIgnore || target.ExtractTar || target.VersionID != "" {
+		return nil
+	}
+
+	objects, err := s.PullManager.List(ctx, pullman.ListCommand{
+		RepositoryConfig: repositoryConfig,
+		Prefix:           target.RemotePath,
+	})
+	if errors.Is(err, pullman.ErrListNotSupported) {
+		s.Log.V(1).Info("Not checking the keys of the model for case collisions, the storage cannot be listed", "modelId", modelID)
+		return nil
+	}
+	if err != nil {
+		// the pull reports any problem with the storage itself
+		s.Log.Info("Not checking the keys of the model for case collisions, listing them failed", "modelId", modelID, "error", err)
+		return nil
+	}
+
+	collisions := findKeyCaseCollisions(objects)
+	if len(collisions) == 0 {
+		return nil
+	}
+	groups := make([]string, len(collisions))
+	for i, keys := range collisions {
+		groups[i] = strings.Join(keys, ", ")
+	}
+	if policy == KeyCaseCollisionsReject {
+		s.Log.Info("Rejecting model with keys that differ only by case", "modelId", modelID, "keys", collisions)
+		return status.Errorf(codes.FailedPrecondition, "Model %s has files whose keys differ only by case and would overwrite each other: %s",
+			modelID, strings.Join(groups, "; "))
+	}
+	s.Log.Info("Warning: model has files whose keys differ only by case, one of them may overwrite the other", "modelId", modelID, "keys", collisions)
+	return nil
+}
+
+// findKeyCaseCollisions returns the sorted groups of object paths that are
+// equal ignoring case
+func findKeyCaseCollisions(objects []pullman.ObjectInfo) [][]string {
+	byFolded := make(map[string][]string, len(objects))
+	for _, obj := range objects {
+		folded := strings.ToLower(obj.Path)
+		byFolded[folded] = append(byFolded[folded], obj.Path)
+	}
+
+	var collisions [][]string
+	for _, keys := range byFolded {
+		if len(keys) > 1 {
+			sort.Strings(keys)
+			collisions = append(collisions, keys)
+		}
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i][0] < collisions[j][0]
+	})
+	return collisions
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+func Test_ProcessLoadModelRequest_KeyCaseCollisions(t *testing.T) {
+	objects := []pullman.ObjectInfo{
+		{Path: "models/mnist/1/model.onnx", Size: 1024},
+		{Path: "models/mnist/1/Model.onnx", Size: 2048},
+		{Path: "models/mnist/labels.txt", Size: 10},
+	}
+	request := func() *mmesh.LoadModelRequest {
+		return &mmesh.LoadModelRequest{
+			ModelId:   "mnist",
+			ModelPath: "models/mnist",
+			ModelType: "rt:ovms",
+			ModelKey:  `{"storage_key": "myStorage", "model_type": {"name": "onnx"}}`,
+		}
+	}
+
+	for _, policy := range []string{KeyCaseCollisionsIgnore, KeyCaseCollisionsWarn, KeyCaseCollisionsReject} {
+		t.Run(policy, func(t *testing.T) {
+			p, mockPuller := newPullerWithMock(t)
+			p.PullerConfig.KeyCaseCollisions = policy
+			var logLines []string
+			p.Log = funcr.New(func(prefix, args string) {
+				logLines = append(logLines, args)
+			}, funcr.Options{})
+
+			if policy != KeyCaseCollisionsIgnore {
+				mockPuller.EXPECT().List(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, lc pullman.ListCommand) ([]pullman.ObjectInfo, error) {
+						assert.Equal(t, "models/mnist", lc.Prefix)
+						return objects, nil
+					}).
+					Times(1)
+			}
+			if policy != KeyCaseCollisionsReject {
+				mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			}
+
+			_, err := p.ProcessLoadModelRequest(context.Background(), request())
+			warned := strings.Contains(strings.Join(logLines, "\n"), "models/mnist/1/Model.onnx")
+			switch policy {
+			case KeyCaseCollisionsIgnore:
+				assert.NoError(t, err)
+				assert.False(t, warned)
+			case KeyCaseCollisionsWarn:
+				assert.NoError(t, err)
+				assert.True(t, warned, "expected a warning with the colliding keys, got %v", logLines)
+			case KeyCaseCollisionsReject:
+				assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+				assert.Contains(t, err.Error(), "models/mnist/1/Model.onnx, models/mnist/1/model.onnx")
+			}
+		})
+	}
+}
+
+func Test_CheckKeyCaseCollisions_Skipped(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+	p.PullerConfig.KeyCaseCollisions = KeyCaseCollisionsReject
+	config := pullman.NewRepositoryConfig("s3", nil)
+
+	// storage that cannot be listed, or fails to list, is not checked
+	mockPuller.EXPECT().List(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("%w by repositories of type 's3'", pullman.ErrListNotSupported)).
+		Times(1)
+	assert.NoError(t, p.checkKeyCaseCollisions(context.Background(), "mnist", config, pullman.Target{RemotePath: "models/mnist"}))
+	mockPuller.EXPECT().List(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("unable to list objects in bucket 'default'")).
+		Times(1)
+	assert.NoError(t, p.checkKeyCaseCollisions(context.Background(), "mnist", config, pullman.Target{RemotePath: "models/mnist"}))
+
+	// nor are targets of a single object
+	assert.NoError(t, p.checkKeyCaseCollisions(context.Background(), "mnist", config, pullman.Target{RemotePath: "models/mnist.tar", ExtractTar: true}))
+	assert.NoError(t, p.checkKeyCaseCollisions(context.Background(), "mnist", config, pullman.Target{RemotePath: "models/mnist.onnx", VersionID: "v1"}))
+}
+
+func Test_FindKeyCaseCollisions(t *testing.T) {
+	collisions := findKeyCaseCollisions([]pullman.ObjectInfo{
+		{Path: "m/b/X.bin"},
+		{Path: "m/a.txt"},
+		{Path: "m/B/x.bin"},
+		{Path: "m/A.TXT"},
+		{Path: "m/a.TXT"},
+		{Path: "m/c.bin"},
+	})
+	assert.Equal(t, [][]string{
+		{"m/A.TXT", "m/a.TXT", "m/a.txt"},
+		{"m/B/x.bin", "m/b/X.bin"},
+	}, collisions)
+
+	assert.Empty(t, findKeyCaseCollisions([]pullman.ObjectInfo{{Path: "m/a"}, {Path: "m/b"}}))
+}
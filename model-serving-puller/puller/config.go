@@ -18,18 +18,43 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
 
 	. "github.com/kserve/modelmesh-runtime-adapter/internal/envconfig"
 )
 
 // PullerConfiguration stores configuration variables for the puller server
 type PullerConfiguration struct {
-	RootModelDir            string // Root directory to store models
-	StorageConfigurationDir string
+	RootModelDir                string // Root directory to store models
+	StorageConfigurationDir     string
+	MaxConcurrentPulls          int           // Maximum number of models pulled at the same time, 0 for no limit
+	PullQueueTimeout            time.Duration // Maximum time a pull waits for one of the MaxConcurrentPulls, 0 to wait until the request's deadline
+	WarmUpStorageKeys           []string      // Storage keys whose clients are created at startup
+	PostLoadHook                string        // Executable run with the model ID and directory after each pull, empty for none
+	PostLoadHookTimeout         time.Duration // Maximum time the PostLoadHook may run
+	DiskSizePrecedence          string        // Whether the size of the model files ("file") or the provided size ("key") wins
+	DiskSizeFile                string        // Name of a file in the model directory that holds the size of the model, used without disk_size_bytes, empty for none
+	DefaultStorageKey           string        // Storage key used by requests without a storage_key or storage type, empty for "default"
+	MaxInFlightBytes            int64         // Maximum estimated size of the models pulled at the same time, 0 for no limit
+	PullSizeEstimate            int64         // Size reserved from MaxInFlightBytes for a model without disk_size_bytes in its ModelKey
+	MaxDownloadConcurrency      int           // Upper bound for the download_concurrency in a ModelKey
+	ArtifactCacheDir            string        // Directory of the cache of small files shared between models, empty to disable the cache
+	ArtifactCacheMaxBytes       int64         // Maximum total size of the files in the ArtifactCacheDir
+	ArtifactCacheMaxObjectBytes int64         // Maximum size of a file in the ArtifactCacheDir
+	RejectEmptyModelFiles       bool          // Fail pulls with DataLoss if a file with one of the ModelFileExtensions is empty
+	ModelFileExtensions         []string      // Extensions of the files checked by RejectEmptyModelFiles, empty for the defaults
+	AllowedStorageTypes         []string      // Storage types that models may be pulled from, empty to allow every type
+	MinFreeInodes               int64         // Inodes that must be free on the filesystem of the RootModelDir to pull a model, 0 for no check
+	StorageUserAgent            string        // User-Agent sent to the storage by storage configs without a user_agent, empty for the defaults of the providers
+	KeyCaseCollisions           string        // Whether model files whose keys differ only by case are ignored, logged ("warn") or fail the pull ("reject")
+	ModelKeyMaxSize             int           // Maximum size in bytes of the ModelKey of a request, checked before it is parsed, 0 for no limit
 }
 
 // StorageConfiguration models the json credentials read from a storage secret
@@ -51,10 +76,87 @@ func GetPullerConfigFromEnv(log logr.Logger) (*PullerConfiguration, error) {
 	pullerConfig := new(PullerConfiguration)
 	pullerConfig.RootModelDir = GetEnvString("ROOT_MODEL_DIR", "/models")
 	pullerConfig.StorageConfigurationDir = GetEnvString("STORAGE_CONFIG_DIR", "/storage-config")
+	pullerConfig.MaxConcurrentPulls = GetEnvInt("MAX_CONCURRENT_PULLS", 0, log)
+	pullerConfig.PullQueueTimeout = GetEnvDuration("PULL_QUEUE_TIMEOUT", 0, log)
+	pullerConfig.WarmUpStorageKeys = splitList(GetEnvString("WARM_UP_STORAGE_KEYS", ""))
+	pullerConfig.PostLoadHook = GetEnvString("POST_LOAD_HOOK", "")
+	pullerConfig.PostLoadHookTimeout = GetEnvDuration("POST_LOAD_HOOK_TIMEOUT", defaultPostLoadHookTimeout, log)
+	pullerConfig.DiskSizePrecedence = GetEnvString("DISK_SIZE_PRECEDENCE", util.DiskSizePrecedenceFile)
+	pullerConfig.DiskSizeFile = GetEnvString("DISK_SIZE_FILE", "")
+	pullerConfig.DefaultStorageKey = strings.TrimSpace(GetEnvString("DEFAULT_STORAGE_KEY", ""))
+	pullerConfig.MaxInFlightBytes = int64(GetEnvInt("MAX_IN_FLIGHT_BYTES", 0, log))
+	pullerConfig.PullSizeEstimate = int64(GetEnvInt("PULL_SIZE_ESTIMATE_BYTES", defaultPullSizeEstimate, log))
+	pullerConfig.MaxDownloadConcurrency = GetEnvInt("MAX_DOWNLOAD_CONCURRENCY", defaultMaxDownloadConcurrency, log)
+	pullerConfig.ArtifactCacheDir = GetEnvString("ARTIFACT_CACHE_DIR", "")
+	pullerConfig.ArtifactCacheMaxBytes = int64(GetEnvInt("ARTIFACT_CACHE_MAX_BYTES", defaultArtifactCacheMaxBytes, log))
+	pullerConfig.ArtifactCacheMaxObjectBytes = int64(GetEnvInt("ARTIFACT_CACHE_MAX_OBJECT_BYTES", defaultArtifactCacheMaxObjectBytes, log))
+	pullerConfig.RejectEmptyModelFiles = GetEnvBool("REJECT_EMPTY_MODEL_FILES", false, log)
+	pullerConfig.ModelFileExtensions = splitList(GetEnvString("MODEL_FILE_EXTENSIONS", ""))
+	pullerConfig.AllowedStorageTypes = splitList(GetEnvString("ALLOWED_STORAGE_TYPES", ""))
+	pullerConfig.MinFreeInodes = int64(GetEnvInt("MIN_FREE_INODES", 0, log))
+	pullerConfig.StorageUserAgent = GetEnvString("STORAGE_USER_AGENT", "")
+	pullerConfig.KeyCaseCollisions = GetEnvString("KEY_CASE_COLLISIONS", KeyCaseCollisionsIgnore)
+	pullerConfig.ModelKeyMaxSize = GetEnvInt("MODEL_KEY_MAX_SIZE", 0, log)
+
+	if pullerConfig.MaxConcurrentPulls < 0 {
+		return nil, fmt.Errorf("MAX_CONCURRENT_PULLS environment variable must not be negative, got %d", pullerConfig.MaxConcurrentPulls)
+	}
+	if pullerConfig.PullQueueTimeout < 0 {
+		return nil, fmt.Errorf("PULL_QUEUE_TIMEOUT environment variable must not be negative, got %s", pullerConfig.PullQueueTimeout)
+	}
+	if pullerConfig.MaxInFlightBytes < 0 {
+		return nil, fmt.Errorf("MAX_IN_FLIGHT_BYTES environment variable must not be negative, got %d", pullerConfig.MaxInFlightBytes)
+	}
+	if pullerConfig.PullSizeEstimate <= 0 {
+		return nil, fmt.Errorf("PULL_SIZE_ESTIMATE_BYTES environment variable must be positive, got %d", pullerConfig.PullSizeEstimate)
+	}
+	if pullerConfig.MaxDownloadConcurrency <= 0 {
+		return nil, fmt.Errorf("MAX_DOWNLOAD_CONCURRENCY environment variable must be positive, got %d", pullerConfig.MaxDownloadConcurrency)
+	}
+	if pullerConfig.ArtifactCacheMaxBytes <= 0 {
+		return nil, fmt.Errorf("ARTIFACT_CACHE_MAX_BYTES environment variable must be positive, got %d", pullerConfig.ArtifactCacheMaxBytes)
+	}
+	if pullerConfig.ArtifactCacheMaxObjectBytes <= 0 {
+		return nil, fmt.Errorf("ARTIFACT_CACHE_MAX_OBJECT_BYTES environment variable must be positive, got %d", pullerConfig.ArtifactCacheMaxObjectBytes)
+	}
+	if pullerConfig.ModelKeyMaxSize < 0 {
+		return nil, fmt.Errorf("MODEL_KEY_MAX_SIZE environment variable must not be negative, got %d", pullerConfig.ModelKeyMaxSize)
+	}
+	if pullerConfig.MinFreeInodes < 0 {
+		return nil, fmt.Errorf("MIN_FREE_INODES environment variable must not be negative, got %d", pullerConfig.MinFreeInodes)
+	}
+	if pullerConfig.PostLoadHookTimeout <= 0 {
+		return nil, fmt.Errorf("POST_LOAD_HOOK_TIMEOUT environment variable must be positive, got %s", pullerConfig.PostLoadHookTimeout)
+	}
+	if pullerConfig.DiskSizePrecedence != util.DiskSizePrecedenceFile && pullerConfig.DiskSizePrecedence != util.DiskSizePrecedenceKey {
+		return nil, fmt.Errorf("DISK_SIZE_PRECEDENCE environment variable must be '%s' or '%s', got '%s'", util.DiskSizePrecedenceFile, util.DiskSizePrecedenceKey, pullerConfig.DiskSizePrecedence)
+	}
+
+	switch pullerConfig.KeyCaseCollisions {
+	case KeyCaseCollisionsIgnore, KeyCaseCollisionsWarn, KeyCaseCollisionsReject:
+	default:
+		return nil, fmt.Errorf("KEY_CASE_COLLISIONS environment variable must be '%s', '%s' or '%s', got '%s'",
+			KeyCaseCollisionsIgnore, KeyCaseCollisionsWarn, KeyCaseCollisionsReject, pullerConfig.KeyCaseCollisions)
+	}
+
+	if name := pullerConfig.DiskSizeFile; name != "" && (name != filepath.Base(name) || name == "." || name == "..") {
+		return nil, fmt.Errorf("DISK_SIZE_FILE environment variable must be a file name, got '%s'", name)
+	}
 
 	return pullerConfig, nil
 }
 
+// splitList splits a comma separated list, dropping empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // GetStorageConfiguration returns configuration read from the mounted secret at the given key
 func (config *PullerConfiguration) GetStorageConfiguration(storageKey string, log logr.Logger) (map[string]interface{}, error) {
 	// TODO: cache the storage configs in memory and watch for changes
@@ -68,18 +170,26 @@ func (config *PullerConfiguration) GetStorageConfiguration(storageKey string, lo
 
 	log.V(1).Info("Reading storage credentials")
 
-	if _, err = os.Stat(configPath); os.IsNotExist(err) {
+	info, err := os.Stat(configPath)
+	if os.IsNotExist(err) {
 		// TODO consider a retry period in case the secret isn't updated yet
 		return nil, fmt.Errorf("Storage secretKey not found: %s", storageKey)
 	}
 
-	bytes, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("Could not read storage configuration from %s: %v", configPath, err)
-	}
 	var storageConfig map[string]interface{}
-	if err = json.Unmarshal(bytes, &storageConfig); err != nil {
-		return nil, fmt.Errorf("Could not parse storage configuration json from %s: %v", configPath, err)
+	if err == nil && info.IsDir() {
+		// the config is split into a file per field
+		if storageConfig, err = pullman.ReadConfigDir(configPath); err != nil {
+			return nil, fmt.Errorf("Could not read storage configuration from %s: %v", configPath, err)
+		}
+	} else {
+		bytes, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read storage configuration from %s: %v", configPath, err)
+		}
+		if err = json.Unmarshal(bytes, &storageConfig); err != nil {
+			return nil, fmt.Errorf("Could not parse storage configuration json from %s: %v", configPath, err)
+		}
 	}
 
 	// copy fields where PullMan uses a different key for s3
@@ -94,5 +204,41 @@ func (config *PullerConfiguration) GetStorageConfiguration(storageKey string, lo
 		}
 	}
 
+	if err = checkRequiredStorageConfigFields(storageConfig); err != nil {
+		return nil, fmt.Errorf("Invalid storage configuration %s: %w", storageKey, err)
+	}
+
 	return storageConfig, nil
 }
+
+// requiredStorageConfigFields are the fields that the storage configuration
+// of each storage type must have, as alternatives where one of several fields
+// will do. The fields that can differ per model, like a bucket or a URL, are
+// not required here since the storage parameters of a model can set them.
+var requiredStorageConfigFields = map[string][][]string{
+	"s3":      {{"access_key_id"}, {"secret_access_key"}, {"endpoint_url", "endpoints"}},
+	"azure":   {{"account_name"}, {"container"}},
+	"webhdfs": {{"url"}},
+}
+
+// checkRequiredStorageConfigFields returns an error naming every required
+// field of the storage type that the storage configuration is missing
+func checkRequiredStorageConfigFields(storageConfig map[string]interface{}) error {
+	storageType, _ := storageConfig["type"].(string)
+	var missing []string
+	for _, alternatives := range requiredStorageConfigFields[storageType] {
+		found := false
+		for _, field := range alternatives {
+			if _, found = storageConfig[field]; found {
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, strings.Join(alternatives, " or "))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("storage type %s requires the fields %s", storageType, strings.Join(missing, ", "))
+	}
+	return nil
+}
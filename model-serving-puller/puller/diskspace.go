@@ -0,0 +1,48 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+// availableDiskSpace is replaced in tests to fake a filesystem
+var availableDiskSpace = util.AvailableDiskSpace
+
+// checkFreeInodes fails with ResourceExhausted if fewer than MinFreeInodes
+// inodes are free on the filesystem of the RootModelDir
+//
+// A model of many small files can exhaust the inodes while there are still
+// free bytes, and the pull would then fail part way. If the free inodes
+// cannot be determined, the model is pulled.
+func (s *Puller) checkFreeInodes(modelID string) error {
+	if s.PullerConfig.MinFreeInodes <= 0 {
+		return nil
+	}
+	space, err := availableDiskSpace(s.PullerConfig.RootModelDir)
+	if err != nil {
+		s.Log.Error(err, "Unable to check the free inodes, pulling the model anyway", "modelId", modelID)
+		return nil
+	}
+	if space.FreeInodes < uint64(s.PullerConfig.MinFreeInodes) {
+		s.Log.Info("Rejecting model, too few inodes are free", "modelId", modelID, "freeInodes", space.FreeInodes, "minFreeInodes", s.PullerConfig.MinFreeInodes)
+		return status.Errorf(codes.ResourceExhausted, "Only %d inodes are free on the filesystem of %s, at least %d are required to pull a model",
+			space.FreeInodes, s.PullerConfig.RootModelDir, s.PullerConfig.MinFreeInodes)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+func Test_ProcessLoadModelRequest_MinFreeInodes(t *testing.T) {
+	originalDiskSpace := availableDiskSpace
+	defer func() { availableDiskSpace = originalDiskSpace }()
+
+	// a filesystem with plenty of bytes but few inodes left
+	availableDiskSpace = func(path string) (util.DiskSpace, error) {
+		return util.DiskSpace{FreeBytes: 100 * 1024 * 1024 * 1024, FreeInodes: 50}, nil
+	}
+
+	for _, tc := range []struct {
+		minFreeInodes int64
+		expectedCode  codes.Code
+	}{
+		{0, codes.OK},
+		{50, codes.OK},
+		{1000, codes.ResourceExhausted},
+	} {
+		p, mockPuller := newPullerWithMock(t)
+		p.PullerConfig.MinFreeInodes = tc.minFreeInodes
+
+		pulls := 1
+		if tc.expectedCode != codes.OK {
+			pulls = 0
+		}
+		mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil).Times(pulls)
+
+		_, err := p.ProcessLoadModelRequest(context.Background(), &mmesh.LoadModelRequest{
+			ModelId:   "testmodel",
+			ModelPath: "model.zip",
+			ModelType: "rt:triton",
+			ModelKey:  `{"storage_key": "myStorage", "bucket": "bucket1"}`,
+		})
+		assert.Equal(t, tc.expectedCode, status.Code(err), "MinFreeInodes %d: %v", tc.minFreeInodes, err)
+	}
+}
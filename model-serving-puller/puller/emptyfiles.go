@@ -0,0 +1,84 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultModelFileExtensions are the extensions of the files that hold the
+// weights or graph of a model in the formats of the supported runtimes
+var defaultModelFileExtensions = []string{
+	"bin", "bst", "h5", "joblib", "keras", "onnx", "pb", "pkl", "plan", "pt", "pth", "savedmodel", "tflite", "xml",
+}
+
+// modelFileExtensions defaults to defaultModelFileExtensions for
+// configurations that do not set them
+func (s *Puller) modelFileExtensions() map[string]bool {
+	extensions := s.PullerConfig.ModelFileExtensions
+	if len(extensions) == 0 {
+		extensions = defaultModelFileExtensions
+	}
+	set := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		set[strings.ToLower(strings.TrimPrefix(ext, "."))] = true
+	}
+	return set
+}
+
+// checkEmptyModelFiles fails with DataLoss if a file with one of the
+// ModelFileExtensions under the model path is empty, which is almost always a
+// failed upload that the runtime would report with an obscure error
+//
+// Files with other extensions, like labels or configs, may be empty.
+func (s *Puller) checkEmptyModelFiles(modelID string, modelPath string) error {
+	if !s.PullerConfig.RejectEmptyModelFiles {
+		return nil
+	}
+	extensions := s.modelFileExtensions()
+
+	// the model path may be a symlink, eg. to a PVC
+	root := modelPath
+	if resolved, err := filepath.EvalSymlinks(modelPath); err == nil {
+		root = resolved
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("Error checking the model files of %s: %w", modelID, err)
+		}
+		if !d.Type().IsRegular() || !extensions[strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("Error checking the model files of %s: %w", modelID, err)
+		}
+		if info.Size() == 0 {
+			name, _ := filepath.Rel(root, path)
+			if name == "." {
+				name = filepath.Base(modelPath)
+			}
+			s.Log.Info("Rejecting model with an empty model file", "modelId", modelID, "file", name)
+			return status.Errorf(codes.DataLoss, "Model file %s of model %s is empty, it may not have been uploaded completely", name, modelID)
+		}
+		return nil
+	})
+}
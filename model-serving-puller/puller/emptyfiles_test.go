@@ -0,0 +1,137 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+func Test_ProcessLoadModelRequest_EmptyModelFile(t *testing.T) {
+	for _, reject := range []bool{true, false} {
+		p, mockPuller := newPullerWithMock(t)
+		p.PullerConfig.RootModelDir = t.TempDir()
+		p.PullerConfig.RejectEmptyModelFiles = reject
+
+		// the model.onnx is empty, while an empty labels file is allowed
+		mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, pc pullman.PullCommand) error {
+				modelDir := filepath.Join(pc.Directory, "mnist", "1")
+				if err := os.MkdirAll(modelDir, 0755); err != nil {
+					return err
+				}
+				if err := os.WriteFile(filepath.Join(modelDir, "model.onnx"), nil, 0644); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(modelDir, "labels.txt"), nil, 0644)
+			}).
+			Times(1)
+
+		_, err := p.ProcessLoadModelRequest(context.Background(), &mmesh.LoadModelRequest{
+			ModelId:   "mnist",
+			ModelPath: "models/mnist",
+			ModelType: "rt:ovms",
+			ModelKey:  `{"storage_key": "myStorage", "model_type": {"name": "onnx"}}`,
+		})
+		if reject {
+			assert.Equal(t, codes.DataLoss, status.Code(err))
+			assert.Contains(t, err.Error(), filepath.Join("1", "model.onnx"))
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+// pulls through the S3 provider, which skips empty objects unless the puller
+// asks to keep them
+func Test_ProcessLoadModelRequest_EmptyModelFileFromS3(t *testing.T) {
+	objects := map[string]string{
+		"models/mnist/1/model.onnx": "",
+		"models/mnist/1/labels.txt": "label",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("list-type") == "2" {
+			fmt.Fprint(w, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>bucket</Name><IsTruncated>false</IsTruncated>`)
+			for key, content := range objects {
+				fmt.Fprintf(w, `<Contents><Key>%s</Key><Size>%d</Size></Contents>`, key, len(content))
+			}
+			fmt.Fprint(w, `</ListBucketResult>`)
+			return
+		}
+		content, ok := objects[r.URL.Path[len("/bucket/"):]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		fmt.Fprint(w, content)
+	}))
+	defer server.Close()
+
+	storageConfigDir := t.TempDir()
+	storageConfig := fmt.Sprintf(`{"type": "s3", "access_key_id": "key", "secret_access_key": "secret", "endpoint_url": "%s", "region": "us-east-1", "bucket": "bucket"}`, server.URL)
+	assert.NoError(t, os.WriteFile(filepath.Join(storageConfigDir, "myStorage"), []byte(storageConfig), 0644))
+
+	for _, reject := range []bool{true, false} {
+		p := NewPullerFromConfig(zap.New(), &PullerConfiguration{
+			RootModelDir:            t.TempDir(),
+			StorageConfigurationDir: storageConfigDir,
+			RejectEmptyModelFiles:   reject,
+		})
+
+		_, err := p.ProcessLoadModelRequest(context.Background(), &mmesh.LoadModelRequest{
+			ModelId:   "mnist",
+			ModelPath: "models/mnist",
+			ModelType: "rt:ovms",
+			ModelKey:  `{"storage_key": "myStorage", "model_type": {"name": "onnx"}}`,
+		})
+		if reject {
+			assert.Equal(t, codes.DataLoss, status.Code(err))
+			assert.Contains(t, err.Error(), filepath.Join("1", "model.onnx"))
+		} else {
+			assert.NoError(t, err)
+			modelDir := filepath.Join(p.PullerConfig.RootModelDir, "mnist", "mnist", "1")
+			assert.FileExists(t, filepath.Join(modelDir, "labels.txt"))
+			assert.NoFileExists(t, filepath.Join(modelDir, "model.onnx"))
+		}
+	}
+}
+
+func Test_CheckEmptyModelFiles_Extensions(t *testing.T) {
+	p, _ := newPullerWithMock(t)
+	p.PullerConfig.RejectEmptyModelFiles = true
+	p.PullerConfig.ModelFileExtensions = []string{".safetensors"}
+
+	modelDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(modelDir, "model.onnx"), nil, 0644))
+	assert.NoError(t, p.checkEmptyModelFiles("model", modelDir))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(modelDir, "model.SafeTensors"), nil, 0644))
+	assert.Equal(t, codes.DataLoss, status.Code(p.checkEmptyModelFiles("model", modelDir)))
+}
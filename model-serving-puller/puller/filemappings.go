@@ -0,0 +1,98 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/modelkey"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+// validateFileMappings checks that each file mapping has a key and a
+// destination within the model path
+func validateFileMappings(mappings []modelkey.FileMapping) error {
+	for _, m := range mappings {
+		if m.Key == "" || m.Dest == "" {
+			return status.Errorf(codes.InvalidArgument, "ModelKey %s must each have a key and a dest, got key '%s' and dest '%s'", modelkey.FileMappingsKey, m.Key, m.Dest)
+		}
+		dest := filepath.Clean(filepath.FromSlash(m.Dest))
+		if filepath.IsAbs(dest) || dest == "." || dest == ".." || strings.HasPrefix(dest, ".."+string(filepath.Separator)) {
+			return status.Errorf(codes.InvalidArgument, "ModelKey %s dest '%s' must be a relative path within the model", modelkey.FileMappingsKey, m.Dest)
+		}
+	}
+	return nil
+}
+
+// pullFileMappings pulls the file at the key of each file mapping into its
+// destination within the model path, which must be a directory
+//
+// The files are pulled with a command of their own after the model files,
+// with a target per mapping whose LocalPath names the destination.
+func (s *Puller) pullFileMappings(ctx context.Context, pullCommand pullman.PullCommand, modelPathFilename string, mappings []modelkey.FileMapping) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	modelFullPath := filepath.Join(pullCommand.Directory, modelPathFilename)
+	info, err := os.Lstat(modelFullPath)
+	if err != nil {
+		return fmt.Errorf("Error reading the model path for the file mappings: %w", err)
+	}
+	// the files would be written into the storage that the link points to
+	if info.Mode()&os.ModeSymlink != 0 {
+		return status.Errorf(codes.InvalidArgument, "ModelKey %s is not supported for a model that is linked from its storage, eg. a PVC", modelkey.FileMappingsKey)
+	}
+	if !info.IsDir() {
+		return status.Errorf(codes.InvalidArgument, "ModelKey %s requires the model path to be a directory, but the model path is the single file %s", modelkey.FileMappingsKey, modelPathFilename)
+	}
+
+	targets := make([]pullman.Target, len(mappings))
+	for i, m := range mappings {
+		targets[i] = pullman.Target{
+			RemotePath: m.Key,
+			LocalPath:  filepath.Join(modelPathFilename, filepath.FromSlash(m.Dest)),
+		}
+	}
+	mappingCommand := pullCommand
+	mappingCommand.Targets = targets
+	if err = s.PullManager.Pull(ctx, mappingCommand); err != nil {
+		return status.Errorf(status.Code(err), "Error pulling the file mappings: %s", err)
+	}
+
+	// a key that is a prefix of several objects is pulled into a directory
+	for i, m := range mappings {
+		dest, err := util.SecureJoin(pullCommand.Directory, targets[i].LocalPath)
+		if err != nil {
+			return fmt.Errorf("Error joining paths '%s' and '%s': %w", pullCommand.Directory, targets[i].LocalPath, err)
+		}
+		info, err := os.Stat(dest)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "ModelKey %s key %s did not pull a file to %s: %s", modelkey.FileMappingsKey, m.Key, m.Dest, err)
+		}
+		if !info.Mode().IsRegular() {
+			return status.Errorf(codes.InvalidArgument, "ModelKey %s key %s must be a single file", modelkey.FileMappingsKey, m.Key)
+		}
+		s.Log.V(1).Info("Pulled file mapping", "key", m.Key, "dest", dest)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+// pullFiles writes a file for each of the files in storage under the remote
+// path of each target of the command to its local path, like a storage
+// provider would
+func pullFiles(t *testing.T, files map[string]string) func(context.Context, pullman.PullCommand) error {
+	return func(_ context.Context, pc pullman.PullCommand) error {
+		for _, target := range pc.Targets {
+			localPath := filepath.ToSlash(target.LocalPath)
+			if localPath == "" {
+				localPath = path.Base(target.RemotePath)
+			}
+			for key, contents := range files {
+				var rel string
+				switch {
+				case key == target.RemotePath:
+					rel = localPath
+				case strings.HasPrefix(key, target.RemotePath+"/"):
+					rel = path.Join(localPath, strings.TrimPrefix(key, target.RemotePath+"/"))
+				default:
+					continue
+				}
+				filename := filepath.Join(pc.Directory, filepath.FromSlash(rel))
+				if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func Test_ProcessLoadModelRequest_FileMappings(t *testing.T) {
+	files := map[string]string{
+		"models/mnist/1/weights.bin": "weights",
+		"exports/mnist-v2.onnx":      "onnx",
+		"exports/configs/a.json":     "a",
+		"exports/configs/b.json":     "b",
+	}
+	request := func(modelKey string) *mmesh.LoadModelRequest {
+		return &mmesh.LoadModelRequest{
+			ModelId:   "mnist",
+			ModelPath: "models/mnist",
+			ModelType: "rt:triton",
+			ModelKey:  modelKey,
+		}
+	}
+
+	t.Run("renamed", func(t *testing.T) {
+		p, mockPuller := newPullerWithMock(t)
+		p.PullerConfig.RootModelDir = t.TempDir()
+		var pulledDirs []string
+		mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, pc pullman.PullCommand) error {
+			pulledDirs = append(pulledDirs, pc.Directory)
+			return pullFiles(t, files)(ctx, pc)
+		}).Times(2)
+
+		returnRequest, err := p.ProcessLoadModelRequest(context.Background(),
+			request(`{"storage_key": "myStorage", "file_mappings": [{"key": "exports/mnist-v2.onnx", "dest": "1/model.onnx"}]}`))
+		assert.NoError(t, err)
+
+		modelDir := filepath.Join(p.PullerConfig.RootModelDir, "mnist")
+		assert.Equal(t, filepath.Join(modelDir, "mnist"), returnRequest.ModelPath)
+		contents, err := os.ReadFile(filepath.Join(returnRequest.ModelPath, "1", "model.onnx"))
+		assert.NoError(t, err)
+		assert.Equal(t, "onnx", string(contents))
+		// the model files are pulled as usual
+		_, err = os.Stat(filepath.Join(returnRequest.ModelPath, "1", "weights.bin"))
+		assert.NoError(t, err)
+		// the mapped files are pulled together after the model files
+		assert.Equal(t, []string{modelDir, modelDir}, pulledDirs)
+	})
+
+	t.Run("single file model path", func(t *testing.T) {
+		p, mockPuller := newPullerWithMock(t)
+		p.PullerConfig.RootModelDir = t.TempDir()
+		mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).DoAndReturn(pullFiles(t, files)).Times(1)
+
+		_, err := p.ProcessLoadModelRequest(context.Background(), &mmesh.LoadModelRequest{
+			ModelId:   "mnist",
+			ModelPath: "exports/mnist-v2.onnx",
+			ModelType: "rt:triton",
+			ModelKey:  `{"storage_key": "myStorage", "file_mappings": [{"key": "exports/configs/a.json", "dest": "config.json"}]}`,
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, err.Error(), "requires the model path to be a directory")
+	})
+
+	t.Run("not a single file", func(t *testing.T) {
+		p, mockPuller := newPullerWithMock(t)
+		p.PullerConfig.RootModelDir = t.TempDir()
+		mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).DoAndReturn(pullFiles(t, files)).Times(2)
+
+		_, err := p.ProcessLoadModelRequest(context.Background(),
+			request(`{"storage_key": "myStorage", "file_mappings": [{"key": "exports/configs", "dest": "config.json"}]}`))
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, err.Error(), "key exports/configs must be a single file")
+	})
+
+	for _, dest := range []string{"", "/etc/model.onnx", "../model.onnx", "1/../../model.onnx"} {
+		t.Run("dest "+dest, func(t *testing.T) {
+			p, _ := newPullerWithMock(t)
+			p.PullerConfig.RootModelDir = t.TempDir()
+
+			_, err := p.ProcessLoadModelRequest(context.Background(),
+				request(`{"storage_key": "myStorage", "file_mappings": [{"key": "exports/mnist-v2.onnx", "dest": "`+dest+`"}]}`))
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
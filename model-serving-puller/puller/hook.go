@@ -0,0 +1,75 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPostLoadHookTimeout = 5 * time.Minute
+
+	// only the end of the hook's output is included in errors
+	maxPostLoadHookOutput = 4096
+)
+
+// runPostLoadHook runs the configured PostLoadHook with the model ID and the
+// directory the model was pulled into as arguments
+//
+// A hook that exits with a nonzero code or runs for longer than the
+// PostLoadHookTimeout fails the load, with the hook's output in the error.
+func (s *Puller) runPostLoadHook(ctx context.Context, modelID string, modelDir string) error {
+	if s.PullerConfig.PostLoadHook == "" {
+		return nil
+	}
+	log := s.Log.WithValues("hook", s.PullerConfig.PostLoadHook, "modelId", modelID)
+
+	hookCtx, cancel := context.WithTimeout(ctx, s.PullerConfig.PostLoadHookTimeout)
+	defer cancel()
+
+	log.Info("Running post-load hook")
+	start := time.Now()
+	output, err := exec.CommandContext(hookCtx, s.PullerConfig.PostLoadHook, modelID, modelDir).CombinedOutput()
+	if err == nil {
+		log.Info("Post-load hook completed", "duration", time.Since(start))
+		return nil
+	}
+
+	outputTail := strings.TrimSpace(string(output))
+	if len(outputTail) > maxPostLoadHookOutput {
+		outputTail = "..." + outputTail[len(outputTail)-maxPostLoadHookOutput:]
+	}
+
+	var code codes.Code
+	switch {
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	case errors.Is(hookCtx.Err(), context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+		err = fmt.Errorf("timed out after %s", s.PullerConfig.PostLoadHookTimeout)
+	default:
+		code = codes.Internal
+	}
+	log.Error(err, "Post-load hook failed", "output", outputTail)
+	return status.Errorf(code, "Post-load hook %s failed for model %s: %s, output: %s", s.PullerConfig.PostLoadHook, modelID, err, outputTail)
+}
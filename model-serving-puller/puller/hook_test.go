@@ -0,0 +1,72 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func writeHookScript(t *testing.T, script string) string {
+	hookPath := filepath.Join(t.TempDir(), "hook.sh")
+	if err := os.WriteFile(hookPath, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("Failed to write hook script: %v", err)
+	}
+	return hookPath
+}
+
+func Test_RunPostLoadHook_Success(t *testing.T) {
+	p, _ := newPullerWithMock(t)
+	modelDir := t.TempDir()
+	p.PullerConfig.PostLoadHook = writeHookScript(t, `echo "$1" > "$2/hook.out"`)
+	p.PullerConfig.PostLoadHookTimeout = 10 * time.Second
+
+	err := p.runPostLoadHook(context.Background(), "testmodel", modelDir)
+	assert.NoError(t, err)
+
+	out, err := os.ReadFile(filepath.Join(modelDir, "hook.out"))
+	assert.NoError(t, err)
+	assert.Equal(t, "testmodel\n", string(out))
+}
+
+func Test_RunPostLoadHook_Failure(t *testing.T) {
+	p, _ := newPullerWithMock(t)
+	p.PullerConfig.PostLoadHook = writeHookScript(t, "echo conversion failed >&2\nexit 3")
+	p.PullerConfig.PostLoadHookTimeout = 10 * time.Second
+
+	err := p.runPostLoadHook(context.Background(), "testmodel", t.TempDir())
+	assert.Error(t, err)
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Contains(t, err.Error(), "exit status 3")
+	assert.Contains(t, err.Error(), "conversion failed")
+}
+
+func Test_RunPostLoadHook_Timeout(t *testing.T) {
+	p, _ := newPullerWithMock(t)
+	p.PullerConfig.PostLoadHook = writeHookScript(t, "exec sleep 10")
+	p.PullerConfig.PostLoadHookTimeout = 100 * time.Millisecond
+
+	err := p.runPostLoadHook(context.Background(), "testmodel", t.TempDir())
+	assert.Error(t, err)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Contains(t, err.Error(), "timed out")
+}
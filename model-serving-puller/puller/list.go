@@ -0,0 +1,52 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/modelkey"
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+// ListObjects returns the objects under the prefix in the storage of the
+// storage key, without downloading them
+//
+// The storage is resolved like the storage of a model, so a nil storage key
+// uses the default storage and the storage parameters override the storage
+// config. Storage that cannot be listed returns an Unimplemented error.
+func (s *Puller) ListObjects(ctx context.Context, storageKey *string, storageParams map[string]string, prefix string) ([]pullman.ObjectInfo, error) {
+	storageConfig, storageType, err := s.getStorageConfig(&modelkey.ModelKey{StorageKey: storageKey, StorageParams: storageParams})
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := s.PullManager.List(ctx, pullman.ListCommand{
+		RepositoryConfig: pullman.NewRepositoryConfig(storageType, storageConfig),
+		Prefix:           prefix,
+	})
+	if errors.Is(err, pullman.ErrListNotSupported) {
+		return nil, status.Errorf(codes.Unimplemented, "Listing objects in storage of type %s is not supported", storageType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list objects under '%s': %w", prefix, err)
+	}
+	return objects, nil
+}
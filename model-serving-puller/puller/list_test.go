@@ -0,0 +1,80 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+func Test_ListObjects(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+
+	expectedConfig, err := readStorageConfig("myStorage")
+	assert.NoError(t, err)
+	expectedConfig.Set("default_bucket", "default") // from myStorage
+	expectedConfig.Set("bucket", "bucket1")         // from storage_params
+
+	expectedObjects := []pullman.ObjectInfo{
+		{Path: "models/mnist/model.onnx", Size: 1024},
+	}
+	mockPuller.EXPECT().List(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, lc pullman.ListCommand) ([]pullman.ObjectInfo, error) {
+			assert.Equal(t, "models/", lc.Prefix)
+			assert.Equal(t, expectedConfig, lc.RepositoryConfig)
+			return expectedObjects, nil
+		}).
+		Times(1)
+
+	storageKey := "myStorage"
+	objects, err := p.ListObjects(context.Background(), &storageKey, map[string]string{"bucket": "bucket1"}, "models/")
+	assert.NoError(t, err)
+	assert.Equal(t, expectedObjects, objects)
+}
+
+func Test_ListObjects_Errors(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+	storageKey := "myStorage"
+
+	mockPuller.EXPECT().List(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("%w by repositories of type 's3'", pullman.ErrListNotSupported)).
+		Times(1)
+	_, err := p.ListObjects(context.Background(), &storageKey, nil, "models/")
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	mockPuller.EXPECT().List(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("unable to list objects in bucket 'default'")).
+		Times(1)
+	_, err = p.ListObjects(context.Background(), &storageKey, nil, "models/")
+	assert.ErrorContains(t, err, "unable to list objects in bucket 'default'")
+
+	// the storage is resolved like for a pull
+	missingKey := "missing"
+	_, err = p.ListObjects(context.Background(), &missingKey, nil, "models/")
+	assert.ErrorContains(t, err, "Did not find storage config for key missing")
+
+	p.PullerConfig.AllowedStorageTypes = []string{"gcs"}
+	_, err = p.ListObjects(context.Background(), &storageKey, nil, "models/")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const metricLoadQueueWaitSeconds = "load_queue_wait_seconds"
+
+// loadQueueWaitBuckets are the upper bounds in seconds of the buckets of the
+// load_queue_wait_seconds histogram
+var loadQueueWaitBuckets = []float64{0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 120, 300}
+
+// pullerMetrics tracks how long the loads wait for the MaxConcurrentPulls and
+// MaxInFlightBytes limiters before they are pulled
+//
+// The metrics are served in the Prometheus text exposition format.
+type pullerMetrics struct {
+	mutex sync.Mutex
+	// cumulative counts of the waits up to each of the loadQueueWaitBuckets
+	queueWaitBuckets []uint64
+	queueWaitCount   uint64
+	queueWaitSum     float64
+}
+
+func newPullerMetrics() *pullerMetrics {
+	return &pullerMetrics{
+		queueWaitBuckets: make([]uint64, len(loadQueueWaitBuckets)),
+	}
+}
+
+func (m *pullerMetrics) observeQueueWait(wait time.Duration) {
+	seconds := wait.Seconds()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i, bound := range loadQueueWaitBuckets {
+		if seconds <= bound {
+			m.queueWaitBuckets[i]++
+		}
+	}
+	m.queueWaitCount++
+	m.queueWaitSum += seconds
+}
+
+// ServeHTTP writes the current values of the metrics
+func (m *pullerMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# HELP %s Time that loads waited for the pull limiters before they could be pulled, in seconds.\n", metricLoadQueueWaitSeconds)
+	fmt.Fprintf(&sb, "# TYPE %s histogram\n", metricLoadQueueWaitSeconds)
+	for i, bound := range loadQueueWaitBuckets {
+		fmt.Fprintf(&sb, "%s_bucket{le=\"%s\"} %d\n", metricLoadQueueWaitSeconds, strconv.FormatFloat(bound, 'g', -1, 64), m.queueWaitBuckets[i])
+	}
+	fmt.Fprintf(&sb, "%s_bucket{le=\"+Inf\"} %d\n", metricLoadQueueWaitSeconds, m.queueWaitCount)
+	fmt.Fprintf(&sb, "%s_sum %s\n", metricLoadQueueWaitSeconds, strconv.FormatFloat(m.queueWaitSum, 'g', -1, 64))
+	fmt.Fprintf(&sb, "%s_count %d\n", metricLoadQueueWaitSeconds, m.queueWaitCount)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprint(w, sb.String())
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+func Test_ProcessLoadModelRequest_QueueWaitMetric(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+	p.PullerConfig.MaxConcurrentPulls = 1
+	p.pullSlots = semaphore.NewWeighted(1)
+
+	newRequest := func() *mmesh.LoadModelRequest {
+		return &mmesh.LoadModelRequest{
+			ModelId:   "singlefile",
+			ModelPath: "model.zip",
+			ModelType: "rt:triton",
+			ModelKey:  `{"storage_key": "myStorage", "model_type": {"name": "tensorflow"}}`,
+		}
+	}
+
+	// the first pull holds the only slot until it is released
+	started := make(chan struct{})
+	release := make(chan struct{})
+	gomock.InOrder(
+		mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ pullman.PullCommand) error {
+				close(started)
+				<-release
+				return nil
+			}),
+		mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil),
+	)
+
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := p.ProcessLoadModelRequest(context.Background(), newRequest())
+		firstErr <- err
+	}()
+	<-started
+
+	// the second load waits in the queue until the slot is released
+	time.AfterFunc(200*time.Millisecond, func() { close(release) })
+	_, err := p.ProcessLoadModelRequest(context.Background(), newRequest())
+	assert.NoError(t, err)
+	assert.NoError(t, <-firstErr)
+
+	p.metrics.mutex.Lock()
+	count, sum := p.metrics.queueWaitCount, p.metrics.queueWaitSum
+	p.metrics.mutex.Unlock()
+	assert.Equal(t, uint64(2), count)
+	assert.GreaterOrEqual(t, sum, 0.2)
+
+	// the saturated wait falls in the buckets above 0.1s only
+	rec := httptest.NewRecorder()
+	p.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE load_queue_wait_seconds histogram\n")
+	assert.Contains(t, body, "load_queue_wait_seconds_bucket{le=\"0.1\"} 1\n")
+	assert.Contains(t, body, "load_queue_wait_seconds_bucket{le=\"+Inf\"} 2\n")
+	assert.Contains(t, body, "load_queue_wait_seconds_count 2\n")
+}
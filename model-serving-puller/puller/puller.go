@@ -18,49 +18,70 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/kserve/modelmesh-runtime-adapter/internal/modelkey"
 	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 	"github.com/kserve/modelmesh-runtime-adapter/pullman"
 	_ "github.com/kserve/modelmesh-runtime-adapter/pullman/storageproviders/azure"
 	_ "github.com/kserve/modelmesh-runtime-adapter/pullman/storageproviders/gcs"
 	_ "github.com/kserve/modelmesh-runtime-adapter/pullman/storageproviders/http"
+	_ "github.com/kserve/modelmesh-runtime-adapter/pullman/storageproviders/huggingface"
 	_ "github.com/kserve/modelmesh-runtime-adapter/pullman/storageproviders/pvc"
 	_ "github.com/kserve/modelmesh-runtime-adapter/pullman/storageproviders/s3"
+	_ "github.com/kserve/modelmesh-runtime-adapter/pullman/storageproviders/signedurl"
+	_ "github.com/kserve/modelmesh-runtime-adapter/pullman/storageproviders/webhdfs"
 )
 
 const (
-	parameterKeyType  = "type"
-	defaultStorageKey = "default"
+	parameterKeyType = "type"
+	// the version of the model object, which the storage config may set
+	// instead of the ModelKey
+	parameterKeyVersionID = "version_id"
+	defaultStorageKey     = "default"
+	// the storage type whose provider can pull a version of an object
+	versionIDStorageType = "s3"
+	// reserved from the in-flight bytes for models of unknown size
+	defaultPullSizeEstimate = 256 * 1024 * 1024
+	// upper bound for the download_concurrency of a ModelKey
+	defaultMaxDownloadConcurrency = 32
+	// bounds of the cache of small files shared between models
+	defaultArtifactCacheMaxBytes       = 256 * 1024 * 1024
+	defaultArtifactCacheMaxObjectBytes = 1024 * 1024
 )
 
-// JSON passed in ModelInfo.Key field of registration requests
-type ModelKeyInfo struct {
-	// Pass through model_type as-is (it's actually a json object hence interface{} type)
-	ModelType     interface{}       `json:"model_type,omitempty"`
-	Bucket        string            `json:"bucket,omitempty"`
-	DiskSizeBytes int64             `json:"disk_size_bytes"`
-	SchemaPath    *string           `json:"schema_path,omitempty"`
-	StorageKey    *string           `json:"storage_key,omitempty"`
-	StorageParams map[string]string `json:"storage_params,omitempty"`
-}
-
 // Puller represents the GRPC server and its configuration
 type Puller struct {
 	PullerConfig *PullerConfiguration
 	Log          logr.Logger
 	PullManager  PullerInterface
+
+	// limits the number of concurrent pulls, nil if there is no limit
+	pullSlots *semaphore.Weighted
+	// limits the estimated bytes of concurrent pulls, nil if there is no limit
+	inFlightBytes *semaphore.Weighted
+	// small files shared between models, nil if they are not cached
+	artifactCache *pullman.ArtifactCache
+	metrics       *pullerMetrics
 }
 
 // PullerInterface is the interface for `pullman`
 // useful to mock for testing
 type PullerInterface interface {
 	Pull(context.Context, pullman.PullCommand) error
+	List(context.Context, pullman.ListCommand) ([]pullman.ObjectInfo, error)
 }
 
 // NewPuller creates a new Puller instance and initializes it with configuration from the environment
@@ -78,13 +99,72 @@ func NewPullerFromConfig(log logr.Logger, config *PullerConfiguration) *Puller {
 	s := new(Puller)
 	s.Log = log
 	s.PullerConfig = config
-	s.PullManager = pullman.NewPullManager(log)
+	pullManager := pullman.NewPullManager(log)
+	s.PullManager = pullManager
+	s.metrics = newPullerMetrics()
+	if s.PullerConfig.MaxConcurrentPulls > 0 {
+		s.pullSlots = semaphore.NewWeighted(int64(s.PullerConfig.MaxConcurrentPulls))
+	}
+	if s.PullerConfig.MaxInFlightBytes > 0 {
+		s.inFlightBytes = semaphore.NewWeighted(s.PullerConfig.MaxInFlightBytes)
+	}
+
+	if s.PullerConfig.ArtifactCacheDir != "" {
+		cache, err := pullman.NewArtifactCache(s.PullerConfig.ArtifactCacheDir, s.PullerConfig.ArtifactCacheMaxBytes, s.PullerConfig.ArtifactCacheMaxObjectBytes)
+		if err != nil {
+			log.Error(err, "Unable to create the artifact cache, shared files will be downloaded for every model", "dir", s.PullerConfig.ArtifactCacheDir)
+		} else {
+			s.artifactCache = cache
+		}
+	}
+
+	log.Info("Initializing Puller", "Dir", s.PullerConfig.RootModelDir, "MaxConcurrentPulls", s.PullerConfig.MaxConcurrentPulls,
+		"MaxInFlightBytes", s.PullerConfig.MaxInFlightBytes)
 
-	log.Info("Initializing Puller", "Dir", s.PullerConfig.RootModelDir)
+	s.warmUpClients(pullManager)
 
 	return s
 }
 
+// MetricsHandler returns an http.Handler serving the load queue wait
+// histogram in the Prometheus text format
+func (s *Puller) MetricsHandler() http.Handler {
+	return s.metrics
+}
+
+// warmUpClients creates the repository clients for the WarmUpStorageKeys so
+// that the first pulls do not pay for creating them
+//
+// Failures are logged and otherwise ignored, the client is then created on
+// the first pull as usual.
+func (s *Puller) warmUpClients(pullManager *pullman.PullManager) {
+	for _, storageKey := range s.PullerConfig.WarmUpStorageKeys {
+		log := s.Log.WithValues("storageKey", storageKey)
+
+		storageConfig, err := s.PullerConfig.GetStorageConfiguration(storageKey, log)
+		if err != nil {
+			log.Error(err, "Failed to read storage config to warm up client")
+			continue
+		}
+		s.applyStorageUserAgent(storageConfig)
+		storageType, ok := storageConfig[parameterKeyType].(string)
+		if !ok {
+			log.Info("Skipping warm up of client, storage config has no type")
+			continue
+		}
+		if !s.storageTypeAllowed(storageType) {
+			log.Info("Skipping warm up of client, storage type is not allowed", "type", storageType)
+			continue
+		}
+
+		if err := pullManager.WarmUp(pullman.NewRepositoryConfig(storageType, storageConfig)); err != nil {
+			log.Error(err, "Failed to warm up client")
+			continue
+		}
+		log.Info("Warmed up client", "type", storageType)
+	}
+}
+
 // ProcessLoadModelRequest is for use in an mmesh serving runtime that embeds the puller
 //
 // The input request is modified in place and also returned.
@@ -93,50 +173,27 @@ func NewPullerFromConfig(log logr.Logger, config *PullerConfiguration) *Puller {
 // - rewrite ModelKey["schema_path"] to a local filesystem path
 // - add the size of the model on disk to ModelKey["disk_size_bytes"]
 func (s *Puller) ProcessLoadModelRequest(ctx context.Context, req *mmesh.LoadModelRequest) (*mmesh.LoadModelRequest, error) {
-	var modelKey ModelKeyInfo
-	if parseErr := json.Unmarshal([]byte(req.ModelKey), &modelKey); parseErr != nil {
-		return nil, fmt.Errorf("Invalid modelKey in LoadModelRequest. Error processing JSON '%s': %w", req.ModelKey, parseErr)
-	}
-
-	var storageConfig map[string]interface{}
-	if modelKey.StorageKey == nil {
-		// if storageKey is unspecified, check for a default storage key in the storage config
-		storageType := modelKey.StorageParams[parameterKeyType]
-		var keyToCheck string
-		if storageType == "" {
-			keyToCheck = defaultStorageKey
-		} else {
-			keyToCheck = fmt.Sprintf("%s_%s", defaultStorageKey, storageType)
-		}
+	return s.ProcessLoadModelRequestInDir(ctx, req, req.ModelId)
+}
 
-		var err error
-		if storageConfig, err = s.PullerConfig.GetStorageConfiguration(keyToCheck, s.Log); err != nil {
-			// do not error here, try to load from the parameters only
-			storageConfig = map[string]interface{}{}
-		}
-	} else {
-		var err error
-		if storageConfig, err = s.PullerConfig.GetStorageConfiguration(*modelKey.StorageKey, s.Log); err != nil {
-			// if key was specified, but we could not find it, that is an error
-			return nil, fmt.Errorf("Did not find storage config for key %s: %w", *modelKey.StorageKey, err)
-		}
+// ProcessLoadModelRequestInDir is ProcessLoadModelRequest, but pulls the model
+// files to the directory dirName under the root model dir instead of to the
+// directory named after the model id
+func (s *Puller) ProcessLoadModelRequestInDir(ctx context.Context, req *mmesh.LoadModelRequest, dirName string) (*mmesh.LoadModelRequest, error) {
+	if limitErr := util.CheckModelKeyLimits(req, s.PullerConfig.ModelKeyMaxSize); limitErr != nil {
+		return nil, limitErr
 	}
-
-	// DEPRECATED: allow top-level bucket key for backwards compatibility
-	if modelKey.Bucket != "" && storageConfig["bucket"] != nil {
-		s.Log.Info(`Warning: use of ModelKey["bucket"] is deprecated, use ModelKey["storage_params"]["bucket"] instead`)
-		storageConfig["bucket"] = modelKey.Bucket
+	modelKey, parseErr := modelkey.Parse(req.ModelKey)
+	if parseErr != nil {
+		return nil, fmt.Errorf("Invalid modelKey in LoadModelRequest. Error processing JSON '%s': %w", req.ModelKey, parseErr)
 	}
-
-	// override the storage configs with per-request storage parameters
-	if err := ApplyParameterOverrides(storageConfig, modelKey.StorageParams); err != nil {
-		return nil, fmt.Errorf("Unable to merge storage parameters from the storage config and the Predictor Storage field: %w", err)
+	if modelKey.DownloadConcurrency < 0 {
+		return nil, fmt.Errorf("Invalid modelKey in LoadModelRequest. '%s' must not be negative, got %d", modelkey.DownloadConcurrencyKey, modelKey.DownloadConcurrency)
 	}
 
-	// if we still don't know the storage type, we cannot download the model, so return an error
-	storageType, ok := storageConfig[parameterKeyType].(string)
-	if !ok {
-		return nil, fmt.Errorf("Predictor Storage field missing")
+	storageConfig, storageType, err := s.getStorageConfig(modelKey)
+	if err != nil {
+		return nil, err
 	}
 
 	// build and execute the pull command
@@ -152,11 +209,50 @@ func (s *Puller) ProcessLoadModelRequest(ctx context.Context, req *mmesh.LoadMod
 		modelPathFilename = basePath
 	}
 
-	targets := []pullman.Target{
-		{
-			RemotePath: req.ModelPath,
-			LocalPath:  modelPathFilename,
-		},
+	modelTarget := pullman.Target{
+		RemotePath: req.ModelPath,
+		LocalPath:  modelPathFilename,
+	}
+
+	// extract only the model from an archive with many models, which is
+	// placed at the LocalPath whether it is a directory or a single file
+	if modelKey.TarSubpath != "" {
+		switch base := path.Base(path.Clean("/" + modelKey.TarSubpath)); base {
+		case "/":
+			modelPathFilename = "_model"
+		default:
+			modelPathFilename = base
+		}
+		modelTarget.LocalPath = modelPathFilename
+		modelTarget.ExtractTar = true
+		modelTarget.TarSubpath = modelKey.TarSubpath
+	}
+
+	// pin the model to a version of its object, from the ModelKey or else the
+	// storage config, which is removed from the config so that it does not
+	// apply to the schema or the file mappings
+	versionID := modelKey.VersionID
+	if v, exists := storageConfig[parameterKeyVersionID]; exists {
+		configVersionID, ok := v.(string)
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "Storage config %s must be a string, got %v", parameterKeyVersionID, v)
+		}
+		if versionID == "" {
+			versionID = configVersionID
+		}
+		delete(storageConfig, parameterKeyVersionID)
+	}
+	if versionID != "" {
+		if storageType != versionIDStorageType {
+			return nil, status.Errorf(codes.InvalidArgument, "%s is only supported for storage of type %s, not %s", modelkey.VersionIDKey, versionIDStorageType, storageType)
+		}
+		modelTarget.VersionID = versionID
+	}
+
+	targets := []pullman.Target{modelTarget}
+
+	if mappingsErr := validateFileMappings(modelKey.FileMappings); mappingsErr != nil {
+		return nil, mappingsErr
 	}
 
 	// if included, add the schema to the pull
@@ -176,21 +272,56 @@ func (s *Puller) ProcessLoadModelRequest(ctx context.Context, req *mmesh.LoadMod
 		targets = append(targets, schemaTarget)
 	}
 
-	modelDir, joinErr := util.SecureJoin(s.PullerConfig.RootModelDir, req.ModelId)
+	modelDir, joinErr := util.SecureJoin(s.PullerConfig.RootModelDir, dirName)
 	if joinErr != nil {
-		return nil, fmt.Errorf("Error joining paths '%s' and '%s': %v", s.PullerConfig.RootModelDir, req.ModelId, joinErr)
+		return nil, fmt.Errorf("Error joining paths '%s' and '%s': %v", s.PullerConfig.RootModelDir, dirName, joinErr)
 	}
 
 	pullCommand := pullman.PullCommand{
 		RepositoryConfig: pullman.NewRepositoryConfig(storageType, storageConfig),
 		Directory:        modelDir,
 		Targets:          targets,
+		Concurrency:      s.downloadConcurrency(modelKey),
+		ArtifactCache:    s.artifactCache,
+		KeepEmptyFiles:   s.PullerConfig.RejectEmptyModelFiles,
+	}
+	if caseErr := s.checkKeyCaseCollisions(ctx, req.ModelId, pullCommand.RepositoryConfig, modelTarget); caseErr != nil {
+		return nil, caseErr
+	}
+	if inodesErr := s.checkFreeInodes(req.ModelId); inodesErr != nil {
+		return nil, inodesErr
+	}
+	queued := time.Now()
+	release, slotErr := s.acquirePullSlot(ctx)
+	if slotErr != nil {
+		return nil, slotErr
 	}
+	releaseBytes, bytesErr := s.acquirePullBytes(ctx, s.estimatePullSize(modelKey))
+	if bytesErr != nil {
+		release()
+		return nil, bytesErr
+	}
+	queueWait := time.Since(queued)
+	s.metrics.observeQueueWait(queueWait)
+	s.Log.V(1).Info("Pulling model after waiting in the load queue", "modelId", req.ModelId, "queueWait", queueWait.String())
 	pullerErr := s.PullManager.Pull(ctx, pullCommand)
+	if pullerErr == nil {
+		pullerErr = s.pullFileMappings(ctx, pullCommand, modelPathFilename, modelKey.FileMappings)
+	}
+	releaseBytes()
+	release()
 	if pullerErr != nil {
 		return nil, status.Errorf(status.Code(pullerErr), "Failed to pull model from storage due to error: %s", pullerErr)
 	}
 
+	if emptyErr := s.checkEmptyModelFiles(req.ModelId, filepath.Join(modelDir, modelPathFilename)); emptyErr != nil {
+		return nil, emptyErr
+	}
+
+	if hookErr := s.runPostLoadHook(ctx, req.ModelId, modelDir); hookErr != nil {
+		return nil, hookErr
+	}
+
 	// update model path to an absolute path in the local filesystem
 
 	// SecureJoin doesn't allow symlinks pointing outside the scope of the first element, which breaks PVC support since
@@ -208,18 +339,29 @@ func (s *Puller) ProcessLoadModelRequest(ctx context.Context, req *mmesh.LoadMod
 		modelKey.SchemaPath = &schemaFullPath
 	}
 
-	// update the model key to add the disk size
-	if size, err1 := s.getModelDiskSize(modelFullPath); err1 != nil {
-		s.Log.Error(err1, "Model disk size will not be included in the LoadModelRequest due to error", "model_key", modelKey)
+	// update the model key to add the disk size, the model files are not
+	// walked if a provided size takes precedence
+	providedSize := modelKey.DiskSizeBytes
+	if providedSize == nil {
+		providedSize = s.readDiskSizeFile(modelFullPath)
+	}
+	diskSize, sizeErr := util.ResolveDiskSize(providedSize, func() (int64, error) {
+		return measureModelDiskSize(s, modelFullPath)
+	}, s.diskSizePrecedence())
+	if sizeErr != nil {
+		s.Log.Error(sizeErr, "Model disk size will not be included in the LoadModelRequest due to error", "model_key", modelKey)
 	} else {
-		s.Log.Info("Calculated disk size", "modelFullPath", modelFullPath, "disk_size", size)
-		modelKey.DiskSizeBytes = size
+		s.Log.Info("Resolved disk size", "modelFullPath", modelFullPath, "disk_size", diskSize, "precedence", s.diskSizePrecedence())
 	}
+	modelKey.DiskSizeBytes = &diskSize
 
 	// Clear storage parameters from the processed modelKey
 	modelKey.StorageKey = nil
 	modelKey.StorageParams = nil
 	modelKey.Bucket = ""
+	modelKey.TarSubpath = ""
+	modelKey.VersionID = ""
+	modelKey.DownloadConcurrency = 0
 
 	// rewrite the ModelKey JSON with any updates that have been made
 	modelKeyBytes, err := json.Marshal(modelKey)
@@ -231,6 +373,110 @@ func (s *Puller) ProcessLoadModelRequest(ctx context.Context, req *mmesh.LoadMod
 	return req, nil
 }
 
+// getStorageConfig returns the config of the storage of the ModelKey, from
+// the storage config of its storage key overridden by its storage parameters,
+// and the type of the storage
+func (s *Puller) getStorageConfig(modelKey *modelkey.ModelKey) (map[string]interface{}, string, error) {
+	var storageConfig map[string]interface{}
+	if modelKey.StorageKey == nil {
+		// if storageKey is unspecified, check for a default storage key in the storage config
+		storageType := modelKey.StorageParams[parameterKeyType]
+		var keyToCheck string
+		if storageType == "" {
+			keyToCheck = defaultStorageKey
+			if s.PullerConfig.DefaultStorageKey != "" {
+				keyToCheck = s.PullerConfig.DefaultStorageKey
+			}
+		} else {
+			keyToCheck = fmt.Sprintf("%s_%s", defaultStorageKey, storageType)
+		}
+
+		var err error
+		if storageConfig, err = s.PullerConfig.GetStorageConfiguration(keyToCheck, s.Log); err != nil {
+			if keyToCheck == s.PullerConfig.DefaultStorageKey {
+				// a configured default is expected to exist
+				return nil, "", fmt.Errorf("Did not find storage config for the default storage key %s: %w", keyToCheck, err)
+			}
+			// do not error here, try to load from the parameters only
+			storageConfig = map[string]interface{}{}
+		}
+	} else {
+		var err error
+		if storageConfig, err = s.PullerConfig.GetStorageConfiguration(*modelKey.StorageKey, s.Log); err != nil {
+			// if key was specified, but we could not find it, that is an error
+			return nil, "", fmt.Errorf("Did not find storage config for key %s: %w", *modelKey.StorageKey, err)
+		}
+	}
+
+	// DEPRECATED: allow top-level bucket key for backwards compatibility
+	if modelKey.Bucket != "" && storageConfig["bucket"] != nil {
+		s.Log.Info(`Warning: use of ModelKey["bucket"] is deprecated, use ModelKey["storage_params"]["bucket"] instead`)
+		storageConfig["bucket"] = modelKey.Bucket
+	}
+
+	// override the storage configs with per-request storage parameters
+	if err := ApplyParameterOverrides(storageConfig, modelKey.StorageParams); err != nil {
+		return nil, "", fmt.Errorf("Unable to merge storage parameters from the storage config and the Predictor Storage field: %w", err)
+	}
+	s.applyStorageUserAgent(storageConfig)
+
+	// if we still don't know the storage type, we cannot download the model, so return an error
+	storageType, ok := storageConfig[parameterKeyType].(string)
+	if !ok {
+		return nil, "", fmt.Errorf("Predictor Storage field missing")
+	}
+	if !s.storageTypeAllowed(storageType) {
+		return nil, "", status.Errorf(codes.PermissionDenied, "Pulling models from storage of type %s is not allowed, the allowed types are %s",
+			storageType, strings.Join(s.PullerConfig.AllowedStorageTypes, ", "))
+	}
+
+	return storageConfig, storageType, nil
+}
+
+// applyStorageUserAgent sets the StorageUserAgent as the user_agent of a
+// storage config that does not have its own
+func (s *Puller) applyStorageUserAgent(storageConfig map[string]interface{}) {
+	if s.PullerConfig.StorageUserAgent == "" {
+		return
+	}
+	if _, exists := storageConfig[pullman.ConfigUserAgent]; !exists {
+		storageConfig[pullman.ConfigUserAgent] = s.PullerConfig.StorageUserAgent
+	}
+}
+
+// diskSizePrecedence defaults to the size of the model files for
+// configurations that do not set it
+func (s *Puller) diskSizePrecedence() string {
+	if s.PullerConfig.DiskSizePrecedence == "" {
+		return util.DiskSizePrecedenceFile
+	}
+	return s.PullerConfig.DiskSizePrecedence
+}
+
+// readDiskSizeFile returns the size of the model in the DiskSizeFile of its
+// directory, which is nil if the file is not configured, missing or invalid
+func (s *Puller) readDiskSizeFile(modelPath string) *int64 {
+	if s.PullerConfig.DiskSizeFile == "" {
+		return nil
+	}
+	sizeBytes, err := os.ReadFile(filepath.Join(modelPath, s.PullerConfig.DiskSizeFile))
+	if err != nil {
+		// a model that is a single file has no disk size file
+		s.Log.V(1).Info("The model has no disk size file", "modelPath", modelPath, "error", err)
+		return nil
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(string(sizeBytes)), 10, 64)
+	if err != nil || size < 0 {
+		s.Log.Info("Ignoring the disk size file of the model, it does not hold a number of bytes", "modelPath", modelPath, "contents", string(sizeBytes))
+		return nil
+	}
+	return &size
+}
+
+// measureModelDiskSize walks the model files to size them, which is replaced
+// in tests
+var measureModelDiskSize = (*Puller).getModelDiskSize
+
 func (s *Puller) getModelDiskSize(modelPath string) (int64, error) {
 	// This walks the local filesystem and accumulates the size of the model
 	// It would be more efficient to accumulate the size as the files are downloaded,
@@ -270,6 +516,103 @@ func (s *Puller) getModelDiskSize(modelPath string) (int64, error) {
 	return size, nil
 }
 
+// acquirePullSlot waits until fewer than MaxConcurrentPulls models are being
+// pulled, for at most PullQueueTimeout, and returns the function to release
+// the slot
+func (s *Puller) acquirePullSlot(ctx context.Context) (func(), error) {
+	if s.pullSlots == nil {
+		return func() {}, nil
+	}
+
+	waitCtx := ctx
+	if s.PullerConfig.PullQueueTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, s.PullerConfig.PullQueueTimeout)
+		defer cancel()
+	}
+
+	if err := s.pullSlots.Acquire(waitCtx, 1); err != nil {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return nil, status.Errorf(codes.ResourceExhausted, "Timed out after %s waiting to pull the model, %d models are already being pulled",
+			s.PullerConfig.PullQueueTimeout, s.PullerConfig.MaxConcurrentPulls)
+	}
+	return func() { s.pullSlots.Release(1) }, nil
+}
+
+// storageTypeAllowed returns true if models may be pulled from storage of
+// the type, which is any type if no AllowedStorageTypes are configured
+func (s *Puller) storageTypeAllowed(storageType string) bool {
+	if len(s.PullerConfig.AllowedStorageTypes) == 0 {
+		return true
+	}
+	for _, allowed := range s.PullerConfig.AllowedStorageTypes {
+		if allowed == storageType {
+			return true
+		}
+	}
+	return false
+}
+
+// downloadConcurrency returns the number of files to download in parallel
+// from the download_concurrency of the ModelKey, clamped to the configured
+// MaxDownloadConcurrency, or 0 for the default of the storage provider
+func (s *Puller) downloadConcurrency(modelKey *modelkey.ModelKey) int {
+	maxConcurrency := s.PullerConfig.MaxDownloadConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultMaxDownloadConcurrency
+	}
+	if modelKey.DownloadConcurrency > maxConcurrency {
+		s.Log.Info("Limiting the download concurrency of the ModelKey", "download_concurrency", modelKey.DownloadConcurrency, "max", maxConcurrency)
+		return maxConcurrency
+	}
+	return modelKey.DownloadConcurrency
+}
+
+// estimatePullSize returns the bytes that pulling the model is expected to
+// hold, from its disk_size_bytes or the configured PullSizeEstimate
+func (s *Puller) estimatePullSize(modelKey *modelkey.ModelKey) int64 {
+	if modelKey.DiskSizeBytes != nil && *modelKey.DiskSizeBytes > 0 {
+		return *modelKey.DiskSizeBytes
+	}
+	if s.PullerConfig.PullSizeEstimate > 0 {
+		return s.PullerConfig.PullSizeEstimate
+	}
+	return defaultPullSizeEstimate
+}
+
+// acquirePullBytes waits until the estimated size of the model fits in the
+// MaxInFlightBytes that are not reserved by other pulls, for at most
+// PullQueueTimeout, and returns the function to release the reservation
+//
+// A model larger than MaxInFlightBytes reserves all of them, so that it is
+// pulled alone instead of never.
+func (s *Puller) acquirePullBytes(ctx context.Context, estimate int64) (func(), error) {
+	if s.inFlightBytes == nil {
+		return func() {}, nil
+	}
+	if estimate > s.PullerConfig.MaxInFlightBytes {
+		estimate = s.PullerConfig.MaxInFlightBytes
+	}
+
+	waitCtx := ctx
+	if s.PullerConfig.PullQueueTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, s.PullerConfig.PullQueueTimeout)
+		defer cancel()
+	}
+
+	if err := s.inFlightBytes.Acquire(waitCtx, estimate); err != nil {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return nil, status.Errorf(codes.ResourceExhausted, "Timed out after %s waiting to reserve %d bytes to pull the model, at most %d bytes are pulled at the same time",
+			s.PullerConfig.PullQueueTimeout, estimate, s.PullerConfig.MaxInFlightBytes)
+	}
+	return func() { s.inFlightBytes.Release(estimate) }, nil
+}
+
 func (p *Puller) CleanupModel(modelID string) error {
 	// Now delete the local file
 	pathToModel, err := util.SecureJoin(p.PullerConfig.RootModelDir, modelID)
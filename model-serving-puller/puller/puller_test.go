@@ -20,12 +20,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	gomock "github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/kserve/modelmesh-runtime-adapter/internal/modelkey"
 	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 	"github.com/kserve/modelmesh-runtime-adapter/model-serving-puller/generated/mocks"
 	"github.com/kserve/modelmesh-runtime-adapter/pullman"
 
@@ -57,6 +64,10 @@ func (pcm pullCommandMatcher) Matches(pci interface{}) bool {
 		return false
 	}
 
+	if pc.Concurrency != pcm.expected.Concurrency {
+		return false
+	}
+
 	if !gomock.Eq(pcm.expected.Targets).Matches(pc.Targets) {
 		return false
 	}
@@ -83,7 +94,7 @@ func (pcm pullCommandMatcher) String() string {
 }
 
 func describePullCommand(pc *pullman.PullCommand) string {
-	return fmt.Sprintf("PullCommand\n\tDirectory: %s\n\tTargets: %v\n\tConfig: %v", pc.Directory, pc.Targets, pc.RepositoryConfig)
+	return fmt.Sprintf("PullCommand\n\tDirectory: %s\n\tTargets: %v\n\tConcurrency: %d\n\tConfig: %v", pc.Directory, pc.Targets, pc.Concurrency, pc.RepositoryConfig)
 }
 
 func eqPullCommand(pc *pullman.PullCommand) gomock.Matcher {
@@ -510,6 +521,94 @@ func Test_ProcessLoadModelRequest_DefaultStorageKeyTyped(t *testing.T) {
 	assert.Equal(t, expectedRequestRewrite, returnRequest)
 }
 
+func Test_ProcessLoadModelRequest_ConfiguredDefaultStorageKey(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+	p.PullerConfig.DefaultStorageKey = "genericParameters"
+
+	request := &mmesh.LoadModelRequest{
+		ModelId:   "singlefile",
+		ModelPath: "model.zip",
+		ModelType: "rt:triton",
+		ModelKey:  `{"model_type": {"name": "tensorflow"}}`,
+	}
+
+	expectedRequestRewrite := &mmesh.LoadModelRequest{
+		ModelId:   "singlefile",
+		ModelPath: filepath.Join(p.PullerConfig.RootModelDir, "singlefile", "model.zip"),
+		ModelType: "rt:triton",
+		ModelKey:  `{"model_type":{"name":"tensorflow"},"disk_size_bytes":60}`,
+	}
+
+	expectedConfig, err := readStorageConfig("genericParameters")
+	assert.NoError(t, err)
+
+	expectedPullCommand := pullman.PullCommand{
+		RepositoryConfig: expectedConfig,
+		Directory:        filepath.Join(p.PullerConfig.RootModelDir, "singlefile"),
+		Targets: []pullman.Target{
+			{
+				RemotePath: "model.zip",
+				LocalPath:  "model.zip",
+			},
+		},
+	}
+
+	mockPuller.EXPECT().Pull(gomock.Any(), eqPullCommand(&expectedPullCommand)).Return(nil).Times(1)
+
+	returnRequest, err := p.ProcessLoadModelRequest(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedRequestRewrite, returnRequest)
+}
+
+func Test_ProcessLoadModelRequest_ConfiguredDefaultStorageKeyOverridden(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+	p.PullerConfig.DefaultStorageKey = "genericParameters"
+
+	request := &mmesh.LoadModelRequest{
+		ModelId:   "singlefile",
+		ModelPath: "model.zip",
+		ModelType: "rt:triton",
+		ModelKey:  `{"storage_params":{"bucket":"bucket1"}, "storage_key": "myStorage", "model_type": {"name": "tensorflow"}}`,
+	}
+
+	expectedConfig, err := readStorageConfig("myStorage")
+	assert.NoError(t, err)
+	expectedConfig.Set("default_bucket", "default") // from myStorage
+	expectedConfig.Set("bucket", "bucket1")         // from storage_params
+
+	expectedPullCommand := pullman.PullCommand{
+		RepositoryConfig: expectedConfig,
+		Directory:        filepath.Join(p.PullerConfig.RootModelDir, "singlefile"),
+		Targets: []pullman.Target{
+			{
+				RemotePath: "model.zip",
+				LocalPath:  "model.zip",
+			},
+		},
+	}
+
+	mockPuller.EXPECT().Pull(gomock.Any(), eqPullCommand(&expectedPullCommand)).Return(nil).Times(1)
+
+	_, err = p.ProcessLoadModelRequest(context.Background(), request)
+	assert.Nil(t, err)
+}
+
+func Test_ProcessLoadModelRequest_FailMissingConfiguredDefaultStorageKey(t *testing.T) {
+	p, _ := newPullerWithMock(t)
+	p.PullerConfig.DefaultStorageKey = "missing"
+
+	request := &mmesh.LoadModelRequest{
+		ModelId:   "singlefile",
+		ModelPath: "model.zip",
+		ModelType: "rt:triton",
+		ModelKey:  `{"model_type": {"name": "tensorflow"}}`,
+	}
+
+	returnRequest, err := p.ProcessLoadModelRequest(context.Background(), request)
+	assert.Nil(t, returnRequest)
+	assert.ErrorContains(t, err, "default storage key missing")
+}
+
 func Test_ProcessLoadModelRequest_StorageParamsOverrides(t *testing.T) {
 	p, mockPuller := newPullerWithMock(t)
 
@@ -550,6 +649,37 @@ func Test_ProcessLoadModelRequest_StorageParamsOverrides(t *testing.T) {
 	assert.Equal(t, expectedRequestRewrite, returnRequest)
 }
 
+func Test_ProcessLoadModelRequest_StorageUserAgent(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+	p.PullerConfig.StorageUserAgent = "model-serving/1.0"
+
+	pullUserAgent := func(modelKey string) string {
+		var userAgent string
+		mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, pc pullman.PullCommand) error {
+			userAgent = pullman.GetUserAgent(pc.RepositoryConfig)
+			return nil
+		}).Times(1)
+
+		request := &mmesh.LoadModelRequest{
+			ModelId:   "singlefile",
+			ModelPath: "model.zip",
+			ModelType: "mt:tensorflow",
+			ModelKey:  modelKey,
+		}
+		_, err := p.ProcessLoadModelRequest(context.Background(), request)
+		assert.NoError(t, err)
+		return userAgent
+	}
+
+	// the configured user agent is added to the storage config
+	assert.Equal(t, "model-serving/1.0",
+		pullUserAgent(`{"storage_key": "genericParameters", "model_type": {"name": "tensorflow"}}`))
+
+	// a user agent of the storage wins
+	assert.Equal(t, "custom-agent",
+		pullUserAgent(`{"storage_key": "genericParameters", "storage_params": {"user_agent": "custom-agent"}, "model_type": {"name": "tensorflow"}}`))
+}
+
 func Test_ProcessLoadModelRequest_FailInvalidModelKey(t *testing.T) {
 	request := &mmesh.LoadModelRequest{
 		ModelId:   "singlefile",
@@ -597,6 +727,30 @@ func Test_ProcessLoadModelRequest_FailMissingStorageKeyAndType(t *testing.T) {
 	assert.EqualError(t, err, expectedError)
 }
 
+func Test_GetStorageConfiguration_RequiredFields(t *testing.T) {
+	log := zap.New()
+	pullerConfig := &PullerConfiguration{StorageConfigurationDir: t.TempDir()}
+
+	// a config directory of s3 fields that lacks the secret key
+	configDir := filepath.Join(pullerConfig.StorageConfigurationDir, "split")
+	assert.NoError(t, os.MkdirAll(configDir, 0755))
+	for field, value := range map[string]string{"type": "s3", "access_key_id": "key", "endpoint_url": "https://s3.example.com\n"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(configDir, field), []byte(value), 0644))
+	}
+	_, err := pullerConfig.GetStorageConfiguration("split", log)
+	assert.EqualError(t, err, "Invalid storage configuration split: storage type s3 requires the fields secret_access_key")
+
+	assert.NoError(t, os.WriteFile(filepath.Join(configDir, "secret_access_key"), []byte("secret"), 0644))
+	storageConfig, err := pullerConfig.GetStorageConfiguration("split", log)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://s3.example.com", storageConfig["endpoint_url"])
+
+	// the required fields of a JSON config are checked the same
+	assert.NoError(t, os.WriteFile(filepath.Join(pullerConfig.StorageConfigurationDir, "azure"), []byte(`{"type": "azure"}`), 0644))
+	_, err = pullerConfig.GetStorageConfiguration("azure", log)
+	assert.EqualError(t, err, "Invalid storage configuration azure: storage type azure requires the fields account_name, container")
+}
+
 func Test_getModelDiskSize(t *testing.T) {
 	var diskSizeTests = []struct {
 		modelPath    string
@@ -635,3 +789,498 @@ func validatePathDiskSize(t *testing.T, p *Puller, path string, expectedSize int
 	assert.NoError(t, err)
 	assert.EqualValues(t, expectedSize, diskSize)
 }
+
+func Test_ProcessLoadModelRequest_MaxConcurrentPulls(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+	p.PullerConfig.MaxConcurrentPulls = 2
+	p.PullerConfig.PullQueueTimeout = 100 * time.Millisecond
+	p.pullSlots = semaphore.NewWeighted(2)
+
+	newRequest := func() *mmesh.LoadModelRequest {
+		return &mmesh.LoadModelRequest{
+			ModelId:   "singlefile",
+			ModelPath: "model.zip",
+			ModelType: "rt:triton",
+			ModelKey:  `{"storage_key": "myStorage", "model_type": {"name": "tensorflow"}}`,
+		}
+	}
+
+	// the pulls block until released, tracking how many run at the same time
+	var mutex sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ pullman.PullCommand) error {
+			mutex.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mutex.Unlock()
+			started <- struct{}{}
+
+			<-release
+
+			mutex.Lock()
+			inFlight--
+			mutex.Unlock()
+			return nil
+		}).Times(3)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.ProcessLoadModelRequest(context.Background(), newRequest())
+			errs <- err
+		}()
+	}
+	<-started
+	<-started
+
+	// a third pull queues and times out while both slots are taken
+	start := time.Now()
+	_, err := p.ProcessLoadModelRequest(context.Background(), newRequest())
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, maxInFlight)
+
+	// the released slots can be used again
+	_, err = p.ProcessLoadModelRequest(context.Background(), newRequest())
+	assert.NoError(t, err)
+}
+
+func Test_ProcessLoadModelRequest_MaxInFlightBytes(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+	p.PullerConfig.MaxInFlightBytes = 100
+	p.inFlightBytes = semaphore.NewWeighted(100)
+
+	// together, the two models exceed the budget
+	newRequest := func() *mmesh.LoadModelRequest {
+		return &mmesh.LoadModelRequest{
+			ModelId:   "singlefile",
+			ModelPath: "model.zip",
+			ModelType: "rt:triton",
+			ModelKey:  `{"storage_key": "myStorage", "model_type": {"name": "tensorflow"}, "disk_size_bytes": 80}`,
+		}
+	}
+
+	var mutex sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ pullman.PullCommand) error {
+			mutex.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mutex.Unlock()
+			started <- struct{}{}
+
+			<-release
+
+			mutex.Lock()
+			inFlight--
+			mutex.Unlock()
+			return nil
+		}).Times(2)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.ProcessLoadModelRequest(context.Background(), newRequest())
+			errs <- err
+		}()
+	}
+
+	// only one pull starts until it completes
+	<-started
+	select {
+	case <-started:
+		t.Fatal("Expected the second pull to wait for the byte budget")
+	case <-time.After(100 * time.Millisecond):
+	}
+	release <- struct{}{}
+	<-started
+	close(release)
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, maxInFlight)
+}
+
+func Test_estimatePullSize(t *testing.T) {
+	p, _ := newPullerWithMock(t)
+	p.PullerConfig.PullSizeEstimate = 50
+
+	size := int64(80)
+	assert.EqualValues(t, 80, p.estimatePullSize(&modelkey.ModelKey{DiskSizeBytes: &size}))
+	assert.EqualValues(t, 50, p.estimatePullSize(&modelkey.ModelKey{}))
+}
+
+func Test_ProcessLoadModelRequest_DownloadConcurrency(t *testing.T) {
+	testCases := []struct {
+		name                string
+		downloadConcurrency string
+		expectedConcurrency int
+	}{
+		{"unset", "", 0},
+		{"per-model value", `, "download_concurrency": 8`, 8},
+		{"clamped to the max", `, "download_concurrency": 100`, 16},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, mockPuller := newPullerWithMock(t)
+			p.PullerConfig.MaxDownloadConcurrency = 16
+
+			request := &mmesh.LoadModelRequest{
+				ModelId:   "singlefile",
+				ModelPath: "model.zip",
+				ModelType: "rt:triton",
+				ModelKey:  `{"storage_params":{"bucket":"bucket1"}, "storage_key": "myStorage"` + tc.downloadConcurrency + `}`,
+			}
+
+			expectedConfig, err := readStorageConfig("myStorage")
+			assert.NoError(t, err)
+			expectedConfig.Set("default_bucket", "default") // from myStorage
+			expectedConfig.Set("bucket", "bucket1")         // from storage_params
+
+			expectedPullCommand := pullman.PullCommand{
+				RepositoryConfig: expectedConfig,
+				Directory:        filepath.Join(p.PullerConfig.RootModelDir, "singlefile"),
+				Targets: []pullman.Target{
+					{
+						RemotePath: "model.zip",
+						LocalPath:  "model.zip",
+					},
+				},
+				Concurrency: tc.expectedConcurrency,
+			}
+
+			mockPuller.EXPECT().Pull(gomock.Any(), eqPullCommand(&expectedPullCommand)).Return(nil).Times(1)
+
+			returnRequest, err := p.ProcessLoadModelRequest(context.Background(), request)
+			assert.Nil(t, err)
+			// the hint is not passed on to the runtime
+			assert.NotContains(t, returnRequest.ModelKey, "download_concurrency")
+		})
+	}
+}
+
+func Test_ProcessLoadModelRequest_FailNegativeDownloadConcurrency(t *testing.T) {
+	p, _ := newPullerWithMock(t)
+
+	request := &mmesh.LoadModelRequest{
+		ModelId:   "singlefile",
+		ModelPath: "model.zip",
+		ModelType: "rt:triton",
+		ModelKey:  `{"storage_key": "myStorage", "download_concurrency": -1}`,
+	}
+
+	_, err := p.ProcessLoadModelRequest(context.Background(), request)
+	assert.ErrorContains(t, err, "download_concurrency")
+}
+
+func Test_ProcessLoadModelRequest_DiskSizePrecedence(t *testing.T) {
+	testCases := []struct {
+		precedence       string
+		expectedDiskSize int
+	}{
+		// the model.zip in the testdata is 60 bytes
+		{util.DiskSizePrecedenceFile, 60},
+		{util.DiskSizePrecedenceKey, 12345},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.precedence, func(t *testing.T) {
+			p, mockPuller := newPullerWithMock(t)
+			p.PullerConfig.DiskSizePrecedence = tc.precedence
+
+			request := &mmesh.LoadModelRequest{
+				ModelId:   "singlefile",
+				ModelPath: "model.zip",
+				ModelType: "rt:triton",
+				ModelKey:  `{"storage_params":{"bucket":"bucket1"}, "storage_key": "myStorage", "model_type": {"name": "tensorflow"}, "disk_size_bytes": 12345}`,
+			}
+
+			mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+			returnRequest, err := p.ProcessLoadModelRequest(context.Background(), request)
+			assert.Nil(t, err)
+			assert.Equal(t, fmt.Sprintf(`{"model_type":{"name":"tensorflow"},"disk_size_bytes":%d}`, tc.expectedDiskSize), returnRequest.ModelKey)
+		})
+	}
+}
+
+func Test_ProcessLoadModelRequest_DiskSizeFile(t *testing.T) {
+	testCases := []struct {
+		name             string
+		precedence       string
+		keySize          string
+		sizeFile         string
+		expectedDiskSize int
+		expectWalk       bool
+	}{
+		// the model.onnx written by the pull is 60 bytes
+		{"trusted size file", util.DiskSizePrecedenceKey, "", "12345\n", 12345, false},
+		{"trusted key size", util.DiskSizePrecedenceKey, `, "disk_size_bytes": 999`, "12345", 999, false},
+		{"measured size", util.DiskSizePrecedenceFile, "", "12345", 60 + 5, true},
+		{"invalid size file", util.DiskSizePrecedenceKey, "", "large", 60 + 5, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, mockPuller := newPullerWithMock(t)
+			p.PullerConfig.RootModelDir = t.TempDir()
+			p.PullerConfig.DiskSizePrecedence = tc.precedence
+			p.PullerConfig.DiskSizeFile = "disk_size"
+
+			walks := 0
+			defer func(measure func(*Puller, string) (int64, error)) { measureModelDiskSize = measure }(measureModelDiskSize)
+			measure := measureModelDiskSize
+			measureModelDiskSize = func(s *Puller, modelPath string) (int64, error) {
+				walks++
+				return measure(s, modelPath)
+			}
+
+			mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, pc pullman.PullCommand) error {
+					modelDir := filepath.Join(pc.Directory, "mnist")
+					if err := os.MkdirAll(modelDir, 0755); err != nil {
+						return err
+					}
+					if err := os.WriteFile(filepath.Join(modelDir, "model.onnx"), make([]byte, 60), 0644); err != nil {
+						return err
+					}
+					return os.WriteFile(filepath.Join(modelDir, "disk_size"), []byte(tc.sizeFile), 0644)
+				}).
+				Times(1)
+
+			request := &mmesh.LoadModelRequest{
+				ModelId:   "mnist",
+				ModelPath: "models/mnist",
+				ModelType: "rt:ovms",
+				ModelKey:  `{"storage_key": "myStorage", "model_type": {"name": "onnx"}` + tc.keySize + `}`,
+			}
+			returnRequest, err := p.ProcessLoadModelRequest(context.Background(), request)
+			assert.Nil(t, err)
+			assert.Equal(t, fmt.Sprintf(`{"model_type":{"name":"onnx"},"disk_size_bytes":%d}`, tc.expectedDiskSize), returnRequest.ModelKey)
+			if tc.expectWalk {
+				assert.Equal(t, 1, walks, "expected the model files to be walked")
+			} else {
+				assert.Equal(t, 0, walks, "expected the model files not to be walked")
+			}
+		})
+	}
+}
+
+func Test_ProcessLoadModelRequest_TarSubpath(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+
+	request := &mmesh.LoadModelRequest{
+		ModelId:   "tarsubpath",
+		ModelPath: "models.tar.gz",
+		ModelType: "rt:triton",
+		ModelKey:  `{"storage_params": {"type": "http"}, "tar_subpath": "models/mnist"}`,
+	}
+
+	expectedPullCommand := pullman.PullCommand{
+		RepositoryConfig: pullman.NewRepositoryConfig("http", nil),
+		Directory:        filepath.Join(p.PullerConfig.RootModelDir, "tarsubpath"),
+		Targets: []pullman.Target{
+			{
+				RemotePath: "models.tar.gz",
+				LocalPath:  "mnist",
+				ExtractTar: true,
+				TarSubpath: "models/mnist",
+			},
+		},
+	}
+
+	mockPuller.EXPECT().Pull(gomock.Any(), eqPullCommand(&expectedPullCommand)).Return(nil).Times(1)
+
+	returnRequest, err := p.ProcessLoadModelRequest(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(p.PullerConfig.RootModelDir, "tarsubpath", "mnist"), returnRequest.ModelPath)
+	// the hint is not passed on to the runtime
+	assert.NotContains(t, returnRequest.ModelKey, "tar_subpath")
+}
+
+func Test_ProcessLoadModelRequest_VersionID(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+
+	request := &mmesh.LoadModelRequest{
+		ModelId:   "singlefile",
+		ModelPath: "model.zip",
+		ModelType: "rt:triton",
+		ModelKey:  `{"storage_params": {"type": "s3"}, "version_id": "v1"}`,
+	}
+
+	expectedPullCommand := pullman.PullCommand{
+		RepositoryConfig: pullman.NewRepositoryConfig("s3", nil),
+		Directory:        filepath.Join(p.PullerConfig.RootModelDir, "singlefile"),
+		Targets: []pullman.Target{
+			{
+				RemotePath: "model.zip",
+				LocalPath:  "model.zip",
+				VersionID:  "v1",
+			},
+		},
+	}
+
+	mockPuller.EXPECT().Pull(gomock.Any(), eqPullCommand(&expectedPullCommand)).Return(nil).Times(1)
+
+	returnRequest, err := p.ProcessLoadModelRequest(context.Background(), request)
+	assert.Nil(t, err)
+	// the version is not passed on to the runtime
+	assert.NotContains(t, returnRequest.ModelKey, "version_id")
+}
+
+func Test_ProcessLoadModelRequest_VersionIDInStorageParams(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+
+	request := &mmesh.LoadModelRequest{
+		ModelId:   "singlefile",
+		ModelPath: "model.zip",
+		ModelType: "rt:triton",
+		ModelKey:  `{"storage_params": {"type": "s3", "version_id": "v1"}, "schema_path": "schema.json"}`,
+	}
+
+	// the version pins the model, but not the schema
+	expectedPullCommand := pullman.PullCommand{
+		RepositoryConfig: pullman.NewRepositoryConfig("s3", nil),
+		Directory:        filepath.Join(p.PullerConfig.RootModelDir, "singlefile"),
+		Targets: []pullman.Target{
+			{
+				RemotePath: "model.zip",
+				LocalPath:  "model.zip",
+				VersionID:  "v1",
+			},
+			{
+				RemotePath: "schema.json",
+				LocalPath:  "schema.json",
+			},
+		},
+	}
+
+	mockPuller.EXPECT().Pull(gomock.Any(), eqPullCommand(&expectedPullCommand)).Return(nil).Times(1)
+
+	_, err := p.ProcessLoadModelRequest(context.Background(), request)
+	assert.Nil(t, err)
+}
+
+func Test_ProcessLoadModelRequest_VersionIDUnsupportedStorage(t *testing.T) {
+	p, _ := newPullerWithMock(t)
+
+	request := &mmesh.LoadModelRequest{
+		ModelId:   "singlefile",
+		ModelPath: "model.zip",
+		ModelKey:  `{"storage_params": {"type": "generic"}, "version_id": "v1"}`,
+	}
+
+	_, err := p.ProcessLoadModelRequest(context.Background(), request)
+	assert.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_ProcessLoadModelRequest_TarSubpathSingleFile(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+
+	request := &mmesh.LoadModelRequest{
+		ModelId:   "tarsubpath",
+		ModelPath: "models.tar.gz",
+		ModelType: "rt:triton",
+		ModelKey:  `{"storage_params": {"type": "s3"}, "tar_subpath": "models/resnet/1/model.plan"}`,
+	}
+
+	expectedPullCommand := pullman.PullCommand{
+		RepositoryConfig: pullman.NewRepositoryConfig("s3", nil),
+		Directory:        filepath.Join(p.PullerConfig.RootModelDir, "tarsubpath"),
+		Targets: []pullman.Target{
+			{
+				RemotePath: "models.tar.gz",
+				LocalPath:  "model.plan",
+				ExtractTar: true,
+				TarSubpath: "models/resnet/1/model.plan",
+			},
+		},
+	}
+
+	mockPuller.EXPECT().Pull(gomock.Any(), eqPullCommand(&expectedPullCommand)).Return(nil).Times(1)
+
+	returnRequest, err := p.ProcessLoadModelRequest(context.Background(), request)
+	assert.Nil(t, err)
+	// the file is extracted to the model path itself
+	assert.Equal(t, filepath.Join(p.PullerConfig.RootModelDir, "tarsubpath", "model.plan"), returnRequest.ModelPath)
+}
+
+func Test_ProcessLoadModelRequest_AllowedStorageTypes(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+	p.PullerConfig.AllowedStorageTypes = []string{"s3", "generic"}
+
+	request := &mmesh.LoadModelRequest{
+		ModelId:   "singlefile",
+		ModelPath: "model.zip",
+		ModelKey:  `{"storage_params": {"type": "generic"}}`,
+	}
+
+	mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	_, err := p.ProcessLoadModelRequest(context.Background(), request)
+	assert.Nil(t, err)
+}
+
+func Test_ProcessLoadModelRequest_FailDisallowedStorageType(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+	p.PullerConfig.AllowedStorageTypes = []string{"s3"}
+
+	request := &mmesh.LoadModelRequest{
+		ModelId:   "singlefile",
+		ModelPath: "model.zip",
+		ModelKey:  `{"storage_params": {"type": "generic"}}`,
+	}
+
+	// the storage is never accessed
+	mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := p.ProcessLoadModelRequest(context.Background(), request)
+	assert.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func Test_ProcessLoadModelRequest_FailOversizedModelKey(t *testing.T) {
+	p, mockPuller := newPullerWithMock(t)
+	p.PullerConfig.ModelKeyMaxSize = 64
+
+	request := &mmesh.LoadModelRequest{
+		ModelId:   "singlefile",
+		ModelPath: "model.zip",
+		ModelKey:  `{"storage_key": "myStorage", "model_type": {"name": "tensorflow"}, "padding": "xxxxxxxxxx"}`,
+	}
+
+	// the storage is never accessed
+	mockPuller.EXPECT().Pull(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := p.ProcessLoadModelRequest(context.Background(), request)
+	assert.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
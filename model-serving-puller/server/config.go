@@ -15,6 +15,8 @@
 package server
 
 import (
+	"time"
+
 	"github.com/go-logr/logr"
 
 	. "github.com/kserve/modelmesh-runtime-adapter/internal/envconfig"
@@ -24,6 +26,14 @@ import (
 type PullerServerConfiguration struct {
 	Port                int    // Port to run this puller grpc server
 	ModelServerEndpoint string // model server endpoint
+	StatusPort          int    // Port to serve the HTTP model status endpoint, 0 to disable
+	KeepPreviousVersion bool   // Keep the previous version of a model until a reload succeeds, to roll back to it
+	StorageObjects      bool   // Serve the storage objects endpoint on the StatusPort, with the credentials of the puller
+	StorageObjectsLimit int    // Maximum number of objects returned by the storage objects endpoint, 0 for no limit
+
+	// Identical load failures logged within this window are collapsed into
+	// one log with a count, 0 to log every one
+	LogDedupWindow time.Duration
 }
 
 // GetPullerServerConfigFromEnv creates a new PullerConfiguration populated from environment variables
@@ -31,5 +41,10 @@ func GetPullerServerConfigFromEnv(log logr.Logger) *PullerServerConfiguration {
 	pullerConfig := new(PullerServerConfiguration)
 	pullerConfig.Port = GetEnvInt("PORT", 8084, log)
 	pullerConfig.ModelServerEndpoint = GetEnvString("MODEL_SERVER_ENDPOINT", "port:8085")
+	pullerConfig.StatusPort = GetEnvInt("STATUS_PORT", 0, log)
+	pullerConfig.KeepPreviousVersion = GetEnvBool("KEEP_PREVIOUS_MODEL_VERSION", false, log)
+	pullerConfig.StorageObjects = GetEnvBool("STORAGE_OBJECTS_ENDPOINT", false, log)
+	pullerConfig.StorageObjectsLimit = GetEnvInt("STORAGE_OBJECTS_LIMIT", 1000, log)
+	pullerConfig.LogDedupWindow = GetEnvDuration("LOG_DEDUP_WINDOW", time.Minute, log)
 	return pullerConfig
 }
@@ -0,0 +1,183 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/modelkey"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+)
+
+// modelManifest identifies the source of a loaded model. Two LoadModel
+// requests with equal manifests are expected to produce the same model.
+//
+// A LoadModelRequest carries no checksum of the model files, so an object
+// that is overwritten at the same path is not pulled again. Only the version
+// of the object, the version_id of the ModelKey or of its storage_params,
+// identifies the content, and a model pinned to another version is pulled
+// again.
+type modelManifest struct {
+	modelPath string
+	modelType string
+	modelKey  string
+	versionID string
+}
+
+func newModelManifest(req *mmesh.LoadModelRequest) modelManifest {
+	manifest := modelManifest{
+		modelPath: req.ModelPath,
+		modelType: req.ModelType,
+		modelKey:  req.ModelKey,
+	}
+	// an invalid ModelKey fails the pull, so the version is not needed then
+	if mk, err := modelkey.ParseFields(req.ModelKey, modelkey.VersionIDKey, modelkey.StorageParamsKey); err == nil {
+		manifest.versionID = mk.VersionID
+		if manifest.versionID == "" {
+			manifest.versionID = mk.StorageParams[modelkey.VersionIDKey]
+		}
+	}
+	return manifest
+}
+
+// States of the models tracked by the loadedModelCache
+const (
+	ModelStateLoading string = "LOADING"
+	ModelStateLoaded  string = "LOADED"
+	ModelStateFailed  string = "FAILED"
+)
+
+type loadedModel struct {
+	manifest modelManifest
+	state    string
+	// path to the pulled model files in the local filesystem
+	localPath string
+	// the LoadModel request sent to the model runtime
+	request  *mmesh.LoadModelRequest
+	response *mmesh.LoadModelResponse
+	// error from the last failed load
+	loadError string
+	updated   time.Time
+}
+
+// loadedModelCache keeps track of the models that were successfully loaded
+// so that a repeated LoadModel for an identical model can skip the pull and
+// the runtime load
+//
+// Models that are loading or failed to load are also kept, with the manifest
+// of the last LoadModel, to report their status. Unloaded models are removed.
+type loadedModelCache struct {
+	mutex  sync.Mutex
+	models map[string]*loadedModel
+}
+
+func newLoadedModelCache() *loadedModelCache {
+	return &loadedModelCache{models: make(map[string]*loadedModel)}
+}
+
+// lookup returns the cached response if the model is loaded with the same
+// manifest and its files are still present on disk
+func (c *loadedModelCache) lookup(modelID string, manifest modelManifest) (*mmesh.LoadModelResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	lm, ok := c.models[modelID]
+	if !ok || lm.state != ModelStateLoaded || lm.manifest != manifest {
+		return nil, false
+	}
+	if _, err := os.Stat(lm.localPath); err != nil {
+		return nil, false
+	}
+	if lm.response == nil {
+		return &mmesh.LoadModelResponse{}, true
+	}
+	return proto.Clone(lm.response).(*mmesh.LoadModelResponse), true
+}
+
+// store records that the model was loaded by the model runtime with the
+// given (rewritten) request
+func (c *loadedModelCache) store(modelID string, manifest modelManifest, req *mmesh.LoadModelRequest, response *mmesh.LoadModelResponse) {
+	var cached *mmesh.LoadModelResponse
+	if response != nil {
+		cached = proto.Clone(response).(*mmesh.LoadModelResponse)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.models[modelID] = &loadedModel{
+		manifest:  manifest,
+		state:     ModelStateLoaded,
+		localPath: req.ModelPath,
+		request:   proto.Clone(req).(*mmesh.LoadModelRequest),
+		response:  cached,
+		updated:   time.Now(),
+	}
+}
+
+// restore puts back the entry of a previous version of the model after a
+// rollback, keeping the error of the version that failed to load
+func (c *loadedModelCache) restore(modelID string, previous loadedModel, err error) {
+	previous.loadError = err.Error()
+	previous.updated = time.Now()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.models[modelID] = &previous
+}
+
+// setLoading records that the model is being loaded with the given manifest,
+// replacing any previous entry
+func (c *loadedModelCache) setLoading(modelID string, manifest modelManifest) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.models[modelID] = &loadedModel{manifest: manifest, state: ModelStateLoading, updated: time.Now()}
+}
+
+func (c *loadedModelCache) setFailed(modelID string, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if lm, ok := c.models[modelID]; ok {
+		lm.state = ModelStateFailed
+		lm.loadError = err.Error()
+		lm.updated = time.Now()
+	}
+}
+
+func (c *loadedModelCache) remove(modelID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.models, modelID)
+}
+
+// get returns a copy of the entry for the model
+func (c *loadedModelCache) get(modelID string) (loadedModel, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	lm, ok := c.models[modelID]
+	if !ok {
+		return loadedModel{}, false
+	}
+	return *lm, true
+}
+
+func (c *loadedModelCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.models = make(map[string]*loadedModel)
+}
@@ -17,6 +17,8 @@ package server
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"google.golang.org/grpc/codes"
@@ -48,6 +50,15 @@ type modelData struct {
 	results  chan *result
 	m        iPullerServer
 	refCount int
+	// the load requests that are queued or running, by their result channel,
+	// which an unload of the model cancels
+	loads map[chan *result]*pendingLoad
+}
+
+type pendingLoad struct {
+	cancel context.CancelFunc
+	// set when an unload of the model cancelled the load
+	cancelled bool
 }
 
 type grpcRequest interface {
@@ -137,15 +148,42 @@ func (m *modelStateManager) execute() {
 					refCount: 0,
 					requests: make(chan *request, StateManagerChannelLength),
 					results:  m.results,
+					loads:    make(map[chan *result]*pendingLoad),
 				}
 				m.data[req.modelId] = data
 				go data.execute()
 			}
 
+			switch req.grpcRequest.(type) {
+			case *mmesh.LoadModelRequest:
+				// the load runs with a context of its own, so that an unload
+				// sent while it pulls the model does not wait for it
+				var cancel context.CancelFunc
+				req.ctx, cancel = context.WithCancel(req.ctx)
+				data.loads[req.c] = &pendingLoad{cancel: cancel}
+			case *mmesh.UnloadModelRequest:
+				for _, load := range data.loads {
+					if !load.cancelled {
+						m.log.Info("Cancelling the load of the model that is being unloaded", "model_id", req.modelId)
+						load.cancelled = true
+						load.cancel()
+					}
+				}
+			}
+
 			data.refCount += 1
 			data.requests <- req
 
 		case result := <-m.results:
+			if data, ok := m.data[result.modelId]; ok {
+				if load, ok := data.loads[result.c]; ok {
+					load.cancel()
+					delete(data.loads, result.c)
+					if load.cancelled && result.err != nil {
+						result.err = status.Errorf(codes.Canceled, "Load of the model was cancelled by an unload: %s", result.err)
+					}
+				}
+			}
 			result.c <- result
 			if data, ok := m.data[result.modelId]; ok {
 				data.refCount -= 1
@@ -181,11 +219,24 @@ func (d *modelData) execute() {
 
 func (m *modelStateManager) unloadAll() error {
 	pullerServer := m.s.(*PullerServer)
+	pullerServer.loadedModels.clear()
 	modelids, err := pullerServer.puller.ListModels()
 	if err != nil {
 		m.log.Error(err, "Unable to list the models for unloading")
 		return err
 	}
+	unloadFromRuntime := func(modelid string) error {
+		unloadReq := &mmesh.UnloadModelRequest{ModelId: modelid}
+		if _, err := pullerServer.modelRuntimeClient.UnloadModel(context.Background(), unloadReq); err != nil {
+			if status, ok := status.FromError(err); !ok || status.Code() != codes.NotFound { // ignore NotFound
+				// When an error occurs unloading a model, abort all model
+				// unloading with the error
+				m.log.Error(err, "Error requesting unload of model")
+				return err
+			}
+		}
+		return nil
+	}
 	for _, modelid := range modelids {
 		excluded := false
 		for _, exclude := range PurgeExcludePrefixes {
@@ -196,17 +247,24 @@ func (m *modelStateManager) unloadAll() error {
 		}
 
 		if !excluded {
-			unloadReq := &mmesh.UnloadModelRequest{ModelId: modelid}
-			ctx := context.Background()
-			if _, err = pullerServer.modelRuntimeClient.UnloadModel(ctx, unloadReq); err != nil {
-				if status, ok := status.FromError(err); !ok || status.Code() != codes.NotFound { // ignore NotFound
-					// When an error occurs unloading a model, abort all model
-					// unloading with the error
-					m.log.Error(err, "Error requesting unload of model")
+			if err = unloadFromRuntime(modelid); err != nil {
+				return err
+			}
+			if err = pullerServer.puller.CleanupModel(modelid); err != nil {
+				return err
+			}
+		} else if modelid == stagingDirName {
+			// the models whose loaded version was pulled to the staging
+			// directory, or left over from a load that did not complete
+			stagedIds, err := os.ReadDir(filepath.Join(pullerServer.puller.PullerConfig.RootModelDir, stagingDirName))
+			if err != nil {
+				return err
+			}
+			for _, staged := range stagedIds {
+				if err = unloadFromRuntime(staged.Name()); err != nil {
 					return err
 				}
 			}
-
 			if err = pullerServer.puller.CleanupModel(modelid); err != nil {
 				return err
 			}
@@ -17,9 +17,14 @@ package server
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
@@ -114,3 +119,66 @@ func TestStateManagerErrors(t *testing.T) {
 	}
 
 }
+
+type mockPullerServerSlowLoad struct {
+	modelDir string
+	started  chan struct{}
+}
+
+func (m *mockPullerServerSlowLoad) loadModel(ctx context.Context, req *mmesh.LoadModelRequest) (*mmesh.LoadModelResponse, error) {
+	// write part of the model files, then wait like a slow download
+	if err := os.MkdirAll(m.modelDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(m.modelDir, "model.part"), []byte("partial"), 0644); err != nil {
+		return nil, err
+	}
+	close(m.started)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(10 * time.Second):
+		return &mmesh.LoadModelResponse{}, nil
+	}
+}
+
+func (m *mockPullerServerSlowLoad) unloadModel(ctx context.Context, req *mmesh.UnloadModelRequest) (*mmesh.UnloadModelResponse, error) {
+	return &mmesh.UnloadModelResponse{}, os.RemoveAll(m.modelDir)
+}
+
+func TestStateManagerUnloadCancelsLoad(t *testing.T) {
+	log := zap.New()
+	s := NewPullerServer(log)
+	sm := s.sm
+	mockPullerServer := &mockPullerServerSlowLoad{
+		modelDir: filepath.Join(t.TempDir(), "model-id"),
+		started:  make(chan struct{}),
+	}
+	sm.s = mockPullerServer
+
+	loadErr := make(chan error, 1)
+	go func() {
+		_, err := sm.loadModel(context.Background(), &mmesh.LoadModelRequest{ModelId: "model-id"})
+		loadErr <- err
+	}()
+	select {
+	case <-mockPullerServer.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Load should have started")
+	}
+
+	start := time.Now()
+	if _, err := sm.unloadModel(context.Background(), &mmesh.UnloadModelRequest{ModelId: "model-id"}); err != nil {
+		t.Fatalf("Unload should have succeeded: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Unload should not have waited for the load to finish, took %v", elapsed)
+	}
+
+	if err := <-loadErr; status.Code(err) != codes.Canceled {
+		t.Errorf("Load should have been cancelled, got: %v", err)
+	}
+	if _, err := os.Stat(mockPullerServer.modelDir); !os.IsNotExist(err) {
+		t.Errorf("The partial model files should have been removed, got: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+const (
+	modelStatusPathPrefix = "/v1/models/"
+	modelStatusPathSuffix = "/status"
+)
+
+// States of a model as reported by the model runtime
+const (
+	RuntimeStateLoaded    string = "LOADED"
+	RuntimeStateNotLoaded string = "NOT_LOADED"
+	// the model runtime failed to report the model, see the RuntimeError
+	RuntimeStateUnknown string = "UNKNOWN"
+)
+
+// ModelStatus is the status of a single model in the model runtime and in
+// the puller
+type ModelStatus struct {
+	ModelId string `json:"model_id"`
+	// one of the RuntimeState* values
+	State string `json:"state"`
+	// as reported by the model runtime now
+	SizeInBytes uint64 `json:"size_in_bytes,omitempty"`
+	// error of the model runtime if the State is unknown
+	RuntimeError string `json:"runtime_error,omitempty"`
+	// state of the last LoadModel through the puller, one of the ModelState*
+	// values, empty if the model was unloaded or not loaded by the puller
+	LoadState string `json:"load_state,omitempty"`
+	// as reported by the model runtime when the model was loaded
+	MaxConcurrency uint32 `json:"max_concurrency,omitempty"`
+	// error of the last failed load
+	Error string `json:"error,omitempty"`
+	// whether the model files are present in the local filesystem
+	OnDisk bool `json:"on_disk"`
+	// source of the model, from the last LoadModel request
+	ModelPath string     `json:"model_path,omitempty"`
+	ModelType string     `json:"model_type,omitempty"`
+	ModelKey  string     `json:"model_key,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// GetModelStatus returns the status of the model with the given id, or a
+// NotFound error if neither the model runtime nor the puller know about it
+func (s *PullerServer) GetModelStatus(ctx context.Context, modelID string) (*ModelStatus, error) {
+	modelDir, err := util.SecureJoin(s.puller.PullerConfig.RootModelDir, modelID)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid model id '%s': %s", modelID, err)
+	}
+	_, statErr := os.Stat(modelDir)
+
+	ms := &ModelStatus{
+		ModelId: modelID,
+		OnDisk:  statErr == nil,
+	}
+
+	// the model runtime only reports the size of a loaded model
+	sizeResponse, err := s.modelRuntimeClient.ModelSize(ctx, &mmesh.ModelSizeRequest{ModelId: modelID})
+	switch {
+	case err == nil:
+		ms.State = RuntimeStateLoaded
+		ms.SizeInBytes = sizeResponse.SizeInBytes
+	case status.Code(err) == codes.NotFound:
+		ms.State = RuntimeStateNotLoaded
+	default:
+		ms.State = RuntimeStateUnknown
+		ms.RuntimeError = err.Error()
+	}
+
+	lm, ok := s.loadedModels.get(modelID)
+	if !ok {
+		if ms.State != RuntimeStateLoaded && !ms.OnDisk {
+			return nil, status.Errorf(codes.NotFound, "Model '%s' not found", modelID)
+		}
+		return ms, nil
+	}
+
+	ms.LoadState = lm.state
+	ms.Error = lm.loadError
+	ms.ModelPath = lm.manifest.modelPath
+	ms.ModelType = lm.manifest.modelType
+	ms.ModelKey = lm.manifest.modelKey
+	ms.UpdatedAt = &lm.updated
+	if lm.response != nil {
+		ms.MaxConcurrency = lm.response.MaxConcurrency
+	}
+	return ms, nil
+}
+
+// ModelStatusHandler serves GetModelStatus as JSON at GET /v1/models/{modelId}/status
+func (s *PullerServer) ModelStatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		modelID := strings.TrimPrefix(r.URL.Path, modelStatusPathPrefix)
+		if !strings.HasSuffix(modelID, modelStatusPathSuffix) {
+			http.NotFound(w, r)
+			return
+		}
+		modelID = strings.TrimSuffix(modelID, modelStatusPathSuffix)
+		if modelID == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		ms, err := s.GetModelStatus(r.Context(), modelID)
+		if err != nil {
+			switch status.Code(err) {
+			case codes.NotFound:
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case codes.InvalidArgument:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(ms); err != nil {
+			s.Log.Error(err, "Failed to write model status", "model_id", modelID)
+		}
+	})
+}
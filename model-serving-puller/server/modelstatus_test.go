@@ -0,0 +1,164 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+)
+
+func TestGetModelStatus(t *testing.T) {
+	s, mockClient, mockPullManager := newPullerServerWithMocks(t)
+	// the unload deletes the model files, so do not use the testdata dir
+	s.puller.PullerConfig.RootModelDir = t.TempDir()
+	for _, modelID := range []string{"loaded", "unloaded"} {
+		modelDir := filepath.Join(s.puller.PullerConfig.RootModelDir, modelID)
+		if err := os.Mkdir(modelDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(modelDir, "model.zip"), []byte("model"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mockPullManager.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	mockClient.EXPECT().LoadModel(gomock.Any(), gomock.Any()).Return(&mmesh.LoadModelResponse{SizeInBytes: 1234, MaxConcurrency: 2}, nil).Times(2)
+	mockClient.EXPECT().UnloadModel(gomock.Any(), gomock.Any()).Return(&mmesh.UnloadModelResponse{}, nil).Times(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	for _, modelID := range []string{"loaded", "unloaded"} {
+		_, err := s.LoadModel(ctx, &mmesh.LoadModelRequest{
+			ModelId:   modelID,
+			ModelPath: "model.zip",
+			ModelType: "mt:tensorflow",
+			ModelKey:  `{"model_type": {"name": "tensorflow"}, "storage_key": "myStorage", "bucket": "bucket1"}`,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error from LoadModel: %v", err)
+		}
+	}
+	if _, err := s.UnloadModel(ctx, &mmesh.UnloadModelRequest{ModelId: "unloaded"}); err != nil {
+		t.Fatalf("Unexpected error from UnloadModel: %v", err)
+	}
+
+	// the model runtime reports the size of the loaded model only
+	mockClient.EXPECT().ModelSize(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req *mmesh.ModelSizeRequest, _ ...grpc.CallOption) (*mmesh.ModelSizeResponse, error) {
+			if req.ModelId == "loaded" {
+				return &mmesh.ModelSizeResponse{SizeInBytes: 2345}, nil
+			}
+			return nil, status.Errorf(codes.NotFound, "Model %s not found", req.ModelId)
+		}).AnyTimes()
+
+	ms, err := s.GetModelStatus(ctx, "loaded")
+	if err != nil {
+		t.Fatalf("Unexpected error from GetModelStatus: %v", err)
+	}
+	if ms.State != RuntimeStateLoaded || ms.LoadState != ModelStateLoaded || !ms.OnDisk || ms.SizeInBytes != 2345 || ms.MaxConcurrency != 2 {
+		t.Errorf("Unexpected status for the loaded model: %+v", ms)
+	}
+	if ms.ModelPath != "model.zip" || ms.ModelType != "mt:tensorflow" {
+		t.Errorf("Expected the manifest of the LoadModel request but got %+v", ms)
+	}
+
+	// the unload removes the model from the puller, and the runtime does not
+	// know it anymore
+	if _, ok := s.loadedModels.get("unloaded"); ok {
+		t.Errorf("Expected the unloaded model to be removed from the loaded models")
+	}
+	if _, err = s.GetModelStatus(ctx, "unloaded"); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unloaded model but got %v", err)
+	}
+
+	if _, err = s.GetModelStatus(ctx, "unknown"); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unknown model but got %v", err)
+	}
+
+	// the same over HTTP
+	handler := s.ModelStatusHandler()
+	for modelID, expectedCode := range map[string]int{"loaded": http.StatusOK, "unloaded": http.StatusNotFound, "unknown": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/models/"+modelID+"/status", nil))
+		if rec.Code != expectedCode {
+			t.Errorf("Expected HTTP status %d for model '%s' but got %d", expectedCode, modelID, rec.Code)
+			continue
+		}
+		if expectedCode != http.StatusOK {
+			continue
+		}
+		var body ModelStatus
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to parse model status JSON: %v", err)
+		}
+		if body.ModelId != modelID {
+			t.Errorf("Expected model id '%s' but got '%s'", modelID, body.ModelId)
+		}
+	}
+}
+
+func TestGetModelStatusRuntimeState(t *testing.T) {
+	s, mockClient, mockPullManager := newPullerServerWithMocks(t)
+	s.puller.PullerConfig.RootModelDir = t.TempDir()
+	modelDir := filepath.Join(s.puller.PullerConfig.RootModelDir, "failed")
+	if err := os.Mkdir(modelDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// a failed load is reported with the state of the runtime
+	mockPullManager.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mockClient.EXPECT().LoadModel(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.Internal, "load failed")).Times(1)
+	mockClient.EXPECT().ModelSize(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.Unimplemented, "unimplemented")).Times(1)
+	if _, err := s.LoadModel(ctx, &mmesh.LoadModelRequest{
+		ModelId:   "failed",
+		ModelPath: "model.zip",
+		ModelKey:  `{"model_type": {"name": "tensorflow"}, "storage_key": "myStorage"}`,
+	}); err == nil {
+		t.Fatal("Expected an error from LoadModel")
+	}
+	ms, err := s.GetModelStatus(ctx, "failed")
+	if err != nil {
+		t.Fatalf("Unexpected error from GetModelStatus: %v", err)
+	}
+	if ms.State != RuntimeStateUnknown || ms.RuntimeError == "" || ms.LoadState != ModelStateFailed || ms.Error == "" {
+		t.Errorf("Unexpected status for the failed model: %+v", ms)
+	}
+
+	// a model loaded in the runtime without the puller
+	mockClient.EXPECT().ModelSize(gomock.Any(), gomock.Any()).Return(&mmesh.ModelSizeResponse{SizeInBytes: 100}, nil).Times(1)
+	ms, err = s.GetModelStatus(ctx, "external")
+	if err != nil {
+		t.Fatalf("Unexpected error from GetModelStatus: %v", err)
+	}
+	if ms.State != RuntimeStateLoaded || ms.LoadState != "" || ms.SizeInBytes != 100 {
+		t.Errorf("Unexpected status for the model loaded without the puller: %+v", ms)
+	}
+}
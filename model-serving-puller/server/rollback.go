@@ -0,0 +1,88 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+)
+
+// directory under the root model dir where a new version of a loaded model
+// is pulled to, while the files of the loaded version keep being served
+const stagingDirName = "_staging"
+
+// stageDirName returns the directory under the root model dir that a new
+// version of the model is pulled to, which is the one of the two directories
+// of the model that the loaded version does not use
+func (s *PullerServer) stageDirName(modelID string, previous loadedModel) (string, error) {
+	stagingDir, err := util.SecureJoin(s.puller.PullerConfig.RootModelDir, stagingDirName, modelID)
+	if err != nil {
+		return "", err
+	}
+	if isWithinDir(stagingDir, previous.localPath) {
+		return modelID, nil
+	}
+	return filepath.Join(stagingDirName, modelID), nil
+}
+
+func isWithinDir(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// removeModelDir removes the directory under the root model dir that the
+// files of a version of the model were pulled to
+func (s *PullerServer) removeModelDir(dirName string) error {
+	dir, err := util.SecureJoin(s.puller.PullerConfig.RootModelDir, dirName)
+	if err != nil {
+		return err
+	}
+	if err = os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("Failed to delete model files from local filesystem: %w", err)
+	}
+	return nil
+}
+
+// rollbackModel removes the files of the new version of the model after it
+// failed with loadErr, and loads the previous version, whose files were kept
+// in place, in the model runtime again if the runtime was asked to load the
+// new version
+//
+// Without a previous version, the model is only marked as failed.
+func (s *PullerServer) rollbackModel(ctx context.Context, modelID string, previous loadedModel, stagedDir string, reload bool, loadErr error) {
+	log := s.Log.WithValues("model_id", modelID)
+	if err := s.removeModelDir(stagedDir); err != nil {
+		log.Error(err, "Failed to remove the files of the new model version", "local_dir", stagedDir)
+	}
+	if previous.state != ModelStateLoaded {
+		s.loadedModels.setFailed(modelID, loadErr)
+		return
+	}
+
+	log.Info("Rolling back to the previous version of the model", "model_path", previous.manifest.modelPath)
+	if reload {
+		if _, err := s.modelRuntimeClient.LoadModel(ctx, previous.request); err != nil {
+			log.Error(err, "Model runtime failed to load the previous version of the model")
+			s.loadedModels.setFailed(modelID, loadErr)
+			return
+		}
+	}
+	s.loadedModels.restore(modelID, previous, loadErr)
+}
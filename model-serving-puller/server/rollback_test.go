@@ -0,0 +1,173 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/proto/mmesh"
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+func TestLoadModelRollbackOnFailure(t *testing.T) {
+	s, mockClient, mockPullManager := newPullerServerWithMocks(t)
+	s.pullerServerConfig.KeepPreviousVersion = true
+	// the pulls write to the model dir, so do not use the testdata dir
+	s.puller.PullerConfig.RootModelDir = t.TempDir()
+
+	newRequest := func(modelPath string) *mmesh.LoadModelRequest {
+		return &mmesh.LoadModelRequest{
+			ModelId:   "mymodel",
+			ModelPath: modelPath,
+			ModelType: "mt:tensorflow",
+			ModelKey:  `{"model_type": {"name": "tensorflow"}, "storage_key": "myStorage", "bucket": "bucket1"}`,
+		}
+	}
+
+	// each pull writes the remote path of the model as the content of the file
+	mockPullManager.EXPECT().Pull(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, pc pullman.PullCommand) error {
+			for _, target := range pc.Targets {
+				if err := os.MkdirAll(pc.Directory, 0755); err != nil {
+					return err
+				}
+				if err := os.WriteFile(filepath.Join(pc.Directory, target.LocalPath), []byte(target.RemotePath), 0644); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Times(2)
+
+	liveFile := filepath.Join(s.puller.PullerConfig.RootModelDir, "mymodel", "model")
+	var v1Request *mmesh.LoadModelRequest
+	gomock.InOrder(
+		mockClient.EXPECT().LoadModel(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, req *mmesh.LoadModelRequest, _ ...interface{}) (*mmesh.LoadModelResponse, error) {
+				v1Request = req
+				return &mmesh.LoadModelResponse{SizeInBytes: 1234}, nil
+			}),
+		mockClient.EXPECT().LoadModel(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, req *mmesh.LoadModelRequest, _ ...interface{}) (*mmesh.LoadModelResponse, error) {
+				// the new version is staged next to the files of the
+				// loaded version, which are left in place
+				if req.ModelPath == liveFile {
+					t.Errorf("Expected the new version to be staged in another directory than %s", liveFile)
+				}
+				if content, err := os.ReadFile(liveFile); err != nil || string(content) != "v1/model" {
+					t.Errorf("Expected the files of the loaded version while the new version loads, got '%s': %v", string(content), err)
+				}
+				return nil, errors.New("bad model")
+			}),
+		// the rollback loads the previous version again
+		mockClient.EXPECT().LoadModel(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, req *mmesh.LoadModelRequest, _ ...interface{}) (*mmesh.LoadModelResponse, error) {
+				if req.ModelPath != v1Request.ModelPath || req.ModelKey != v1Request.ModelKey {
+					t.Errorf("Expected the request of the previous version %v but got %v", v1Request, req)
+				}
+				return &mmesh.LoadModelResponse{SizeInBytes: 1234}, nil
+			}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := s.LoadModel(ctx, newRequest("v1/model")); err != nil {
+		t.Fatalf("Unexpected error from LoadModel: %v", err)
+	}
+	if _, err := s.LoadModel(ctx, newRequest("v2/model")); err == nil {
+		t.Fatal("Expected an error from LoadModel for the failing version")
+	}
+
+	// the files of the previous version are still in place
+	content, err := os.ReadFile(liveFile)
+	if err != nil {
+		t.Fatalf("Expected the files of the previous version: %v", err)
+	}
+	if string(content) != "v1/model" {
+		t.Errorf("Expected the files of version 'v1/model' but got '%s'", string(content))
+	}
+	if _, err = os.Stat(filepath.Join(s.puller.PullerConfig.RootModelDir, stagingDirName, "mymodel")); !os.IsNotExist(err) {
+		t.Errorf("Expected the staged files to be removed but got %v", err)
+	}
+
+	// and the previous version is still active
+	mockClient.EXPECT().ModelSize(gomock.Any(), gomock.Any()).Return(&mmesh.ModelSizeResponse{SizeInBytes: 1}, nil).Times(1)
+	ms, err := s.GetModelStatus(ctx, "mymodel")
+	if err != nil {
+		t.Fatalf("Unexpected error from GetModelStatus: %v", err)
+	}
+	if ms.State != RuntimeStateLoaded || ms.LoadState != ModelStateLoaded || ms.ModelPath != "v1/model" || ms.Error == "" {
+		t.Errorf("Expected the previous version to be loaded with the error of the new version but got %+v", ms)
+	}
+}
+
+func TestLoadModelSwapsStagedVersion(t *testing.T) {
+	s, mockClient, mockPullManager := newPullerServerWithMocks(t)
+	s.pullerServerConfig.KeepPreviousVersion = true
+	s.puller.PullerConfig.RootModelDir = t.TempDir()
+
+	mockPullManager.EXPECT().Pull(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, pc pullman.PullCommand) error {
+			if err := os.MkdirAll(pc.Directory, 0755); err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(pc.Directory, pc.Targets[0].LocalPath), []byte(pc.Targets[0].RemotePath), 0644)
+		}).Times(3)
+	var loadedPaths []string
+	mockClient.EXPECT().LoadModel(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, req *mmesh.LoadModelRequest, _ ...interface{}) (*mmesh.LoadModelResponse, error) {
+			loadedPaths = append(loadedPaths, req.ModelPath)
+			return &mmesh.LoadModelResponse{SizeInBytes: 1234}, nil
+		}).Times(3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	for _, version := range []string{"v1", "v2", "v3"} {
+		if _, err := s.LoadModel(ctx, &mmesh.LoadModelRequest{
+			ModelId:   "mymodel",
+			ModelPath: version + "/model",
+			ModelType: "mt:tensorflow",
+			ModelKey:  `{"model_type": {"name": "tensorflow"}, "storage_key": "myStorage", "bucket": "bucket1"}`,
+		}); err != nil {
+			t.Fatalf("Unexpected error from LoadModel of %s: %v", version, err)
+		}
+	}
+
+	// the versions alternate between the two directories of the model, and
+	// the directory of the replaced version is removed
+	rootDir := s.puller.PullerConfig.RootModelDir
+	expected := []string{
+		filepath.Join(rootDir, "mymodel", "model"),
+		filepath.Join(rootDir, stagingDirName, "mymodel", "model"),
+		filepath.Join(rootDir, "mymodel", "model"),
+	}
+	for i := range expected {
+		if i >= len(loadedPaths) || loadedPaths[i] != expected[i] {
+			t.Fatalf("Expected the versions to be loaded from %v but got %v", expected, loadedPaths)
+		}
+	}
+	if content, err := os.ReadFile(expected[2]); err != nil || string(content) != "v3/model" {
+		t.Errorf("Expected the files of version 'v3/model' but got '%s': %v", string(content), err)
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, stagingDirName, "mymodel")); !os.IsNotExist(err) {
+		t.Errorf("Expected the files of the replaced version to be removed but got %v", err)
+	}
+}
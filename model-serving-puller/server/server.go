@@ -18,7 +18,9 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
@@ -42,9 +44,12 @@ var PurgeExcludePrefixes = []string{"_"}
 type PullerServer struct {
 	modelRuntimeClient mmesh.ModelRuntimeClient
 	Log                logr.Logger
+	// log of the load failures, which repeat during a storage outage
+	failureLog         logr.Logger
 	pullerServerConfig *PullerServerConfiguration
 	puller             *puller.Puller
 	sm                 *modelStateManager
+	loadedModels       *loadedModelCache
 
 	// embed generated Unimplemented type for forward-compatibility for gRPC
 	mmesh.UnimplementedModelRuntimeServer
@@ -59,8 +64,10 @@ func NewPullerServer(log logr.Logger) *PullerServer {
 func NewPullerServerFromConfig(log logr.Logger, config *PullerServerConfiguration) *PullerServer {
 	s := new(PullerServer)
 	s.Log = log
+	s.failureLog = util.NewDedupLogger(log, config.LogDedupWindow)
 	s.pullerServerConfig = config
 	s.puller = puller.NewPuller(log)
+	s.loadedModels = newLoadedModelCache()
 
 	s.sm, _ = newModelStateManager(log, s)
 	return s
@@ -103,6 +110,15 @@ func (s *PullerServer) StartServer() error {
 		os.Exit(1)
 	}
 	log.Info("Puller will run at port", "port", s.pullerServerConfig.Port)
+	if port := s.pullerServerConfig.StatusPort; port > 0 {
+		go func() {
+			log.Info("Serving model status", "port", port)
+			if err := http.ListenAndServe(fmt.Sprintf("localhost:%d", port), s.statusHandler()); err != nil {
+				log.Error(err, "Model status server stopped", "port", port)
+			}
+		}()
+	}
+
 	grpcServer := grpc.NewServer()
 	mmesh.RegisterModelRuntimeServer(grpcServer, s)
 	log.Info("gRPC Server Registered...")
@@ -110,6 +126,17 @@ func (s *PullerServer) StartServer() error {
 	return err
 }
 
+// statusHandler serves the HTTP endpoints of the StatusPort
+func (s *PullerServer) statusHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(modelStatusPathPrefix, s.ModelStatusHandler())
+	mux.Handle("/metrics", s.puller.MetricsHandler())
+	if s.pullerServerConfig.StorageObjects {
+		mux.Handle(storageObjectsPath, s.StorageObjectsHandler())
+	}
+	return mux
+}
+
 // LoadModel loads a model and returns when model is fully loaded.
 // See model-runtime.proto loadModel()
 func (s *PullerServer) LoadModel(ctx context.Context, req *mmesh.LoadModelRequest) (*mmesh.LoadModelResponse, error) {
@@ -120,21 +147,66 @@ func (s *PullerServer) LoadModel(ctx context.Context, req *mmesh.LoadModelReques
 func (s *PullerServer) loadModel(ctx context.Context, req *mmesh.LoadModelRequest) (*mmesh.LoadModelResponse, error) {
 	log := s.Log
 	log = log.WithValues("model_id", req.ModelId, "model_path", req.ModelPath, "model_key", req.ModelKey, "model_type", req.ModelType)
+	failureLog := s.failureLog.WithValues("model_id", req.ModelId, "model_path", req.ModelPath, "model_key", req.ModelKey, "model_type", req.ModelType)
 	log.Info("Loading model")
 
+	// ModelMesh may re-send LoadModel for a model that is already loaded, in which
+	// case there is no need to pull and load it again
+	manifest := newModelManifest(req)
+	if response, ok := s.loadedModels.lookup(req.ModelId, manifest); ok {
+		log.Info("Model is already loaded with an identical manifest, skipping pull and load")
+		return response, nil
+	}
+	modelID := req.ModelId
+
+	// pull a new version of a loaded model to another directory, so that the
+	// files of the loaded version keep being served until the new version is
+	// loaded and it can be rolled back to if the new version fails
+	previous, _ := s.loadedModels.get(modelID)
+	if !s.pullerServerConfig.KeepPreviousVersion {
+		previous = loadedModel{}
+	}
+	dirName := modelID
+	var previousDir string
+	if previous.state == ModelStateLoaded {
+		var stageErr error
+		if dirName, stageErr = s.stageDirName(modelID, previous); stageErr != nil {
+			return nil, stageErr
+		}
+		previousDir = filepath.Join(stagingDirName, modelID)
+		if dirName == previousDir {
+			previousDir = modelID
+		}
+		// left over from a load that did not complete
+		if stageErr = s.removeModelDir(dirName); stageErr != nil {
+			return nil, stageErr
+		}
+	}
+	s.loadedModels.setLoading(modelID, manifest)
+
 	// Pull the model from storage
 	var pullerErr error
-	req, pullerErr = s.puller.ProcessLoadModelRequest(ctx, req)
+	req, pullerErr = s.puller.ProcessLoadModelRequestInDir(ctx, req, dirName)
 	if pullerErr != nil {
-		log.Error(pullerErr, "Failed to pull model from storage")
+		failureLog.Error(pullerErr, "Failed to pull model from storage")
+		s.rollbackModel(ctx, modelID, previous, dirName, false, pullerErr)
 		return nil, pullerErr
 	}
 
 	// Call model runtime grpc loadModel
 	response, err := s.modelRuntimeClient.LoadModel(ctx, req)
 	if err != nil {
-		log.Error(err, "Model runtime failed to load model", "model_id", req.ModelId)
-		return nil, status.Errorf(status.Code(err), "Failed to load model due to model runtime error: %s", err)
+		failureLog.Error(err, "Model runtime failed to load model", "model_id", req.ModelId)
+		err = status.Errorf(status.Code(err), "Failed to load model due to model runtime error: %s", err)
+		s.rollbackModel(ctx, modelID, previous, dirName, true, err)
+		return nil, err
+	}
+
+	s.loadedModels.store(req.ModelId, manifest, req, response)
+	if previousDir != "" {
+		if err = s.removeModelDir(previousDir); err != nil {
+			log.Error(err, "Failed to remove the files of the previous model version", "local_dir", previousDir)
+		}
 	}
 
 	return response, nil
@@ -151,6 +223,8 @@ func (s *PullerServer) UnloadModel(ctx context.Context, req *mmesh.UnloadModelRe
 func (s *PullerServer) unloadModel(ctx context.Context, req *mmesh.UnloadModelRequest) (*mmesh.UnloadModelResponse, error) {
 	log := s.Log.WithValues("model_id", req.ModelId)
 	log.Info("Unloading model")
+	s.loadedModels.remove(req.ModelId)
+
 	// First unload the model from the runtime.
 	unloadResponse, err := s.modelRuntimeClient.UnloadModel(ctx, req)
 	if err != nil {
@@ -167,6 +241,9 @@ func (s *PullerServer) unloadModel(ctx context.Context, req *mmesh.UnloadModelRe
 
 	// Now delete the local file
 	err = s.puller.CleanupModel(req.ModelId)
+	if err == nil {
+		err = s.removeModelDir(filepath.Join(stagingDirName, req.ModelId))
+	}
 	if err != nil {
 		return nil, status.Errorf(status.Code(err), "Failed to delete model from local filesystem: %s", err)
 	}
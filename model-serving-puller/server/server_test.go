@@ -16,7 +16,10 @@ package server
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -113,3 +116,119 @@ func TestLoadModel(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadModelIdenticalReload(t *testing.T) {
+	s, mockClient, mockPullManager := newPullerServerWithMocks(t)
+
+	newRequest := func() *mmesh.LoadModelRequest {
+		return &mmesh.LoadModelRequest{
+			ModelId:   "singlefile",
+			ModelPath: "model.zip",
+			ModelType: "mt:tensorflow",
+			ModelKey:  `{"model_type": {"name": "tensorflow"}, "storage_key": "myStorage", "bucket": "bucket1"}`,
+		}
+	}
+
+	// Assert the pull and the model runtime LoadModel rpc only happen once
+	mockPullManager.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mockClient.EXPECT().LoadModel(gomock.Any(), gomock.Any()).Return(&mmesh.LoadModelResponse{SizeInBytes: 1234}, nil).Times(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		resp, err := s.LoadModel(ctx, newRequest())
+		if err != nil {
+			t.Fatalf("Unexpected error from LoadModel: %v", err)
+		}
+		if resp.SizeInBytes != 1234 {
+			t.Errorf("Expected SizeInBytes 1234 but got %d", resp.SizeInBytes)
+		}
+	}
+}
+
+func TestLoadModelChangedSource(t *testing.T) {
+	s, mockClient, mockPullManager := newPullerServerWithMocks(t)
+
+	request1 := &mmesh.LoadModelRequest{
+		ModelId:   "multifile",
+		ModelPath: "model",
+		ModelType: "mt:tensorflow",
+		ModelKey:  `{"model_type": {"name": "tensorflow"}, "storage_key": "myStorage", "bucket": "bucket1"}`,
+	}
+	request2 := &mmesh.LoadModelRequest{
+		ModelId:   "multifile",
+		ModelPath: "other/model",
+		ModelType: "mt:tensorflow",
+		ModelKey:  `{"model_type": {"name": "tensorflow"}, "storage_key": "myStorage", "bucket": "bucket1"}`,
+	}
+
+	// Assert a changed source results in a full pull and load
+	mockPullManager.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	gomock.InOrder(
+		mockClient.EXPECT().LoadModel(gomock.Any(), gomock.Any()).Return(&mmesh.LoadModelResponse{SizeInBytes: 1234}, nil).Times(1),
+		mockClient.EXPECT().LoadModel(gomock.Any(), gomock.Any()).Return(&mmesh.LoadModelResponse{SizeInBytes: 5678}, nil).Times(1),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := s.LoadModel(ctx, request1); err != nil {
+		t.Fatalf("Unexpected error from LoadModel: %v", err)
+	}
+	resp, err := s.LoadModel(ctx, request2)
+	if err != nil {
+		t.Fatalf("Unexpected error from LoadModel: %v", err)
+	}
+	if resp.SizeInBytes != 5678 {
+		t.Errorf("Expected SizeInBytes 5678 but got %d", resp.SizeInBytes)
+	}
+}
+
+func TestLoadModelChangedVersion(t *testing.T) {
+	s, mockClient, mockPullManager := newPullerServerWithMocks(t)
+
+	newRequest := func(versionID string) *mmesh.LoadModelRequest {
+		return &mmesh.LoadModelRequest{
+			ModelId:   "multifile",
+			ModelPath: "model",
+			ModelType: "mt:tensorflow",
+			ModelKey:  `{"model_type": {"name": "tensorflow"}, "storage_key": "myStorage", "bucket": "bucket1", "storage_params": {"version_id": "` + versionID + `"}}`,
+		}
+	}
+	if newModelManifest(newRequest("v1")).versionID != "v1" {
+		t.Errorf("Expected the manifest to have the version v1 but got %v", newModelManifest(newRequest("v1")))
+	}
+
+	// Assert a model pinned to another version of its object is pulled and
+	// loaded again
+	mockPullManager.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	gomock.InOrder(
+		mockClient.EXPECT().LoadModel(gomock.Any(), gomock.Any()).Return(&mmesh.LoadModelResponse{SizeInBytes: 1234}, nil).Times(1),
+		mockClient.EXPECT().LoadModel(gomock.Any(), gomock.Any()).Return(&mmesh.LoadModelResponse{SizeInBytes: 5678}, nil).Times(1),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := s.LoadModel(ctx, newRequest("v1")); err != nil {
+		t.Fatalf("Unexpected error from LoadModel: %v", err)
+	}
+	resp, err := s.LoadModel(ctx, newRequest("v2"))
+	if err != nil {
+		t.Fatalf("Unexpected error from LoadModel: %v", err)
+	}
+	if resp.SizeInBytes != 5678 {
+		t.Errorf("Expected SizeInBytes 5678 but got %d", resp.SizeInBytes)
+	}
+}
+
+func TestStatusHandlerMetrics(t *testing.T) {
+	s, _, _ := newPullerServerWithMocks(t)
+
+	rec := httptest.NewRecorder()
+	s.statusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected HTTP status 200 but got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "load_queue_wait_seconds_count 0") {
+		t.Errorf("Expected the load queue wait histogram but got %s", rec.Body.String())
+	}
+}
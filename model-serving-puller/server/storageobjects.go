@@ -0,0 +1,99 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const storageObjectsPath = "/v1/storage/objects"
+
+// the storage parameters that can be set in the query of a storage objects
+// request, other parameters could redirect the credentials of the storage
+var storageObjectsParams = []string{"type", "bucket"}
+
+// StorageObject is an object in storage, which can be the ModelPath of a model
+type StorageObject struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// StorageObjectsHandler serves the objects under a prefix in storage as JSON
+// at GET /v1/storage/objects?storage_key={key}&prefix={prefix}, eg.
+// {"objects": [{"path": "models/mnist/model.onnx", "size": 1024}, ...]}
+//
+// The storage_key is optional like in a ModelKey, and the type and bucket
+// query parameters override the storage config. At most StorageObjectsLimit
+// objects are returned, with "truncated": true if there are more.
+//
+// The storage is listed with the credentials of the puller, so the handler is
+// only served on the StatusPort if StorageObjects is enabled.
+func (s *PullerServer) StorageObjectsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		query := r.URL.Query()
+
+		var storageKey *string
+		if query.Has("storage_key") {
+			key := query.Get("storage_key")
+			storageKey = &key
+		}
+		storageParams := map[string]string{}
+		for _, param := range storageObjectsParams {
+			if query.Has(param) {
+				storageParams[param] = query.Get(param)
+			}
+		}
+
+		objects, err := s.puller.ListObjects(r.Context(), storageKey, storageParams, query.Get("prefix"))
+		if err != nil {
+			switch status.Code(err) {
+			case codes.InvalidArgument:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case codes.PermissionDenied:
+				http.Error(w, err.Error(), http.StatusForbidden)
+			case codes.Unimplemented:
+				http.Error(w, err.Error(), http.StatusNotImplemented)
+			default:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		response := struct {
+			Objects   []StorageObject `json:"objects"`
+			Truncated bool            `json:"truncated,omitempty"`
+		}{}
+		if limit := s.pullerServerConfig.StorageObjectsLimit; limit > 0 && len(objects) > limit {
+			objects = objects[:limit]
+			response.Truncated = true
+		}
+		response.Objects = make([]StorageObject, len(objects))
+		for i, object := range objects {
+			response.Objects[i] = StorageObject{Path: object.Path, Size: object.Size}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			s.Log.Error(err, "Failed to write storage objects", "prefix", query.Get("prefix"))
+		}
+	})
+}
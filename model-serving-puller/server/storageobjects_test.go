@@ -0,0 +1,117 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+func TestStorageObjectsHandler(t *testing.T) {
+	s, _, mockPullManager := newPullerServerWithMocks(t)
+	s.pullerServerConfig.StorageObjects = true
+
+	mockPullManager.EXPECT().List(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, lc pullman.ListCommand) ([]pullman.ObjectInfo, error) {
+			if lc.Prefix != "models/" {
+				t.Errorf("Expected the prefix 'models/' but got '%s'", lc.Prefix)
+			}
+			if bucket, _ := pullman.GetString(lc.RepositoryConfig, "bucket"); bucket != "bucket1" {
+				t.Errorf("Expected the bucket of the query but got '%s'", bucket)
+			}
+			return []pullman.ObjectInfo{{Path: "models/mnist/model.onnx", Size: 1024}}, nil
+		}).
+		Times(1)
+
+	handler := s.statusHandler()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/storage/objects?storage_key=myStorage&bucket=bucket1&prefix=models/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected HTTP status 200 but got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Objects []StorageObject `json:"objects"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse storage objects JSON: %v", err)
+	}
+	if len(body.Objects) != 1 || body.Objects[0] != (StorageObject{Path: "models/mnist/model.onnx", Size: 1024}) {
+		t.Errorf("Unexpected storage objects: %+v", body.Objects)
+	}
+
+	// storage that cannot be listed
+	mockPullManager.EXPECT().List(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("%w by repositories of type 's3'", pullman.ErrListNotSupported)).
+		Times(1)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/storage/objects?storage_key=myStorage", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected HTTP status 501 for storage that cannot be listed but got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/storage/objects", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected HTTP status 405 for a POST but got %d", rec.Code)
+	}
+}
+
+func TestStorageObjectsHandlerLimit(t *testing.T) {
+	s, _, mockPullManager := newPullerServerWithMocks(t)
+	s.pullerServerConfig.StorageObjects = true
+	s.pullerServerConfig.StorageObjectsLimit = 2
+
+	mockPullManager.EXPECT().List(gomock.Any(), gomock.Any()).
+		Return([]pullman.ObjectInfo{{Path: "a"}, {Path: "b"}, {Path: "c"}}, nil).
+		Times(1)
+
+	rec := httptest.NewRecorder()
+	s.statusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/storage/objects?storage_key=myStorage", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected HTTP status 200 but got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Objects   []StorageObject `json:"objects"`
+		Truncated bool            `json:"truncated"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse storage objects JSON: %v", err)
+	}
+	if len(body.Objects) != 2 || !body.Truncated {
+		t.Errorf("Expected 2 storage objects and a truncated response but got %+v", body)
+	}
+}
+
+func TestStorageObjectsHandlerDisabled(t *testing.T) {
+	s, _, mockPullManager := newPullerServerWithMocks(t)
+	s.pullerServerConfig.StorageObjects = false
+
+	// the storage is never listed
+	mockPullManager.EXPECT().List(gomock.Any(), gomock.Any()).Times(0)
+
+	rec := httptest.NewRecorder()
+	s.statusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/storage/objects?storage_key=myStorage", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected HTTP status 404 without the storage objects endpoint but got %d", rec.Code)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pullman
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// suffix of the files in the directory of an ArtifactCache
+const artifactFileSuffix = ".artifact"
+
+// ArtifactCache keeps small files that are pulled for many models, like the
+// files shared by Mediapipe graphs or ensembles, so that they are downloaded
+// once and linked into the directory of each model
+//
+// Files are identified by an ArtifactKey from a checksum of their content, so
+// the same file is cached once whichever repository or path it is pulled
+// from. The least recently used files are evicted once the cache holds more
+// than its maximum size. Files are hard linked where possible, so evicting a
+// file does not affect the models that use it. A nil *ArtifactCache caches
+// nothing.
+type ArtifactCache struct {
+	dir            string
+	maxBytes       int64
+	maxObjectBytes int64
+
+	lock sync.Mutex
+	size int64
+	// the cached files, the most recently used first
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type artifactEntry struct {
+	key  string
+	path string
+	size int64
+}
+
+// ArtifactKey returns the key of a file in an ArtifactCache from a checksum of
+// its content and its size
+//
+// The kind of the checksum, like "md5", is part of the key, so that checksums
+// of different kinds never match.
+func ArtifactKey(kind string, checksum string, size int64) string {
+	return fmt.Sprintf("%s:%s:%d", kind, strings.ToLower(checksum), size)
+}
+
+// NewArtifactCache creates a cache of files of up to maxObjectBytes each in
+// dir, which holds up to maxBytes in total
+//
+// Files left in dir by a previous cache are removed.
+func NewArtifactCache(dir string, maxBytes int64, maxObjectBytes int64) (*ArtifactCache, error) {
+	if maxBytes <= 0 || maxObjectBytes <= 0 {
+		return nil, fmt.Errorf("artifact cache sizes must be positive, got %d and %d", maxBytes, maxObjectBytes)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create artifact cache directory '%s': %w", dir, err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read artifact cache directory '%s': %w", dir, err)
+	}
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), artifactFileSuffix) {
+			if err = os.Remove(filepath.Join(dir, f.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("unable to remove stale artifact '%s': %w", f.Name(), err)
+			}
+		}
+	}
+
+	return &ArtifactCache{
+		dir:            dir,
+		maxBytes:       maxBytes,
+		maxObjectBytes: maxObjectBytes,
+		lru:            list.New(),
+		entries:        map[string]*list.Element{},
+	}, nil
+}
+
+// Cacheable returns true if a file of the size is small enough to be cached
+func (c *ArtifactCache) Cacheable(size int64) bool {
+	return c != nil && size > 0 && size <= c.maxObjectBytes
+}
+
+// Link creates the file at path from the cached file with the key
+//
+// It returns false if no file is cached with the key.
+func (c *ArtifactCache) Link(key string, path string) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return false, nil
+	}
+	c.lru.MoveToFront(elem)
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return false, fmt.Errorf("error creating directories: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("unable to replace file '%s': %w", path, err)
+	}
+	if err := linkOrCopy(elem.Value.(*artifactEntry).path, path); err != nil {
+		return false, fmt.Errorf("unable to link cached artifact to '%s': %w", path, err)
+	}
+	return true, nil
+}
+
+// Store adds the pulled file at path to the cache with the key, if it is
+// small enough to be cached
+func (c *ArtifactCache) Store(key string, path string) error {
+	if c == nil {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("unable to stat file '%s': %w", path, err)
+	}
+	if !c.Cacheable(info.Size()) {
+		return nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		return nil
+	}
+
+	hash := sha256.Sum256([]byte(key))
+	cachePath := filepath.Join(c.dir, hex.EncodeToString(hash[:])+artifactFileSuffix)
+	if err = linkOrCopy(path, cachePath); err != nil {
+		return fmt.Errorf("unable to add file '%s' to the artifact cache: %w", path, err)
+	}
+	c.entries[key] = c.lru.PushFront(&artifactEntry{key: key, path: cachePath, size: info.Size()})
+	c.size += info.Size()
+
+	for c.size > c.maxBytes {
+		oldest := c.lru.Remove(c.lru.Back()).(*artifactEntry)
+		delete(c.entries, oldest.key)
+		c.size -= oldest.size
+		if err = os.Remove(oldest.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("unable to evict artifact '%s': %w", oldest.path, err)
+		}
+	}
+	return nil
+}
+
+// linkOrCopy hard links dst to src, or copies src if they are on different
+// filesystems
+func linkOrCopy(src string, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pullman
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeArtifact(t *testing.T, path string, content string) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func Test_ArtifactCache_LinkAndEvict(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	// a stale artifact of a previous cache is removed
+	writeArtifact(t, filepath.Join(cacheDir, "stale"+artifactFileSuffix), "stale")
+
+	cache, err := NewArtifactCache(cacheDir, 10, 5)
+	assert.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(cacheDir, "stale"+artifactFileSuffix))
+
+	modelDir := t.TempDir()
+	writeArtifact(t, filepath.Join(modelDir, "a"), "aaaa")
+	writeArtifact(t, filepath.Join(modelDir, "b"), "bbbb")
+	writeArtifact(t, filepath.Join(modelDir, "c"), "cccc")
+	writeArtifact(t, filepath.Join(modelDir, "large"), "too large")
+
+	// files larger than the object limit are not cached
+	assert.False(t, cache.Cacheable(9))
+	assert.NoError(t, cache.Store("large", filepath.Join(modelDir, "large")))
+	linked, err := cache.Link("large", filepath.Join(modelDir, "copy", "large"))
+	assert.NoError(t, err)
+	assert.False(t, linked)
+
+	assert.NoError(t, cache.Store("a", filepath.Join(modelDir, "a")))
+	assert.NoError(t, cache.Store("b", filepath.Join(modelDir, "b")))
+
+	// using "a" makes "b" the least recently used
+	linked, err = cache.Link("a", filepath.Join(modelDir, "copy", "a"))
+	assert.NoError(t, err)
+	assert.True(t, linked)
+	content, err := os.ReadFile(filepath.Join(modelDir, "copy", "a"))
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaa", string(content))
+
+	// exceeding the size evicts "b"
+	assert.NoError(t, cache.Store("c", filepath.Join(modelDir, "c")))
+	linked, err = cache.Link("b", filepath.Join(modelDir, "copy", "b"))
+	assert.NoError(t, err)
+	assert.False(t, linked)
+	for _, key := range []string{"a", "c"} {
+		linked, err = cache.Link(key, filepath.Join(modelDir, "copy", key))
+		assert.NoError(t, err)
+		assert.True(t, linked, "expected %s to be cached", key)
+	}
+
+	files, err := os.ReadDir(cacheDir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func Test_ArtifactCache_Nil(t *testing.T) {
+	var cache *ArtifactCache
+	assert.False(t, cache.Cacheable(1))
+	assert.NoError(t, cache.Store("key", "path"))
+	linked, err := cache.Link("key", "path")
+	assert.NoError(t, err)
+	assert.False(t, linked)
+}
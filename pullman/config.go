@@ -17,10 +17,26 @@ package pullman
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
 )
 
 const (
 	storageTypeKey = "type"
+
+	// Optional timeouts that are honored by the storage providers
+	ConfigConnectTimeout = "connect_timeout"
+	ConfigRequestTimeout = "request_timeout"
+
+	// Optional HTTP(S) proxy that is honored by the HTTP and S3 storage providers
+	ConfigProxyURL = "proxy_url"
+	ConfigNoProxy  = "no_proxy"
+
+	// Optional User-Agent of the requests that the storage providers send
+	ConfigUserAgent = "user_agent"
 )
 
 // Config represents simple key/value configuration with a type/class
@@ -44,6 +60,123 @@ func GetString(c Config, key string) (string, bool) {
 	return s, ok
 }
 
+// GetDuration returns a key's value as a duration and a bool if it was specified
+// The value can be a string accepted by time.ParseDuration (eg. "30s") or a
+// number of seconds
+func GetDuration(c Config, key string) (time.Duration, bool, error) {
+	val, exists := c.Get(key)
+	if !exists {
+		return 0, false, nil
+	}
+
+	var d time.Duration
+	switch v := val.(type) {
+	case string:
+		var err error
+		if d, err = time.ParseDuration(v); err != nil {
+			return 0, true, fmt.Errorf("could not parse '%s' as a duration for '%s': %w", v, key, err)
+		}
+	case float64:
+		d = time.Duration(v * float64(time.Second))
+	case int:
+		d = time.Duration(v) * time.Second
+	default:
+		return 0, true, fmt.Errorf("could not parse '%v' as a duration for '%s'", val, key)
+	}
+
+	if d < 0 {
+		return 0, true, fmt.Errorf("duration for '%s' must not be negative", key)
+	}
+	return d, true, nil
+}
+
+// Timeouts are the connection and request timeouts configured for a repository
+// A zero value means that the provider's default is used
+type Timeouts struct {
+	// maximum time to establish a connection to the remote service
+	Connect time.Duration
+	// maximum time for a single request, including reading the response body
+	Request time.Duration
+}
+
+// GetTimeouts reads the optional connect_timeout and request_timeout from the config
+func GetTimeouts(c Config) (Timeouts, error) {
+	var t Timeouts
+	var err error
+	if t.Connect, _, err = GetDuration(c, ConfigConnectTimeout); err != nil {
+		return t, err
+	}
+	if t.Request, _, err = GetDuration(c, ConfigRequestTimeout); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// String returns a representation of the timeouts that can be used as part of a
+// provider's client key
+func (t Timeouts) String() string {
+	return fmt.Sprintf("connect=%s,request=%s", t.Connect, t.Request)
+}
+
+// Proxy is the HTTP(S) proxy configured for a repository
+// A zero value means that the proxy is taken from the environment, see
+// http.ProxyFromEnvironment
+type Proxy struct {
+	// URL of the proxy for both http and https requests
+	URL string
+	// comma-separated hosts, domains, IP addresses and CIDRs that are reached
+	// directly, with the same format as the NO_PROXY environment variable
+	NoProxy string
+}
+
+// GetProxy reads the optional proxy_url and no_proxy from the config
+// If proxy_url is set without no_proxy, the NO_PROXY environment variable is used
+func GetProxy(c Config) (Proxy, error) {
+	var p Proxy
+	p.URL, _ = GetString(c, ConfigProxyURL)
+	if p.URL == "" {
+		return p, nil
+	}
+	if _, err := url.Parse(p.URL); err != nil {
+		return p, fmt.Errorf("could not parse '%s' as a URL for '%s': %w", p.URL, ConfigProxyURL, err)
+	}
+
+	var ok bool
+	if p.NoProxy, ok = GetString(c, ConfigNoProxy); !ok {
+		p.NoProxy = httpproxy.FromEnvironment().NoProxy
+	}
+	return p, nil
+}
+
+// ProxyFunc returns the function to select the proxy of a request, to be used
+// as the Proxy of an http.Transport
+func (p Proxy) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	if p.URL == "" {
+		return http.ProxyFromEnvironment
+	}
+	proxyFunc := (&httpproxy.Config{
+		HTTPProxy:  p.URL,
+		HTTPSProxy: p.URL,
+		NoProxy:    p.NoProxy,
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+// String returns a representation of the proxy that can be used as part of a
+// provider's client key
+func (p Proxy) String() string {
+	return fmt.Sprintf("proxy=%s,no_proxy=%s", p.URL, p.NoProxy)
+}
+
+// GetUserAgent reads the optional user_agent from the config, an empty string
+// means that the provider's client sends its default User-Agent
+func GetUserAgent(c Config) string {
+	userAgent, _ := GetString(c, ConfigUserAgent)
+	return userAgent
+}
+
 // Generic config abstraction used by PullMan
 type RepositoryConfig struct {
 	config      map[string]interface{}
@@ -16,7 +16,10 @@ package pullman
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func Test_ConfigParsing(t *testing.T) {
@@ -44,3 +47,226 @@ func Test_ConfigParsing(t *testing.T) {
 		t.Error("expected config type to be 's3'")
 	}
 }
+
+func Test_GetTimeouts(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      map[string]interface{}
+		expected    Timeouts
+		expectError bool
+	}{
+		{
+			name:     "unset",
+			config:   nil,
+			expected: Timeouts{},
+		},
+		{
+			name: "duration strings",
+			config: map[string]interface{}{
+				ConfigConnectTimeout: "500ms",
+				ConfigRequestTimeout: "2m",
+			},
+			expected: Timeouts{Connect: 500 * time.Millisecond, Request: 2 * time.Minute},
+		},
+		{
+			name: "seconds",
+			config: map[string]interface{}{
+				ConfigConnectTimeout: float64(1.5),
+				ConfigRequestTimeout: float64(30),
+			},
+			expected: Timeouts{Connect: 1500 * time.Millisecond, Request: 30 * time.Second},
+		},
+		{
+			name: "invalid string",
+			config: map[string]interface{}{
+				ConfigRequestTimeout: "forever",
+			},
+			expectError: true,
+		},
+		{
+			name: "negative",
+			config: map[string]interface{}{
+				ConfigConnectTimeout: "-1s",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid type",
+			config: map[string]interface{}{
+				ConfigConnectTimeout: true,
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewRepositoryConfig("test", tc.config)
+			timeouts, err := GetTimeouts(c)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got timeouts %v", timeouts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if timeouts != tc.expected {
+				t.Errorf("expected timeouts %v but got %v", tc.expected, timeouts)
+			}
+		})
+	}
+}
+
+func Test_NewHTTPClient_RequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(Timeouts{Request: 100 * time.Millisecond})
+
+	start := time.Now()
+	resp, err := client.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the request to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the request to time out after 100ms but it took %s", elapsed)
+	}
+}
+
+func Test_NewProxiedHTTPClient(t *testing.T) {
+	// a stub proxy that answers the requests itself
+	var proxiedHosts []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHosts = append(proxiedHosts, r.URL.Host)
+	}))
+	defer proxy.Close()
+
+	client := NewProxiedHTTPClient(Timeouts{}, Proxy{URL: proxy.URL})
+
+	resp, err := client.Get("http://s3.example.com/bucket")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if len(proxiedHosts) != 1 || proxiedHosts[0] != "s3.example.com" {
+		t.Errorf("expected the request to s3.example.com to go through the proxy but it got %v", proxiedHosts)
+	}
+}
+
+func Test_NewUserAgentTransport(t *testing.T) {
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(Timeouts{Connect: time.Second})
+	client.Transport = NewUserAgentTransport(client.Transport, "modelmesh-puller/1.0 (model-server)")
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("User-Agent", "sdk/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if len(userAgents) != 1 || userAgents[0] != "modelmesh-puller/1.0 (model-server)" {
+		t.Errorf("expected the configured user agent to be sent but got %v", userAgents)
+	}
+	if req.Header.Get("User-Agent") != "sdk/1.0" {
+		t.Errorf("expected the request of the caller not to be modified but got %s", req.Header.Get("User-Agent"))
+	}
+}
+
+func Test_GetProxy(t *testing.T) {
+	t.Setenv("NO_PROXY", "env.example.com")
+
+	testCases := []struct {
+		name        string
+		config      map[string]interface{}
+		expected    Proxy
+		proxied     map[string]bool
+		expectError bool
+	}{
+		{
+			name:     "unset",
+			config:   nil,
+			expected: Proxy{},
+		},
+		{
+			name: "no_proxy from the environment",
+			config: map[string]interface{}{
+				ConfigProxyURL: "http://proxy.example.com:3128",
+			},
+			expected: Proxy{URL: "http://proxy.example.com:3128", NoProxy: "env.example.com"},
+			proxied: map[string]bool{
+				"https://s3.example.com/bucket": true,
+				"http://env.example.com/model":  false,
+			},
+		},
+		{
+			name: "no_proxy",
+			config: map[string]interface{}{
+				ConfigProxyURL: "http://proxy.example.com:3128",
+				ConfigNoProxy:  ".internal.example.com,10.0.0.0/8",
+			},
+			expected: Proxy{URL: "http://proxy.example.com:3128", NoProxy: ".internal.example.com,10.0.0.0/8"},
+			proxied: map[string]bool{
+				"http://env.example.com/model":          true,
+				"https://s3.internal.example.com/model": false,
+				"http://10.1.2.3:9000/bucket":           false,
+			},
+		},
+		{
+			name: "invalid URL",
+			config: map[string]interface{}{
+				ConfigProxyURL: "http://proxy\x7f",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewRepositoryConfig("test", tc.config)
+			proxy, err := GetProxy(c)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got proxy %v", proxy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if proxy != tc.expected {
+				t.Errorf("expected proxy %v but got %v", tc.expected, proxy)
+			}
+
+			proxyFunc := proxy.ProxyFunc()
+			for target, expectProxied := range tc.proxied {
+				req := httptest.NewRequest("GET", target, nil)
+				proxyURL, err := proxyFunc(req)
+				if err != nil {
+					t.Fatalf("unexpected error selecting the proxy for %s: %v", target, err)
+				}
+				if (proxyURL != nil) != expectProxied {
+					t.Errorf("expected request to %s to be proxied %v but got proxy %v", target, expectProxied, proxyURL)
+				}
+			}
+		})
+	}
+}
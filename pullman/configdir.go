@@ -0,0 +1,86 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pullman
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadConfigDir reads configuration fields from a directory where each file is
+// a field: the file name is the key and the file contents are the value
+//
+// This supports configuration mounted from a secret with one file per key.
+// Hidden files and sub-directories are ignored, which skips the bookkeeping
+// entries (eg. `..data`) that Kubernetes creates for mounted volumes. Trailing
+// newlines are trimmed from the values.
+func ReadConfigDir(dir string) (map[string]interface{}, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config directory '%s': %w", dir, err)
+	}
+
+	config := make(map[string]interface{}, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		// follow symlinks, since mounted files usually are
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config field file '%s': %w", path, err)
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config field file '%s': %w", path, err)
+		}
+		config[entry.Name()] = strings.TrimRight(string(value), "\r\n")
+	}
+
+	return config, nil
+}
+
+// NewRepositoryConfigFromDir assembles a RepositoryConfig from a directory of
+// field files, see ReadConfigDir
+//
+// The `type` field is always required, and an error naming the missing fields
+// is returned if it or any of the requiredFields is not in the directory.
+func NewRepositoryConfigFromDir(dir string, requiredFields ...string) (*RepositoryConfig, error) {
+	config, err := ReadConfigDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, field := range append([]string{storageTypeKey}, requiredFields...) {
+		if _, ok := config[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("config directory '%s' is missing required fields: %s", dir, strings.Join(missing, ", "))
+	}
+
+	storageType, _ := config[storageTypeKey].(string)
+	return NewRepositoryConfig(storageType, config), nil
+}
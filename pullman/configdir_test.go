@@ -0,0 +1,85 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pullman
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigDir(t *testing.T, fields map[string]string) string {
+	dir := t.TempDir()
+	for name, value := range fields {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0644); err != nil {
+			t.Fatalf("error writing config field file: %v", err)
+		}
+	}
+	return dir
+}
+
+func Test_NewRepositoryConfigFromDir(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"type":              "s3",
+		"endpoint_url":      "https://s3.example.service\n",
+		"access_key_id":     "access key",
+		"secret_access_key": "secret key\n",
+		// hidden files are ignored
+		".hidden": "ignored",
+	})
+	// so are sub-directories
+	if err := os.Mkdir(filepath.Join(dir, "..data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := NewRepositoryConfigFromDir(dir, "endpoint_url", "access_key_id", "secret_access_key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rc.GetType() != "s3" {
+		t.Errorf("expected config type to be 's3' but got '%s'", rc.GetType())
+	}
+	expected := map[string]string{
+		"endpoint_url":      "https://s3.example.service",
+		"access_key_id":     "access key",
+		"secret_access_key": "secret key",
+	}
+	for key, value := range expected {
+		if v, _ := rc.GetString(key); v != value {
+			t.Errorf("expected '%s' to be '%s' but got '%s'", key, value, v)
+		}
+	}
+	for _, key := range []string{".hidden", "..data"} {
+		if _, ok := rc.Get(key); ok {
+			t.Errorf("expected '%s' to be ignored", key)
+		}
+	}
+}
+
+func Test_NewRepositoryConfigFromDir_MissingFields(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"endpoint_url": "https://s3.example.service",
+	})
+
+	_, err := NewRepositoryConfigFromDir(dir, "endpoint_url", "access_key_id")
+	if err == nil {
+		t.Fatal("expected an error for missing required fields")
+	}
+	if !strings.Contains(err.Error(), "missing required fields: type, access_key_id") {
+		t.Errorf("expected the error to name the missing fields, got: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pullman
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ExtractTar extracts the tar archive read from r into dir as it is read, so
+// that the archive itself is never written to disk. A gzip compressed archive
+// is detected and decompressed.
+//
+// Entries that would be written outside of dir are rejected, as are links,
+// since they could be used to escape dir. If maxBytes is positive, an error
+// is returned once the extracted files exceed it.
+func ExtractTar(r io.Reader, dir string, maxBytes int64) error {
+	return ExtractTarSubpath(r, dir, "", maxBytes)
+}
+
+// ExtractTarSubpath is ExtractTar for only the entries under subpath within
+// the archive, which are extracted relative to subpath, eg. the entry
+// `models/mnist/1/model.onnx` is extracted to `<dir>/1/model.onnx` for the
+// subpath `models/mnist`. If subpath is a file, it is extracted to the path
+// dir itself, so that a single model file is placed where the model is
+// expected rather than in a directory of the same name.
+//
+// The other entries are skipped, and it is an error if no entry is under
+// subpath. An empty subpath extracts the whole archive.
+func ExtractTarSubpath(r io.Reader, dir string, subpath string, maxBytes int64) error {
+	subpath = path.Clean("/" + filepath.ToSlash(subpath))[1:]
+
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gzr, gzErr := gzip.NewReader(br)
+		if gzErr != nil {
+			return fmt.Errorf("error reading gzip stream: %w", gzErr)
+		}
+		defer gzr.Close()
+		r = gzr
+	} else {
+		r = br
+	}
+
+	// with a subpath, dir is only created once it is known whether the
+	// subpath is a directory
+	if subpath == "" {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return fmt.Errorf("error creating directory '%s': %w", dir, err)
+		}
+	}
+
+	var extractedBytes int64
+	found := false
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading tar stream: %w", err)
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			// PAX metadata, nothing to extract
+			continue
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("tar entry '%s' is outside of the target directory", header.Name)
+		}
+		if subpath != "" {
+			var ok bool
+			if name, ok = relativeToSubpath(filepath.ToSlash(name), subpath, header.Typeflag); !ok {
+				continue
+			}
+			name = filepath.FromSlash(name)
+		}
+		found = true
+		target := filepath.Join(dir, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return fmt.Errorf("error creating directory '%s': %w", target, err)
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			extractedBytes += header.Size
+			if maxBytes > 0 && extractedBytes > maxBytes {
+				return fmt.Errorf("extracted files exceed the limit of %d bytes", maxBytes)
+			}
+			if err := extractFile(tr, target); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("tar entry '%s' has unsupported type '%c'", header.Name, header.Typeflag)
+		}
+	}
+
+	if subpath != "" && !found {
+		return fmt.Errorf("tar archive has no entries under '%s'", subpath)
+	}
+	return nil
+}
+
+// relativeToSubpath returns the path of the entry relative to subpath, and
+// false if the entry is not under subpath
+func relativeToSubpath(name string, subpath string, typeflag byte) (string, bool) {
+	switch {
+	case name == subpath && typeflag == tar.TypeDir:
+		return ".", true
+	case name == subpath:
+		return ".", true
+	case strings.HasPrefix(name, subpath+"/"):
+		return strings.TrimPrefix(name, subpath+"/"), true
+	default:
+		return "", false
+	}
+}
+
+func extractFile(r io.Reader, path string) error {
+	file, err := OpenFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to open local file '%s' for writing: %w", path, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, r); err != nil {
+		return fmt.Errorf("error writing tar entry to local file '%s': %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pullman
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+func buildTar(t *testing.T, entries []tarEntry, compress bool) io.Reader {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gzw *gzip.Writer
+	if compress {
+		gzw = gzip.NewWriter(&buf)
+		w = gzw
+	}
+
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		header := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Mode:     0644,
+			Size:     int64(len(e.content)),
+			Linkname: e.linkname,
+		}
+		if e.typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("error writing tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatalf("error writing tar entry: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("error closing tar writer: %v", err)
+	}
+	if gzw != nil {
+		if err := gzw.Close(); err != nil {
+			t.Fatalf("error closing gzip writer: %v", err)
+		}
+	}
+	return &buf
+}
+
+func TestExtractTar(t *testing.T) {
+	entries := []tarEntry{
+		{name: "model/", typeflag: tar.TypeDir},
+		{name: "model/saved_model.pb", typeflag: tar.TypeReg, content: "graph"},
+		{name: "model/variables/variables.index", typeflag: tar.TypeReg, content: "index"},
+		{name: "config.pbtxt", typeflag: tar.TypeReg, content: "config"},
+	}
+
+	for _, compress := range []bool{false, true} {
+		dir := t.TempDir()
+		if err := ExtractTar(buildTar(t, entries, compress), dir, 0); err != nil {
+			t.Fatalf("Unexpected error extracting tar (compressed: %v): %v", compress, err)
+		}
+
+		for _, e := range entries[1:] {
+			content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(e.name)))
+			if err != nil {
+				t.Fatalf("Expected extracted file '%s': %v", e.name, err)
+			}
+			if string(content) != e.content {
+				t.Errorf("Expected content '%s' in '%s' but got '%s'", e.content, e.name, string(content))
+			}
+		}
+	}
+}
+
+func TestExtractTarRejectsEscapingEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry tarEntry
+	}{
+		{"parent dir", tarEntry{name: "../escaped", typeflag: tar.TypeReg, content: "evil"}},
+		{"nested parent dir", tarEntry{name: "model/../../escaped", typeflag: tar.TypeReg, content: "evil"}},
+		{"absolute path", tarEntry{name: "/escaped", typeflag: tar.TypeReg, content: "evil"}},
+		{"symlink", tarEntry{name: "link", typeflag: tar.TypeSymlink, linkname: "/etc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "target")
+			entries := []tarEntry{{name: "ok.txt", typeflag: tar.TypeReg, content: "ok"}, tt.entry}
+
+			if err := ExtractTar(buildTar(t, entries, false), dir, 0); err == nil {
+				t.Fatal("Expected an error extracting the tar")
+			}
+			if _, err := os.Stat(filepath.Join(parent, "escaped")); !os.IsNotExist(err) {
+				t.Errorf("Expected no file outside of the target directory but got %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractTarSizeLimit(t *testing.T) {
+	entries := []tarEntry{
+		{name: "a", typeflag: tar.TypeReg, content: strings.Repeat("a", 60)},
+		{name: "b", typeflag: tar.TypeReg, content: strings.Repeat("b", 60)},
+	}
+
+	dir := t.TempDir()
+	err := ExtractTar(buildTar(t, entries, false), dir, 100)
+	if err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Fatalf("Expected a size limit error but got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b")); !os.IsNotExist(err) {
+		t.Errorf("Expected the file over the limit not to be written but got %v", err)
+	}
+
+	if err := ExtractTar(buildTar(t, entries, false), t.TempDir(), 120); err != nil {
+		t.Errorf("Unexpected error extracting tar within the limit: %v", err)
+	}
+}
+
+func TestExtractTarSubpath(t *testing.T) {
+	entries := []tarEntry{
+		{name: "models/", typeflag: tar.TypeDir},
+		{name: "models/mnist/", typeflag: tar.TypeDir},
+		{name: "models/mnist/1/model.onnx", typeflag: tar.TypeReg, content: "mnist"},
+		{name: "models/mnist/config.pbtxt", typeflag: tar.TypeReg, content: "config"},
+		{name: "models/mnist2/1/model.onnx", typeflag: tar.TypeReg, content: "mnist2"},
+		{name: "models/resnet/1/model.plan", typeflag: tar.TypeReg, content: "resnet"},
+		// links outside the subpath are skipped rather than rejected
+		{name: "models/latest", typeflag: tar.TypeSymlink, linkname: "mnist"},
+	}
+
+	dir := t.TempDir()
+	if err := ExtractTarSubpath(buildTar(t, entries, true), dir, "./models/mnist/", 0); err != nil {
+		t.Fatalf("Unexpected error extracting tar subpath: %v", err)
+	}
+
+	var extracted []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			extracted = append(extracted, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	expected := []string{"1/model.onnx", "config.pbtxt"}
+	if strings.Join(extracted, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected only files %v to be extracted but got %v", expected, extracted)
+	}
+	content, _ := os.ReadFile(filepath.Join(dir, "1", "model.onnx"))
+	if string(content) != "mnist" {
+		t.Errorf("Expected content 'mnist' but got '%s'", string(content))
+	}
+
+	// a single file is placed at the target path itself
+	dir = t.TempDir()
+	target := filepath.Join(dir, "model.plan")
+	if err := ExtractTarSubpath(buildTar(t, entries, false), target, "models/resnet/1/model.plan", 0); err != nil {
+		t.Fatalf("Unexpected error extracting tar subpath: %v", err)
+	}
+	if content, err := os.ReadFile(target); err != nil || string(content) != "resnet" {
+		t.Errorf("Expected extracted file '%s' with content 'resnet', got '%s' (error: %v)", target, string(content), err)
+	}
+
+	// a subpath that is not in the archive
+	err := ExtractTarSubpath(buildTar(t, entries, false), t.TempDir(), "models/bert", 0)
+	if err == nil || !strings.Contains(err.Error(), "no entries under 'models/bert'") {
+		t.Errorf("Expected an error for a missing subpath but got %v", err)
+	}
+}
@@ -17,8 +17,11 @@ package pullman
 import (
 	"fmt"
 	"hash/fnv"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // OpenFile will check the path and the filesystem for mismatch errors
@@ -58,3 +61,79 @@ func HashStrings(strings ...string) string {
 
 	return fmt.Sprintf("%#x", h.Sum64())
 }
+
+// NewHTTPClient creates an HTTP client that honors the configured timeouts
+// Providers whose SDK accepts a custom HTTP client can use this to apply the
+// timeouts. Unset timeouts keep the defaults of net/http.
+func NewHTTPClient(t Timeouts) *http.Client {
+	return NewProxiedHTTPClient(t, Proxy{})
+}
+
+// NewProxiedHTTPClient creates an HTTP client that honors the configured
+// timeouts and sends its requests through the configured proxy
+func NewProxiedHTTPClient(t Timeouts, p Proxy) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = p.ProxyFunc()
+	if t.Connect > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   t.Connect,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+		transport.TLSHandshakeTimeout = t.Connect
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   t.Request,
+	}
+}
+
+// NewUserAgentTransport wraps the base transport to set the User-Agent of each
+// request, for SDKs that do not set their configured user agent on a custom
+// HTTP client
+func NewUserAgentTransport(base http.RoundTripper, userAgent string) http.RoundTripper {
+	if userAgent == "" {
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &userAgentTransport{base: base, userAgent: userAgent}
+}
+
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
+
+// RequestIDError is an error from a storage service together with the id the
+// service assigned to the failed request, which the service's support needs
+// to trace the request
+type RequestIDError struct {
+	RequestID string
+	Err       error
+}
+
+func (e *RequestIDError) Error() string {
+	return fmt.Sprintf("%s (request id: %s)", e.Err, e.RequestID)
+}
+
+func (e *RequestIDError) Unwrap() error {
+	return e.Err
+}
+
+// WithRequestID adds the request id to the error, or returns the error as is
+// if the request id is empty
+func WithRequestID(err error, requestID string) error {
+	if err == nil || requestID == "" {
+		return err
+	}
+	return &RequestIDError{RequestID: requestID, Err: err}
+}
@@ -16,13 +16,24 @@ package pullman
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 )
 
+// ErrListNotSupported is returned by List for repositories whose client is
+// not a RepositoryLister
+var ErrListNotSupported = errors.New("listing objects is not supported")
+
 // package global map to track registered storage providers
 var registeredStorageProviders map[string]StorageProvider
 
@@ -78,9 +89,107 @@ func (p *PullManager) Pull(ctx context.Context, pc PullCommand) error {
 		return fmt.Errorf("could not process pull command: %w", err)
 	}
 
+	if extractor, ok := repo.(TarExtractor); (!ok || !extractor.ExtractsTar()) && hasExtractTarTargets(pc.Targets) {
+		return pullAndExtract(ctx, repo, pc)
+	}
 	return repo.Pull(ctx, pc)
 }
 
+func hasExtractTarTargets(targets []Target) bool {
+	for _, t := range targets {
+		if t.ExtractTar {
+			return true
+		}
+	}
+	return false
+}
+
+// pullAndExtract pulls the archives of the Targets with ExtractTar as files
+// into a temporary directory in the pull directory, and extracts them from
+// there to their LocalPath
+func pullAndExtract(ctx context.Context, repo RepositoryClient, pc PullCommand) error {
+	if err := os.MkdirAll(pc.Directory, os.ModePerm); err != nil {
+		return fmt.Errorf("error creating directory '%s': %w", pc.Directory, err)
+	}
+	archiveDir, err := os.MkdirTemp(pc.Directory, ".archives-")
+	if err != nil {
+		return fmt.Errorf("error creating directory for archives in '%s': %w", pc.Directory, err)
+	}
+	defer os.RemoveAll(archiveDir)
+
+	// the archives are pulled to files named by the index of their target
+	targets := make([]Target, len(pc.Targets))
+	for i, t := range pc.Targets {
+		targets[i] = t
+		if t.ExtractTar {
+			targets[i] = Target{
+				RemotePath: t.RemotePath,
+				LocalPath:  path.Join(filepath.Base(archiveDir), strconv.Itoa(i)),
+				VersionID:  t.VersionID,
+			}
+		}
+	}
+	archivePC := pc
+	archivePC.Targets = targets
+	if err = repo.Pull(ctx, archivePC); err != nil {
+		return err
+	}
+
+	for i, t := range pc.Targets {
+		if !t.ExtractTar {
+			continue
+		}
+		dest, joinErr := util.SecureJoin(pc.Directory, t.LocalPath)
+		if joinErr != nil {
+			return fmt.Errorf("error joining filepaths '%s' and '%s': %w", pc.Directory, t.LocalPath, joinErr)
+		}
+		if err = extractArchiveFile(filepath.Join(archiveDir, strconv.Itoa(i)), dest, t.TarSubpath, t.MaxExtractedBytes); err != nil {
+			return fmt.Errorf("unable to extract archive '%s': %w", t.RemotePath, err)
+		}
+	}
+	return nil
+}
+
+func extractArchiveFile(archive string, dest string, subpath string, maxBytes int64) error {
+	info, err := os.Stat(archive)
+	if err != nil {
+		return fmt.Errorf("error reading pulled archive: %w", err)
+	}
+	if info.IsDir() {
+		return errors.New("the remote path is not a single archive file")
+	}
+	file, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("error reading pulled archive: %w", err)
+	}
+	defer file.Close()
+	return ExtractTarSubpath(file, dest, subpath, maxBytes)
+}
+
+// List returns the objects under the prefix of the ListCommand, without
+// downloading them
+func (p *PullManager) List(ctx context.Context, lc ListCommand) ([]ObjectInfo, error) {
+	repo, err := p.getRepositoryClient(lc.RepositoryConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not process list command: %w", err)
+	}
+
+	lister, ok := repo.(RepositoryLister)
+	if !ok {
+		return nil, fmt.Errorf("%w by repositories of type '%s'", ErrListNotSupported, lc.RepositoryConfig.GetType())
+	}
+	return lister.List(ctx, lc)
+}
+
+// WarmUp creates and caches the repository client for the config ahead of
+// the first Pull that uses it
+func (p *PullManager) WarmUp(config Config) error {
+	if _, err := p.getRepositoryClient(config); err != nil {
+		return fmt.Errorf("could not warm up repository client: %w", err)
+	}
+	return nil
+}
+
 func (p *PullManager) getRepositoryClient(config Config) (RepositoryClient, error) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
@@ -15,10 +15,15 @@
 package pullman
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/go-logr/logr"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -149,3 +154,179 @@ func Test_Errors_InvalidConfig(t *testing.T) {
 	// should have no cached client
 	assert.Equal(t, 0, len(pm.clientCache.cache))
 }
+
+func Test_WarmUp_CachesClient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	pm, msp := newPullManagerWithMock(ctrl)
+	mrc := NewMockRepositoryClient(ctrl)
+	ckey := "warm"
+	msp.RegisterMockClient(ckey, mrc)
+
+	mrcConfig := NewRepositoryConfig(mockProviderType, nil)
+	mrcConfig.Set(mockConfigKey, ckey)
+
+	err := pm.WarmUp(mrcConfig)
+	assert.NoError(t, err)
+	// the client is cached before any pull
+	assert.Equal(t, 1, len(pm.clientCache.cache))
+
+	// the first pull uses the cached client
+	pc := PullCommand{
+		RepositoryConfig: mrcConfig,
+		Targets:          []Target{},
+	}
+	ctx := context.Background()
+	mrc.EXPECT().Pull(ctx, pc).Return(nil).Times(1)
+	err = pm.Pull(ctx, pc)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(pm.clientCache.cache))
+}
+
+func Test_WarmUp_InvalidConfig(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	pm, _ := newPullManagerWithMock(ctrl)
+
+	err := pm.WarmUp(NewRepositoryConfig("unknown", nil))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no provider registered")
+	assert.Equal(t, 0, len(pm.clientCache.cache))
+}
+
+// a provider of clients that can only pull
+type pullOnlyProvider struct{}
+
+type pullOnlyClient struct{}
+
+func (p pullOnlyProvider) GetKey(config Config) string {
+	return ""
+}
+
+func (p pullOnlyProvider) NewRepository(config Config, log logr.Logger) (RepositoryClient, error) {
+	return pullOnlyClient{}, nil
+}
+
+func (c pullOnlyClient) Pull(ctx context.Context, pc PullCommand) error {
+	return nil
+}
+
+func Test_List(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	pm, msp := newPullManagerWithMock(ctrl)
+	mrc := NewMockRepositoryClient(ctrl)
+	ckey := "list"
+	msp.RegisterMockClient(ckey, mrc)
+
+	mrcConfig := NewRepositoryConfig(mockProviderType, nil)
+	mrcConfig.Set(mockConfigKey, ckey)
+
+	lc := ListCommand{
+		RepositoryConfig: mrcConfig,
+		Prefix:           "models/",
+	}
+	expectedObjects := []ObjectInfo{
+		{Path: "models/mnist/model.onnx", Size: 1024},
+		{Path: "models/resnet/model.onnx", Size: 2048},
+	}
+	ctx := context.Background()
+	mrc.EXPECT().List(ctx, lc).Return(expectedObjects, nil).Times(1)
+
+	objects, err := pm.List(ctx, lc)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedObjects, objects)
+}
+
+func Test_List_NotSupported(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	pm, _ := newPullManagerWithMock(ctrl)
+	pm.storageProviders["pullonly"] = pullOnlyProvider{}
+
+	_, err := pm.List(context.Background(), ListCommand{RepositoryConfig: NewRepositoryConfig("pullonly", nil)})
+	assert.ErrorIs(t, err, ErrListNotSupported)
+	assert.Contains(t, err.Error(), "'pullonly'")
+}
+
+// a client that pulls every target as a tar archive of the files
+type archiveClient struct {
+	files map[string]string
+	pulls []PullCommand
+}
+
+func (c *archiveClient) Pull(ctx context.Context, pc PullCommand) error {
+	c.pulls = append(c.pulls, pc)
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range c.files {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	for _, t := range pc.Targets {
+		file, err := OpenFile(filepath.Join(pc.Directory, t.LocalPath))
+		if err != nil {
+			return err
+		}
+		file.Write(buf.Bytes())
+		file.Close()
+	}
+	return nil
+}
+
+type archiveProvider struct {
+	client *archiveClient
+}
+
+func (p archiveProvider) GetKey(config Config) string {
+	return ""
+}
+
+func (p archiveProvider) NewRepository(config Config, log logr.Logger) (RepositoryClient, error) {
+	return p.client, nil
+}
+
+func Test_Pull_ExtractTar(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	pm, _ := newPullManagerWithMock(ctrl)
+	client := &archiveClient{files: map[string]string{
+		"models/mnist/1/model.onnx":  "mnist",
+		"models/resnet/1/model.plan": "resnet",
+		"models/resnet/config.pbtxt": "config",
+	}}
+	pm.storageProviders["archive"] = archiveProvider{client: client}
+
+	dir := t.TempDir()
+	err := pm.Pull(context.Background(), PullCommand{
+		RepositoryConfig: NewRepositoryConfig("archive", nil),
+		Directory:        dir,
+		Targets: []Target{
+			{RemotePath: "models.tar", LocalPath: "mnist", ExtractTar: true, TarSubpath: "models/mnist"},
+			{RemotePath: "models.tar", LocalPath: "model.plan", ExtractTar: true, TarSubpath: "models/resnet/1/model.plan"},
+		},
+	})
+	assert.NoError(t, err)
+
+	// the archives are pulled as files, which are removed once extracted
+	assert.Len(t, client.pulls, 1)
+	for _, target := range client.pulls[0].Targets {
+		assert.False(t, target.ExtractTar)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "mnist", "1", "model.onnx"))
+	assert.NoError(t, err)
+	assert.Equal(t, "mnist", string(content))
+	// a single file is placed at the LocalPath
+	content, err = os.ReadFile(filepath.Join(dir, "model.plan"))
+	assert.NoError(t, err)
+	assert.Equal(t, "resnet", string(content))
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"mnist", "model.plan"}, names)
+}
@@ -16,9 +16,13 @@ package azureprovider
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/go-logr/logr"
@@ -38,8 +42,8 @@ type azureImplDownloader struct {
 	log    logr.Logger
 }
 
-func (f azureClientFactory) newDownloaderWithNoCredential(log logr.Logger, containerUrl string) (azureDownloader, error) {
-	containerClient, err := azblob.NewContainerClientWithNoCredential(containerUrl, nil)
+func (f azureClientFactory) newDownloaderWithNoCredential(log logr.Logger, containerUrl string, timeouts pullman.Timeouts, userAgent string) (azureDownloader, error) {
+	containerClient, err := azblob.NewContainerClientWithNoCredential(containerUrl, newClientOptions(timeouts, userAgent))
 	if err != nil {
 		return nil, err
 	}
@@ -49,8 +53,8 @@ func (f azureClientFactory) newDownloaderWithNoCredential(log logr.Logger, conta
 	}, nil
 }
 
-func (f azureClientFactory) newDownloaderWithConnectionString(log logr.Logger, containerName string, connectionString string) (azureDownloader, error) {
-	containerClient, err := azblob.NewContainerClientFromConnectionString(connectionString, containerName, nil)
+func (f azureClientFactory) newDownloaderWithConnectionString(log logr.Logger, containerName string, connectionString string, timeouts pullman.Timeouts, userAgent string) (azureDownloader, error) {
+	containerClient, err := azblob.NewContainerClientFromConnectionString(connectionString, containerName, newClientOptions(timeouts, userAgent))
 	if err != nil {
 		return nil, err
 	}
@@ -60,12 +64,12 @@ func (f azureClientFactory) newDownloaderWithConnectionString(log logr.Logger, c
 	}, nil
 }
 
-func (f azureClientFactory) newDownloaderWithServicePrincipal(log logr.Logger, containerUrl string, credentials servicePrincipalCredentials) (azureDownloader, error) {
+func (f azureClientFactory) newDownloaderWithServicePrincipal(log logr.Logger, containerUrl string, credentials servicePrincipalCredentials, timeouts pullman.Timeouts, userAgent string) (azureDownloader, error) {
 	cred, err := azidentity.NewClientSecretCredential(credentials.tenantId, credentials.clientId, credentials.clientSecret, nil)
 	if err != nil {
 		return nil, err
 	}
-	containerClient, err := azblob.NewContainerClient(containerUrl, cred, nil)
+	containerClient, err := azblob.NewContainerClient(containerUrl, cred, newClientOptions(timeouts, userAgent))
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +79,49 @@ func (f azureClientFactory) newDownloaderWithServicePrincipal(log logr.Logger, c
 	}, nil
 }
 
-func (d *azureImplDownloader) listObjects(ctx context.Context, prefix string) ([]string, error) {
+// newClientOptions returns nil to use the SDK defaults unless timeouts or a
+// user agent are configured
+//
+// The user agent is added by a policy of its own rather than as the
+// application ID of the SDK, which would truncate it to 24 characters.
+func newClientOptions(timeouts pullman.Timeouts, userAgent string) *azblob.ClientOptions {
+	if timeouts == (pullman.Timeouts{}) && userAgent == "" {
+		return nil
+	}
+	options := &azblob.ClientOptions{}
+	if userAgent != "" {
+		options.PerCallOptions = []policy.Policy{userAgentPolicy{userAgent: userAgent}}
+	}
+	if timeouts != (pullman.Timeouts{}) {
+		options.Transporter = pullman.NewHTTPClient(timeouts)
+	}
+	return options
+}
+
+// userAgentPolicy appends the user agent to the telemetry of the SDK in the
+// User-Agent of each request
+type userAgentPolicy struct {
+	userAgent string
+}
+
+func (p userAgentPolicy) Do(req *policy.Request) (*http.Response, error) {
+	userAgent := p.userAgent
+	if sdk := req.Raw().Header.Get("User-Agent"); sdk != "" {
+		userAgent = sdk + " " + userAgent
+	}
+	req.Raw().Header.Set("User-Agent", userAgent)
+	return req.Next()
+}
+
+func (d *azureImplDownloader) listObjects(ctx context.Context, prefix string, keepEmpty bool) ([]pullman.ObjectInfo, error) {
+	return d.listBlobs(ctx, prefix, keepEmpty)
+}
+
+func (d *azureImplDownloader) listObjectInfos(ctx context.Context, prefix string) ([]pullman.ObjectInfo, error) {
+	return d.listBlobs(ctx, prefix, false)
+}
+
+func (d *azureImplDownloader) listBlobs(ctx context.Context, prefix string, keepEmpty bool) ([]pullman.ObjectInfo, error) {
 	pager := d.client.ListBlobsFlat(&azblob.ContainerListBlobFlatSegmentOptions{
 		Prefix: &prefix,
 	})
@@ -85,16 +131,16 @@ func (d *azureImplDownloader) listObjects(ctx context.Context, prefix string) ([
 	if pager.Err() != nil {
 		return nil, pager.Err()
 	}
-	blobList := make([]string, 0, 10)
+	objects := make([]pullman.ObjectInfo, 0, 10)
 	for pager.NextPage(context.Background()) {
 		res := pager.PageResponse()
 		for _, blob := range res.Segment.BlobItems {
-			if !d.shouldIgnoreObject(blob, prefix) {
-				blobList = append(blobList, *blob.Name)
+			if !d.shouldIgnoreObject(blob, prefix, keepEmpty) {
+				objects = append(objects, pullman.ObjectInfo{Path: *blob.Name, Size: *blob.Properties.ContentLength, MD5: hex.EncodeToString(blob.Properties.ContentMD5)})
 			}
 		}
 	}
-	return blobList, nil
+	return objects, nil
 }
 
 func (d *azureImplDownloader) downloadBatch(ctx context.Context, targets []pullman.Target) error {
@@ -108,9 +154,7 @@ func (d *azureImplDownloader) downloadBatch(ctx context.Context, targets []pullm
 		d.log.V(1).Info("downloading blob", "path", target.RemotePath, "filename", target.LocalPath)
 		blobClient := d.client.NewBlobClient(target.RemotePath)
 
-		err := blobClient.DownloadBlobToFile(ctx, 0, 0, file, azblob.HighLevelDownloadFromBlobOptions{
-			Parallelism: maxDownloadConcurrency,
-		})
+		err := defaultRangedDownloader.download(ctx, azureBlobRangeReader{client: blobClient}, file)
 		if err != nil {
 			return fmt.Errorf("unable to download blob '%s' to local file '%s' for writing: %w", target.RemotePath, target.LocalPath, err)
 		}
@@ -118,8 +162,8 @@ func (d *azureImplDownloader) downloadBatch(ctx context.Context, targets []pullm
 	return nil
 }
 
-func (d *azureImplDownloader) shouldIgnoreObject(blob *azblob.BlobItemInternal, prefix string) bool {
-	if *blob.Properties.ContentLength == 0 {
+func (d *azureImplDownloader) shouldIgnoreObject(blob *azblob.BlobItemInternal, prefix string, keepEmpty bool) bool {
+	if *blob.Properties.ContentLength == 0 && !keepEmpty {
 		d.log.V(1).Info("ignore downloading azure object of 0 byte size", "azure_path", blob.Name)
 		return true
 	}
@@ -135,3 +179,34 @@ func (d *azureImplDownloader) shouldIgnoreObject(blob *azblob.BlobItemInternal,
 	}
 	return false
 }
+
+// header of the Azure responses that identifies the request
+const azureRequestIDHeader = "x-ms-request-id"
+
+// implemented by the errors of the Azure SDK that carry the HTTP response
+type rawResponseError interface {
+	RawResponse() *http.Response
+}
+
+// implemented by the azblob StorageError
+type storageResponseError interface {
+	Response() *http.Response
+}
+
+// requestIDFromError returns the id that Azure assigned to the failed request,
+// or the empty string
+func requestIDFromError(err error) string {
+	var resp *http.Response
+	var rawErr rawResponseError
+	var storageErr storageResponseError
+	if errors.As(err, &rawErr) {
+		resp = rawErr.RawResponse()
+	} else if errors.As(err, &storageErr) {
+		resp = storageErr.Response()
+	}
+
+	if resp == nil {
+		return ""
+	}
+	return resp.Header.Get(azureRequestIDHeader)
+}
@@ -51,48 +51,48 @@ func (m *MockazureDownloaderFactory) EXPECT() *MockazureDownloaderFactoryMockRec
 }
 
 // newDownloaderWithConnectionString mocks base method.
-func (m *MockazureDownloaderFactory) newDownloaderWithConnectionString(log logr.Logger, containerName, connectionString string) (azureDownloader, error) {
+func (m *MockazureDownloaderFactory) newDownloaderWithConnectionString(log logr.Logger, containerName, connectionString string, timeouts pullman.Timeouts, userAgent string) (azureDownloader, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "newDownloaderWithConnectionString", log, containerName, connectionString)
+	ret := m.ctrl.Call(m, "newDownloaderWithConnectionString", log, containerName, connectionString, timeouts, userAgent)
 	ret0, _ := ret[0].(azureDownloader)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // newDownloaderWithConnectionString indicates an expected call of newDownloaderWithConnectionString.
-func (mr *MockazureDownloaderFactoryMockRecorder) newDownloaderWithConnectionString(log, containerName, connectionString interface{}) *gomock.Call {
+func (mr *MockazureDownloaderFactoryMockRecorder) newDownloaderWithConnectionString(log, containerName, connectionString, timeouts, userAgent interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "newDownloaderWithConnectionString", reflect.TypeOf((*MockazureDownloaderFactory)(nil).newDownloaderWithConnectionString), log, containerName, connectionString)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "newDownloaderWithConnectionString", reflect.TypeOf((*MockazureDownloaderFactory)(nil).newDownloaderWithConnectionString), log, containerName, connectionString, timeouts, userAgent)
 }
 
 // newDownloaderWithNoCredential mocks base method.
-func (m *MockazureDownloaderFactory) newDownloaderWithNoCredential(log logr.Logger, containerUrl string) (azureDownloader, error) {
+func (m *MockazureDownloaderFactory) newDownloaderWithNoCredential(log logr.Logger, containerUrl string, timeouts pullman.Timeouts, userAgent string) (azureDownloader, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "newDownloaderWithNoCredential", log, containerUrl)
+	ret := m.ctrl.Call(m, "newDownloaderWithNoCredential", log, containerUrl, timeouts, userAgent)
 	ret0, _ := ret[0].(azureDownloader)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // newDownloaderWithNoCredential indicates an expected call of newDownloaderWithNoCredential.
-func (mr *MockazureDownloaderFactoryMockRecorder) newDownloaderWithNoCredential(log, containerUrl interface{}) *gomock.Call {
+func (mr *MockazureDownloaderFactoryMockRecorder) newDownloaderWithNoCredential(log, containerUrl, timeouts, userAgent interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "newDownloaderWithNoCredential", reflect.TypeOf((*MockazureDownloaderFactory)(nil).newDownloaderWithNoCredential), log, containerUrl)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "newDownloaderWithNoCredential", reflect.TypeOf((*MockazureDownloaderFactory)(nil).newDownloaderWithNoCredential), log, containerUrl, timeouts, userAgent)
 }
 
 // newDownloaderWithServicePrincipal mocks base method.
-func (m *MockazureDownloaderFactory) newDownloaderWithServicePrincipal(log logr.Logger, containerUrl string, credentials servicePrincipalCredentials) (azureDownloader, error) {
+func (m *MockazureDownloaderFactory) newDownloaderWithServicePrincipal(log logr.Logger, containerUrl string, credentials servicePrincipalCredentials, timeouts pullman.Timeouts, userAgent string) (azureDownloader, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "newDownloaderWithServicePrincipal", log, containerUrl, credentials)
+	ret := m.ctrl.Call(m, "newDownloaderWithServicePrincipal", log, containerUrl, credentials, timeouts, userAgent)
 	ret0, _ := ret[0].(azureDownloader)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // newDownloaderWithServicePrincipal indicates an expected call of newDownloaderWithServicePrincipal.
-func (mr *MockazureDownloaderFactoryMockRecorder) newDownloaderWithServicePrincipal(log, containerUrl, credentials interface{}) *gomock.Call {
+func (mr *MockazureDownloaderFactoryMockRecorder) newDownloaderWithServicePrincipal(log, containerUrl, credentials, timeouts, userAgent interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "newDownloaderWithServicePrincipal", reflect.TypeOf((*MockazureDownloaderFactory)(nil).newDownloaderWithServicePrincipal), log, containerUrl, credentials)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "newDownloaderWithServicePrincipal", reflect.TypeOf((*MockazureDownloaderFactory)(nil).newDownloaderWithServicePrincipal), log, containerUrl, credentials, timeouts, userAgent)
 }
 
 // MockazureDownloader is a mock of azureDownloader interface.
@@ -132,17 +132,32 @@ func (mr *MockazureDownloaderMockRecorder) downloadBatch(ctx, targets interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "downloadBatch", reflect.TypeOf((*MockazureDownloader)(nil).downloadBatch), ctx, targets)
 }
 
+// listObjectInfos mocks base method.
+func (m *MockazureDownloader) listObjectInfos(ctx context.Context, prefix string) ([]pullman.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "listObjectInfos", ctx, prefix)
+	ret0, _ := ret[0].([]pullman.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// listObjectInfos indicates an expected call of listObjectInfos.
+func (mr *MockazureDownloaderMockRecorder) listObjectInfos(ctx, prefix interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "listObjectInfos", reflect.TypeOf((*MockazureDownloader)(nil).listObjectInfos), ctx, prefix)
+}
+
 // listObjects mocks base method.
-func (m *MockazureDownloader) listObjects(ctx context.Context, prefix string) ([]string, error) {
+func (m *MockazureDownloader) listObjects(ctx context.Context, prefix string, keepEmpty bool) ([]pullman.ObjectInfo, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "listObjects", ctx, prefix)
-	ret0, _ := ret[0].([]string)
+	ret := m.ctrl.Call(m, "listObjects", ctx, prefix, keepEmpty)
+	ret0, _ := ret[0].([]pullman.ObjectInfo)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // listObjects indicates an expected call of listObjects.
-func (mr *MockazureDownloaderMockRecorder) listObjects(ctx, prefix interface{}) *gomock.Call {
+func (mr *MockazureDownloaderMockRecorder) listObjects(ctx, prefix, keepEmpty interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "listObjects", reflect.TypeOf((*MockazureDownloader)(nil).listObjects), ctx, prefix)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "listObjects", reflect.TypeOf((*MockazureDownloader)(nil).listObjects), ctx, prefix, keepEmpty)
 }
@@ -46,15 +46,19 @@ const (
 // azureDownloaderFactory is the interface used create Azure Blob Storage downloaders
 // useful to mock for testing
 type azureDownloaderFactory interface {
-	newDownloaderWithNoCredential(log logr.Logger, containerUrl string) (azureDownloader, error)
-	newDownloaderWithConnectionString(log logr.Logger, containerName string, connectionString string) (azureDownloader, error)
-	newDownloaderWithServicePrincipal(log logr.Logger, containerUrl string, credentials servicePrincipalCredentials) (azureDownloader, error)
+	newDownloaderWithNoCredential(log logr.Logger, containerUrl string, timeouts pullman.Timeouts, userAgent string) (azureDownloader, error)
+	newDownloaderWithConnectionString(log logr.Logger, containerName string, connectionString string, timeouts pullman.Timeouts, userAgent string) (azureDownloader, error)
+	newDownloaderWithServicePrincipal(log logr.Logger, containerUrl string, credentials servicePrincipalCredentials, timeouts pullman.Timeouts, userAgent string) (azureDownloader, error)
 }
 
 // azureDownloader is the interface used to download resources from Azure Blob Storage
 // useful to mock for testing
 type azureDownloader interface {
-	listObjects(ctx context.Context, prefix string) ([]string, error)
+	// listObjects returns the blobs to download under the prefix, including
+	// empty blobs if keepEmpty is set
+	listObjects(ctx context.Context, prefix string, keepEmpty bool) ([]pullman.ObjectInfo, error)
+	// listObjectInfos is listObjects without empty blobs
+	listObjectInfos(ctx context.Context, prefix string) ([]pullman.ObjectInfo, error)
 	downloadBatch(ctx context.Context, targets []pullman.Target) error
 }
 
@@ -74,8 +78,9 @@ func (p azureProvider) GetKey(config pullman.Config) string {
 	connectionString, _ := pullman.GetString(config, configConnectionString)
 	accountName, _ := pullman.GetString(config, configAccountName)
 	container, _ := pullman.GetString(config, configContainer)
+	timeouts, _ := pullman.GetTimeouts(config)
 
-	return pullman.HashStrings(clientId, clientSecret, tenantId, connectionString, accountName, container)
+	return pullman.HashStrings(clientId, clientSecret, tenantId, connectionString, accountName, container, timeouts.String(), pullman.GetUserAgent(config))
 }
 
 func (p azureProvider) NewRepository(config pullman.Config, log logr.Logger) (pullman.RepositoryClient, error) {
@@ -91,12 +96,17 @@ func (p azureProvider) NewRepository(config pullman.Config, log logr.Logger) (pu
 		return nil, errors.New("both the Azure account name and storage container name must be specified")
 	}
 
-	var err error
+	timeouts, err := pullman.GetTimeouts(config)
+	if err != nil {
+		return nil, err
+	}
+	userAgent := pullman.GetUserAgent(config)
+
 	var azclient azureDownloader
 	containerUrl := "https://" + accountName + azureBlobStorageURL + container
 	if connectionString != "" {
 		// If connection string is provided, use that.
-		azclient, err = p.azureDownloaderFactory.newDownloaderWithConnectionString(log, container, connectionString)
+		azclient, err = p.azureDownloaderFactory.newDownloaderWithConnectionString(log, container, connectionString, timeouts, userAgent)
 	} else if clientId != "" && clientSecret != "" && tenantId != "" {
 		// If service principal credentials were provided, use that.
 		spc := servicePrincipalCredentials{
@@ -104,10 +114,10 @@ func (p azureProvider) NewRepository(config pullman.Config, log logr.Logger) (pu
 			clientSecret: clientSecret,
 			tenantId:     tenantId,
 		}
-		azclient, err = p.azureDownloaderFactory.newDownloaderWithServicePrincipal(log, containerUrl, spc)
+		azclient, err = p.azureDownloaderFactory.newDownloaderWithServicePrincipal(log, containerUrl, spc, timeouts, userAgent)
 	} else {
 		// Otherwise use no authentication.
-		azclient, err = p.azureDownloaderFactory.newDownloaderWithNoCredential(log, containerUrl)
+		azclient, err = p.azureDownloaderFactory.newDownloaderWithNoCredential(log, containerUrl, timeouts, userAgent)
 	}
 
 	if err != nil {
@@ -130,6 +140,9 @@ type azureRepositoryClient struct {
 	log      logr.Logger
 }
 
+// azureRepositoryClient implements RepositoryLister
+var _ pullman.RepositoryLister = (*azureRepositoryClient)(nil)
+
 func (r *azureRepositoryClient) Pull(ctx context.Context, pc pullman.PullCommand) error {
 	destDir := pc.Directory
 	targets := pc.Targets
@@ -143,15 +156,18 @@ func (r *azureRepositoryClient) Pull(ctx context.Context, pc pullman.PullCommand
 	// Resolve full paths of objects to download and local paths for the resulting files
 	// Mainly, this means resolving the objects referenced by a "directory" in Azure.
 	resolvedTargets := make([]pullman.Target, 0, len(targets))
+	// keys of the downloaded files to add to the artifact cache
+	cacheKeys := map[string]string{}
 	for _, pt := range targets {
-		objPaths, err := r.azclient.listObjects(ctx, pt.RemotePath)
+		objects, err := r.azclient.listObjects(ctx, pt.RemotePath, pc.KeepEmptyFiles)
 
 		if err != nil {
-			return fmt.Errorf("unable to list objects in container '%s': %w", container, err)
+			return pullman.WithRequestID(fmt.Errorf("unable to list objects in container '%s': %w", container, err), requestIDFromError(err))
 		}
-		r.log.V(1).Info("found objects to download", "path", pt.RemotePath, "count", len(objPaths))
+		r.log.V(1).Info("found objects to download", "path", pt.RemotePath, "count", len(objects))
 
-		for _, objPath := range objPaths {
+		for _, object := range objects {
+			objPath := object.Path
 			localPath := pt.LocalPath
 			relativePath := strings.TrimPrefix(objPath, pt.RemotePath)
 
@@ -171,22 +187,58 @@ func (r *azureRepositoryClient) Pull(ctx context.Context, pc pullman.PullCommand
 				return fmt.Errorf("error joining filepaths '%s' and '%s': %w", pt.LocalPath, relativePath, joinErr)
 			}
 
+			// small blobs shared between models are only downloaded once,
+			// identified by their Content-MD5, which is not set for every blob
+			var cacheKey string
+			if object.MD5 != "" && pc.ArtifactCache.Cacheable(object.Size) {
+				cacheKey = pullman.ArtifactKey("md5", object.MD5, object.Size)
+				linked, linkErr := pc.ArtifactCache.Link(cacheKey, filePath)
+				if linkErr != nil {
+					r.log.Info("unable to use cached artifact, downloading it", "path", objPath, "error", linkErr.Error())
+				} else if linked {
+					r.log.V(1).Info("using cached artifact", "path", objPath, "filename", filePath)
+					continue
+				}
+			}
+
 			t := pullman.Target{
 				RemotePath: objPath,
 				LocalPath:  filePath,
 			}
 			resolvedTargets = append(resolvedTargets, t)
+			if cacheKey != "" {
+				cacheKeys[filePath] = cacheKey
+			}
 		}
 	}
 
 	if err := r.azclient.downloadBatch(ctx, resolvedTargets); err != nil {
-		return fmt.Errorf("unable to download objects in container '%s': %w", container, err)
+		return pullman.WithRequestID(fmt.Errorf("unable to download objects in container '%s': %w", container, err), requestIDFromError(err))
+	}
+
+	for filePath, cacheKey := range cacheKeys {
+		if err := pc.ArtifactCache.Store(cacheKey, filePath); err != nil {
+			r.log.Info("unable to cache artifact", "filename", filePath, "error", err.Error())
+		}
 	}
 
 	return nil
 
 }
 
+func (r *azureRepositoryClient) List(ctx context.Context, lc pullman.ListCommand) ([]pullman.ObjectInfo, error) {
+	container, ok := pullman.GetString(lc.RepositoryConfig, configContainer)
+	if !ok {
+		return nil, fmt.Errorf("required configuration '%s' missing from command", configContainer)
+	}
+
+	objects, err := r.azclient.listObjectInfos(ctx, lc.Prefix)
+	if err != nil {
+		return nil, pullman.WithRequestID(fmt.Errorf("unable to list objects in container '%s': %w", container, err), requestIDFromError(err))
+	}
+	return objects, nil
+}
+
 func init() {
 	p := azureProvider{
 		azureDownloaderFactory: azureClientFactory{},
@@ -16,9 +16,16 @@ package azureprovider
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/go-logr/logr"
 	gomock "github.com/golang/mock/gomock"
 	"github.com/kserve/modelmesh-runtime-adapter/pullman"
@@ -67,7 +74,7 @@ func Test_NewRepositoryWithServicePrincipalCredentials(t *testing.T) {
 
 	// Test that credentials are passed to newDownloader if specified.
 	mdf.EXPECT().newDownloaderWithServicePrincipal(gomock.Any(), gomock.Any(), gomock.Eq(servicePrincipalCredentials{
-		clientId: clientId, clientSecret: clientSecret, tenantId: tenantId})).Times(1)
+		clientId: clientId, clientSecret: clientSecret, tenantId: tenantId}), gomock.Eq(pullman.Timeouts{}), gomock.Any()).Times(1)
 
 	_, err := g.NewRepository(c, log)
 	assert.NoError(t, err)
@@ -82,7 +89,7 @@ func Test_NewRepositoryWithConnectionStringCredentials(t *testing.T) {
 	c.Set(configAccountName, accountName)
 	c.Set("connection_string", connectionString)
 
-	mdf.EXPECT().newDownloaderWithConnectionString(gomock.Any(), containerName, connectionString).Times(1)
+	mdf.EXPECT().newDownloaderWithConnectionString(gomock.Any(), containerName, connectionString, gomock.Eq(pullman.Timeouts{}), gomock.Any()).Times(1)
 
 	_, err := g.NewRepository(c, log)
 	assert.NoError(t, err)
@@ -95,12 +102,38 @@ func Test_NewRepositoryWithNoCredentials(t *testing.T) {
 	c.Set(configContainer, containerName)
 	c.Set(configAccountName, accountName)
 
-	mdf.EXPECT().newDownloaderWithNoCredential(gomock.Any(), gomock.Any()).Times(1)
+	mdf.EXPECT().newDownloaderWithNoCredential(gomock.Any(), gomock.Any(), gomock.Eq(pullman.Timeouts{}), gomock.Any()).Times(1)
 
 	_, err := g.NewRepository(c, log)
 	assert.NoError(t, err)
 }
 
+func Test_NewRepositoryWithTimeouts(t *testing.T) {
+	g, mdf, log := newAzureProviderWithMocks(t)
+	c := pullman.NewRepositoryConfig("azure", nil)
+
+	c.Set(configContainer, containerName)
+	c.Set(configAccountName, accountName)
+	c.Set(pullman.ConfigConnectTimeout, "500ms")
+	c.Set(pullman.ConfigRequestTimeout, float64(30))
+
+	mdf.EXPECT().newDownloaderWithNoCredential(gomock.Any(), gomock.Any(),
+		gomock.Eq(pullman.Timeouts{Connect: 500 * time.Millisecond, Request: 30 * time.Second}), gomock.Any()).Times(1)
+
+	_, err := g.NewRepository(c, log)
+	assert.NoError(t, err)
+}
+
+// objectsWithPaths returns blobs without an MD5 checksum, which are not
+// cached
+func objectsWithPaths(paths ...string) []pullman.ObjectInfo {
+	objects := make([]pullman.ObjectInfo, 0, len(paths))
+	for _, path := range paths {
+		objects = append(objects, pullman.ObjectInfo{Path: path, Size: 1})
+	}
+	return objects
+}
+
 func Test_Download_SimpleDirectory(t *testing.T) {
 	azureRc, mdf := newAzureRepositoryClientWithMock(t)
 	c := pullman.NewRepositoryConfig("azure", nil)
@@ -117,8 +150,8 @@ func Test_Download_SimpleDirectory(t *testing.T) {
 		},
 	}
 
-	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("path/to/modeldir")).
-		Return([]string{"path/to/modeldir/file.ext", "path/to/modeldir/subdir/another_file"}, nil).
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("path/to/modeldir"), gomock.Any()).
+		Return(objectsWithPaths("path/to/modeldir/file.ext", "path/to/modeldir/subdir/another_file"), nil).
 		Times(1)
 
 	expectedTargets := []pullman.Target{
@@ -176,20 +209,20 @@ func Test_Download_MultipleTargets(t *testing.T) {
 		},
 	}
 
-	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("dir")).
-		Return([]string{"dir/file1", "dir/file2"}, nil).
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("dir"), gomock.Any()).
+		Return(objectsWithPaths("dir/file1", "dir/file2"), nil).
 		Times(1)
-	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("some_file")).
-		Return([]string{"some_file"}, nil).
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("some_file"), gomock.Any()).
+		Return(objectsWithPaths("some_file"), nil).
 		Times(1)
-	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("another_dir")).
-		Return([]string{"another_dir/another_file", "another_dir/subdir1/subdir2/nested_file"}, nil).
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("another_dir"), gomock.Any()).
+		Return(objectsWithPaths("another_dir/another_file", "another_dir/subdir1/subdir2/nested_file"), nil).
 		Times(1)
-	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("another_file")).
-		Return([]string{"another_file"}, nil).
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("another_file"), gomock.Any()).
+		Return(objectsWithPaths("another_file"), nil).
 		Times(1)
-	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("yet_another_file")).
-		Return([]string{"yet_another_file"}, nil).
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("yet_another_file"), gomock.Any()).
+		Return(objectsWithPaths("yet_another_file"), nil).
 		Times(1)
 
 	expectedTargets := []pullman.Target{
@@ -230,6 +263,66 @@ func Test_Download_MultipleTargets(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func Test_Download_ArtifactCache(t *testing.T) {
+	azureRc, mdf := newAzureRepositoryClientWithMock(t)
+
+	c := pullman.NewRepositoryConfig("azure", nil)
+	c.Set("container", containerName)
+
+	cache, err := pullman.NewArtifactCache(filepath.Join(t.TempDir(), "cache"), 1024, 256)
+	assert.NoError(t, err)
+
+	// two models reference the same small blob at different paths, next to
+	// their own model file
+	rootDir := t.TempDir()
+	sharedDownloads := 0
+	for _, model := range []string{"model-a", "model-b"} {
+		shared := pullman.ObjectInfo{Path: model + "-shared/labels.txt", Size: 5, MD5: "5d41402abc4b2a76b9719d911017c592"}
+		modelDir := filepath.Join(rootDir, model)
+		mdf.EXPECT().listObjects(gomock.Any(), gomock.Eq(shared.Path), gomock.Any()).
+			Return([]pullman.ObjectInfo{shared}, nil).
+			Times(1)
+		mdf.EXPECT().listObjects(gomock.Any(), gomock.Eq(model), gomock.Any()).
+			Return(objectsWithPaths(model+"/model.onnx"), nil).
+			Times(1)
+		mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, targets []pullman.Target) error {
+				for _, target := range targets {
+					content := "large"
+					if target.RemotePath == shared.Path {
+						content = "hello"
+						sharedDownloads++
+					}
+					file, err := pullman.OpenFile(target.LocalPath)
+					if err != nil {
+						return err
+					}
+					file.WriteString(content)
+					file.Close()
+				}
+				return nil
+			}).
+			Times(1)
+
+		err = azureRc.Pull(context.Background(), pullman.PullCommand{
+			RepositoryConfig: c,
+			Directory:        modelDir,
+			Targets: []pullman.Target{
+				{RemotePath: shared.Path},
+				{RemotePath: model},
+			},
+			ArtifactCache: cache,
+		})
+		assert.NoError(t, err)
+
+		labels, readErr := os.ReadFile(filepath.Join(modelDir, "labels.txt"))
+		assert.NoError(t, readErr)
+		assert.Equal(t, "hello", string(labels))
+		assert.FileExists(t, filepath.Join(modelDir, "model.onnx"))
+	}
+	assert.Equal(t, 1, sharedDownloads, "expected the shared artifact to be downloaded once")
+}
+
 func Test_GetKey(t *testing.T) {
 	provider := azureProvider{}
 
@@ -266,3 +359,99 @@ func Test_GetKey(t *testing.T) {
 		assert.NotEqual(t, provider.GetKey(config1), provider.GetKey(config2))
 	})
 }
+
+// an error carrying the HTTP response, like the errors of the Azure SDK
+type responseError struct {
+	resp *http.Response
+}
+
+func (e responseError) Error() string {
+	return fmt.Sprintf("unexpected status code %d", e.resp.StatusCode)
+}
+
+func (e responseError) RawResponse() *http.Response {
+	return e.resp
+}
+
+func Test_Download_ErrorIncludesRequestID(t *testing.T) {
+	azureRc, mdf := newAzureRepositoryClientWithMock(t)
+	c := pullman.NewRepositoryConfig("azure", nil)
+	c.Set(configContainer, containerName)
+
+	inputPullCommand := pullman.PullCommand{
+		RepositoryConfig: c,
+		Directory:        filepath.Join("test", "output"),
+		Targets: []pullman.Target{
+			{
+				RemotePath: "path/to/model.zip",
+			},
+		},
+	}
+
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq("path/to/model.zip"), gomock.Any()).
+		Return(nil, responseError{resp: &http.Response{
+			StatusCode: http.StatusForbidden,
+			Header:     http.Header{"X-Ms-Request-Id": []string{"0d1f4b2e-601e-0045-0c3a-5c2c8a000000"}},
+		}}).
+		Times(1)
+
+	err := azureRc.Pull(context.Background(), inputPullCommand)
+	assert.ErrorContains(t, err, "request id: 0d1f4b2e-601e-0045-0c3a-5c2c8a000000")
+
+	var reqIDErr *pullman.RequestIDError
+	assert.ErrorAs(t, err, &reqIDErr)
+	assert.Equal(t, "0d1f4b2e-601e-0045-0c3a-5c2c8a000000", reqIDErr.RequestID)
+}
+
+func Test_List(t *testing.T) {
+	azureRc, mdf := newAzureRepositoryClientWithMock(t)
+	c := pullman.NewRepositoryConfig("azure", nil)
+	c.Set(configContainer, containerName)
+
+	expectedObjects := []pullman.ObjectInfo{
+		{Path: "models/mnist/model.onnx", Size: 1024},
+		{Path: "models/resnet/saved_model.pb", Size: 2048},
+	}
+	mdf.EXPECT().listObjectInfos(context.Background(), gomock.Eq("models/")).
+		Return(expectedObjects, nil).
+		Times(1)
+
+	objects, err := azureRc.List(context.Background(), pullman.ListCommand{RepositoryConfig: c, Prefix: "models/"})
+	assert.NoError(t, err)
+	assert.Equal(t, expectedObjects, objects)
+}
+
+func Test_List_ErrorIncludesRequestID(t *testing.T) {
+	azureRc, mdf := newAzureRepositoryClientWithMock(t)
+	c := pullman.NewRepositoryConfig("azure", nil)
+	c.Set(configContainer, containerName)
+
+	mdf.EXPECT().listObjectInfos(context.Background(), gomock.Eq("models/")).
+		Return(nil, responseError{resp: &http.Response{
+			StatusCode: http.StatusForbidden,
+			Header:     http.Header{"X-Ms-Request-Id": []string{"0d1f4b2e-601e-0045-0c3a-5c2c8a000000"}},
+		}}).
+		Times(1)
+
+	_, err := azureRc.List(context.Background(), pullman.ListCommand{RepositoryConfig: c, Prefix: "models/"})
+	assert.ErrorContains(t, err, "request id: 0d1f4b2e-601e-0045-0c3a-5c2c8a000000")
+}
+
+func Test_NewClientOptions_UserAgent(t *testing.T) {
+	userAgents := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents <- r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	// longer than the 24 characters of an application ID, with a space
+	userAgent := "modelmesh-puller/1.0 (model-server)"
+	client, err := azblob.NewContainerClientWithNoCredential(server.URL+"/"+containerName, newClientOptions(pullman.Timeouts{}, userAgent))
+	assert.NoError(t, err)
+	_, err = client.GetProperties(context.Background(), nil)
+	assert.NoError(t, err)
+
+	received := <-userAgents
+	assert.Contains(t, received, "azsdk-go-")
+	assert.True(t, strings.HasSuffix(received, " "+userAgent), "Expected the user agent %s to end with %s", received, userAgent)
+}
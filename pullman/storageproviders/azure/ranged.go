@@ -0,0 +1,145 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azureprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// blobs larger than the threshold are downloaded in concurrent ranged
+	// requests of the block size
+	rangedDownloadThreshold = 32 * 1024 * 1024
+	rangedDownloadBlockSize = 8 * 1024 * 1024
+	// retries of a ranged request that fails while reading the body
+	maxRetryRequestsPerBlock = 3
+)
+
+// blobRangeReader reads byte ranges of a single blob
+// useful to fake for testing
+type blobRangeReader interface {
+	getSize(ctx context.Context) (int64, error)
+	readRange(ctx context.Context, offset int64, count int64) (io.ReadCloser, error)
+}
+
+// azureBlobRangeReader implements blobRangeReader
+var _ blobRangeReader = azureBlobRangeReader{}
+
+type azureBlobRangeReader struct {
+	client azblob.BlobClient
+}
+
+func (r azureBlobRangeReader) getSize(ctx context.Context) (int64, error) {
+	props, err := r.client.GetProperties(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	if props.ContentLength == nil {
+		return 0, errors.New("blob properties do not include the Content-Length")
+	}
+	return *props.ContentLength, nil
+}
+
+func (r azureBlobRangeReader) readRange(ctx context.Context, offset int64, count int64) (io.ReadCloser, error) {
+	resp, err := r.client.Download(ctx, &azblob.DownloadBlobOptions{
+		Offset: &offset,
+		Count:  &count,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body(&azblob.RetryReaderOptions{MaxRetryRequests: maxRetryRequestsPerBlock}), nil
+}
+
+// rangedDownloader downloads a blob in blocks that are requested concurrently
+// and written in place, similar to the multipart download of S3
+type rangedDownloader struct {
+	threshold   int64
+	blockSize   int64
+	concurrency int
+}
+
+var defaultRangedDownloader = rangedDownloader{
+	threshold:   rangedDownloadThreshold,
+	blockSize:   rangedDownloadBlockSize,
+	concurrency: maxDownloadConcurrency,
+}
+
+// download writes the blob to w, verifying that each block has the length
+// that it was requested with so that the assembled blob has the blob's
+// Content-Length
+func (d rangedDownloader) download(ctx context.Context, r blobRangeReader, w io.WriterAt) error {
+	size, err := r.getSize(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get blob size: %w", err)
+	}
+	if size == 0 {
+		return nil
+	}
+
+	// small blobs are read in a single request
+	if size <= d.threshold || d.blockSize <= 0 {
+		return readBlock(ctx, r, w, 0, size)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(d.concurrency)
+	for offset := int64(0); offset < size; offset += d.blockSize {
+		offset := offset
+		count := d.blockSize
+		if offset+count > size {
+			count = size - offset
+		}
+		g.Go(func() error {
+			return readBlock(gctx, r, w, offset, count)
+		})
+	}
+	return g.Wait()
+}
+
+func readBlock(ctx context.Context, r blobRangeReader, w io.WriterAt, offset int64, count int64) error {
+	body, err := r.readRange(ctx, offset, count)
+	if err != nil {
+		return fmt.Errorf("unable to request blob range at offset %d: %w", offset, err)
+	}
+	defer body.Close()
+
+	n, err := io.Copy(&offsetWriter{w: w, offset: offset}, body)
+	if err != nil {
+		return fmt.Errorf("unable to read blob range at offset %d: %w", offset, err)
+	}
+	if n != count {
+		return fmt.Errorf("blob range at offset %d has %d bytes but %d were expected from the Content-Length", offset, n, count)
+	}
+	return nil
+}
+
+// offsetWriter writes sequentially to an io.WriterAt from the offset
+type offsetWriter struct {
+	w      io.WriterAt
+	offset int64
+}
+
+func (ow *offsetWriter) Write(p []byte) (int, error) {
+	n, err := ow.w.WriteAt(p, ow.offset)
+	ow.offset += int64(n)
+	return n, err
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azureprovider
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBlob serves ranges of its data like Azure Blob Storage
+type fakeBlob struct {
+	data []byte
+	// number of bytes cut from the end of each range, to fake a short read
+	truncate int64
+
+	mutex  sync.Mutex
+	ranges [][2]int64
+}
+
+func (b *fakeBlob) getSize(ctx context.Context) (int64, error) {
+	return int64(len(b.data)), nil
+}
+
+func (b *fakeBlob) readRange(ctx context.Context, offset int64, count int64) (io.ReadCloser, error) {
+	b.mutex.Lock()
+	b.ranges = append(b.ranges, [2]int64{offset, count})
+	b.mutex.Unlock()
+
+	return io.NopCloser(bytes.NewReader(b.data[offset : offset+count-b.truncate])), nil
+}
+
+func newFakeBlob(size int) *fakeBlob {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+	return &fakeBlob{data: data}
+}
+
+func downloadToFile(t *testing.T, d rangedDownloader, blob *fakeBlob) ([]byte, error) {
+	path := filepath.Join(t.TempDir(), "blob")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Unable to create file: %v", err)
+	}
+	defer file.Close()
+
+	if err = d.download(context.Background(), blob, file); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func Test_RangedDownload_Chunked(t *testing.T) {
+	blob := newFakeBlob(1000)
+	d := rangedDownloader{threshold: 100, blockSize: 64, concurrency: 4}
+
+	downloaded, err := downloadToFile(t, d, blob)
+	assert.NoError(t, err)
+	assert.Equal(t, blob.data, downloaded)
+	// 15 full blocks and the remaining 40 bytes
+	assert.Equal(t, 16, len(blob.ranges))
+	assert.Contains(t, blob.ranges, [2]int64{960, 40})
+}
+
+func Test_RangedDownload_BelowThreshold(t *testing.T) {
+	blob := newFakeBlob(100)
+	d := rangedDownloader{threshold: 100, blockSize: 64, concurrency: 4}
+
+	downloaded, err := downloadToFile(t, d, blob)
+	assert.NoError(t, err)
+	assert.Equal(t, blob.data, downloaded)
+	assert.Equal(t, [][2]int64{{0, 100}}, blob.ranges)
+}
+
+func Test_RangedDownload_ShortRange(t *testing.T) {
+	blob := newFakeBlob(1000)
+	blob.truncate = 1
+	d := rangedDownloader{threshold: 100, blockSize: 64, concurrency: 4}
+
+	_, err := downloadToFile(t, d, blob)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Content-Length")
+}
@@ -16,15 +16,19 @@ package gcsprovider
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"sync"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/go-logr/logr"
 	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
@@ -37,65 +41,93 @@ var _ gcsDownloaderFactory = (*gcsClientFactory)(nil)
 // gcsImplDownloader implements gcsDownloader
 var _ gcsDownloader = (*gcsImplDownloader)(nil)
 
-func (f gcsClientFactory) newDownloader(log logr.Logger, credentials map[string]string) (gcsDownloader, error) {
+func (f gcsClientFactory) newDownloader(log logr.Logger, credentials map[string]string, timeouts pullman.Timeouts, userAgent string) (gcsDownloader, error) {
 	ctx := context.Background()
-	var cl *storage.Client
-	var err error
+	var opts []option.ClientOption
 
 	if len(credentials) > 0 {
 		credJson, marshalErr := json.Marshal(credentials)
 		if marshalErr != nil {
 			return nil, fmt.Errorf("unable to marshal json credentials: %w", marshalErr)
 		}
-		cl, err = storage.NewClient(ctx, option.WithCredentialsJSON(credJson))
+		// the SDK builds its own authenticated transport, so the connect timeout
+		// cannot be applied here and only the request timeout is honored
+		opts = append(opts, option.WithCredentialsJSON(credJson))
+	} else if timeouts.Connect > 0 {
+		// without authentication a custom HTTP client can be used, the SDK
+		// does not set the user agent on it so its transport does
+		httpClient := pullman.NewHTTPClient(pullman.Timeouts{Connect: timeouts.Connect})
+		httpClient.Transport = pullman.NewUserAgentTransport(httpClient.Transport, userAgent)
+		opts = append(opts, option.WithoutAuthentication(), option.WithHTTPClient(httpClient))
 	} else {
-		cl, err = storage.NewClient(ctx, option.WithoutAuthentication())
+		opts = append(opts, option.WithoutAuthentication())
+	}
+	if userAgent != "" {
+		opts = append(opts, option.WithUserAgent(userAgent))
 	}
 
+	cl, err := storage.NewClient(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &gcsImplDownloader{
-		client: cl,
-		log:    log,
+		client:         cl,
+		log:            log,
+		requestTimeout: timeouts.Request,
 	}, nil
 }
 
 type gcsImplDownloader struct {
 	client *storage.Client
 	log    logr.Logger
+	// applied to each request as a context deadline, if set
+	requestTimeout time.Duration
 
 	wg  sync.WaitGroup
 	mu  sync.Mutex
 	err error
 }
 
-func (d *gcsImplDownloader) listObjects(ctx context.Context, bucket string, prefix string) ([]string, error) {
+func (d *gcsImplDownloader) listObjects(ctx context.Context, bucket string, prefix string, keepEmpty bool) ([]pullman.ObjectInfo, error) {
+	return d.listObjectAttrs(ctx, bucket, prefix, keepEmpty)
+}
+
+func (d *gcsImplDownloader) listObjectInfos(ctx context.Context, bucket string, prefix string) ([]pullman.ObjectInfo, error) {
+	return d.listObjectAttrs(ctx, bucket, prefix, false)
+}
+
+func (d *gcsImplDownloader) listObjectAttrs(ctx context.Context, bucket string, prefix string, keepEmpty bool) ([]pullman.ObjectInfo, error) {
+	ctx, cancel := d.withRequestTimeout(ctx)
+	defer cancel()
+
 	query := &storage.Query{Prefix: prefix}
 	it := d.client.Bucket(bucket).Objects(ctx, query)
 
-	objectPaths := make([]string, 0, 10)
+	objects := make([]pullman.ObjectInfo, 0, 10)
 
 	for {
 		obj, err := it.Next()
 		if err == iterator.Done {
-			return objectPaths, nil
+			return objects, nil
 		}
 		if err != nil {
-			return nil, fmt.Errorf("GCS listObjects: unable to list bucket %q: %v", bucket, err)
+			return nil, fmt.Errorf("GCS listObjects: unable to list bucket %q: %w", bucket, err)
 		}
 
-		if !d.shouldIgnoreObject(obj, prefix) {
-			objectPaths = append(objectPaths, obj.Name)
+		if !d.shouldIgnoreObject(obj, prefix, keepEmpty) {
+			objects = append(objects, pullman.ObjectInfo{Path: obj.Name, Size: obj.Size, MD5: hex.EncodeToString(obj.MD5)})
 		}
 	}
 }
 
-func (d *gcsImplDownloader) downloadBatch(ctx context.Context, bucket string, targets []pullman.Target) error {
+func (d *gcsImplDownloader) downloadBatch(ctx context.Context, bucket string, targets []pullman.Target, concurrency int) error {
 	ch := make(chan pullman.Target)
 
 	workerCount := maxDownloadConcurrency
+	if concurrency > 0 {
+		workerCount = concurrency
+	}
 	if len(targets) < workerCount {
 		workerCount = len(targets)
 	}
@@ -138,19 +170,28 @@ func (d *gcsImplDownloader) downloadFilesFromChannel(ctx context.Context, bucket
 		}
 		defer file.Close()
 		d.log.V(1).Info("downloading object", "path", target.RemotePath, "filename", target.LocalPath)
-		reader, err := d.client.Bucket(bucket).Object(target.RemotePath).NewReader(ctx)
+		reqCtx, cancel := d.withRequestTimeout(ctx)
+		defer cancel()
+		reader, err := d.client.Bucket(bucket).Object(target.RemotePath).NewReader(reqCtx)
 		if err != nil {
-			return fmt.Errorf("failed to create reader for object(%s) in bucket(%s): %v", target.RemotePath, bucket, err)
+			return fmt.Errorf("failed to create reader for object(%s) in bucket(%s): %w", target.RemotePath, bucket, err)
 		}
 		defer reader.Close()
 		if _, err = io.Copy(file, reader); err != nil {
-			return fmt.Errorf("failed to write data to file(%s): from object(%s) in bucket(%s): %v",
+			return fmt.Errorf("failed to write data to file(%s): from object(%s) in bucket(%s): %w",
 				file.Name(), target.RemotePath, bucket, err)
 		}
 	}
 	return nil
 }
 
+func (d *gcsImplDownloader) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.requestTimeout > 0 {
+		return context.WithTimeout(ctx, d.requestTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
 func (d *gcsImplDownloader) getError() error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -163,8 +204,8 @@ func (d *gcsImplDownloader) setError(err error) {
 	d.err = err
 }
 
-func (d *gcsImplDownloader) shouldIgnoreObject(object *storage.ObjectAttrs, prefix string) bool {
-	if object.Size == 0 {
+func (d *gcsImplDownloader) shouldIgnoreObject(object *storage.ObjectAttrs, prefix string, keepEmpty bool) bool {
+	if object.Size == 0 && !keepEmpty {
 		d.log.V(1).Info("ignore downloading gcs object of 0 byte size", "gcs_path", object.Name)
 		return true
 	}
@@ -180,3 +221,16 @@ func (d *gcsImplDownloader) shouldIgnoreObject(object *storage.ObjectAttrs, pref
 	}
 	return false
 }
+
+// header of the GCS responses that identifies the request
+const gcsRequestIDHeader = "X-GUploader-UploadID"
+
+// requestIDFromError returns the id that GCS assigned to the failed request,
+// or the empty string
+func requestIDFromError(err error) string {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Header != nil {
+		return apiErr.Header.Get(gcsRequestIDHeader)
+	}
+	return ""
+}
@@ -51,18 +51,18 @@ func (m *MockgcsDownloaderFactory) EXPECT() *MockgcsDownloaderFactoryMockRecorde
 }
 
 // newDownloader mocks base method.
-func (m *MockgcsDownloaderFactory) newDownloader(log logr.Logger, credentials map[string]string) (gcsDownloader, error) {
+func (m *MockgcsDownloaderFactory) newDownloader(log logr.Logger, credentials map[string]string, timeouts pullman.Timeouts, userAgent string) (gcsDownloader, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "newDownloader", log, credentials)
+	ret := m.ctrl.Call(m, "newDownloader", log, credentials, timeouts, userAgent)
 	ret0, _ := ret[0].(gcsDownloader)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // newDownloader indicates an expected call of newDownloader.
-func (mr *MockgcsDownloaderFactoryMockRecorder) newDownloader(log, credentials interface{}) *gomock.Call {
+func (mr *MockgcsDownloaderFactoryMockRecorder) newDownloader(log, credentials, timeouts, userAgent interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "newDownloader", reflect.TypeOf((*MockgcsDownloaderFactory)(nil).newDownloader), log, credentials)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "newDownloader", reflect.TypeOf((*MockgcsDownloaderFactory)(nil).newDownloader), log, credentials, timeouts, userAgent)
 }
 
 // MockgcsDownloader is a mock of gcsDownloader interface.
@@ -89,30 +89,45 @@ func (m *MockgcsDownloader) EXPECT() *MockgcsDownloaderMockRecorder {
 }
 
 // downloadBatch mocks base method.
-func (m *MockgcsDownloader) downloadBatch(ctx context.Context, bucket string, targets []pullman.Target) error {
+func (m *MockgcsDownloader) downloadBatch(ctx context.Context, bucket string, targets []pullman.Target, concurrency int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "downloadBatch", ctx, bucket, targets)
+	ret := m.ctrl.Call(m, "downloadBatch", ctx, bucket, targets, concurrency)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // downloadBatch indicates an expected call of downloadBatch.
-func (mr *MockgcsDownloaderMockRecorder) downloadBatch(ctx, bucket, targets interface{}) *gomock.Call {
+func (mr *MockgcsDownloaderMockRecorder) downloadBatch(ctx, bucket, targets, concurrency interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "downloadBatch", reflect.TypeOf((*MockgcsDownloader)(nil).downloadBatch), ctx, bucket, targets)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "downloadBatch", reflect.TypeOf((*MockgcsDownloader)(nil).downloadBatch), ctx, bucket, targets, concurrency)
+}
+
+// listObjectInfos mocks base method.
+func (m *MockgcsDownloader) listObjectInfos(ctx context.Context, bucket, prefix string) ([]pullman.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "listObjectInfos", ctx, bucket, prefix)
+	ret0, _ := ret[0].([]pullman.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// listObjectInfos indicates an expected call of listObjectInfos.
+func (mr *MockgcsDownloaderMockRecorder) listObjectInfos(ctx, bucket, prefix interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "listObjectInfos", reflect.TypeOf((*MockgcsDownloader)(nil).listObjectInfos), ctx, bucket, prefix)
 }
 
 // listObjects mocks base method.
-func (m *MockgcsDownloader) listObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+func (m *MockgcsDownloader) listObjects(ctx context.Context, bucket, prefix string, keepEmpty bool) ([]pullman.ObjectInfo, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "listObjects", ctx, bucket, prefix)
-	ret0, _ := ret[0].([]string)
+	ret := m.ctrl.Call(m, "listObjects", ctx, bucket, prefix, keepEmpty)
+	ret0, _ := ret[0].([]pullman.ObjectInfo)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // listObjects indicates an expected call of listObjects.
-func (mr *MockgcsDownloaderMockRecorder) listObjects(ctx, bucket, prefix interface{}) *gomock.Call {
+func (mr *MockgcsDownloaderMockRecorder) listObjects(ctx, bucket, prefix, keepEmpty interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "listObjects", reflect.TypeOf((*MockgcsDownloader)(nil).listObjects), ctx, bucket, prefix)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "listObjects", reflect.TypeOf((*MockgcsDownloader)(nil).listObjects), ctx, bucket, prefix, keepEmpty)
 }
@@ -41,14 +41,19 @@ const (
 // gcsDownloaderFactory is the interface used create GCS downloaders
 // useful to mock for testing
 type gcsDownloaderFactory interface {
-	newDownloader(log logr.Logger, credentials map[string]string) (gcsDownloader, error)
+	newDownloader(log logr.Logger, credentials map[string]string, timeouts pullman.Timeouts, userAgent string) (gcsDownloader, error)
 }
 
 // gcsDownloader is the interface used to download resources from GCS
 // useful to mock for testing
 type gcsDownloader interface {
-	listObjects(ctx context.Context, bucket string, prefix string) ([]string, error)
-	downloadBatch(ctx context.Context, bucket string, targets []pullman.Target) error
+	// listObjects returns the objects to download under the prefix,
+	// including empty objects if keepEmpty is set
+	listObjects(ctx context.Context, bucket string, prefix string, keepEmpty bool) ([]pullman.ObjectInfo, error)
+	// listObjectInfos is listObjects without empty objects
+	listObjectInfos(ctx context.Context, bucket string, prefix string) ([]pullman.ObjectInfo, error)
+	// a concurrency that is not positive uses maxDownloadConcurrency
+	downloadBatch(ctx context.Context, bucket string, targets []pullman.Target, concurrency int) error
 }
 
 type gcsProvider struct {
@@ -64,8 +69,9 @@ func (p gcsProvider) GetKey(config pullman.Config) string {
 	privateKey, _ := pullman.GetString(config, configPrivateKey)
 	clientEmail, _ := pullman.GetString(config, configClientEmail)
 	tokenUri, _ := pullman.GetString(config, configTokenUri)
+	timeouts, _ := pullman.GetTimeouts(config)
 
-	return pullman.HashStrings(privateKey, clientEmail, tokenUri)
+	return pullman.HashStrings(privateKey, clientEmail, tokenUri, timeouts.String(), pullman.GetUserAgent(config))
 }
 
 func (p gcsProvider) NewRepository(config pullman.Config, log logr.Logger) (pullman.RepositoryClient, error) {
@@ -93,7 +99,12 @@ func (p gcsProvider) NewRepository(config pullman.Config, log logr.Logger) (pull
 		}
 	}
 
-	cl, err := p.gcsDownloaderFactory.newDownloader(log, creds)
+	timeouts, err := pullman.GetTimeouts(config)
+	if err != nil {
+		return nil, err
+	}
+
+	cl, err := p.gcsDownloaderFactory.newDownloader(log, creds, timeouts, pullman.GetUserAgent(config))
 	if err != nil {
 		return nil, err
 	}
@@ -108,8 +119,9 @@ type gcsRepositoryClient struct {
 	log       logr.Logger
 }
 
-// gcsRepository implements RepositoryClient
+// gcsRepository implements RepositoryClient and RepositoryLister
 var _ pullman.RepositoryClient = (*gcsRepositoryClient)(nil)
+var _ pullman.RepositoryLister = (*gcsRepositoryClient)(nil)
 
 func (r *gcsRepositoryClient) Pull(ctx context.Context, pc pullman.PullCommand) error {
 	destDir := pc.Directory
@@ -124,15 +136,18 @@ func (r *gcsRepositoryClient) Pull(ctx context.Context, pc pullman.PullCommand)
 	// Resolve full paths of objects to download and local paths for the resulting files
 	// Mainly, this means resolving the objects referenced by a "directory" in GCS
 	resolvedTargets := make([]pullman.Target, 0, len(targets))
+	// keys of the downloaded files to add to the artifact cache
+	cacheKeys := map[string]string{}
 	for _, pt := range targets {
-		objPaths, err := r.gcsclient.listObjects(ctx, bucket, pt.RemotePath)
+		objects, err := r.gcsclient.listObjects(ctx, bucket, pt.RemotePath, pc.KeepEmptyFiles)
 
 		if err != nil {
-			return fmt.Errorf("unable to list objects in bucket '%s': %w", bucket, err)
+			return pullman.WithRequestID(fmt.Errorf("unable to list objects in bucket '%s': %w", bucket, err), requestIDFromError(err))
 		}
-		r.log.V(1).Info("found objects to download", "path", pt.RemotePath, "count", len(objPaths))
+		r.log.V(1).Info("found objects to download", "path", pt.RemotePath, "count", len(objects))
 
-		for _, objPath := range objPaths {
+		for _, object := range objects {
+			objPath := object.Path
 			localPath := pt.LocalPath
 			relativePath := strings.TrimPrefix(objPath, pt.RemotePath)
 
@@ -152,22 +167,58 @@ func (r *gcsRepositoryClient) Pull(ctx context.Context, pc pullman.PullCommand)
 				return fmt.Errorf("error joining filepaths '%s' and '%s': %w", pt.LocalPath, relativePath, joinErr)
 			}
 
+			// small objects shared between models are only downloaded once,
+			// identified by their MD5 checksum, which composite objects lack
+			var cacheKey string
+			if object.MD5 != "" && pc.ArtifactCache.Cacheable(object.Size) {
+				cacheKey = pullman.ArtifactKey("md5", object.MD5, object.Size)
+				linked, linkErr := pc.ArtifactCache.Link(cacheKey, filePath)
+				if linkErr != nil {
+					r.log.Info("unable to use cached artifact, downloading it", "path", objPath, "error", linkErr.Error())
+				} else if linked {
+					r.log.V(1).Info("using cached artifact", "path", objPath, "filename", filePath)
+					continue
+				}
+			}
+
 			t := pullman.Target{
 				RemotePath: objPath,
 				LocalPath:  filePath,
 			}
 			resolvedTargets = append(resolvedTargets, t)
+			if cacheKey != "" {
+				cacheKeys[filePath] = cacheKey
+			}
 		}
 	}
 
-	if err := r.gcsclient.downloadBatch(ctx, bucket, resolvedTargets); err != nil {
-		return fmt.Errorf("unable to download objects in bucket '%s': %w", bucket, err)
+	if err := r.gcsclient.downloadBatch(ctx, bucket, resolvedTargets, pc.Concurrency); err != nil {
+		return pullman.WithRequestID(fmt.Errorf("unable to download objects in bucket '%s': %w", bucket, err), requestIDFromError(err))
+	}
+
+	for filePath, cacheKey := range cacheKeys {
+		if err := pc.ArtifactCache.Store(cacheKey, filePath); err != nil {
+			r.log.Info("unable to cache artifact", "filename", filePath, "error", err.Error())
+		}
 	}
 
 	return nil
 
 }
 
+func (r *gcsRepositoryClient) List(ctx context.Context, lc pullman.ListCommand) ([]pullman.ObjectInfo, error) {
+	bucket, ok := pullman.GetString(lc.RepositoryConfig, configBucket)
+	if !ok {
+		return nil, errors.New("required configuration 'bucket' missing from command")
+	}
+
+	objects, err := r.gcsclient.listObjectInfos(ctx, bucket, lc.Prefix)
+	if err != nil {
+		return nil, pullman.WithRequestID(fmt.Errorf("unable to list objects in bucket '%s': %w", bucket, err), requestIDFromError(err))
+	}
+	return objects, nil
+}
+
 func init() {
 	p := gcsProvider{
 		gcsDownloaderFactory: gcsClientFactory{},
@@ -16,13 +16,18 @@ package gcsprovider
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/golang/mock/gomock"
 	"github.com/kserve/modelmesh-runtime-adapter/pullman"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -58,7 +63,7 @@ func Test_NewRepositoryWithCredentials(t *testing.T) {
 
 	// Test that credentials are passed to newDownloader if specified.
 	mdf.EXPECT().newDownloader(gomock.Any(), gomock.Eq(map[string]string{
-		"private_key": privateKey, "client_email": clientEmail, "type": "service_account"})).Times(1)
+		"private_key": privateKey, "client_email": clientEmail, "type": "service_account"}), gomock.Eq(pullman.Timeouts{}), gomock.Any()).Times(1)
 
 	_, err := g.NewRepository(c, log)
 	assert.NoError(t, err)
@@ -68,7 +73,7 @@ func Test_NewRepositoryWithCredentials(t *testing.T) {
 
 	// Test that optional token_uri field is passed to newDownloader if specified.
 	mdf.EXPECT().newDownloader(gomock.Any(), gomock.Eq(map[string]string{
-		"private_key": privateKey, "client_email": clientEmail, "type": "service_account", "token_uri": tokenUri})).Times(1)
+		"private_key": privateKey, "client_email": clientEmail, "type": "service_account", "token_uri": tokenUri}), gomock.Eq(pullman.Timeouts{}), gomock.Any()).Times(1)
 
 	_, err = g.NewRepository(c, log)
 	assert.NoError(t, err)
@@ -77,12 +82,35 @@ func Test_NewRepositoryWithCredentials(t *testing.T) {
 func Test_NewRepositoryNoCredentials(t *testing.T) {
 	g, mdf, log := newGCSProviderWithMocks(t)
 	c := pullman.NewRepositoryConfig("gcs", nil)
-	mdf.EXPECT().newDownloader(gomock.Any(), gomock.Nil()).Times(1)
+	mdf.EXPECT().newDownloader(gomock.Any(), gomock.Nil(), gomock.Eq(pullman.Timeouts{}), gomock.Any()).Times(1)
 
 	_, err := g.NewRepository(c, log)
 	assert.NoError(t, err)
 }
 
+func Test_NewRepositoryWithTimeouts(t *testing.T) {
+	g, mdf, log := newGCSProviderWithMocks(t)
+	c := pullman.NewRepositoryConfig("gcs", nil)
+	c.Set(pullman.ConfigConnectTimeout, "2s")
+	c.Set(pullman.ConfigRequestTimeout, "1m")
+
+	mdf.EXPECT().newDownloader(gomock.Any(), gomock.Nil(),
+		gomock.Eq(pullman.Timeouts{Connect: 2 * time.Second, Request: time.Minute}), gomock.Any()).Times(1)
+
+	_, err := g.NewRepository(c, log)
+	assert.NoError(t, err)
+}
+
+// objectsWithPaths returns objects without an MD5 checksum, which are not
+// cached
+func objectsWithPaths(paths ...string) []pullman.ObjectInfo {
+	objects := make([]pullman.ObjectInfo, 0, len(paths))
+	for _, path := range paths {
+		objects = append(objects, pullman.ObjectInfo{Path: path, Size: 1})
+	}
+	return objects
+}
+
 func Test_Download_SimpleDirectory(t *testing.T) {
 	gcsRc, mdf := newGCSRepositoryClientWithMock(t)
 
@@ -101,8 +129,8 @@ func Test_Download_SimpleDirectory(t *testing.T) {
 		},
 	}
 
-	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("path/to/modeldir")).
-		Return([]string{"path/to/modeldir/file.ext", "path/to/modeldir/subdir/another_file"}, nil).
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("path/to/modeldir"), gomock.Any()).
+		Return(objectsWithPaths("path/to/modeldir/file.ext", "path/to/modeldir/subdir/another_file"), nil).
 		Times(1)
 
 	expectedTargets := []pullman.Target{
@@ -115,7 +143,7 @@ func Test_Download_SimpleDirectory(t *testing.T) {
 			LocalPath:  filepath.Join(downloadDir, "subdir", "another_file"),
 		},
 	}
-	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Eq(expectedTargets)).
+	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Eq(expectedTargets), gomock.Eq(0)).
 		Return(nil).
 		Times(1)
 
@@ -161,20 +189,20 @@ func Test_Download_MultipleTargets(t *testing.T) {
 		},
 	}
 
-	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("dir")).
-		Return([]string{"dir/file1", "dir/file2"}, nil).
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("dir"), gomock.Any()).
+		Return(objectsWithPaths("dir/file1", "dir/file2"), nil).
 		Times(1)
-	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("some_file")).
-		Return([]string{"some_file"}, nil).
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("some_file"), gomock.Any()).
+		Return(objectsWithPaths("some_file"), nil).
 		Times(1)
-	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("another_dir")).
-		Return([]string{"another_dir/another_file", "another_dir/subdir1/subdir2/nested_file"}, nil).
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("another_dir"), gomock.Any()).
+		Return(objectsWithPaths("another_dir/another_file", "another_dir/subdir1/subdir2/nested_file"), nil).
 		Times(1)
-	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("another_file")).
-		Return([]string{"another_file"}, nil).
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("another_file"), gomock.Any()).
+		Return(objectsWithPaths("another_file"), nil).
 		Times(1)
-	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("yet_another_file")).
-		Return([]string{"yet_another_file"}, nil).
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("yet_another_file"), gomock.Any()).
+		Return(objectsWithPaths("yet_another_file"), nil).
 		Times(1)
 
 	expectedTargets := []pullman.Target{
@@ -207,7 +235,7 @@ func Test_Download_MultipleTargets(t *testing.T) {
 			LocalPath:  filepath.Join(downloadDir, "local_another_dir", "yet_another_file"),
 		},
 	}
-	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq("bucket"), gomock.Eq(expectedTargets)).
+	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq("bucket"), gomock.Eq(expectedTargets), gomock.Eq(0)).
 		Return(nil).
 		Times(1)
 
@@ -215,6 +243,67 @@ func Test_Download_MultipleTargets(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func Test_Download_ArtifactCache(t *testing.T) {
+	gcsRc, mdf := newGCSRepositoryClientWithMock(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("gcs", nil)
+	c.Set("bucket", bucket)
+
+	cache, err := pullman.NewArtifactCache(filepath.Join(t.TempDir(), "cache"), 1024, 256)
+	assert.NoError(t, err)
+
+	// two models reference the same small file at different paths, next to
+	// their own model file
+	rootDir := t.TempDir()
+	sharedDownloads := 0
+	for _, model := range []string{"model-a", "model-b"} {
+		shared := pullman.ObjectInfo{Path: model + "-shared/labels.txt", Size: 5, MD5: "5d41402abc4b2a76b9719d911017c592"}
+		modelDir := filepath.Join(rootDir, model)
+		mdf.EXPECT().listObjects(gomock.Any(), gomock.Eq(bucket), gomock.Eq(shared.Path), gomock.Any()).
+			Return([]pullman.ObjectInfo{shared}, nil).
+			Times(1)
+		mdf.EXPECT().listObjects(gomock.Any(), gomock.Eq(bucket), gomock.Eq(model), gomock.Any()).
+			Return(objectsWithPaths(model+"/model.onnx"), nil).
+			Times(1)
+		mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Any(), gomock.Eq(0)).
+			DoAndReturn(func(_ context.Context, _ string, targets []pullman.Target, _ int) error {
+				for _, target := range targets {
+					content := "large"
+					if target.RemotePath == shared.Path {
+						content = "hello"
+						sharedDownloads++
+					}
+					file, err := pullman.OpenFile(target.LocalPath)
+					if err != nil {
+						return err
+					}
+					file.WriteString(content)
+					file.Close()
+				}
+				return nil
+			}).
+			Times(1)
+
+		err = gcsRc.Pull(context.Background(), pullman.PullCommand{
+			RepositoryConfig: c,
+			Directory:        modelDir,
+			Targets: []pullman.Target{
+				{RemotePath: shared.Path},
+				{RemotePath: model},
+			},
+			ArtifactCache: cache,
+		})
+		assert.NoError(t, err)
+
+		labels, readErr := os.ReadFile(filepath.Join(modelDir, "labels.txt"))
+		assert.NoError(t, readErr)
+		assert.Equal(t, "hello", string(labels))
+		assert.FileExists(t, filepath.Join(modelDir, "model.onnx"))
+	}
+	assert.Equal(t, 1, sharedDownloads, "expected the shared artifact to be downloaded once")
+}
+
 func Test_GetKey(t *testing.T) {
 	provider := gcsProvider{}
 
@@ -251,3 +340,78 @@ func Test_GetKey(t *testing.T) {
 		assert.Equal(t, provider.GetKey(config1), provider.GetKey(config2))
 	})
 }
+
+func Test_Download_ErrorIncludesRequestID(t *testing.T) {
+	gcsRc, mdf := newGCSRepositoryClientWithMock(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("gcs", nil)
+	c.Set("bucket", bucket)
+
+	inputPullCommand := pullman.PullCommand{
+		RepositoryConfig: c,
+		Directory:        filepath.Join("test", "output"),
+		Targets: []pullman.Target{
+			{
+				RemotePath: "path/to/model.zip",
+			},
+		},
+	}
+
+	mdf.EXPECT().listObjects(context.Background(), gomock.Eq(bucket), gomock.Eq("path/to/model.zip"), gomock.Any()).
+		Return(objectsWithPaths("path/to/model.zip"), nil).
+		Times(1)
+	apiErr := &googleapi.Error{
+		Code:   http.StatusForbidden,
+		Header: http.Header{"X-Guploader-Uploadid": []string{"ADPycdt1ZsYAt"}},
+	}
+	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Any(), gomock.Eq(0)).
+		Return(fmt.Errorf("failed to create reader for object(path/to/model.zip) in bucket(bucket): %w", apiErr)).
+		Times(1)
+
+	err := gcsRc.Pull(context.Background(), inputPullCommand)
+	assert.ErrorContains(t, err, "request id: ADPycdt1ZsYAt")
+
+	var reqIDErr *pullman.RequestIDError
+	assert.ErrorAs(t, err, &reqIDErr)
+	assert.Equal(t, "ADPycdt1ZsYAt", reqIDErr.RequestID)
+}
+
+func Test_List(t *testing.T) {
+	gcsRc, mdf := newGCSRepositoryClientWithMock(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("gcs", nil)
+	c.Set("bucket", bucket)
+
+	expectedObjects := []pullman.ObjectInfo{
+		{Path: "models/mnist/model.onnx", Size: 1024},
+		{Path: "models/resnet/saved_model.pb", Size: 2048},
+	}
+	mdf.EXPECT().listObjectInfos(context.Background(), gomock.Eq(bucket), gomock.Eq("models/")).
+		Return(expectedObjects, nil).
+		Times(1)
+
+	objects, err := gcsRc.List(context.Background(), pullman.ListCommand{RepositoryConfig: c, Prefix: "models/"})
+	assert.NoError(t, err)
+	assert.Equal(t, expectedObjects, objects)
+}
+
+func Test_List_ErrorIncludesRequestID(t *testing.T) {
+	gcsRc, mdf := newGCSRepositoryClientWithMock(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("gcs", nil)
+	c.Set("bucket", bucket)
+
+	apiErr := &googleapi.Error{
+		Code:   http.StatusForbidden,
+		Header: http.Header{"X-Guploader-Uploadid": []string{"ADPycdt1ZsYAt"}},
+	}
+	mdf.EXPECT().listObjectInfos(context.Background(), gomock.Eq(bucket), gomock.Eq("models/")).
+		Return(nil, fmt.Errorf("GCS listObjects: unable to list bucket %q: %w", bucket, apiErr)).
+		Times(1)
+
+	_, err := gcsRc.List(context.Background(), pullman.ListCommand{RepositoryConfig: c, Prefix: "models/"})
+	assert.ErrorContains(t, err, "request id: ADPycdt1ZsYAt")
+}
@@ -20,6 +20,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"time"
 
@@ -27,12 +28,14 @@ import (
 	"github.com/kserve/modelmesh-runtime-adapter/pullman"
 )
 
+const defaultRequestTimeout = 15 * time.Minute
+
 type httpClientFactory struct{}
 
 // httpClientFactory implements fetcherFactory
 var _ fetcherFactory = (*httpClientFactory)(nil)
 
-func (f httpClientFactory) newClient(log logr.Logger, ca *x509.CertPool, client_tls *tls.Certificate) fetcher {
+func (f httpClientFactory) newClient(log logr.Logger, ca *x509.CertPool, client_tls *tls.Certificate, timeouts pullman.Timeouts, proxy pullman.Proxy) fetcher {
 
 	// net/http provides a default Transport and Client, but the settings
 	// are not conducive to production use so we create our own here
@@ -41,6 +44,7 @@ func (f httpClientFactory) newClient(log logr.Logger, ca *x509.CertPool, client_
 		MaxIdleConns:        100,
 		MaxConnsPerHost:     100,
 		MaxIdleConnsPerHost: 100,
+		Proxy:               proxy.ProxyFunc(),
 	}
 	if ca != nil {
 		t.TLSClientConfig.RootCAs = ca
@@ -48,11 +52,20 @@ func (f httpClientFactory) newClient(log logr.Logger, ca *x509.CertPool, client_
 	if client_tls != nil {
 		t.TLSClientConfig.Certificates = []tls.Certificate{*client_tls}
 	}
+	if timeouts.Connect > 0 {
+		t.DialContext = (&net.Dialer{Timeout: timeouts.Connect}).DialContext
+		t.TLSHandshakeTimeout = timeouts.Connect
+	}
+
+	requestTimeout := defaultRequestTimeout
+	if timeouts.Request > 0 {
+		requestTimeout = timeouts.Request
+	}
 
 	return &httpFetcher{
 		httpClient: &http.Client{
 			Transport: &t,
-			Timeout:   15 * time.Minute,
+			Timeout:   requestTimeout,
 		},
 		log: log,
 	}
@@ -88,3 +101,18 @@ func (c *httpFetcher) download(ctx context.Context, req *http.Request, filename
 
 	return nil
 }
+
+func (c *httpFetcher) downloadAndExtract(ctx context.Context, req *http.Request, dir string, subpath string, maxBytes int64) error {
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error getting resource '%s': %w", req.URL.String(), err)
+	}
+	defer resp.Body.Close()
+
+	if err = pullman.ExtractTarSubpath(resp.Body, dir, subpath, maxBytes); err != nil {
+		return fmt.Errorf("error extracting resource to local directory '%s': %w", dir, err)
+	}
+
+	return nil
+}
@@ -25,6 +25,7 @@ import (
 
 	logr "github.com/go-logr/logr"
 	gomock "github.com/golang/mock/gomock"
+	pullman "github.com/kserve/modelmesh-runtime-adapter/pullman"
 )
 
 // MockfetcherFactory is a mock of fetcherFactory interface.
@@ -51,17 +52,17 @@ func (m *MockfetcherFactory) EXPECT() *MockfetcherFactoryMockRecorder {
 }
 
 // newClient mocks base method.
-func (m *MockfetcherFactory) newClient(log logr.Logger, ca *x509.CertPool, client_tls *tls.Certificate) fetcher {
+func (m *MockfetcherFactory) newClient(log logr.Logger, ca *x509.CertPool, client_tls *tls.Certificate, timeouts pullman.Timeouts, proxy pullman.Proxy) fetcher {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "newClient", log, ca, client_tls)
+	ret := m.ctrl.Call(m, "newClient", log, ca, client_tls, timeouts, proxy)
 	ret0, _ := ret[0].(fetcher)
 	return ret0
 }
 
 // newClient indicates an expected call of newClient.
-func (mr *MockfetcherFactoryMockRecorder) newClient(log, ca, client_tls interface{}) *gomock.Call {
+func (mr *MockfetcherFactoryMockRecorder) newClient(log, ca, client_tls, timeouts, proxy interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "newClient", reflect.TypeOf((*MockfetcherFactory)(nil).newClient), log, ca, client_tls)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "newClient", reflect.TypeOf((*MockfetcherFactory)(nil).newClient), log, ca, client_tls, timeouts, proxy)
 }
 
 // Mockfetcher is a mock of fetcher interface.
@@ -100,3 +101,17 @@ func (mr *MockfetcherMockRecorder) download(ctx, req, filename interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "download", reflect.TypeOf((*Mockfetcher)(nil).download), ctx, req, filename)
 }
+
+// downloadAndExtract mocks base method.
+func (m *Mockfetcher) downloadAndExtract(ctx context.Context, req *http.Request, dir, subpath string, maxBytes int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "downloadAndExtract", ctx, req, dir, subpath, maxBytes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// downloadAndExtract indicates an expected call of downloadAndExtract.
+func (mr *MockfetcherMockRecorder) downloadAndExtract(ctx, req, dir, subpath, maxBytes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "downloadAndExtract", reflect.TypeOf((*Mockfetcher)(nil).downloadAndExtract), ctx, req, dir, subpath, maxBytes)
+}
@@ -25,6 +25,7 @@ import (
 	"path"
 
 	"github.com/go-logr/logr"
+	"golang.org/x/net/http/httpproxy"
 
 	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
 	"github.com/kserve/modelmesh-runtime-adapter/pullman"
@@ -43,11 +44,12 @@ const (
 // fetcherFactory is the interface used create clients
 // useful to mock for testing
 type fetcherFactory interface {
-	newClient(log logr.Logger, ca *x509.CertPool, client_tls *tls.Certificate) fetcher
+	newClient(log logr.Logger, ca *x509.CertPool, client_tls *tls.Certificate, timeouts pullman.Timeouts, proxy pullman.Proxy) fetcher
 }
 
 type fetcher interface {
 	download(ctx context.Context, req *http.Request, filename string) error
+	downloadAndExtract(ctx context.Context, req *http.Request, dir string, subpath string, maxBytes int64) error
 }
 
 // structs
@@ -60,13 +62,15 @@ type httpProvider struct {
 var _ pullman.StorageProvider = (*httpProvider)(nil)
 
 func (p httpProvider) GetKey(config pullman.Config) string {
-	// the TLS config goes into the transport, so changes to that require a new client
+	// the TLS config, timeouts and proxy go into the client, so changes to those require a new client
 	// everything else is handled per Pull()
 	cert, _ := pullman.GetString(config, configCertificate)
 	clientCert, _ := pullman.GetString(config, configClientCertificate)
 	clientKey, _ := pullman.GetString(config, configClientKey)
+	timeouts, _ := pullman.GetTimeouts(config)
+	proxy, _ := pullman.GetProxy(config)
 
-	return pullman.HashStrings(cert, clientCert, clientKey)
+	return pullman.HashStrings(cert, clientCert, clientKey, timeouts.String(), proxy.String())
 }
 
 func (p httpProvider) NewRepository(config pullman.Config, log logr.Logger) (pullman.RepositoryClient, error) {
@@ -103,8 +107,24 @@ func (p httpProvider) NewRepository(config pullman.Config, log logr.Logger) (pul
 		client_tls = &c
 	}
 
+	timeouts, err := pullman.GetTimeouts(config)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := pullman.GetProxy(config)
+	if err != nil {
+		return nil, err
+	}
+	if proxy.URL == "" {
+		// without proxy_url, net/http uses the proxy of the environment
+		if env := httpproxy.FromEnvironment(); env.HTTPProxy != "" || env.HTTPSProxy != "" {
+			log.Info("No proxy_url is set, the requests use the proxy of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables")
+		}
+	}
+
 	return &httpRepository{
-		client: p.fetcherFactory.newClient(log, ca, client_tls),
+		client: p.fetcherFactory.newClient(log, ca, client_tls, timeouts, proxy),
 		log:    log,
 	}, nil
 }
@@ -116,6 +136,13 @@ type httpRepository struct {
 
 // httpRepository implements RepositoryClient
 var _ pullman.RepositoryClient = (*httpRepository)(nil)
+var _ pullman.TarExtractor = (*httpRepository)(nil)
+
+// ExtractsTar returns true, since archives are extracted as they are
+// downloaded so that they are never written to disk
+func (r *httpRepository) ExtractsTar() bool {
+	return true
+}
 
 func (r *httpRepository) Pull(ctx context.Context, pc pullman.PullCommand) error {
 	destDir := pc.Directory
@@ -142,6 +169,14 @@ func (r *httpRepository) Pull(ctx context.Context, pc pullman.PullCommand) error
 
 		header = http.Header(mm)
 	}
+	// a User-Agent in the headers takes precedence over the user_agent
+	if userAgent := pullman.GetUserAgent(pc.RepositoryConfig); userAgent != "" && header.Get("User-Agent") == "" {
+		header = header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set("User-Agent", userAgent)
+	}
 
 	for _, pt := range targets {
 		// construct the request
@@ -157,7 +192,7 @@ func (r *httpRepository) Pull(ctx context.Context, pc pullman.PullCommand) error
 		// construct the LocalPath
 		localPath := pt.LocalPath
 		// handle default value for LocalPath
-		if localPath == "" {
+		if localPath == "" && !pt.ExtractTar {
 			localPath = path.Base(pt.RemotePath)
 		}
 
@@ -165,9 +200,14 @@ func (r *httpRepository) Pull(ctx context.Context, pc pullman.PullCommand) error
 		if joinErr != nil {
 			return fmt.Errorf("error joining filepaths '%s' and '%s': %w", destDir, localPath, joinErr)
 		}
-		r.log.V(1).Info("constructed local path to download file", "local", filePath, "remote", pt.RemotePath)
+		r.log.V(1).Info("constructed local path to download file", "local", filePath, "remote", pt.RemotePath, "extract", pt.ExtractTar)
 
-		downloadErr := r.client.download(ctx, req, filePath)
+		var downloadErr error
+		if pt.ExtractTar {
+			downloadErr = r.client.downloadAndExtract(ctx, req, filePath, pt.TarSubpath, pt.MaxExtractedBytes)
+		} else {
+			downloadErr = r.client.download(ctx, req, filePath)
+		}
 		if downloadErr != nil {
 			return fmt.Errorf("unable to download file from '%s': %w", baseURL.String(), downloadErr)
 		}
@@ -15,13 +15,18 @@
 package httpprovider
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/golang/mock/gomock"
@@ -185,3 +190,164 @@ func Test_GetKey(t *testing.T) {
 		assert.Equal(t, provider.GetKey(config1), provider.GetKey(config2))
 	})
 }
+
+func Test_NewRepository_Timeouts(t *testing.T) {
+	testProvider, mockFactory, _, _, log := newTestMocks(t)
+
+	c := pullman.NewRepositoryConfig("http", nil)
+	c.Set(pullman.ConfigConnectTimeout, "1s")
+	c.Set(pullman.ConfigRequestTimeout, "10s")
+
+	mockFactory.EXPECT().newClient(gomock.Any(), gomock.Nil(), gomock.Nil(),
+		gomock.Eq(pullman.Timeouts{Connect: time.Second, Request: 10 * time.Second}), gomock.Eq(pullman.Proxy{})).
+		Return(nil).
+		Times(1)
+
+	_, err := testProvider.NewRepository(c, log)
+	assert.NoError(t, err)
+}
+
+func Test_Download_RequestTimeout(t *testing.T) {
+	// a slow server that does not respond within the configured request timeout
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	log := zap.New()
+	client := httpClientFactory{}.newClient(log, nil, nil, pullman.Timeouts{Request: 200 * time.Millisecond}, pullman.Proxy{})
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+	assert.NoError(t, err)
+
+	start := time.Now()
+	err = client.download(context.Background(), req, filepath.Join(t.TempDir(), "file"))
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func Test_Download_ExtractTar(t *testing.T) {
+	_, _, testRepo, mockClient, _ := newTestMocks(t)
+
+	testURL := "http://someurl:8080"
+	c := pullman.NewRepositoryConfig("http", nil)
+	c.Set("url", testURL)
+
+	downloadDir := filepath.Join("test", "output")
+	inputPullCommand := pullman.PullCommand{
+		RepositoryConfig: c,
+		Directory:        downloadDir,
+		Targets: []pullman.Target{
+			{
+				RemotePath:        "models/model.tar.gz",
+				ExtractTar:        true,
+				MaxExtractedBytes: 1024,
+			},
+		},
+	}
+
+	// the archive is extracted into the pull directory
+	expectedURL := testURL + "/models/model.tar.gz"
+	mockClient.EXPECT().downloadAndExtract(gomock.Any(), newHttpRequestMatcher("GET", expectedURL), gomock.Eq(downloadDir), gomock.Eq(""), gomock.Eq(int64(1024))).
+		Return(nil).
+		Times(1)
+
+	err := testRepo.Pull(context.Background(), inputPullCommand)
+	assert.NoError(t, err)
+}
+
+func Test_DownloadAndExtract_Stream(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "model/weights", Typeflag: tar.TypeReg, Mode: 0644, Size: 7}))
+	_, err := tw.Write([]byte("weights"))
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive.Bytes())
+	}))
+	defer server.Close()
+
+	log := zap.New()
+	client := httpClientFactory{}.newClient(log, nil, nil, pullman.Timeouts{}, pullman.Proxy{})
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	assert.NoError(t, client.downloadAndExtract(context.Background(), req, dir, "", 0))
+
+	content, err := os.ReadFile(filepath.Join(dir, "model", "weights"))
+	assert.NoError(t, err)
+	assert.Equal(t, "weights", string(content))
+}
+
+func Test_Download_Proxy(t *testing.T) {
+	// a stub proxy that serves the file itself
+	var proxiedURLs []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedURLs = append(proxiedURLs, r.URL.String())
+		_, _ = w.Write([]byte("model"))
+	}))
+	defer proxy.Close()
+
+	log := zap.New()
+	client := httpClientFactory{}.newClient(log, nil, nil, pullman.Timeouts{}, pullman.Proxy{URL: proxy.URL})
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", "http://models.example.com/models/model.bin", nil)
+	assert.NoError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "model.bin")
+	assert.NoError(t, client.download(context.Background(), req, filename))
+	assert.Equal(t, []string{"http://models.example.com/models/model.bin"}, proxiedURLs)
+
+	content, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "model", string(content))
+}
+
+func Test_Pull_UserAgent(t *testing.T) {
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.UserAgent())
+		_, _ = w.Write([]byte("model"))
+	}))
+	defer server.Close()
+
+	log := zap.New()
+	pull := func(t *testing.T, config *pullman.RepositoryConfig) {
+		repo, err := httpProvider{fetcherFactory: httpClientFactory{}}.NewRepository(config, log)
+		assert.NoError(t, err)
+		pc := pullman.PullCommand{
+			RepositoryConfig: config,
+			Directory:        t.TempDir(),
+			Targets:          []pullman.Target{{RemotePath: "model.bin"}},
+		}
+		assert.NoError(t, repo.Pull(context.Background(), pc))
+	}
+
+	// the configured user agent is sent
+	userAgents = nil
+	config := pullman.NewRepositoryConfig("http", nil)
+	config.Set(configURL, server.URL)
+	config.Set(pullman.ConfigUserAgent, "model-serving/1.0")
+	pull(t, config)
+	assert.Equal(t, []string{"model-serving/1.0"}, userAgents)
+
+	// a User-Agent in the headers takes precedence
+	userAgents = nil
+	config.Set(configHeaders, map[string]interface{}{"User-Agent": "custom-agent"})
+	pull(t, config)
+	assert.Equal(t, []string{"custom-agent"}, userAgents)
+
+	// without either, the default of net/http is sent
+	userAgents = nil
+	config = pullman.NewRepositoryConfig("http", nil)
+	config.Set(configURL, server.URL)
+	pull(t, config)
+	assert.Equal(t, []string{"Go-http-client/1.1"}, userAgents)
+}
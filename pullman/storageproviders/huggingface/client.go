@@ -0,0 +1,235 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package huggingfaceprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+const (
+	entryTypeFile      = "file"
+	entryTypeDirectory = "directory"
+)
+
+// treeEntry is an entry of a response of the tree API of the Hub
+type treeEntry struct {
+	Type string `json:"type"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// set for files stored with Git LFS, the size is the size of the file
+	// rather than of its pointer
+	LFS *struct {
+		Size int64 `json:"size"`
+	} `json:"lfs,omitempty"`
+}
+
+// errorResponse is the body of a failed request to the Hub
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// hubFile is a file of a repository, with its path relative to the root of
+// the repository
+type hubFile struct {
+	path string
+	size int64
+}
+
+type hubClient struct {
+	httpClient *http.Client
+	baseURL    url.URL
+	// sent as a bearer token if not empty, for private and gated repositories
+	token string
+	// sent as the User-Agent of the requests if not empty
+	userAgent string
+	log       logr.Logger
+}
+
+func (c *hubClient) newRequest(ctx context.Context, u string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	// the header is removed on redirects to other hosts by checkRedirect
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return req, nil
+}
+
+// do sends a request for the url and returns the response if it succeeded,
+// what names what is requested in the errors
+func (c *hubClient) do(ctx context.Context, u string, what string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, u)
+	if err != nil {
+		return nil, fmt.Errorf("error building request for %s: %w", what, err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request for %s: %w", what, errorWithoutURL(err))
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var hubErr errorResponse
+	if body, readErr := io.ReadAll(resp.Body); readErr == nil && json.Unmarshal(body, &hubErr) == nil && hubErr.Error != "" {
+		return nil, fmt.Errorf("request for %s failed with status %d: %s", what, resp.StatusCode, hubErr.Error)
+	}
+	return nil, fmt.Errorf("request for %s failed with status %d", what, resp.StatusCode)
+}
+
+// listFiles returns the files of the repository at the revision,
+// recursively, following the pages of the listing
+func (c *hubClient) listFiles(ctx context.Context, repoID string, revision string) ([]hubFile, error) {
+	what := fmt.Sprintf("the files of repository '%s' at revision '%s'", repoID, revision)
+	next := c.baseURL.String() + "/api/models/" + repoID + "/tree/" + url.PathEscape(revision) + "?recursive=true"
+
+	var files []hubFile
+	for next != "" {
+		resp, err := c.do(ctx, next, what)
+		if err != nil {
+			return nil, err
+		}
+		var entries []treeEntry
+		err = json.NewDecoder(resp.Body).Decode(&entries)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing the listing of %s: %w", what, err)
+		}
+		for _, entry := range entries {
+			switch entry.Type {
+			case entryTypeFile:
+				f := hubFile{path: entry.Path, size: entry.Size}
+				if entry.LFS != nil {
+					f.size = entry.LFS.Size
+				}
+				files = append(files, f)
+			case entryTypeDirectory:
+				// the files of the directory are listed on their own
+			default:
+				c.log.V(1).Info("skipping entry that is neither a file nor a directory", "path", entry.Path, "type", entry.Type)
+			}
+		}
+		if next, err = nextPage(resp); err != nil {
+			return nil, fmt.Errorf("error following the listing of %s: %w", what, err)
+		}
+	}
+	return files, nil
+}
+
+// nextPage returns the url of the next page of a listing from the Link
+// header of the response, or the empty string if it is the last page
+func nextPage(resp *http.Response) (string, error) {
+	for _, link := range strings.Split(resp.Header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if strings.ReplaceAll(strings.TrimSpace(param), " ", "") != `rel="next"` {
+				continue
+			}
+			u, err := resp.Request.URL.Parse(strings.Trim(target, "<>"))
+			if err != nil {
+				return "", err
+			}
+			return u.String(), nil
+		}
+	}
+	return "", nil
+}
+
+// download writes the file of the repository at the revision to filename
+//
+// The file is requested from the resolve endpoint, which redirects to the
+// storage of the files stored with Git LFS, so that the file itself is
+// written rather than its pointer. A file whose size differs from the size
+// in the listing fails the download.
+func (c *hubClient) download(ctx context.Context, repoID string, revision string, f hubFile, filename string) error {
+	what := fmt.Sprintf("file '%s' of repository '%s'", f.path, repoID)
+	u := c.baseURL.String() + "/" + repoID + "/resolve/" + url.PathEscape(revision) + "/" + escapePath(f.path)
+	resp, err := c.do(ctx, u, what)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	file, fileErr := pullman.OpenFile(filename)
+	if fileErr != nil {
+		return fmt.Errorf("unable to open local file '%s' for writing: %w", filename, fileErr)
+	}
+	defer file.Close()
+
+	written, err := io.Copy(file, resp.Body)
+	if err != nil {
+		return fmt.Errorf("error writing %s to local file '%s': %w", what, filename, err)
+	}
+	if written != f.size {
+		return fmt.Errorf("downloaded %d bytes of %s, expected %d; it may have been served as a Git LFS pointer", written, what, f.size)
+	}
+	return nil
+}
+
+// maxRedirects is the limit of redirects of the default policy of net/http
+const maxRedirects = 10
+
+// checkRedirect returns a redirect policy that removes the Authorization
+// header on redirects to hosts other than host. net/http keeps the header on
+// redirects to subdomains, like the storage of the LFS files on
+// cdn-lfs.huggingface.co, which must not receive the token.
+func checkRedirect(host string) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if req.URL.Host != host {
+			req.Header.Del("Authorization")
+		}
+		return nil
+	}
+}
+
+// escapePath escapes each element of a slash-separated path
+func escapePath(p string) string {
+	elements := strings.Split(p, "/")
+	for i, e := range elements {
+		elements[i] = url.PathEscape(e)
+	}
+	return strings.Join(elements, "/")
+}
+
+// errorWithoutURL removes the request URL from errors of the HTTP client
+func errorWithoutURL(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
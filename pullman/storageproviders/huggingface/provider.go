@@ -0,0 +1,318 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package huggingfaceprovider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+const (
+	configURL      = "url"
+	configToken    = "token"
+	configRepoID   = "repo_id"
+	configRevision = "revision"
+	configInclude  = "include"
+	configExclude  = "exclude"
+)
+
+const (
+	defaultURL      = "https://huggingface.co"
+	defaultRevision = "main"
+	// the number of files downloaded in parallel if the PullCommand does not
+	// set a concurrency
+	maxDownloadConcurrency = 4
+)
+
+// a repository id is a name, optionally under the name of a user or an
+// organization
+var repoIDRegex = regexp.MustCompile(`^([\w.-]+/)?[\w.-]+$`)
+
+type huggingfaceProvider struct{}
+
+// huggingfaceProvider implements StorageProvider
+var _ pullman.StorageProvider = (*huggingfaceProvider)(nil)
+
+func (p huggingfaceProvider) GetKey(config pullman.Config) string {
+	// the endpoint, token, timeouts, proxy and user agent go into the client, so changes to those require a new client
+	// the repository, revision and patterns are handled per Pull()
+	endpoint, _ := pullman.GetString(config, configURL)
+	token, _ := pullman.GetString(config, configToken)
+	timeouts, _ := pullman.GetTimeouts(config)
+	proxy, _ := pullman.GetProxy(config)
+
+	return pullman.HashStrings(endpoint, token, timeouts.String(), proxy.String(), pullman.GetUserAgent(config))
+}
+
+func (p huggingfaceProvider) NewRepository(config pullman.Config, log logr.Logger) (pullman.RepositoryClient, error) {
+	endpoint, ok := pullman.GetString(config, configURL)
+	if !ok || endpoint == "" {
+		endpoint = defaultURL
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("url '%s' must start with http:// or https://", endpoint)
+	}
+
+	timeouts, err := pullman.GetTimeouts(config)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := pullman.GetProxy(config)
+	if err != nil {
+		return nil, err
+	}
+	token, _ := pullman.GetString(config, configToken)
+
+	// redirects are followed by the client, up to the limit of net/http,
+	// without the token unless they stay on the host of the Hub
+	httpClient := pullman.NewProxiedHTTPClient(timeouts, proxy)
+	httpClient.CheckRedirect = checkRedirect(baseURL.Host)
+
+	return &huggingfaceRepository{
+		client: &hubClient{
+			httpClient: httpClient,
+			baseURL:    *baseURL,
+			token:      token,
+			userAgent:  pullman.GetUserAgent(config),
+			log:        log,
+		},
+		log: log,
+	}, nil
+}
+
+// getRepo returns the repository id and the revision of the config
+func getRepo(config pullman.Config) (string, string, error) {
+	repoID, ok := pullman.GetString(config, configRepoID)
+	if !ok || repoID == "" {
+		return "", "", fmt.Errorf("missing required string configuration '%s'", configRepoID)
+	}
+	if !repoIDRegex.MatchString(repoID) || strings.Contains(repoID, "..") {
+		return "", "", fmt.Errorf("configuration '%s' must be a repository name, optionally under a user or organization like 'org/name', found '%s'", configRepoID, repoID)
+	}
+	revision, ok := pullman.GetString(config, configRevision)
+	if !ok || revision == "" {
+		revision = defaultRevision
+	}
+	return repoID, revision, nil
+}
+
+// getPatterns returns the file patterns of the config key, which is either a
+// list of patterns or a comma-separated string of them
+func getPatterns(config pullman.Config, key string) ([]string, error) {
+	value, exists := config.Get(key)
+	if !exists || value == nil {
+		return nil, nil
+	}
+
+	var patterns []string
+	switch v := value.(type) {
+	case string:
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				patterns = append(patterns, p)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			p, ok := item.(string)
+			if !ok || p == "" {
+				return nil, fmt.Errorf("configuration '%s' must be a list of non-empty strings, found '%v'", key, item)
+			}
+			patterns = append(patterns, p)
+		}
+	default:
+		return nil, fmt.Errorf("configuration '%s' must be a list of patterns or a comma-separated string, found '%v'", key, value)
+	}
+
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s' in configuration '%s': %w", p, key, err)
+		}
+	}
+	return patterns, nil
+}
+
+// matchesAny returns true if a pattern matches the path of the file within
+// the repository or its base name
+func matchesAny(patterns []string, filePath string) bool {
+	for _, p := range patterns {
+		if matched, _ := path.Match(p, filePath); matched {
+			return true
+		}
+		if matched, _ := path.Match(p, path.Base(filePath)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// filesUnder returns the files at or under the prefix, which is the path of a
+// file or directory within the repository, with their paths relative to the
+// prefix; the path of a file that is the prefix is empty
+func filesUnder(files []hubFile, prefix string) []hubFile {
+	prefix = strings.Trim(prefix, "/")
+	var under []hubFile
+	for _, f := range files {
+		switch {
+		case prefix == "":
+			under = append(under, f)
+		case f.path == prefix:
+			under = append(under, hubFile{path: "", size: f.size})
+		case strings.HasPrefix(f.path, prefix+"/"):
+			under = append(under, hubFile{path: strings.TrimPrefix(f.path, prefix+"/"), size: f.size})
+		}
+	}
+	return under
+}
+
+type huggingfaceRepository struct {
+	client *hubClient
+	log    logr.Logger
+}
+
+// huggingfaceRepository implements RepositoryClient and RepositoryLister
+var _ pullman.RepositoryClient = (*huggingfaceRepository)(nil)
+var _ pullman.RepositoryLister = (*huggingfaceRepository)(nil)
+
+func (r *huggingfaceRepository) Pull(ctx context.Context, pc pullman.PullCommand) error {
+	repoID, revision, err := getRepo(pc.RepositoryConfig)
+	if err != nil {
+		return err
+	}
+	include, err := getPatterns(pc.RepositoryConfig, configInclude)
+	if err != nil {
+		return err
+	}
+	exclude, err := getPatterns(pc.RepositoryConfig, configExclude)
+	if err != nil {
+		return err
+	}
+
+	files, err := r.client.listFiles(ctx, repoID, revision)
+	if err != nil {
+		return fmt.Errorf("unable to list files of repository '%s': %w", repoID, err)
+	}
+
+	// the files of all the targets are resolved before any is downloaded, so
+	// that an invalid target fails the pull without downloading anything
+	var downloads []download
+	destDir := pc.Directory
+	for _, pt := range pc.Targets {
+		remotePath := strings.Trim(pt.RemotePath, "/")
+
+		var selected []hubFile
+		for _, f := range filesUnder(files, remotePath) {
+			repoPath := path.Join(remotePath, f.path)
+			if len(include) > 0 && !matchesAny(include, repoPath) || matchesAny(exclude, repoPath) {
+				r.log.V(1).Info("skipping file that does not match the patterns", "path", repoPath)
+				continue
+			}
+			selected = append(selected, f)
+		}
+		if len(selected) == 0 {
+			return fmt.Errorf("no files to pull under '%s' in repository '%s' at revision '%s'", pt.RemotePath, repoID, revision)
+		}
+		r.log.V(1).Info("found files to download", "path", pt.RemotePath, "count", len(selected))
+
+		for _, f := range selected {
+			localPath := pt.LocalPath
+			relativePath := f.path
+			// handle case where the remote path is a single file
+			if relativePath == "" {
+				// allow renaming of the file
+				if localPath != "" && !strings.HasSuffix(localPath, "/") {
+					relativePath = path.Base(localPath)
+					localPath = path.Dir(localPath)
+				} else {
+					relativePath = path.Base(remotePath)
+				}
+			}
+			filePath, joinErr := util.SecureJoin(destDir, localPath, relativePath)
+			if joinErr != nil {
+				return fmt.Errorf("error joining filepaths '%s' and '%s': %w", pt.LocalPath, relativePath, joinErr)
+			}
+
+			downloads = append(downloads, download{
+				file:     hubFile{path: path.Join(remotePath, f.path), size: f.size},
+				filename: filePath,
+			})
+		}
+	}
+
+	workerCount := maxDownloadConcurrency
+	if pc.Concurrency > 0 {
+		workerCount = pc.Concurrency
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(workerCount)
+	for _, d := range downloads {
+		d := d
+		g.Go(func() error {
+			r.log.V(1).Info("downloading file", "path", d.file.path, "filename", d.filename)
+			if err := r.client.download(gctx, repoID, revision, d.file, d.filename); err != nil {
+				return fmt.Errorf("unable to download file '%s': %w", d.file.path, err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// download is a file of the repository and the local file it is written to
+type download struct {
+	file     hubFile
+	filename string
+}
+
+// List returns the files under the prefix, which is the path of a file or
+// directory within the repository, recursively
+//
+// The include and exclude patterns are not applied, the paths of the files
+// are relative to the root of the repository.
+func (r *huggingfaceRepository) List(ctx context.Context, lc pullman.ListCommand) ([]pullman.ObjectInfo, error) {
+	repoID, revision, err := getRepo(lc.RepositoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	files, err := r.client.listFiles(ctx, repoID, revision)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list files of repository '%s': %w", repoID, err)
+	}
+
+	prefix := strings.Trim(lc.Prefix, "/")
+	objects := []pullman.ObjectInfo{}
+	for _, f := range filesUnder(files, prefix) {
+		objects = append(objects, pullman.ObjectInfo{Path: path.Join(prefix, f.path), Size: f.size})
+	}
+	return objects, nil
+}
+
+func init() {
+	pullman.RegisterProvider("huggingface", huggingfaceProvider{})
+}
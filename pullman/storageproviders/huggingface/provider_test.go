@@ -0,0 +1,421 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package huggingfaceprovider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const stubRepoID = "org/mnist"
+
+// hubStub serves the files of the stubRepoID at some revisions like the Hub,
+// redirecting the resolve requests of LFS files to a storage path of the same
+// server
+type hubStub struct {
+	// revisions to file paths to contents
+	revisions map[string]map[string]string
+	// the paths of the files stored with LFS
+	lfs map[string]bool
+	// serve the LFS files as their pointers, like a broken mirror
+	servePointers bool
+	// the number of entries of a page of the listing, 0 for a single page
+	pageSize int
+	// the URL of the server the LFS files are redirected to, empty for the
+	// same server
+	lfsURL string
+	// the time it takes to serve an LFS file
+	lfsDelay time.Duration
+
+	mutex sync.Mutex
+	// the Authorization header of every request
+	authHeaders []string
+	// the LFS files being served, and the most that were served at once
+	lfsInFlight    int
+	maxLFSInFlight int
+}
+
+func (s *hubStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.authHeaders = append(s.authHeaders, r.Header.Get("Authorization"))
+	s.mutex.Unlock()
+
+	escaped := r.URL.EscapedPath()
+	switch {
+	case strings.HasPrefix(escaped, "/lfs/"):
+		s.mutex.Lock()
+		s.lfsInFlight++
+		if s.lfsInFlight > s.maxLFSInFlight {
+			s.maxLFSInFlight = s.lfsInFlight
+		}
+		s.mutex.Unlock()
+		time.Sleep(s.lfsDelay)
+		s.mutex.Lock()
+		s.lfsInFlight--
+		s.mutex.Unlock()
+
+		contents := s.revisions["main"][strings.TrimPrefix(r.URL.Path, "/lfs/")]
+		w.Write([]byte(contents))
+	case strings.HasPrefix(escaped, "/api/models/"+stubRepoID+"/tree/"):
+		revision, _ := url.PathUnescape(strings.TrimPrefix(escaped, "/api/models/"+stubRepoID+"/tree/"))
+		files, ok := s.revisions[revision]
+		if !ok || r.URL.Query().Get("recursive") != "true" {
+			s.notFound(w, "Revision Not Found")
+			return
+		}
+		s.serveTree(w, r, files)
+	case strings.HasPrefix(escaped, "/"+stubRepoID+"/resolve/"):
+		rest := strings.SplitN(strings.TrimPrefix(escaped, "/"+stubRepoID+"/resolve/"), "/", 2)
+		revision, _ := url.PathUnescape(rest[0])
+		filePath, _ := url.PathUnescape(rest[1])
+		contents, ok := s.revisions[revision][filePath]
+		if !ok {
+			s.notFound(w, "Entry not found")
+			return
+		}
+		if s.lfs[filePath] && !s.servePointers {
+			http.Redirect(w, r, s.lfsURL+"/lfs/"+filePath, http.StatusFound)
+			return
+		}
+		if s.lfs[filePath] {
+			contents = "version https://git-lfs.github.com/spec/v1\n"
+		}
+		w.Write([]byte(contents))
+	default:
+		s.notFound(w, "Repository Not Found")
+	}
+}
+
+func (s *hubStub) notFound(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(errorResponse{Error: message})
+}
+
+// serveTree lists the files and their directories, a page at a time
+func (s *hubStub) serveTree(w http.ResponseWriter, r *http.Request, files map[string]string) {
+	dirs := map[string]bool{}
+	var entries []treeEntry
+	for p, contents := range files {
+		entry := treeEntry{Type: entryTypeFile, Path: p, Size: int64(len(contents))}
+		if s.lfs[p] {
+			// the size of an LFS file is the size of the file, not of its pointer
+			entry.LFS = &struct {
+				Size int64 `json:"size"`
+			}{Size: int64(len(contents))}
+		}
+		entries = append(entries, entry)
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			if !dirs[dir] {
+				dirs[dir] = true
+				entries = append(entries, treeEntry{Type: entryTypeDirectory, Path: dir})
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	if s.pageSize > 0 {
+		cursor, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		end := cursor + s.pageSize
+		if end < len(entries) {
+			next := *r.URL
+			query := next.Query()
+			query.Set("cursor", strconv.Itoa(end))
+			next.RawQuery = query.Encode()
+			w.Header().Set("Link", "<"+next.RequestURI()+`>; rel="next"`)
+		} else {
+			end = len(entries)
+		}
+		entries = entries[cursor:end]
+	}
+	json.NewEncoder(w).Encode(entries)
+}
+
+func newTestRepository(t *testing.T, stub *hubStub, settings map[string]interface{}) pullman.RepositoryClient {
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	config := pullman.NewRepositoryConfig("huggingface", settings)
+	config.Set(configURL, server.URL)
+	repo, err := huggingfaceProvider{}.NewRepository(config, zap.New())
+	if err != nil {
+		t.Fatalf("unable to create repository: %v", err)
+	}
+	return repo
+}
+
+func newStub() *hubStub {
+	return &hubStub{
+		revisions: map[string]map[string]string{
+			"main": {
+				"README.md":               "readme",
+				"config.json":             "config",
+				"model.safetensors":       "safetensors weights",
+				"onnx/model.onnx":         "onnx weights",
+				"onnx/tokenizer.json":     "tokenizer",
+				"flax/flax_model.msgpack": "flax weights",
+			},
+			"refs/pr/1": {
+				"config.json": "config of pr",
+			},
+		},
+		lfs: map[string]bool{"model.safetensors": true, "onnx/model.onnx": true, "flax/flax_model.msgpack": true},
+	}
+}
+
+func pullConfig(settings map[string]interface{}) pullman.Config {
+	config := pullman.NewRepositoryConfig("huggingface", map[string]interface{}{configRepoID: stubRepoID})
+	for k, v := range settings {
+		config.Set(k, v)
+	}
+	return config
+}
+
+func assertFiles(t *testing.T, dir string, expected map[string]string) {
+	var found []string
+	filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, p)
+			found = append(found, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	var expectedFiles []string
+	for file, contents := range expected {
+		expectedFiles = append(expectedFiles, file)
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		assert.NoError(t, err)
+		assert.Equal(t, contents, string(data))
+	}
+	sort.Strings(expectedFiles)
+	assert.Equal(t, expectedFiles, found)
+}
+
+func Test_Pull_Repository(t *testing.T) {
+	stub := newStub()
+	stub.pageSize = 2
+	repo := newTestRepository(t, stub, map[string]interface{}{configToken: "hf_token"})
+
+	dir := t.TempDir()
+	err := repo.Pull(context.Background(), pullman.PullCommand{
+		RepositoryConfig: pullConfig(nil),
+		Directory:        dir,
+		Targets:          []pullman.Target{{RemotePath: ""}},
+	})
+	assert.NoError(t, err)
+
+	// the LFS files are followed to their contents, on every page
+	assertFiles(t, dir, stub.revisions["main"])
+	for _, auth := range stub.authHeaders {
+		assert.Equal(t, "Bearer hf_token", auth)
+	}
+}
+
+func Test_Pull_RedirectToOtherHost(t *testing.T) {
+	// the storage of the LFS files is another server on the same hostname,
+	// to which net/http would send the Authorization header
+	cdn := newStub()
+	cdnServer := httptest.NewServer(cdn)
+	t.Cleanup(cdnServer.Close)
+
+	stub := newStub()
+	stub.lfsURL = cdnServer.URL
+	repo := newTestRepository(t, stub, map[string]interface{}{configToken: "hf_token"})
+
+	dir := t.TempDir()
+	err := repo.Pull(context.Background(), pullman.PullCommand{
+		RepositoryConfig: pullConfig(nil),
+		Directory:        dir,
+		Targets:          []pullman.Target{{RemotePath: ""}},
+	})
+	assert.NoError(t, err)
+
+	assertFiles(t, dir, stub.revisions["main"])
+	for _, auth := range stub.authHeaders {
+		assert.Equal(t, "Bearer hf_token", auth)
+	}
+	// the token is not sent to the storage of the LFS files
+	assert.Len(t, cdn.authHeaders, len(stub.lfs))
+	for _, auth := range cdn.authHeaders {
+		assert.Empty(t, auth)
+	}
+}
+
+func Test_Pull_Concurrency(t *testing.T) {
+	stub := newStub()
+	stub.lfsDelay = 50 * time.Millisecond
+	repo := newTestRepository(t, stub, nil)
+
+	dir := t.TempDir()
+	err := repo.Pull(context.Background(), pullman.PullCommand{
+		RepositoryConfig: pullConfig(nil),
+		Directory:        dir,
+		Targets:          []pullman.Target{{RemotePath: ""}},
+		Concurrency:      2,
+	})
+	assert.NoError(t, err)
+
+	assertFiles(t, dir, stub.revisions["main"])
+	// the three LFS files are downloaded two at a time
+	assert.Equal(t, 2, stub.maxLFSInFlight)
+}
+
+func Test_Pull_Patterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		expected []string
+	}{
+		{
+			name:     "include list",
+			settings: map[string]interface{}{configInclude: []interface{}{"*.json", "onnx/*"}},
+			expected: []string{"config.json", "onnx/model.onnx", "onnx/tokenizer.json"},
+		},
+		{
+			name:     "exclude string",
+			settings: map[string]interface{}{configExclude: "*.msgpack, *.md"},
+			expected: []string{"config.json", "model.safetensors", "onnx/model.onnx", "onnx/tokenizer.json"},
+		},
+		{
+			name:     "include and exclude",
+			settings: map[string]interface{}{configInclude: "*.json,*.onnx", configExclude: "onnx/tokenizer.json"},
+			expected: []string{"config.json", "onnx/model.onnx"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStub()
+			repo := newTestRepository(t, stub, nil)
+
+			dir := t.TempDir()
+			err := repo.Pull(context.Background(), pullman.PullCommand{
+				RepositoryConfig: pullConfig(tt.settings),
+				Directory:        dir,
+				Targets:          []pullman.Target{{RemotePath: "/"}},
+			})
+			assert.NoError(t, err)
+
+			expected := map[string]string{}
+			for _, file := range tt.expected {
+				expected[file] = stub.revisions["main"][file]
+			}
+			assertFiles(t, dir, expected)
+		})
+	}
+}
+
+func Test_Pull_SubdirectoryAndSingleFile(t *testing.T) {
+	stub := newStub()
+	repo := newTestRepository(t, stub, nil)
+
+	dir := t.TempDir()
+	err := repo.Pull(context.Background(), pullman.PullCommand{
+		RepositoryConfig: pullConfig(nil),
+		Directory:        dir,
+		Targets: []pullman.Target{
+			{RemotePath: "onnx", LocalPath: "model"},
+			{RemotePath: "config.json", LocalPath: "model/1/config.json"},
+		},
+	})
+	assert.NoError(t, err)
+
+	assertFiles(t, dir, map[string]string{
+		"model/model.onnx":     "onnx weights",
+		"model/tokenizer.json": "tokenizer",
+		"model/1/config.json":  "config",
+	})
+	// without a token, no Authorization header is sent
+	for _, auth := range stub.authHeaders {
+		assert.Empty(t, auth)
+	}
+}
+
+func Test_Pull_Revision(t *testing.T) {
+	repo := newTestRepository(t, newStub(), nil)
+
+	dir := t.TempDir()
+	err := repo.Pull(context.Background(), pullman.PullCommand{
+		RepositoryConfig: pullConfig(map[string]interface{}{configRevision: "refs/pr/1"}),
+		Directory:        dir,
+		Targets:          []pullman.Target{{RemotePath: ""}},
+	})
+	assert.NoError(t, err)
+	assertFiles(t, dir, map[string]string{"config.json": "config of pr"})
+}
+
+func Test_Pull_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		stub       func(*hubStub)
+		settings   map[string]interface{}
+		remotePath string
+		errMsg     string
+	}{
+		{name: "missing revision", settings: map[string]interface{}{configRevision: "v2"}, errMsg: "Revision Not Found"},
+		{name: "missing repository", settings: map[string]interface{}{configRepoID: "org/missing"}, errMsg: "Repository Not Found"},
+		{name: "invalid repository", settings: map[string]interface{}{configRepoID: "org/../admin"}, errMsg: "must be a repository name"},
+		{name: "missing path", remotePath: "tf", errMsg: "no files to pull under 'tf'"},
+		{name: "invalid pattern", settings: map[string]interface{}{configInclude: "[*.json"}, errMsg: "invalid pattern '[*.json'"},
+		{name: "LFS pointer", stub: func(s *hubStub) { s.servePointers = true }, remotePath: "onnx/model.onnx", errMsg: "Git LFS pointer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStub()
+			if tt.stub != nil {
+				tt.stub(stub)
+			}
+			repo := newTestRepository(t, stub, nil)
+
+			err := repo.Pull(context.Background(), pullman.PullCommand{
+				RepositoryConfig: pullConfig(tt.settings),
+				Directory:        t.TempDir(),
+				Targets:          []pullman.Target{{RemotePath: tt.remotePath}},
+			})
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func Test_List(t *testing.T) {
+	repo := newTestRepository(t, newStub(), nil)
+
+	objects, err := repo.(pullman.RepositoryLister).List(context.Background(), pullman.ListCommand{
+		RepositoryConfig: pullConfig(nil),
+		Prefix:           "onnx/",
+	})
+	assert.NoError(t, err)
+	// the directories are not listed, the LFS files have their own size
+	assert.Equal(t, []pullman.ObjectInfo{
+		{Path: "onnx/model.onnx", Size: 12},
+		{Path: "onnx/tokenizer.json", Size: 9},
+	}, objects)
+}
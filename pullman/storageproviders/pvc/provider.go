@@ -17,6 +17,7 @@ package pvcprovider
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
@@ -74,8 +75,9 @@ type pvcRepositoryClient struct {
 	log         logr.Logger
 }
 
-// pvcRepositoryClient implements RepositoryClient
+// pvcRepositoryClient implements RepositoryClient and RepositoryLister
 var _ pullman.RepositoryClient = (*pvcRepositoryClient)(nil)
+var _ pullman.RepositoryLister = (*pvcRepositoryClient)(nil)
 
 func (r *pvcRepositoryClient) Pull(ctx context.Context, pc pullman.PullCommand) error {
 	targets := pc.Targets
@@ -123,6 +125,55 @@ func (r *pvcRepositoryClient) Pull(ctx context.Context, pc pullman.PullCommand)
 	return nil
 }
 
+// List returns the files under the prefix, which is the path of a file or
+// directory in the PVC, recursively
+//
+// The paths of the files are relative to the PVC. Symbolic links to files
+// are listed with the size of the file, links to directories are not
+// followed.
+func (r *pvcRepositoryClient) List(ctx context.Context, lc pullman.ListCommand) ([]pullman.ObjectInfo, error) {
+	pvcName, ok := pullman.GetString(lc.RepositoryConfig, configPVCName)
+	if !ok {
+		return nil, fmt.Errorf("required configuration '%s' missing from command", configPVCName)
+	}
+
+	pvcDir, joinErr := util.SecureJoin(r.pvcProvider.pvcMountBase, pvcName)
+	if joinErr != nil {
+		return nil, fmt.Errorf("unable to join paths '%s' and '%s': %v", r.pvcProvider.pvcMountBase, pvcName, joinErr)
+	}
+	listDir, joinErr := util.SecureJoin(pvcDir, lc.Prefix)
+	if joinErr != nil {
+		return nil, fmt.Errorf("unable to join paths '%s' and '%s': %v", pvcDir, lc.Prefix, joinErr)
+	}
+
+	objects := []pullman.ObjectInfo{}
+	err := filepath.WalkDir(listDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return ctx.Err()
+		}
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(pvcDir, filePath)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, pullman.ObjectInfo{Path: filepath.ToSlash(relativePath), Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list files under '%s': %w", listDir, err)
+	}
+	return objects, nil
+}
+
 func init() {
 	p := pvcProvider{}
 	pullman.RegisterProvider("pvc", p)
@@ -17,6 +17,7 @@ package pvcprovider
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/go-logr/logr"
@@ -108,3 +109,37 @@ func Test_GetKey(t *testing.T) {
 		assert.Equal(t, provider.GetKey(config1), provider.GetKey(config2))
 	})
 }
+
+func Test_List(t *testing.T) {
+	pvcRc := newPVCRepositoryClient(t)
+	pvcRc.pvcProvider.pvcMountBase = t.TempDir()
+	c := pullman.NewRepositoryConfig("pvc", nil)
+	c.Set("name", "pvcName")
+
+	pvcDir := filepath.Join(pvcRc.pvcProvider.pvcMountBase, "pvcName")
+	for file, contents := range map[string]string{
+		"models/mnist/config.pbtxt": "config",
+		"models/mnist/1/model.onnx": "weights",
+		"datasets/train.csv":        "data",
+	} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(pvcDir, file)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(pvcDir, file), []byte(contents), 0644))
+	}
+	assert.NoError(t, os.MkdirAll(filepath.Join(pvcDir, "models", "empty"), 0755))
+	assert.NoError(t, os.Symlink(filepath.Join(pvcDir, "datasets", "train.csv"), filepath.Join(pvcDir, "models", "train.csv")))
+
+	objects, err := pvcRc.List(context.Background(), pullman.ListCommand{RepositoryConfig: c, Prefix: "models"})
+	assert.NoError(t, err)
+	assert.Equal(t, []pullman.ObjectInfo{
+		{Path: "models/mnist/1/model.onnx", Size: 7},
+		{Path: "models/mnist/config.pbtxt", Size: 6},
+		{Path: "models/train.csv", Size: 4},
+	}, objects)
+
+	// a prefix outside of the PVC is rejected
+	_, err = pvcRc.List(context.Background(), pullman.ListCommand{RepositoryConfig: c, Prefix: "../other"})
+	assert.Error(t, err)
+
+	_, err = pvcRc.List(context.Background(), pullman.ListCommand{RepositoryConfig: c, Prefix: "missing"})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
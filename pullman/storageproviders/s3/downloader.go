@@ -16,15 +16,21 @@ package s3provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"strings"
 
 	"github.com/IBM/ibm-cos-sdk-go/aws"
+	"github.com/IBM/ibm-cos-sdk-go/aws/awserr"
 	"github.com/IBM/ibm-cos-sdk-go/aws/credentials"
+	"github.com/IBM/ibm-cos-sdk-go/aws/request"
 	"github.com/IBM/ibm-cos-sdk-go/aws/session"
 	"github.com/IBM/ibm-cos-sdk-go/service/s3"
 	"github.com/IBM/ibm-cos-sdk-go/service/s3/s3manager"
 	"github.com/go-logr/logr"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/kserve/modelmesh-runtime-adapter/pullman"
 )
@@ -36,7 +42,7 @@ type ibmS3DownloaderFactory struct {
 // ibmS3DownloaderFactory implements s3DownloaderFactory
 var _ s3DownloaderFactory = (*ibmS3DownloaderFactory)(nil)
 
-func (f ibmS3DownloaderFactory) newDownloader(log logr.Logger, accessKeyID, secretAccessKey, endpoint, region, certificate string) s3Downloader {
+func (f ibmS3DownloaderFactory) newDownloader(log logr.Logger, accessKeyID, secretAccessKey, endpoint, region, certificate string, timeouts pullman.Timeouts, proxy pullman.Proxy, userAgent string) s3Downloader {
 	s3Config := aws.NewConfig().
 		WithS3ForcePathStyle(true).
 		WithEndpoint(endpoint).
@@ -46,13 +52,24 @@ func (f ibmS3DownloaderFactory) newDownloader(log logr.Logger, accessKeyID, secr
 			SecretAccessKey: secretAccessKey,
 		}))
 
+	// the SDK's default HTTP client is only replaced if timeouts or a proxy are configured
+	sessionConfig := aws.NewConfig()
+	if timeouts != (pullman.Timeouts{}) || proxy != (pullman.Proxy{}) {
+		sessionConfig.WithHTTPClient(pullman.NewProxiedHTTPClient(timeouts, proxy))
+	}
+
 	var s3Session *session.Session
 	if certificate != "" {
 		s3Session = session.Must(session.NewSessionWithOptions(session.Options{
+			Config:         *sessionConfig,
 			CustomCABundle: strings.NewReader(certificate),
 		}))
 	} else {
-		s3Session = session.Must(session.NewSession())
+		s3Session = session.Must(session.NewSession(sessionConfig))
+	}
+	// the user agent is appended to the one of the SDK
+	if userAgent != "" {
+		s3Session.Handlers.Build.PushBack(request.MakeAddToUserAgentFreeFormHandler(userAgent))
 	}
 
 	return &ibmS3Downloader{
@@ -71,72 +88,152 @@ type ibmS3Downloader struct {
 // ibmS3Downloader implements s3Downloader
 var _ s3Downloader = (*ibmS3Downloader)(nil)
 
-func (d *ibmS3Downloader) listObjects(bucket string, prefix string) ([]string, error) {
-	objectPaths := make([]string, 0, 10)
+func (d *ibmS3Downloader) listObjects(bucket string, prefix string, keepEmpty bool) ([]s3Object, error) {
+	objects := make([]s3Object, 0, 10)
 	err := d.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
 		Bucket:  aws.String(bucket),
 		Prefix:  aws.String(prefix),
 		MaxKeys: aws.Int64(100),
 	}, func(listObjectsResult *s3.ListObjectsV2Output, lastPage bool) bool {
-		// turn the results into a string array, ignoring 0 byte objects and objects ending with a '/'
+		// ignore 0 byte objects and objects ending with a '/' other than directory markers
 		for _, object := range listObjectsResult.Contents {
-			if d.shouldIgnoreObject(object, prefix) {
+			if d.shouldIgnoreObject(object, prefix, keepEmpty) {
 				continue
 			}
-			objectPaths = append(objectPaths, *object.Key)
+			objects = append(objects, s3Object{
+				key:  *object.Key,
+				etag: aws.StringValue(object.ETag),
+				size: *object.Size,
+			})
 		}
 		return lastPage // continue until the last page
 	})
 	if err != nil {
 		return nil, err
 	}
-	return objectPaths, nil
+	return objects, nil
+}
+
+// getObjectVersion gets the ETag and size of the version of the object
+func (d *ibmS3Downloader) getObjectVersion(ctx context.Context, bucket string, key string, versionID string) (s3Object, error) {
+	output, err := d.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		return s3Object{}, err
+	}
+	return s3Object{
+		key:       key,
+		etag:      aws.StringValue(output.ETag),
+		size:      aws.Int64Value(output.ContentLength),
+		versionID: versionID,
+	}, nil
+}
+
+// getObjectTags makes a request per object, so it is only used when the
+// repository has a tag filter
+func (d *ibmS3Downloader) getObjectTags(ctx context.Context, bucket string, key string, versionID string) (map[string]string, error) {
+	output, err := d.client.GetObjectTaggingWithContext(ctx, &s3.GetObjectTaggingInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: optionalString(versionID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(output.TagSet))
+	for _, tag := range output.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
+// getObjectChecksum makes a request per object, so it is only used to
+// verify objects whose ETag is not an MD5
+func (d *ibmS3Downloader) getObjectChecksum(ctx context.Context, bucket string, key string, versionID string) (string, error) {
+	var checksum string
+	_, err := d.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: optionalString(versionID),
+	}, func(r *request.Request) {
+		// S3 only returns the checksums of an object when asked to
+		r.HTTPRequest.Header.Set("x-amz-checksum-mode", "ENABLED")
+	}, request.WithGetResponseHeader("x-amz-checksum-sha256", &checksum))
+	if err != nil {
+		return "", err
+	}
+	return checksum, nil
 }
 
 // downloadBatch
 // assumes that `targets` has a separate entry for each object to download and
 // LocalPath is the full path to the desired target file
-func (d *ibmS3Downloader) downloadBatch(ctx context.Context, bucket string, targets []pullman.Target) error {
-	downloadIter := s3manager.DownloadObjectsIterator{}
-	for _, target := range targets {
-		file, fileErr := pullman.OpenFile(target.LocalPath)
-		if fileErr != nil {
-			return fmt.Errorf("unable to open local file '%s' for writing: %w", target.LocalPath, fileErr)
-		}
-		defer file.Close()
-
-		d.log.V(1).Info("downloading object", "path", target.RemotePath, "filename", target.LocalPath)
-		downloadIter.Objects = append(downloadIter.Objects, s3manager.BatchDownloadObject{
-			Object: &s3.GetObjectInput{
-				Bucket: aws.String(bucket),
-				Key:    aws.String(target.RemotePath),
-			},
-			Writer: file,
-		})
-	}
-
-	if len(downloadIter.Objects) == 0 {
+//
+// Up to concurrency objects are downloaded in parallel, or the downloader's
+// default if it is not positive. A single object is downloaded in that many
+// parts in parallel instead, and the objects of a batch in one part each, so
+// that the number of requests in flight stays within the concurrency.
+func (d *ibmS3Downloader) downloadBatch(ctx context.Context, bucket string, targets []pullman.Target, concurrency int) error {
+	if len(targets) == 0 {
 		d.log.Info("no objects to download")
 		return nil
 	}
 
+	if concurrency <= 0 {
+		concurrency = d.downloaderConcurrency
+	}
+	partConcurrency := 1
+	if len(targets) == 1 {
+		partConcurrency = concurrency
+	}
 	downloader := s3manager.NewDownloaderWithClient(d.client, func(s3d *s3manager.Downloader) {
-		s3d.Concurrency = d.downloaderConcurrency
+		s3d.Concurrency = partConcurrency
 	})
-	downloadErr := downloader.DownloadWithIterator(ctx, &downloadIter)
-	if downloadErr != nil {
-		return fmt.Errorf("unable to download objects in bucket '%s': %w", bucket, downloadErr)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for _, target := range targets {
+		target := target
+		g.Go(func() error {
+			file, fileErr := pullman.OpenFile(target.LocalPath)
+			if fileErr != nil {
+				return fmt.Errorf("unable to open local file '%s' for writing: %w", target.LocalPath, fileErr)
+			}
+			defer file.Close()
+
+			d.log.V(1).Info("downloading object", "path", target.RemotePath, "filename", target.LocalPath)
+			if _, err := downloader.DownloadWithContext(gctx, file, &s3.GetObjectInput{
+				Bucket:    aws.String(bucket),
+				Key:       aws.String(target.RemotePath),
+				VersionId: optionalString(target.VersionID),
+			}); err != nil {
+				return fmt.Errorf("unable to download objects in bucket '%s': %w", bucket, err)
+			}
+			return nil
+		})
 	}
+	return g.Wait()
+}
 
-	return nil
+// optionalString returns nil for the empty string, to leave out optional
+// parameters of requests
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
 }
 
-func (d *ibmS3Downloader) shouldIgnoreObject(object *s3.Object, prefix string) bool {
-	if *object.Size == 0 {
+func (d *ibmS3Downloader) shouldIgnoreObject(object *s3.Object, prefix string, keepEmpty bool) bool {
+	isMarker := *object.Size == 0 && isDirectoryMarker(*object.Key)
+	if *object.Size == 0 && !isMarker && !keepEmpty {
 		d.log.V(1).Info("ignore downloading s3 object of 0 byte size", "s3_path", *object.Key)
 		return true
 	}
-	if strings.HasSuffix(*object.Key, "/") {
+	if strings.HasSuffix(*object.Key, "/") && !isMarker {
 		d.log.V(1).Info("ignore downloading s3 object with trailing '/'", "s3_path", *object.Key)
 		return true
 	}
@@ -148,3 +245,51 @@ func (d *ibmS3Downloader) shouldIgnoreObject(object *s3.Object, prefix string) b
 	}
 	return false
 }
+
+// isFailoverError returns true if the error means that the endpoint could not
+// serve the request, so that another endpoint may succeed
+//
+// Connection errors, server errors and wrong region responses qualify, but
+// not errors about the request itself like missing objects or access denied.
+func isFailoverError(err error) bool {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		return reqErr.StatusCode() >= http.StatusInternalServerError ||
+			reqErr.StatusCode() == http.StatusMovedPermanently ||
+			reqErr.Code() == "AuthorizationHeaderMalformed"
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code() == request.ErrCodeRequestError
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// requestIDFromError returns the id that S3 assigned to the failed request,
+// which is the x-amz-request-id header of the response, or the empty string
+func requestIDFromError(err error) string {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		return reqErr.RequestID()
+	}
+
+	// batch downloads report the errors of the individual objects
+	var batchErr awserr.BatchedErrors
+	if errors.As(err, &batchErr) {
+		for _, objErr := range batchErr.OrigErrs() {
+			if requestID := requestIDFromError(objErr); requestID != "" {
+				return requestID
+			}
+		}
+		return ""
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.OrigErr() != nil {
+		return requestIDFromError(awsErr.OrigErr())
+	}
+	return ""
+}
@@ -15,10 +15,20 @@
 package s3provider
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/IBM/ibm-cos-sdk-go/service/s3"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
 )
 
 func Test_shouldIgnoreObject(t *testing.T) {
@@ -30,6 +40,7 @@ func Test_shouldIgnoreObject(t *testing.T) {
 		objSize      int64
 		objKey       string
 		prefix       string
+		keepEmpty    bool
 		shouldIgnore bool
 	}{
 		// ingore an empty object
@@ -69,6 +80,34 @@ func Test_shouldIgnoreObject(t *testing.T) {
 			prefix:       "path",
 			shouldIgnore: true,
 		},
+		// keep a directory marker, which is an empty object ending with a slash
+		{
+			objSize:      0,
+			objKey:       "path/empty_dir/",
+			prefix:       "path",
+			shouldIgnore: false,
+		},
+		{
+			objSize:      0,
+			objKey:       "path_with_more/",
+			prefix:       "path",
+			shouldIgnore: true,
+		},
+		// keep an empty object when asked to, so that it can be checked
+		{
+			objSize:      0,
+			objKey:       "path/model.onnx",
+			prefix:       "path",
+			keepEmpty:    true,
+			shouldIgnore: false,
+		},
+		{
+			objSize:      0,
+			objKey:       "path_with_more/model.onnx",
+			prefix:       "path",
+			keepEmpty:    true,
+			shouldIgnore: true,
+		},
 	}
 
 	for _, tt := range tableTests {
@@ -77,7 +116,7 @@ func Test_shouldIgnoreObject(t *testing.T) {
 				Size: &tt.objSize,
 				Key:  &tt.objKey,
 			}
-			res := downloader.shouldIgnoreObject(&obj, tt.prefix)
+			res := downloader.shouldIgnoreObject(&obj, tt.prefix, tt.keepEmpty)
 
 			if tt.shouldIgnore && !res {
 				t.Errorf("object should have been ignored but it should have passed")
@@ -88,3 +127,48 @@ func Test_shouldIgnoreObject(t *testing.T) {
 		})
 	}
 }
+
+func Test_downloadBatchInParallel(t *testing.T) {
+	// an S3 endpoint that tracks the number of object requests in flight
+	var inFlight, maxInFlight int32
+	content := []byte("model")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	factory := ibmS3DownloaderFactory{downloaderConcurrency: 10}
+	downloader := factory.newDownloader(zap.New(), "key", "secret", server.URL, "us-east-1", "", pullman.Timeouts{}, pullman.Proxy{}, "")
+
+	dir := t.TempDir()
+	targets := make([]pullman.Target, 6)
+	for i := range targets {
+		targets[i] = pullman.Target{
+			RemotePath: fmt.Sprintf("model/file%d", i),
+			LocalPath:  filepath.Join(dir, fmt.Sprintf("file%d", i)),
+		}
+	}
+	if err := downloader.downloadBatch(context.Background(), "bucket", targets, 3); err != nil {
+		t.Fatalf("Unexpected error downloading the objects: %v", err)
+	}
+
+	if max := atomic.LoadInt32(&maxInFlight); max < 2 || max > 3 {
+		t.Errorf("Expected 2 to 3 objects to be downloaded in parallel, got %d", max)
+	}
+	for _, target := range targets {
+		if got, err := os.ReadFile(target.LocalPath); err != nil || string(got) != string(content) {
+			t.Errorf("Expected the content of %s to be downloaded, got '%s': %v", target.RemotePath, string(got), err)
+		}
+	}
+}
@@ -49,17 +49,17 @@ func (m *Mocks3DownloaderFactory) EXPECT() *Mocks3DownloaderFactoryMockRecorder
 }
 
 // newDownloader mocks base method.
-func (m *Mocks3DownloaderFactory) newDownloader(log logr.Logger, accessKeyID, secretAccessKey, endpoint, region, certificate string) s3Downloader {
+func (m *Mocks3DownloaderFactory) newDownloader(log logr.Logger, accessKeyID, secretAccessKey, endpoint, region, certificate string, timeouts pullman.Timeouts, proxy pullman.Proxy, userAgent string) s3Downloader {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "newDownloader", log, accessKeyID, secretAccessKey, endpoint, region, certificate)
+	ret := m.ctrl.Call(m, "newDownloader", log, accessKeyID, secretAccessKey, endpoint, region, certificate, timeouts, proxy, userAgent)
 	ret0, _ := ret[0].(s3Downloader)
 	return ret0
 }
 
 // newDownloader indicates an expected call of newDownloader.
-func (mr *Mocks3DownloaderFactoryMockRecorder) newDownloader(log, accessKeyID, secretAccessKey, endpoint, region, certificate interface{}) *gomock.Call {
+func (mr *Mocks3DownloaderFactoryMockRecorder) newDownloader(log, accessKeyID, secretAccessKey, endpoint, region, certificate, timeouts, proxy, userAgent interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "newDownloader", reflect.TypeOf((*Mocks3DownloaderFactory)(nil).newDownloader), log, accessKeyID, secretAccessKey, endpoint, region, certificate)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "newDownloader", reflect.TypeOf((*Mocks3DownloaderFactory)(nil).newDownloader), log, accessKeyID, secretAccessKey, endpoint, region, certificate, timeouts, proxy, userAgent)
 }
 
 // Mocks3Downloader is a mock of s3Downloader interface.
@@ -86,30 +86,75 @@ func (m *Mocks3Downloader) EXPECT() *Mocks3DownloaderMockRecorder {
 }
 
 // downloadBatch mocks base method.
-func (m *Mocks3Downloader) downloadBatch(ctx context.Context, bucket string, targets []pullman.Target) error {
+func (m *Mocks3Downloader) downloadBatch(ctx context.Context, bucket string, targets []pullman.Target, concurrency int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "downloadBatch", ctx, bucket, targets)
+	ret := m.ctrl.Call(m, "downloadBatch", ctx, bucket, targets, concurrency)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // downloadBatch indicates an expected call of downloadBatch.
-func (mr *Mocks3DownloaderMockRecorder) downloadBatch(ctx, bucket, targets interface{}) *gomock.Call {
+func (mr *Mocks3DownloaderMockRecorder) downloadBatch(ctx, bucket, targets, concurrency interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "downloadBatch", reflect.TypeOf((*Mocks3Downloader)(nil).downloadBatch), ctx, bucket, targets)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "downloadBatch", reflect.TypeOf((*Mocks3Downloader)(nil).downloadBatch), ctx, bucket, targets, concurrency)
+}
+
+// getObjectChecksum mocks base method.
+func (m *Mocks3Downloader) getObjectChecksum(ctx context.Context, bucket, key, versionID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getObjectChecksum", ctx, bucket, key, versionID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// getObjectChecksum indicates an expected call of getObjectChecksum.
+func (mr *Mocks3DownloaderMockRecorder) getObjectChecksum(ctx, bucket, key, versionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getObjectChecksum", reflect.TypeOf((*Mocks3Downloader)(nil).getObjectChecksum), ctx, bucket, key, versionID)
+}
+
+// getObjectTags mocks base method.
+func (m *Mocks3Downloader) getObjectTags(ctx context.Context, bucket, key, versionID string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getObjectTags", ctx, bucket, key, versionID)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// getObjectTags indicates an expected call of getObjectTags.
+func (mr *Mocks3DownloaderMockRecorder) getObjectTags(ctx, bucket, key, versionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getObjectTags", reflect.TypeOf((*Mocks3Downloader)(nil).getObjectTags), ctx, bucket, key, versionID)
+}
+
+// getObjectVersion mocks base method.
+func (m *Mocks3Downloader) getObjectVersion(ctx context.Context, bucket, key, versionID string) (s3Object, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getObjectVersion", ctx, bucket, key, versionID)
+	ret0, _ := ret[0].(s3Object)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// getObjectVersion indicates an expected call of getObjectVersion.
+func (mr *Mocks3DownloaderMockRecorder) getObjectVersion(ctx, bucket, key, versionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getObjectVersion", reflect.TypeOf((*Mocks3Downloader)(nil).getObjectVersion), ctx, bucket, key, versionID)
 }
 
 // listObjects mocks base method.
-func (m *Mocks3Downloader) listObjects(bucket, prefix string) ([]string, error) {
+func (m *Mocks3Downloader) listObjects(bucket, prefix string, keepEmpty bool) ([]s3Object, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "listObjects", bucket, prefix)
-	ret0, _ := ret[0].([]string)
+	ret := m.ctrl.Call(m, "listObjects", bucket, prefix, keepEmpty)
+	ret0, _ := ret[0].([]s3Object)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // listObjects indicates an expected call of listObjects.
-func (mr *Mocks3DownloaderMockRecorder) listObjects(bucket, prefix interface{}) *gomock.Call {
+func (mr *Mocks3DownloaderMockRecorder) listObjects(bucket, prefix, keepEmpty interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "listObjects", reflect.TypeOf((*Mocks3Downloader)(nil).listObjects), bucket, prefix)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "listObjects", reflect.TypeOf((*Mocks3Downloader)(nil).listObjects), bucket, prefix, keepEmpty)
 }
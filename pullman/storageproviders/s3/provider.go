@@ -16,9 +16,17 @@ package s3provider
 
 import (
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"os"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/go-logr/logr"
@@ -34,6 +42,12 @@ const (
 	configRegion          = "region"
 	configBucket          = "bucket"
 	configCertificate     = "certificate"
+	configEndpoints       = "endpoints"
+	configTagFilter       = "tag_filter"
+	configVerifyChecksums = "verify_checksums"
+	configVersionID       = "version_id"
+
+	missingRequiredStringConfigTemplate = "missing required string configuration '%s'"
 )
 
 // interfaces
@@ -41,18 +55,44 @@ const (
 // s3DownloaderFactory is the interface used create s3 downloaders
 // useful to mock for testing
 type s3DownloaderFactory interface {
-	newDownloader(log logr.Logger, accessKeyID, secretAccessKey, endpoint, region, certificate string) s3Downloader
+	newDownloader(log logr.Logger, accessKeyID, secretAccessKey, endpoint, region, certificate string, timeouts pullman.Timeouts, proxy pullman.Proxy, userAgent string) s3Downloader
 }
 
 // s3Downloader is the interface used to download resources from s3
 // useful to mock for testing
 type s3Downloader interface {
-	listObjects(bucket, prefix string) ([]string, error)
-	downloadBatch(ctx context.Context, bucket string, targets []pullman.Target) error
+	// listObjects returns the objects to download under the prefix,
+	// including directory markers, and empty objects if keepEmpty is set
+	listObjects(bucket, prefix string, keepEmpty bool) ([]s3Object, error)
+	// a concurrency that is not positive uses the downloader's default
+	downloadBatch(ctx context.Context, bucket string, targets []pullman.Target, concurrency int) error
+	// getObjectVersion returns a version of an object, which may not be the
+	// latest one that is listed
+	getObjectVersion(ctx context.Context, bucket, key, versionID string) (s3Object, error)
+	// getObjectTags returns the tag set of an object, of its latest version
+	// if the versionID is empty
+	getObjectTags(ctx context.Context, bucket, key, versionID string) (map[string]string, error)
+	// getObjectChecksum returns the base64 encoded SHA256 checksum that S3
+	// stored for an object, or the empty string if it has none
+	getObjectChecksum(ctx context.Context, bucket, key, versionID string) (string, error)
 }
 
 // structs
 
+type s3Object struct {
+	key  string
+	etag string
+	size int64
+	// empty for the latest version
+	versionID string
+}
+
+// s3Endpoint is one of the endpoints that can serve the repository
+type s3Endpoint struct {
+	endpoint string
+	region   string
+}
+
 type s3Provider struct {
 	s3DownloaderFactory s3DownloaderFactory
 }
@@ -66,16 +106,21 @@ func (p s3Provider) GetKey(config pullman.Config) string {
 	// no need to validate the config here
 	accessKeyID, _ := pullman.GetString(config, configAccessKeyID)
 	secretAccessKey, _ := pullman.GetString(config, configSecretAccessKey)
-	endpoint, _ := pullman.GetString(config, configEndpoint)
-	region, _ := pullman.GetString(config, configRegion)
 	certificate, _ := pullman.GetString(config, configCertificate)
+	timeouts, _ := pullman.GetTimeouts(config)
+	proxy, _ := pullman.GetProxy(config)
 
-	return pullman.HashStrings(endpoint, region, accessKeyID, secretAccessKey, certificate)
+	values := []string{accessKeyID, secretAccessKey, certificate, timeouts.String(), proxy.String(), pullman.GetUserAgent(config)}
+	if endpoints, err := getEndpoints(config); err == nil {
+		for _, e := range endpoints {
+			values = append(values, e.endpoint, e.region)
+		}
+	}
+
+	return pullman.HashStrings(values...)
 }
 
 func (p s3Provider) NewRepository(config pullman.Config, log logr.Logger) (pullman.RepositoryClient, error) {
-	missingRequiredStringConfigTemplate := "missing required string configuration '%s'"
-
 	accessKeyID, ok := pullman.GetString(config, configAccessKeyID)
 	if !ok {
 		return nil, fmt.Errorf(missingRequiredStringConfigTemplate, configAccessKeyID)
@@ -86,58 +131,234 @@ func (p s3Provider) NewRepository(config pullman.Config, log logr.Logger) (pullm
 		return nil, fmt.Errorf(missingRequiredStringConfigTemplate, configSecretAccessKey)
 	}
 
-	endpoint, ok := pullman.GetString(config, configEndpoint)
-	if !ok {
-		return nil, fmt.Errorf(missingRequiredStringConfigTemplate, configEndpoint)
-	}
-
-	region, ok := pullman.GetString(config, configRegion)
-	if !ok {
-		return nil, fmt.Errorf(missingRequiredStringConfigTemplate, configRegion)
+	endpoints, err := getEndpoints(config)
+	if err != nil {
+		return nil, err
 	}
 
 	// certificate is optional
 	certificate, _ := pullman.GetString(config, configCertificate)
 
+	timeouts, err := pullman.GetTimeouts(config)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := pullman.GetProxy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent := pullman.GetUserAgent(config)
+
+	s3clients := make([]s3Downloader, len(endpoints))
+	for i, e := range endpoints {
+		s3clients[i] = p.s3DownloaderFactory.newDownloader(log, accessKeyID, secretAccessKey, e.endpoint, e.region, certificate, timeouts, proxy, userAgent)
+	}
+
 	return &s3RepositoryClient{
-		s3client: p.s3DownloaderFactory.newDownloader(log, accessKeyID, secretAccessKey, endpoint, region, certificate),
-		log:      log,
+		s3clients: s3clients,
+		endpoints: endpoints,
+		log:       log,
 	}, nil
 
 }
 
+// getEndpoints returns the endpoints to try in order
+//
+// Without the `endpoints` list, the single endpoint is configured with
+// `endpoint_url` and `region`. Entries in `endpoints` are either an endpoint
+// URL or an object with `endpoint_url` and `region`; a missing region defaults
+// to the value of `region`.
+func getEndpoints(config pullman.Config) ([]s3Endpoint, error) {
+	defaultRegion, hasDefaultRegion := pullman.GetString(config, configRegion)
+
+	val, exists := config.Get(configEndpoints)
+	if !exists {
+		endpoint, ok := pullman.GetString(config, configEndpoint)
+		if !ok {
+			return nil, fmt.Errorf(missingRequiredStringConfigTemplate, configEndpoint)
+		}
+		if !hasDefaultRegion {
+			return nil, fmt.Errorf(missingRequiredStringConfigTemplate, configRegion)
+		}
+		return []s3Endpoint{{endpoint: endpoint, region: defaultRegion}}, nil
+	}
+
+	list, ok := val.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("configuration '%s' must be a non-empty list", configEndpoints)
+	}
+
+	endpoints := make([]s3Endpoint, 0, len(list))
+	for i, entry := range list {
+		e := s3Endpoint{region: defaultRegion}
+		switch v := entry.(type) {
+		case string:
+			e.endpoint = v
+		case map[string]interface{}:
+			e.endpoint, _ = v[configEndpoint].(string)
+			if region, ok := v[configRegion].(string); ok {
+				e.region = region
+			}
+		default:
+			return nil, fmt.Errorf("entry %d of configuration '%s' must be a string or an object", i, configEndpoints)
+		}
+
+		if e.endpoint == "" {
+			return nil, fmt.Errorf("entry %d of configuration '%s' is missing '%s'", i, configEndpoints, configEndpoint)
+		}
+		if e.region == "" {
+			return nil, fmt.Errorf("entry %d of configuration '%s' is missing '%s' and no default is configured", i, configEndpoints, configRegion)
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	return endpoints, nil
+}
+
 type s3RepositoryClient struct {
-	s3client s3Downloader
-	log      logr.Logger
+	// one client per endpoint, in the order they are tried
+	s3clients []s3Downloader
+	endpoints []s3Endpoint
+	log       logr.Logger
 }
 
-// s3RepositoryClient implements RepositoryClient
+// s3RepositoryClient implements RepositoryClient and RepositoryLister
 var _ pullman.RepositoryClient = (*s3RepositoryClient)(nil)
+var _ pullman.RepositoryLister = (*s3RepositoryClient)(nil)
 
+// Pull downloads the targets from the first endpoint that can be reached
+//
+// The next endpoint is only tried if the error is connection or region
+// related, see isFailoverError; other errors, like missing objects, are
+// returned as is.
 func (r *s3RepositoryClient) Pull(ctx context.Context, pc pullman.PullCommand) error {
-	destDir := pc.Directory
-	targets := pc.Targets
-
 	// process per-command configuration
 	bucket, ok := pullman.GetString(pc.RepositoryConfig, configBucket)
 	if !ok {
 		// we need a value for bucket
 		return errors.New("required configuration 'bucket' missing from command")
 	}
+	tagFilter, err := getTagFilter(pc.RepositoryConfig)
+	if err != nil {
+		return err
+	}
+	verifyChecksums, err := getVerifyChecksums(pc.RepositoryConfig)
+	if err != nil {
+		return err
+	}
+	if versionID, _ := pullman.GetString(pc.RepositoryConfig, configVersionID); versionID != "" {
+		pc.Targets = withVersionID(pc.Targets, versionID)
+	}
+
+	for i, s3client := range r.s3clients {
+		if err = r.pull(ctx, s3client, bucket, tagFilter, verifyChecksums, pc); err == nil || !isFailoverError(err) {
+			return err
+		}
+		if i < len(r.s3clients)-1 {
+			r.log.Info("failed to pull from endpoint, trying the next one", "endpoint", r.endpoints[i].endpoint,
+				"next_endpoint", r.endpoints[i+1].endpoint, "error", err.Error())
+		}
+	}
+
+	return err
+}
+
+// List returns the objects under the prefix from the first endpoint that can
+// be reached, without the directory markers
+//
+// The tag_filter of the config is not applied, as that takes a request per
+// object.
+func (r *s3RepositoryClient) List(ctx context.Context, lc pullman.ListCommand) ([]pullman.ObjectInfo, error) {
+	bucket, ok := pullman.GetString(lc.RepositoryConfig, configBucket)
+	if !ok {
+		return nil, errors.New("required configuration 'bucket' missing from command")
+	}
+
+	var objects []s3Object
+	var err error
+	for i, s3client := range r.s3clients {
+		if objects, err = s3client.listObjects(bucket, lc.Prefix, false); err == nil || !isFailoverError(err) {
+			break
+		}
+		if i < len(r.s3clients)-1 {
+			r.log.Info("failed to list objects from endpoint, trying the next one", "endpoint", r.endpoints[i].endpoint,
+				"next_endpoint", r.endpoints[i+1].endpoint, "error", err.Error())
+		}
+	}
+	if err != nil {
+		return nil, pullman.WithRequestID(fmt.Errorf("unable to list objects in bucket '%s': %w", bucket, err), requestIDFromError(err))
+	}
+
+	infos := make([]pullman.ObjectInfo, 0, len(objects))
+	for _, object := range objects {
+		if !isDirectoryMarker(object.key) {
+			infos = append(infos, pullman.ObjectInfo{Path: object.key, Size: object.size})
+		}
+	}
+	return infos, nil
+}
+
+func (r *s3RepositoryClient) pull(ctx context.Context, s3client s3Downloader, bucket string, tagFilter map[string]string, verifyChecksums bool, pc pullman.PullCommand) error {
+	destDir := pc.Directory
+	targets := pc.Targets
 
 	// resolve full paths of objects to download and local paths for the resulting files
 	//  mainly, this means resolving the objects referenced by a "directory" in s3
 	resolvedTargets := make([]pullman.Target, 0, len(targets))
+	// keys of the downloaded files to add to the artifact cache
+	cacheKeys := map[string]string{}
+	// objects of the downloaded files to verify
+	verifyObjects := map[string]s3Object{}
 	for _, pt := range targets {
-		objPaths, err := r.s3client.listObjects(bucket, pt.RemotePath)
-		if err != nil {
-			return fmt.Errorf("unable to list objects in bucket '%s': %w", bucket, err)
+		var objects []s3Object
+		if pt.VersionID != "" {
+			// a version is of a single object, which is not listed if it was
+			// deleted since
+			object, err := s3client.getObjectVersion(ctx, bucket, pt.RemotePath, pt.VersionID)
+			if err != nil {
+				return pullman.WithRequestID(fmt.Errorf("unable to get version '%s' of object '%s' in bucket '%s': %w", pt.VersionID, pt.RemotePath, bucket, err), requestIDFromError(err))
+			}
+			objects = []s3Object{object}
+		} else {
+			var err error
+			if objects, err = s3client.listObjects(bucket, pt.RemotePath, pc.KeepEmptyFiles); err != nil {
+				return pullman.WithRequestID(fmt.Errorf("unable to list objects in bucket '%s': %w", bucket, err), requestIDFromError(err))
+			}
 		}
-		r.log.V(1).Info("found objects to download", "path", pt.RemotePath, "count", len(objPaths))
+		r.log.V(1).Info("found objects to download", "path", pt.RemotePath, "count", len(objects))
 
-		for _, objPath := range objPaths {
+		// the objects of the target that were filtered out by their tags, to
+		// fail a target of which none match instead of pulling nothing
+		filteredOut, matched := 0, 0
+		for _, object := range objects {
+			objPath := object.key
 			localPath := pt.LocalPath
 			relativePath := strings.TrimPrefix(objPath, pt.RemotePath)
+			if isDirectoryMarker(objPath) {
+				// there is nothing to download, but the runtime may expect the directory to exist
+				dirPath, joinErr := util.SecureJoin(destDir, localPath, relativePath)
+				if joinErr != nil {
+					return fmt.Errorf("error joining filepaths '%s' and '%s': %w", pt.LocalPath, relativePath, joinErr)
+				}
+				if mkdirErr := os.MkdirAll(dirPath, 0755); mkdirErr != nil {
+					return fmt.Errorf("unable to create directory '%s' for marker '%s': %w", dirPath, objPath, mkdirErr)
+				}
+				continue
+			}
+			if len(tagFilter) > 0 {
+				tags, tagsErr := s3client.getObjectTags(ctx, bucket, objPath, object.versionID)
+				if tagsErr != nil {
+					return pullman.WithRequestID(fmt.Errorf("unable to get the tags of object '%s' in bucket '%s': %w", objPath, bucket, tagsErr), requestIDFromError(tagsErr))
+				}
+				if !matchesTagFilter(tags, tagFilter) {
+					r.log.V(1).Info("skipping object that does not match the tag filter", "path", objPath)
+					filteredOut++
+					continue
+				}
+			}
+			matched++
 			// handle case where the remote path is a single object
 			if relativePath == "" {
 				// allow renaming of the file
@@ -153,22 +374,204 @@ func (r *s3RepositoryClient) Pull(ctx context.Context, pc pullman.PullCommand) e
 				return fmt.Errorf("error joining filepaths '%s' and '%s': %w", pt.LocalPath, relativePath, joinErr)
 			}
 
+			// small objects shared between models are only downloaded once
+			var cacheKey string
+			if object.etag != "" && pc.ArtifactCache.Cacheable(object.size) {
+				cacheKey = artifactKey(object)
+				linked, linkErr := pc.ArtifactCache.Link(cacheKey, filePath)
+				if linkErr != nil {
+					r.log.Info("unable to use cached artifact, downloading it", "path", objPath, "error", linkErr.Error())
+				} else if linked {
+					r.log.V(1).Info("using cached artifact", "path", objPath, "filename", filePath)
+					continue
+				}
+			}
+
 			t := pullman.Target{
 				RemotePath: objPath,
 				LocalPath:  filePath,
+				VersionID:  object.versionID,
 			}
 			resolvedTargets = append(resolvedTargets, t)
+			if cacheKey != "" {
+				cacheKeys[filePath] = cacheKey
+			}
+			if verifyChecksums {
+				verifyObjects[filePath] = object
+			}
+		}
+		if filteredOut > 0 && matched == 0 {
+			return fmt.Errorf("none of the %d objects at '%s' in bucket '%s' match the tag filter", filteredOut, pt.RemotePath, bucket)
 		}
 	}
 
-	downloadErr := r.s3client.downloadBatch(ctx, bucket, resolvedTargets)
+	downloadErr := s3client.downloadBatch(ctx, bucket, resolvedTargets, pc.Concurrency)
 	if downloadErr != nil {
-		return fmt.Errorf("unable to download objects in bucket '%s': %w", bucket, downloadErr)
+		return pullman.WithRequestID(fmt.Errorf("unable to download objects in bucket '%s': %w", bucket, downloadErr), requestIDFromError(downloadErr))
+	}
+
+	// files are verified before they are cached, so cached files are not
+	// verified again
+	for filePath, object := range verifyObjects {
+		if err := r.verifyChecksum(ctx, s3client, bucket, object, filePath); err != nil {
+			return err
+		}
+	}
+
+	for filePath, cacheKey := range cacheKeys {
+		if err := pc.ArtifactCache.Store(cacheKey, filePath); err != nil {
+			r.log.Info("unable to cache artifact", "filename", filePath, "error", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// withVersionID returns the targets with the versionID set on those that do
+// not have their own VersionID
+func withVersionID(targets []pullman.Target, versionID string) []pullman.Target {
+	versioned := make([]pullman.Target, len(targets))
+	for i, t := range targets {
+		if t.VersionID == "" {
+			t.VersionID = versionID
+		}
+		versioned[i] = t
+	}
+	return versioned
+}
+
+// getTagFilter returns the tags that objects must have to be pulled, from the
+// optional `tag_filter` object of tag keys to values
+func getTagFilter(config pullman.Config) (map[string]string, error) {
+	val, exists := config.Get(configTagFilter)
+	if !exists || val == nil {
+		return nil, nil
+	}
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("configuration '%s' must be an object of tag keys to values", configTagFilter)
+	}
+	tagFilter := make(map[string]string, len(m))
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("value of tag '%s' in configuration '%s' must be a string", k, configTagFilter)
+		}
+		tagFilter[k] = s
 	}
+	return tagFilter, nil
+}
+
+// matchesTagFilter returns true if the tags include every tag of the filter
+func matchesTagFilter(tags, tagFilter map[string]string) bool {
+	for k, v := range tagFilter {
+		if tag, ok := tags[k]; !ok || tag != v {
+			return false
+		}
+	}
+	return true
+}
 
+// getVerifyChecksums returns the optional `verify_checksums` flag, which can
+// be a boolean or a string like "true"
+func getVerifyChecksums(config pullman.Config) (bool, error) {
+	val, exists := config.Get(configVerifyChecksums)
+	if !exists || val == nil {
+		return false, nil
+	}
+	switch v := val.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, nil
+		}
+	}
+	return false, fmt.Errorf("configuration '%s' must be a boolean", configVerifyChecksums)
+}
+
+// verifyChecksum checks a downloaded file against the checksum of its object
+//
+// The ETag of an object uploaded in a single part is the MD5 of its content,
+// but the ETag of a multipart upload has a "-<parts>" suffix and is not. For
+// these, the SHA256 checksum that S3 stores for objects uploaded with one is
+// verified instead, if it is a checksum of the whole object. Objects without
+// a usable checksum are not verified.
+func (r *s3RepositoryClient) verifyChecksum(ctx context.Context, s3client s3Downloader, bucket string, object s3Object, filePath string) error {
+	etag := strings.Trim(object.etag, `"`)
+	if isMD5ETag(etag) {
+		actual, err := fileChecksum(filePath, md5.New())
+		if err != nil {
+			return fmt.Errorf("unable to compute the MD5 checksum of file '%s': %w", filePath, err)
+		}
+		if hex.EncodeToString(actual) != strings.ToLower(etag) {
+			return fmt.Errorf("MD5 checksum of object '%s' in bucket '%s' does not match its ETag '%s'", object.key, bucket, etag)
+		}
+		return nil
+	}
+
+	checksum, err := s3client.getObjectChecksum(ctx, bucket, object.key, object.versionID)
+	if err != nil {
+		return pullman.WithRequestID(fmt.Errorf("unable to get the checksum of object '%s' in bucket '%s': %w", object.key, bucket, err), requestIDFromError(err))
+	}
+	// the checksum of a multipart upload is a checksum of the checksums of
+	// its parts, with a "-<parts>" suffix
+	if checksum == "" || strings.Contains(checksum, "-") {
+		r.log.V(1).Info("skipping checksum verification of object without a checksum of its content", "path", object.key, "etag", etag)
+		return nil
+	}
+	actual, err := fileChecksum(filePath, sha256.New())
+	if err != nil {
+		return fmt.Errorf("unable to compute the SHA256 checksum of file '%s': %w", filePath, err)
+	}
+	if base64.StdEncoding.EncodeToString(actual) != checksum {
+		return fmt.Errorf("SHA256 checksum of object '%s' in bucket '%s' does not match its checksum '%s'", object.key, bucket, checksum)
+	}
 	return nil
 }
 
+// artifactKey returns the key of an object in the ArtifactCache
+//
+// An MD5 ETag is keyed as the MD5 checksum of the object, so the object is
+// shared with the same file from any repository. Other ETags are derived from
+// the content of the object as well, like the ETag of a multipart upload from
+// the MD5 checksums of its parts.
+func artifactKey(object s3Object) string {
+	etag := strings.Trim(object.etag, `"`)
+	if isMD5ETag(etag) {
+		return pullman.ArtifactKey("md5", etag, object.size)
+	}
+	return pullman.ArtifactKey("s3-etag", etag, object.size)
+}
+
+// isMD5ETag returns true if the ETag, without quotes, is the MD5 of a single
+// part upload
+func isMD5ETag(etag string) bool {
+	if len(etag) != 2*md5.Size {
+		return false
+	}
+	_, err := hex.DecodeString(etag)
+	return err == nil
+}
+
+func fileChecksum(filePath string, h hash.Hash) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// isDirectoryMarker returns true for keys ending with a '/', which are
+// created by some tools to represent a directory, possibly an empty one
+func isDirectoryMarker(key string) bool {
+	return strings.HasSuffix(key, "/")
+}
+
 func init() {
 	p := s3Provider{
 		s3DownloaderFactory: ibmS3DownloaderFactory{
@@ -16,9 +16,15 @@ package s3provider
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/IBM/ibm-cos-sdk-go/aws/awserr"
+	"github.com/IBM/ibm-cos-sdk-go/aws/request"
 	"github.com/golang/mock/gomock"
 	"github.com/kserve/modelmesh-runtime-adapter/pullman"
 	"github.com/stretchr/testify/assert"
@@ -32,13 +38,23 @@ func newS3RepositoryClientWithMock(t *testing.T) (*s3RepositoryClient, *Mocks3Do
 
 	log := zap.New()
 	s3rc := s3RepositoryClient{
-		s3client: mdf,
-		log:      log,
+		s3clients: []s3Downloader{mdf},
+		endpoints: []s3Endpoint{{endpoint: "https://s3.example.service", region: "region"}},
+		log:       log,
 	}
 
 	return &s3rc, mdf
 }
 
+// objectsWithKeys returns objects without an ETag, which are not cached
+func objectsWithKeys(keys ...string) []s3Object {
+	objects := make([]s3Object, 0, len(keys))
+	for _, key := range keys {
+		objects = append(objects, s3Object{key: key, size: 1})
+	}
+	return objects
+}
+
 func Test_Download_SimpleDirectory(t *testing.T) {
 	s3rc, mdf := newS3RepositoryClientWithMock(t)
 
@@ -57,8 +73,8 @@ func Test_Download_SimpleDirectory(t *testing.T) {
 		},
 	}
 
-	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("path/to/modeldir")).
-		Return([]string{"path/to/modeldir/file.ext", "path/to/modeldir/subdir/another_file"}, nil).
+	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("path/to/modeldir"), gomock.Any()).
+		Return(objectsWithKeys("path/to/modeldir/file.ext", "path/to/modeldir/subdir/another_file"), nil).
 		Times(1)
 
 	expectedTargets := []pullman.Target{
@@ -71,7 +87,7 @@ func Test_Download_SimpleDirectory(t *testing.T) {
 			LocalPath:  filepath.Join(downloadDir, "subdir", "another_file"),
 		},
 	}
-	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Eq(expectedTargets)).
+	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Eq(expectedTargets), gomock.Eq(0)).
 		Return(nil).
 		Times(1)
 
@@ -117,20 +133,20 @@ func Test_Download_MultipleTargets(t *testing.T) {
 		},
 	}
 
-	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("dir")).
-		Return([]string{"dir/file1", "dir/file2"}, nil).
+	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("dir"), gomock.Any()).
+		Return(objectsWithKeys("dir/file1", "dir/file2"), nil).
 		Times(1)
-	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("some_file")).
-		Return([]string{"some_file"}, nil).
+	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("some_file"), gomock.Any()).
+		Return(objectsWithKeys("some_file"), nil).
 		Times(1)
-	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("another_dir")).
-		Return([]string{"another_dir/another_file", "another_dir/subdir1/subdir2/nested_file"}, nil).
+	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("another_dir"), gomock.Any()).
+		Return(objectsWithKeys("another_dir/another_file", "another_dir/subdir1/subdir2/nested_file"), nil).
 		Times(1)
-	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("another_file")).
-		Return([]string{"another_file"}, nil).
+	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("another_file"), gomock.Any()).
+		Return(objectsWithKeys("another_file"), nil).
 		Times(1)
-	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("yet_another_file")).
-		Return([]string{"yet_another_file"}, nil).
+	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("yet_another_file"), gomock.Any()).
+		Return(objectsWithKeys("yet_another_file"), nil).
 		Times(1)
 
 	expectedTargets := []pullman.Target{
@@ -163,7 +179,7 @@ func Test_Download_MultipleTargets(t *testing.T) {
 			LocalPath:  filepath.Join(downloadDir, "local_another_dir", "yet_another_file"),
 		},
 	}
-	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq("bucket"), gomock.Eq(expectedTargets)).
+	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq("bucket"), gomock.Eq(expectedTargets), gomock.Eq(0)).
 		Return(nil).
 		Times(1)
 
@@ -171,6 +187,125 @@ func Test_Download_MultipleTargets(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func newFailoverS3RepositoryClientWithMocks(t *testing.T) (*s3RepositoryClient, *Mocks3Downloader, *Mocks3Downloader) {
+	mockCtrl := gomock.NewController(t)
+
+	primary := NewMocks3Downloader(mockCtrl)
+	secondary := NewMocks3Downloader(mockCtrl)
+
+	log := zap.New()
+	s3rc := s3RepositoryClient{
+		s3clients: []s3Downloader{primary, secondary},
+		endpoints: []s3Endpoint{
+			{endpoint: "https://s3.us-east.example.service", region: "us-east"},
+			{endpoint: "https://s3.eu-west.example.service", region: "eu-west"},
+		},
+		log: log,
+	}
+
+	return &s3rc, primary, secondary
+}
+
+func Test_Download_FailoverToSecondaryEndpoint(t *testing.T) {
+	s3rc, primary, secondary := newFailoverS3RepositoryClientWithMocks(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("s3", nil)
+	c.Set("bucket", bucket)
+
+	downloadDir := filepath.Join("test", "output")
+	inputPullCommand := pullman.PullCommand{
+		RepositoryConfig: c,
+		Directory:        downloadDir,
+		Targets: []pullman.Target{
+			{
+				RemotePath: "path/to/model.zip",
+			},
+		},
+	}
+
+	// the primary endpoint is down
+	primary.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("path/to/model.zip"), gomock.Any()).
+		Return(nil, awserr.New(request.ErrCodeRequestError, "send request failed", nil)).
+		Times(1)
+
+	secondary.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("path/to/model.zip"), gomock.Any()).
+		Return(objectsWithKeys("path/to/model.zip"), nil).
+		Times(1)
+	expectedTargets := []pullman.Target{
+		{
+			RemotePath: "path/to/model.zip",
+			LocalPath:  filepath.Join(downloadDir, "model.zip"),
+		},
+	}
+	secondary.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Eq(expectedTargets), gomock.Eq(0)).
+		Return(nil).
+		Times(1)
+
+	err := s3rc.Pull(context.Background(), inputPullCommand)
+	assert.NoError(t, err)
+}
+
+func Test_Download_NoFailoverForMissingBucket(t *testing.T) {
+	s3rc, primary, _ := newFailoverS3RepositoryClientWithMocks(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("s3", nil)
+	c.Set("bucket", bucket)
+
+	inputPullCommand := pullman.PullCommand{
+		RepositoryConfig: c,
+		Directory:        filepath.Join("test", "output"),
+		Targets: []pullman.Target{
+			{
+				RemotePath: "path/to/model.zip",
+			},
+		},
+	}
+
+	// a 404 is returned as is, the secondary endpoint is not tried
+	primary.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("path/to/model.zip"), gomock.Any()).
+		Return(nil, awserr.NewRequestFailure(awserr.New("NoSuchBucket", "The specified bucket does not exist", nil), 404, "")).
+		Times(1)
+
+	err := s3rc.Pull(context.Background(), inputPullCommand)
+	assert.ErrorContains(t, err, "NoSuchBucket")
+}
+
+func Test_NewRepository_Endpoints(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mdf := NewMocks3DownloaderFactory(mockCtrl)
+	provider := s3Provider{s3DownloaderFactory: mdf}
+	log := zap.New()
+
+	config := pullman.NewRepositoryConfig("s3", nil)
+	config.Set(configAccessKeyID, "access key")
+	config.Set(configSecretAccessKey, "secret key")
+	config.Set(configRegion, "us-east")
+	config.Set(configEndpoints, []interface{}{
+		"https://s3.us-east.example.service",
+		map[string]interface{}{
+			configEndpoint: "https://s3.eu-west.example.service",
+			configRegion:   "eu-west",
+		},
+	})
+
+	// a client is created for each endpoint, in order
+	gomock.InOrder(
+		mdf.EXPECT().newDownloader(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Eq("https://s3.us-east.example.service"),
+			gomock.Eq("us-east"), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(1),
+		mdf.EXPECT().newDownloader(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Eq("https://s3.eu-west.example.service"),
+			gomock.Eq("eu-west"), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(1),
+	)
+	_, err := provider.NewRepository(config, log)
+	assert.NoError(t, err)
+
+	// entries need an endpoint
+	config.Set(configEndpoints, []interface{}{map[string]interface{}{configRegion: "eu-west"}})
+	_, err = provider.NewRepository(config, log)
+	assert.Error(t, err)
+}
+
 func Test_GetKey(t *testing.T) {
 	provider := s3Provider{}
 
@@ -202,6 +337,24 @@ func Test_GetKey(t *testing.T) {
 		assert.NotEqual(t, provider.GetKey(config1), provider.GetKey(config2))
 	})
 
+	// adding a failover endpoint should change the key
+	t.Run("shouldChangeForEndpoints", func(t *testing.T) {
+		config1 := createTestConfig()
+		config2 := createTestConfig()
+		config2.Set(configEndpoints, []interface{}{"https://s3.example.service", "https://s3.secondary.service"})
+
+		assert.NotEqual(t, provider.GetKey(config1), provider.GetKey(config2))
+	})
+
+	// changing a timeout should change the key
+	t.Run("shouldChangeForTimeouts", func(t *testing.T) {
+		config1 := createTestConfig()
+		config2 := createTestConfig()
+		config2.Set(pullman.ConfigRequestTimeout, "30s")
+
+		assert.NotEqual(t, provider.GetKey(config1), provider.GetKey(config2))
+	})
+
 	// changing the bucket should NOT change the key
 	t.Run("shouldNotChangeForBucket", func(t *testing.T) {
 		config1 := createTestConfig()
@@ -211,3 +364,488 @@ func Test_GetKey(t *testing.T) {
 		assert.Equal(t, provider.GetKey(config1), provider.GetKey(config2))
 	})
 }
+
+func Test_NewRepository_Timeouts(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mdf := NewMocks3DownloaderFactory(mockCtrl)
+	provider := s3Provider{s3DownloaderFactory: mdf}
+	log := zap.New()
+
+	createTestConfig := func() *pullman.RepositoryConfig {
+		config := pullman.NewRepositoryConfig("s3", nil)
+		config.Set(configAccessKeyID, "access key")
+		config.Set(configSecretAccessKey, "secret key")
+		config.Set(configEndpoint, "https://s3.example.service")
+		config.Set(configRegion, "region")
+		return config
+	}
+
+	// defaults are left to the SDK
+	mdf.EXPECT().newDownloader(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+		gomock.Eq(pullman.Timeouts{}), gomock.Any(), gomock.Any()).Times(1)
+	_, err := provider.NewRepository(createTestConfig(), log)
+	assert.NoError(t, err)
+
+	// configured timeouts are passed to the downloader
+	config := createTestConfig()
+	config.Set(pullman.ConfigConnectTimeout, "5s")
+	config.Set(pullman.ConfigRequestTimeout, float64(60))
+	mdf.EXPECT().newDownloader(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+		gomock.Eq(pullman.Timeouts{Connect: 5 * time.Second, Request: 60 * time.Second}), gomock.Any(), gomock.Any()).Times(1)
+	_, err = provider.NewRepository(config, log)
+	assert.NoError(t, err)
+
+	// a configured proxy is passed to the downloader
+	config = createTestConfig()
+	config.Set(pullman.ConfigProxyURL, "http://proxy.example.com:3128")
+	config.Set(pullman.ConfigNoProxy, ".internal.example.com")
+	mdf.EXPECT().newDownloader(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+		gomock.Any(), gomock.Eq(pullman.Proxy{URL: "http://proxy.example.com:3128", NoProxy: ".internal.example.com"}), gomock.Any()).Times(1)
+	_, err = provider.NewRepository(config, log)
+	assert.NoError(t, err)
+
+	// invalid timeouts are an error
+	config = createTestConfig()
+	config.Set(pullman.ConfigRequestTimeout, "soon")
+	_, err = provider.NewRepository(config, log)
+	assert.Error(t, err)
+}
+
+func Test_Download_ErrorIncludesRequestID(t *testing.T) {
+	s3rc, mdf := newS3RepositoryClientWithMock(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("s3", nil)
+	c.Set("bucket", bucket)
+
+	inputPullCommand := pullman.PullCommand{
+		RepositoryConfig: c,
+		Directory:        filepath.Join("test", "output"),
+		Targets: []pullman.Target{
+			{
+				RemotePath: "path/to/model.zip",
+			},
+		},
+	}
+
+	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("path/to/model.zip"), gomock.Any()).
+		Return(objectsWithKeys("path/to/model.zip"), nil).
+		Times(1)
+	// batch downloads report the errors of the individual objects
+	objErr := awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, "4442587FB7D0A2F9")
+	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Any(), gomock.Eq(0)).
+		Return(awserr.NewBatchError("BatchedDownloadIncomplete", "some objects have failed to download.", []error{objErr})).
+		Times(1)
+
+	err := s3rc.Pull(context.Background(), inputPullCommand)
+	assert.ErrorContains(t, err, "request id: 4442587FB7D0A2F9")
+
+	var reqIDErr *pullman.RequestIDError
+	assert.ErrorAs(t, err, &reqIDErr)
+	assert.Equal(t, "4442587FB7D0A2F9", reqIDErr.RequestID)
+}
+
+func Test_Download_DirectoryMarker(t *testing.T) {
+	s3rc, mdf := newS3RepositoryClientWithMock(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("s3", nil)
+	c.Set("bucket", bucket)
+
+	downloadDir := t.TempDir()
+	inputPullCommand := pullman.PullCommand{
+		RepositoryConfig: c,
+		Directory:        downloadDir,
+		Targets: []pullman.Target{
+			{
+				RemotePath: "path/to/modeldir",
+			},
+		},
+	}
+
+	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("path/to/modeldir"), gomock.Any()).
+		Return(objectsWithKeys("path/to/modeldir/file.ext", "path/to/modeldir/1/variables/"), nil).
+		Times(1)
+
+	// the marker is not downloaded
+	expectedTargets := []pullman.Target{
+		{
+			RemotePath: "path/to/modeldir/file.ext",
+			LocalPath:  filepath.Join(downloadDir, "file.ext"),
+		},
+	}
+	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Eq(expectedTargets), gomock.Eq(0)).
+		Return(nil).
+		Times(1)
+
+	err := s3rc.Pull(context.Background(), inputPullCommand)
+	assert.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(downloadDir, "1", "variables"))
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func Test_Download_SharedArtifactDownloadedOnce(t *testing.T) {
+	s3rc, mdf := newS3RepositoryClientWithMock(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("s3", nil)
+	c.Set("bucket", bucket)
+
+	cache, err := pullman.NewArtifactCache(filepath.Join(t.TempDir(), "cache"), 1024, 256)
+	assert.NoError(t, err)
+
+	// two models reference the same small file next to their own model file,
+	// which is stored at a different path for each
+	rootDir := t.TempDir()
+	sharedDownloads := 0
+	for _, model := range []string{"model-a", "model-b"} {
+		shared := s3Object{key: model + "-shared/labels.txt", etag: `"5d41402abc4b2a76b9719d911017c592"`, size: 5}
+		modelDir := filepath.Join(rootDir, model)
+		mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq(shared.key), gomock.Any()).
+			Return([]s3Object{shared}, nil).
+			Times(1)
+		mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq(model), gomock.Any()).
+			Return([]s3Object{{key: model + "/model.onnx", etag: `"` + model + `"`, size: 1000}}, nil).
+			Times(1)
+		mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Any(), gomock.Eq(0)).
+			DoAndReturn(func(_ context.Context, _ string, targets []pullman.Target, _ int) error {
+				for _, target := range targets {
+					content := "large"
+					if target.RemotePath == shared.key {
+						content = "hello"
+						sharedDownloads++
+					}
+					file, err := pullman.OpenFile(target.LocalPath)
+					if err != nil {
+						return err
+					}
+					file.WriteString(content)
+					file.Close()
+				}
+				return nil
+			}).
+			Times(1)
+
+		err = s3rc.Pull(context.Background(), pullman.PullCommand{
+			RepositoryConfig: c,
+			Directory:        modelDir,
+			Targets: []pullman.Target{
+				{RemotePath: shared.key},
+				{RemotePath: model},
+			},
+			ArtifactCache: cache,
+		})
+		assert.NoError(t, err)
+
+		labels, readErr := os.ReadFile(filepath.Join(modelDir, "labels.txt"))
+		assert.NoError(t, readErr)
+		assert.Equal(t, "hello", string(labels))
+		assert.FileExists(t, filepath.Join(modelDir, "model.onnx"))
+	}
+	assert.Equal(t, 1, sharedDownloads, "expected the shared artifact to be downloaded once")
+}
+
+func Test_Download_TagFilter(t *testing.T) {
+	s3rc, mdf := newS3RepositoryClientWithMock(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("s3", nil)
+	c.Set("bucket", bucket)
+	c.Set("tag_filter", map[string]interface{}{"stage": "production"})
+
+	downloadDir := filepath.Join("test", "output")
+	inputPullCommand := pullman.PullCommand{
+		RepositoryConfig: c,
+		Directory:        downloadDir,
+		Targets: []pullman.Target{
+			{
+				RemotePath: "path/to/modeldir",
+			},
+		},
+	}
+
+	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("path/to/modeldir"), gomock.Any()).
+		Return(objectsWithKeys("path/to/modeldir/model.onnx", "path/to/modeldir/draft.onnx", "path/to/modeldir/notes.txt"), nil).
+		Times(1)
+	objectTags := map[string]map[string]string{
+		"path/to/modeldir/model.onnx": {"stage": "production", "owner": "team-a"},
+		"path/to/modeldir/draft.onnx": {"stage": "staging"},
+		"path/to/modeldir/notes.txt":  {},
+	}
+	for key, tags := range objectTags {
+		mdf.EXPECT().getObjectTags(gomock.Any(), gomock.Eq(bucket), gomock.Eq(key), gomock.Eq("")).
+			Return(tags, nil).
+			Times(1)
+	}
+
+	// only the object with the matching tag is downloaded
+	expectedTargets := []pullman.Target{
+		{
+			RemotePath: "path/to/modeldir/model.onnx",
+			LocalPath:  filepath.Join(downloadDir, "model.onnx"),
+		},
+	}
+	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Eq(expectedTargets), gomock.Eq(0)).
+		Return(nil).
+		Times(1)
+
+	err := s3rc.Pull(context.Background(), inputPullCommand)
+	assert.NoError(t, err)
+}
+
+func Test_Download_TagFilterNoMatch(t *testing.T) {
+	s3rc, mdf := newS3RepositoryClientWithMock(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("s3", nil)
+	c.Set("bucket", bucket)
+	c.Set("tag_filter", map[string]interface{}{"stage": "production"})
+
+	inputPullCommand := pullman.PullCommand{
+		RepositoryConfig: c,
+		Directory:        filepath.Join("test", "output"),
+		Targets: []pullman.Target{
+			{
+				RemotePath: "path/to/model.onnx",
+			},
+		},
+	}
+
+	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("path/to/model.onnx"), gomock.Any()).
+		Return(objectsWithKeys("path/to/model.onnx"), nil).
+		Times(1)
+	mdf.EXPECT().getObjectTags(gomock.Any(), gomock.Eq(bucket), gomock.Eq("path/to/model.onnx"), gomock.Eq("")).
+		Return(map[string]string{"stage": "staging"}, nil).
+		Times(1)
+
+	// the single object is filtered out, which fails the pull instead of
+	// pulling an empty model
+	err := s3rc.Pull(context.Background(), inputPullCommand)
+	assert.ErrorContains(t, err, "match the tag filter")
+}
+
+func Test_Download_VerifyChecksums(t *testing.T) {
+	helloMD5 := `"5d41402abc4b2a76b9719d911017c592"`
+	helloSHA256 := sha256.Sum256([]byte("hello"))
+	otherSHA256 := sha256.Sum256([]byte("other"))
+
+	testCases := []struct {
+		name string
+		etag string
+		// the SHA256 checksum that S3 returns, nil if it is not requested
+		checksum      *string
+		expectedError string
+	}{
+		{
+			name: "single part MD5 verified",
+			etag: helloMD5,
+		},
+		{
+			name:          "single part MD5 mismatch",
+			etag:          `"00000000000000000000000000000000"`,
+			expectedError: "MD5 checksum of object 'model/model.onnx'",
+		},
+		{
+			name:     "multipart without checksum skipped",
+			etag:     `"d41d8cd98f00b204e9800998ecf8427e-3"`,
+			checksum: new(string),
+		},
+		{
+			name:     "multipart with composite checksum skipped",
+			etag:     `"d41d8cd98f00b204e9800998ecf8427e-3"`,
+			checksum: stringPtr("x2Z0bj1B3gEeoeDRrqTAJm7q3wgJMqRZ5Vk0b8RbwXo=-3"),
+		},
+		{
+			name:     "multipart with SHA256 checksum verified",
+			etag:     `"d41d8cd98f00b204e9800998ecf8427e-3"`,
+			checksum: stringPtr(base64.StdEncoding.EncodeToString(helloSHA256[:])),
+		},
+		{
+			name:          "multipart with SHA256 checksum mismatch",
+			etag:          `"d41d8cd98f00b204e9800998ecf8427e-3"`,
+			checksum:      stringPtr(base64.StdEncoding.EncodeToString(otherSHA256[:])),
+			expectedError: "SHA256 checksum of object 'model/model.onnx'",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s3rc, mdf := newS3RepositoryClientWithMock(t)
+
+			bucket := "bucket"
+			c := pullman.NewRepositoryConfig("s3", nil)
+			c.Set("bucket", bucket)
+			c.Set("verify_checksums", true)
+
+			mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("model"), gomock.Any()).
+				Return([]s3Object{{key: "model/model.onnx", etag: tc.etag, size: 5}}, nil).
+				Times(1)
+			mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Any(), gomock.Eq(0)).
+				DoAndReturn(func(_ context.Context, _ string, targets []pullman.Target, _ int) error {
+					for _, target := range targets {
+						file, err := pullman.OpenFile(target.LocalPath)
+						if err != nil {
+							return err
+						}
+						file.WriteString("hello")
+						file.Close()
+					}
+					return nil
+				}).
+				Times(1)
+			if tc.checksum != nil {
+				mdf.EXPECT().getObjectChecksum(gomock.Any(), gomock.Eq(bucket), gomock.Eq("model/model.onnx"), gomock.Eq("")).
+					Return(*tc.checksum, nil).
+					Times(1)
+			}
+
+			err := s3rc.Pull(context.Background(), pullman.PullCommand{
+				RepositoryConfig: c,
+				Directory:        t.TempDir(),
+				Targets:          []pullman.Target{{RemotePath: "model"}},
+			})
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func Test_Download_VersionID(t *testing.T) {
+	s3rc, mdf := newS3RepositoryClientWithMock(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("s3", nil)
+	c.Set("bucket", bucket)
+
+	// the latest version of the object has since been overwritten
+	versions := map[string]string{"v1": "pinned", "": "overwritten"}
+
+	// the version is not listed, but looked up directly
+	mdf.EXPECT().listObjects(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	mdf.EXPECT().getObjectVersion(gomock.Any(), gomock.Eq(bucket), gomock.Eq("model/model.onnx"), gomock.Eq("v1")).
+		Return(s3Object{key: "model/model.onnx", size: 6, versionID: "v1"}, nil).
+		Times(1)
+
+	downloadDir := t.TempDir()
+	expectedTargets := []pullman.Target{
+		{
+			RemotePath: "model/model.onnx",
+			LocalPath:  filepath.Join(downloadDir, "model.onnx"),
+			VersionID:  "v1",
+		},
+	}
+	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Eq(expectedTargets), gomock.Eq(0)).
+		DoAndReturn(func(_ context.Context, _ string, targets []pullman.Target, _ int) error {
+			for _, target := range targets {
+				if err := os.WriteFile(target.LocalPath, []byte(versions[target.VersionID]), 0644); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).
+		Times(1)
+
+	err := s3rc.Pull(context.Background(), pullman.PullCommand{
+		RepositoryConfig: c,
+		Directory:        downloadDir,
+		Targets:          []pullman.Target{{RemotePath: "model/model.onnx", VersionID: "v1"}},
+	})
+	assert.NoError(t, err)
+
+	contents, err := os.ReadFile(filepath.Join(downloadDir, "model.onnx"))
+	assert.NoError(t, err)
+	assert.Equal(t, "pinned", string(contents))
+}
+
+func Test_Download_VersionIDInConfig(t *testing.T) {
+	s3rc, mdf := newS3RepositoryClientWithMock(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("s3", nil)
+	c.Set("bucket", bucket)
+	c.Set("version_id", "v1")
+
+	// the version of the config applies to the target without its own
+	mdf.EXPECT().listObjects(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	mdf.EXPECT().getObjectVersion(gomock.Any(), gomock.Eq(bucket), gomock.Eq("model/model.onnx"), gomock.Eq("v1")).
+		Return(s3Object{key: "model/model.onnx", size: 6, versionID: "v1"}, nil).
+		Times(1)
+
+	downloadDir := filepath.Join("test", "output")
+	expectedTargets := []pullman.Target{
+		{
+			RemotePath: "model/model.onnx",
+			LocalPath:  filepath.Join(downloadDir, "model.onnx"),
+			VersionID:  "v1",
+		},
+	}
+	mdf.EXPECT().downloadBatch(gomock.Any(), gomock.Eq(bucket), gomock.Eq(expectedTargets), gomock.Eq(0)).
+		Return(nil).
+		Times(1)
+
+	err := s3rc.Pull(context.Background(), pullman.PullCommand{
+		RepositoryConfig: c,
+		Directory:        downloadDir,
+		Targets:          []pullman.Target{{RemotePath: "model/model.onnx"}},
+	})
+	assert.NoError(t, err)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func Test_List(t *testing.T) {
+	s3rc, mdf := newS3RepositoryClientWithMock(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("s3", nil)
+	c.Set("bucket", bucket)
+
+	mdf.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("models/"), gomock.Any()).
+		Return([]s3Object{
+			{key: "models/mnist/", size: 0},
+			{key: "models/mnist/model.onnx", size: 1024},
+			{key: "models/resnet/saved_model.pb", size: 2048},
+		}, nil).
+		Times(1)
+
+	objects, err := s3rc.List(context.Background(), pullman.ListCommand{RepositoryConfig: c, Prefix: "models/"})
+	assert.NoError(t, err)
+	// the directory marker is not listed
+	assert.Equal(t, []pullman.ObjectInfo{
+		{Path: "models/mnist/model.onnx", Size: 1024},
+		{Path: "models/resnet/saved_model.pb", Size: 2048},
+	}, objects)
+}
+
+func Test_List_FailoverToSecondaryEndpoint(t *testing.T) {
+	s3rc, primary, secondary := newFailoverS3RepositoryClientWithMocks(t)
+
+	bucket := "bucket"
+	c := pullman.NewRepositoryConfig("s3", nil)
+	c.Set("bucket", bucket)
+
+	primary.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("models/"), gomock.Any()).
+		Return(nil, awserr.New(request.ErrCodeRequestError, "send request failed", nil)).
+		Times(1)
+	secondary.EXPECT().listObjects(gomock.Eq(bucket), gomock.Eq("models/"), gomock.Any()).
+		Return(objectsWithKeys("models/model.zip"), nil).
+		Times(1)
+
+	objects, err := s3rc.List(context.Background(), pullman.ListCommand{RepositoryConfig: c, Prefix: "models/"})
+	assert.NoError(t, err)
+	assert.Equal(t, []pullman.ObjectInfo{{Path: "models/model.zip", Size: 1}}, objects)
+}
+
+func Test_List_MissingBucket(t *testing.T) {
+	s3rc, _ := newS3RepositoryClientWithMock(t)
+
+	_, err := s3rc.List(context.Background(), pullman.ListCommand{RepositoryConfig: pullman.NewRepositoryConfig("s3", nil)})
+	assert.ErrorContains(t, err, "'bucket' missing")
+}
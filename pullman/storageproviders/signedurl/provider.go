@@ -0,0 +1,275 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signedurlprovider
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/go-logr/logr"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+const (
+	configURLs        = "urls"
+	configCertificate = "certificate"
+)
+
+// the fields of an object in the urls
+const (
+	configURL      = "url"
+	configFilename = "filename"
+)
+
+type signedURLProvider struct{}
+
+// signedURLProvider implements StorageProvider
+var _ pullman.StorageProvider = (*signedURLProvider)(nil)
+
+func (p signedURLProvider) GetKey(config pullman.Config) string {
+	// the TLS config, timeouts, proxy and user agent go into the client, so changes to those require a new client
+	// the urls are handled per Pull()
+	cert, _ := pullman.GetString(config, configCertificate)
+	timeouts, _ := pullman.GetTimeouts(config)
+	proxy, _ := pullman.GetProxy(config)
+
+	return pullman.HashStrings(cert, timeouts.String(), proxy.String(), pullman.GetUserAgent(config))
+}
+
+func (p signedURLProvider) NewRepository(config pullman.Config, log logr.Logger) (pullman.RepositoryClient, error) {
+	timeouts, err := pullman.GetTimeouts(config)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := pullman.GetProxy(config)
+	if err != nil {
+		return nil, err
+	}
+	// redirects are followed by the client, up to the limit of net/http
+	httpClient := pullman.NewProxiedHTTPClient(timeouts, proxy)
+
+	// the certificate is optional
+	if cert, _ := pullman.GetString(config, configCertificate); cert != "" {
+		ca := x509.NewCertPool()
+		if ok := ca.AppendCertsFromPEM([]byte(cert)); !ok {
+			return nil, errors.New("failed to add certificate to CA pool")
+		}
+		httpClient.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: ca}
+	}
+
+	return &signedURLRepository{
+		httpClient: httpClient,
+		userAgent:  pullman.GetUserAgent(config),
+		log:        log,
+	}, nil
+}
+
+// signedURL is a URL to download and the path of the file to download it to
+type signedURL struct {
+	url string
+	// relative to the LocalPath of the target, empty to name the file after
+	// the response
+	filename string
+}
+
+// getURLs parses the urls of the config, each of which is either a URL or
+// an object with a url and a filename
+func getURLs(config pullman.Config) ([]signedURL, error) {
+	value, ok := config.Get(configURLs)
+	if !ok {
+		return nil, fmt.Errorf("missing required configuration '%s'", configURLs)
+	}
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("configuration '%s' must be a non-empty list, found '%v'", configURLs, value)
+	}
+
+	urls := make([]signedURL, 0, len(list))
+	for i, item := range list {
+		var su signedURL
+		switch v := item.(type) {
+		case string:
+			su.url = v
+		case map[string]interface{}:
+			su.url, _ = v[configURL].(string)
+			if filename, exists := v[configFilename]; exists {
+				if su.filename, ok = filename.(string); !ok || su.filename == "" {
+					return nil, fmt.Errorf("the %s of url %d must be a non-empty string, found '%v'", configFilename, i, filename)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("url %d must be a string or an object, found '%v'", i, item)
+		}
+
+		u, err := url.Parse(su.url)
+		if err != nil {
+			// the error would include the url, which may be signed
+			return nil, fmt.Errorf("url %d is not a valid url", i)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("url %d '%s' must start with http:// or https://", i, redactURL(u))
+		}
+		urls = append(urls, su)
+	}
+	return urls, nil
+}
+
+type signedURLRepository struct {
+	httpClient *http.Client
+	// sent as the User-Agent of the requests if not empty
+	userAgent string
+	log       logr.Logger
+}
+
+// signedURLRepository implements RepositoryClient
+var _ pullman.RepositoryClient = (*signedURLRepository)(nil)
+
+// Pull downloads the urls of the config into the LocalPath of the target,
+// the RemotePath of the target is not used
+func (r *signedURLRepository) Pull(ctx context.Context, pc pullman.PullCommand) error {
+	urls, err := getURLs(pc.RepositoryConfig)
+	if err != nil {
+		return err
+	}
+	if len(pc.Targets) != 1 {
+		return fmt.Errorf("the urls are pulled into a single target, found %d targets", len(pc.Targets))
+	}
+	pt := pc.Targets[0]
+	if pt.ExtractTar {
+		return errors.New("extracting a tar archive is not supported")
+	}
+	targetDir, err := util.SecureJoin(pc.Directory, pt.LocalPath)
+	if err != nil {
+		return fmt.Errorf("error joining filepaths '%s' and '%s': %w", pc.Directory, pt.LocalPath, err)
+	}
+
+	// the url that each file was downloaded from
+	downloaded := make(map[string]int, len(urls))
+	for i, su := range urls {
+		filePath, err := r.download(ctx, su, targetDir, downloaded)
+		if err != nil {
+			u, _ := url.Parse(su.url)
+			return fmt.Errorf("unable to download url %d '%s': %w", i, redactURL(u), err)
+		}
+		if j, exists := downloaded[filePath]; exists {
+			return fmt.Errorf("urls %d and %d are both downloaded to '%s'", j, i, filePath)
+		}
+		downloaded[filePath] = i
+	}
+
+	return nil
+}
+
+// download writes the response of the url to its file in the dir and returns
+// the path of the file; a file that was downloaded before is not overwritten
+func (r *signedURLRepository) download(ctx context.Context, su signedURL, dir string, downloaded map[string]int) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, su.url, nil)
+	if err != nil {
+		return "", errors.New("error building HTTP request")
+	}
+	if r.userAgent != "" {
+		req.Header.Set("User-Agent", r.userAgent)
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return "", errorWithoutURL(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response status '%s'", resp.Status)
+	}
+
+	filename := su.filename
+	if filename == "" {
+		if filename = responseFilename(resp); filename == "" {
+			return "", fmt.Errorf("unable to name the file after the response, set the %s of the url", configFilename)
+		}
+	}
+	filePath, err := util.SecureJoin(dir, filename)
+	if err != nil {
+		return "", fmt.Errorf("error joining filepaths '%s' and '%s': %w", dir, filename, err)
+	}
+	if _, exists := downloaded[filePath]; exists {
+		return filePath, nil
+	}
+	r.log.V(1).Info("downloading file", "url", redactURL(resp.Request.URL), "filename", filePath)
+
+	file, err := pullman.OpenFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("unable to open local file '%s' for writing: %w", filePath, err)
+	}
+	defer file.Close()
+
+	if _, err = io.Copy(file, resp.Body); err != nil {
+		return "", fmt.Errorf("error writing to local file '%s': %w", filePath, err)
+	}
+	return filePath, nil
+}
+
+// responseFilename returns the base name of the filename in the
+// Content-Disposition of the response, or else of the path of the URL the
+// response was redirected to; it is empty if neither has a name
+func responseFilename(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		// the filename* parameter is decoded into filename
+		if name := baseName(params["filename"]); name != "" {
+			return name
+		}
+	}
+	return baseName(resp.Request.URL.Path)
+}
+
+// baseName returns the last element of the slash separated path, which is
+// empty if the path has no name
+func baseName(p string) string {
+	switch name := path.Base(p); name {
+	case ".", "..", "/":
+		return ""
+	default:
+		return name
+	}
+}
+
+// redactURL removes the query and the user info from the URL, which carry
+// the signature or the credentials of a signed URL
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+	return redacted.String()
+}
+
+// errorWithoutURL removes the request URL from errors of the HTTP client
+func errorWithoutURL(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
+
+func init() {
+	pullman.RegisterProvider("signedurl", signedURLProvider{})
+}
@@ -0,0 +1,204 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signedurlprovider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// newSignedURLStub serves files like a bucket of signed URLs and a Drive
+// export link, which redirect to the file with a Content-Disposition
+func newSignedURLStub(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bucket/model.onnx", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("signature") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("onnx"))
+	})
+	mux.HandleFunc("/uc", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/download?id="+r.URL.Query().Get("id"), http.StatusSeeOther)
+	})
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="../config.pbtxt"; filename*=UTF-8''config.pbtxt`)
+		w.Write([]byte("config " + r.URL.Query().Get("id")))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("index"))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestRepository(t *testing.T) pullman.RepositoryClient {
+	repo, err := signedURLProvider{}.NewRepository(pullman.NewRepositoryConfig("signedurl", nil), zap.New())
+	if err != nil {
+		t.Fatalf("unable to create repository: %v", err)
+	}
+	return repo
+}
+
+func newPullCommand(dir string, urls ...interface{}) pullman.PullCommand {
+	return pullman.PullCommand{
+		RepositoryConfig: pullman.NewRepositoryConfig("signedurl", map[string]interface{}{configURLs: urls}),
+		Directory:        dir,
+		Targets:          []pullman.Target{{RemotePath: "model", LocalPath: "model"}},
+	}
+}
+
+func Test_Pull_FilenamesAndRedirects(t *testing.T) {
+	server := newSignedURLStub(t)
+	dir := t.TempDir()
+
+	pc := newPullCommand(dir,
+		server.URL+"/bucket/model.onnx?signature=secret",
+		server.URL+"/uc?export=download&id=42",
+		map[string]interface{}{
+			configURL:      server.URL + "/bucket/model.onnx?signature=secret",
+			configFilename: "1/renamed.onnx",
+		},
+		// a filename is kept within the target
+		map[string]interface{}{
+			configURL:      server.URL + "/bucket/model.onnx?signature=secret",
+			configFilename: "../../escaped.onnx",
+		},
+	)
+	err := newTestRepository(t).Pull(context.Background(), pc)
+	assert.NoError(t, err)
+
+	for filename, expected := range map[string]string{
+		"model.onnx":     "onnx",
+		"config.pbtxt":   "config 42",
+		"1/renamed.onnx": "onnx",
+		"escaped.onnx":   "onnx",
+	} {
+		contents, readErr := os.ReadFile(filepath.Join(dir, "model", filename))
+		assert.NoError(t, readErr)
+		assert.Equal(t, expected, string(contents))
+	}
+}
+
+func Test_Pull_Errors(t *testing.T) {
+	server := newSignedURLStub(t)
+
+	tests := []struct {
+		name          string
+		urls          []interface{}
+		expectedError string
+	}{
+		{
+			name:          "status",
+			urls:          []interface{}{server.URL + "/bucket/model.onnx?signature=wrong"},
+			expectedError: "403 Forbidden",
+		},
+		{
+			name:          "redirect loop",
+			urls:          []interface{}{server.URL + "/loop"},
+			expectedError: "stopped after 10 redirects",
+		},
+		{
+			name: "same filename",
+			urls: []interface{}{
+				server.URL + "/bucket/model.onnx?signature=secret",
+				map[string]interface{}{configURL: server.URL + "/uc?id=1", configFilename: "model.onnx"},
+			},
+			expectedError: "urls 0 and 1 are both downloaded to",
+		},
+		{
+			name:          "no name",
+			urls:          []interface{}{server.URL + "/?signature=secret"},
+			expectedError: "unable to name the file",
+		},
+		{
+			name:          "scheme",
+			urls:          []interface{}{"ftp://example.com/model.onnx"},
+			expectedError: "must start with http:// or https://",
+		},
+		{
+			name:          "empty",
+			urls:          []interface{}{},
+			expectedError: "must be a non-empty list",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestRepository(t).Pull(context.Background(), newPullCommand(t.TempDir(), tt.urls...))
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.expectedError)
+				// the signature is not leaked in errors
+				assert.False(t, strings.Contains(err.Error(), "signature="), "error contains the signature: %v", err)
+			}
+		})
+	}
+}
+
+func Test_Pull_MultipleTargets(t *testing.T) {
+	server := newSignedURLStub(t)
+	pc := newPullCommand(t.TempDir(), server.URL+"/bucket/model.onnx?signature=secret")
+	pc.Targets = append(pc.Targets, pullman.Target{RemotePath: "schema.json", LocalPath: "_schema.json"})
+
+	err := newTestRepository(t).Pull(context.Background(), pc)
+	assert.ErrorContains(t, err, "found 2 targets")
+}
+
+func Test_GetKey(t *testing.T) {
+	provider := signedURLProvider{}
+
+	createTestConfig := func() *pullman.RepositoryConfig {
+		config := pullman.NewRepositoryConfig("signedurl", nil)
+		config.Set(configURLs, []interface{}{"https://example.com/model.onnx?signature=1"})
+		return config
+	}
+
+	// should return the same result given the same config
+	t.Run("shouldMatchForSameConfig", func(t *testing.T) {
+		assert.Equal(t, provider.GetKey(createTestConfig()), provider.GetKey(createTestConfig()))
+	})
+
+	// the urls are handled per pull, so they do not change the key
+	t.Run("shouldNotChangeForURLs", func(t *testing.T) {
+		config := createTestConfig()
+		config.Set(configURLs, []interface{}{"https://example.com/model.onnx?signature=2"})
+		assert.Equal(t, provider.GetKey(createTestConfig()), provider.GetKey(config))
+	})
+
+	t.Run("shouldChangeForCertificate", func(t *testing.T) {
+		config := createTestConfig()
+		config.Set(configCertificate, "cert")
+		assert.NotEqual(t, provider.GetKey(createTestConfig()), provider.GetKey(config))
+	})
+}
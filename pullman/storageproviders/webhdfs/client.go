@@ -0,0 +1,199 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhdfsprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/go-logr/logr"
+
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+// the REST API of HDFS is served under this path of the NameNode
+const webhdfsPathPrefix = "/webhdfs/v1"
+
+const (
+	fileTypeFile      = "FILE"
+	fileTypeDirectory = "DIRECTORY"
+)
+
+// fileStatus is an entry of a LISTSTATUS response
+type fileStatus struct {
+	PathSuffix string `json:"pathSuffix"`
+	Type       string `json:"type"`
+	Length     int64  `json:"length"`
+}
+
+type listStatusResponse struct {
+	FileStatuses struct {
+		FileStatus []fileStatus `json:"FileStatus"`
+	} `json:"FileStatuses"`
+}
+
+// remoteExceptionResponse is the body of a failed WebHDFS request
+type remoteExceptionResponse struct {
+	RemoteException struct {
+		Exception string `json:"exception"`
+		Message   string `json:"message"`
+	} `json:"RemoteException"`
+}
+
+// hdfsEntry is a file or directory found under a path, relative to the path
+type hdfsEntry struct {
+	path  string
+	isDir bool
+	// length of a file in bytes
+	size int64
+}
+
+type webhdfsClient struct {
+	httpClient *http.Client
+	baseURL    url.URL
+	// the query parameters that authenticate each request
+	auth url.Values
+	// sent as the User-Agent of the requests if not empty
+	userAgent string
+	log       logr.Logger
+}
+
+func (c *webhdfsClient) newRequest(ctx context.Context, hdfsPath string, op string) (*http.Request, error) {
+	u := c.baseURL
+	u.Path = path.Join(c.baseURL.Path, webhdfsPathPrefix, hdfsPath)
+
+	query := url.Values{"op": []string{op}}
+	for k, v := range c.auth {
+		query[k] = v
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return req, nil
+}
+
+// do sends the request and returns the response if it succeeded
+//
+// The errors name the operation and path instead of the URL, which may hold
+// a delegation token.
+func (c *webhdfsClient) do(ctx context.Context, hdfsPath string, op string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, hdfsPath, op)
+	if err != nil {
+		return nil, fmt.Errorf("error building %s request for '%s': %w", op, hdfsPath, err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending %s request for '%s': %w", op, hdfsPath, errorWithoutURL(err))
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var remoteErr remoteExceptionResponse
+	if body, readErr := io.ReadAll(resp.Body); readErr == nil && json.Unmarshal(body, &remoteErr) == nil && remoteErr.RemoteException.Exception != "" {
+		return nil, fmt.Errorf("%s request for '%s' failed with status %d: %s: %s", op, hdfsPath, resp.StatusCode,
+			remoteErr.RemoteException.Exception, remoteErr.RemoteException.Message)
+	}
+	return nil, fmt.Errorf("%s request for '%s' failed with status %d", op, hdfsPath, resp.StatusCode)
+}
+
+func (c *webhdfsClient) listStatus(ctx context.Context, hdfsPath string) ([]fileStatus, error) {
+	resp, err := c.do(ctx, hdfsPath, "LISTSTATUS")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list listStatusResponse
+	if err = json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("error parsing LISTSTATUS response for '%s': %w", hdfsPath, err)
+	}
+	return list.FileStatuses.FileStatus, nil
+}
+
+// listEntries returns the files and directories under the path, recursively
+//
+// If the path is a file, the only entry has an empty path.
+func (c *webhdfsClient) listEntries(ctx context.Context, hdfsPath string) ([]hdfsEntry, error) {
+	statuses, err := c.listStatus(ctx, hdfsPath)
+	if err != nil {
+		return nil, err
+	}
+	// the status of a file has no suffix to the path
+	if len(statuses) == 1 && statuses[0].PathSuffix == "" && statuses[0].Type == fileTypeFile {
+		return []hdfsEntry{{path: "", size: statuses[0].Length}}, nil
+	}
+
+	entries := make([]hdfsEntry, 0, len(statuses))
+	for _, status := range statuses {
+		switch status.Type {
+		case fileTypeFile:
+			entries = append(entries, hdfsEntry{path: status.PathSuffix, size: status.Length})
+		case fileTypeDirectory:
+			entries = append(entries, hdfsEntry{path: status.PathSuffix, isDir: true})
+			children, err := c.listEntries(ctx, path.Join(hdfsPath, status.PathSuffix))
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				entries = append(entries, hdfsEntry{path: path.Join(status.PathSuffix, child.path), isDir: child.isDir, size: child.size})
+			}
+		default:
+			c.log.V(1).Info("skipping entry that is neither a file nor a directory", "path", path.Join(hdfsPath, status.PathSuffix), "type", status.Type)
+		}
+	}
+	return entries, nil
+}
+
+// download writes the file to filename, following the redirect of the
+// NameNode to a DataNode that has the data
+func (c *webhdfsClient) download(ctx context.Context, hdfsPath string, filename string) error {
+	resp, err := c.do(ctx, hdfsPath, "OPEN")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	file, fileErr := pullman.OpenFile(filename)
+	if fileErr != nil {
+		return fmt.Errorf("unable to open local file '%s' for writing: %w", filename, fileErr)
+	}
+	defer file.Close()
+
+	if _, err = io.Copy(file, resp.Body); err != nil {
+		return fmt.Errorf("error writing '%s' to local file '%s': %w", hdfsPath, filename, err)
+	}
+	return nil
+}
+
+// errorWithoutURL removes the request URL from errors of the HTTP client
+func errorWithoutURL(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,224 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhdfsprovider
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/kserve/modelmesh-runtime-adapter/internal/util"
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+)
+
+const (
+	configURL             = "url"
+	configAuthType        = "auth_type"
+	configUser            = "user"
+	configDelegationToken = "delegation_token"
+	configCertificate     = "certificate"
+)
+
+const (
+	// the user is passed in the user.name parameter, for clusters without
+	// Kerberos
+	authTypeSimple = "simple"
+	// a delegation token issued to a Kerberos principal is passed in the
+	// delegation parameter
+	authTypeDelegationToken = "delegation_token"
+)
+
+type webhdfsProvider struct{}
+
+// webhdfsProvider implements StorageProvider
+var _ pullman.StorageProvider = (*webhdfsProvider)(nil)
+
+func (p webhdfsProvider) GetKey(config pullman.Config) string {
+	// all of the configuration goes into the client, so any change requires a new client
+	endpoint, _ := pullman.GetString(config, configURL)
+	authType, _ := pullman.GetString(config, configAuthType)
+	user, _ := pullman.GetString(config, configUser)
+	token, _ := pullman.GetString(config, configDelegationToken)
+	cert, _ := pullman.GetString(config, configCertificate)
+	timeouts, _ := pullman.GetTimeouts(config)
+	proxy, _ := pullman.GetProxy(config)
+
+	return pullman.HashStrings(endpoint, authType, user, token, cert, timeouts.String(), proxy.String(), pullman.GetUserAgent(config))
+}
+
+func (p webhdfsProvider) NewRepository(config pullman.Config, log logr.Logger) (pullman.RepositoryClient, error) {
+	endpoint, ok := pullman.GetString(config, configURL)
+	if !ok {
+		return nil, fmt.Errorf("missing required string configuration '%s'", configURL)
+	}
+	baseURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("url '%s' must start with http:// or https://", endpoint)
+	}
+
+	auth, err := getAuth(config)
+	if err != nil {
+		return nil, err
+	}
+
+	timeouts, err := pullman.GetTimeouts(config)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := pullman.GetProxy(config)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := pullman.NewProxiedHTTPClient(timeouts, proxy)
+
+	// the certificate is optional
+	if cert, _ := pullman.GetString(config, configCertificate); cert != "" {
+		ca := x509.NewCertPool()
+		if ok := ca.AppendCertsFromPEM([]byte(cert)); !ok {
+			return nil, errors.New("failed to add certificate to CA pool")
+		}
+		httpClient.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: ca}
+	}
+
+	return &webhdfsRepository{
+		client: &webhdfsClient{
+			httpClient: httpClient,
+			baseURL:    *baseURL,
+			auth:       auth,
+			userAgent:  pullman.GetUserAgent(config),
+			log:        log,
+		},
+		log: log,
+	}, nil
+}
+
+// getAuth returns the query parameters that authenticate the requests
+func getAuth(config pullman.Config) (url.Values, error) {
+	authType, ok := pullman.GetString(config, configAuthType)
+	if !ok {
+		authType = authTypeSimple
+	}
+
+	switch authType {
+	case authTypeSimple:
+		// without a user, the cluster applies its default for anonymous requests
+		if user, _ := pullman.GetString(config, configUser); user != "" {
+			return url.Values{"user.name": []string{user}}, nil
+		}
+		return url.Values{}, nil
+	case authTypeDelegationToken:
+		token, _ := pullman.GetString(config, configDelegationToken)
+		if token == "" {
+			return nil, fmt.Errorf("missing required string configuration '%s' for auth type '%s'", configDelegationToken, authTypeDelegationToken)
+		}
+		return url.Values{"delegation": []string{token}}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth type '%s', expected '%s' or '%s'", authType, authTypeSimple, authTypeDelegationToken)
+	}
+}
+
+type webhdfsRepository struct {
+	client *webhdfsClient
+	log    logr.Logger
+}
+
+// webhdfsRepository implements RepositoryClient and RepositoryLister
+var _ pullman.RepositoryClient = (*webhdfsRepository)(nil)
+var _ pullman.RepositoryLister = (*webhdfsRepository)(nil)
+
+func (r *webhdfsRepository) Pull(ctx context.Context, pc pullman.PullCommand) error {
+	destDir := pc.Directory
+
+	for _, pt := range pc.Targets {
+		remotePath := strings.TrimSuffix(pt.RemotePath, "/")
+		entries, err := r.client.listEntries(ctx, remotePath)
+		if err != nil {
+			return fmt.Errorf("unable to list files under '%s': %w", pt.RemotePath, err)
+		}
+		r.log.V(1).Info("found files to download", "path", pt.RemotePath, "count", len(entries))
+
+		for _, entry := range entries {
+			localPath := pt.LocalPath
+			relativePath := entry.path
+			if entry.isDir {
+				// create the directory, which may be empty
+				dirPath, joinErr := util.SecureJoin(destDir, localPath, relativePath)
+				if joinErr != nil {
+					return fmt.Errorf("error joining filepaths '%s' and '%s': %w", pt.LocalPath, relativePath, joinErr)
+				}
+				if mkdirErr := os.MkdirAll(dirPath, 0755); mkdirErr != nil {
+					return fmt.Errorf("unable to create directory '%s': %w", dirPath, mkdirErr)
+				}
+				continue
+			}
+			// handle case where the remote path is a single file
+			if relativePath == "" {
+				// allow renaming of the file
+				if localPath != "" && !strings.HasSuffix(localPath, "/") {
+					relativePath = path.Base(localPath)
+					localPath = path.Dir(localPath)
+				} else {
+					relativePath = path.Base(remotePath)
+				}
+			}
+			filePath, joinErr := util.SecureJoin(destDir, localPath, relativePath)
+			if joinErr != nil {
+				return fmt.Errorf("error joining filepaths '%s' and '%s': %w", pt.LocalPath, relativePath, joinErr)
+			}
+
+			hdfsPath := path.Join(remotePath, entry.path)
+			r.log.V(1).Info("downloading file", "path", hdfsPath, "filename", filePath)
+			if err := r.client.download(ctx, hdfsPath, filePath); err != nil {
+				return fmt.Errorf("unable to download file '%s': %w", hdfsPath, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// List returns the files under the prefix, which is the path of a file or
+// directory, recursively
+func (r *webhdfsRepository) List(ctx context.Context, lc pullman.ListCommand) ([]pullman.ObjectInfo, error) {
+	remotePath := strings.TrimSuffix(lc.Prefix, "/")
+	entries, err := r.client.listEntries(ctx, remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list files under '%s': %w", lc.Prefix, err)
+	}
+
+	objects := make([]pullman.ObjectInfo, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.isDir {
+			objects = append(objects, pullman.ObjectInfo{Path: path.Join(remotePath, entry.path), Size: entry.size})
+		}
+	}
+	return objects, nil
+}
+
+func init() {
+	pullman.RegisterProvider("webhdfs", webhdfsProvider{})
+}
@@ -0,0 +1,267 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhdfsprovider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kserve/modelmesh-runtime-adapter/pullman"
+
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// webhdfsStub serves a file tree like a NameNode, redirecting OPEN requests
+// to a DataNode path of the same server
+type webhdfsStub struct {
+	// file paths to contents, directories are the paths ending with a '/'
+	files map[string]string
+	// the query parameters of every request
+	queries []url.Values
+}
+
+func (s *webhdfsStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.queries = append(s.queries, r.URL.Query())
+
+	if strings.HasPrefix(r.URL.Path, "/datanode/") {
+		w.Write([]byte(s.files[strings.TrimPrefix(r.URL.Path, "/datanode")]))
+		return
+	}
+
+	hdfsPath := strings.TrimPrefix(r.URL.Path, webhdfsPathPrefix)
+	_, isFile := s.files[hdfsPath]
+	_, isEmptyDir := s.files[hdfsPath+"/"]
+	children := s.children(hdfsPath)
+	if !isFile && !isEmptyDir && len(children) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"RemoteException": map[string]string{
+				"exception": "FileNotFoundException",
+				"message":   "File " + hdfsPath + " does not exist.",
+			},
+		})
+		return
+	}
+
+	switch r.URL.Query().Get("op") {
+	case "LISTSTATUS":
+		var list listStatusResponse
+		if isFile {
+			list.FileStatuses.FileStatus = []fileStatus{{Type: fileTypeFile, Length: int64(len(s.files[hdfsPath]))}}
+		} else {
+			list.FileStatuses.FileStatus = children
+		}
+		json.NewEncoder(w).Encode(list)
+	case "OPEN":
+		http.Redirect(w, r, "/datanode"+hdfsPath+"?"+r.URL.RawQuery, http.StatusTemporaryRedirect)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// children returns the statuses of the entries directly under the directory
+func (s *webhdfsStub) children(dir string) []fileStatus {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	seen := map[string]bool{}
+	var statuses []fileStatus
+	for p := range s.files {
+		rest := strings.TrimPrefix(p, prefix)
+		if !strings.HasPrefix(p, prefix) || rest == "" {
+			continue
+		}
+		status := fileStatus{PathSuffix: rest, Type: fileTypeFile, Length: int64(len(s.files[p]))}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			status = fileStatus{PathSuffix: rest[:i], Type: fileTypeDirectory}
+		}
+		if !seen[status.PathSuffix] {
+			seen[status.PathSuffix] = true
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].PathSuffix < statuses[j].PathSuffix })
+	return statuses
+}
+
+func newTestRepository(t *testing.T, stub *webhdfsStub, settings map[string]interface{}) pullman.RepositoryClient {
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	config := pullman.NewRepositoryConfig("webhdfs", settings)
+	config.Set(configURL, server.URL)
+	repo, err := webhdfsProvider{}.NewRepository(config, zap.New())
+	if err != nil {
+		t.Fatalf("unable to create repository: %v", err)
+	}
+	return repo
+}
+
+func Test_Pull_Directory(t *testing.T) {
+	stub := &webhdfsStub{files: map[string]string{
+		"/models/mnist/config.pbtxt":   "config",
+		"/models/mnist/1/model.onnx":   "weights",
+		"/models/mnist/empty/":         "",
+		"/models/other/model.onnx":     "other",
+		"/models/mnist-old/model.onnx": "old",
+	}}
+	repo := newTestRepository(t, stub, map[string]interface{}{configUser: "modelmesh"})
+
+	dir := t.TempDir()
+	err := repo.Pull(context.Background(), pullman.PullCommand{
+		RepositoryConfig: pullman.NewRepositoryConfig("webhdfs", nil),
+		Directory:        dir,
+		Targets:          []pullman.Target{{RemotePath: "models/mnist"}},
+	})
+	assert.NoError(t, err)
+
+	for file, contents := range map[string]string{"config.pbtxt": "config", "1/model.onnx": "weights"} {
+		data, readErr := os.ReadFile(filepath.Join(dir, file))
+		assert.NoError(t, readErr)
+		assert.Equal(t, contents, string(data))
+	}
+	info, err := os.Stat(filepath.Join(dir, "empty"))
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+	assert.NoFileExists(t, filepath.Join(dir, "model.onnx"))
+
+	// every request, including the ones to the DataNode, has the user
+	for _, query := range stub.queries {
+		assert.Equal(t, "modelmesh", query.Get("user.name"))
+	}
+}
+
+func Test_Pull_SingleFileRenamed(t *testing.T) {
+	stub := &webhdfsStub{files: map[string]string{
+		"/models/mnist.onnx": "weights",
+	}}
+	repo := newTestRepository(t, stub, nil)
+
+	dir := t.TempDir()
+	err := repo.Pull(context.Background(), pullman.PullCommand{
+		RepositoryConfig: pullman.NewRepositoryConfig("webhdfs", nil),
+		Directory:        dir,
+		Targets:          []pullman.Target{{RemotePath: "models/mnist.onnx", LocalPath: "1/model.onnx"}},
+	})
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "1", "model.onnx"))
+	assert.NoError(t, err)
+	assert.Equal(t, "weights", string(data))
+}
+
+func Test_Pull_DelegationToken(t *testing.T) {
+	stub := &webhdfsStub{files: map[string]string{
+		"/models/mnist/model.onnx": "weights",
+	}}
+	repo := newTestRepository(t, stub, map[string]interface{}{
+		configAuthType:        authTypeDelegationToken,
+		configDelegationToken: "token",
+		configUser:            "ignored",
+	})
+
+	err := repo.Pull(context.Background(), pullman.PullCommand{
+		RepositoryConfig: pullman.NewRepositoryConfig("webhdfs", nil),
+		Directory:        t.TempDir(),
+		Targets:          []pullman.Target{{RemotePath: "models/mnist"}},
+	})
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, stub.queries)
+	for _, query := range stub.queries {
+		assert.Equal(t, "token", query.Get("delegation"))
+		assert.False(t, query.Has("user.name"))
+	}
+}
+
+func Test_Pull_NotFound(t *testing.T) {
+	repo := newTestRepository(t, &webhdfsStub{files: map[string]string{}}, nil)
+
+	err := repo.Pull(context.Background(), pullman.PullCommand{
+		RepositoryConfig: pullman.NewRepositoryConfig("webhdfs", nil),
+		Directory:        t.TempDir(),
+		Targets:          []pullman.Target{{RemotePath: "models/missing"}},
+	})
+	assert.ErrorContains(t, err, "FileNotFoundException")
+}
+
+func Test_NewRepository_Auth(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		errMsg   string
+	}{
+		{"simple without user", map[string]interface{}{configAuthType: authTypeSimple}, ""},
+		{"missing token", map[string]interface{}{configAuthType: authTypeDelegationToken}, "missing required string configuration 'delegation_token'"},
+		{"unknown auth type", map[string]interface{}{configAuthType: "kerberos"}, "unknown auth type 'kerberos'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := pullman.NewRepositoryConfig("webhdfs", tt.settings)
+			config.Set(configURL, "http://namenode:9870")
+			_, err := webhdfsProvider{}.NewRepository(config, zap.New())
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func Test_List(t *testing.T) {
+	stub := &webhdfsStub{files: map[string]string{
+		"/models/mnist/config.pbtxt": "config",
+		"/models/mnist/1/model.onnx": "weights",
+		"/models/mnist/empty/":       "",
+		"/models/other.onnx":         "other",
+		"/datasets/train.csv":        "data",
+	}}
+	repo := newTestRepository(t, stub, nil)
+
+	objects, err := repo.(pullman.RepositoryLister).List(context.Background(), pullman.ListCommand{
+		RepositoryConfig: pullman.NewRepositoryConfig("webhdfs", nil),
+		Prefix:           "models/",
+	})
+	assert.NoError(t, err)
+	// the directories are not listed
+	assert.Equal(t, []pullman.ObjectInfo{
+		{Path: "models/mnist/1/model.onnx", Size: 7},
+		{Path: "models/mnist/config.pbtxt", Size: 6},
+		{Path: "models/other.onnx", Size: 5},
+	}, objects)
+}
+
+func Test_List_SingleFile(t *testing.T) {
+	stub := &webhdfsStub{files: map[string]string{
+		"/models/mnist.onnx": "weights",
+	}}
+	repo := newTestRepository(t, stub, nil)
+
+	objects, err := repo.(pullman.RepositoryLister).List(context.Background(), pullman.ListCommand{
+		RepositoryConfig: pullman.NewRepositoryConfig("webhdfs", nil),
+		Prefix:           "models/mnist.onnx",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []pullman.ObjectInfo{{Path: "models/mnist.onnx", Size: 7}}, objects)
+}
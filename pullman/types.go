@@ -36,6 +36,39 @@ type RepositoryClient interface {
 	Pull(context.Context, PullCommand) error
 }
 
+// A RepositoryLister is a RepositoryClient that can also list the objects
+// in the repository without downloading them
+type RepositoryLister interface {
+	List(context.Context, ListCommand) ([]ObjectInfo, error)
+}
+
+// A TarExtractor is a RepositoryClient that extracts the Targets with
+// ExtractTar itself, as they are downloaded. For other RepositoryClients, the
+// PullManager pulls the archives of these Targets as files and then extracts
+// them.
+type TarExtractor interface {
+	ExtractsTar() bool
+}
+
+// Represents the command sent to PullMan to list objects
+type ListCommand struct {
+	// repository in which objects will be listed
+	RepositoryConfig Config
+	// path under which objects are listed, which follows the same rules as
+	// the RemotePath of a Target
+	Prefix string
+}
+
+// ObjectInfo is an object found by a List
+type ObjectInfo struct {
+	// remote path of the object, which can be pulled as a Target
+	Path string
+	// size of the object in bytes
+	Size int64
+	// hex MD5 checksum of the content of the object, empty if not known
+	MD5 string
+}
+
 // Represents the command sent to PullMan to be fulfilled
 type PullCommand struct {
 	// repository from which files will be pulled
@@ -44,6 +77,16 @@ type PullCommand struct {
 	Directory string
 	// the list of paths referring to resources to be pulled
 	Targets []Target
+	// number of files downloaded in parallel, 0 for the default of the
+	// storage provider; ignored by providers that download one file at a time
+	Concurrency int
+	// cache of small files shared between pulls, nil to download every
+	// file; used by the providers that know the checksums of the files
+	ArtifactCache *ArtifactCache
+	// pull empty objects as empty files instead of skipping them, so that
+	// they can be checked after the pull; used by the S3, GCS and Azure
+	// providers, which skip them by default
+	KeepEmptyFiles bool
 }
 
 type Target struct {
@@ -51,4 +94,15 @@ type Target struct {
 	RemotePath string
 	// filepath to write the file(s) to
 	LocalPath string
+	// if set, the resource is a tar archive that is extracted into the
+	// LocalPath directory while it is downloaded, see ExtractTar
+	ExtractTar bool
+	// limit on the total size of the extracted files, 0 for no limit
+	MaxExtractedBytes int64
+	// if set with ExtractTar, only the entries under this path within the
+	// archive are extracted, see ExtractTarSubpath
+	TarSubpath string
+	// if set, this version of the object at the RemotePath is pulled instead
+	// of the latest one; only supported by storage that keeps versions
+	VersionID string
 }